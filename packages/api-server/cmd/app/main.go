@@ -13,6 +13,7 @@ import (
 	restful "github.com/emicklei/go-restful/v3"
 
 	"github.com/babelcloud/gbox/packages/api-server/config"
+	adminApi "github.com/babelcloud/gbox/packages/api-server/internal/admin/api"
 	boxApi "github.com/babelcloud/gbox/packages/api-server/internal/box/api"
 	boxService "github.com/babelcloud/gbox/packages/api-server/internal/box/service"
 	_ "github.com/babelcloud/gbox/packages/api-server/internal/box/service/impl/docker"
@@ -47,9 +48,14 @@ func main() {
 	// Initialize Access Tracker
 	accessTracker := tracker.NewInMemoryAccessTracker()
 	log.Info("Initialized In-Memory Access Tracker")
-	log.Info("Box reclaim stop threshold: %s, delete threshold: %s",
-		common.FormatDurationConcise(cfg.Cluster.ReclaimStopThreshold),
-		common.FormatDurationConcise(cfg.Cluster.ReclaimDeleteThreshold))
+	if cfg.Cluster.ReclaimDeleteEnabled {
+		log.Info("Box reclaim stop threshold: %s, delete threshold: %s",
+			common.FormatDurationConcise(cfg.Cluster.ReclaimStopThreshold),
+			common.FormatDurationConcise(cfg.Cluster.ReclaimDeleteThreshold))
+	} else {
+		log.Info("Box reclaim stop threshold: %s, deletion disabled",
+			common.FormatDurationConcise(cfg.Cluster.ReclaimStopThreshold))
+	}
 
 	// Initialize services
 	boxSvc, err := boxService.New(cfg.Cluster.Mode, accessTracker)
@@ -84,7 +90,7 @@ func main() {
 	}()
 
 	// Initialize cron manager (pass fileSvc pointer)
-	cronManager := cron.NewManager(log, boxSvc, fileSvc)
+	cronManager := cron.NewManager(log, boxSvc, fileSvc, accessTracker)
 	cronManager.Start()
 	defer cronManager.Stop()
 
@@ -92,6 +98,8 @@ func main() {
 	boxHandler := boxApi.NewBoxHandler(boxSvc)
 	fileHandler := fileApi.NewFileHandler(*fileSvc)
 	miscHandler := miscApi.NewMiscHandler(miscSvc)
+	readOnly := common.NewReadOnlyMode(cfg.Server.ReadOnly)
+	adminHandler := adminApi.NewAdminHandler(cronManager, readOnly)
 	browserHandler := browserApi.NewHandler(browserSvc)
 	cuaHandler := cuaApi.NewCuaHandler()
 
@@ -108,6 +116,7 @@ func main() {
 	boxApi.RegisterRoutes(ws, boxHandler)
 	fileApi.RegisterRoutes(ws, fileHandler)
 	miscApi.RegisterRoutes(ws, miscHandler)
+	adminApi.RegisterRoutes(ws, adminHandler)
 	browserApi.RegisterBrowserRoutes(ws, browserHandler)
 	cuaApi.RegisterCuaRoutes(ws, cuaHandler)
 
@@ -126,7 +135,7 @@ func main() {
 
 	// Add CORS filter
 	cors := restful.CrossOriginResourceSharing{
-		AllowedHeaders: []string{"Content-Type", "Accept"},
+		AllowedHeaders: []string{"Content-Type", "Accept", common.APIKeyHeader, "Authorization"},
 		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
 		AllowedDomains: []string{"*"},
 	}
@@ -162,15 +171,15 @@ func main() {
 	})
 
 	// Start server
-	addr := fmt.Sprintf(":%d", cfg.Server.Port)
+	addr := common.ListenAddress(cfg.Server.BindAddress, cfg.Server.Port)
 	log.Info("%s", format.FormatServerMode(cfg.Cluster.Mode))
 	log.Info("Starting server on %s", addr)
 
-	// Get all local IPs
-	ips := common.GetLocalIPs()
+	// Get the hosts the server is reachable on
+	hosts := common.GetAccessibleHosts(cfg.Server.BindAddress)
 	log.Info("Accessible URLs:")
-	for _, ip := range ips {
-		log.Info("  http://%s:%d", ip, cfg.Server.Port)
+	for _, host := range hosts {
+		log.Info("  http://%s", common.ListenAddress(host, cfg.Server.Port))
 	}
 
 	// Create a channel to receive OS signals
@@ -178,10 +187,17 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	// Start server in a goroutine
-	server := &http.Server{
-		Addr:    addr,
-		Handler: container,
+	// Require API keys when configured; version and health stay public
+	// Admin routes stay writable in read-only mode so operators can leave it
+	handler := common.RejectWritesWhenReadOnly(container, readOnly, "/api/v1/admin/")
+	handler = common.RequireAPIKey(handler, cfg.Server.Auth.Keys, "/api/v1/version", "/api/v1/health")
+	if len(cfg.Server.Auth.Keys) > 0 {
+		log.Info("API key authentication enabled with %d keys", len(cfg.Server.Auth.Keys))
+	}
+	if cfg.Server.ReadOnly {
+		log.Warn("Starting in read-only maintenance mode, mutating requests are rejected")
 	}
+	server := common.NewServer(addr, handler, cfg.Server)
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatal("Failed to start server: %v", err)
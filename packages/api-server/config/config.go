@@ -2,13 +2,18 @@ package config
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/babelcloud/gbox/packages/api-server/pkg/id"
 	"github.com/babelcloud/gbox/packages/api-server/pkg/logger"
+	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
@@ -32,6 +37,48 @@ type Config struct {
 // ServerConfig represents server configuration
 type ServerConfig struct {
 	Port int
+	// BindAddress is the host or IP the server listens on; empty means all interfaces
+	BindAddress string `mapstructure:"bind_address"`
+	// MaxURLLength limits the request URI (path and query) in bytes; 0 disables the limit
+	MaxURLLength int `mapstructure:"max_url_length"`
+	// MaxJSONBodyBytes limits the size of JSON request bodies; 0 disables the limit
+	MaxJSONBodyBytes int64 `mapstructure:"max_json_body_bytes"`
+	// MaxHeaderBytes limits the size of request headers, including the request line
+	MaxHeaderBytes int `mapstructure:"max_header_bytes"`
+	// ReadHeaderTimeout limits the time to read request headers
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	// ReadTimeout limits the time to read a whole request; 0 disables the limit
+	ReadTimeout time.Duration `mapstructure:"read_timeout"`
+	// WriteTimeout limits the time to write a response; streaming routes are exempt
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	// IdleTimeout closes keep-alive connections idle for longer than this
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+	// HTTP2 accepts cleartext HTTP/2 (h2c) connections alongside HTTP/1.1
+	HTTP2 bool `mapstructure:"http2"`
+	// Auth requires API keys on requests once any key is configured
+	Auth AuthConfig `mapstructure:"auth"`
+	// ReadOnly starts the server in maintenance mode, rejecting mutating
+	// requests until an operator disables it
+	ReadOnly bool `mapstructure:"read_only"`
+}
+
+// AuthConfig represents API key authentication configuration. Authentication
+// is disabled unless Keys, after loading KeysFile, holds at least one key.
+type AuthConfig struct {
+	// Keys are excluded from YAML so debug output never prints them
+	Keys []APIKey `mapstructure:"keys" yaml:"-"`
+	// KeysFile is a YAML file with a top-level "keys" list in the format of Keys
+	KeysFile string `mapstructure:"keys_file"`
+}
+
+// APIKey is an API key and the identity of the caller it authenticates, which
+// is recorded as the owner of the boxes the caller creates
+type APIKey struct {
+	Key   string `mapstructure:"key" yaml:"key"`
+	Owner string `mapstructure:"owner" yaml:"owner"`
+	// Box binds the key to one box, whose share subdirectory is the only one
+	// it can reach through /files when file.box_scoped is enabled
+	Box string `mapstructure:"box" yaml:"box"`
 }
 
 type CuaServerConfig struct {
@@ -44,6 +91,110 @@ type FileConfig struct {
 	Home      string `mapstructure:"home"`
 	Share     string `mapstructure:"share"`
 	HostShare string `mapstructure:"host_share"`
+	// BoxScoped restricts /files requests to the share subdirectory of the
+	// box their API key is bound to, preventing cross-box file access.
+	BoxScoped bool `mapstructure:"box_scoped"`
+	// Screenshot controls where box screenshots are stored and how long they are kept
+	Screenshot ScreenshotConfig `mapstructure:"screenshot"`
+	// ShareTiers are named share directories a box can be created in instead
+	// of Share, each reclaimed on its own schedule, e.g. a scratch tier emptied
+	// hourly next to a persist tier that is never reclaimed. The /files API
+	// and browser uploads only reach boxes in Share.
+	ShareTiers []ShareTier `mapstructure:"share_tiers"`
+}
+
+// ShareTier is a named share directory with its own file reclaim policy
+type ShareTier struct {
+	Name     string `mapstructure:"name"`
+	Path     string `mapstructure:"path"`      // Directory holding the share directories of the tier's boxes
+	HostPath string `mapstructure:"host_path"` // Path as seen by the Docker host; defaults to Path
+	// ReclaimAfter removes files not modified for this long; 0 never reclaims
+	ReclaimAfter time.Duration `mapstructure:"reclaim_after"`
+	// ReclaimSchedule is the cron spec of the tier's reclaim job, e.g.
+	// "@every 1h"; defaults to daily at midnight
+	ReclaimSchedule string `mapstructure:"reclaim_schedule"`
+}
+
+// defaultShareTierReclaimSchedule runs a tier's reclaim job daily at
+// midnight, like the reclamation of the default share directory
+const defaultShareTierReclaimSchedule = "0 0 * * *"
+
+var shareTierNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ShareRoot returns the server and Docker host paths of the share tier named
+// tier, or of the default share directory when tier is empty
+func (c FileConfig) ShareRoot(tier string) (string, string, bool) {
+	if tier == "" {
+		return c.Share, c.HostShare, true
+	}
+	for _, t := range c.ShareTiers {
+		if t.Name == tier {
+			return t.Path, t.HostPath, true
+		}
+	}
+	return "", "", false
+}
+
+// BoxShareDir returns the server and Docker host paths of a box's share
+// directory. Box IDs are unique across tiers, so the box's tier is the one
+// holding a directory for it; boxes without one belong to Share.
+func (c FileConfig) BoxShareDir(boxID string) (string, string) {
+	for _, t := range c.ShareTiers {
+		if _, err := os.Stat(filepath.Join(t.Path, boxID)); err == nil {
+			return filepath.Join(t.Path, boxID), filepath.Join(t.HostPath, boxID)
+		}
+	}
+	return filepath.Join(c.Share, boxID), filepath.Join(c.HostShare, boxID)
+}
+
+// resolveShareTiers validates the share tiers, fills in their defaults and
+// creates their directories
+func resolveShareTiers(tiers []ShareTier) error {
+	seen := make(map[string]bool, len(tiers))
+	for i := range tiers {
+		t := &tiers[i]
+		if !shareTierNamePattern.MatchString(t.Name) {
+			return fmt.Errorf("invalid share tier name '%s': use lowercase letters, digits, '_' and '-'", t.Name)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("share tier '%s' is configured more than once", t.Name)
+		}
+		seen[t.Name] = true
+
+		t.Path = os.ExpandEnv(t.Path)
+		if !filepath.IsAbs(t.Path) {
+			return fmt.Errorf("share tier '%s' must have an absolute path", t.Name)
+		}
+		t.HostPath = os.ExpandEnv(t.HostPath)
+		if t.HostPath == "" {
+			t.HostPath = t.Path
+		}
+
+		if t.ReclaimAfter < 0 {
+			return fmt.Errorf("share tier '%s' has a negative reclaim_after", t.Name)
+		}
+		if t.ReclaimSchedule == "" {
+			t.ReclaimSchedule = defaultShareTierReclaimSchedule
+		}
+		if _, err := cron.ParseStandard(t.ReclaimSchedule); err != nil {
+			return fmt.Errorf("invalid reclaim schedule '%s' of share tier '%s': %v", t.ReclaimSchedule, t.Name, err)
+		}
+
+		if err := os.MkdirAll(t.Path, 0755); err != nil {
+			return fmt.Errorf("failed to create directory of share tier '%s': %v", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// ScreenshotConfig represents screenshot storage and retention configuration
+type ScreenshotConfig struct {
+	// Dir is the subdirectory of each box's share directory holding screenshots
+	Dir string `mapstructure:"dir"`
+	// MaxCount keeps at most this many screenshots per box; 0 disables the limit
+	MaxCount int `mapstructure:"max_count"`
+	// MaxAge removes screenshots older than this; 0 disables the limit
+	MaxAge time.Duration `mapstructure:"max_age"`
 }
 
 // ClusterConfig represents cluster configuration
@@ -54,11 +205,48 @@ type ClusterConfig struct {
 	Namespace              string        `yaml:"namespace"`
 	Docker                 DockerConfig  `yaml:"docker"`
 	K8s                    K8sConfig     `yaml:"k8s"`
+	// ReclaimDeleteEnabled lets reclaim delete boxes stopped for longer than
+	// ReclaimDeleteThreshold; when false idle boxes are only stopped
+	ReclaimDeleteEnabled bool `yaml:"reclaimDeleteEnabled"`
+	// ReclaimSnapshotEnabled commits boxes reclaim deletes to an image first,
+	// so they can be resurrected later. Docker only.
+	ReclaimSnapshotEnabled bool `yaml:"reclaimSnapshotEnabled"`
+	// ReclaimWarnThreshold warns about running boxes idle for longer than
+	// this before they are stopped; 0 stops them without warning. A warned
+	// box is stopped once past ReclaimStopThreshold and at least
+	// ReclaimGracePeriod after the warning, unless it is accessed meanwhile.
+	ReclaimWarnThreshold time.Duration `yaml:"reclaimWarnThreshold"`
+	ReclaimGracePeriod   time.Duration `yaml:"reclaimGracePeriod"`
+	// ReclaimWebhook receives a JSON POST for every reclaim warning; warnings
+	// are always logged
+	ReclaimWebhook string `yaml:"reclaimWebhook"`
+	// DefaultEnv is merged into the environment of every box; variables set
+	// in a create request take precedence. It is configured as a list of
+	// KEY=VALUE entries since viper would lowercase the keys of a map.
+	DefaultEnv map[string]string `mapstructure:"-"`
+	// BoxIDFormat is the format of generated box IDs: "uuid" or "short".
+	// Short IDs are BoxIDPrefix followed by 12 random characters.
+	BoxIDFormat string `yaml:"boxIdFormat"`
+	BoxIDPrefix string `yaml:"boxIdPrefix"`
+	// AllowUnsafeSysctls lets create requests set sysctls that are not
+	// namespaced. Such sysctls change the kernel of the host and every box.
+	AllowUnsafeSysctls bool `mapstructure:"allow_unsafe_sysctls"`
+	// RedactEnv lists glob patterns, e.g. *_TOKEN, of environment variable
+	// names whose values are hidden when boxes are listed or inspected.
+	// Names are matched case-insensitively; the box still gets the values.
+	RedactEnv []string `mapstructure:"redact_env"`
 }
 
 // DockerConfig represents Docker-specific configuration
 type DockerConfig struct {
 	Host string
+	// AllowRawDockerOpts lets box creation requests set an allowlisted subset
+	// of Docker host options by name.
+	AllowRawDockerOpts bool `mapstructure:"allow_raw_docker_opts"`
+	// AllowDockerSocketMount lets box creation requests bind-mount the host
+	// Docker socket. A box holding the socket controls the Docker daemon and
+	// therefore has root access to the host.
+	AllowDockerSocketMount bool `mapstructure:"allow_docker_socket_mount"`
 }
 
 // K8sConfig represents Kubernetes-specific configuration
@@ -70,6 +258,9 @@ type K8sConfig struct {
 type BrowserConfig struct {
 	Host         string `yaml:"host"`
 	InternalPort int    `yaml:"internal_port"`
+	// BrowserType is the engine browser contexts use unless a request names
+	// one: chromium, firefox or webkit
+	BrowserType string `yaml:"browser_type"`
 }
 
 func init() {
@@ -80,17 +271,45 @@ func init() {
 	v.BindEnv("cluster.mode", "CLUSTER_MODE")
 	v.BindEnv("cluster.reclaimStopThreshold", "RECLAIM_STOP_THRESHOLD")
 	v.BindEnv("cluster.reclaimDeleteThreshold", "RECLAIM_DELETE_THRESHOLD")
+	v.BindEnv("cluster.reclaimDeleteEnabled", "RECLAIM_DELETE_ENABLED")
+	v.BindEnv("cluster.reclaimSnapshotEnabled", "RECLAIM_SNAPSHOT_ENABLED")
+	v.BindEnv("cluster.reclaimWarnThreshold", "RECLAIM_WARN_THRESHOLD")
+	v.BindEnv("cluster.reclaimGracePeriod", "RECLAIM_GRACE_PERIOD")
+	v.BindEnv("cluster.reclaimWebhook", "RECLAIM_WEBHOOK")
 	v.BindEnv("server.port", "PORT")
+	v.BindEnv("server.bind_address", "GBOX_BIND_ADDRESS")
+	v.BindEnv("server.max_url_length", "GBOX_MAX_URL_LENGTH")
+	v.BindEnv("server.max_json_body_bytes", "GBOX_MAX_JSON_BODY_BYTES")
+	v.BindEnv("server.max_header_bytes", "GBOX_MAX_HEADER_BYTES")
+	v.BindEnv("server.read_header_timeout", "GBOX_READ_HEADER_TIMEOUT")
+	v.BindEnv("server.read_timeout", "GBOX_READ_TIMEOUT")
+	v.BindEnv("server.write_timeout", "GBOX_WRITE_TIMEOUT")
+	v.BindEnv("server.idle_timeout", "GBOX_IDLE_TIMEOUT")
+	v.BindEnv("server.http2", "GBOX_HTTP2")
+	v.BindEnv("server.read_only", "GBOX_READ_ONLY")
+	v.BindEnv("server.auth.keys_file", "GBOX_API_KEYS_FILE")
 	v.BindEnv("cua.host", "CUA_SERVER_HOST")
 	v.BindEnv("cua.port", "CUA_SERVER_PORT")
 	v.BindEnv("cluster.docker.host", "DOCKER_HOST")
+	v.BindEnv("cluster.docker.allow_raw_docker_opts", "GBOX_ALLOW_RAW_DOCKER_OPTS")
+	v.BindEnv("cluster.docker.allow_docker_socket_mount", "GBOX_ALLOW_DOCKER_SOCKET_MOUNT")
 	v.BindEnv("cluster.k8s.cfg", "KUBECONFIG")
 	v.BindEnv("file.home", "GBOX_HOME")
 	v.BindEnv("file.share", "GBOX_SHARE")
 	v.BindEnv("file.host_share", "GBOX_HOST_SHARE")
+	v.BindEnv("file.box_scoped", "GBOX_SHARE_BOX_SCOPED")
+	v.BindEnv("file.screenshot.dir", "GBOX_SCREENSHOT_DIR")
+	v.BindEnv("file.screenshot.max_count", "GBOX_SCREENSHOT_MAX_COUNT")
+	v.BindEnv("file.screenshot.max_age", "GBOX_SCREENSHOT_MAX_AGE")
 	v.BindEnv("cluster.namespace", "GBOX_NAMESPACE")
+	v.BindEnv("cluster.default_env", "GBOX_DEFAULT_ENV")
+	v.BindEnv("cluster.boxIdFormat", "GBOX_BOX_ID_FORMAT")
+	v.BindEnv("cluster.boxIdPrefix", "GBOX_BOX_ID_PREFIX")
+	v.BindEnv("cluster.allow_unsafe_sysctls", "GBOX_ALLOW_UNSAFE_SYSCTLS")
+	v.BindEnv("cluster.redact_env", "GBOX_REDACT_ENV")
 	v.BindEnv("browser.host", "GBOX_BROWSER_HOST")
 	v.BindEnv("browser.internalport", "GBOX_BROWSER_INTERNAL_PORT")
+	v.BindEnv("browser.browsertype", "GBOX_BROWSER_TYPE")
 
 	// Image environment variables (bound to dynamically generated keys)
 	v.BindEnv("gbox.python.img.tag", "PY_IMG_TAG")
@@ -220,6 +439,54 @@ func initClusterConfig(homeDir string) (ClusterConfig, error) {
 	}, nil
 }
 
+// parseDefaultEnv converts KEY=VALUE entries into a map
+func parseDefaultEnv(entries []string) (map[string]string, error) {
+	env := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid default env entry '%s': expected KEY=VALUE", entry)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
+// loadAPIKeys returns the inline keys followed by the keys of file, if set,
+// rejecting keys without an owner, keys bound to an invalid box ID and keys
+// configured twice
+func loadAPIKeys(inline []APIKey, file string) ([]APIKey, error) {
+	keys := append([]APIKey(nil), inline...)
+	if file != "" {
+		data, err := os.ReadFile(os.ExpandEnv(file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API keys file: %v", err)
+		}
+		var parsed struct {
+			Keys []APIKey `yaml:"keys"`
+		}
+		if err := yaml.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse API keys file '%s': %v", file, err)
+		}
+		keys = append(keys, parsed.Keys...)
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		if key.Key == "" || key.Owner == "" {
+			return nil, fmt.Errorf("API key %d must have both a key and an owner", i+1)
+		}
+		if strings.ContainsAny(key.Box, `/\`) || key.Box == "." || key.Box == ".." {
+			return nil, fmt.Errorf("API key of owner '%s' is bound to an invalid box ID '%s'", key.Owner, key.Box)
+		}
+		if seen[key.Key] {
+			return nil, fmt.Errorf("API key of owner '%s' is configured more than once", key.Owner)
+		}
+		seen[key.Key] = true
+	}
+	return keys, nil
+}
+
 // findDockerSocket finds the Docker socket path
 func findDockerSocket(homeDir string) string {
 	// Try user's home directory socket first
@@ -259,7 +526,15 @@ func New() (*Config, error) {
 	// Initialize default values
 	cfg := &Config{
 		Server: ServerConfig{
-			Port: 28080,
+			Port:              28080,
+			MaxURLLength:      8192,
+			MaxJSONBodyBytes:  10 << 20,
+			MaxHeaderBytes:    http.DefaultMaxHeaderBytes,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       5 * time.Minute,
+			WriteTimeout:      5 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			HTTP2:             true,
 		},
 		Cua: CuaServerConfig{
 			Host: "localhost",
@@ -269,12 +544,21 @@ func New() (*Config, error) {
 			Home:      filepath.Join(os.Getenv("HOME"), ".gbox"),
 			Share:     filepath.Join(os.Getenv("HOME"), ".gbox", "share"), // Default based on container's HOME
 			HostShare: filepath.Join(os.Getenv("HOME"), ".gbox", "share"), // Default based on container's HOME
+			Screenshot: ScreenshotConfig{
+				Dir:      "screenshot",
+				MaxCount: 200,
+				MaxAge:   7 * 24 * time.Hour,
+			},
 		},
 		Cluster: ClusterConfig{
 			Mode:                   "docker",
 			ReclaimStopThreshold:   30 * time.Minute,
 			ReclaimDeleteThreshold: 24 * time.Hour,
+			ReclaimDeleteEnabled:   true,
+			ReclaimGracePeriod:     5 * time.Minute,
 			Namespace:              "gbox-boxes",
+			BoxIDFormat:            string(id.FormatUUID),
+			RedactEnv:              []string{"*_TOKEN", "*_SECRET", "*_PASSWORD", "PASSWORD", "*_API_KEY"},
 			Docker: DockerConfig{
 				Host: findDockerSocket(os.Getenv("HOME")),
 			},
@@ -285,6 +569,7 @@ func New() (*Config, error) {
 		Browser: BrowserConfig{
 			Host:         "localhost",
 			InternalPort: 3000,
+			BrowserType:  "chromium",
 		},
 	}
 
@@ -298,6 +583,12 @@ func New() (*Config, error) {
 	cfg.File.Share = os.ExpandEnv(cfg.File.Share)
 	cfg.File.HostShare = os.ExpandEnv(cfg.File.HostShare)
 
+	screenshotDir := filepath.Clean(cfg.File.Screenshot.Dir)
+	if filepath.IsAbs(screenshotDir) || screenshotDir == "." || screenshotDir == ".." || strings.HasPrefix(screenshotDir, "../") {
+		return nil, fmt.Errorf("screenshot directory '%s' must be a subdirectory of the box share directory", cfg.File.Screenshot.Dir)
+	}
+	cfg.File.Screenshot.Dir = screenshotDir
+
 	// Create directories if they don't exist
 	if err := os.MkdirAll(cfg.File.Home, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create home directory '%s': %v", cfg.File.Home, err)
@@ -305,6 +596,35 @@ func New() (*Config, error) {
 	if err := os.MkdirAll(cfg.File.Share, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create share directory '%s': %v", cfg.File.Share, err)
 	}
+	if err := resolveShareTiers(cfg.File.ShareTiers); err != nil {
+		return nil, err
+	}
+
+	defaultEnv, err := parseDefaultEnv(v.GetStringSlice("cluster.default_env"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Cluster.DefaultEnv = defaultEnv
+
+	for _, pattern := range cfg.Cluster.RedactEnv {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid redact_env pattern '%s': %v", pattern, err)
+		}
+	}
+
+	apiKeys, err := loadAPIKeys(cfg.Server.Auth.Keys, cfg.Server.Auth.KeysFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Server.Auth.Keys = apiKeys
+
+	if _, err := id.NewGenerator(id.Format(cfg.Cluster.BoxIDFormat), cfg.Cluster.BoxIDPrefix); err != nil {
+		return nil, err
+	}
+
+	if err := resolveClusterMode(cfg, defaultClusterProbes(cfg.Cluster)); err != nil {
+		return nil, err
+	}
 
 	// Note: findDockerSocket/findKubeConfig are called during default initialization.
 	// If Viper unmarshals non-empty values for Docker.Host or K8s.Config, those will be used.
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDefaultEnv(t *testing.T) {
+	env, err := parseDefaultEnv([]string{"HTTP_PROXY=http://proxy:3128", "EMPTY=", "OPTS=a=b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"HTTP_PROXY": "http://proxy:3128", "EMPTY": "", "OPTS": "a=b"}, env)
+
+	for _, entry := range []string{"NO_VALUE", "=value"} {
+		_, err := parseDefaultEnv([]string{entry})
+		assert.Error(t, err, "entry %q", entry)
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("keys:\n  - key: k2\n    owner: bob\n    box: box-1\n"), 0600))
+
+	keys, err := loadAPIKeys([]APIKey{{Key: "k1", Owner: "alice"}}, file)
+	require.NoError(t, err)
+	assert.Equal(t, []APIKey{{Key: "k1", Owner: "alice"}, {Key: "k2", Owner: "bob", Box: "box-1"}}, keys)
+
+	_, err = loadAPIKeys([]APIKey{{Key: "k2", Owner: "carol"}}, file)
+	assert.Error(t, err, "duplicate key")
+	_, err = loadAPIKeys([]APIKey{{Key: "k1"}}, "")
+	assert.Error(t, err, "key without owner")
+	_, err = loadAPIKeys([]APIKey{{Key: "k1", Owner: "alice", Box: "../box-2"}}, "")
+	assert.Error(t, err, "key bound to an invalid box")
+	_, err = loadAPIKeys(nil, filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err, "missing file")
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// ClusterModeAuto selects docker or k8s based on the environment at startup
+	ClusterModeAuto   = "auto"
+	ClusterModeDocker = "docker"
+	ClusterModeK8s    = "k8s"
+
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// ClusterProbes reports which cluster backends are usable in the current environment
+type ClusterProbes struct {
+	Docker    func() bool // a Docker daemon socket or host is available
+	InCluster func() bool // running inside a Kubernetes pod with a service account
+	KubeCfg   func() bool // a kubeconfig file is available
+}
+
+// DetectClusterMode picks a cluster mode using the given probes. Docker wins
+// when available; otherwise an in-cluster service account or a kubeconfig
+// selects k8s.
+func DetectClusterMode(probes ClusterProbes) (string, error) {
+	switch {
+	case probes.Docker != nil && probes.Docker():
+		return ClusterModeDocker, nil
+	case probes.InCluster != nil && probes.InCluster():
+		return ClusterModeK8s, nil
+	case probes.KubeCfg != nil && probes.KubeCfg():
+		return ClusterModeK8s, nil
+	}
+	return "", fmt.Errorf("cluster mode %q could not find a Docker socket, in-cluster service account or kubeconfig; set cluster.mode to docker or k8s explicitly", ClusterModeAuto)
+}
+
+// defaultClusterProbes returns probes that inspect the local filesystem
+func defaultClusterProbes(cluster ClusterConfig) ClusterProbes {
+	return ClusterProbes{
+		Docker: func() bool {
+			host := cluster.Docker.Host
+			if !strings.HasPrefix(host, "unix://") {
+				// Remote daemons (tcp://, ssh://) cannot be probed cheaply; trust the configuration
+				return host != ""
+			}
+			_, err := os.Stat(strings.TrimPrefix(host, "unix://"))
+			return err == nil
+		},
+		InCluster: func() bool {
+			_, err := os.Stat(serviceAccountTokenPath)
+			return err == nil && os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+		},
+		KubeCfg: func() bool {
+			if cluster.K8s.Config == "" {
+				return false
+			}
+			_, err := os.Stat(cluster.K8s.Config)
+			return err == nil
+		},
+	}
+}
+
+// resolveClusterMode replaces the auto mode with the detected one
+func resolveClusterMode(cfg *Config, probes ClusterProbes) error {
+	if cfg.Cluster.Mode != ClusterModeAuto {
+		return nil
+	}
+	mode, err := DetectClusterMode(probes)
+	if err != nil {
+		return err
+	}
+	if mode == ClusterModeK8s && probes.InCluster != nil && probes.InCluster() && (probes.KubeCfg == nil || !probes.KubeCfg()) {
+		// An empty kubeconfig path makes client-go fall back to the in-cluster config
+		cfg.Cluster.K8s.Config = ""
+	}
+	log.Info("Cluster mode auto-detected: %s", mode)
+	cfg.Cluster.Mode = mode
+	return nil
+}
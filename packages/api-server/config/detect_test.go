@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probe(v bool) func() bool {
+	return func() bool { return v }
+}
+
+func TestDetectClusterMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		probes ClusterProbes
+		want   string
+	}{
+		{"docker socket", ClusterProbes{Docker: probe(true), InCluster: probe(true), KubeCfg: probe(true)}, ClusterModeDocker},
+		{"in-cluster service account", ClusterProbes{Docker: probe(false), InCluster: probe(true), KubeCfg: probe(false)}, ClusterModeK8s},
+		{"kubeconfig", ClusterProbes{Docker: probe(false), InCluster: probe(false), KubeCfg: probe(true)}, ClusterModeK8s},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, err := DetectClusterMode(tt.probes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, mode)
+		})
+	}
+}
+
+func TestDetectClusterModeNothingAvailable(t *testing.T) {
+	_, err := DetectClusterMode(ClusterProbes{Docker: probe(false), InCluster: probe(false), KubeCfg: probe(false)})
+	assert.Error(t, err)
+}
+
+func TestResolveClusterModeInCluster(t *testing.T) {
+	cfg := &Config{Cluster: ClusterConfig{Mode: ClusterModeAuto, K8s: K8sConfig{Config: "/missing/kubeconfig"}}}
+	err := resolveClusterMode(cfg, ClusterProbes{Docker: probe(false), InCluster: probe(true), KubeCfg: probe(false)})
+	require.NoError(t, err)
+	assert.Equal(t, ClusterModeK8s, cfg.Cluster.Mode)
+	assert.Empty(t, cfg.Cluster.K8s.Config)
+
+	// Explicit modes are left untouched
+	cfg = &Config{Cluster: ClusterConfig{Mode: ClusterModeDocker}}
+	require.NoError(t, resolveClusterMode(cfg, ClusterProbes{}))
+	assert.Equal(t, ClusterModeDocker, cfg.Cluster.Mode)
+}
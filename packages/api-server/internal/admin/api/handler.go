@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	apierrors "github.com/babelcloud/gbox/packages/api-server/internal/common/errors"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/admin"
+	"github.com/emicklei/go-restful/v3"
+)
+
+// ReclaimScheduler controls the scheduled reclamation of idle boxes
+type ReclaimScheduler interface {
+	PauseReclaim()
+	ResumeReclaim()
+	ReclaimStatus() model.ReclaimStatus
+	ReclaimBoxesStatus(ctx context.Context) (*model.ReclaimBoxesStatus, error)
+}
+
+// ReadOnlySwitch controls the server's read-only maintenance mode
+type ReadOnlySwitch interface {
+	EnableReadOnly()
+	DisableReadOnly()
+	ReadOnlyStatus() model.ReadOnlyStatus
+}
+
+// AdminHandler handles operator requests
+type AdminHandler struct {
+	reclaim  ReclaimScheduler
+	readOnly ReadOnlySwitch
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(reclaim ReclaimScheduler, readOnly ReadOnlySwitch) *AdminHandler {
+	return &AdminHandler{
+		reclaim:  reclaim,
+		readOnly: readOnly,
+	}
+}
+
+// GetReclaimStatus handles GET /admin/reclaim request
+func (h *AdminHandler) GetReclaimStatus(req *restful.Request, resp *restful.Response) {
+	resp.WriteHeaderAndJson(http.StatusOK, h.reclaim.ReclaimStatus(), restful.MIME_JSON)
+}
+
+// GetReclaimBoxesStatus handles GET /admin/reclaim/status request
+func (h *AdminHandler) GetReclaimBoxesStatus(req *restful.Request, resp *restful.Response) {
+	status, err := h.reclaim.ReclaimBoxesStatus(req.Request.Context())
+	if err != nil {
+		resp.WriteHeaderAndJson(http.StatusInternalServerError,
+			apierrors.Newf(http.StatusInternalServerError, "Failed to get box reclamation status: %v", err), restful.MIME_JSON)
+		return
+	}
+	resp.WriteHeaderAndJson(http.StatusOK, status, restful.MIME_JSON)
+}
+
+// PauseReclaim handles POST /admin/reclaim/pause request
+func (h *AdminHandler) PauseReclaim(req *restful.Request, resp *restful.Response) {
+	h.reclaim.PauseReclaim()
+	resp.WriteHeaderAndJson(http.StatusOK, h.reclaim.ReclaimStatus(), restful.MIME_JSON)
+}
+
+// ResumeReclaim handles POST /admin/reclaim/resume request
+func (h *AdminHandler) ResumeReclaim(req *restful.Request, resp *restful.Response) {
+	h.reclaim.ResumeReclaim()
+	resp.WriteHeaderAndJson(http.StatusOK, h.reclaim.ReclaimStatus(), restful.MIME_JSON)
+}
+
+// GetReadOnlyStatus handles GET /admin/read-only request
+func (h *AdminHandler) GetReadOnlyStatus(req *restful.Request, resp *restful.Response) {
+	resp.WriteHeaderAndJson(http.StatusOK, h.readOnly.ReadOnlyStatus(), restful.MIME_JSON)
+}
+
+// EnableReadOnly handles POST /admin/read-only/enable request
+func (h *AdminHandler) EnableReadOnly(req *restful.Request, resp *restful.Response) {
+	h.readOnly.EnableReadOnly()
+	resp.WriteHeaderAndJson(http.StatusOK, h.readOnly.ReadOnlyStatus(), restful.MIME_JSON)
+}
+
+// DisableReadOnly handles POST /admin/read-only/disable request
+func (h *AdminHandler) DisableReadOnly(req *restful.Request, resp *restful.Response) {
+	h.readOnly.DisableReadOnly()
+	resp.WriteHeaderAndJson(http.StatusOK, h.readOnly.ReadOnlyStatus(), restful.MIME_JSON)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/admin"
+	"github.com/emicklei/go-restful/v3"
+)
+
+// RegisterRoutes registers the admin routes
+func RegisterRoutes(ws *restful.WebService, handler *AdminHandler) {
+	ws.Route(ws.GET("/admin/reclaim").To(handler.GetReclaimStatus).
+		Doc("get whether scheduled box reclamation is paused and when it next runs").
+		Returns(200, "OK", model.ReclaimStatus{}))
+
+	ws.Route(ws.GET("/admin/reclaim/status").To(handler.GetReclaimBoxesStatus).
+		Doc("list each box's idle time and the action the next reclamation run would take on it").
+		Returns(200, "OK", model.ReclaimBoxesStatus{}).
+		Returns(500, "Internal Server Error", nil))
+
+	ws.Route(ws.POST("/admin/reclaim/pause").To(handler.PauseReclaim).
+		Doc("pause scheduled box reclamation, e.g. during maintenance").
+		Returns(200, "OK", model.ReclaimStatus{}))
+
+	ws.Route(ws.POST("/admin/reclaim/resume").To(handler.ResumeReclaim).
+		Doc("resume scheduled box reclamation").
+		Returns(200, "OK", model.ReclaimStatus{}))
+
+	ws.Route(ws.GET("/admin/read-only").To(handler.GetReadOnlyStatus).
+		Doc("get whether the server is in read-only maintenance mode").
+		Returns(200, "OK", model.ReadOnlyStatus{}))
+
+	ws.Route(ws.POST("/admin/read-only/enable").To(handler.EnableReadOnly).
+		Doc("reject mutating requests with 503 while reads keep working, e.g. during upgrades").
+		Returns(200, "OK", model.ReadOnlyStatus{}))
+
+	ws.Route(ws.POST("/admin/read-only/disable").To(handler.DisableReadOnly).
+		Doc("accept mutating requests again").
+		Returns(200, "OK", model.ReadOnlyStatus{}))
+}
@@ -1,14 +1,23 @@
 package api
 
 import (
+	"archive/tar"
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
 	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
 	"github.com/babelcloud/gbox/packages/api-server/pkg/logger"
@@ -23,6 +32,8 @@ var log = logger.New()
 const (
 	mediaTypeRawStream         = "application/vnd.gbox.raw-stream"
 	mediaTypeMultiplexedStream = "application/vnd.gbox.multiplexed-stream"
+	mediaTypeJSONStream        = "application/json-stream"
+	mediaTypeEventStream       = "text/event-stream"
 )
 
 // Configure the WebSocket upgrader
@@ -47,8 +58,36 @@ func NewBoxHandler(service service.BoxService) *BoxHandler {
 	}
 }
 
+// acceptsStream reports whether the client asked for a streaming response
+func acceptsStream(req *restful.Request) bool {
+	accept := req.HeaderParameter("Accept")
+	return strings.Contains(accept, mediaTypeJSONStream) || strings.Contains(accept, mediaTypeEventStream)
+}
+
+// writeEventStream re-frames newline-delimited JSON objects from src as
+// Server-Sent Events, flushing after each event when dst supports it.
+func writeEventStream(dst io.Writer, src io.Reader) error {
+	flusher, _ := dst.(http.Flusher)
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(dst, "data: %s\n\n", line); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return scanner.Err()
+}
+
 // streamServiceOperation is a helper function to handle streaming responses for service operations.
 // serviceFunc is expected to write intermediate progress to the progressWriter and return the final data object on success.
+// The stream is emitted as json-stream by default, or as SSE when the client sends `Accept: text/event-stream`.
 func (h *BoxHandler) streamServiceOperation(
 	req *restful.Request,
 	resp *restful.Response,
@@ -56,7 +95,12 @@ func (h *BoxHandler) streamServiceOperation(
 	serviceFunc func(ctx context.Context, params interface{}, progressWriter io.Writer) (finalData interface{}, err error),
 	isCreateBox bool, // Flag to determine the final success message structure
 ) {
-	resp.Header().Set("Content-Type", "application/json-stream")
+	sse := strings.Contains(req.HeaderParameter("Accept"), mediaTypeEventStream)
+	if sse {
+		resp.Header().Set("Content-Type", mediaTypeEventStream)
+	} else {
+		resp.Header().Set("Content-Type", mediaTypeJSONStream)
+	}
 	resp.Header().Set("X-Content-Type-Options", "nosniff")
 	resp.Header().Set("Cache-Control", "no-cache")
 	resp.Header().Set("Connection", "keep-alive")
@@ -86,6 +130,11 @@ func (h *BoxHandler) streamServiceOperation(
 			return
 		}
 
+		// A nil result means serviceFunc already wrote its final payload
+		if finalData == nil && !isCreateBox {
+			return
+		}
+
 		// Encode the final success message.
 		var successPayload interface{}
 		if isCreateBox {
@@ -104,7 +153,13 @@ func (h *BoxHandler) streamServiceOperation(
 	}()
 
 	// Copy from pipe to response
-	if _, err := io.Copy(resp.ResponseWriter, pr); err != nil {
+	if sse {
+		if err := writeEventStream(resp.ResponseWriter, pr); err != nil {
+			log.Errorf("Error writing event stream to HTTP response: %v", err)
+		}
+		return
+	}
+	if err := copyFlushed(resp, pr); err != nil {
 		log.Errorf("Error copying stream to HTTP response: %v", err)
 	}
 }
@@ -129,6 +184,23 @@ func (h *BoxHandler) ListBoxes(req *restful.Request, resp *restful.Response) {
 		})
 	}
 
+	// Only the boxes created by the caller
+	if req.QueryParameter("mine") == "true" {
+		owner := requestOwner(req.Request)
+		if owner == "" {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", "mine requires an API key or the "+userHeader+" header to identify the caller")
+			return
+		}
+		params.Filters = append(params.Filters, model.Filter{
+			Field:    "owner",
+			Operator: model.FilterOperatorEquals,
+			Value:    owner,
+		})
+	}
+
+	// Disk usage is expensive to compute, so it is only included on request
+	params.Size = req.QueryParameter("size") == "true"
+
 	result, err := h.service.List(req.Request.Context(), params)
 	if err != nil {
 		writeError(resp, http.StatusInternalServerError, "ListBoxesError", err.Error())
@@ -138,6 +210,30 @@ func (h *BoxHandler) ListBoxes(req *restful.Request, resp *restful.Response) {
 	resp.WriteEntity(result)
 }
 
+// ListImages lists the images boxes can be created from
+func (h *BoxHandler) ListImages(req *restful.Request, resp *restful.Response) {
+	params := &model.BoxImageListParams{All: req.QueryParameter("all") == "true"}
+
+	result, err := h.service.ListImages(req.Request.Context(), params)
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, "ListImagesError", err.Error())
+		return
+	}
+
+	resp.WriteEntity(result)
+}
+
+// PruneImages removes dangling images
+func (h *BoxHandler) PruneImages(req *restful.Request, resp *restful.Response) {
+	result, err := h.service.PruneImages(req.Request.Context())
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, "PruneImagesError", err.Error())
+		return
+	}
+
+	resp.WriteEntity(result)
+}
+
 // GetBox returns a box by ID
 func (h *BoxHandler) GetBox(req *restful.Request, resp *restful.Response) {
 	boxID := req.PathParameter("id")
@@ -154,6 +250,24 @@ func (h *BoxHandler) GetBox(req *restful.Request, resp *restful.Response) {
 	resp.WriteEntity(box)
 }
 
+// userHeader names the local user of an unauthenticated client
+const userHeader = "X-Gbox-User"
+
+// requestOwner identifies the caller of a request. A key authenticated by the
+// server is identified by its configured owner. Other API keys are identified
+// by a digest so the key itself never ends up in box labels; without one the
+// user the client reports is used, as for local unauthenticated use.
+func requestOwner(req *http.Request) string {
+	if owner := common.APIKeyOwner(req.Context()); owner != "" {
+		return owner
+	}
+	if key, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok && key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "apikey-" + hex.EncodeToString(sum[:])[:12]
+	}
+	return strings.TrimSpace(req.Header.Get(userHeader))
+}
+
 func (h *BoxHandler) CreateLinuxBox(req *restful.Request, resp *restful.Response) {
 	// Read request body directly into the internal model type
 	var createParams model.LinuxAndroidBoxCreateParam
@@ -161,8 +275,39 @@ func (h *BoxHandler) CreateLinuxBox(req *restful.Request, resp *restful.Response
 		writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
 		return
 	}
+	createParams.Owner = requestOwner(req.Request)
+
+	// A dry run returns the spec the box would be created with
+	if req.QueryParameter("dryRun") == "true" {
+		plan, err := h.service.PlanLinuxBox(req.Request.Context(), &createParams)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidParams) {
+				writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+				return
+			}
+			if errors.Is(err, service.ErrForbidden) {
+				writeError(resp, http.StatusForbidden, "Forbidden", err.Error())
+				return
+			}
+			writeError(resp, http.StatusInternalServerError, "PlanLinuxBoxError", err.Error())
+			return
+		}
+		resp.WriteHeaderAndEntity(http.StatusOK, plan)
+		return
+	}
+
+	// Stream progress when the client negotiated json-stream or SSE
+	if acceptsStream(req) {
+		h.streamServiceOperation(req, resp, &createParams, func(ctx context.Context, params interface{}, progressWriter io.Writer) (interface{}, error) {
+			json.NewEncoder(progressWriter).Encode(model.ProgressUpdate{
+				Status:  model.ProgressStatusPrepare,
+				Message: "creating box",
+			})
+			return h.service.CreateLinuxBox(ctx, params.(*model.LinuxAndroidBoxCreateParam))
+		}, true)
+		return
+	}
 
-	// CreateLinuxBox no longer supports streaming or progressWriter
 	// Call the service directly
 	box, err := h.service.CreateLinuxBox(req.Request.Context(), &createParams)
 	if err != nil {
@@ -171,6 +316,14 @@ func (h *BoxHandler) CreateLinuxBox(req *restful.Request, resp *restful.Response
 			writeError(resp, http.StatusServiceUnavailable, "ImageResourcesPreparing", err.Error())
 			return
 		}
+		if errors.Is(err, service.ErrInvalidParams) {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(resp, http.StatusForbidden, "Forbidden", err.Error())
+			return
+		}
 		writeError(resp, http.StatusInternalServerError, "CreateLinuxBoxError", err.Error())
 		return
 	}
@@ -178,6 +331,35 @@ func (h *BoxHandler) CreateLinuxBox(req *restful.Request, resp *restful.Response
 	resp.WriteHeaderAndEntity(http.StatusCreated, box)
 }
 
+// ComposeBoxes creates a group of boxes from a multi-service spec
+func (h *BoxHandler) ComposeBoxes(req *restful.Request, resp *restful.Response) {
+	var composeParams model.BoxComposeParams
+	if err := req.ReadEntity(&composeParams); err != nil {
+		writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+		return
+	}
+
+	result, err := h.service.Compose(req.Request.Context(), &composeParams)
+	if err != nil {
+		if strings.Contains(err.Error(), "image resources are being prepared") {
+			writeError(resp, http.StatusServiceUnavailable, "ImageResourcesPreparing", err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrInvalidParams) {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			writeError(resp, http.StatusForbidden, "Forbidden", err.Error())
+			return
+		}
+		writeError(resp, http.StatusInternalServerError, "ComposeBoxesError", err.Error())
+		return
+	}
+
+	resp.WriteHeaderAndEntity(http.StatusCreated, result)
+}
+
 func (h *BoxHandler) CreateAndroidBox(req *restful.Request, resp *restful.Response) {
 	writeError(resp, http.StatusNotImplemented, "NotImplemented", "This feature is exclusively available in the cloud version. Learn more at https://gbox.cloud/.")
 }
@@ -195,10 +377,18 @@ func (h *BoxHandler) DeleteBox(req *restful.Request, resp *restful.Response) {
 	if err := req.ReadEntity(&deleteParams); err != nil {
 		deleteParams.Force = true // Default
 	}
+	if req.QueryParameter("ignoreNotFound") == "true" {
+		deleteParams.IgnoreNotFound = true
+	}
 
 	result, err := h.service.Delete(req.Request.Context(), boxID, &deleteParams)
 	if err != nil {
-		if err == service.ErrBoxNotFound {
+		if errors.Is(err, service.ErrBoxNotFound) {
+			// A retried delete of a box that is already gone succeeds
+			if deleteParams.IgnoreNotFound {
+				resp.WriteHeaderAndEntity(http.StatusOK, model.BoxDeleteResult{Message: "Box not found, nothing to delete"})
+				return
+			}
 			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
 			return
 		}
@@ -227,6 +417,44 @@ func (h *BoxHandler) DeleteBoxes(req *restful.Request, resp *restful.Response) {
 	resp.WriteHeaderAndEntity(http.StatusOK, result)
 }
 
+// DeleteGroup deletes every box of a group
+func (h *BoxHandler) DeleteGroup(req *restful.Request, resp *restful.Response) {
+	group := req.PathParameter("group")
+
+	var deleteParams model.BoxesDeleteParams
+	if req.Request.ContentLength != 0 {
+		if err := req.ReadEntity(&deleteParams); err != nil {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+	}
+
+	result, err := h.service.DeleteGroup(req.Request.Context(), group, &deleteParams)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidParams) {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		writeError(resp, http.StatusInternalServerError, "DeleteGroupError", err.Error())
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// StopGroup stops every running box of a group
+func (h *BoxHandler) StopGroup(req *restful.Request, resp *restful.Response) {
+	result, err := h.service.StopGroup(req.Request.Context(), req.PathParameter("group"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidParams) {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		writeError(resp, http.StatusInternalServerError, "StopGroupError", err.Error())
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
 // ReclaimBoxes reclaims inactive boxes
 func (h *BoxHandler) ReclaimBoxes(req *restful.Request, resp *restful.Response) {
 	result, err := h.service.Reclaim(req.Request.Context())
@@ -353,6 +581,31 @@ func (h *BoxHandler) ExecBox(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
+	// Detached commands keep running server-side; the client polls the session
+	if execReq.Detach {
+		if execReq.StdoutFile != "" || execReq.StderrFile != "" || execReq.Input != "" {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", "stdoutFile, stderrFile and input cannot be combined with detach")
+			return
+		}
+		session, err := h.service.ExecDetached(req.Request.Context(), boxID, &execReq)
+		if err != nil {
+			if err == service.ErrBoxNotFound {
+				writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
+				return
+			}
+			writeError(resp, http.StatusInternalServerError, "ExecBoxError", err.Error())
+			return
+		}
+		resp.WriteHeaderAndEntity(http.StatusAccepted, session)
+		return
+	}
+
+	// A raw stream carries the command's stdout unmodified over the hijacked connection
+	if strings.Contains(req.HeaderParameter("Accept"), mediaTypeRawStream) {
+		h.execRawStream(req, resp, boxID, &execReq)
+		return
+	}
+
 	// Execute command using simplified service method
 	result, err := h.service.Exec(req.Request.Context(), boxID, &execReq)
 	if err != nil {
@@ -360,6 +613,10 @@ func (h *BoxHandler) ExecBox(req *restful.Request, resp *restful.Response) {
 			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
 			return
 		}
+		if errors.Is(err, service.ErrInvalidParams) {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
 		writeError(resp, http.StatusInternalServerError, "ExecBoxError", err.Error())
 		return
 	}
@@ -368,6 +625,104 @@ func (h *BoxHandler) ExecBox(req *restful.Request, resp *restful.Response) {
 	resp.WriteHeaderAndEntity(http.StatusOK, result)
 }
 
+// execRawStream runs a command over a hijacked connection, which carries
+// stdin from the client, if requested, and the command's stdout back to it
+func (h *BoxHandler) execRawStream(req *restful.Request, resp *restful.Response, boxID string, execReq *model.BoxExecParams) {
+	if execReq.StdoutFile != "" || execReq.StderrFile != "" || execReq.Input != "" {
+		writeError(resp, http.StatusBadRequest, "InvalidRequest", "stdoutFile, stderrFile and input cannot be combined with a raw stream")
+		return
+	}
+
+	hijacker, ok := resp.ResponseWriter.(http.Hijacker)
+	if !ok {
+		writeError(resp, http.StatusInternalServerError, "HijackError", "response does not support hijacking")
+		return
+	}
+	clientConn, buf, err := hijacker.Hijack()
+	if err != nil {
+		log.Errorf("ExecBox [%s]: Failed to hijack connection: %v", boxID, err)
+		return
+	}
+	defer clientConn.Close()
+
+	writeResponseHeaders(clientConn, req.HeaderParameter("Upgrade"), req.HeaderParameter("Connection"), true)
+
+	// Errors can no longer be reported to the client once the connection is hijacked
+	result, err := h.service.ExecStream(req.Request.Context(), boxID, execReq, buf.Reader, clientConn)
+	if err != nil {
+		log.Errorf("ExecBox [%s]: Error during raw stream exec: %v", boxID, err)
+		return
+	}
+	if result.Stderr != "" {
+		log.Debugf("ExecBox [%s]: Raw stream command wrote to stderr: %s", boxID, result.Stderr)
+	}
+	log.Infof("ExecBox [%s]: Raw stream command finished with exit code: %d", boxID, result.ExitCode)
+}
+
+// GetExecSession returns the state and new output of a detached exec session
+func (h *BoxHandler) GetExecSession(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	sessionID := req.PathParameter("sessionId")
+
+	var offset int64
+	if offsetStr := req.QueryParameter("offset"); offsetStr != "" {
+		var err error
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil || offset < 0 {
+			writeError(resp, http.StatusBadRequest, "InvalidOffset", "Invalid offset parameter")
+			return
+		}
+	}
+
+	session, err := h.service.GetExecSession(req.Request.Context(), boxID, sessionID, offset)
+	if err != nil {
+		if err == service.ErrExecSessionNotFound {
+			writeError(resp, http.StatusNotFound, "ExecSessionNotFound", err.Error())
+			return
+		}
+		writeError(resp, http.StatusInternalServerError, "GetExecSessionError", err.Error())
+		return
+	}
+
+	resp.WriteEntity(session)
+}
+
+// ListExecSessions lists the exec sessions still running in a box
+func (h *BoxHandler) ListExecSessions(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+
+	result, err := h.service.ListExecSessions(req.Request.Context(), boxID)
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, "ListExecSessionsError", err.Error())
+		return
+	}
+
+	resp.WriteEntity(result)
+}
+
+// KillExecSession kills the command of an exec session and returns the
+// session's final state
+func (h *BoxHandler) KillExecSession(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	sessionID := req.PathParameter("sessionId")
+
+	session, err := h.service.KillExecSession(req.Request.Context(), boxID, sessionID)
+	if err != nil {
+		if err == service.ErrExecSessionNotFound {
+			writeError(resp, http.StatusNotFound, "ExecSessionNotFound", err.Error())
+			return
+		}
+		if err == service.ErrBoxNotFound {
+			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
+			return
+		}
+		writeError(resp, http.StatusInternalServerError, "KillExecSessionError", err.Error())
+		return
+	}
+
+	resp.WriteEntity(session)
+}
+
 // ExecBoxWS handles command execution via WebSocket
 func (h *BoxHandler) ExecBoxWS(req *restful.Request, resp *restful.Response) {
 	boxID := req.PathParameter("id")
@@ -385,9 +740,15 @@ func (h *BoxHandler) ExecBoxWS(req *restful.Request, resp *restful.Response) {
 	// The first message from the client contains the command to execute.
 	var initPayload struct {
 		Command struct {
-			Commands    []string `json:"commands"`
-			Interactive bool     `json:"interactive"`
-			WorkingDir  string   `json:"workingDir"`
+			Commands     []string `json:"commands"`
+			Interactive  bool     `json:"interactive"`
+			WorkingDir   string   `json:"workingDir"`
+			Login        bool     `json:"login"`
+			Detach       bool     `json:"detach"`
+			Record       string   `json:"record"`
+			Cols         int      `json:"cols"`
+			Rows         int      `json:"rows"`
+			LineBuffered bool     `json:"lineBuffered"`
 		} `json:"command"`
 	}
 
@@ -400,8 +761,14 @@ func (h *BoxHandler) ExecBoxWS(req *restful.Request, resp *restful.Response) {
 
 	// Prepare parameters for the service call from the initial payload.
 	execParams := &model.BoxExecWSParams{
-		TTY:        initPayload.Command.Interactive, // Assume interactive means TTY for now.
-		WorkingDir: initPayload.Command.WorkingDir,
+		TTY:          initPayload.Command.Interactive, // Assume interactive means TTY for now.
+		WorkingDir:   initPayload.Command.WorkingDir,
+		Login:        initPayload.Command.Login,
+		Detach:       initPayload.Command.Detach,
+		Record:       initPayload.Command.Record,
+		Cols:         initPayload.Command.Cols,
+		Rows:         initPayload.Command.Rows,
+		LineBuffered: initPayload.Command.LineBuffered,
 	}
 	if len(initPayload.Command.Commands) > 0 {
 		execParams.Cmd = []string{initPayload.Command.Commands[0]}
@@ -424,6 +791,11 @@ func (h *BoxHandler) ExecBoxWS(req *restful.Request, resp *restful.Response) {
 		// Log error. Cannot easily send structured error over WS after exec starts/fails mid-stream.
 		// The service layer ExecWS might attempt to send a final error/exit message.
 		log.Errorf("ExecBoxWS [%s]: Error during WebSocket exec: %v", boxID, err)
+		if errors.Is(err, service.ErrInvalidParams) {
+			// Rejected before the command started, so the client can be told why
+			wsConn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInvalidFramePayloadData, err.Error()))
+			return
+		}
 		// Connection will be closed by defer wsConn.Close()
 		// Optionally send a specific WebSocket close message with error code?
 		// wsConn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()))
@@ -440,6 +812,44 @@ func (h *BoxHandler) ExecBoxWS(req *restful.Request, resp *restful.Response) {
 	// Final WebSocket closure is handled by defer
 }
 
+// AttachExecSessionWS re-attaches a WebSocket to an interactive exec session,
+// replaying its recent output before relaying live I/O
+func (h *BoxHandler) AttachExecSessionWS(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	sessionID := req.PathParameter("sessionId")
+
+	// Check the session before upgrading so errors get a regular HTTP response
+	session, err := h.service.GetExecSession(req.Request.Context(), boxID, sessionID, -1)
+	if err != nil {
+		if err == service.ErrExecSessionNotFound {
+			writeError(resp, http.StatusNotFound, "ExecSessionNotFound", err.Error())
+			return
+		}
+		writeError(resp, http.StatusInternalServerError, "GetExecSessionError", err.Error())
+		return
+	}
+	if !session.Interactive {
+		writeError(resp, http.StatusConflict, "ExecSessionNotInteractive", service.ErrExecSessionNotInteractive.Error())
+		return
+	}
+
+	wsConn, err := upgrader.Upgrade(resp.ResponseWriter, req.Request, nil)
+	if err != nil {
+		log.Errorf("AttachExecSessionWS [%s]: Failed to upgrade connection: %v", boxID, err)
+		return
+	}
+	defer wsConn.Close()
+
+	result, err := h.service.AttachExecSession(req.Request.Context(), boxID, sessionID, wsConn)
+	if err != nil {
+		log.Errorf("AttachExecSessionWS [%s]: Error during session %s: %v", boxID, sessionID, err)
+		return
+	}
+	if result != nil {
+		log.Infof("AttachExecSessionWS [%s]: Session %s finished with exit code: %d", boxID, sessionID, result.ExitCode)
+	}
+}
+
 // RunBox runs a command in a box
 func (h *BoxHandler) RunBox(req *restful.Request, resp *restful.Response) {
 	boxID := req.PathParameter("id")
@@ -450,6 +860,21 @@ func (h *BoxHandler) RunBox(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
+	// Stream output as it is produced when the client negotiated json-stream
+	// or SSE, or asked for it with stream=true
+	if stream, _ := strconv.ParseBool(req.QueryParameter("stream")); stream || acceptsStream(req) {
+		h.streamServiceOperation(req, resp, &runReq, func(ctx context.Context, params interface{}, progressWriter io.Writer) (interface{}, error) {
+			runParams := params.(*model.BoxRunCodeParams)
+			runParams.Output = progressWriter
+			result, err := h.service.RunCode(ctx, boxID, runParams)
+			if err != nil {
+				return nil, err
+			}
+			return model.BoxRunCodeEvent{Status: model.RunCodeEventExit, ExitCode: &result.ExitCode}, nil
+		}, false)
+		return
+	}
+
 	result, err := h.service.RunCode(req.Request.Context(), boxID, &runReq)
 	if err != nil {
 		if err == service.ErrBoxNotFound {
@@ -480,6 +905,89 @@ func (h *BoxHandler) StartBox(req *restful.Request, resp *restful.Response) {
 	resp.WriteHeaderAndEntity(http.StatusOK, result)
 }
 
+// ResurrectBox creates a box reclaim deleted again from its snapshot
+func (h *BoxHandler) ResurrectBox(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	result, err := h.service.Resurrect(req.Request.Context(), boxID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrBoxNotFound):
+			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
+		case errors.Is(err, service.ErrInvalidParams):
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+		default:
+			writeError(resp, http.StatusInternalServerError, "ResurrectBoxError", err.Error())
+		}
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// TouchBox refreshes the last access time of a box so reclaim leaves it alone
+func (h *BoxHandler) TouchBox(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	result, err := h.service.Touch(req.Request.Context(), boxID)
+	if err != nil {
+		if errors.Is(err, service.ErrBoxNotFound) {
+			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
+			return
+		}
+		writeError(resp, http.StatusInternalServerError, "TouchBoxError", err.Error())
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// UpdateBox changes the resource limits of a box
+func (h *BoxHandler) UpdateBox(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	var params model.BoxUpdateParams
+	if err := req.ReadEntity(&params); err != nil {
+		writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+		return
+	}
+
+	result, err := h.service.Update(req.Request.Context(), boxID, &params)
+	if err != nil {
+		if errors.Is(err, service.ErrBoxNotFound) {
+			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrInvalidParams) {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		writeError(resp, http.StatusInternalServerError, "UpdateBoxError", err.Error())
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// GetBoxPort returns the host port a box port is published on
+func (h *BoxHandler) GetBoxPort(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	port, err := strconv.Atoi(req.PathParameter("port"))
+	if err != nil || port < 1 || port > 65535 {
+		writeError(resp, http.StatusBadRequest, "InvalidRequest", fmt.Sprintf("invalid port %q", req.PathParameter("port")))
+		return
+	}
+
+	externalPort, err := h.service.GetExternalPort(req.Request.Context(), boxID, port)
+	if err != nil {
+		if errors.Is(err, service.ErrBoxNotFound) {
+			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrPortNotPublished) {
+			writeError(resp, http.StatusNotFound, "PortNotPublished", err.Error())
+			return
+		}
+		writeError(resp, http.StatusInternalServerError, "GetBoxPortError", err.Error())
+		return
+	}
+	resp.WriteHeaderAndEntity(http.StatusOK, model.BoxPortResult{InternalPort: port, ExternalPort: externalPort})
+}
+
 // StopBox stops a running box
 func (h *BoxHandler) StopBox(req *restful.Request, resp *restful.Response) {
 	boxID := req.PathParameter("id")
@@ -501,8 +1009,10 @@ func (h *BoxHandler) GetArchive(req *restful.Request, resp *restful.Response) {
 	boxID := req.PathParameter("id")
 	path := req.QueryParameter("path")
 
+	follow, _ := strconv.ParseBool(req.QueryParameter("follow"))
 	archiveReq := &model.BoxArchiveGetParams{
-		Path: path,
+		Path:   path,
+		Follow: follow,
 	}
 
 	archiveResp, archive, err := h.service.GetArchive(req.Request.Context(), boxID, archiveReq)
@@ -511,6 +1021,14 @@ func (h *BoxHandler) GetArchive(req *restful.Request, resp *restful.Response) {
 			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
 			return
 		}
+		if errors.Is(err, service.ErrPathNotFound) {
+			writeError(resp, http.StatusNotFound, "FileNotFound", err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrInvalidParams) {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
 		writeError(resp, http.StatusInternalServerError, "GetArchiveError", err.Error())
 		return
 	}
@@ -524,12 +1042,20 @@ func (h *BoxHandler) GetArchive(req *restful.Request, resp *restful.Response) {
 	}
 
 	// Set response headers
-	resp.Header().Set("Content-Type", "application/x-tar")
 	resp.Header().Set("X-Gbox-Path-Stat", string(statJSON))
 	resp.Header().Set("Last-Modified", archiveResp.Mtime) // Use actual Mtime
 
-	// Copy the archive to response
-	_, err = io.Copy(resp.ResponseWriter, archive)
+	// A single regular file can be served as is; directories stay archived
+	if raw, _ := strconv.ParseBool(req.QueryParameter("raw")); raw && !follow && os.FileMode(archiveResp.Mode).IsRegular() {
+		err = writeRawFile(resp, archive)
+	} else if follow {
+		resp.Header().Set("Content-Type", "application/x-tar")
+		resp.WriteHeader(http.StatusOK)
+		err = copyFlushed(resp, archive)
+	} else {
+		resp.Header().Set("Content-Type", "application/x-tar")
+		_, err = io.Copy(resp.ResponseWriter, archive)
+	}
 
 	if err != nil {
 		// Log the error, but don't try to writeError as headers might have been sent
@@ -538,6 +1064,28 @@ func (h *BoxHandler) GetArchive(req *restful.Request, resp *restful.Response) {
 	}
 }
 
+// writeRawFile streams the only file of a tar archive with its detected
+// MIME type instead of the tar framing
+func writeRawFile(resp *restful.Response, archive io.Reader) error {
+	tr := tar.NewReader(archive)
+	header, err := tr.Next()
+	if err != nil {
+		writeError(resp, http.StatusInternalServerError, "GetArchiveError", fmt.Sprintf("Failed to read archive: %v", err))
+		return nil
+	}
+
+	content := bufio.NewReaderSize(tr, 512)
+	contentType := mime.TypeByExtension(filepath.Ext(header.Name))
+	if contentType == "" {
+		sniff, _ := content.Peek(512)
+		contentType = http.DetectContentType(sniff)
+	}
+	resp.Header().Set("Content-Type", contentType)
+	resp.Header().Set("Content-Length", strconv.FormatInt(header.Size, 10))
+	_, err = io.Copy(resp.ResponseWriter, content)
+	return err
+}
+
 // HeadArchive gets metadata about files in box
 func (h *BoxHandler) HeadArchive(req *restful.Request, resp *restful.Response) {
 	boxID := req.PathParameter("id")
@@ -583,9 +1131,22 @@ func (h *BoxHandler) ExtractArchive(req *restful.Request, resp *restful.Response
 	}
 
 	// Use actual params
+	transactional, _ := strconv.ParseBool(req.QueryParameter("transactional"))
 	extractParams := &model.BoxArchiveExtractParams{
-		Path:    path,
-		Content: content,
+		Path:          path,
+		Content:       content,
+		Transactional: transactional,
+	}
+
+	// Stream per-entry progress and a summary when requested
+	if acceptsStream(req) {
+		h.streamServiceOperation(req, resp, extractParams, func(ctx context.Context, params interface{}, progressWriter io.Writer) (interface{}, error) {
+			p := params.(*model.BoxArchiveExtractParams)
+			p.Progress = progressWriter
+			// The summary is written to the stream by the service itself
+			return nil, h.service.ExtractArchive(ctx, boxID, p)
+		}, false)
+		return
 	}
 
 	// Call actual service method
@@ -594,6 +1155,10 @@ func (h *BoxHandler) ExtractArchive(req *restful.Request, resp *restful.Response
 			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
 			return
 		}
+		if errors.Is(err, service.ErrInvalidParams) {
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
 		writeError(resp, http.StatusInternalServerError, "ExtractArchiveError", err.Error())
 		return
 	}
@@ -696,6 +1261,145 @@ func (h *BoxHandler) WriteFile(req *restful.Request, resp *restful.Response) {
 	resp.WriteHeaderAndEntity(http.StatusOK, result)
 }
 
+// StatFile returns the metadata of a path in a box without downloading it
+func (h *BoxHandler) StatFile(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	path := req.QueryParameter("path")
+
+	if path == "" {
+		writeError(resp, http.StatusBadRequest, "InvalidRequest", "Path parameter is required")
+		return
+	}
+
+	result, err := h.service.StatFile(req.Request.Context(), boxID, &model.BoxFileStatParams{Path: path})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidParams):
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+		case errors.Is(err, service.ErrBoxNotFound):
+			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
+		case errors.Is(err, service.ErrPathNotFound):
+			writeError(resp, http.StatusNotFound, "FileNotFound", err.Error())
+		default:
+			writeError(resp, http.StatusInternalServerError, "StatFileError", err.Error())
+		}
+		return
+	}
+
+	resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// GetLogs streams the output of a box's main process as text, filtered by
+// the grep query parameter when set
+func (h *BoxHandler) GetLogs(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	params := &model.BoxLogsParams{
+		Follow:      req.QueryParameter("follow") == "true",
+		Tail:        req.QueryParameter("tail"),
+		Grep:        req.QueryParameter("grep"),
+		InvertMatch: req.QueryParameter("invertMatch") == "true",
+	}
+
+	logs, err := h.service.Logs(req.Request.Context(), boxID, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidParams):
+			writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+		case errors.Is(err, service.ErrBoxNotFound):
+			writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
+		default:
+			writeError(resp, http.StatusInternalServerError, "GetLogsError", err.Error())
+		}
+		return
+	}
+	defer logs.Close()
+
+	resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	resp.WriteHeader(http.StatusOK)
+	if err := copyFlushed(resp, logs); err != nil {
+		log.Warnf("Failed to stream logs of box %s: %v", boxID, err)
+	}
+}
+
+// copyFlushed copies r to the response, flushing after every read so a
+// long-lived stream reaches the client as it is produced. Only read errors
+// are returned; a failed write means the client went away.
+func copyFlushed(resp *restful.Response, r io.Reader) error {
+	flusher, _ := resp.ResponseWriter.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, werr := resp.Write(buf[:n]); werr != nil {
+				return nil
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// GetSyncManifest returns the content hashes of the files under a directory in a box
+func (h *BoxHandler) GetSyncManifest(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	path := req.QueryParameter("path")
+
+	if path == "" {
+		writeError(resp, http.StatusBadRequest, "InvalidRequest", "Path parameter is required")
+		return
+	}
+
+	result, err := h.service.SyncManifest(req.Request.Context(), boxID, &model.BoxSyncManifestParams{Path: path})
+	if err != nil {
+		writeSyncError(resp, err)
+		return
+	}
+
+	resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// ApplySync prepares a directory in a box for a sync and removes deleted files from it
+func (h *BoxHandler) ApplySync(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+
+	var params model.BoxSyncApplyParams
+	if err := req.ReadEntity(&params); err != nil {
+		writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+		return
+	}
+	params.Path = req.QueryParameter("path")
+	if params.Path == "" {
+		writeError(resp, http.StatusBadRequest, "InvalidRequest", "Path parameter is required")
+		return
+	}
+
+	result, err := h.service.ApplySync(req.Request.Context(), boxID, &params)
+	if err != nil {
+		writeSyncError(resp, err)
+		return
+	}
+
+	resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+func writeSyncError(resp *restful.Response, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidParams):
+		writeError(resp, http.StatusBadRequest, "InvalidRequest", err.Error())
+	case errors.Is(err, service.ErrBoxNotFound):
+		writeError(resp, http.StatusNotFound, "BoxNotFound", err.Error())
+	default:
+		writeError(resp, http.StatusInternalServerError, "SyncError", err.Error())
+	}
+}
+
 // UpdateBoxImage method has been removed - image management is now handled by background ImageManager service
 
 func (h *BoxHandler) BoxActionClick(req *restful.Request, resp *restful.Response) {
@@ -746,7 +1450,7 @@ func writeError(resp *restful.Response, status int, code, message string) {
 	}
 }
 
-// --- Hijacking-related helper functions (temporarily commented out for future stream support) ---
+// --- Hijacking-related helper functions ---
 
 // writeResponseHeaders writes HTTP response headers for Hijacked connection
 func writeResponseHeaders(w io.Writer, upgrade, connection string, tty bool) {
@@ -0,0 +1,517 @@
+package api
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	"github.com/emicklei/go-restful/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBoxService embeds the interface so tests only implement what they use
+type fakeBoxService struct {
+	service.BoxService
+	createErr error
+	// Owners of the created boxes, by box ID
+	owners map[string]string
+	// Parameters of the last archive extraction, which fails with extractErr
+	extracted  *model.BoxArchiveExtractParams
+	extractErr error
+}
+
+func (f *fakeBoxService) CreateLinuxBox(ctx context.Context, params *model.LinuxAndroidBoxCreateParam) (*model.Box, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	id := fmt.Sprintf("box-%d", len(f.owners)+1)
+	if f.owners == nil {
+		f.owners = make(map[string]string)
+	}
+	f.owners[id] = params.Owner
+	return &model.Box{ID: id, Status: "running", Owner: params.Owner}, nil
+}
+
+func (f *fakeBoxService) PlanLinuxBox(ctx context.Context, params *model.LinuxAndroidBoxCreateParam) (*model.BoxCreatePlan, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	return &model.BoxCreatePlan{ID: "box-planned", Name: "gbox-box-planned", Image: "alpine"}, nil
+}
+
+func (f *fakeBoxService) List(ctx context.Context, params *model.BoxListParams) (*model.BoxListResult, error) {
+	result := &model.BoxListResult{Data: []model.Box{}}
+	for id, owner := range f.owners {
+		matches := true
+		for _, filter := range params.Filters {
+			if filter.Field == "owner" && filter.Value != owner {
+				matches = false
+			}
+		}
+		if matches {
+			result.Data = append(result.Data, model.Box{ID: id, Owner: owner})
+		}
+	}
+	result.Total = len(result.Data)
+	return result, nil
+}
+
+func (f *fakeBoxService) GetExternalPort(ctx context.Context, id string, internalPort int) (int, error) {
+	if internalPort != 8080 {
+		return 0, fmt.Errorf("%w: internal port %d not exposed or mapped for box %s", service.ErrPortNotPublished, internalPort, id)
+	}
+	return 32768, nil
+}
+
+// GetArchive serves /data as a directory and any other path as a file
+// holding its own path
+func (f *fakeBoxService) GetArchive(ctx context.Context, id string, params *model.BoxArchiveGetParams) (*model.BoxArchiveResult, io.ReadCloser, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	result := &model.BoxArchiveResult{Name: path.Base(params.Path), Mode: 0o644}
+	if params.Path == "/data" {
+		result.Mode = uint32(os.ModeDir | 0o755)
+		tw.WriteHeader(&tar.Header{Name: "data/", Typeflag: tar.TypeDir, Mode: 0o755})
+	} else {
+		content := "<html>" + params.Path + "</html>"
+		result.Size = int64(len(content))
+		tw.WriteHeader(&tar.Header{Name: result.Name, Typeflag: tar.TypeReg, Mode: 0o644, Size: result.Size})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	return result, io.NopCloser(&buf), nil
+}
+
+// ExtractArchive reports each entry of the archive like the docker service
+func (f *fakeBoxService) ExtractArchive(ctx context.Context, id string, params *model.BoxArchiveExtractParams) error {
+	f.extracted = params
+	if f.extractErr != nil {
+		return f.extractErr
+	}
+	if params.Progress == nil {
+		return nil
+	}
+	encoder := json.NewEncoder(params.Progress)
+	summary := model.ArchiveExtractSummary{Status: "complete"}
+	tr := tar.NewReader(bytes.NewReader(params.Content))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		encoder.Encode(model.ArchiveExtractEvent{Status: "extracted", Path: hdr.Name, Size: hdr.Size, Type: "file"})
+		summary.FilesWritten++
+		summary.Bytes += hdr.Size
+	}
+	return encoder.Encode(summary)
+}
+
+func (f *fakeBoxService) Get(ctx context.Context, id string) (*model.Box, error) {
+	return &model.Box{ID: id, Status: "running"}, nil
+}
+
+// ExecStream runs `cat`: it echoes stdin back as the command's stdout
+func (f *fakeBoxService) ExecStream(ctx context.Context, id string, params *model.BoxExecParams, stdin io.Reader, stdout io.Writer) (*model.BoxExecResult, error) {
+	if params.Stdin {
+		if _, err := io.Copy(stdout, stdin); err != nil {
+			return nil, err
+		}
+	}
+	return &model.BoxExecResult{}, nil
+}
+
+func (f *fakeBoxService) Delete(ctx context.Context, id string, params *model.BoxDeleteParams) (*model.BoxDeleteResult, error) {
+	if _, ok := f.owners[id]; !ok {
+		return nil, fmt.Errorf("box %s not found: %w", id, service.ErrBoxNotFound)
+	}
+	delete(f.owners, id)
+	return &model.BoxDeleteResult{Message: "Box deleted successfully"}, nil
+}
+
+func (f *fakeBoxService) ListImages(ctx context.Context, params *model.BoxImageListParams) (*model.BoxImageListResult, error) {
+	result := &model.BoxImageListResult{Images: []model.BoxImage{{ID: "sha256:pw", RepoTags: []string{"babelcloud/gbox-playwright:latest"}}}}
+	if params.All {
+		result.Images = append(result.Images, model.BoxImage{ID: "sha256:py", RepoTags: []string{"python:3.12"}})
+	}
+	return result, nil
+}
+
+func newTestContainer(svc service.BoxService) *restful.Container {
+	container := restful.NewContainer()
+	ws := new(restful.WebService)
+	ws.Path("/api/v1").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+	RegisterRoutes(ws, NewBoxHandler(svc))
+	container.Add(ws)
+	return container
+}
+
+func TestCreateLinuxBoxEventStream(t *testing.T) {
+	container := newTestContainer(&fakeBoxService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/boxes/linux", strings.NewReader(`{"type":"linux"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	rec := httptest.NewRecorder()
+	container.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+	events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+	require.Len(t, events, 2)
+	for _, event := range events {
+		assert.True(t, strings.HasPrefix(event, "data: {"), "event not data-framed: %q", event)
+	}
+	assert.Contains(t, events[0], `"status":"prepare"`)
+	assert.Contains(t, events[1], `"status":"complete"`)
+	assert.Contains(t, events[1], `"id":"box-1"`)
+}
+
+func TestCreateLinuxBoxJSONStreamDefault(t *testing.T) {
+	container := newTestContainer(&fakeBoxService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/boxes/linux", strings.NewReader(`{"type":"linux"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json-stream")
+	rec := httptest.NewRecorder()
+	container.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json-stream", rec.Header().Get("Content-Type"))
+	assert.NotContains(t, rec.Body.String(), "data:")
+	assert.Len(t, strings.Split(strings.TrimSpace(rec.Body.String()), "\n"), 2)
+}
+
+func TestCreateLinuxBoxForbidden(t *testing.T) {
+	container := newTestContainer(&fakeBoxService{createErr: fmt.Errorf("%w: docker socket", service.ErrForbidden)})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/boxes/linux", strings.NewReader(`{"type":"linux","config":{"dockerSocket":true}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	container.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Forbidden")
+}
+
+func TestGetBoxPort(t *testing.T) {
+	container := newTestContainer(&fakeBoxService{})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/v1/boxes/box-1/ports/8080")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"internalPort":8080,"externalPort":32768}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/boxes/box-1/ports/9090").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/boxes/box-1/ports/http").Code)
+}
+
+func TestGetArchiveRaw(t *testing.T) {
+	container := newTestContainer(&fakeBoxService{})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// Detected from the extension
+	rec := get("/api/v1/boxes/box-1/archive?path=/app/index.html&raw=true")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "<html>/app/index.html</html>", rec.Body.String())
+	assert.Equal(t, "28", rec.Header().Get("Content-Length"))
+
+	// Sniffed from the content
+	rec = get("/api/v1/boxes/box-1/archive?path=/app/page&raw=true")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "<html>/app/page</html>", rec.Body.String())
+
+	// Without raw, and for directories, the tar archive is served
+	for _, path := range []string{"/api/v1/boxes/box-1/archive?path=/app/index.html", "/api/v1/boxes/box-1/archive?path=/data&raw=true"} {
+		rec = get(path)
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Equal(t, "application/x-tar", rec.Header().Get("Content-Type"), path)
+		_, err := tar.NewReader(rec.Body).Next()
+		assert.NoError(t, err, path)
+	}
+}
+
+func TestBoxOwnerFromCaller(t *testing.T) {
+	svc := &fakeBoxService{}
+	container := newTestContainer(svc)
+
+	do := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		var body io.Reader
+		if method == http.MethodPost {
+			body = strings.NewReader(`{"type":"linux"}`)
+		}
+		req := httptest.NewRequest(method, path, body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, req)
+		return rec
+	}
+	alice := map[string]string{"Authorization": "Bearer key-alice"}
+	bob := map[string]string{"Authorization": "Bearer key-bob"}
+
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/boxes/linux", alice).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/boxes/linux", bob).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/boxes/linux", map[string]string{"X-Gbox-User": "carol"}).Code)
+
+	assert.Regexp(t, `^apikey-[0-9a-f]{12}$`, svc.owners["box-1"])
+	assert.NotEqual(t, svc.owners["box-1"], svc.owners["box-2"])
+	assert.NotContains(t, svc.owners["box-1"], "key-alice", "the API key must not be stored")
+	assert.Equal(t, "carol", svc.owners["box-3"])
+
+	var listed model.BoxListResult
+	rec := do(http.MethodGet, "/api/v1/boxes?mine=true", alice)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed.Data, 1)
+	assert.Equal(t, "box-1", listed.Data[0].ID)
+
+	rec = do(http.MethodGet, "/api/v1/boxes", alice)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	assert.Len(t, listed.Data, 3)
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/api/v1/boxes?mine=true", nil).Code)
+}
+
+func TestBoxOwnerFromAuthenticatedKey(t *testing.T) {
+	svc := &fakeBoxService{}
+	handler := common.RequireAPIKey(newTestContainer(svc), []config.APIKey{{Key: "key-alice", Owner: "alice"}})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/boxes/linux", strings.NewReader(`{"type":"linux"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(common.APIKeyHeader, "key-alice")
+	req.Header.Set("X-Gbox-User", "mallory")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "alice", svc.owners["box-1"], "the configured owner of the key takes precedence")
+}
+
+func TestDeleteBoxIgnoreNotFound(t *testing.T) {
+	svc := &fakeBoxService{owners: map[string]string{"box-1": ""}}
+	container := newTestContainer(svc)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/v1/boxes/missing").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/api/v1/boxes/missing/terminate").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/api/v1/boxes/missing?ignoreNotFound=true").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/api/v1/boxes/missing/terminate?ignoreNotFound=true").Code)
+
+	// Retrying a delete that already succeeded
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/api/v1/boxes/box-1?ignoreNotFound=true").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/api/v1/boxes/box-1?ignoreNotFound=true").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/v1/boxes/box-1").Code)
+}
+
+func TestCreateLinuxBoxDryRun(t *testing.T) {
+	svc := &fakeBoxService{}
+	container := newTestContainer(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/boxes/linux?dryRun=true", strings.NewReader(`{"type":"linux"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	container.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var plan model.BoxCreatePlan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &plan))
+	assert.Equal(t, "box-planned", plan.ID)
+	assert.Empty(t, svc.owners, "a dry run must not create a box")
+
+	svc.createErr = fmt.Errorf("%w: bad memory", service.ErrInvalidParams)
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/boxes/linux?dryRun=true", strings.NewReader(`{"type":"linux"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	container.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// Test that /boxes/images is not routed as the box with ID "images"
+func TestListImagesRoute(t *testing.T) {
+	container := newTestContainer(&fakeBoxService{})
+
+	rec := httptest.NewRecorder()
+	container.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/boxes/images?all=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var result model.BoxImageListResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Len(t, result.Images, 2)
+}
+
+// testArchive returns a tar archive holding the named files, each containing its own name
+func testArchive(t *testing.T, names ...string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(name))}))
+		_, err := tw.Write([]byte(name))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestExtractArchiveStreamsProgress(t *testing.T) {
+	svc := &fakeBoxService{}
+	container := newTestContainer(svc)
+	archive := testArchive(t, "app/main.py", "app/requirements.txt")
+
+	put := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/boxes/box-1/archive?path=/app", bytes.NewReader(archive))
+		req.Header.Set("Content-Type", "application/x-tar")
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := put("application/json-stream")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/json-stream", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "/app", svc.extracted.Path)
+	assert.Equal(t, archive, svc.extracted.Content)
+	decoder := json.NewDecoder(rec.Body)
+	for _, name := range []string{"app/main.py", "app/requirements.txt"} {
+		var event model.ArchiveExtractEvent
+		require.NoError(t, decoder.Decode(&event))
+		assert.Equal(t, model.ArchiveExtractEvent{Status: "extracted", Path: name, Size: int64(len(name)), Type: "file"}, event)
+	}
+	var summary model.ArchiveExtractSummary
+	require.NoError(t, decoder.Decode(&summary))
+	assert.Equal(t, model.ArchiveExtractSummary{Status: "complete", FilesWritten: 2, Bytes: 31}, summary)
+	assert.False(t, decoder.More(), "nothing follows the summary")
+
+	rec = put("text/event-stream")
+	require.Equal(t, http.StatusOK, rec.Code)
+	events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+	require.Len(t, events, 3)
+	assert.Contains(t, events[0], `"path":"app/main.py"`)
+	assert.Contains(t, events[2], `"filesWritten":2`)
+
+	// Without a stream the plain response is kept
+	rec = put("application/json")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Nil(t, svc.extracted.Progress)
+}
+
+func TestExtractArchiveTransactionalFailure(t *testing.T) {
+	svc := &fakeBoxService{extractErr: fmt.Errorf("extraction failed, /app was left unchanged: tar: short write")}
+	container := newTestContainer(svc)
+
+	put := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/boxes/box-1/archive?path=/app&transactional=true", bytes.NewReader(testArchive(t, "app/main.py")))
+		req.Header.Set("Content-Type", "application/x-tar")
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := put("application/json")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "ExtractArchiveError")
+	assert.Contains(t, rec.Body.String(), "/app was left unchanged")
+	require.NotNil(t, svc.extracted)
+	assert.True(t, svc.extracted.Transactional, "the query parameter must reach the service")
+
+	// A streamed extraction ends with the error instead of a summary
+	rec = put("application/json-stream")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var final struct{ Status, Error string }
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(rec.Body.Bytes()), &final))
+	assert.Equal(t, "error", final.Status)
+	assert.Contains(t, final.Error, "/app was left unchanged")
+	assert.True(t, svc.extracted.Transactional)
+
+	svc.extractErr = nil
+	put("application/json")
+	assert.True(t, svc.extracted.Transactional)
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/boxes/box-1/archive?path=/app", bytes.NewReader(testArchive(t, "app/main.py")))
+	req.Header.Set("Content-Type", "application/x-tar")
+	container.ServeHTTP(httptest.NewRecorder(), req)
+	assert.False(t, svc.extracted.Transactional, "extraction is not transactional by default")
+}
+
+// Test that binary stdin echoed by the command round-trips byte-for-byte over a raw stream
+func TestExecBoxRawStreamRoundTrip(t *testing.T) {
+	server := httptest.NewServer(newTestContainer(&fakeBoxService{}))
+	defer server.Close()
+
+	payload := make([]byte, 256*1024)
+	for i := range payload {
+		payload[i] = byte(i % 256)
+	}
+	// Start with bytes that look like a multiplexed stream header
+	copy(payload, []byte{2, 0, 0, 0, 0xff, 0xff, 0xff, 0xff})
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	body := `{"commands":["cat"],"stdin":true}`
+	fmt.Fprintf(conn, "POST /api/v1/boxes/box-1/commands HTTP/1.1\r\nHost: gbox\r\n"+
+		"Content-Type: application/json\r\nAccept: application/vnd.gbox.raw-stream\r\n"+
+		"Connection: Upgrade\r\nUpgrade: tcp\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "application/vnd.gbox.raw-stream", resp.Header.Get("Content-Type"))
+
+	go func() {
+		conn.Write(payload)
+		conn.(*net.TCPConn).CloseWrite()
+	}()
+	output, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(payload, output), "raw stream output differs from input")
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
 	"github.com/emicklei/go-restful/v3"
 )
@@ -13,7 +14,10 @@ func RegisterRoutes(ws *restful.WebService, boxHandler *BoxHandler) {
 		//page and pageSize are only supported for cloud version
 		Param(ws.QueryParameter("page", "page number").DataType("float64").Required(false)).
 		Param(ws.QueryParameter("pageSize", "page size").DataType("float64").Required(false)).
+		Param(ws.QueryParameter("size", "include disk usage of each box (expensive)").DataType("boolean").Required(false)).
+		Param(ws.QueryParameter("mine", "only boxes created by the caller, identified by API key or the X-Gbox-User header").DataType("boolean").Required(false)).
 		Returns(200, "OK", []model.Box{}).
+		Returns(400, "Bad Request", model.BoxError{}).
 		Returns(500, "Internal Server Error", model.BoxError{}))
 
 	ws.Route(ws.GET("/boxes/{id}").To(boxHandler.GetBox).
@@ -24,25 +28,37 @@ func RegisterRoutes(ws *restful.WebService, boxHandler *BoxHandler) {
 		Returns(500, "Internal Server Error", model.BoxError{}))
 
 	ws.Route(ws.POST("/boxes/linux").To(boxHandler.CreateLinuxBox).
+		Filter(common.NoTimeouts).
 		Doc("create a linux box").
+		Param(ws.QueryParameter("dryRun", "return the spec the box would be created with, without creating it").DataType("boolean").Required(false)).
 		Reads(model.LinuxAndroidBoxCreateParam{}).
-		Produces("application/json", "application/json-stream").
+		Produces("application/json", "application/json-stream", "text/event-stream").
+		Returns(200, "Dry run spec", model.BoxCreatePlan{}).
 		Returns(201, "Created", model.Box{}).
 		Returns(202, "Accepted", model.BoxError{}).
 		Returns(400, "Bad Request", model.BoxError{}).
 		Returns(500, "Internal Server Error", model.BoxError{}))
 
 	ws.Route(ws.POST("/boxes/android").To(boxHandler.CreateAndroidBox).
+		Filter(common.NoTimeouts).
 		Doc("create a android box").
 		Reads(model.LinuxAndroidBoxCreateParam{}).
-		Produces("application/json", "application/json-stream").
+		Produces("application/json", "application/json-stream", "text/event-stream").
 		Returns(201, "Created", model.Box{}).
 		Returns(202, "Accepted", model.BoxError{}).
 		Returns(400, "Bad Request", model.BoxError{}).
 		Returns(500, "Internal Server Error", model.BoxError{}))
 
+	ws.Route(ws.POST("/boxes/compose").To(boxHandler.ComposeBoxes).
+		Doc("create a group of boxes sharing a network").
+		Reads(model.BoxComposeParams{}).
+		Returns(201, "Created", model.BoxComposeResult{}).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
 	ws.Route(ws.DELETE("/boxes/{id}").To(boxHandler.DeleteBox).
 		Doc("delete a box").
+		Param(ws.QueryParameter("ignoreNotFound", "succeed when the box is already gone").DataType("boolean").Required(false)).
 		Reads(model.BoxDeleteParams{}).
 		Returns(200, "OK", model.BoxDeleteResult{}).
 		Returns(404, "Not Found", model.BoxError{}).
@@ -51,10 +67,27 @@ func RegisterRoutes(ws *restful.WebService, boxHandler *BoxHandler) {
 	ws.Route(ws.POST("/boxes/{id}/terminate").To(boxHandler.DeleteBox).
 		Doc("terminate a box").
 		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.QueryParameter("ignoreNotFound", "succeed when the box is already gone").DataType("boolean").Required(false)).
 		Returns(200, "OK", model.BoxDeleteResult{}).
 		Returns(404, "Not Found", model.BoxError{}).
 		Returns(500, "Internal Server Error", model.BoxError{}))
 
+	ws.Route(ws.DELETE("/groups/{group}").To(boxHandler.DeleteGroup).
+		Doc("delete every box of a group").
+		Param(ws.PathParameter("group", "name of the group").DataType("string")).
+		Reads(model.BoxesDeleteParams{}).
+		Returns(200, "OK", model.BoxesDeleteResult{}).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
+	ws.Route(ws.POST("/groups/{group}/stop").To(boxHandler.StopGroup).
+		Doc("stop every running box of a group").
+		Param(ws.PathParameter("group", "name of the group").DataType("string")).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(200, "OK", model.BoxesStopResult{}).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
 	// ws.Route(ws.DELETE("/boxes").To(boxHandler.DeleteBoxes).
 	// 	Doc("delete all boxes").
 	// 	Reads(model.BoxesDeleteParams{}).
@@ -68,21 +101,55 @@ func RegisterRoutes(ws *restful.WebService, boxHandler *BoxHandler) {
 
 	// Box Runtime Operations
 	ws.Route(ws.POST("/boxes/{id}/commands").To(boxHandler.ExecBox).
+		Filter(common.NoTimeouts).
 		Doc("execute a command in a box").
 		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Notes("With `Accept: application/vnd.gbox.raw-stream` and `Upgrade: tcp` the connection is hijacked: "+
+			"it carries stdin to the command when `stdin` is set, and the command's stdout back unmodified.").
 		Reads(model.BoxExecParams{}).
 		Consumes(restful.MIME_JSON).
-		Produces(restful.MIME_JSON).
+		Produces(restful.MIME_JSON, mediaTypeRawStream).
 		Returns(200, "OK", model.BoxExecResult{}).
+		Returns(202, "Accepted", model.BoxExecSession{}).
 		Returns(400, "Bad Request", model.BoxError{}).
 		Returns(404, "Not Found", model.BoxError{}).
 		Returns(409, "Conflict", model.BoxError{}).
 		Returns(500, "Internal Server Error", model.BoxError{}))
 
+	ws.Route(ws.GET("/boxes/{id}/exec-sessions/{sessionId}").To(boxHandler.GetExecSession).
+		Doc("get the state and output of a detached command").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.PathParameter("sessionId", "identifier of the exec session").DataType("string")).
+		Param(ws.QueryParameter("offset", "output offset returned by the previous poll").DataType("integer").Required(false)).
+		Returns(200, "OK", model.BoxExecSession{}).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
+	ws.Route(ws.GET("/boxes/{id}/exec-sessions").To(boxHandler.ListExecSessions).
+		Doc("list the exec sessions still running in a box").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Returns(200, "OK", model.BoxExecSessionListResult{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
+	ws.Route(ws.DELETE("/boxes/{id}/exec-sessions/{sessionId}").To(boxHandler.KillExecSession).
+		Doc("kill the command of an exec session, e.g. a stuck one").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.PathParameter("sessionId", "identifier of the exec session").DataType("string")).
+		Returns(200, "OK", model.BoxExecSession{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
 	ws.Route(ws.POST("/boxes/{id}/run-code").To(boxHandler.RunBox).
+		Filter(common.NoTimeouts).
 		Doc("run code in a box").
+		Notes("With stream=true or an Accept header of application/json-stream or text/event-stream, output is streamed "+
+			"as it is produced: one event per chunk with status stdout or stderr and the chunk in data, then an event "+
+			"with status exit and the exitCode, or status error and the error.").
 		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.QueryParameter("stream", "stream output events instead of returning the collected output").DataType("boolean").Required(false)).
 		Reads(model.BoxRunCodeParams{}).
+		Produces("application/json", "application/json-stream", "text/event-stream").
 		Returns(200, "OK", model.BoxRunCodeResult{}).
 		Returns(400, "Bad Request", model.BoxError{}).
 		Returns(404, "Not Found", model.BoxError{}).
@@ -104,14 +171,59 @@ func RegisterRoutes(ws *restful.WebService, boxHandler *BoxHandler) {
 		Returns(404, "Not Found", model.BoxError{}).
 		Returns(500, "Internal Server Error", model.BoxError{}))
 
+	ws.Route(ws.POST("/boxes/{id}/resurrect").To(boxHandler.ResurrectBox).
+		Doc("create a box reclaim deleted again from its snapshot image").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(200, "OK", model.Box{}).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
+	ws.Route(ws.POST("/boxes/{id}/touch").To(boxHandler.TouchBox).
+		Doc("refresh the last access time of a box so reclaim does not stop it").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(200, "OK", model.BoxTouchResult{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
+	ws.Route(ws.POST("/boxes/{id}/update").To(boxHandler.UpdateBox).
+		Doc("change the CPU and memory limits of a box without recreating it").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Reads(model.BoxUpdateParams{}).
+		Returns(200, "OK", model.BoxUpdateResult{}).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
+	ws.Route(ws.GET("/boxes/{id}/ports/{port}").To(boxHandler.GetBoxPort).
+		Doc("get the host port a box port is published on").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.PathParameter("port", "port inside the box").DataType("integer")).
+		Returns(200, "OK", model.BoxPortResult{}).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(404, "Box not found or port not published", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
 	// WebSocket route for executing commands
 	ws.Route(ws.GET("/boxes/{id}/exec").To(boxHandler.ExecBoxWS).
+		Filter(common.NoTimeouts).
 		Doc("execute a command in a box via WebSocket").
 		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
 		Returns(400, "Bad Request", model.BoxError{}). // e.g., missing cmd parameter
 		Returns(404, "Not Found", model.BoxError{}).
 		Returns(500, "Internal Server Error", model.BoxError{})) // e.g., upgrade failed
 
+	ws.Route(ws.GET("/boxes/{id}/exec-sessions/{sessionId}/attach").To(boxHandler.AttachExecSessionWS).
+		Filter(common.NoTimeouts).
+		Doc("re-attach to an interactive exec session via WebSocket, replaying its recent output").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.PathParameter("sessionId", "identifier of the exec session").DataType("string")).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(409, "Conflict", model.BoxError{}). // session does not accept input
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
 	// // Box Archive Operations
 	// ws.Route(ws.HEAD("/boxes/{id}/archive").To(boxHandler.HeadArchive).
 	// 	Doc("get metadata about files in box").
@@ -122,25 +234,50 @@ func RegisterRoutes(ws *restful.WebService, boxHandler *BoxHandler) {
 	// 	Returns(404, "Not Found", model.BoxError{}).
 	// 	Returns(500, "Internal Server Error", model.BoxError{}))
 
-	// ws.Route(ws.GET("/boxes/{id}/archive").To(boxHandler.GetArchive).
-	// 	Doc("get files from box as tar archive").
-	// 	Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
-	// 	Param(ws.QueryParameter("path", "path to get files from").DataType("string").Required(true)).
-	// 	Produces("application/x-tar").
-	// 	Returns(200, "OK", nil).
-	// 	Returns(400, "Bad Request", model.BoxError{}).
-	// 	Returns(404, "Not Found", model.BoxError{}).
-	// 	Returns(500, "Internal Server Error", model.BoxError{}))
+	ws.Route(ws.GET("/boxes/{id}/archive").To(boxHandler.GetArchive).
+		Filter(common.NoTimeouts).
+		Doc("get files from box as tar archive").
+		Notes("With follow, the archive of a directory in the box's share directory stays open and a tar entry is appended "+
+			"for each file created or changed under it until the client disconnects. A file can appear more than once, "+
+			"the last entry holding its latest content, and the archive has no end marker unless the server ends it.").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.QueryParameter("path", "path to get files from").DataType("string").Required(true)).
+		Param(ws.QueryParameter("raw", "serve a single regular file as is, with its detected content type, instead of as a tar archive").DataType("boolean").Required(false)).
+		Param(ws.QueryParameter("follow", "keep streaming files created or changed under the directory").DataType("boolean").Required(false)).
+		Produces("application/x-tar", "*/*").
+		Returns(200, "OK", nil).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
 
-	// ws.Route(ws.PUT("/boxes/{id}/archive").To(boxHandler.ExtractArchive).
-	// 	Doc("extract tar archive to box").
-	// 	Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
-	// 	Param(ws.QueryParameter("path", "path to extract files to").DataType("string").Required(true)).
-	// 	Consumes("application/x-tar").
-	// 	Returns(200, "OK", nil).
-	// 	Returns(400, "Bad Request", model.BoxError{}).
-	// 	Returns(404, "Not Found", model.BoxError{}).
-	// 	Returns(500, "Internal Server Error", model.BoxError{}))
+	ws.Route(ws.PUT("/boxes/{id}/archive").To(boxHandler.ExtractArchive).
+		Filter(common.NoTimeouts).
+		Doc("extract tar archive to box").
+		Notes("With Accept: application/json-stream or text/event-stream, an event is streamed for each entry written, "+
+			"followed by a summary of the extraction.").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.QueryParameter("path", "path to extract files to").DataType("string").Required(true)).
+		Param(ws.QueryParameter("transactional", "extract into a staging copy and move it into place only on full success").DataType("boolean").Required(false)).
+		Consumes("application/x-tar").
+		Produces("application/json", "application/json-stream", "text/event-stream").
+		Returns(200, "OK", nil).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
+	ws.Route(ws.GET("/boxes/{id}/logs").To(boxHandler.GetLogs).
+		Filter(common.NoTimeouts).
+		Doc("stream the output of a box's main process").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.QueryParameter("follow", "keep streaming lines as they are written").DataType("boolean").Required(false)).
+		Param(ws.QueryParameter("tail", "number of lines from the end to start with, or all").DataType("string").Required(false)).
+		Param(ws.QueryParameter("grep", "regular expression; only matching lines are streamed").DataType("string").Required(false)).
+		Param(ws.QueryParameter("invertMatch", "stream only the lines not matching grep").DataType("boolean").Required(false)).
+		Produces("text/plain", "*/*").
+		Returns(200, "OK", nil).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
 
 	// Box Filesystem Operations
 	ws.Route(ws.GET("/boxes/{id}/fs/list").To(boxHandler.ListFiles).
@@ -174,7 +311,52 @@ func RegisterRoutes(ws *restful.WebService, boxHandler *BoxHandler) {
 		Returns(404, "Not Found", model.BoxError{}).
 		Returns(500, "Internal Server Error", model.BoxError{}))
 
+	ws.Route(ws.GET("/boxes/{id}/stat").To(boxHandler.StatFile).
+		Doc("get the metadata of a path without downloading it").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.QueryParameter("path", "absolute path to stat; symlinks are not followed").DataType("string").Required(true)).
+		Produces("application/json").
+		Returns(200, "OK", model.BoxFileStat{}).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
+	ws.Route(ws.GET("/boxes/{id}/sync").To(boxHandler.GetSyncManifest).
+		Doc("list the content hashes of the files under a directory, for incremental sync").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.QueryParameter("path", "absolute directory to sync into").DataType("string").Required(true)).
+		Produces("application/json").
+		Returns(200, "OK", model.BoxSyncManifest{}).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
+	ws.Route(ws.POST("/boxes/{id}/sync").To(boxHandler.ApplySync).
+		Doc("create a directory to sync into and remove deleted files from it").
+		Notes("Changed files are then uploaded as a tar stream with PUT /boxes/{id}/archive.").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.QueryParameter("path", "absolute directory to sync into").DataType("string").Required(true)).
+		Reads(model.BoxSyncApplyParams{}).
+		Produces("application/json").
+		Returns(200, "OK", model.BoxSyncResult{}).
+		Returns(400, "Bad Request", model.BoxError{}).
+		Returns(404, "Not Found", model.BoxError{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
 	// Image management operations - removed /boxes/images/update route as images are now managed by background service
+	ws.Route(ws.GET("/boxes/images").To(boxHandler.ListImages).
+		Doc("list the images boxes can be created from").
+		Notes("By default only gbox images and images used by existing boxes are listed. On Kubernetes, the images referenced by box deployments are listed.").
+		Param(ws.QueryParameter("all", "list every local image").DataType("boolean").Required(false)).
+		Produces("application/json").
+		Returns(200, "OK", model.BoxImageListResult{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
+
+	ws.Route(ws.POST("/boxes/images/prune").To(boxHandler.PruneImages).
+		Doc("remove dangling images to reclaim disk space").
+		Produces("application/json").
+		Returns(200, "OK", model.BoxImagePruneResult{}).
+		Returns(500, "Internal Server Error", model.BoxError{}))
 
 	// these are only supported for cloud version
 	ws.Route(ws.POST("/boxes/{id}/actions/click").To(boxHandler.BoxActionClick).
@@ -9,9 +9,27 @@ var (
 	// ErrInvalidConfig is returned when the provided configuration is invalid
 	ErrInvalidConfig = errors.New("invalid box service configuration")
 
+	// ErrInvalidParams is returned when request parameters for a box operation are invalid
+	ErrInvalidParams = errors.New("invalid box parameters")
+
 	// ErrBoxNotFound is returned when a box with the specified ID does not exist
 	ErrBoxNotFound = errors.New("box not found")
 
+	// ErrExecSessionNotFound is returned when a detached exec session does not exist
+	ErrExecSessionNotFound = errors.New("exec session not found")
+
+	// ErrExecSessionNotInteractive is returned when attaching to a session that does not accept input
+	ErrExecSessionNotInteractive = errors.New("exec session is not interactive")
+
 	// ErrBoxNotRunning is returned when trying to execute a command in a box that is not running
 	ErrBoxNotRunning = errors.New("box is not running")
+
+	// ErrPortNotPublished is returned when a box port has no host port mapping
+	ErrPortNotPublished = errors.New("port is not published")
+
+	// ErrPathNotFound is returned when a path does not exist inside a box
+	ErrPathNotFound = errors.New("path not found")
+
+	// ErrForbidden is returned when a request asks for something the server configuration does not permit
+	ErrForbidden = errors.New("not permitted by server configuration")
 )
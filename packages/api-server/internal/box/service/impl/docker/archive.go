@@ -1,12 +1,17 @@
 package docker
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"path"
+	"strings"
 	"time"
 
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
 	"github.com/docker/docker/api/types"
 )
@@ -21,10 +26,25 @@ func (s *Service) GetArchive(ctx context.Context, id string, req *model.BoxArchi
 		return nil, nil, err
 	}
 
+	var hostDir string
+	if req.Follow {
+		if hostDir, err = followHostDir(id, req.Path); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	reader, stat, err := s.client.CopyFromContainer(ctx, containerInfo.ID, req.Path)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to copy from container: %w", err)
 	}
+	if req.Follow {
+		followed, err := s.followArchive(ctx, reader, hostDir, stat.Name)
+		if err != nil {
+			reader.Close()
+			return nil, nil, err
+		}
+		reader = followed
+	}
 
 	response := &model.BoxArchiveResult{
 		Name:  stat.Name,
@@ -71,11 +91,156 @@ func (s *Service) ExtractArchive(ctx context.Context, id string, req *model.BoxA
 		return err
 	}
 
-	reader := bytes.NewReader(req.Content)
-	err = s.client.CopyToContainer(ctx, containerInfo.ID, req.Path, reader, types.CopyToContainerOptions{})
+	// A transactional extract works on a staging copy of the directory
+	target := req.Path
+	var staging string
+	if req.Transactional {
+		if staging, err = s.stageArchiveTarget(ctx, id, req.Path); err != nil {
+			return err
+		}
+		target = staging
+	}
+
+	var reader io.Reader = bytes.NewReader(req.Content)
+	var pw *io.PipeWriter
+	var summaryCh chan model.ArchiveExtractSummary
+	if req.Progress != nil {
+		// Scan the tar stream alongside the upload so events follow the daemon's progress
+		var pr *io.PipeReader
+		pr, pw = io.Pipe()
+		reader = io.TeeReader(reader, pw)
+		summaryCh = make(chan model.ArchiveExtractSummary, 1)
+		go func() {
+			summaryCh <- reportArchiveEntries(pr, req.Progress)
+		}()
+	}
+
+	err = s.client.CopyToContainer(ctx, containerInfo.ID, target, reader, types.CopyToContainerOptions{})
+	var summary model.ArchiveExtractSummary
+	if pw != nil {
+		pw.Close()
+		summary = <-summaryCh
+	}
 	if err != nil {
+		if staging != "" {
+			s.discardArchiveStaging(id, staging)
+		}
 		return fmt.Errorf("failed to copy to container: %w", err)
 	}
+	if staging != "" {
+		if err := s.commitArchiveStaging(ctx, id, req.Path, staging); err != nil {
+			s.discardArchiveStaging(id, staging)
+			return err
+		}
+	}
+	if pw != nil {
+		json.NewEncoder(req.Progress).Encode(summary)
+	}
+
+	return nil
+}
+
+// stageScript copies directory $1 to a new staging directory $2 next to it,
+// keeping ownership, modes and timestamps
+const stageScript = `set -e; [ -d "$1" ]; mkdir -- "$2"; cp -a -- "$1/." "$2/"`
+
+// commitScript swaps staging directory $2 into the place of $1 by renaming,
+// putting $1 back when the swap fails, and then removes the old directory
+const commitScript = `mv -- "$1" "$3" || exit 1
+if ! mv -- "$2" "$1"; then mv -- "$3" "$1"; exit 1; fi
+rm -rf -- "$3"`
+
+// stageArchiveTarget prepares a transactional extract into dir, returning
+// the staging directory the archive is extracted into instead. It lives next
+// to dir so it can be moved into place by a rename on the same filesystem.
+func (s *Service) stageArchiveTarget(ctx context.Context, id, dir string) (string, error) {
+	dir = path.Clean(dir)
+	if !path.IsAbs(dir) || dir == "/" {
+		return "", fmt.Errorf("%w: transactional extract needs an absolute directory other than /, got %q", service.ErrInvalidParams, dir)
+	}
+	staging := path.Join(path.Dir(dir), fmt.Sprintf(".%s.gbox-staging-%d", path.Base(dir), time.Now().UnixNano()))
+
+	result, err := s.Exec(ctx, id, &model.BoxExecParams{
+		Commands: []string{"sh", "-c", stageScript, "sh", dir, staging},
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		s.discardArchiveStaging(id, staging)
+		return "", fmt.Errorf("failed to stage %s for extraction: %s", dir, strings.TrimSpace(result.Stderr))
+	}
+	return staging, nil
+}
 
+// commitArchiveStaging moves a fully extracted staging directory into the
+// place of dir
+func (s *Service) commitArchiveStaging(ctx context.Context, id, dir, staging string) error {
+	dir = path.Clean(dir)
+	result, err := s.Exec(ctx, id, &model.BoxExecParams{
+		Commands: []string{"sh", "-c", commitScript, "sh", dir, staging, staging + ".old"},
+	})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to move extracted files into %s: %s", dir, strings.TrimSpace(result.Stderr))
+	}
 	return nil
 }
+
+// discardArchiveStaging removes the staging directory of a failed
+// transactional extract. It runs even when the request was canceled.
+func (s *Service) discardArchiveStaging(id, staging string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result, err := s.Exec(ctx, id, &model.BoxExecParams{
+		Commands: []string{"rm", "-rf", "--", staging},
+	})
+	if err == nil && result.ExitCode != 0 {
+		err = fmt.Errorf("%s", strings.TrimSpace(result.Stderr))
+	}
+	if err != nil {
+		s.logger.Warn("Failed to remove staging directory %s of box %s: %v", staging, id, err)
+	}
+}
+
+// reportArchiveEntries reads tar headers from r, writing an ArchiveExtractEvent
+// for each entry to w, and returns the totals. r is always drained so the
+// writing side of the pipe never blocks.
+func reportArchiveEntries(r io.Reader, w io.Writer) model.ArchiveExtractSummary {
+	summary := model.ArchiveExtractSummary{Status: "complete"}
+	encoder := json.NewEncoder(w)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		event := model.ArchiveExtractEvent{Status: "extracted", Path: hdr.Name, Size: hdr.Size}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			event.Type = "file"
+		case tar.TypeDir:
+			event.Type = "directory"
+		case tar.TypeSymlink:
+			event.Type = "symlink"
+		case tar.TypeLink:
+			event.Type = "link"
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			event.Type = "other"
+			event.Status = "skipped"
+		}
+		if event.Status == "skipped" {
+			summary.Skipped++
+		} else {
+			summary.FilesWritten++
+			summary.Bytes += hdr.Size
+		}
+		encoder.Encode(event)
+	}
+	io.Copy(io.Discard, r)
+	return summary
+}
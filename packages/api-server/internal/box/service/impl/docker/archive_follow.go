@@ -0,0 +1,252 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
+)
+
+// followBatchInterval is how long file events are collected before the
+// changed files are appended, so a file being written in many small chunks
+// is sent once rather than once per write
+const followBatchInterval = 100 * time.Millisecond
+
+// followHostDir maps a directory in the box's share directory to its host
+// path, the only place the server can watch for changes. The box can write to
+// the share directory, so the path is resolved and must stay inside it.
+func followHostDir(boxID, boxPath string) (string, error) {
+	clean := path.Clean(boxPath)
+	if clean != common.DefaultShareDirPath && !strings.HasPrefix(clean, common.DefaultShareDirPath+"/") {
+		return "", fmt.Errorf("%w: follow is only supported under %s", service.ErrInvalidParams, common.DefaultShareDirPath)
+	}
+	rel := strings.TrimPrefix(clean, common.DefaultShareDirPath)
+	shareDir, _ := config.GetInstance().File.BoxShareDir(boxID)
+	hostDir := filepath.Join(shareDir, filepath.FromSlash(rel))
+
+	root, err := filepath.EvalSymlinks(shareDir)
+	if err == nil {
+		hostDir, err = filepath.EvalSymlinks(hostDir)
+	}
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %s", service.ErrPathNotFound, boxPath)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", boxPath, err)
+	}
+	if !withinDir(root, hostDir) {
+		return "", fmt.Errorf("%w: %s resolves outside the box share directory", service.ErrInvalidParams, boxPath)
+	}
+
+	info, err := os.Stat(hostDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", boxPath, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%w: follow requires a directory, %s is not one", service.ErrInvalidParams, boxPath)
+	}
+	return hostDir, nil
+}
+
+// withinDir reports whether the resolved path p is dir or below it
+func withinDir(dir, p string) bool {
+	return p == dir || strings.HasPrefix(p, dir+string(filepath.Separator))
+}
+
+// followReader stops following when the client closes the archive
+type followReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (r *followReader) Close() error {
+	r.cancel()
+	return r.PipeReader.Close()
+}
+
+// followArchive re-streams the entries of the initial archive and then
+// appends one for every file created or changed under hostDir, named below
+// base like the initial entries, until ctx is done or the result is closed.
+func (s *Service) followArchive(ctx context.Context, initial io.ReadCloser, hostDir, base string) (io.ReadCloser, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", hostDir, err)
+	}
+	// Watch before copying the initial archive so no change falls in between;
+	// such a file may be sent twice, which tar readers resolve by taking the last
+	if err := watchTree(watcher, hostDir, nil); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", hostDir, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	go func() {
+		defer cancel()
+		defer watcher.Close()
+		tw := tar.NewWriter(pw)
+		err := copyTarEntries(tw, initial)
+		initial.Close()
+		if err == nil {
+			err = s.appendChanges(ctx, tw, watcher, hostDir, base)
+		}
+		pw.CloseWithError(err)
+	}()
+	return &followReader{PipeReader: pr, cancel: cancel}, nil
+}
+
+// copyTarEntries writes every entry of r to tw, leaving tw open for more
+func copyTarEntries(tw *tar.Writer, r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return tw.Flush()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// appendChanges appends the files reported by watcher in batches until ctx
+// is done, then ends the archive
+func (s *Service) appendChanges(ctx context.Context, tw *tar.Writer, watcher *fsnotify.Watcher, hostDir, base string) error {
+	pending := make(map[string]struct{})
+	add := func(name string) { pending[name] = struct{}{} }
+	var batch <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return tw.Close()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return tw.Close()
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+				// Files may land in a new directory before it is watched
+				if err := watchTree(watcher, event.Name, add); err != nil {
+					s.logger.Warn("Failed to watch %s: %v", event.Name, err)
+				}
+			} else {
+				add(event.Name)
+			}
+			if batch == nil {
+				batch = time.After(followBatchInterval)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return tw.Close()
+			}
+			s.logger.Warn("Error watching %s: %v", hostDir, err)
+		case <-batch:
+			batch = nil
+			names := make([]string, 0, len(pending))
+			for name := range pending {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			pending = make(map[string]struct{})
+			for _, name := range names {
+				if err := appendFile(tw, hostDir, base, name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// watchTree adds dir and the directories below it to watcher, passing the
+// files found to found when it is set
+func watchTree(watcher *fsnotify.Watcher, dir string, found func(string)) error {
+	return filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(p)
+		}
+		if found != nil {
+			found(p)
+		}
+		return nil
+	})
+}
+
+// appendFile writes the regular file at name as an entry of tw. Files that
+// are gone or not regular are skipped, symlinks the box planted included, and
+// neither the file nor its directory is followed out of hostDir.
+func appendFile(tw *tar.Writer, hostDir, base, name string) error {
+	if info, err := os.Lstat(name); err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(name))
+	if err != nil || !withinDir(hostDir, parent) {
+		return nil
+	}
+	f, err := os.OpenFile(filepath.Join(parent, filepath.Base(name)), os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	rel, err := filepath.Rel(hostDir, name)
+	if err != nil {
+		return nil
+	}
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return nil
+	}
+	hdr.Name = path.Join(base, filepath.ToSlash(rel))
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	n, err := io.CopyN(tw, f, hdr.Size)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	// Pad a file truncated while it was copied to the size in its header
+	if n < hdr.Size {
+		if _, err := io.CopyN(tw, zeroReader{}, hdr.Size-n); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
@@ -0,0 +1,334 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+func TestExtractArchiveStreamsProgress(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for _, f := range []struct{ name, body string }{
+		{"dir/a.txt", "alpha"},
+		{"dir/b.txt", "bravo!"},
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(f.body))}))
+		_, err := tw.Write([]byte(f.body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "dir/fifo", Typeflag: tar.TypeFifo, Mode: 0644}))
+	require.NoError(t, tw.Close())
+
+	var uploaded []byte
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"PUT /containers/c1/archive": func(w http.ResponseWriter, r *http.Request) {
+			uploaded, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+		},
+	}}
+	svc := newTestService(t, daemon)
+
+	var progress bytes.Buffer
+	err := svc.ExtractArchive(context.Background(), "box-1", &model.BoxArchiveExtractParams{
+		Path:     "/tmp",
+		Content:  buf.Bytes(),
+		Progress: &progress,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, buf.Bytes(), uploaded)
+
+	decoder := json.NewDecoder(&progress)
+	var events []model.ArchiveExtractEvent
+	for i := 0; i < 4; i++ {
+		var event model.ArchiveExtractEvent
+		require.NoError(t, decoder.Decode(&event))
+		events = append(events, event)
+	}
+	assert.Equal(t, []model.ArchiveExtractEvent{
+		{Status: "extracted", Path: "dir/", Type: "directory"},
+		{Status: "extracted", Path: "dir/a.txt", Size: 5, Type: "file"},
+		{Status: "extracted", Path: "dir/b.txt", Size: 6, Type: "file"},
+		{Status: "skipped", Path: "dir/fifo", Type: "other"},
+	}, events)
+
+	var summary model.ArchiveExtractSummary
+	require.NoError(t, decoder.Decode(&summary))
+	assert.Equal(t, model.ArchiveExtractSummary{Status: "complete", FilesWritten: 3, Bytes: 11, Skipped: 1}, summary)
+	assert.False(t, decoder.More())
+}
+
+func TestGetArchiveFollow(t *testing.T) {
+	setupShareDir(t)
+	outDir := filepath.Join(config.GetInstance().File.Share, "box-1", "out")
+	require.NoError(t, os.MkdirAll(outDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(outDir, "first.txt"), []byte("first"), 0644))
+
+	// The daemon's archive of the directory as it was when following started
+	var initial bytes.Buffer
+	tw := tar.NewWriter(&initial)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "out/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "out/first.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 5}))
+	_, err := tw.Write([]byte("first"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	stat, _ := json.Marshal(map[string]interface{}{"name": "out", "mode": uint32(os.ModeDir | 0755), "mtime": time.Now()})
+
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"GET /containers/c1/archive": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
+			w.Write(initial.Bytes())
+		},
+	}}
+	svc := newTestService(t, daemon)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, archive, err := svc.GetArchive(ctx, "box-1", &model.BoxArchiveGetParams{Path: "/var/gbox/share/out", Follow: true})
+	require.NoError(t, err)
+	defer archive.Close()
+
+	tr := tar.NewReader(archive)
+	next := func() (string, string) {
+		hdr, err := tr.Next()
+		require.NoError(t, err)
+		body, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		return hdr.Name, string(body)
+	}
+
+	name, _ := next()
+	assert.Equal(t, "out/", name)
+	name, body := next()
+	assert.Equal(t, "out/first.txt", name)
+	assert.Equal(t, "first", body)
+
+	// Files written after the initial archive are appended as they appear
+	require.NoError(t, os.MkdirAll(filepath.Join(outDir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(outDir, "sub", "second.txt"), []byte("second"), 0644))
+	name, body = next()
+	assert.Equal(t, "out/sub/second.txt", name)
+	assert.Equal(t, "second", body)
+}
+
+func TestGetArchiveFollowSkipsBoxSymlinks(t *testing.T) {
+	setupShareDir(t)
+	shareDir := filepath.Join(config.GetInstance().File.Share, "box-1")
+	outDir := filepath.Join(shareDir, "watched")
+	require.NoError(t, os.MkdirAll(outDir, 0755))
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "shadow")
+	require.NoError(t, os.WriteFile(secret, []byte("root:$6$hash"), 0600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(shareDir, "escape")))
+	t.Cleanup(func() {
+		os.RemoveAll(outDir)
+		os.Remove(filepath.Join(shareDir, "escape"))
+	})
+
+	var initial bytes.Buffer
+	tw := tar.NewWriter(&initial)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "watched/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.Close())
+	stat, _ := json.Marshal(map[string]interface{}{"name": "watched", "mode": uint32(os.ModeDir | 0755), "mtime": time.Now()})
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"GET /containers/c1/archive": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
+			w.Write(initial.Bytes())
+		},
+	}}
+	svc := newTestService(t, daemon)
+
+	// A directory linked out of the share directory cannot be followed
+	_, _, err := svc.GetArchive(context.Background(), "box-1", &model.BoxArchiveGetParams{Path: "/var/gbox/share/escape", Follow: true})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, archive, err := svc.GetArchive(ctx, "box-1", &model.BoxArchiveGetParams{Path: "/var/gbox/share/watched", Follow: true})
+	require.NoError(t, err)
+	defer archive.Close()
+	tr := tar.NewReader(archive)
+	hdr, err := tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "watched/", hdr.Name)
+
+	// The box links a host file into the followed directory, then writes a real one
+	require.NoError(t, os.Symlink(secret, filepath.Join(outDir, "shadow")))
+	time.Sleep(2 * followBatchInterval)
+	require.NoError(t, os.WriteFile(filepath.Join(outDir, "real.txt"), []byte("real"), 0644))
+	hdr, err = tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "watched/real.txt", hdr.Name, "the planted link must be skipped")
+	body, err := io.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Equal(t, "real", string(body))
+}
+
+func TestGetArchiveFollowOutsideShareDir(t *testing.T) {
+	setupShareDir(t)
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+	}}
+	svc := newTestService(t, daemon)
+
+	for _, p := range []string{"/tmp", "/var/gbox/shared", "/var/gbox/share/../../etc"} {
+		_, _, err := svc.GetArchive(context.Background(), "box-1", &model.BoxArchiveGetParams{Path: p, Follow: true})
+		assert.ErrorIs(t, err, service.ErrInvalidParams, p)
+	}
+	assert.NotContains(t, daemon.Calls(), "GET /containers/c1/archive")
+}
+
+// newHostExecDaemon fakes a running box whose execs run on the test host and
+// whose archive uploads are extracted there, failing with failExtract after
+// the first entry when it is set
+func newHostExecDaemon(t *testing.T, failExtract *bool) *fakeDaemon {
+	var cmd []string
+	var exitCode int
+	return &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"POST /containers/c1/exec": func(w http.ResponseWriter, r *http.Request) {
+			var body struct{ Cmd []string }
+			json.NewDecoder(r.Body).Decode(&body)
+			cmd = body.Cmd
+			writeJSON(map[string]string{"Id": "exec-1"})(w, r)
+		},
+		"POST /exec/exec-1/start": func(w http.ResponseWriter, r *http.Request) {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+
+			var stderr bytes.Buffer
+			run := exec.Command(cmd[0], cmd[1:]...)
+			run.Stdout = stdcopy.NewStdWriter(conn, stdcopy.Stdout)
+			run.Stderr = &stderr
+			exitCode = 0
+			if err := run.Run(); err != nil {
+				exitCode = 1
+			}
+			stdcopy.NewStdWriter(conn, stdcopy.Stderr).Write(stderr.Bytes())
+		},
+		"GET /exec/exec-1/json": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(map[string]interface{}{"Running": false, "ExitCode": exitCode})(w, r)
+		},
+		"PUT /containers/c1/archive": func(w http.ResponseWriter, r *http.Request) {
+			dir := r.URL.Query().Get("path")
+			tr := tar.NewReader(r.Body)
+			for i := 0; ; i++ {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				if *failExtract && i == 1 {
+					http.Error(w, `{"message":"write /app/new.txt: no space left on device"}`, http.StatusInternalServerError)
+					return
+				}
+				data, _ := io.ReadAll(tr)
+				require.NoError(t, os.WriteFile(filepath.Join(dir, hdr.Name), data, 0644))
+			}
+			w.WriteHeader(http.StatusOK)
+		},
+	}}
+}
+
+func TestExtractArchiveTransactional(t *testing.T) {
+	parent := t.TempDir()
+	target := filepath.Join(parent, "app")
+	require.NoError(t, os.Mkdir(target, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(target, "config.txt"), []byte("v1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(target, "keep.txt"), []byte("kept"), 0644))
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ name, body string }{
+		{"config.txt", "v2"},
+		{"new.txt", "new"},
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(f.body))}))
+		_, err := tw.Write([]byte(f.body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	failExtract := true
+	svc := newTestService(t, newHostExecDaemon(t, &failExtract))
+	extract := func() error {
+		return svc.ExtractArchive(context.Background(), "box-1", &model.BoxArchiveExtractParams{
+			Path:          target,
+			Content:       buf.Bytes(),
+			Transactional: true,
+		})
+	}
+	readFile := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(target, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+	assertNoStaging := func() {
+		entries, err := os.ReadDir(parent)
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "staging directories must be removed")
+		assert.Equal(t, "app", entries[0].Name())
+	}
+
+	// The extraction fails after overwriting the first file
+	assert.ErrorContains(t, extract(), "no space left on device")
+	assert.Equal(t, "v1", readFile("config.txt"), "the target must be unchanged")
+	assert.NoFileExists(t, filepath.Join(target, "new.txt"))
+	assertNoStaging()
+
+	failExtract = false
+	require.NoError(t, extract())
+	assert.Equal(t, "v2", readFile("config.txt"))
+	assert.Equal(t, "new", readFile("new.txt"))
+	assert.Equal(t, "kept", readFile("keep.txt"), "files not in the archive are kept")
+	assertNoStaging()
+
+	err := svc.ExtractArchive(context.Background(), "box-1", &model.BoxArchiveExtractParams{Path: "/", Transactional: true})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
@@ -0,0 +1,167 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	"github.com/babelcloud/gbox/packages/api-server/pkg/id"
+)
+
+// groupNetworkName returns the docker network name for a compose group
+func groupNetworkName(groupID string) string {
+	return fmt.Sprintf("gbox-group-%s", groupID)
+}
+
+// Compose implements Service.Compose
+func (s *Service) Compose(ctx context.Context, params *model.BoxComposeParams) (*model.BoxComposeResult, error) {
+	services, err := composeStartOrder(params.Services)
+	if err != nil {
+		return nil, err
+	}
+
+	groupID := id.GenerateBoxID()
+	networkName := groupNetworkName(groupID)
+	if _, err := s.client.NetworkCreate(ctx, networkName, types.NetworkCreate{
+		Driver: "bridge",
+		Labels: map[string]string{
+			labelName:  "gbox",
+			labelGroup: groupID,
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create group network: %w", err)
+	}
+
+	result := &model.BoxComposeResult{
+		GroupID: groupID,
+		Network: networkName,
+	}
+	for _, svc := range services {
+		box, err := s.createLinuxBox(ctx, &model.LinuxAndroidBoxCreateParam{
+			Type: "linux",
+			Config: model.CreateBoxConfigParam{
+				Envs: svc.Envs,
+				Cmd:  svc.Cmd,
+			},
+		}, boxCreateOptions{
+			image:   svc.Image,
+			network: networkName,
+			aliases: []string{svc.Name},
+			labels: map[string]string{
+				labelGroup:        groupID,
+				labelGroupService: svc.Name,
+			},
+		})
+		if err != nil {
+			// Don't leave a partial group behind
+			s.removeGroup(context.Background(), groupID)
+			return nil, fmt.Errorf("failed to create service %s: %w", svc.Name, err)
+		}
+		result.Boxes = append(result.Boxes, *box)
+	}
+
+	return result, nil
+}
+
+// composeStartOrder validates the services and orders them so that every
+// service comes after the services it depends on. Services without
+// dependencies between them keep their request order.
+func composeStartOrder(services []model.ComposeService) ([]model.ComposeService, error) {
+	if len(services) == 0 {
+		return nil, fmt.Errorf("%w: at least one service is required", service.ErrInvalidParams)
+	}
+
+	byName := make(map[string]model.ComposeService, len(services))
+	for _, svc := range services {
+		if !dnsLabelPattern.MatchString(svc.Name) {
+			return nil, fmt.Errorf("%w: invalid service name %q", service.ErrInvalidParams, svc.Name)
+		}
+		if _, dup := byName[svc.Name]; dup {
+			return nil, fmt.Errorf("%w: duplicate service name %q", service.ErrInvalidParams, svc.Name)
+		}
+		byName[svc.Name] = svc
+	}
+	for _, svc := range services {
+		for _, dep := range svc.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("%w: service %q depends on unknown service %q", service.ErrInvalidParams, svc.Name, dep)
+			}
+		}
+	}
+
+	ordered := make([]model.ComposeService, 0, len(services))
+	started := make(map[string]bool, len(services))
+	for len(ordered) < len(services) {
+		progressed := false
+		for _, svc := range services {
+			if started[svc.Name] || !dependenciesStarted(svc, started) {
+				continue
+			}
+			ordered = append(ordered, svc)
+			started[svc.Name] = true
+			progressed = true
+		}
+		if !progressed {
+			return nil, fmt.Errorf("%w: services have circular dependencies", service.ErrInvalidParams)
+		}
+	}
+	return ordered, nil
+}
+
+func dependenciesStarted(svc model.ComposeService, started map[string]bool) bool {
+	for _, dep := range svc.DependsOn {
+		if !started[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// removeGroup force-removes every box of a compose group and its network
+func (s *Service) removeGroup(ctx context.Context, groupID string) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", fmt.Sprintf("%s=%s", labelGroup, groupID))
+
+	containers, err := s.client.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filterArgs,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to list boxes of group %s: %v", groupID, err)
+	}
+	for _, c := range containers {
+		if err := s.client.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{Force: true}); err != nil {
+			s.logger.Warn("Failed to remove box %s of group %s: %v", c.Labels[labelID], groupID, err)
+			continue
+		}
+		s.accessTracker.Remove(c.Labels[labelID])
+		s.execSessions.removeBox(c.Labels[labelID])
+	}
+
+	if err := s.client.NetworkRemove(ctx, groupNetworkName(groupID)); err != nil {
+		s.logger.Warn("Failed to remove network of group %s: %v", groupID, err)
+	}
+}
+
+// removeGroupNetworks removes the networks of all compose groups. It is used
+// once every box has been deleted, so no network is still in use.
+func (s *Service) removeGroupNetworks(ctx context.Context) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", fmt.Sprintf("%s=gbox", labelName))
+	filterArgs.Add("label", labelGroup)
+
+	networks, err := s.client.NetworkList(ctx, types.NetworkListOptions{Filters: filterArgs})
+	if err != nil {
+		s.logger.Warn("Failed to list group networks: %v", err)
+		return
+	}
+	for _, n := range networks {
+		if err := s.client.NetworkRemove(ctx, n.ID); err != nil {
+			s.logger.Warn("Failed to remove group network %s: %v", n.Name, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+type createdContainer struct {
+	Labels     map[string]string
+	Cmd        []string
+	HostConfig struct {
+		NetworkMode string
+	}
+	NetworkingConfig struct {
+		EndpointsConfig map[string]struct {
+			Aliases []string
+		}
+	}
+}
+
+func TestComposeCreatesGroupOnSharedNetwork(t *testing.T) {
+	setupShareDir(t)
+
+	var (
+		mu          sync.Mutex
+		networkName string
+		created     []createdContainer
+		started     []string
+	)
+	containerIDs := []string{"c1", "c2"}
+	daemon := &fakeDaemon{
+		handlers: map[string]http.HandlerFunc{
+			"GET /images/" + GetImage("") + "/json": writeJSON(map[string]interface{}{"Id": "img"}),
+			"GET /images/postgres:16/json":          writeJSON(map[string]interface{}{"Id": "pg"}),
+			"POST /networks/create": func(w http.ResponseWriter, r *http.Request) {
+				var req struct{ Name string }
+				json.NewDecoder(r.Body).Decode(&req)
+				networkName = req.Name
+				w.WriteHeader(http.StatusCreated)
+				writeJSON(map[string]string{"Id": "net1"})(w, r)
+			},
+			"POST /containers/create": func(w http.ResponseWriter, r *http.Request) {
+				var c createdContainer
+				json.NewDecoder(r.Body).Decode(&c)
+				mu.Lock()
+				id := containerIDs[len(created)]
+				created = append(created, c)
+				mu.Unlock()
+				w.WriteHeader(http.StatusCreated)
+				writeJSON(map[string]string{"Id": id})(w, r)
+			},
+		},
+		inspect: map[string]interface{}{
+			"Id":     "c1",
+			"State":  map[string]interface{}{"Status": "running"},
+			"Config": map[string]interface{}{"Labels": map[string]string{labelID: "box-1"}},
+		},
+	}
+	for _, id := range containerIDs {
+		id := id
+		daemon.handlers["POST /containers/"+id+"/start"] = func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			started = append(started, id)
+			mu.Unlock()
+			noContent(w, r)
+		}
+	}
+	svc := newTestService(t, daemon)
+
+	result, err := svc.Compose(context.Background(), &model.BoxComposeParams{Services: []model.ComposeService{
+		{Name: "app", Cmd: []string{"python3", "app.py"}, DependsOn: []string{"db"}},
+		{Name: "db", Image: "postgres:16", Envs: map[string]string{"POSTGRES_PASSWORD": "secret"}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, result.Boxes, 2)
+	assert.Equal(t, groupNetworkName(result.GroupID), result.Network)
+	assert.Equal(t, result.Network, networkName)
+
+	// db is created and started before app, which depends on it
+	require.Len(t, created, 2)
+	assert.Equal(t, []string{"c1", "c2"}, started)
+	assert.Equal(t, "db", created[0].Labels[labelGroupService])
+	assert.Equal(t, "app", created[1].Labels[labelGroupService])
+	assert.Nil(t, created[0].Cmd, "custom images keep their own command")
+
+	for _, c := range created {
+		assert.Equal(t, result.GroupID, c.Labels[labelGroup])
+		assert.Equal(t, result.Network, c.HostConfig.NetworkMode)
+		require.Contains(t, c.NetworkingConfig.EndpointsConfig, result.Network)
+		assert.Equal(t, []string{c.Labels[labelGroupService]}, c.NetworkingConfig.EndpointsConfig[result.Network].Aliases)
+	}
+}
+
+func TestComposeRejectsInvalidSpecs(t *testing.T) {
+	daemon := &fakeDaemon{}
+	svc := newTestService(t, daemon)
+
+	for name, services := range map[string][]model.ComposeService{
+		"empty":     nil,
+		"duplicate": {{Name: "a"}, {Name: "a"}},
+		"unknown":   {{Name: "a", DependsOn: []string{"b"}}},
+		"cycle":     {{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}},
+		"bad name":  {{Name: "not_a_hostname"}},
+	} {
+		_, err := svc.Compose(context.Background(), &model.BoxComposeParams{Services: services})
+		assert.ErrorIs(t, err, service.ErrInvalidParams, name)
+	}
+	assert.Empty(t, daemon.Calls())
+}
@@ -0,0 +1,105 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// defaultDependsOnTimeout bounds the wait for a dependency box when the
+// create request does not set one
+const defaultDependsOnTimeout = time.Minute
+
+// dependencyPollInterval is how often the status of a dependency box is
+// checked while a create waits for it
+var dependencyPollInterval = time.Second
+
+// boxDependency is a validated depends-on request
+type boxDependency struct {
+	boxID   string
+	timeout time.Duration
+}
+
+// parseDependency validates the depends-on options of a create request. It
+// returns nil when the box does not depend on another.
+func parseDependency(cfg model.CreateBoxConfigParam) (*boxDependency, error) {
+	if cfg.DependsOn == "" {
+		if cfg.DependsOnTimeout != "" {
+			return nil, fmt.Errorf("%w: dependsOnTimeout requires dependsOn", service.ErrInvalidParams)
+		}
+		return nil, nil
+	}
+
+	timeout := defaultDependsOnTimeout
+	if cfg.DependsOnTimeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(cfg.DependsOnTimeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("%w: invalid dependsOnTimeout %q", service.ErrInvalidParams, cfg.DependsOnTimeout)
+		}
+	}
+	return &boxDependency{boxID: cfg.DependsOn, timeout: timeout}, nil
+}
+
+// waitForDependency polls the dependency box until it is running and, when
+// it has a health check, healthy, or until the timeout elapses
+func (s *Service) waitForDependency(ctx context.Context, dep *boxDependency) error {
+	ctx, cancel := context.WithTimeout(ctx, dep.timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(dependencyPollInterval)
+	defer ticker.Stop()
+
+	status := "unknown"
+	for {
+		containerInfo, err := s.inspectContainerByID(ctx, dep.boxID)
+		switch {
+		case errors.Is(err, service.ErrBoxNotFound):
+			return fmt.Errorf("%w: dependency box %s not found", service.ErrInvalidParams, dep.boxID)
+		case err == nil:
+			if dependencyReady(containerInfo.State) {
+				s.logger.Debug("Dependency box %s is ready", dep.boxID)
+				return nil
+			}
+			status = dependencyStatus(containerInfo.State)
+		case ctx.Err() == nil:
+			return fmt.Errorf("failed to check dependency box %s: %w", dep.boxID, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("dependency box %s was not ready within %s, last status: %s", dep.boxID, dep.timeout, status)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// dependencyReady reports whether a dependency box may be relied on: it is
+// running and, when its image defines a health check, healthy
+func dependencyReady(state *types.ContainerState) bool {
+	if state == nil || !state.Running {
+		return false
+	}
+	return state.Health == nil || state.Health.Status == types.Healthy
+}
+
+// dependencyStatus describes the state of a dependency box that is not yet
+// ready, for the timeout error
+func dependencyStatus(state *types.ContainerState) string {
+	if state == nil {
+		return "unknown"
+	}
+	if state.Running && state.Health != nil {
+		return state.Health.Status
+	}
+	return state.Status
+}
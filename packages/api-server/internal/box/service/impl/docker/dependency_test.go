@@ -0,0 +1,102 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+func TestCreateLinuxBoxWaitsForDependency(t *testing.T) {
+	setupShareDir(t)
+	orig := dependencyPollInterval
+	dependencyPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { dependencyPollInterval = orig })
+
+	// Box A is still starting until the test marks it running
+	var running atomic.Bool
+	var polls atomic.Int32
+	daemon := newCreateDaemon(&struct{}{})
+	daemon.handlers["GET /containers/gbox-box-a/json"] = func(w http.ResponseWriter, r *http.Request) {
+		polls.Add(1)
+		status := "created"
+		if running.Load() {
+			status = "running"
+		}
+		writeJSON(map[string]interface{}{
+			"Id":     "a1",
+			"State":  map[string]interface{}{"Status": status, "Running": running.Load()},
+			"Config": map[string]interface{}{"Labels": map[string]string{labelID: "box-a"}},
+		})(w, r)
+	}
+	svc := newTestService(t, daemon)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+			DependsOn: "box-a",
+		}})
+		done <- err
+	}()
+
+	require.Eventually(t, func() bool { return polls.Load() >= 3 }, 5*time.Second, 5*time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("create returned before the dependency was running: %v", err)
+	default:
+	}
+	assert.Equal(t, -1, indexOf(daemon.Calls(), "POST /containers/create"), "box B must not be created while box A is starting")
+
+	running.Store(true)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("create did not finish after the dependency started running")
+	}
+	assert.NotEqual(t, -1, indexOf(daemon.Calls(), "POST /containers/c1/start"))
+}
+
+func TestCreateLinuxBoxDependencyFailures(t *testing.T) {
+	setupShareDir(t)
+	orig := dependencyPollInterval
+	dependencyPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { dependencyPollInterval = orig })
+
+	daemon := newCreateDaemon(&struct{}{})
+	daemon.handlers["GET /containers/gbox-box-a/json"] = writeJSON(map[string]interface{}{
+		"Id": "a1",
+		"State": map[string]interface{}{
+			"Status":  "running",
+			"Running": true,
+			"Health":  map[string]interface{}{"Status": "unhealthy"},
+		},
+	})
+	svc := newTestService(t, daemon)
+
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		DependsOn:        "box-a",
+		DependsOnTimeout: "100ms",
+	}})
+	assert.ErrorContains(t, err, "was not ready within 100ms, last status: unhealthy")
+	assert.Equal(t, -1, indexOf(daemon.Calls(), "POST /containers/create"))
+
+	// Only box A exists
+	daemon.inspect = nil
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		DependsOn: "box-missing",
+	}})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		DependsOnTimeout: "1m",
+	}})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-units"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+)
+
+// dockerOptSetter applies a single raw docker option value to a host config
+type dockerOptSetter func(hostConfig *container.HostConfig, value string) error
+
+// allowedDockerOpts lists the HostConfig fields that may be set through the
+// raw dockerOpts escape hatch. Options that would widen a box's access to the
+// host (privileged, host namespaces, capabilities, devices) are deliberately
+// absent.
+//
+//	shm-size     HostConfig.ShmSize, e.g. "1g"
+//	pids-limit   HostConfig.PidsLimit, e.g. "512"
+//	cpu-shares   HostConfig.CPUShares, e.g. "512"
+//	cpuset-cpus  HostConfig.CpusetCpus, e.g. "0-3"
+//	init         HostConfig.Init, "true" or "false"
+//	read-only    HostConfig.ReadonlyRootfs, "true" or "false"
+var allowedDockerOpts = map[string]dockerOptSetter{
+	"shm-size": func(hc *container.HostConfig, value string) error {
+		size, err := units.RAMInBytes(value)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("invalid size %q", value)
+		}
+		hc.ShmSize = size
+		return nil
+	},
+	"pids-limit": func(hc *container.HostConfig, value string) error {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid limit %q", value)
+		}
+		hc.PidsLimit = &limit
+		return nil
+	},
+	"cpu-shares": func(hc *container.HostConfig, value string) error {
+		shares, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || shares < 0 {
+			return fmt.Errorf("invalid shares %q", value)
+		}
+		hc.CPUShares = shares
+		return nil
+	},
+	"cpuset-cpus": func(hc *container.HostConfig, value string) error {
+		if strings.Trim(value, "0123456789,-") != "" {
+			return fmt.Errorf("invalid cpu set %q", value)
+		}
+		hc.CpusetCpus = value
+		return nil
+	},
+	"init": func(hc *container.HostConfig, value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		hc.Init = &enabled
+		return nil
+	},
+	"read-only": func(hc *container.HostConfig, value string) error {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		hc.ReadonlyRootfs = enabled
+		return nil
+	},
+}
+
+// supportedDockerOpts returns the allowlisted option names in sorted order
+func supportedDockerOpts() []string {
+	names := make([]string, 0, len(allowedDockerOpts))
+	for name := range allowedDockerOpts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyDockerOpts sets allowlisted raw docker options on the host config
+func applyDockerOpts(hostConfig *container.HostConfig, opts map[string]string) error {
+	for key, value := range opts {
+		setter, ok := allowedDockerOpts[key]
+		if !ok {
+			return fmt.Errorf("%w: docker option %q is not allowed (supported: %s)",
+				service.ErrInvalidParams, key, strings.Join(supportedDockerOpts(), ", "))
+		}
+		if err := setter(hostConfig, value); err != nil {
+			return fmt.Errorf("%w: docker option %s: %v", service.ErrInvalidParams, key, err)
+		}
+	}
+	return nil
+}
+
+// dockerSocketPath is where the Docker socket lives, on the host and in a box
+const dockerSocketPath = "/var/run/docker.sock"
+
+// dockerSocketMount returns the bind mount of the host Docker socket, if the
+// request asks for it and the server permits it
+func (s *Service) dockerSocketMount(requested bool) (*mount.Mount, error) {
+	if !requested {
+		return nil, nil
+	}
+	if !s.allowDockerSocketMount {
+		return nil, fmt.Errorf("%w: mounting the Docker socket is disabled on this server", service.ErrForbidden)
+	}
+	return &mount.Mount{Type: mount.TypeBind, Source: dockerSocketPath, Target: dockerSocketPath}, nil
+}
+
+// validateDockerOpts checks raw docker options before any resources are created
+func (s *Service) validateDockerOpts(opts map[string]string) error {
+	if len(opts) == 0 {
+		return nil
+	}
+	if !s.allowRawDockerOpts {
+		return fmt.Errorf("%w: raw docker options are disabled on this server", service.ErrInvalidParams)
+	}
+	return applyDockerOpts(&container.HostConfig{}, opts)
+}
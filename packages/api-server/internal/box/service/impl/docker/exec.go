@@ -4,12 +4,16 @@ import (
 	"bufio"
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
 
 	"github.com/babelcloud/gbox/packages/api-server/internal/common"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
@@ -39,31 +43,23 @@ func (s *Service) Exec(ctx context.Context, id string, req *model.BoxExecParams)
 		}
 	}
 
-	// Set working directory
-	workingDir := common.DefaultWorkDirPath
-	if req.WorkingDir != "" {
-		workingDir = req.WorkingDir
-	}
-
-	// Convert envs to []string
-	envs := make([]string, 0, len(req.Envs))
-	for k, v := range req.Envs {
-		envs = append(envs, fmt.Sprintf("%s=%s", k, v))
+	// Reject bad output paths up front, but only create the files once the
+	// exec exists, so a failed exec does not truncate them
+	for _, path := range []string{req.StdoutFile, req.StderrFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := resolveSharePath(id, path); err != nil {
+			return nil, err
+		}
 	}
 
-	// Create exec configuration (non-interactive)
-	execConfig := types.ExecConfig{
-		User:         "", // Use default user
-		Privileged:   false,
-		Tty:          false, // Non-interactive
-		AttachStdin:  false, // No stdin for non-interactive
-		AttachStdout: true,
-		AttachStderr: true,
-		Detach:       false,
-		DetachKeys:   "", // Use default detach keys
-		Env:          envs,
-		WorkingDir:   workingDir,
-		Cmd:          req.Commands,
+	execConfig := createExecConfig(req, boxShell(containerInfo.Labels))
+	execConfig.AttachStdin = req.Input != ""
+	if req.CleanEnv {
+		if err := s.applyCleanEnv(ctx, containerInfo.ID, &execConfig); err != nil {
+			return nil, err
+		}
 	}
 
 	// Create exec instance
@@ -72,6 +68,12 @@ func (s *Service) Exec(ctx context.Context, id string, req *model.BoxExecParams)
 		return nil, fmt.Errorf("failed to create exec: %w", err)
 	}
 
+	output, err := openExecOutput(id, req)
+	if err != nil {
+		return nil, err
+	}
+	defer output.Close()
+
 	// Attach to exec instance
 	attachResp, err := s.client.ContainerExecAttach(ctx, execResp.ID, types.ExecStartCheck{
 		Detach: false,
@@ -82,8 +84,23 @@ func (s *Service) Exec(ctx context.Context, id string, req *model.BoxExecParams)
 	}
 	defer attachResp.Close()
 
-	// Collect output
-	stdout, stderr := s.collectOutput(attachResp.Reader, -1, -1)
+	if execConfig.AttachStdin {
+		go func() {
+			io.WriteString(attachResp.Conn, req.Input)
+			attachResp.CloseWrite()
+		}()
+	}
+
+	// Collect output, or write it to the requested share directory files
+	var stdout, stderr string
+	if output.redirected() {
+		if err := output.copy(attachResp.Reader); err != nil {
+			return nil, fmt.Errorf("failed to write command output: %w", err)
+		}
+		stdout, stderr = output.stdout.String(), output.stderr.String()
+	} else {
+		stdout, stderr = s.collectOutput(attachResp.Reader, -1, -1)
+	}
 
 	// Get exit code
 	inspectResp, err := s.client.ContainerExecInspect(ctx, execResp.ID)
@@ -98,6 +115,188 @@ func (s *Service) Exec(ctx context.Context, id string, req *model.BoxExecParams)
 	}, nil
 }
 
+// ExecStream implements Service.ExecStream. It copies the command's stdout
+// to stdout byte-for-byte, without Docker's stream headers, and feeds stdin
+// to the command when req.Stdin is set. Stderr is returned in the result.
+func (s *Service) ExecStream(ctx context.Context, id string, req *model.BoxExecParams, stdin io.Reader, stdout io.Writer) (*model.BoxExecResult, error) {
+	s.accessTracker.Update(id)
+
+	containerInfo, err := s.getContainerByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if containerInfo.State != "running" {
+		return nil, fmt.Errorf("box %s is not running (current state: %s)", id, containerInfo.State)
+	}
+
+	if req.Timeout != "" {
+		if duration, err := time.ParseDuration(req.Timeout); err == nil {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, duration)
+			defer cancel()
+		}
+	}
+
+	execConfig := createExecConfig(req, boxShell(containerInfo.Labels))
+	execConfig.AttachStdin = req.Stdin && stdin != nil
+	if req.CleanEnv {
+		if err := s.applyCleanEnv(ctx, containerInfo.ID, &execConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	execResp, err := s.client.ContainerExecCreate(ctx, containerInfo.ID, execConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attachResp, err := s.client.ContainerExecAttach(ctx, execResp.ID, types.ExecStartCheck{
+		Detach: false,
+		Tty:    false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer attachResp.Close()
+
+	if execConfig.AttachStdin {
+		go func() {
+			io.Copy(attachResp.Conn, stdin)
+			// Let the command see EOF while its output is still read
+			attachResp.CloseWrite()
+		}()
+	}
+
+	var stderr strings.Builder
+	if _, err := stdcopy.StdCopy(stdout, &stderr, attachResp.Reader); err != nil && !isConnectionClosed(err) {
+		return nil, fmt.Errorf("failed to stream command output: %w", err)
+	}
+
+	inspectResp, err := s.client.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+
+	return &model.BoxExecResult{
+		ExitCode: inspectResp.ExitCode,
+		Stderr:   stderr.String(),
+	}, nil
+}
+
+// execOutput holds where each stream of a command is written: a file in the
+// box's share directory when requested, otherwise a buffer that is returned.
+type execOutput struct {
+	stdout, stderr         strings.Builder
+	stdoutFile, stderrFile *os.File
+}
+
+// openExecOutput creates the output files requested by req
+func openExecOutput(boxID string, req *model.BoxExecParams) (*execOutput, error) {
+	output := &execOutput{}
+	var err error
+	if req.StdoutFile != "" {
+		if output.stdoutFile, err = createShareFile(boxID, req.StdoutFile); err != nil {
+			return nil, err
+		}
+	}
+	if req.StderrFile != "" {
+		if req.StderrFile == req.StdoutFile {
+			output.stderrFile = output.stdoutFile
+		} else if output.stderrFile, err = createShareFile(boxID, req.StderrFile); err != nil {
+			output.Close()
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+func (o *execOutput) redirected() bool {
+	return o.stdoutFile != nil || o.stderrFile != nil
+}
+
+// copy demultiplexes a Docker stream into the files and buffers
+func (o *execOutput) copy(reader io.Reader) error {
+	var stdout, stderr io.Writer = &o.stdout, &o.stderr
+	if o.stdoutFile != nil {
+		stdout = o.stdoutFile
+	}
+	if o.stderrFile != nil {
+		stderr = o.stderrFile
+	}
+	_, err := stdcopy.StdCopy(stdout, stderr, reader)
+	return err
+}
+
+// Close closes the output files
+func (o *execOutput) Close() error {
+	var err error
+	if o.stdoutFile != nil {
+		err = o.stdoutFile.Close()
+	}
+	if o.stderrFile != nil && o.stderrFile != o.stdoutFile {
+		if closeErr := o.stderrFile.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// createExecConfig creates the non-interactive exec configuration for a command
+func createExecConfig(req *model.BoxExecParams, shell string) types.ExecConfig {
+	// Set working directory
+	workingDir := common.DefaultWorkDirPath
+	if req.WorkingDir != "" {
+		workingDir = req.WorkingDir
+	}
+
+	// Convert envs to []string
+	envs := make([]string, 0, len(req.Envs))
+	for k, v := range req.Envs {
+		envs = append(envs, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Shell form runs the commands as one script of the box's shell
+	cmd := req.Commands
+	if req.Shell {
+		cmd = []string{shell, "-c", strings.Join(req.Commands, " ")}
+	}
+
+	return types.ExecConfig{
+		User:         "", // Use default user
+		Privileged:   false,
+		Tty:          false, // Non-interactive
+		AttachStdin:  false, // No stdin for non-interactive
+		AttachStdout: true,
+		AttachStderr: true,
+		Detach:       false,
+		DetachKeys:   "", // Use default detach keys
+		Env:          envs,
+		WorkingDir:   workingDir,
+		Cmd:          cmd,
+	}
+}
+
+// applyCleanEnv makes config run its command with only the variables of
+// config.Env and the container's PATH. Docker always adds the container's
+// environment to an exec, so the command is wrapped in `env -i` instead.
+func (s *Service) applyCleanEnv(ctx context.Context, containerID string, config *types.ExecConfig) error {
+	inspect, err := s.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %w", err)
+	}
+	path := defaultExecPath
+	if inspect.Config != nil {
+		for _, kv := range inspect.Config.Env {
+			if value, ok := strings.CutPrefix(kv, "PATH="); ok {
+				path = value
+			}
+		}
+	}
+	config.Cmd = cleanEnvArgv(path, config.Env, config.Cmd)
+	config.Env = nil
+	return nil
+}
+
 // readDockerStream reads from a Docker stream and returns stdout and stderr content
 func readDockerStream(reader io.Reader) (string, string, error) {
 	header := make([]byte, 8)
@@ -168,6 +367,16 @@ func (s *Service) collectOutput(reader io.Reader, stdoutLimit, stderrLimit int)
 	return stdout, stderr
 }
 
+// collectCombinedOutput reads a multiplexed stream into a single string,
+// keeping frames in the order they arrived
+func (s *Service) collectCombinedOutput(reader io.Reader) string {
+	var output strings.Builder
+	if _, err := stdcopy.StdCopy(&output, &output, reader); err != nil {
+		s.logger.Error("Error reading Docker stream: %v", err)
+	}
+	return output.String()
+}
+
 // RunCode implements Service.RunCode
 func (s *Service) RunCode(ctx context.Context, id string, req *model.BoxRunCodeParams) (*model.BoxRunCodeResult, error) {
 	// Update access time on run
@@ -219,9 +428,20 @@ func (s *Service) prepareRunCodeCommand(req *model.BoxRunCodeParams) ([]string,
 	// Add argv to cmd
 	cmd = append(cmd, req.Argv...)
 
+	if req.CombineOutput {
+		cmd = combineOutputCommand(cmd)
+	}
+
 	return cmd, stdin, nil
 }
 
+// combineOutputCommand wraps cmd so its stderr is redirected into stdout.
+// Sharing one pipe keeps the relative order of writes to both streams, which
+// separate pipes cannot guarantee.
+func combineOutputCommand(cmd []string) []string {
+	return append([]string{"sh", "-c", `exec "$0" "$@" 2>&1`}, cmd...)
+}
+
 // executeRunCode executes the prepared command and collects results
 func (s *Service) executeRunCode(ctx context.Context, containerID string, cmd []string, stdin string, req *model.BoxRunCodeParams) (*model.BoxRunCodeResult, error) {
 	// Create exec configuration
@@ -243,7 +463,7 @@ func (s *Service) executeRunCode(ctx context.Context, containerID string, cmd []
 	defer attachResp.Close()
 
 	// Handle stdin and collect output
-	return s.handleRunCodeExecution(ctx, execResp.ID, attachResp, stdin)
+	return s.handleRunCodeExecution(ctx, execResp.ID, attachResp, stdin, req.CombineOutput, req.Output)
 }
 
 // createRunCodeExecConfig creates the exec configuration for running code
@@ -276,7 +496,8 @@ func (s *Service) createRunCodeExecConfig(cmd []string, stdin string, req *model
 }
 
 // handleRunCodeExecution handles the execution, stdin writing, and output collection
-func (s *Service) handleRunCodeExecution(ctx context.Context, execID string, attachResp types.HijackedResponse, stdin string) (*model.BoxRunCodeResult, error) {
+// Output is streamed to events instead of collected when events is set.
+func (s *Service) handleRunCodeExecution(ctx context.Context, execID string, attachResp types.HijackedResponse, stdin string, combineOutput bool, events io.Writer) (*model.BoxRunCodeResult, error) {
 	// Use a single channel for coordination
 	type executionResult struct {
 		stdout   string
@@ -300,7 +521,14 @@ func (s *Service) handleRunCodeExecution(ctx context.Context, execID string, att
 		}
 
 		// Collect output
-		stdout, stderr := s.collectOutput(attachResp.Reader, -1, -1)
+		var stdout, stderr string
+		if events != nil {
+			s.streamRunCodeOutput(attachResp.Reader, events)
+		} else if combineOutput {
+			stdout = s.collectCombinedOutput(attachResp.Reader)
+		} else {
+			stdout, stderr = s.collectOutput(attachResp.Reader, -1, -1)
+		}
 
 		// Get exit code
 		exitCode, err := s.getExecExitCode(ctx, execID)
@@ -332,6 +560,53 @@ func (s *Service) handleRunCodeExecution(ctx context.Context, execID string, att
 	}
 }
 
+// streamRunCodeOutput writes a BoxRunCodeEvent to events for every chunk
+// of the multiplexed Docker stream in reader as it arrives
+func (s *Service) streamRunCodeOutput(reader io.Reader, events io.Writer) {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(events)
+	stdout := &runCodeEventWriter{mu: &mu, encoder: encoder, status: model.RunCodeEventStdout}
+	stderr := &runCodeEventWriter{mu: &mu, encoder: encoder, status: model.RunCodeEventStderr}
+	if _, err := stdcopy.StdCopy(stdout, stderr, reader); err != nil {
+		s.logger.Error("Error reading Docker stream: %v", err)
+	}
+	stdout.flush()
+	stderr.flush()
+}
+
+// runCodeEventWriter encodes each write as an output event of one stream.
+// A character split across writes is held back until it is complete.
+type runCodeEventWriter struct {
+	mu      *sync.Mutex
+	encoder *json.Encoder
+	status  string
+	partial []byte
+}
+
+func (w *runCodeEventWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data := append(w.partial, p...)
+	complete := completeUTF8Len(data)
+	w.partial = append([]byte(nil), data[complete:]...)
+	if complete > 0 {
+		if err := w.encoder.Encode(model.BoxRunCodeEvent{Status: w.status, Data: string(data[:complete])}); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flush writes held back bytes once the stream has ended
+func (w *runCodeEventWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) > 0 {
+		w.encoder.Encode(model.BoxRunCodeEvent{Status: w.status, Data: string(w.partial)})
+		w.partial = nil
+	}
+}
+
 // writeStdinForRunCode writes stdin data and closes the write end
 func (s *Service) writeStdinForRunCode(writer io.Writer, stdin string) error {
 	if _, err := io.WriteString(writer, stdin); err != nil {
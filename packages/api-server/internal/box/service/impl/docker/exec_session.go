@@ -0,0 +1,542 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gorilla/websocket"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	"github.com/babelcloud/gbox/packages/api-server/pkg/id"
+)
+
+// maxReplayBytes bounds the recent output an interactive session keeps to
+// replay when a client re-attaches
+const maxReplayBytes = 64 * 1024
+
+// attachQueueLen bounds the output chunks queued for an attached client. A
+// client that falls this far behind is disconnected so it cannot stall the
+// command; it can re-attach and get the replay buffer.
+const attachQueueLen = 64
+
+// attachWriteTimeout bounds sending one chunk of output to an attached client
+var attachWriteTimeout = 10 * time.Second
+
+// maxSessionOutputBytes bounds the output a non-interactive session keeps.
+// Older output is dropped, so a client tailing too slowly misses it, but a
+// long-running job cannot exhaust the server's memory.
+const maxSessionOutputBytes = 4 * 1024 * 1024
+
+// execSessionTTL is how long a finished session is kept for its client to
+// read the rest of its output and exit code
+var execSessionTTL = 10 * time.Minute
+
+// execSessionMarkerEnv is set in the environment of a session's command so
+// its processes, and the children that inherit it, can be found to kill them
+const execSessionMarkerEnv = "GBOX_EXEC_SESSION"
+
+// killExecSessionScript kills every process of a box whose environment holds
+// the marker given as $1. Docker cannot kill an exec, and the PID it reports
+// is in the host's namespace, so the processes are looked up in the box.
+const killExecSessionScript = `for p in /proc/[0-9]*; do
+  if tr '\0' '\n' 2>/dev/null < "$p/environ" | grep -qxF "$1"; then
+    kill -KILL "${p#/proc/}" 2>/dev/null
+  fi
+done
+true`
+
+// killExecSessionTimeout bounds killing a session and waiting for it to end
+const killExecSessionTimeout = 10 * time.Second
+
+// execSession holds the state and buffered output of a detached exec
+type execSession struct {
+	mu      sync.Mutex
+	info    model.BoxExecSession
+	output  bytes.Buffer
+	dropped int64         // Bytes of output dropped from the start of output
+	marker  string        // Value of execSessionMarkerEnv in the command's environment
+	done    chan struct{} // Closed once the command has finished
+
+	// Interactive sessions only; recent is the replay buffer, stdin the
+	// exec's input and attached the client currently receiving output
+	recent   []byte
+	stdin    net.Conn
+	attached *execAttachment
+}
+
+// execAttachment is a client connected to an interactive session. Output is
+// queued on out and sent by the client's own goroutine, so the session never
+// waits on the network.
+type execAttachment struct {
+	out   chan []byte
+	close func()
+}
+
+// Write appends command output to the session buffer
+func (e *execSession) Write(p []byte) (int, error) {
+	e.mu.Lock()
+	if !e.info.Interactive {
+		defer e.mu.Unlock()
+		e.output.Write(p)
+		if over := e.output.Len() - maxSessionOutputBytes; over > 0 {
+			e.output.Next(over)
+			e.dropped += int64(over)
+		}
+		return len(p), nil
+	}
+
+	e.recent = append(e.recent, p...)
+	if over := len(e.recent) - maxReplayBytes; over > 0 {
+		e.recent = append(e.recent[:0], e.recent[over:]...)
+	}
+	var slow *execAttachment
+	if e.attached != nil {
+		select {
+		case e.attached.out <- append([]byte(nil), p...):
+		default:
+			// The client cannot keep up; keep the session running for a re-attach
+			slow = e.attached
+			e.attached = nil
+		}
+	}
+	e.mu.Unlock()
+
+	if slow != nil {
+		slow.close()
+	}
+	return len(p), nil
+}
+
+// attach sets a as the client receiving output, queueing recent output to
+// replay to it first. A client that was still attached is disconnected.
+func (e *execSession) attach(a *execAttachment) {
+	e.mu.Lock()
+	previous := e.attached
+	e.attached = a
+	if len(e.recent) > 0 {
+		a.out <- append([]byte(nil), e.recent...)
+	}
+	e.mu.Unlock()
+
+	if previous != nil {
+		previous.close()
+	}
+}
+
+// detach stops sending output to a if it is still the attached client
+func (e *execSession) detach(a *execAttachment) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.attached == a {
+		e.attached = nil
+	}
+}
+
+// finish records the final state of the session
+func (e *execSession) finish(exitCode int, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.info.Running = false
+	e.info.ExitCode = exitCode
+	e.info.FinishedAt = time.Now()
+	if err != nil {
+		e.info.Error = err.Error()
+	}
+	if e.done != nil {
+		close(e.done)
+	}
+}
+
+// snapshot returns the session state with the output written since offset.
+// Offsets count all output, including any already dropped; output dropped
+// since offset is skipped.
+func (e *execSession) snapshot(offset int64) *model.BoxExecSession {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	info := e.info
+	buf := e.output.Bytes()
+	end := e.dropped + int64(len(buf))
+	if offset < 0 || offset > end {
+		offset = end
+	}
+	if offset < e.dropped {
+		offset = e.dropped
+	}
+	info.Output = string(buf[offset-e.dropped:])
+	info.Offset = end
+	return &info
+}
+
+// execSessionStore tracks detached exec sessions by ID
+type execSessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*execSession
+}
+
+func newExecSessionStore() *execSessionStore {
+	return &execSessionStore{sessions: make(map[string]*execSession)}
+}
+
+func (st *execSessionStore) add(sess *execSession) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.sessions[sess.info.ID] = sess
+}
+
+// expire removes a finished session once its client had execSessionTTL to
+// read it
+func (st *execSessionStore) expire(id string) {
+	time.AfterFunc(execSessionTTL, func() {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		delete(st.sessions, id)
+	})
+}
+
+// removeBox removes the sessions of a deleted box
+func (st *execSessionStore) removeBox(boxID string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for id, sess := range st.sessions {
+		if sess.info.BoxID == boxID {
+			delete(st.sessions, id)
+		}
+	}
+}
+
+func (st *execSessionStore) get(id string) (*execSession, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	sess, ok := st.sessions[id]
+	return sess, ok
+}
+
+// running returns the sessions of a box whose command is still running,
+// oldest first
+func (st *execSessionStore) running(boxID string) []*execSession {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	var sessions []*execSession
+	for _, sess := range st.sessions {
+		sess.mu.Lock()
+		running := sess.info.BoxID == boxID && sess.info.Running
+		sess.mu.Unlock()
+		if running {
+			sessions = append(sessions, sess)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].info.StartedAt.Before(sessions[j].info.StartedAt)
+	})
+	return sessions
+}
+
+// withExecSessionMarker returns a marker for a new session and sets it in the
+// environment of the session's command
+func withExecSessionMarker(execConfig *types.ExecConfig) string {
+	marker := id.GenerateBoxID()
+	execConfig.Env = append(execConfig.Env, execSessionMarkerEnv+"="+marker)
+	return marker
+}
+
+// ExecDetached implements Service.ExecDetached. The command keeps running and
+// its output keeps being collected after the request that started it ends.
+func (s *Service) ExecDetached(ctx context.Context, id string, req *model.BoxExecParams) (*model.BoxExecSession, error) {
+	s.accessTracker.Update(id)
+
+	containerInfo, err := s.getContainerByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if containerInfo.State != "running" {
+		return nil, fmt.Errorf("box %s is not running (current state: %s)", id, containerInfo.State)
+	}
+
+	execConfig := createExecConfig(req, boxShell(containerInfo.Labels))
+	if req.CleanEnv {
+		if err := s.applyCleanEnv(ctx, containerInfo.ID, &execConfig); err != nil {
+			return nil, err
+		}
+	}
+	marker := withExecSessionMarker(&execConfig)
+	execResp, err := s.client.ContainerExecCreate(ctx, containerInfo.ID, execConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	// The session outlives the request, so it must not inherit its context
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if duration, err := time.ParseDuration(req.Timeout); err == nil && req.Timeout != "" {
+		runCtx, cancel = context.WithTimeout(context.Background(), duration)
+	} else {
+		runCtx, cancel = context.WithCancel(context.Background())
+	}
+
+	attachResp, err := s.client.ContainerExecAttach(runCtx, execResp.ID, types.ExecStartCheck{})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+
+	sess := &execSession{
+		info: model.BoxExecSession{
+			ID:        execResp.ID,
+			BoxID:     id,
+			Commands:  req.Commands,
+			Running:   true,
+			StartedAt: time.Now(),
+		},
+		marker: marker,
+		done:   make(chan struct{}),
+	}
+	s.execSessions.add(sess)
+
+	go func() {
+		defer cancel()
+		defer attachResp.Close()
+
+		if _, err := stdcopy.StdCopy(sess, sess, attachResp.Reader); err != nil && !isConnectionClosed(err) {
+			s.logger.Warn("Error reading output of exec session %s: %v", sess.info.ID, err)
+		}
+		exitCode, err := s.getExecExitCode(context.Background(), execResp.ID)
+		sess.finish(exitCode, err)
+		s.execSessions.expire(sess.info.ID)
+		s.accessTracker.Update(id)
+	}()
+
+	return sess.snapshot(0), nil
+}
+
+// GetExecSession implements Service.GetExecSession
+func (s *Service) GetExecSession(ctx context.Context, id string, sessionID string, offset int64) (*model.BoxExecSession, error) {
+	sess, ok := s.execSessions.get(sessionID)
+	if !ok || sess.info.BoxID != id {
+		return nil, service.ErrExecSessionNotFound
+	}
+	return sess.snapshot(offset), nil
+}
+
+// ListExecSessions implements Service.ListExecSessions
+func (s *Service) ListExecSessions(ctx context.Context, id string) (*model.BoxExecSessionListResult, error) {
+	sessions := s.execSessions.running(id)
+	result := &model.BoxExecSessionListResult{Data: make([]model.BoxExecSession, 0, len(sessions)), Total: len(sessions)}
+	for _, sess := range sessions {
+		info := sess.snapshot(-1)
+		info.Output, info.Offset = "", 0
+		result.Data = append(result.Data, *info)
+	}
+	return result, nil
+}
+
+// KillExecSession implements Service.KillExecSession. The session's processes
+// are killed and the session is returned once it has finished; a session
+// that already finished is returned as is.
+func (s *Service) KillExecSession(ctx context.Context, id string, sessionID string) (*model.BoxExecSession, error) {
+	sess, ok := s.execSessions.get(sessionID)
+	if !ok || sess.info.BoxID != id {
+		return nil, service.ErrExecSessionNotFound
+	}
+	if info := sess.snapshot(-1); !info.Running {
+		return info, nil
+	}
+
+	containerInfo, err := s.getContainerByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cmd := []string{"/bin/sh", "-c", killExecSessionScript, "sh", execSessionMarkerEnv + "=" + sess.marker}
+	if _, err := s.execAndWait(ctx, containerInfo.ID, cmd, killExecSessionTimeout); err != nil {
+		return nil, fmt.Errorf("failed to kill exec session %s: %w", sessionID, err)
+	}
+
+	select {
+	case <-sess.done:
+	case <-time.After(killExecSessionTimeout):
+		return nil, fmt.Errorf("exec session %s did not finish after it was killed", sessionID)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	s.logger.Info("Killed exec session %s of box %s", sessionID, id)
+	return sess.snapshot(-1), nil
+}
+
+// execWSDetached starts an interactive exec that keeps running when the
+// WebSocket drops, then serves wsConn as its first attached client.
+func (s *Service) execWSDetached(ctx context.Context, id string, containerID string, execConfig types.ExecConfig, wsConn *websocket.Conn) (*model.BoxExecResult, error) {
+	marker := withExecSessionMarker(&execConfig)
+	execResp, err := s.client.ContainerExecCreate(ctx, containerID, execConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	// The session outlives the connection, so it must not inherit its context
+	attachResp, err := s.client.ContainerExecAttach(context.Background(), execResp.ID, types.ExecStartCheck{Tty: execConfig.Tty})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+
+	sess := &execSession{
+		info: model.BoxExecSession{
+			ID:          execResp.ID,
+			BoxID:       id,
+			Commands:    execConfig.Cmd,
+			Running:     true,
+			StartedAt:   time.Now(),
+			Interactive: true,
+			TTY:         execConfig.Tty,
+		},
+		marker: marker,
+		stdin:  attachResp.Conn,
+		done:   make(chan struct{}),
+	}
+	s.execSessions.add(sess)
+
+	go func() {
+		defer attachResp.Close()
+
+		var err error
+		if execConfig.Tty {
+			_, err = io.Copy(sess, attachResp.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(sess, sess, attachResp.Reader)
+		}
+		if err != nil && !isConnectionClosed(err) {
+			s.logger.Warn("Error reading output of exec session %s: %v", sess.info.ID, err)
+		}
+		exitCode, err := s.getExecExitCode(context.Background(), execResp.ID)
+		sess.finish(exitCode, err)
+		s.execSessions.expire(sess.info.ID)
+		s.accessTracker.Update(id)
+	}()
+
+	// Tell the client which session to re-attach to if the connection drops
+	if err := wsConn.WriteJSON(map[string]string{"event": "session", "id": sess.info.ID}); err != nil {
+		return nil, fmt.Errorf("failed to send exec session ID: %w", err)
+	}
+	return s.serveExecAttachment(ctx, sess, wsConn)
+}
+
+// AttachExecSession implements Service.AttachExecSession
+func (s *Service) AttachExecSession(ctx context.Context, id string, sessionID string, wsConn *websocket.Conn) (*model.BoxExecResult, error) {
+	sess, ok := s.execSessions.get(sessionID)
+	if !ok || sess.info.BoxID != id {
+		return nil, service.ErrExecSessionNotFound
+	}
+	if !sess.info.Interactive {
+		return nil, service.ErrExecSessionNotInteractive
+	}
+	s.accessTracker.Update(id)
+	return s.serveExecAttachment(ctx, sess, wsConn)
+}
+
+// serveExecAttachment relays wsConn's input to the session and the session's
+// output to wsConn until the command finishes or the client goes away. A
+// dropped client leaves the command running and yields a nil result.
+func (s *Service) serveExecAttachment(ctx context.Context, sess *execSession, wsConn *websocket.Conn) (*model.BoxExecResult, error) {
+	gone := make(chan struct{})
+	var goneOnce sync.Once
+	attachment := &execAttachment{
+		out: make(chan []byte, attachQueueLen),
+		close: func() {
+			goneOnce.Do(func() { close(gone) })
+			wsConn.Close()
+		},
+	}
+	sess.attach(attachment)
+	defer sess.detach(attachment)
+
+	// Session output -> client; the only writer of wsConn from here on. Once
+	// the command has finished, the queued output is sent before closing.
+	finished := make(chan struct{})
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		send := func(messageType int, p []byte) bool {
+			wsConn.SetWriteDeadline(time.Now().Add(attachWriteTimeout))
+			if err := wsConn.WriteMessage(messageType, p); err != nil {
+				attachment.close()
+				return false
+			}
+			return true
+		}
+		for {
+			select {
+			case p := <-attachment.out:
+				if !send(websocket.BinaryMessage, p) {
+					return
+				}
+			case <-finished:
+				for {
+					select {
+					case p := <-attachment.out:
+						if !send(websocket.BinaryMessage, p) {
+							return
+						}
+					default:
+						send(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "Command finished"))
+						return
+					}
+				}
+			case <-gone:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Client input -> exec stdin
+	go func() {
+		defer goneOnce.Do(func() { close(gone) })
+		for {
+			messageType, message, err := wsConn.ReadMessage()
+			if err != nil {
+				return
+			}
+			switch messageType {
+			case websocket.BinaryMessage:
+				if _, err := sess.stdin.Write(message); err != nil && !isConnectionClosed(err) {
+					s.logger.Warn("Error writing to exec session %s: %v", sess.info.ID, err)
+				}
+			case websocket.TextMessage:
+				var controlMsg map[string]string
+				if json.Unmarshal(message, &controlMsg) == nil && controlMsg["type"] == "stdin_eof" {
+					if closeWriter, ok := sess.stdin.(interface{ CloseWrite() error }); ok {
+						closeWriter.CloseWrite()
+					}
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-sess.done:
+		info := sess.snapshot(-1)
+		close(finished)
+		<-sent
+		return &model.BoxExecResult{ExitCode: info.ExitCode}, nil
+	case <-gone:
+		s.logger.Info("Client detached from exec session %s; command keeps running", sess.info.ID)
+		return nil, nil
+	case <-ctx.Done():
+		return nil, nil
+	}
+}
+
+// writerFunc adapts a function to io.Writer
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
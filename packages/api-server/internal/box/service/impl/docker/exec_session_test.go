@@ -0,0 +1,399 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+func TestExecDetachedContinuesAfterClientCloses(t *testing.T) {
+	finish := make(chan struct{})
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"POST /containers/c1/exec": writeJSON(map[string]string{"Id": "exec-1"}),
+		"POST /exec/exec-1/start": func(w http.ResponseWriter, r *http.Request) {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+
+			stdout := stdcopy.NewStdWriter(conn, stdcopy.Stdout)
+			stdout.Write([]byte("started\n"))
+			// The command keeps running after the client has gone away
+			<-finish
+			stdout.Write([]byte("done\n"))
+		},
+		"GET /exec/exec-1/json": writeJSON(map[string]interface{}{"Running": false, "ExitCode": 3}),
+	}}
+	svc := newTestService(t, daemon)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session, err := svc.ExecDetached(ctx, "box-1", &model.BoxExecParams{Commands: []string{"long-task"}})
+	require.NoError(t, err)
+	assert.True(t, session.Running)
+
+	// Simulate the client stream closing
+	cancel()
+	close(finish)
+
+	var final *model.BoxExecSession
+	require.Eventually(t, func() bool {
+		final, err = svc.GetExecSession(context.Background(), "box-1", session.ID, 0)
+		return err == nil && !final.Running
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "started\ndone\n", final.Output)
+	assert.Equal(t, 3, final.ExitCode)
+	assert.Empty(t, final.Error)
+
+	// Polling from the returned offset yields no repeated output
+	next, err := svc.GetExecSession(context.Background(), "box-1", session.ID, final.Offset)
+	require.NoError(t, err)
+	assert.Empty(t, next.Output)
+}
+
+// newExecSessionsDaemon fakes detached execs that run until they are
+// killed. Killing runs the kill script as a separate exec, which ends the
+// execs carrying the marker it is given.
+func newExecSessionsDaemon(ids ...string) *fakeDaemon {
+	var mu sync.Mutex
+	markers := map[string]string{}       // exec ID -> marker env of its command
+	killed := map[string]chan struct{}{} // exec ID -> closed once killed
+	for _, id := range ids {
+		killed[id] = make(chan struct{})
+	}
+	next := 0
+
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"POST /containers/c1/exec": func(w http.ResponseWriter, r *http.Request) {
+			var body types.ExecConfig
+			json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			defer mu.Unlock()
+			if len(body.Cmd) == 5 && body.Cmd[2] == killExecSessionScript {
+				for id, marker := range markers {
+					if marker == body.Cmd[4] {
+						close(killed[id])
+						delete(markers, id)
+					}
+				}
+				writeJSON(map[string]string{"Id": "kill"})(w, r)
+				return
+			}
+			id := ids[next]
+			next++
+			for _, env := range body.Env {
+				if strings.HasPrefix(env, execSessionMarkerEnv+"=") {
+					markers[id] = env
+				}
+			}
+			writeJSON(map[string]string{"Id": id})(w, r)
+		},
+		"POST /exec/kill/start": noContent,
+		"GET /exec/kill/json":   writeJSON(map[string]interface{}{"Running": false, "ExitCode": 0}),
+	}}
+	for _, id := range ids {
+		done := killed[id]
+		daemon.handlers["POST /exec/"+id+"/start"] = func(w http.ResponseWriter, r *http.Request) {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+			<-done
+		}
+		daemon.handlers["GET /exec/"+id+"/json"] = writeJSON(map[string]interface{}{"Running": false, "ExitCode": 137})
+	}
+	return daemon
+}
+
+func TestKillExecSessionLeavesOthersRunning(t *testing.T) {
+	daemon := newExecSessionsDaemon("exec-1", "exec-2")
+	svc := newTestService(t, daemon)
+	t.Cleanup(func() {
+		// Let the remaining session end with the test server
+		svc.KillExecSession(context.Background(), "box-1", "exec-2")
+	})
+
+	first, err := svc.ExecDetached(context.Background(), "box-1", &model.BoxExecParams{Commands: []string{"stuck-task"}})
+	require.NoError(t, err)
+	second, err := svc.ExecDetached(context.Background(), "box-1", &model.BoxExecParams{Commands: []string{"other-task"}})
+	require.NoError(t, err)
+
+	list, err := svc.ListExecSessions(context.Background(), "box-1")
+	require.NoError(t, err)
+	require.Equal(t, 2, list.Total)
+	assert.Equal(t, first.ID, list.Data[0].ID, "sessions are listed oldest first")
+	assert.Equal(t, []string{"stuck-task"}, list.Data[0].Commands)
+	assert.Equal(t, second.ID, list.Data[1].ID)
+
+	killed, err := svc.KillExecSession(context.Background(), "box-1", first.ID)
+	require.NoError(t, err)
+	assert.False(t, killed.Running)
+	assert.Equal(t, 137, killed.ExitCode)
+
+	list, err = svc.ListExecSessions(context.Background(), "box-1")
+	require.NoError(t, err)
+	require.Equal(t, 1, list.Total, "only the killed session should be gone")
+	assert.Equal(t, second.ID, list.Data[0].ID)
+	assert.True(t, list.Data[0].Running)
+
+	_, err = svc.KillExecSession(context.Background(), "box-2", second.ID)
+	assert.ErrorIs(t, err, service.ErrExecSessionNotFound, "a session is only killed through its own box")
+}
+
+// newInteractiveExecDaemon fakes a TTY exec that echoes each input line.
+// The line "later" also makes it print "background" after a short delay, so
+// output can be produced while no client is attached.
+func newInteractiveExecDaemon() *fakeDaemon {
+	return &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"POST /containers/c1/exec": writeJSON(map[string]string{"Id": "exec-1"}),
+		"POST /exec/exec-1/start": func(w http.ResponseWriter, r *http.Request) {
+			// Consume the start request so only stdin remains on the connection
+			io.Copy(io.Discard, r.Body)
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+
+			var writeMu sync.Mutex
+			write := func(s string) {
+				writeMu.Lock()
+				defer writeMu.Unlock()
+				conn.Write([]byte(s))
+			}
+			var background sync.WaitGroup
+			defer background.Wait()
+			lines := bufio.NewScanner(buf.Reader)
+			for lines.Scan() {
+				write(lines.Text() + "\n")
+				if lines.Text() == "later" {
+					background.Add(1)
+					go func() {
+						defer background.Done()
+						time.Sleep(100 * time.Millisecond)
+						write("background\n")
+					}()
+				}
+			}
+		},
+		"GET /exec/exec-1/json": writeJSON(map[string]interface{}{"Running": false, "ExitCode": 0}),
+	}}
+}
+
+// readUntil reads binary messages from conn until the output contains want
+func readUntil(t *testing.T, conn *websocket.Conn, want string) string {
+	t.Helper()
+	var output strings.Builder
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for !strings.Contains(output.String(), want) {
+		messageType, data, err := conn.ReadMessage()
+		require.NoError(t, err, "output so far: %q", output.String())
+		if messageType == websocket.BinaryMessage {
+			output.Write(data)
+		}
+	}
+	return output.String()
+}
+
+func TestExecSessionReconnect(t *testing.T) {
+	svc := newTestService(t, newInteractiveExecDaemon())
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if r.URL.Path == "/start" {
+			svc.ExecWS(r.Context(), "box-1", &model.BoxExecWSParams{Cmd: []string{"sh"}, TTY: true, Detach: true}, conn)
+		} else {
+			svc.AttachExecSession(r.Context(), "box-1", strings.TrimPrefix(r.URL.Path, "/attach/"), conn)
+		}
+	}))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	first, _, err := websocket.DefaultDialer.Dial(wsURL+"/start", nil)
+	require.NoError(t, err)
+	var event map[string]string
+	require.NoError(t, first.ReadJSON(&event))
+	assert.Equal(t, map[string]string{"event": "session", "id": "exec-1"}, event)
+
+	require.NoError(t, first.WriteMessage(websocket.BinaryMessage, []byte("hello\n")))
+	readUntil(t, first, "hello\n")
+
+	// Drop the connection without a close handshake; the command keeps running
+	require.NoError(t, first.WriteMessage(websocket.BinaryMessage, []byte("later\n")))
+	readUntil(t, first, "later\n")
+	first.UnderlyingConn().Close()
+	time.Sleep(200 * time.Millisecond)
+
+	session, err := svc.GetExecSession(context.Background(), "box-1", "exec-1", 0)
+	require.NoError(t, err)
+	assert.True(t, session.Running)
+	assert.True(t, session.Interactive)
+
+	// Re-attaching replays recent output, including what was written while away
+	second, _, err := websocket.DefaultDialer.Dial(wsURL+"/attach/exec-1", nil)
+	require.NoError(t, err)
+	defer second.Close()
+	assert.Equal(t, "hello\nlater\nbackground\n", readUntil(t, second, "background\n"))
+
+	// Input and output continue on the new connection
+	require.NoError(t, second.WriteMessage(websocket.BinaryMessage, []byte("again\n")))
+	assert.Equal(t, "again\n", readUntil(t, second, "again\n"))
+
+	// Closing stdin ends the command and the session closes the connection
+	require.NoError(t, second.WriteJSON(map[string]string{"type": "stdin_eof"}))
+	_, _, err = second.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "unexpected error: %v", err)
+
+	session, err = svc.GetExecSession(context.Background(), "box-1", "exec-1", 0)
+	require.NoError(t, err)
+	assert.False(t, session.Running)
+	assert.Equal(t, 0, session.ExitCode)
+}
+
+func TestAttachExecSessionRequiresInteractiveSession(t *testing.T) {
+	svc := newTestService(t, &fakeDaemon{})
+	svc.execSessions.add(&execSession{info: model.BoxExecSession{ID: "exec-9", BoxID: "box-1", Running: true}})
+
+	_, err := svc.AttachExecSession(context.Background(), "box-1", "exec-9", nil)
+	assert.ErrorIs(t, err, service.ErrExecSessionNotInteractive)
+	_, err = svc.AttachExecSession(context.Background(), "box-2", "exec-9", nil)
+	assert.ErrorIs(t, err, service.ErrExecSessionNotFound)
+}
+
+func TestExecSessionDropsStalledClient(t *testing.T) {
+	sess := &execSession{info: model.BoxExecSession{ID: "exec-1", BoxID: "box-1", Running: true, Interactive: true}}
+	closed := make(chan struct{})
+	// Nothing ever reads the queue, as with a client that stopped reading
+	sess.attach(&execAttachment{out: make(chan []byte, attachQueueLen), close: func() { close(closed) }})
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for i := 0; i <= attachQueueLen; i++ {
+			sess.Write([]byte("line\n"))
+		}
+		sess.Write([]byte("end\n"))
+	}()
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("output collection blocked on a stalled client")
+	}
+	<-closed
+
+	// The session keeps running and its recent output stays available to a re-attach
+	assert.True(t, sess.snapshot(-1).Running)
+	replay := &execAttachment{out: make(chan []byte, attachQueueLen), close: func() {}}
+	sess.attach(replay)
+	assert.True(t, strings.HasSuffix(string(<-replay.out), "line\nend\n"))
+}
+
+func TestExecSessionOutputIsCapped(t *testing.T) {
+	sess := &execSession{info: model.BoxExecSession{ID: "exec-1", BoxID: "box-1", Running: true}}
+	chunk := []byte(strings.Repeat("x", 1023) + "\n")
+	total := 0
+	for total <= maxSessionOutputBytes+64*1024 {
+		n, err := sess.Write(chunk)
+		require.NoError(t, err)
+		total += n
+	}
+	sess.Write([]byte("end\n"))
+	total += 4
+
+	// Offsets keep counting all output while only the most recent is kept
+	snap := sess.snapshot(0)
+	assert.Equal(t, int64(total), snap.Offset)
+	assert.Len(t, snap.Output, maxSessionOutputBytes)
+	assert.True(t, strings.HasSuffix(snap.Output, "x\nend\n"))
+	assert.Equal(t, "end\n", sess.snapshot(int64(total-4)).Output)
+	assert.Empty(t, sess.snapshot(snap.Offset).Output)
+}
+
+func TestExecSessionsAreRemoved(t *testing.T) {
+	orig := execSessionTTL
+	execSessionTTL = 50 * time.Millisecond
+	t.Cleanup(func() { execSessionTTL = orig })
+
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"POST /containers/c1/exec": writeJSON(map[string]string{"Id": "exec-1"}),
+		"POST /exec/exec-1/start": func(w http.ResponseWriter, r *http.Request) {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+			stdcopy.NewStdWriter(conn, stdcopy.Stdout).Write([]byte("done\n"))
+		},
+		"GET /exec/exec-1/json": writeJSON(map[string]interface{}{"Running": false, "ExitCode": 0}),
+	}}
+	svc := newTestService(t, daemon)
+
+	// A finished session is kept for its client to read, then forgotten
+	session, err := svc.ExecDetached(context.Background(), "box-1", &model.BoxExecParams{Commands: []string{"task"}})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, err := svc.GetExecSession(context.Background(), "box-1", session.ID, 0)
+		return errors.Is(err, service.ErrExecSessionNotFound)
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Deleting a box drops its sessions, running or not
+	svc.execSessions.add(&execSession{info: model.BoxExecSession{ID: "s1", BoxID: "box-1", Running: true}})
+	svc.execSessions.add(&execSession{info: model.BoxExecSession{ID: "s2", BoxID: "box-2", Running: true}})
+	svc.execSessions.removeBox("box-1")
+	_, ok := svc.execSessions.get("s1")
+	assert.False(t, ok)
+	_, ok = svc.execSessions.get("s2")
+	assert.True(t, ok)
+}
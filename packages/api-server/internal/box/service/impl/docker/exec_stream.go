@@ -0,0 +1,113 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// stdHeaderLen is the size of the header of a multiplexed stream frame
+const stdHeaderLen = 8
+
+// copyExecOutput relays the output of an exec to dst as it is produced. A
+// multiplexed non-TTY stream is relayed frame by frame, each frame in one
+// write, so a frame reaches the client as soon as it is demultiplexed rather
+// than whenever a read happens to fill up. With lineBuffered, output is only
+// written once a line is complete, and the rest when the stream ends.
+func copyExecOutput(dst io.Writer, src io.Reader, tty, lineBuffered bool) error {
+	if tty {
+		if !lineBuffered {
+			_, err := io.Copy(dst, src)
+			return err
+		}
+		lines := &lineWriter{write: dst.Write}
+		if _, err := io.Copy(lines, src); err != nil {
+			return err
+		}
+		return lines.flush()
+	}
+
+	// Partial lines are held per stream so stdout and stderr never mix
+	streams := map[stdcopy.StdType]*lineWriter{}
+	writeFrame := func(stream stdcopy.StdType) func([]byte) (int, error) {
+		return func(payload []byte) (int, error) {
+			frame := make([]byte, stdHeaderLen+len(payload))
+			frame[0] = byte(stream)
+			binary.BigEndian.PutUint32(frame[4:], uint32(len(payload)))
+			copy(frame[stdHeaderLen:], payload)
+			if _, err := dst.Write(frame); err != nil {
+				return 0, err
+			}
+			return len(payload), nil
+		}
+	}
+
+	header := make([]byte, stdHeaderLen)
+	for {
+		if _, err := io.ReadFull(src, header); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		stream := stdcopy.StdType(header[0])
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(src, payload); err != nil {
+			return err
+		}
+
+		if !lineBuffered {
+			if _, err := writeFrame(stream)(payload); err != nil {
+				return err
+			}
+			continue
+		}
+		lines := streams[stream]
+		if lines == nil {
+			lines = &lineWriter{write: writeFrame(stream)}
+			streams[stream] = lines
+		}
+		if _, err := lines.Write(payload); err != nil {
+			return err
+		}
+	}
+
+	for _, stream := range []stdcopy.StdType{stdcopy.Stdout, stdcopy.Stderr, stdcopy.Systemerr} {
+		if lines := streams[stream]; lines != nil {
+			if err := lines.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lineWriter passes on complete lines, holding back a trailing partial line
+// until a later write completes it or flush is called
+type lineWriter struct {
+	write   func([]byte) (int, error)
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	if end := bytes.LastIndexByte(w.partial, '\n'); end >= 0 {
+		if _, err := w.write(w.partial[:end+1]); err != nil {
+			return 0, err
+		}
+		w.partial = append([]byte(nil), w.partial[end+1:]...)
+	}
+	return len(p), nil
+}
+
+// flush passes on a held back partial line once the output has ended
+func (w *lineWriter) flush() error {
+	if len(w.partial) == 0 {
+		return nil
+	}
+	_, err := w.write(w.partial)
+	w.partial = nil
+	return err
+}
@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chanWriter hands every write to a channel so a test can see when output
+// was passed on
+type chanWriter chan []byte
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+// nextWrite returns the next write, failing when none arrives promptly
+func nextWrite(t *testing.T, writes chanWriter) []byte {
+	t.Helper()
+	select {
+	case p := <-writes:
+		return p
+	case <-time.After(time.Second):
+		t.Fatal("output was not passed on while the command was running")
+		return nil
+	}
+}
+
+// assertNoWrite fails when output was passed on
+func assertNoWrite(t *testing.T, writes chanWriter) {
+	t.Helper()
+	select {
+	case p := <-writes:
+		t.Fatalf("unexpected output %q", p)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// demux returns the payload of a single multiplexed frame
+func demux(t *testing.T, frame []byte) (stdcopy.StdType, string) {
+	t.Helper()
+	require.GreaterOrEqual(t, len(frame), stdHeaderLen)
+	return stdcopy.StdType(frame[0]), string(frame[stdHeaderLen:])
+}
+
+func TestCopyExecOutputRelaysEachFrame(t *testing.T) {
+	src, pw := io.Pipe()
+	writes := make(chanWriter, 10)
+	done := make(chan error, 1)
+	go func() { done <- copyExecOutput(writes, src, false, false) }()
+
+	stdout := stdcopy.NewStdWriter(pw, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(pw, stdcopy.Stderr)
+
+	// Each line arrives while the stream is still open
+	stdout.Write([]byte("step 1\n"))
+	stream, data := demux(t, nextWrite(t, writes))
+	assert.Equal(t, stdcopy.Stdout, stream)
+	assert.Equal(t, "step 1\n", data)
+
+	stderr.Write([]byte("warn\n"))
+	stream, data = demux(t, nextWrite(t, writes))
+	assert.Equal(t, stdcopy.Stderr, stream)
+	assert.Equal(t, "warn\n", data)
+
+	stdout.Write([]byte("progress 50%"))
+	_, data = demux(t, nextWrite(t, writes))
+	assert.Equal(t, "progress 50%", data, "without line buffering partial lines are sent at once")
+
+	pw.Close()
+	require.NoError(t, <-done)
+}
+
+func TestCopyExecOutputLineBuffered(t *testing.T) {
+	src, pw := io.Pipe()
+	writes := make(chanWriter, 10)
+	done := make(chan error, 1)
+	go func() { done <- copyExecOutput(writes, src, false, true) }()
+
+	stdout := stdcopy.NewStdWriter(pw, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(pw, stdcopy.Stderr)
+
+	stdout.Write([]byte("compiling"))
+	assertNoWrite(t, writes)
+	stderr.Write([]byte("warn\n"))
+	stream, data := demux(t, nextWrite(t, writes))
+	assert.Equal(t, stdcopy.Stderr, stream)
+	assert.Equal(t, "warn\n", data, "streams are buffered separately")
+
+	stdout.Write([]byte(" done\nlinking"))
+	stream, data = demux(t, nextWrite(t, writes))
+	assert.Equal(t, stdcopy.Stdout, stream)
+	assert.Equal(t, "compiling done\n", data)
+	assertNoWrite(t, writes)
+
+	// The rest is sent once the command ends
+	pw.Close()
+	require.NoError(t, <-done)
+	_, data = demux(t, nextWrite(t, writes))
+	assert.Equal(t, "linking", data)
+}
+
+func TestCopyExecOutputTTYLineBuffered(t *testing.T) {
+	src, pw := io.Pipe()
+	writes := make(chanWriter, 10)
+	done := make(chan error, 1)
+	go func() { done <- copyExecOutput(writes, src, true, true) }()
+
+	pw.Write([]byte("$ ls"))
+	assertNoWrite(t, writes)
+	pw.Write([]byte("\r\nfile\r\n"))
+	assert.Equal(t, "$ ls\r\nfile\r\n", string(nextWrite(t, writes)))
+
+	pw.Close()
+	require.NoError(t, <-done)
+}
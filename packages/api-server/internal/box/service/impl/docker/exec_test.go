@@ -0,0 +1,441 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// newRunCodeDaemon fakes a running box whose exec writes interleaved stdout
+// and stderr frames, recording the command each exec was created with.
+func newRunCodeDaemon(cmds *[][]string) *fakeDaemon {
+	return &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"POST /containers/c1/exec": func(w http.ResponseWriter, r *http.Request) {
+			var body struct{ Cmd []string }
+			json.NewDecoder(r.Body).Decode(&body)
+			*cmds = append(*cmds, body.Cmd)
+			writeJSON(map[string]string{"Id": "exec-1"})(w, r)
+		},
+		"POST /exec/exec-1/start": func(w http.ResponseWriter, r *http.Request) {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+
+			stdout := stdcopy.NewStdWriter(conn, stdcopy.Stdout)
+			stderr := stdcopy.NewStdWriter(conn, stdcopy.Stderr)
+			stdout.Write([]byte("out1\n"))
+			stderr.Write([]byte("err1\n"))
+			stdout.Write([]byte("out2\n"))
+		},
+		"GET /exec/exec-1/json": writeJSON(map[string]interface{}{"Running": false, "ExitCode": 0}),
+	}}
+}
+
+func TestRunCodeSeparatesOutputByDefault(t *testing.T) {
+	var cmds [][]string
+	svc := newTestService(t, newRunCodeDaemon(&cmds))
+
+	result, err := svc.RunCode(context.Background(), "box-1", &model.BoxRunCodeParams{
+		Code:     "echo hi",
+		Language: "bash",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "out1\nout2\n", result.Stdout)
+	assert.Equal(t, "err1\n", result.Stderr)
+	require.Len(t, cmds, 1)
+	assert.Equal(t, []string{"sh", "-c", "echo hi"}, cmds[0])
+}
+
+func TestRunCodeStreamsOutputIncrementally(t *testing.T) {
+	var cmds [][]string
+	daemon := newRunCodeDaemon(&cmds)
+	release := make(chan struct{})
+	daemon.handlers["POST /exec/exec-1/start"] = func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		buf.Flush()
+
+		stdout := stdcopy.NewStdWriter(conn, stdcopy.Stdout)
+		stderr := stdcopy.NewStdWriter(conn, stdcopy.Stderr)
+		stdout.Write([]byte("step 1\n"))
+		// The script sleeps before printing again
+		<-release
+		stderr.Write([]byte("warn\n"))
+		// A character split across two writes
+		stdout.Write([]byte("caf\xc3"))
+		stdout.Write([]byte("\xa9\n"))
+	}
+	daemon.handlers["GET /exec/exec-1/json"] = writeJSON(map[string]interface{}{"Running": false, "ExitCode": 3})
+	svc := newTestService(t, daemon)
+
+	pr, pw := io.Pipe()
+	done := make(chan *model.BoxRunCodeResult, 1)
+	go func() {
+		defer pw.Close()
+		result, err := svc.RunCode(context.Background(), "box-1", &model.BoxRunCodeParams{
+			Code:     "echo 'step 1'; sleep 1; echo warn >&2; echo café",
+			Language: "bash",
+			Output:   pw,
+		})
+		assert.NoError(t, err)
+		done <- result
+	}()
+
+	decoder := json.NewDecoder(pr)
+	next := func() model.BoxRunCodeEvent {
+		var event model.BoxRunCodeEvent
+		require.NoError(t, decoder.Decode(&event))
+		return event
+	}
+
+	// The first chunk arrives while the script is still running
+	assert.Equal(t, model.BoxRunCodeEvent{Status: model.RunCodeEventStdout, Data: "step 1\n"}, next())
+	close(release)
+	assert.Equal(t, model.BoxRunCodeEvent{Status: model.RunCodeEventStderr, Data: "warn\n"}, next())
+	assert.Equal(t, model.BoxRunCodeEvent{Status: model.RunCodeEventStdout, Data: "caf"}, next())
+	assert.Equal(t, model.BoxRunCodeEvent{Status: model.RunCodeEventStdout, Data: "é\n"}, next())
+
+	result := <-done
+	require.NotNil(t, result)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Empty(t, result.Stdout, "streamed output is not collected")
+}
+
+func TestRunCodeCombinesOutputInOrder(t *testing.T) {
+	var cmds [][]string
+	svc := newTestService(t, newRunCodeDaemon(&cmds))
+
+	result, err := svc.RunCode(context.Background(), "box-1", &model.BoxRunCodeParams{
+		Code:          "echo hi",
+		Language:      "bash",
+		Argv:          []string{"arg"},
+		CombineOutput: true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "out1\nerr1\nout2\n", result.Stdout)
+	assert.Empty(t, result.Stderr)
+	// stderr is redirected into stdout inside the box so both share one pipe
+	require.Len(t, cmds, 1)
+	assert.Equal(t, []string{"sh", "-c", `exec "$0" "$@" 2>&1`, "sh", "-c", "echo hi", "arg"}, cmds[0])
+}
+
+func TestExecPreservesArgumentsWithSpaces(t *testing.T) {
+	var cmds [][]string
+	svc := newTestService(t, newRunCodeDaemon(&cmds))
+
+	argv := []string{"echo", "a b", `"quoted" $HOME; ls`}
+	_, err := svc.Exec(context.Background(), "box-1", &model.BoxExecParams{Commands: argv})
+	require.NoError(t, err)
+	require.Len(t, cmds, 1)
+	assert.Equal(t, argv, cmds[0], "arguments must reach the executor unchanged, in exec form")
+
+	// The WebSocket path splits the command into Cmd and Args
+	cmd := make([]string, 1, 4)
+	cmd[0] = "echo"
+	assert.Equal(t, []string{"echo", "a b"}, execArgv(cmd, []string{"a b"}))
+	assert.Equal(t, []string{"echo", "c"}, execArgv(cmd, []string{"c"}))
+	assert.Equal(t, []string{"echo"}, cmd[:1])
+}
+
+func TestExecCleanEnv(t *testing.T) {
+	var cmds [][]string
+	var envs [][]string
+	daemon := newRunCodeDaemon(&cmds)
+	daemon.handlers["GET /containers/c1/json"] = writeJSON(map[string]interface{}{
+		"Id":     "c1",
+		"Config": map[string]interface{}{"Env": []string{"PATH=/opt/bin:/usr/bin", "SECRET=container"}},
+	})
+	daemon.handlers["POST /containers/c1/exec"] = func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Cmd, Env []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		cmds = append(cmds, body.Cmd)
+		envs = append(envs, body.Env)
+		writeJSON(map[string]string{"Id": "exec-1"})(w, r)
+	}
+	svc := newTestService(t, daemon)
+
+	_, err := svc.Exec(context.Background(), "box-1", &model.BoxExecParams{
+		Commands: []string{"printenv"},
+		Envs:     map[string]string{"B": "2", "A": "1"},
+		CleanEnv: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, cmds, 1)
+	assert.Equal(t, []string{"env", "-i", "PATH=/opt/bin:/usr/bin", "A=1", "B=2", "printenv"}, cmds[0])
+	assert.Empty(t, envs[0], "variables must not be passed where docker merges them with the container's")
+	assert.NotContains(t, strings.Join(cmds[0], " "), "SECRET", "container variables must not be visible")
+
+	// Without it the container environment is inherited as before
+	_, err = svc.Exec(context.Background(), "box-1", &model.BoxExecParams{
+		Commands: []string{"printenv"},
+		Envs:     map[string]string{"A": "1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"printenv"}, cmds[1])
+	assert.Equal(t, []string{"A=1"}, envs[1])
+}
+
+func TestExecShellFormUsesBoxShell(t *testing.T) {
+	var cmds [][]string
+	daemon := newRunCodeDaemon(&cmds)
+	svc := newTestService(t, daemon)
+	script := &model.BoxExecParams{Commands: []string{"ls", "|", "wc -l"}, Shell: true}
+
+	// A box created without a shell runs scripts with /bin/sh
+	_, err := svc.Exec(context.Background(), "box-1", script)
+	require.NoError(t, err)
+	require.Len(t, cmds, 1)
+	assert.Equal(t, []string{"/bin/sh", "-c", "ls | wc -l"}, cmds[0])
+
+	daemon.handlers["GET /containers/json"] = writeJSON([]map[string]interface{}{{
+		"Id":     "c1",
+		"State":  "running",
+		"Labels": map[string]string{labelID: "box-1", labelShell: "/bin/bash"},
+	}})
+	_, err = svc.Exec(context.Background(), "box-1", script)
+	require.NoError(t, err)
+	require.Len(t, cmds, 2)
+	assert.Equal(t, []string{"/bin/bash", "-c", "ls | wc -l"}, cmds[1])
+
+	// Exec form is left alone
+	_, err = svc.Exec(context.Background(), "box-1", &model.BoxExecParams{Commands: []string{"ls", "|", "wc -l"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls", "|", "wc -l"}, cmds[2])
+}
+
+func TestCreateLinuxBoxShell(t *testing.T) {
+	setupShareDir(t)
+	var created struct {
+		Cmd    []string
+		Labels map[string]string
+	}
+	svc := newTestService(t, newCreateDaemon(&created))
+
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		Cmd:   []string{"python3 -m http.server"},
+		Shell: "/bin/bash",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "/bin/bash", created.Labels[labelShell])
+	assert.Equal(t, []string{"/bin/bash", "-c", "python3 -m http.server"}, created.Cmd)
+
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		Shell: "bash -e",
+	}})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
+
+func TestExecWritesOutputToShareFiles(t *testing.T) {
+	setupShareDir(t)
+	var cmds [][]string
+	svc := newTestService(t, newRunCodeDaemon(&cmds))
+	shareDir := filepath.Join(config.GetInstance().File.Share, "box-1")
+
+	result, err := svc.Exec(context.Background(), "box-1", &model.BoxExecParams{
+		Commands:   []string{"make"},
+		StdoutFile: "logs/build.out",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Empty(t, result.Stdout, "redirected output is not returned")
+	assert.Equal(t, "err1\n", result.Stderr)
+	content, err := os.ReadFile(filepath.Join(shareDir, "logs", "build.out"))
+	require.NoError(t, err)
+	assert.Equal(t, "out1\nout2\n", string(content))
+
+	// Both streams to one file keep their order
+	result, err = svc.Exec(context.Background(), "box-1", &model.BoxExecParams{
+		Commands:   []string{"make"},
+		StdoutFile: "build.log",
+		StderrFile: "build.log",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Stdout)
+	assert.Empty(t, result.Stderr)
+	content, err = os.ReadFile(filepath.Join(shareDir, "build.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "out1\nerr1\nout2\n", string(content))
+
+	_, err = svc.Exec(context.Background(), "box-1", &model.BoxExecParams{Commands: []string{"make"}, StderrFile: "../box-2/err"})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
+
+func TestExecOutputFilesDoNotFollowBoxSymlinks(t *testing.T) {
+	setupShareDir(t)
+	var cmds [][]string
+	daemon := newRunCodeDaemon(&cmds)
+	svc := newTestService(t, daemon)
+	shareDir := filepath.Join(config.GetInstance().File.Share, "box-1")
+	require.NoError(t, os.MkdirAll(shareDir, 0755))
+
+	// The box links output paths to a host file and a host directory
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "passwd")
+	require.NoError(t, os.WriteFile(secret, []byte("root:x:0:0"), 0644))
+	require.NoError(t, os.Symlink(secret, filepath.Join(shareDir, "linked.out")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(shareDir, "linked")))
+	t.Cleanup(func() {
+		os.Remove(filepath.Join(shareDir, "linked.out"))
+		os.Remove(filepath.Join(shareDir, "linked"))
+	})
+
+	for _, path := range []string{"linked.out", "linked/passwd", "linked/newdir/out"} {
+		_, err := svc.Exec(context.Background(), "box-1", &model.BoxExecParams{Commands: []string{"make"}, StdoutFile: path})
+		assert.Error(t, err, "stdout file %q", path)
+	}
+	data, err := os.ReadFile(secret)
+	require.NoError(t, err)
+	assert.Equal(t, "root:x:0:0", string(data), "the host file must not be overwritten")
+	assert.NoDirExists(t, filepath.Join(outside, "newdir"))
+
+	// A failed exec leaves existing output files alone
+	existing := filepath.Join(shareDir, "kept.out")
+	require.NoError(t, os.WriteFile(existing, []byte("previous run\n"), 0644))
+	daemon.handlers["POST /containers/c1/exec"] = func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"container is restarting"}`, http.StatusConflict)
+	}
+	_, err = svc.Exec(context.Background(), "box-1", &model.BoxExecParams{Commands: []string{"make"}, StdoutFile: "kept.out"})
+	require.Error(t, err)
+	data, err = os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "previous run\n", string(data))
+}
+
+func TestLoginShellArgv(t *testing.T) {
+	assert.Equal(t, []string{"bash", "-l"}, loginShellArgv([]string{"bash"}, "/app", defaultShell))
+	assert.Equal(t, []string{"/bin/zsh", "-l"}, loginShellArgv([]string{"/bin/zsh"}, "/app", defaultShell))
+	assert.Equal(t,
+		[]string{"/bin/sh", "-lc", `cd -- "$0" && exec "$@"`, "/app", "python3", "-i"},
+		loginShellArgv([]string{"python3", "-i"}, "/app", defaultShell))
+	assert.Equal(t,
+		[]string{"/bin/sh", "-lc", `exec "$@"`, "sh", "bash", "-c", "env"},
+		loginShellArgv([]string{"bash", "-c", "env"}, "", defaultShell))
+	assert.Equal(t,
+		[]string{"/bin/bash", "-lc", `exec "$@"`, "sh", "python3"},
+		loginShellArgv([]string{"python3"}, "", "/bin/bash"))
+}
+
+func TestExecWSLoginWrapsCommand(t *testing.T) {
+	var created []types.ExecConfig
+	daemon := newInteractiveExecDaemon()
+	daemon.handlers["POST /containers/c1/exec"] = func(w http.ResponseWriter, r *http.Request) {
+		var config types.ExecConfig
+		json.NewDecoder(r.Body).Decode(&config)
+		created = append(created, config)
+		// Stop before attaching, only the exec config matters
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	svc := newTestService(t, daemon)
+
+	svc.ExecWS(context.Background(), "box-1", &model.BoxExecWSParams{Cmd: []string{"bash"}, TTY: true, Login: true, WorkingDir: "/app"}, nil)
+	svc.ExecWS(context.Background(), "box-1", &model.BoxExecWSParams{Cmd: []string{"bash"}, TTY: true, WorkingDir: "/app"}, nil)
+	require.Len(t, created, 2)
+	assert.Equal(t, []string{"bash", "-l"}, []string(created[0].Cmd))
+	assert.Equal(t, "/app", created[0].WorkingDir, "the working directory is kept")
+	assert.Equal(t, []string{"bash"}, []string(created[1].Cmd), "without login the command is unchanged")
+
+	_, err := svc.ExecWS(context.Background(), "box-1", &model.BoxExecWSParams{Cmd: []string{"bash"}, Login: true}, nil)
+	assert.ErrorIs(t, err, service.ErrInvalidParams, "login requires a TTY")
+	assert.Len(t, created, 2)
+}
+
+// newCatExecDaemon fakes a running box whose exec runs `cat`: it echoes stdin
+// as stdout and reports on stderr when stdin ends. attachStdin records
+// whether the exec was created with stdin attached.
+func newCatExecDaemon(attachStdin *bool) *fakeDaemon {
+	var cmds [][]string
+	daemon := newRunCodeDaemon(&cmds)
+	daemon.handlers["POST /containers/c1/exec"] = func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ AttachStdin bool }
+		json.NewDecoder(r.Body).Decode(&body)
+		*attachStdin = body.AttachStdin
+		writeJSON(map[string]string{"Id": "exec-1"})(w, r)
+	}
+	daemon.handlers["POST /exec/exec-1/start"] = func(w http.ResponseWriter, r *http.Request) {
+		// Consume the start request so only stdin remains on the connection
+		io.Copy(io.Discard, r.Body)
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		buf.Flush()
+
+		if *attachStdin {
+			io.Copy(stdcopy.NewStdWriter(conn, stdcopy.Stdout), buf.Reader)
+		}
+		stdcopy.NewStdWriter(conn, stdcopy.Stderr).Write([]byte("eof\n"))
+	}
+	return daemon
+}
+
+// Test that a raw stream exec feeds stdin to the command and passes its
+// stdout through without Docker's stream headers
+func TestExecStreamRoundTrip(t *testing.T) {
+	var attachStdin bool
+	svc := newTestService(t, newCatExecDaemon(&attachStdin))
+
+	payload := make([]byte, 256*1024)
+	for i := range payload {
+		payload[i] = byte(i % 256)
+	}
+	// Start with bytes that look like a multiplexed stream header
+	copy(payload, []byte{2, 0, 0, 0, 0xff, 0xff, 0xff, 0xff})
+
+	var stdout bytes.Buffer
+	result, err := svc.ExecStream(context.Background(), "box-1",
+		&model.BoxExecParams{Commands: []string{"cat"}, Stdin: true}, bytes.NewReader(payload), &stdout)
+	require.NoError(t, err)
+	assert.True(t, attachStdin)
+	assert.True(t, bytes.Equal(payload, stdout.Bytes()), "raw stream output differs from input")
+	assert.Equal(t, "eof\n", result.Stderr)
+}
+
+// Test that a command's input is written to its stdin, which is then closed
+func TestExecWritesInputToStdin(t *testing.T) {
+	var attachStdin bool
+	svc := newTestService(t, newCatExecDaemon(&attachStdin))
+
+	result, err := svc.Exec(context.Background(), "box-1", &model.BoxExecParams{Commands: []string{"cat"}, Input: "piped\ndata"})
+	require.NoError(t, err)
+	assert.True(t, attachStdin)
+	assert.Equal(t, "piped\ndata", result.Stdout)
+	assert.Equal(t, "eof\n", result.Stderr)
+
+	// Without input, stdin is not attached
+	result, err = svc.Exec(context.Background(), "box-1", &model.BoxExecParams{Commands: []string{"cat"}})
+	require.NoError(t, err)
+	assert.False(t, attachStdin)
+	assert.Empty(t, result.Stdout)
+}
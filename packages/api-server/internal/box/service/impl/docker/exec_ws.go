@@ -11,9 +11,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
 	"github.com/babelcloud/gbox/packages/api-server/internal/common"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/gorilla/websocket"
 )
 
@@ -50,7 +52,7 @@ func (s *Service) ExecWS(ctx context.Context, id string, params *model.BoxExecWS
 		DetachKeys:   "",                // Use default detach keys
 		Env:          nil,               // No additional environment variables for now
 		WorkingDir:   params.WorkingDir, // Use provided or default below
-		Cmd:          append(params.Cmd, params.Args...),
+		Cmd:          execArgv(params.Cmd, params.Args),
 	}
 
 	// Use default working directory if not specified
@@ -58,6 +60,35 @@ func (s *Service) ExecWS(ctx context.Context, id string, params *model.BoxExecWS
 		execConfig.WorkingDir = common.DefaultWorkDirPath
 	}
 
+	if params.Login {
+		if !params.TTY {
+			return nil, fmt.Errorf("%w: login shells require a TTY", service.ErrInvalidParams)
+		}
+		execConfig.Cmd = loginShellArgv(execConfig.Cmd, execConfig.WorkingDir, boxShell(containerInfo.Labels))
+	}
+
+	var recorder *castRecorder
+	if params.Record != "" {
+		if params.Detach {
+			return nil, fmt.Errorf("%w: recording is not supported for detached sessions", service.ErrInvalidParams)
+		}
+		file, err := createShareFile(id, params.Record)
+		if err != nil {
+			return nil, err
+		}
+		if recorder, err = newCastRecorder(file, params.Cols, params.Rows, execConfig.Cmd); err != nil {
+			return nil, err
+		}
+		defer recorder.Close()
+	}
+
+	if params.Detach {
+		if params.LineBuffered {
+			return nil, fmt.Errorf("%w: line buffering is not supported for detached sessions", service.ErrInvalidParams)
+		}
+		return s.execWSDetached(ctx, id, containerInfo.ID, execConfig, wsConn)
+	}
+
 	// Create exec instance
 	execResp, err := s.client.ContainerExecCreate(ctx, containerInfo.ID, execConfig)
 	if err != nil {
@@ -86,9 +117,29 @@ func (s *Service) ExecWS(ctx context.Context, id string, params *model.BoxExecWS
 			s.logger.Debugf("ExecWS [%s]: Docker output stream ended. Goroutine finished.", id)
 		}()
 		var writeErr error
-		// Always copy raw stream directly to websocket using wsWriter helper.
-		// This sends the raw Docker stream (TTY or multiplexed with headers) as binary messages.
-		_, writeErr = io.Copy(&wsWriter{conn: wsConn}, attachResp.Reader)
+		var output io.Reader = attachResp.Reader
+		if recorder != nil {
+			if params.TTY {
+				output = io.TeeReader(output, recorder.output())
+			} else {
+				// Record the demultiplexed output, not the stream frames
+				pr, pw := io.Pipe()
+				demuxed := make(chan struct{})
+				go func() {
+					defer close(demuxed)
+					stdcopy.StdCopy(recorder.output(), recorder.output(), pr)
+					io.Copy(io.Discard, pr)
+				}()
+				defer func() {
+					pw.Close()
+					<-demuxed
+				}()
+				output = io.TeeReader(output, pw)
+			}
+		}
+		// Copy the raw Docker stream (TTY or multiplexed with headers) to the
+		// websocket as binary messages, one per multiplexed frame
+		writeErr = copyExecOutput(&wsWriter{conn: wsConn}, output, params.TTY, params.LineBuffered)
 
 		if writeErr != nil && !isConnectionClosed(writeErr) {
 			s.logger.Errorf("ExecWS [%s]: Error writing to WebSocket: %v", id, writeErr)
@@ -154,6 +205,9 @@ func (s *Service) ExecWS(ctx context.Context, id string, params *model.BoxExecWS
 				}
 
 			case websocket.BinaryMessage:
+				if recorder != nil {
+					recorder.input(message)
+				}
 				// Write binary messages directly to container stdin
 				_, writeErr := attachResp.Conn.Write(message)
 				if writeErr != nil {
@@ -224,6 +278,11 @@ loop:
 	}
 	s.logger.Infof("ExecWS [%s]: Command finished. Exit Code: %d. Final Error recorded: %v", id, exitCode, firstError)
 
+	// Finish the recording before the client is told the command is done
+	if recorder != nil {
+		recorder.Close()
+	}
+
 	// 2. Attempt to send a WebSocket close frame
 	s.logger.Debugf("ExecWS [%s]: Attempting to send WebSocket close frame from server side.", id)
 	// Ignore error here, as the connection might already be closing or closed.
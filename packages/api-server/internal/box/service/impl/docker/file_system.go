@@ -12,6 +12,7 @@ import (
 
 	"github.com/docker/docker/api/types"
 
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
 )
 
@@ -265,6 +266,92 @@ func (s *Service) WriteFile(ctx context.Context, id string, params *model.BoxFil
 	}, nil
 }
 
+// statScript prints the raw mode in hex, size, uid, gid and modification time
+// of $1 without following symlinks, then the target when it is a symlink.
+// The raw mode is parsed instead of %F, whose wording differs between stat
+// implementations.
+const statScript = `stat -c '%f %s %u %g %Y' -- "$1" && if [ -L "$1" ]; then readlink -- "$1"; fi`
+
+// File type bits of a raw stat mode (S_IFMT)
+const (
+	sIFMT   = 0o170000
+	sIFSOCK = 0o140000
+	sIFLNK  = 0o120000
+	sIFREG  = 0o100000
+	sIFBLK  = 0o060000
+	sIFDIR  = 0o040000
+	sIFCHR  = 0o020000
+	sIFIFO  = 0o010000
+)
+
+// StatFile returns the metadata of a path in a box without reading its content
+func (s *Service) StatFile(ctx context.Context, id string, params *model.BoxFileStatParams) (*model.BoxFileStat, error) {
+	if !strings.HasPrefix(params.Path, "/") {
+		return nil, fmt.Errorf("%w: path %q must be absolute", service.ErrInvalidParams, params.Path)
+	}
+
+	result, err := s.Exec(ctx, id, &model.BoxExecParams{
+		Commands: []string{"sh", "-c", statScript, "sh", params.Path},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		if strings.Contains(result.Stderr, "No such file or directory") {
+			return nil, fmt.Errorf("%w: %s", service.ErrPathNotFound, params.Path)
+		}
+		return nil, fmt.Errorf("failed to stat %s: %s", params.Path, strings.TrimSpace(result.Stderr))
+	}
+	return parseStatOutput(params.Path, result.Stdout)
+}
+
+// parseStatOutput parses the output of statScript
+func parseStatOutput(path, output string) (*model.BoxFileStat, error) {
+	line, target, _ := strings.Cut(output, "\n")
+	fields := strings.Fields(line)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("unexpected stat output %q", line)
+	}
+	rawMode, err := strconv.ParseUint(fields[0], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected stat mode %q", fields[0])
+	}
+	var values [4]int64
+	for i, field := range fields[1:] {
+		if values[i], err = strconv.ParseInt(field, 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected stat output %q", line)
+		}
+	}
+
+	stat := &model.BoxFileStat{
+		Path:    path,
+		Size:    values[0],
+		Mode:    fmt.Sprintf("%04o", rawMode&0o7777),
+		UID:     int(values[1]),
+		GID:     int(values[2]),
+		ModTime: time.Unix(values[3], 0).UTC(),
+	}
+	switch rawMode & sIFMT {
+	case sIFDIR:
+		stat.Type = model.FileStatTypeDir
+		stat.IsDir = true
+	case sIFLNK:
+		stat.Type = model.FileStatTypeSymlink
+		stat.LinkTarget = strings.TrimSuffix(target, "\n")
+	case sIFIFO:
+		stat.Type = model.FileStatTypeFifo
+	case sIFSOCK:
+		stat.Type = model.FileStatTypeSocket
+	case sIFCHR:
+		stat.Type = model.FileStatTypeCharDevice
+	case sIFBLK:
+		stat.Type = model.FileStatTypeBlockDevice
+	default:
+		stat.Type = model.FileStatTypeFile
+	}
+	return stat, nil
+}
+
 // parseLsOutput parses the output of ls command and returns BoxFile structs
 func (s *Service) parseLsOutput(output, basePath string) ([]model.BoxFile, error) {
 	lines := strings.Split(strings.TrimSpace(output), "\n")
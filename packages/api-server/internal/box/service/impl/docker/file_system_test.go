@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// newPathExecDaemon fakes a running box whose exec prints the output
+// registered for the last argument of the command, or fails like stat does
+// for a missing path
+func newPathExecDaemon(outputs map[string]string) *fakeDaemon {
+	var path string
+	return &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"POST /containers/c1/exec": func(w http.ResponseWriter, r *http.Request) {
+			var body struct{ Cmd []string }
+			json.NewDecoder(r.Body).Decode(&body)
+			path = body.Cmd[len(body.Cmd)-1]
+			writeJSON(map[string]string{"Id": "exec-1"})(w, r)
+		},
+		"POST /exec/exec-1/start": func(w http.ResponseWriter, r *http.Request) {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+
+			if output, ok := outputs[path]; ok {
+				stdcopy.NewStdWriter(conn, stdcopy.Stdout).Write([]byte(output))
+				return
+			}
+			stdcopy.NewStdWriter(conn, stdcopy.Stderr).Write([]byte("stat: cannot statx '" + path + "': No such file or directory\n"))
+		},
+		"GET /exec/exec-1/json": func(w http.ResponseWriter, r *http.Request) {
+			exitCode := 0
+			if _, ok := outputs[path]; !ok {
+				exitCode = 1
+			}
+			writeJSON(map[string]interface{}{"Running": false, "ExitCode": exitCode})(w, r)
+		},
+	}}
+}
+
+func TestStatFile(t *testing.T) {
+	svc := newTestService(t, newPathExecDaemon(map[string]string{
+		"/etc":      "41ed 4096 0 0 1700000000\n",
+		"/etc/link": "a1ff 11 1000 1000 1700000000\n/etc/target\n",
+		"/etc/file": "81a4 12 0 0 1700000000\n",
+	}))
+	ctx := context.Background()
+
+	dir, err := svc.StatFile(ctx, "box-1", &model.BoxFileStatParams{Path: "/etc"})
+	require.NoError(t, err)
+	assert.Equal(t, model.FileStatTypeDir, dir.Type)
+	assert.True(t, dir.IsDir)
+	assert.Equal(t, "0755", dir.Mode)
+	assert.Equal(t, int64(4096), dir.Size)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), dir.ModTime)
+
+	link, err := svc.StatFile(ctx, "box-1", &model.BoxFileStatParams{Path: "/etc/link"})
+	require.NoError(t, err)
+	assert.Equal(t, model.FileStatTypeSymlink, link.Type)
+	assert.False(t, link.IsDir)
+	assert.Equal(t, "/etc/target", link.LinkTarget)
+	assert.Equal(t, 1000, link.UID)
+	assert.Equal(t, 1000, link.GID)
+
+	file, err := svc.StatFile(ctx, "box-1", &model.BoxFileStatParams{Path: "/etc/file"})
+	require.NoError(t, err)
+	assert.Equal(t, model.FileStatTypeFile, file.Type)
+	assert.Equal(t, "0644", file.Mode)
+	assert.Empty(t, file.LinkTarget)
+
+	_, err = svc.StatFile(ctx, "box-1", &model.BoxFileStatParams{Path: "/missing"})
+	assert.ErrorIs(t, err, service.ErrPathNotFound)
+
+	_, err = svc.StatFile(ctx, "box-1", &model.BoxFileStatParams{Path: "etc"})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
@@ -0,0 +1,123 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// groupNamePattern matches the names accepted for box groups
+var groupNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,62}$`)
+
+// validateGroupName checks a group name is safe to use as a label value and
+// in filters
+func validateGroupName(group string) error {
+	if !groupNamePattern.MatchString(group) {
+		return fmt.Errorf("%w: invalid group name %q", service.ErrInvalidParams, group)
+	}
+	return nil
+}
+
+// listGroupContainers returns the containers of every box in a group
+func (s *Service) listGroupContainers(ctx context.Context, group string) ([]types.Container, error) {
+	if err := validateGroupName(group); err != nil {
+		return nil, err
+	}
+
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", fmt.Sprintf("%s=gbox", labelName))
+	filterArgs.Add("label", fmt.Sprintf("%s=%s", labelGroup, group))
+
+	containers, err := s.client.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filterArgs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list boxes of group %s: %w", group, err)
+	}
+	return containers, nil
+}
+
+// stopGroupContainer stops a running box the same way Stop does, running its
+// pre-stop hook first
+func (s *Service) stopGroupContainer(ctx context.Context, c types.Container) error {
+	if err := s.stopContainer(ctx, c.ID, c.Labels); err != nil {
+		return fmt.Errorf("failed to stop box %s: %w", c.Labels[labelID], err)
+	}
+	return nil
+}
+
+// StopGroup implements Service.StopGroup
+func (s *Service) StopGroup(ctx context.Context, group string) (*model.BoxesStopResult, error) {
+	containers, err := s.listGroupContainers(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	stoppedIDs := []string{}
+	for _, c := range containers {
+		if c.State != "running" {
+			continue
+		}
+		if err := s.stopGroupContainer(ctx, c); err != nil {
+			return nil, err
+		}
+		stoppedIDs = append(stoppedIDs, c.Labels[labelID])
+	}
+
+	return &model.BoxesStopResult{
+		Count:   len(stoppedIDs),
+		Message: "Boxes stopped successfully",
+		IDs:     stoppedIDs,
+	}, nil
+}
+
+// DeleteGroup implements Service.DeleteGroup
+func (s *Service) DeleteGroup(ctx context.Context, group string, req *model.BoxesDeleteParams) (*model.BoxesDeleteResult, error) {
+	containers, err := s.listGroupContainers(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	deletedIDs := []string{}
+	for _, c := range containers {
+		boxID := c.Labels[labelID]
+		if c.State == "running" {
+			if err := s.stopGroupContainer(ctx, c); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.client.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{Force: req.Force}); err != nil {
+			return nil, fmt.Errorf("failed to remove box %s: %w", boxID, err)
+		}
+		deletedIDs = append(deletedIDs, boxID)
+		s.accessTracker.Remove(boxID)
+		s.execSessions.removeBox(boxID)
+	}
+
+	// Groups created by compose also own a network
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", fmt.Sprintf("%s=gbox", labelName))
+	filterArgs.Add("label", fmt.Sprintf("%s=%s", labelGroup, group))
+	networks, err := s.client.NetworkList(ctx, types.NetworkListOptions{Filters: filterArgs})
+	if err != nil {
+		s.logger.Warn("Failed to list networks of group %s: %v", group, err)
+	}
+	for _, n := range networks {
+		if err := s.client.NetworkRemove(ctx, n.ID); err != nil {
+			s.logger.Warn("Failed to remove network of group %s: %v", group, err)
+		}
+	}
+
+	return &model.BoxesDeleteResult{
+		Count:   len(deletedIDs),
+		Message: "Boxes deleted successfully",
+		IDs:     deletedIDs,
+	}, nil
+}
@@ -0,0 +1,142 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// newGroupDaemon returns a fake daemon serving the given containers, applying
+// the label filters of list requests, and recording removed container IDs
+func newGroupDaemon(containers []map[string]interface{}, removed *[]string) *fakeDaemon {
+	list := func(w http.ResponseWriter, r *http.Request) {
+		var args map[string]map[string]bool
+		json.Unmarshal([]byte(r.URL.Query().Get("filters")), &args)
+
+		matched := []map[string]interface{}{}
+	next:
+		for _, c := range containers {
+			labels := c["Labels"].(map[string]string)
+			for filter := range args["label"] {
+				key, value, _ := strings.Cut(filter, "=")
+				if labels[key] != value {
+					continue next
+				}
+			}
+			matched = append(matched, c)
+		}
+		writeJSON(matched)(w, r)
+	}
+
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": list,
+		"GET /networks":        writeJSON([]interface{}{}),
+	}}
+	for _, c := range containers {
+		id := c["Id"].(string)
+		daemon.handlers["POST /containers/"+id+"/stop"] = noContent
+		daemon.handlers["DELETE /containers/"+id] = func(w http.ResponseWriter, r *http.Request) {
+			*removed = append(*removed, id)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+	return daemon
+}
+
+func groupContainer(id, boxID, group, state string) map[string]interface{} {
+	labels := map[string]string{labelID: boxID, labelName: "gbox"}
+	if group != "" {
+		labels[labelGroup] = group
+	}
+	return map[string]interface{}{"Id": id, "State": state, "Labels": labels}
+}
+
+func TestDeleteGroupRemovesOnlyGroupBoxes(t *testing.T) {
+	var removed []string
+	daemon := newGroupDaemon([]map[string]interface{}{
+		groupContainer("c1", "box-1", "web", "running"),
+		groupContainer("c2", "box-2", "web", "exited"),
+		groupContainer("c3", "box-3", "", "running"),
+	}, &removed)
+	svc := newTestService(t, daemon)
+
+	result, err := svc.DeleteGroup(context.Background(), "web", &model.BoxesDeleteParams{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Count)
+	assert.ElementsMatch(t, []string{"box-1", "box-2"}, result.IDs)
+	assert.ElementsMatch(t, []string{"c1", "c2"}, removed)
+	assert.Contains(t, daemon.Calls(), "POST /containers/c1/stop", "running boxes are stopped before removal")
+	assert.NotContains(t, daemon.Calls(), "POST /containers/c2/stop")
+}
+
+func TestStopGroupStopsOnlyRunningGroupBoxes(t *testing.T) {
+	var removed []string
+	daemon := newGroupDaemon([]map[string]interface{}{
+		groupContainer("c1", "box-1", "web", "running"),
+		groupContainer("c2", "box-2", "web", "exited"),
+		groupContainer("c3", "box-3", "db", "running"),
+	}, &removed)
+	svc := newTestService(t, daemon)
+
+	result, err := svc.StopGroup(context.Background(), "web")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"box-1"}, result.IDs)
+	assert.NotContains(t, daemon.Calls(), "POST /containers/c3/stop")
+	assert.Empty(t, removed)
+}
+
+func TestGroupNameValidation(t *testing.T) {
+	svc := newTestService(t, &fakeDaemon{})
+
+	_, err := svc.DeleteGroup(context.Background(), "web=1,gbox.name", &model.BoxesDeleteParams{})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+	_, err = svc.StopGroup(context.Background(), "")
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	svc = newTestService(t, daemon)
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{Group: "-bad"}})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+	assert.Empty(t, daemon.Calls())
+}
+
+func TestCreateLinuxBoxGroupLabel(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct {
+		Labels map[string]string
+	}
+	svc := newTestService(t, newCreateDaemon(&created))
+
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{Group: "web"}})
+	require.NoError(t, err)
+	assert.Equal(t, "web", created.Labels[labelGroup])
+}
+
+func TestOwnerLabelAndFilter(t *testing.T) {
+	labels := PrepareLabels("box-1", &model.LinuxAndroidBoxCreateParam{Type: "linux", Owner: "apikey-0123456789ab"})
+	assert.Equal(t, "apikey-0123456789ab", labels[labelOwner])
+
+	mine := groupContainer("c1", "box-1", "", "running")
+	mine["Labels"].(map[string]string)[labelOwner] = "alice"
+	theirs := groupContainer("c2", "box-2", "", "running")
+	theirs["Labels"].(map[string]string)[labelOwner] = "bob"
+	svc := newTestService(t, newGroupDaemon([]map[string]interface{}{mine, theirs}, nil))
+
+	result, err := svc.List(context.Background(), &model.BoxListParams{Filters: []model.Filter{
+		{Field: "owner", Operator: model.FilterOperatorEquals, Value: "alice"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, result.Data, 1)
+	assert.Equal(t, "box-1", result.Data[0].ID)
+	assert.Equal(t, "alice", result.Data[0].Owner)
+}
@@ -0,0 +1,126 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+const (
+	defaultPostStartTimeout = time.Minute
+	defaultPreStopTimeout   = 30 * time.Second
+	hookPollInterval        = 200 * time.Millisecond
+)
+
+// postStartHook is a validated post-start command of a create request
+type postStartHook struct {
+	cmd         string
+	shell       string
+	timeout     time.Duration
+	failOnError bool
+}
+
+// parsePostStartHook validates the post-start options of a create request.
+// It returns nil when no post-start command was requested.
+func parsePostStartHook(cfg model.CreateBoxConfigParam) (*postStartHook, error) {
+	if cfg.PostStart == "" {
+		if cfg.PostStartTimeout != "" || cfg.PostStartFailOnError {
+			return nil, fmt.Errorf("%w: postStartTimeout and postStartFailOnError require postStart", service.ErrInvalidParams)
+		}
+		return nil, nil
+	}
+
+	timeout := defaultPostStartTimeout
+	if cfg.PostStartTimeout != "" {
+		d, err := time.ParseDuration(cfg.PostStartTimeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: invalid postStartTimeout %q", service.ErrInvalidParams, cfg.PostStartTimeout)
+		}
+		timeout = d
+	}
+	return &postStartHook{cmd: cfg.PostStart, shell: cfg.Shell, timeout: timeout, failOnError: cfg.PostStartFailOnError}, nil
+}
+
+// runPostStartHook runs the post-start command in a freshly started box and
+// waits for it to finish, returning an error when it fails, exits non-zero
+// or times out.
+func (s *Service) runPostStartHook(ctx context.Context, boxID, containerID string, hook *postStartHook) error {
+	s.logger.Info("Running post-start hook for box %s (timeout %v)", boxID, hook.timeout)
+	exitCode, err := s.execAndWait(ctx, containerID, GetCommand(hook.shell, hook.cmd, nil), hook.timeout)
+	if err != nil {
+		return fmt.Errorf("post-start hook of box %s did not complete: %w", boxID, err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("post-start hook of box %s exited with code %d", boxID, exitCode)
+	}
+	return nil
+}
+
+// runPreStopHook runs the box's pre-stop command, if one was configured at
+// creation time, and waits for it to finish or time out. Failures are logged
+// but never prevent the box from being stopped.
+func (s *Service) runPreStopHook(ctx context.Context, containerID string, labels map[string]string) {
+	cmd := labels[labelPreStop]
+	if cmd == "" {
+		return
+	}
+
+	timeout := defaultPreStopTimeout
+	if v := labels[labelPreStopTimeout]; v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			timeout = d
+		} else {
+			s.logger.Warn("Invalid pre-stop timeout %q for container %s, using %v", v, containerID, timeout)
+		}
+	}
+
+	s.logger.Info("Running pre-stop hook for container %s (timeout %v)", containerID, timeout)
+	exitCode, err := s.execAndWait(ctx, containerID, GetCommand(boxShell(labels), cmd, nil), timeout)
+	if err != nil {
+		s.logger.Warn("Pre-stop hook for container %s did not complete: %v", containerID, err)
+		return
+	}
+	if exitCode != 0 {
+		s.logger.Warn("Pre-stop hook for container %s exited with code %d", containerID, exitCode)
+	}
+}
+
+// execAndWait starts a detached exec in the container and polls until it
+// exits or the timeout elapses, returning the exit code.
+func (s *Service) execAndWait(ctx context.Context, containerID string, cmd []string, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	execResp, err := s.client.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		Cmd:    cmd,
+		Detach: true,
+	})
+	if err != nil {
+		return -1, err
+	}
+	if err := s.client.ContainerExecStart(ctx, execResp.ID, types.ExecStartCheck{Detach: true}); err != nil {
+		return -1, err
+	}
+
+	ticker := time.NewTicker(hookPollInterval)
+	defer ticker.Stop()
+	for {
+		inspect, err := s.client.ContainerExecInspect(ctx, execResp.ID)
+		if err != nil {
+			return -1, err
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+		select {
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package docker
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultImageCacheTTL bounds how long a positive image inspect result is
+// trusted before the daemon is asked again.
+const defaultImageCacheTTL = time.Minute
+
+// imagePresenceCache remembers images recently confirmed to exist locally so
+// that repeated box creation can skip the ImageInspect round trip.
+type imagePresenceCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	expires map[string]time.Time
+}
+
+func newImagePresenceCache(ttl time.Duration) *imagePresenceCache {
+	return &imagePresenceCache{
+		ttl:     ttl,
+		now:     time.Now,
+		expires: make(map[string]time.Time),
+	}
+}
+
+// has reports whether the image was confirmed present within the TTL.
+func (c *imagePresenceCache) has(image string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiry, ok := c.expires[image]
+	if !ok {
+		return false
+	}
+	if !c.now().Before(expiry) {
+		delete(c.expires, image)
+		return false
+	}
+	return true
+}
+
+// markPresent records a successful inspect of the image.
+func (c *imagePresenceCache) markPresent(image string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires[image] = c.now().Add(c.ttl)
+}
+
+// invalidate drops the cached entry for the image, forcing the next lookup
+// to go to the daemon.
+func (c *imagePresenceCache) invalidate(image string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.expires, image)
+}
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// gboxImageRepoPrefix is the repository prefix of the images gbox ships
+const gboxImageRepoPrefix = "babelcloud/gbox-"
+
+// ListImages implements Service.ListImages
+func (s *Service) ListImages(ctx context.Context, params *model.BoxImageListParams) (*model.BoxImageListResult, error) {
+	images, err := s.client.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", fmt.Sprintf("%s=gbox", labelName))
+	containers, err := s.client.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: filterArgs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	inUse := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		inUse[c.ImageID] = true
+	}
+
+	result := &model.BoxImageListResult{Images: []model.BoxImage{}}
+	for _, img := range images {
+		if !params.All && !inUse[img.ID] && !isGboxImage(img.RepoTags) {
+			continue
+		}
+		repoTags := img.RepoTags
+		if repoTags == nil {
+			repoTags = []string{}
+		}
+		result.Images = append(result.Images, model.BoxImage{
+			ID:        img.ID,
+			RepoTags:  repoTags,
+			Size:      img.Size,
+			CreatedAt: time.Unix(img.Created, 0).UTC(),
+			InUse:     inUse[img.ID],
+		})
+	}
+
+	// Newest first, like docker images
+	sort.SliceStable(result.Images, func(i, j int) bool {
+		return result.Images[i].CreatedAt.After(result.Images[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// isGboxImage reports whether any of the references is a gbox image
+func isGboxImage(repoTags []string) bool {
+	for _, ref := range repoTags {
+		if strings.HasPrefix(ref, gboxImageRepoPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// PruneImages implements Service.PruneImages
+func (s *Service) PruneImages(ctx context.Context) (*model.BoxImagePruneResult, error) {
+	// Only untagged images no other image builds on, like docker image prune
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("dangling", "true")
+
+	report, err := s.client.ImagesPrune(ctx, filterArgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune images: %w", err)
+	}
+
+	result := &model.BoxImagePruneResult{DeletedIDs: []string{}, SpaceReclaimed: report.SpaceReclaimed}
+	for _, item := range report.ImagesDeleted {
+		if item.Deleted != "" {
+			result.DeletedIDs = append(result.DeletedIDs, item.Deleted)
+		}
+	}
+	s.logger.Info("Pruned %d dangling images, reclaiming %d bytes", len(result.DeletedIDs), result.SpaceReclaimed)
+	return result, nil
+}
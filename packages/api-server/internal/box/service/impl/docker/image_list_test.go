@@ -0,0 +1,73 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+func TestListImages(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /images/json": writeJSON([]map[string]interface{}{
+			{"Id": "sha256:old", "RepoTags": []string{"babelcloud/gbox-playwright:old"}, "Size": 1000, "Created": created.Add(-time.Hour).Unix()},
+			{"Id": "sha256:pw", "RepoTags": []string{"babelcloud/gbox-playwright:latest"}, "Size": 2048, "Created": created.Unix()},
+			{"Id": "sha256:py", "RepoTags": []string{"python:3.12"}, "Size": 512, "Created": created.Add(-2 * time.Hour).Unix()},
+			{"Id": "sha256:other", "RepoTags": nil, "Size": 10, "Created": created.Add(time.Hour).Unix()},
+		}),
+		"GET /containers/json": writeJSON([]map[string]interface{}{
+			{"Id": "c1", "ImageID": "sha256:py", "Labels": map[string]string{labelID: "box-1"}},
+		}),
+	}}
+	svc := newTestService(t, daemon)
+
+	result, err := svc.ListImages(context.Background(), &model.BoxImageListParams{})
+	require.NoError(t, err)
+	require.Len(t, result.Images, 3, "only gbox images and images used by boxes are listed")
+	assert.Equal(t, model.BoxImage{
+		ID:        "sha256:pw",
+		RepoTags:  []string{"babelcloud/gbox-playwright:latest"},
+		Size:      2048,
+		CreatedAt: created,
+	}, result.Images[0])
+	assert.Equal(t, "sha256:old", result.Images[1].ID, "images are listed newest first")
+	assert.Equal(t, "sha256:py", result.Images[2].ID)
+	assert.True(t, result.Images[2].InUse)
+
+	result, err = svc.ListImages(context.Background(), &model.BoxImageListParams{All: true})
+	require.NoError(t, err)
+	require.Len(t, result.Images, 4)
+	assert.Equal(t, "sha256:other", result.Images[0].ID)
+	assert.Equal(t, []string{}, result.Images[0].RepoTags)
+}
+
+func TestPruneImages(t *testing.T) {
+	var pruneFilters string
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"POST /images/prune": func(w http.ResponseWriter, r *http.Request) {
+			pruneFilters = r.URL.Query().Get("filters")
+			writeJSON(map[string]interface{}{
+				"ImagesDeleted": []map[string]string{
+					{"Untagged": "sha256:a"},
+					{"Deleted": "sha256:a"},
+					{"Deleted": "sha256:b"},
+				},
+				"SpaceReclaimed": 3000,
+			})(w, r)
+		},
+	}}
+	svc := newTestService(t, daemon)
+
+	result, err := svc.PruneImages(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, daemon.Calls(), "POST /images/prune")
+	assert.Contains(t, pruneFilters, `"dangling":{"true":true}`)
+	assert.Equal(t, []string{"sha256:a", "sha256:b"}, result.DeletedIDs)
+	assert.Equal(t, uint64(3000), result.SpaceReclaimed)
+}
@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// validatePullPolicy checks a create request's image pull policy
+func validatePullPolicy(policy string) error {
+	switch policy {
+	case "", model.PullPolicyMissing, model.PullPolicyAlways, model.PullPolicyNever:
+		return nil
+	}
+	return fmt.Errorf("%w: invalid pull policy %q, must be one of %s, %s or %s", service.ErrInvalidParams,
+		policy, model.PullPolicyMissing, model.PullPolicyAlways, model.PullPolicyNever)
+}
+
+// parsePullTimeout parses a create request's pull timeout, zero meaning the
+// pull is not bounded
+func parsePullTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("%w: invalid pullTimeout %q", service.ErrInvalidParams, value)
+	}
+	return timeout, nil
+}
+
+// ensureImage makes img available for a new box according to the pull
+// policy: an absent image is pulled unless the policy is never. A pull
+// taking longer than pullTimeout, if set, is aborted.
+func (s *Service) ensureImage(ctx context.Context, img string, policy string, pullTimeout time.Duration) error {
+	if policy == model.PullPolicyAlways {
+		return s.pullImage(ctx, img, pullTimeout)
+	}
+
+	// A recent positive result is cached to skip the inspect round trip.
+	if s.imageCache.has(img) {
+		return nil
+	}
+	if _, _, err := s.client.ImageInspectWithRaw(ctx, img); err == nil {
+		s.imageCache.markPresent(img)
+		return nil
+	}
+
+	if policy == model.PullPolicyNever {
+		return fmt.Errorf("%w: image %s is not available locally and the pull policy is %s", service.ErrInvalidParams, img, policy)
+	}
+	s.logger.Info("Image %s not available locally, pulling it", img)
+	return s.pullImage(ctx, img, pullTimeout)
+}
+
+// imageDefaultCmd returns the command set by the image's default command
+// label, or nil when the image has none. A JSON array is used in exec form,
+// anything else is run by the shell like a request's command.
+func (s *Service) imageDefaultCmd(ctx context.Context, img, shell string) ([]string, error) {
+	inspect, _, err := s.client.ImageInspectWithRaw(ctx, img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image %s: %w", img, err)
+	}
+	if inspect.Config == nil {
+		return nil, nil
+	}
+	value := strings.TrimSpace(inspect.Config.Labels[labelDefaultCmd])
+	if value == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(value, "[") {
+		var argv []string
+		if err := json.Unmarshal([]byte(value), &argv); err != nil || len(argv) == 0 {
+			return nil, fmt.Errorf("%w: image %s has an invalid %s label %q", service.ErrInvalidParams, img, labelDefaultCmd, value)
+		}
+		return argv, nil
+	}
+	return GetCommand(shell, value, nil), nil
+}
+
+// pullImage pulls img and waits for the pull to finish. With a timeout, a
+// slow pull is cancelled, which also stops it on the daemon.
+func (s *Service) pullImage(ctx context.Context, img string, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	s.logger.Info("Pulling image %s", img)
+	reader, err := s.client.ImagePull(ctx, img, types.ImagePullOptions{})
+	if err == nil {
+		defer reader.Close()
+		err = ProcessPullProgress(reader, io.Discard)
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("pull of image %s did not finish within the pull timeout of %s", img, timeout)
+		}
+		return fmt.Errorf("failed to pull image %s: %w", img, err)
+	}
+	s.imageCache.markPresent(img)
+	return nil
+}
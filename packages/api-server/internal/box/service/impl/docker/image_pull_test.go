@@ -0,0 +1,165 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+func pullParams(policy string) *model.LinuxAndroidBoxCreateParam {
+	return &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{PullPolicy: policy}}
+}
+
+func TestCreateLinuxBoxPullPolicyAlways(t *testing.T) {
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	var pulled []string
+	daemon.handlers["POST /images/create"] = func(w http.ResponseWriter, r *http.Request) {
+		pulled = append(pulled, r.URL.Query().Get("fromImage")+":"+r.URL.Query().Get("tag"))
+		writeJSON(map[string]string{"status": "Status: Image is up to date"})(w, r)
+	}
+	svc := newTestService(t, daemon)
+
+	// The image is present, yet "always" pulls on every create
+	for i := 0; i < 2; i++ {
+		_, err := svc.CreateLinuxBox(context.Background(), pullParams(model.PullPolicyAlways))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{GetImage(""), GetImage("")}, pulled)
+	assert.NotContains(t, daemon.Calls(), "GET /images/"+GetImage("")+"/json")
+
+	// The default policy uses the local image
+	_, err := svc.CreateLinuxBox(context.Background(), pullParams(""))
+	require.NoError(t, err)
+	assert.Len(t, pulled, 2)
+}
+
+func TestCreateLinuxBoxPullPolicyAlwaysFailure(t *testing.T) {
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	daemon.handlers["POST /images/create"] = writeJSON(map[string]string{"error": "manifest unknown"})
+	svc := newTestService(t, daemon)
+
+	_, err := svc.CreateLinuxBox(context.Background(), pullParams(model.PullPolicyAlways))
+	assert.ErrorContains(t, err, "manifest unknown")
+	assert.NotContains(t, daemon.Calls(), "POST /containers/create")
+}
+
+func TestCreateLinuxBoxPullTimeout(t *testing.T) {
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	// The registry reports progress once, then stalls until the pull is cancelled
+	daemon.handlers["POST /images/create"] = func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(map[string]string{"status": "Pulling fs layer"})(w, r)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}
+	svc := newTestService(t, daemon)
+
+	params := pullParams(model.PullPolicyAlways)
+	params.Config.PullTimeout = "200ms"
+	start := time.Now()
+	_, err := svc.CreateLinuxBox(context.Background(), params)
+	assert.ErrorContains(t, err, "did not finish within the pull timeout of 200ms")
+	assert.Less(t, time.Since(start), 5*time.Second, "the create should abort once the pull timeout elapses")
+	assert.NotContains(t, daemon.Calls(), "POST /containers/create")
+}
+
+func TestCreateLinuxBoxInvalidPullTimeout(t *testing.T) {
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	svc := newTestService(t, daemon)
+
+	params := pullParams("")
+	params.Config.PullTimeout = "-1s"
+	_, err := svc.CreateLinuxBox(context.Background(), params)
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+	assert.Empty(t, daemon.Calls())
+}
+
+func TestCreateLinuxBoxPullPolicyNever(t *testing.T) {
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	delete(daemon.handlers, "GET /images/"+GetImage("")+"/json")
+	svc := newTestService(t, daemon)
+
+	_, err := svc.CreateLinuxBox(context.Background(), pullParams(model.PullPolicyNever))
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+	assert.ErrorContains(t, err, "pull policy is never")
+	assert.NotContains(t, daemon.Calls(), "POST /images/create")
+	assert.NotContains(t, daemon.Calls(), "POST /containers/create")
+}
+
+func TestCreateLinuxBoxInvalidPullPolicy(t *testing.T) {
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	svc := newTestService(t, daemon)
+
+	_, err := svc.CreateLinuxBox(context.Background(), pullParams("sometimes"))
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+	assert.Empty(t, daemon.Calls())
+}
+
+func TestCreateLinuxBoxPullPolicyMissing(t *testing.T) {
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	delete(daemon.handlers, "GET /images/"+GetImage("")+"/json")
+	present := map[string]bool{}
+	for _, img := range []string{GetImage(""), "python:3.12"} {
+		img := img
+		daemon.handlers["GET /images/"+img+"/json"] = func(w http.ResponseWriter, r *http.Request) {
+			if !present[img] {
+				http.Error(w, `{"message":"No such image"}`, http.StatusNotFound)
+				return
+			}
+			writeJSON(map[string]interface{}{"Id": "img"})(w, r)
+		}
+	}
+	var pulled []string
+	daemon.handlers["POST /images/create"] = func(w http.ResponseWriter, r *http.Request) {
+		img := r.URL.Query().Get("fromImage") + ":" + r.URL.Query().Get("tag")
+		pulled = append(pulled, img)
+		present[img] = true
+		writeJSON(map[string]string{"status": "Status: Downloaded newer image"})(w, r)
+	}
+	svc := newTestService(t, daemon)
+
+	// Absent images are pulled, whether the default or a tagged custom one
+	_, err := svc.CreateLinuxBox(context.Background(), pullParams(""))
+	require.NoError(t, err)
+	params := pullParams(model.PullPolicyMissing)
+	params.Config.Image = "python:3.12"
+	_, err = svc.CreateLinuxBox(context.Background(), params)
+	require.NoError(t, err)
+	assert.Equal(t, []string{GetImage(""), "python:3.12"}, pulled)
+
+	// Once present, they are used without pulling again
+	_, err = svc.CreateLinuxBox(context.Background(), params)
+	require.NoError(t, err)
+	assert.Len(t, pulled, 2)
+}
+
+func TestCreateLinuxBoxPullPolicyMissingTimeout(t *testing.T) {
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	daemon.handlers["POST /images/create"] = func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(map[string]string{"status": "Pulling fs layer"})(w, r)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}
+	svc := newTestService(t, daemon)
+
+	params := pullParams(model.PullPolicyMissing)
+	params.Config.Image = "python:3.12"
+	params.Config.PullTimeout = "200ms"
+	_, err := svc.CreateLinuxBox(context.Background(), params)
+	assert.ErrorContains(t, err, "did not finish within the pull timeout of 200ms")
+	assert.NotContains(t, daemon.Calls(), "POST /containers/create")
+}
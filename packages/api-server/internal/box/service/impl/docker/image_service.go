@@ -37,6 +37,9 @@ func (t *pullImageTrigger) execute(ctx context.Context, s *ImageService) error {
 
 	s.logger.Info("ImageService: Successfully pulled image %s", imageWithTag)
 
+	// The pulled tag may now point at different layers; drop any cached presence.
+	s.imageCache.invalidate(imageWithTag)
+
 	// After pulling, trigger a prune to clean up old versions.
 	return s.pruneImages(ctx, imageWithTag)
 }
@@ -60,10 +63,11 @@ type ImageService struct {
 	wg          sync.WaitGroup
 	ctx         context.Context
 	cancel      context.CancelFunc
+	imageCache  *imagePresenceCache
 }
 
 // NewImageService creates a new ImageService.
-func NewImageService(dockerClient *client.Client, logger *logger.Logger) *ImageService {
+func NewImageService(dockerClient *client.Client, logger *logger.Logger, imageCache *imagePresenceCache) *ImageService {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &ImageService{
 		client:      dockerClient,
@@ -72,6 +76,7 @@ func NewImageService(dockerClient *client.Client, logger *logger.Logger) *ImageS
 		wg:          sync.WaitGroup{},
 		ctx:         ctx,
 		cancel:      cancel,
+		imageCache:  imageCache,
 	}
 }
 
@@ -179,6 +184,10 @@ func (s *ImageService) pullImage(ctx context.Context, imageWithTag string, write
 
 // pruneImages removes outdated versions of an image.
 func (s *ImageService) pruneImages(ctx context.Context, imageRef string) error {
+	// A digest pins one image, there are no other versions of it to prune
+	if isDigestReference(imageRef) {
+		return nil
+	}
 	repo, tag, ok := parseImageTag(imageRef)
 	if !ok {
 		s.logger.Error("ImageService: Failed to parse image for pruning: %s", imageRef)
@@ -233,6 +242,7 @@ func (s *ImageService) removeImage(ctx context.Context, imageID, repoTag string)
 		s.logger.Warn("ImageService: Failed to remove outdated image %s: %v", repoTag, err)
 	} else {
 		s.logger.Info("ImageService: Successfully removed outdated image %s", repoTag)
+		s.imageCache.invalidate(repoTag)
 	}
 }
 
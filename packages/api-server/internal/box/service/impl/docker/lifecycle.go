@@ -2,6 +2,7 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,94 +12,342 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
 
 	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
 	"github.com/babelcloud/gbox/packages/api-server/internal/common"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
-	"github.com/babelcloud/gbox/packages/api-server/pkg/id"
 )
 
 const defaultStopTimeout = 10 * time.Second
 
+// cpuPeriod is the CFS period CPU limits are expressed against, in microseconds
+const cpuPeriod = 100000
+
+// boxCreateOptions carries settings for boxes created on behalf of other
+// operations, such as compose, that are not part of the public create params
+type boxCreateOptions struct {
+	image   string            // Image to use instead of the default box image
+	network string            // Network to attach the box to
+	aliases []string          // DNS aliases of the box on the network
+	labels  map[string]string // Additional internal labels
+}
+
+// linuxBoxSpec is everything needed to create a linux box container
+type linuxBoxSpec struct {
+	boxID           string
+	name            string
+	image           string
+	customImage     bool
+	shell           string
+	pullTimeout     time.Duration
+	shareDir        string
+	dependency      *boxDependency
+	logWait         *logWait
+	postStart       *postStartHook
+	files           []provisionFile
+	containerConfig *container.Config
+	hostConfig      *container.HostConfig
+	networkConfig   *network.NetworkingConfig
+}
+
 // CreateLinuxBox creates an Alpine Linux box with specific parameters
 func (s *Service) CreateLinuxBox(ctx context.Context, params *model.LinuxAndroidBoxCreateParam) (*model.Box, error) {
-	// Use Alpine Linux as the default image
-	img := GetImage("")
+	return s.createLinuxBox(ctx, params, boxCreateOptions{})
+}
 
-	// Check if image exists - return error if not available
-	_, _, err := s.client.ImageInspectWithRaw(ctx, img)
+// PlanLinuxBox returns the container spec CreateLinuxBox would use, without
+// pulling the image or creating anything
+func (s *Service) PlanLinuxBox(ctx context.Context, params *model.LinuxAndroidBoxCreateParam) (*model.BoxCreatePlan, error) {
+	spec, err := s.buildLinuxBoxSpec(params, boxCreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &model.BoxCreatePlan{
+		ID:    spec.boxID,
+		Name:  spec.name,
+		Image: spec.image,
+		Spec: map[string]interface{}{
+			"config":           spec.containerConfig,
+			"hostConfig":       spec.hostConfig,
+			"networkingConfig": spec.networkConfig,
+		},
+	}, nil
+}
+
+// buildLinuxBoxSpec validates the create params and builds the container
+// spec. It has no side effects, so it also serves dry runs.
+func (s *Service) buildLinuxBoxSpec(params *model.LinuxAndroidBoxCreateParam, opts boxCreateOptions) (*linuxBoxSpec, error) {
+	if err := validateDNSSearch(params.Config.DNSSearch); err != nil {
+		return nil, err
+	}
+	if err := validateOomScoreAdj(params.Config.OomScoreAdj); err != nil {
+		return nil, err
+	}
+	if err := service.ValidateSysctls(params.Config.Sysctls, s.allowUnsafeSysctls); err != nil {
+		return nil, err
+	}
+	if err := validateStopPolicy(params.Config); err != nil {
+		return nil, err
+	}
+	if err := validateMaxRuntime(params.Config); err != nil {
+		return nil, err
+	}
+	if params.Config.Group != "" {
+		if err := validateGroupName(params.Config.Group); err != nil {
+			return nil, err
+		}
+	}
+	resources, err := buildResources(params.Config)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateDockerOpts(params.Config.DockerOpts); err != nil {
+		return nil, err
+	}
+	if err := validatePullPolicy(params.Config.PullPolicy); err != nil {
+		return nil, err
+	}
+	pullTimeout, err := parsePullTimeout(params.Config.PullTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNameSuffix(params.Config.NameSuffix); err != nil {
+		return nil, err
+	}
+	socketMount, err := s.dockerSocketMount(params.Config.DockerSocket)
+	if err != nil {
+		return nil, err
+	}
+	dependency, err := parseDependency(params.Config)
 	if err != nil {
-		// Image not found, return resource preparation status
-		s.logger.Warn("Image %s not available locally, resources are being prepared", img)
-		return nil, fmt.Errorf("image resources are being prepared, please try again later (image: %s)", img)
+		return nil, err
+	}
+	logWait, err := parseLogWait(params.Config)
+	if err != nil {
+		return nil, err
+	}
+	shell, err := parseShell(params.Config.Shell)
+	if err != nil {
+		return nil, err
+	}
+	postStart, err := parsePostStartHook(params.Config)
+	if err != nil {
+		return nil, err
+	}
+	files, err := parseProvisionFiles(params.Config.Files)
+	if err != nil {
+		return nil, err
+	}
+	shareRoot, hostShareRoot, ok := config.GetInstance().File.ShareRoot(params.Config.ShareTier)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown share tier %q", service.ErrInvalidParams, params.Config.ShareTier)
+	}
+
+	image := opts.image
+	if image == "" {
+		image = params.Config.Image
+	}
+	if err := validateImageDigest(image); err != nil {
+		return nil, err
 	}
 
+	// Use Alpine Linux as the default image
+	img := GetImage(image)
+
 	// Generate box ID
-	boxID := id.GenerateBoxID()
-	containerName := containerName(boxID)
+	boxID := s.boxIDs.Generate()
+	containerName := suffixedContainerName(boxID, params.Config.NameSuffix)
 
 	tempParams := &model.LinuxAndroidBoxCreateParam{
-		Type: "linux",
-		Config: model.CreateBoxConfigParam{
-			ExpiresIn: params.Config.ExpiresIn,
-			Envs:      params.Config.Envs,
-			Labels:    params.Config.Labels,
-		},
+		Type:   "linux",
+		Config: params.Config,
+		Owner:  params.Owner,
 	}
 
 	// Use the same PrepareLabels function as Create method
 	labels := PrepareLabels(boxID, tempParams)
 
 	//image labels
-	labels["gbox.image"] = img
-
-	// Create share directory for the box
-	shareDir := filepath.Join(config.GetInstance().File.Share, boxID)
-	if err := os.MkdirAll(shareDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create share directory: %w", err)
+	labels[labelImage] = img
+	for k, v := range opts.labels {
+		labels[k] = v
 	}
 
 	// Prepare mounts (same as Create method)
 	var mounts []mount.Mount
 	mounts = append(mounts, mount.Mount{
 		Type:   mount.TypeBind,
-		Source: filepath.Join(config.GetInstance().File.HostShare, boxID),
+		Source: filepath.Join(hostShareRoot, boxID),
 		Target: common.DefaultShareDirPath,
 	})
+	if socketMount != nil {
+		mounts = append(mounts, *socketMount)
+	}
 
 	// Create container with same logic as Create method
 	containerConfig := &container.Config{
 		Image:  img,
-		Cmd:    GetCommand("", nil), // Use GetCommand for consistent behavior
-		Env:    MapToEnv(params.Config.Envs),
+		Cmd:    GetCommand(shell, "", nil), // Use GetCommand for consistent behavior
+		Env:    MapToEnv(mergeEnv(s.defaultEnv, params.Config.Envs)),
 		Labels: labels,
 	}
 
+	// Also let Docker itself, e.g. on daemon shutdown, stop the box by its policy
+	if params.Config.StopSignal != "" {
+		containerConfig.StopSignal = normalizeSignal(params.Config.StopSignal)
+	}
+	if params.Config.StopGracePeriod != "" {
+		grace, _ := time.ParseDuration(params.Config.StopGracePeriod)
+		timeout := stopTimeoutSeconds(grace)
+		containerConfig.StopTimeout = &timeout
+	}
+
+	if len(params.Config.Cmd) > 0 {
+		containerConfig.Cmd = GetCommand(shell, params.Config.Cmd[0], params.Config.Cmd[1:])
+	} else if image != "" {
+		// Custom images keep their own default command
+		containerConfig.Cmd = nil
+	}
+
 	hostConfig := &container.HostConfig{
 		Mounts:          mounts,
 		PublishAllPorts: true,
+		AutoRemove:      params.Config.AutoRemove,
+		DNSSearch:       params.Config.DNSSearch,
+		DNSOptions:      params.Config.DNSOptions,
+		OomScoreAdj:     params.Config.OomScoreAdj,
+		Sysctls:         params.Config.Sysctls,
+		Resources:       resources,
+	}
+	if err := applyDockerOpts(hostConfig, params.Config.DockerOpts); err != nil {
+		return nil, err
+	}
+
+	var networkConfig *network.NetworkingConfig
+	if opts.network != "" {
+		hostConfig.NetworkMode = container.NetworkMode(opts.network)
+		networkConfig = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				opts.network: {Aliases: opts.aliases},
+			},
+		}
+	}
+
+	return &linuxBoxSpec{
+		boxID:           boxID,
+		name:            containerName,
+		image:           img,
+		customImage:     image != "",
+		shell:           shell,
+		pullTimeout:     pullTimeout,
+		shareDir:        filepath.Join(shareRoot, boxID),
+		dependency:      dependency,
+		logWait:         logWait,
+		postStart:       postStart,
+		files:           files,
+		containerConfig: containerConfig,
+		hostConfig:      hostConfig,
+		networkConfig:   networkConfig,
+	}, nil
+}
+
+func (s *Service) createLinuxBox(ctx context.Context, params *model.LinuxAndroidBoxCreateParam, opts boxCreateOptions) (*model.Box, error) {
+	spec, err := s.buildLinuxBoxSpec(params, opts)
+	if err != nil {
+		return nil, err
 	}
+	boxID, shareDir, logWait := spec.boxID, spec.shareDir, spec.logWait
 
-	resp, err := s.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, containerName)
+	if err := s.ensureImage(ctx, spec.image, params.Config.PullPolicy, spec.pullTimeout); err != nil {
+		return nil, err
+	}
+	if len(params.Config.Cmd) == 0 && spec.customImage {
+		cmd, err := s.imageDefaultCmd(ctx, spec.image, spec.shell)
+		if err != nil {
+			return nil, err
+		}
+		if cmd != nil {
+			spec.containerConfig.Cmd = cmd
+		}
+	}
+
+	// Start the box only once the box it depends on is ready
+	if spec.dependency != nil {
+		if err := s.waitForDependency(ctx, spec.dependency); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create share directory for the box
+	if err := os.MkdirAll(shareDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create share directory: %w", err)
+	}
+
+	resp, err := s.client.ContainerCreate(ctx, spec.containerConfig, spec.hostConfig, spec.networkConfig, nil, spec.name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create container: %w", err)
 	}
+	for _, warning := range resp.Warnings {
+		s.logger.Warn("Creating box %s: %s", boxID, warning)
+	}
+
+	// Watch for removal before starting so a quick-exit command cannot be missed
+	if params.Config.AutoRemove {
+		s.cleanupOnAutoRemove(resp.ID, boxID, shareDir)
+	}
 
 	// Start container
 	if err := s.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
 		return nil, fmt.Errorf("failed to start container: %w", err)
 	}
 
+	// Provision the spec's files so they are in place before any setup runs
+	if len(spec.files) > 0 {
+		if err := writeProvisionFiles(shareDir, spec.files); err != nil {
+			s.removeFailedBox(ctx, resp.ID, boxID, shareDir)
+			return nil, err
+		}
+	}
+
+	// Run setup such as seeding data before the box is considered ready
+	if spec.postStart != nil {
+		if err := s.runPostStartHook(ctx, boxID, resp.ID, spec.postStart); err != nil {
+			if spec.postStart.failOnError {
+				s.removeFailedBox(ctx, resp.ID, boxID, shareDir)
+				return nil, err
+			}
+			s.logger.Warn("%v", err)
+		}
+	}
+
+	// Hold the response until the box reports it is ready
+	if logWait != nil {
+		if err := s.waitForLogLine(ctx, boxID, resp.ID, logWait); err != nil {
+			s.removeFailedBox(ctx, resp.ID, boxID, shareDir)
+			return nil, err
+		}
+	}
+
 	// Get container details after start (same as Create method)
 	containerInfo, err := s.inspectContainerByID(ctx, boxID)
 	if err != nil {
+		if params.Config.AutoRemove && errors.Is(err, service.ErrBoxNotFound) {
+			// The command already exited and the box was removed
+			return &model.Box{ID: boxID, Status: "removed", Type: model.BoxTypeLinux, Warnings: resp.Warnings}, nil
+		}
 		return nil, fmt.Errorf("failed to get container details after start: %w", err)
 	}
 
 	// Update access time on successful creation (same as Create method)
 	s.accessTracker.Update(boxID)
 
-	return containerToBox(containerInfo), nil
+	box := containerToBox(containerInfo)
+	box.Connection = boxConnection(boxID, containerInfo)
+	box.Warnings = resp.Warnings
+	return box, nil
 }
 
 // not implemented
@@ -158,10 +407,7 @@ func (s *Service) Stop(ctx context.Context, id string) (*model.BoxStopResult, er
 		return box, nil
 	}
 
-	stopTimeout := int(defaultStopTimeout.Seconds())
-	err = s.client.ContainerStop(ctx, containerInfo.ID, container.StopOptions{
-		Timeout: &stopTimeout,
-	})
+	err = s.stopContainer(ctx, containerInfo.ID, containerInfo.Labels)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stop container: %w", err)
 	}
@@ -176,6 +422,87 @@ func (s *Service) Stop(ctx context.Context, id string) (*model.BoxStopResult, er
 	return box, nil
 }
 
+// Touch implements Service.Touch
+func (s *Service) Touch(ctx context.Context, id string) (*model.BoxTouchResult, error) {
+	if _, err := s.getContainerByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	// Refreshing the access time keeps reclaim from stopping the box
+	s.accessTracker.Update(id)
+	lastAccessed, _ := s.accessTracker.GetLastAccessed(id)
+	return &model.BoxTouchResult{ID: id, LastAccessedAt: lastAccessed}, nil
+}
+
+// Update implements Service.Update
+func (s *Service) Update(ctx context.Context, id string, params *model.BoxUpdateParams) (*model.BoxUpdateResult, error) {
+	cpu, memory, err := service.ParseResourceLimits(params)
+	if err != nil {
+		return nil, err
+	}
+	containerInfo, err := s.getContainerByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// CPU is set as a quota, which is how the box model reports it back
+	var resources container.Resources
+	if cpu > 0 {
+		resources.CPUPeriod = cpuPeriod
+		resources.CPUQuota = int64(cpu * cpuPeriod)
+	}
+	if memory > 0 {
+		// Keep the swap allowance Docker gives a memory limit at creation
+		resources.Memory = memory
+		resources.MemorySwap = 2 * memory
+	}
+	if _, err := s.client.ContainerUpdate(ctx, containerInfo.ID, container.UpdateConfig{Resources: resources}); err != nil {
+		if errdefs.IsInvalidParameter(err) {
+			return nil, fmt.Errorf("%w: %v", service.ErrInvalidParams, err)
+		}
+		return nil, fmt.Errorf("failed to update container: %w", err)
+	}
+
+	updatedContainerInfo, err := s.inspectContainerByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container details after update: %w", err)
+	}
+	return containerToBox(updatedContainerInfo), nil
+}
+
+// removeFailedBox force-removes a box whose creation failed after its
+// container was started, together with its share directory
+func (s *Service) removeFailedBox(ctx context.Context, containerID, boxID, shareDir string) {
+	if err := s.client.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true}); err != nil && !errdefs.IsNotFound(err) {
+		s.logger.Warn("Failed to remove box %s after failed creation: %v", boxID, err)
+	}
+	if err := os.RemoveAll(shareDir); err != nil {
+		s.logger.Warn("Failed to remove share directory of box %s: %v", boxID, err)
+	}
+}
+
+// cleanupOnAutoRemove waits in the background for an auto-removed box to be
+// removed by Docker and then drops its share directory and tracking info.
+func (s *Service) cleanupOnAutoRemove(containerID, boxID, shareDir string) {
+	statusCh, errCh := s.client.ContainerWait(context.Background(), containerID, container.WaitConditionRemoved)
+	go func() {
+		select {
+		case <-statusCh:
+		case err := <-errCh:
+			if err != nil {
+				s.logger.Warn("Failed waiting for auto-removed box %s: %v", boxID, err)
+				return
+			}
+		}
+		if err := os.RemoveAll(shareDir); err != nil {
+			s.logger.Warn("Failed to remove share directory of box %s: %v", boxID, err)
+		}
+		s.accessTracker.Remove(boxID)
+		s.execSessions.removeBox(boxID)
+		s.logger.Info("Box %s was auto-removed", boxID)
+	}()
+}
+
 // Delete implements Service.Delete
 func (s *Service) Delete(ctx context.Context, id string, req *model.BoxDeleteParams) (*model.BoxDeleteResult, error) {
 	containerInfo, err := s.getContainerByID(ctx, id)
@@ -185,7 +512,8 @@ func (s *Service) Delete(ctx context.Context, id string, req *model.BoxDeletePar
 
 	if containerInfo.State == "running" {
 		_, err = s.Stop(ctx, id)
-		if err != nil {
+		// A forced delete removes boxes whose stop policy left them running
+		if err != nil && !req.Force {
 			return nil, err
 		}
 	}
@@ -193,12 +521,19 @@ func (s *Service) Delete(ctx context.Context, id string, req *model.BoxDeletePar
 	err = s.client.ContainerRemove(ctx, containerInfo.ID, types.ContainerRemoveOptions{
 		Force: req.Force,
 	})
+	if errdefs.IsNotFound(err) {
+		// Removed concurrently, e.g. by an earlier attempt of a retried delete
+		s.accessTracker.Remove(id)
+		s.execSessions.removeBox(id)
+		return nil, fmt.Errorf("box %s not found: %w", id, service.ErrBoxNotFound)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to remove container: %w", err)
 	}
 
 	// Remove access tracking info on delete
 	s.accessTracker.Remove(id)
+	s.execSessions.removeBox(id)
 
 	return &model.BoxDeleteResult{
 		Message: "Box deleted successfully",
@@ -230,8 +565,12 @@ func (s *Service) DeleteAll(ctx context.Context, req *model.BoxesDeleteParams) (
 		deletedIDs = append(deletedIDs, container.Labels[labelID])
 		// Remove access tracking info on delete
 		s.accessTracker.Remove(container.Labels[labelID])
+		s.execSessions.removeBox(container.Labels[labelID])
 	}
 
+	// With every box gone, the compose group networks are no longer needed
+	s.removeGroupNetworks(ctx)
+
 	return &model.BoxesDeleteResult{
 		Count:   len(deletedIDs),
 		Message: "Boxes deleted successfully",
@@ -245,7 +584,15 @@ func (s *Service) Reclaim(ctx context.Context) (*model.BoxReclaimResult, error)
 	cfg := config.GetInstance()
 	reclaimStopThreshold := cfg.Cluster.ReclaimStopThreshold
 	reclaimDeleteThreshold := cfg.Cluster.ReclaimDeleteThreshold
-	s.logger.Info("Starting box reclaim process with stop threshold: %v, delete threshold: %v", reclaimStopThreshold, reclaimDeleteThreshold)
+	reclaimDeleteEnabled := cfg.Cluster.ReclaimDeleteEnabled
+	reclaimSnapshotEnabled := cfg.Cluster.ReclaimSnapshotEnabled
+	reclaimWarnThreshold := cfg.Cluster.ReclaimWarnThreshold
+	reclaimGracePeriod := cfg.Cluster.ReclaimGracePeriod
+	if reclaimDeleteEnabled {
+		s.logger.Info("Starting box reclaim process with stop threshold: %v, delete threshold: %v", reclaimStopThreshold, reclaimDeleteThreshold)
+	} else {
+		s.logger.Info("Starting box reclaim process with stop threshold: %v, deletion disabled", reclaimStopThreshold)
+	}
 
 	// Build filter for gbox containers
 	filterArgs := filters.NewArgs()
@@ -259,8 +606,8 @@ func (s *Service) Reclaim(ctx context.Context) (*model.BoxReclaimResult, error)
 		return nil, fmt.Errorf("failed to list containers: %w", err)
 	}
 
-	var stoppedCount, deletedCount, skippedCount int
-	var stoppedIDs, deletedIDs []string
+	var stoppedCount, deletedCount, warnedCount, skippedCount int
+	var stoppedIDs, deletedIDs, warnedIDs, snapshotIDs []string
 
 	for _, c := range containers {
 		boxID, ok := c.Labels[labelID]
@@ -284,12 +631,28 @@ func (s *Service) Reclaim(ctx context.Context) (*model.BoxReclaimResult, error)
 
 		// Stop running containers that have been idle longer than the stop threshold
 		if c.State == "running" {
+			// With warnings enabled, a box is only stopped a grace period after its warning
+			if reclaimWarnThreshold > 0 && idleDuration >= reclaimWarnThreshold {
+				warnedAt, warned := s.accessTracker.GetWarned(boxID)
+				if !warned {
+					notBefore := lastAccessed.Add(reclaimStopThreshold)
+					if graceEnd := time.Now().Add(reclaimGracePeriod); graceEnd.After(notBefore) {
+						notBefore = graceEnd
+					}
+					s.warnReclaim(ctx, boxID, idleDuration, notBefore)
+					warnedCount++
+					warnedIDs = append(warnedIDs, boxID)
+					continue
+				}
+				if time.Since(warnedAt) < reclaimGracePeriod {
+					s.logger.Debug("Box %s was warned %v ago, still within the reclaim grace period", boxID, time.Since(warnedAt))
+					skippedCount++
+					continue
+				}
+			}
 			if idleDuration >= reclaimStopThreshold {
 				s.logger.Info("Stopping inactive running box %s (idle for %v)", boxID, idleDuration)
-				stopTimeout := int(defaultStopTimeout.Seconds())
-				err = s.client.ContainerStop(ctx, c.ID, container.StopOptions{
-					Timeout: &stopTimeout,
-				})
+				err = s.stopContainer(ctx, c.ID, c.Labels)
 				if err != nil {
 					s.logger.Error("Failed to stop container %s: %v", c.ID, err)
 					continue // Continue with next container
@@ -307,7 +670,17 @@ func (s *Service) Reclaim(ctx context.Context) (*model.BoxReclaimResult, error)
 
 		// Delete stopped containers that have been idle longer than the delete threshold
 		if c.State == "exited" {
-			if idleDuration >= reclaimDeleteThreshold {
+			if !reclaimDeleteEnabled {
+				s.logger.Debug("Box %s is stopped and reclaim deletion is disabled, skipping deletion", boxID)
+				skippedCount++
+			} else if idleDuration >= reclaimDeleteThreshold {
+				if reclaimSnapshotEnabled {
+					if err := s.snapshotBox(ctx, c.ID, boxID); err != nil {
+						s.logger.Error("Failed to snapshot box %s, keeping it: %v", boxID, err)
+						continue
+					}
+					snapshotIDs = append(snapshotIDs, boxID)
+				}
 				s.logger.Info("Deleting inactive stopped box %s (idle for %v)", boxID, idleDuration)
 				err = s.client.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{
 					Force: false, // Use false for reclaim, maybe true for explicit delete?
@@ -319,6 +692,7 @@ func (s *Service) Reclaim(ctx context.Context) (*model.BoxReclaimResult, error)
 				deletedCount++
 				deletedIDs = append(deletedIDs, boxID)
 				s.accessTracker.Remove(boxID) // Remove tracker info after deleting
+				s.execSessions.removeBox(boxID)
 			} else {
 				// Stopped but not idle long enough to delete
 				s.logger.Debug("Box %s is stopped but not idle long enough for deletion (idle for %v), skipping deletion", boxID, idleDuration)
@@ -333,12 +707,39 @@ func (s *Service) Reclaim(ctx context.Context) (*model.BoxReclaimResult, error)
 
 	}
 
-	s.logger.Info("Box reclaim finished. Skipped: %d, Stopped: %d, Deleted: %d", skippedCount, stoppedCount, deletedCount)
+	s.logger.Info("Box reclaim finished. Skipped: %d, Warned: %d, Stopped: %d, Deleted: %d", skippedCount, warnedCount, stoppedCount, deletedCount)
 
 	return &model.BoxReclaimResult{
 		StoppedCount: stoppedCount,
 		DeletedCount: deletedCount,
+		WarnedCount:  warnedCount,
 		StoppedIDs:   stoppedIDs,
 		DeletedIDs:   deletedIDs,
+		WarnedIDs:    warnedIDs,
+		SnapshotIDs:  snapshotIDs,
 	}, nil
 }
+
+// warnReclaim records and announces that an idle box will be stopped no
+// sooner than notBefore. A failing webhook does not hold the warning back,
+// so the box is still stopped after its grace period.
+func (s *Service) warnReclaim(ctx context.Context, boxID string, idle time.Duration, notBefore time.Time) {
+	s.logger.Warn("Box %s has been idle for %v and will be stopped after %s unless it is accessed",
+		boxID, idle.Round(time.Second), notBefore.Format(time.RFC3339))
+	s.accessTracker.MarkWarned(boxID)
+
+	webhook := config.GetInstance().Cluster.ReclaimWebhook
+	if webhook == "" {
+		return
+	}
+	warning := &model.BoxReclaimWarning{
+		Event:     service.ReclaimWarningEvent,
+		BoxID:     boxID,
+		Action:    "stop",
+		IdleFor:   idle.Round(time.Second).String(),
+		NotBefore: notBefore,
+	}
+	if err := service.NotifyReclaimWarning(ctx, webhook, warning); err != nil {
+		s.logger.Error("Failed to send reclaim warning for box %s: %v", boxID, err)
+	}
+}
@@ -0,0 +1,1243 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
+	"github.com/babelcloud/gbox/packages/api-server/internal/tracker"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	"github.com/babelcloud/gbox/packages/api-server/pkg/logger"
+)
+
+// fakeDaemon is a minimal Docker Engine API stand-in that records the calls it receives
+type fakeDaemon struct {
+	mu       sync.Mutex
+	calls    []string
+	handlers map[string]http.HandlerFunc
+	// inspect, when set, is returned for any box container inspected by name
+	inspect map[string]interface{}
+}
+
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + apiVersionPrefix.ReplaceAllString(r.URL.Path, "")
+	d.mu.Lock()
+	d.calls = append(d.calls, key)
+	d.mu.Unlock()
+
+	if h, ok := d.handlers[key]; ok {
+		h(w, r)
+		return
+	}
+	if d.inspect != nil && strings.HasPrefix(key, "GET /containers/gbox-") {
+		writeJSON(d.inspect)(w, r)
+		return
+	}
+	if strings.HasPrefix(key, "GET /containers/") {
+		// Unknown containers behave as if they were already removed
+		http.Error(w, `{"message":"No such container"}`, http.StatusNotFound)
+		return
+	}
+	http.Error(w, `{"message":"not implemented in fake"}`, http.StatusNotImplemented)
+}
+
+func (d *fakeDaemon) Calls() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.calls...)
+}
+
+func writeJSON(v interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+}
+
+func noContent(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newTestService(t *testing.T, daemon *fakeDaemon) *Service {
+	t.Helper()
+	server := httptest.NewServer(daemon)
+	t.Cleanup(server.Close)
+
+	cli, err := client.NewClientWithOpts(
+		client.WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")),
+		client.WithVersion("1.43"),
+	)
+	require.NoError(t, err)
+
+	return &Service{
+		client:        cli,
+		logger:        logger.New(),
+		accessTracker: tracker.NewInMemoryAccessTracker(),
+		execSessions:  newExecSessionStore(),
+		imageCache:    newImagePresenceCache(defaultImageCacheTTL),
+	}
+}
+
+func indexOf(calls []string, call string) int {
+	for i, c := range calls {
+		if c == call {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestStopRunsPreStopHookBeforeContainerStop(t *testing.T) {
+	var execCmd []string
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":    "c1",
+			"State": "running",
+			"Labels": map[string]string{
+				labelID:      "box-1",
+				labelPreStop: "touch /tmp/drained",
+			},
+		}}),
+		"POST /containers/c1/exec": func(w http.ResponseWriter, r *http.Request) {
+			var body struct{ Cmd []string }
+			json.NewDecoder(r.Body).Decode(&body)
+			execCmd = body.Cmd
+			writeJSON(map[string]string{"Id": "exec-1"})(w, r)
+		},
+		"POST /exec/exec-1/start":  noContent,
+		"GET /exec/exec-1/json":    writeJSON(map[string]interface{}{"Running": false, "ExitCode": 0}),
+		"POST /containers/c1/stop": noContent,
+		"GET /containers/gbox-box-1/json": writeJSON(map[string]interface{}{
+			"Id":     "c1",
+			"State":  map[string]interface{}{"Status": "exited"},
+			"Config": map[string]interface{}{"Labels": map[string]string{labelID: "box-1"}},
+		}),
+	}}
+	svc := newTestService(t, daemon)
+
+	box, err := svc.Stop(context.Background(), "box-1")
+	require.NoError(t, err)
+	assert.Equal(t, "stopped", box.Status)
+
+	assert.Equal(t, []string{"/bin/sh", "-c", "touch /tmp/drained"}, execCmd)
+	calls := daemon.Calls()
+	hookIdx := indexOf(calls, "POST /exec/exec-1/start")
+	stopIdx := indexOf(calls, "POST /containers/c1/stop")
+	require.NotEqual(t, -1, hookIdx, "pre-stop hook was not executed: %v", calls)
+	require.NotEqual(t, -1, stopIdx, "container was not stopped: %v", calls)
+	assert.Less(t, hookIdx, stopIdx, "pre-stop hook must run before ContainerStop")
+}
+
+// setupShareDir points the share directory config at a temporary home
+func setupShareDir(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GBOX_HOME", home)
+	t.Setenv("GBOX_SHARE", filepath.Join(home, "share"))
+	return home
+}
+
+// newCreateDaemon returns a fake daemon that accepts a box creation and
+// decodes the container create request into created.
+func newCreateDaemon(created interface{}) *fakeDaemon {
+	return &fakeDaemon{
+		handlers: map[string]http.HandlerFunc{
+			"GET /images/" + GetImage("") + "/json": writeJSON(map[string]interface{}{"Id": "img"}),
+			"POST /containers/create": func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(created)
+				w.WriteHeader(http.StatusCreated)
+				writeJSON(map[string]string{"Id": "c1"})(w, r)
+			},
+			"POST /containers/c1/start": noContent,
+		},
+		inspect: map[string]interface{}{
+			"Id":     "c1",
+			"State":  map[string]interface{}{"Status": "running"},
+			"Config": map[string]interface{}{"Labels": map[string]string{labelID: "box-1"}},
+		},
+	}
+}
+
+func TestCreateLinuxBoxInShareTier(t *testing.T) {
+	setupShareDir(t)
+	scratch := t.TempDir()
+	file := &config.GetInstance().File
+	orig := file.ShareTiers
+	t.Cleanup(func() { file.ShareTiers = orig })
+	file.ShareTiers = []config.ShareTier{{Name: "scratch", Path: scratch, HostPath: "/host/scratch"}}
+
+	var created struct {
+		HostConfig struct {
+			Mounts []struct{ Source, Target string }
+		}
+	}
+	svc := newTestService(t, newCreateDaemon(&created))
+
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{
+		Config: model.CreateBoxConfigParam{ShareTier: "scratch"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.HostConfig.Mounts)
+	source := created.HostConfig.Mounts[0].Source
+	boxID := filepath.Base(source)
+	assert.Equal(t, "/host/scratch/"+boxID, source, "the share directory is mounted from the tier's host path")
+	assert.DirExists(t, filepath.Join(scratch, boxID))
+	shareDir, hostShareDir := file.BoxShareDir(boxID)
+	assert.Equal(t, filepath.Join(scratch, boxID), shareDir)
+	assert.Equal(t, source, hostShareDir)
+
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{
+		Config: model.CreateBoxConfigParam{ShareTier: "archive"},
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
+
+func TestCreateLinuxBoxReturnsWarnings(t *testing.T) {
+	setupShareDir(t)
+
+	daemon := newCreateDaemon(&struct{}{})
+	warning := "Your kernel does not support memory limit capabilities or the cgroup is not mounted. Limitation discarded."
+	daemon.handlers["POST /containers/create"] = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(map[string]interface{}{"Id": "c1", "Warnings": []string{warning}})(w, r)
+	}
+	svc := newTestService(t, daemon)
+
+	box, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{
+		Config: model.CreateBoxConfigParam{Memory: "512m"},
+	})
+	require.NoError(t, err, "warnings do not fail the create")
+	assert.Equal(t, []string{warning}, box.Warnings)
+
+	// A clean create reports none
+	svc = newTestService(t, newCreateDaemon(&struct{}{}))
+	box, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{})
+	require.NoError(t, err)
+	assert.Empty(t, box.Warnings)
+}
+
+func TestCreateLinuxBoxDNSConfig(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct {
+		HostConfig struct {
+			DNSSearch  []string `json:"DnsSearch"`
+			DNSOptions []string `json:"DnsOptions"`
+		}
+	}
+	svc := newTestService(t, newCreateDaemon(&created))
+
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		DNSSearch:  []string{"corp.example.com", "svc.cluster.local."},
+		DNSOptions: []string{"ndots:2", "timeout:1"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"corp.example.com", "svc.cluster.local."}, created.HostConfig.DNSSearch)
+	assert.Equal(t, []string{"ndots:2", "timeout:1"}, created.HostConfig.DNSOptions)
+
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		DNSSearch: []string{"bad_domain..example"},
+	}})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
+
+func TestCreateLinuxBoxRecordsOwner(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct{ Labels map[string]string }
+	svc := newTestService(t, newCreateDaemon(&created))
+
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Owner: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", created.Labels[labelOwner])
+}
+
+func TestCreateLinuxBoxAutoRemove(t *testing.T) {
+	home := setupShareDir(t)
+
+	var hostConfig struct {
+		HostConfig struct{ AutoRemove bool }
+		Cmd        []string
+	}
+	removed := make(chan struct{})
+	daemon := &fakeDaemon{}
+	daemon.handlers = map[string]http.HandlerFunc{
+		"GET /images/" + GetImage("") + "/json": writeJSON(map[string]interface{}{"Id": "img"}),
+		"POST /containers/create": func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&hostConfig)
+			w.WriteHeader(http.StatusCreated)
+			writeJSON(map[string]string{"Id": "c1"})(w, r)
+		},
+		"POST /containers/c1/wait": func(w http.ResponseWriter, r *http.Request) {
+			// Like the real daemon, send headers right away and the status once the container is gone
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-removed
+			json.NewEncoder(w).Encode(map[string]interface{}{"StatusCode": 0})
+		},
+		"POST /containers/c1/start": func(w http.ResponseWriter, r *http.Request) {
+			close(removed)
+			w.WriteHeader(http.StatusNoContent)
+		},
+	}
+	svc := newTestService(t, daemon)
+
+	params := &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		Cmd:        []string{"true"},
+		AutoRemove: true,
+	}}
+	box, err := svc.CreateLinuxBox(context.Background(), params)
+	require.NoError(t, err)
+
+	assert.True(t, hostConfig.HostConfig.AutoRemove)
+	assert.Equal(t, []string{"/bin/sh", "-c", "true"}, hostConfig.Cmd)
+	assert.Equal(t, "removed", box.Status)
+
+	// The share directory is cleaned up once Docker reports the removal
+	shareDir := filepath.Join(home, "share", box.ID)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(shareDir)
+		return os.IsNotExist(err)
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestCreateLinuxBoxOOMConfig(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct {
+		HostConfig struct {
+			Memory         int64
+			OomKillDisable *bool
+			OomScoreAdj    int
+		}
+	}
+	daemon := newCreateDaemon(&created)
+	svc := newTestService(t, daemon)
+
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		Memory:         "512m",
+		OomKillDisable: true,
+		OomScoreAdj:    -500,
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(512*1024*1024), created.HostConfig.Memory)
+	require.NotNil(t, created.HostConfig.OomKillDisable)
+	assert.True(t, *created.HostConfig.OomKillDisable)
+	assert.Equal(t, -500, created.HostConfig.OomScoreAdj)
+
+	before := len(daemon.Calls())
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		OomKillDisable: true,
+	}})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+	assert.Len(t, daemon.Calls(), before, "rejected request must not reach the daemon")
+
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		OomScoreAdj: 1001,
+	}})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
+
+func TestCreateLinuxBoxConnectionInfo(t *testing.T) {
+	setupShareDir(t)
+
+	var created map[string]interface{}
+	daemon := newCreateDaemon(&created)
+	daemon.inspect["NetworkSettings"] = map[string]interface{}{
+		"Ports": map[string]interface{}{
+			"8080/tcp": []map[string]string{{"HostIp": "0.0.0.0", "HostPort": "32768"}},
+			"53/udp":   []map[string]string{{"HostIp": "0.0.0.0", "HostPort": "32769"}},
+			"9000/tcp": nil, // exposed but not published
+		},
+	}
+	svc := newTestService(t, daemon)
+
+	box, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{})
+	require.NoError(t, err)
+	require.NotNil(t, box.Connection)
+	boxID := filepath.Base(box.Connection.ShareDir)
+	assert.Equal(t, filepath.Join(config.GetInstance().File.HostShare, boxID), box.Connection.ShareDir)
+	assert.DirExists(t, filepath.Join(config.GetInstance().File.Share, boxID))
+	assert.Equal(t, []model.BoxPort{
+		{ContainerPort: 53, Protocol: "udp", HostIP: "0.0.0.0", HostPort: 32769},
+		{ContainerPort: 8080, Protocol: "tcp", HostIP: "0.0.0.0", HostPort: 32768},
+	}, box.Connection.Ports)
+	assert.True(t, strings.HasSuffix(box.Connection.ExecURL, "/api/v1/boxes/"+boxID+"/exec"), box.Connection.ExecURL)
+	assert.True(t, strings.HasPrefix(box.Connection.ExecURL, "ws://"), box.Connection.ExecURL)
+}
+
+func TestCreateLinuxBoxDefaultEnv(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct {
+		Env    []string
+		Labels map[string]string
+	}
+	svc := newTestService(t, newCreateDaemon(&created))
+	svc.defaultEnv = map[string]string{"HTTP_PROXY": "http://proxy:3128", "DO_NOT_TRACK": "1"}
+
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		Envs: map[string]string{"DO_NOT_TRACK": "0", "APP": "web"},
+	}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"HTTP_PROXY=http://proxy:3128", "DO_NOT_TRACK=0", "APP=web"}, created.Env)
+
+	// Only the variables requested for the box are recorded in its labels
+	assert.Equal(t, "0", created.Labels["gbox.env.DO_NOT_TRACK"])
+	assert.Equal(t, "web", created.Labels["gbox.env.APP"])
+	assert.NotContains(t, created.Labels, "gbox.env.HTTP_PROXY")
+}
+
+func TestCreateLinuxBoxMemoryReservation(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct {
+		HostConfig struct {
+			Memory            int64
+			MemoryReservation int64
+		}
+	}
+	daemon := newCreateDaemon(&created)
+	svc := newTestService(t, daemon)
+
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		Memory:            "512m",
+		MemoryReservation: "256m",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(512*1024*1024), created.HostConfig.Memory)
+	assert.Equal(t, int64(256*1024*1024), created.HostConfig.MemoryReservation)
+
+	// A reservation alone is allowed
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		MemoryReservation: "128m",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(128*1024*1024), created.HostConfig.MemoryReservation)
+
+	before := len(daemon.Calls())
+	for _, cfg := range []model.CreateBoxConfigParam{
+		{Memory: "256m", MemoryReservation: "512m"},
+		{MemoryReservation: "lots"},
+	} {
+		_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: cfg})
+		assert.ErrorIs(t, err, service.ErrInvalidParams, "config %+v", cfg)
+	}
+	assert.Len(t, daemon.Calls(), before, "rejected request must not reach the daemon")
+}
+
+func TestCreateLinuxBoxDockerOpts(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct {
+		HostConfig struct {
+			ShmSize   int64
+			PidsLimit *int64
+		}
+	}
+	daemon := newCreateDaemon(&created)
+	svc := newTestService(t, daemon)
+	params := func(opts map[string]string) *model.LinuxAndroidBoxCreateParam {
+		return &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{DockerOpts: opts}}
+	}
+
+	// Disabled by default
+	_, err := svc.CreateLinuxBox(context.Background(), params(map[string]string{"shm-size": "1g"}))
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+
+	svc.allowRawDockerOpts = true
+	_, err = svc.CreateLinuxBox(context.Background(), params(map[string]string{"shm-size": "1g", "pids-limit": "256"}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<30), created.HostConfig.ShmSize)
+	require.NotNil(t, created.HostConfig.PidsLimit)
+	assert.Equal(t, int64(256), *created.HostConfig.PidsLimit)
+
+	before := len(daemon.Calls())
+	for _, opts := range []map[string]string{
+		{"privileged": "true"},
+		{"network-mode": "host"},
+		{"pids-limit": "lots"},
+	} {
+		_, err = svc.CreateLinuxBox(context.Background(), params(opts))
+		assert.ErrorIs(t, err, service.ErrInvalidParams, "opts %v", opts)
+	}
+	assert.Len(t, daemon.Calls(), before, "rejected requests must not reach the daemon")
+}
+
+func TestCreateLinuxBoxDockerSocket(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct {
+		HostConfig struct {
+			Mounts []struct {
+				Type   string
+				Source string
+				Target string
+			}
+		}
+	}
+	daemon := newCreateDaemon(&created)
+	svc := newTestService(t, daemon)
+	params := &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{DockerSocket: true}}
+
+	// Disabled by default, rejected before reaching the daemon
+	_, err := svc.CreateLinuxBox(context.Background(), params)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+	assert.Empty(t, daemon.Calls())
+
+	svc.allowDockerSocketMount = true
+	_, err = svc.CreateLinuxBox(context.Background(), params)
+	require.NoError(t, err)
+	var found bool
+	for _, m := range created.HostConfig.Mounts {
+		if m.Target == dockerSocketPath {
+			found = true
+			assert.Equal(t, "bind", m.Type)
+			assert.Equal(t, dockerSocketPath, m.Source)
+		}
+	}
+	assert.True(t, found, "docker socket mount missing: %+v", created.HostConfig.Mounts)
+}
+
+func TestCreateLinuxBoxCachesImagePresence(t *testing.T) {
+	setupShareDir(t)
+
+	var created map[string]interface{}
+	daemon := newCreateDaemon(&created)
+	svc := newTestService(t, daemon)
+	inspectCall := "GET /images/" + GetImage("") + "/json"
+
+	countInspects := func() int {
+		n := 0
+		for _, c := range daemon.Calls() {
+			if c == inspectCall {
+				n++
+			}
+		}
+		return n
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, countInspects(), "second create should be served from the image cache")
+
+	svc.imageCache.invalidate(GetImage(""))
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, countInspects(), "create after invalidation should inspect again")
+}
+
+func TestImagePresenceCacheExpires(t *testing.T) {
+	cache := newImagePresenceCache(time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.markPresent("img:1")
+	assert.True(t, cache.has("img:1"))
+
+	now = now.Add(time.Minute)
+	assert.False(t, cache.has("img:1"))
+}
+
+func TestListReportsSizeOnlyWhenRequested(t *testing.T) {
+	var sizeQueries []string
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": func(w http.ResponseWriter, r *http.Request) {
+			sizeQueries = append(sizeQueries, r.URL.Query().Get("size"))
+			c := map[string]interface{}{
+				"Id":     "c1",
+				"State":  "running",
+				"Labels": map[string]string{labelID: "box-1"},
+			}
+			if r.URL.Query().Get("size") == "1" {
+				c["SizeRw"] = 4096
+				c["SizeRootFs"] = 1 << 20
+			}
+			writeJSON([]interface{}{c})(w, r)
+		},
+	}}
+	svc := newTestService(t, daemon)
+
+	result, err := svc.List(context.Background(), &model.BoxListParams{Size: true})
+	require.NoError(t, err)
+	require.Len(t, result.Data, 1)
+	require.NotNil(t, result.Data[0].SizeRw)
+	require.NotNil(t, result.Data[0].SizeRootFs)
+	assert.Equal(t, int64(4096), *result.Data[0].SizeRw)
+	assert.Equal(t, int64(1<<20), *result.Data[0].SizeRootFs)
+
+	result, err = svc.List(context.Background(), &model.BoxListParams{})
+	require.NoError(t, err)
+	require.Len(t, result.Data, 1)
+	assert.Nil(t, result.Data[0].SizeRw)
+	assert.Nil(t, result.Data[0].SizeRootFs)
+
+	assert.Equal(t, []string{"1", ""}, sizeQueries, "size should only be requested from the daemon when asked for")
+}
+
+func TestUptimeReflectsStartedAt(t *testing.T) {
+	createdAt := time.Now().Add(-3 * time.Hour).UTC()
+	startedAt := time.Now().Add(-90 * time.Minute).UTC()
+	inspect := func(status string, running bool) map[string]interface{} {
+		return map[string]interface{}{
+			"Id":      "c1",
+			"Created": createdAt.Format(time.RFC3339Nano),
+			"State": map[string]interface{}{
+				"Status":    status,
+				"Running":   running,
+				"StartedAt": startedAt.Format(time.RFC3339Nano),
+			},
+			"Config": map[string]interface{}{"Labels": map[string]string{labelID: "box-1"}},
+		}
+	}
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{
+			{"Id": "c1", "State": "running", "Labels": map[string]string{labelID: "box-1"}},
+		}),
+		"GET /containers/c1/json":         writeJSON(inspect("running", true)),
+		"GET /containers/gbox-box-1/json": writeJSON(inspect("running", true)),
+	}}
+	svc := newTestService(t, daemon)
+
+	box, err := svc.Get(context.Background(), "box-1")
+	require.NoError(t, err)
+	require.NotNil(t, box.StartedAt)
+	assert.True(t, startedAt.Equal(*box.StartedAt))
+	assert.InDelta(t, (90 * time.Minute).Seconds(), box.Uptime, 5, "uptime counts from the start, not the creation")
+
+	result, err := svc.List(context.Background(), &model.BoxListParams{})
+	require.NoError(t, err)
+	require.Len(t, result.Data, 1)
+	require.NotNil(t, result.Data[0].StartedAt)
+	assert.InDelta(t, (90 * time.Minute).Seconds(), result.Data[0].Uptime, 5)
+
+	// A stopped box keeps its last start time but has no uptime
+	daemon.handlers["GET /containers/gbox-box-1/json"] = writeJSON(inspect("exited", false))
+	box, err = svc.Get(context.Background(), "box-1")
+	require.NoError(t, err)
+	require.NotNil(t, box.StartedAt)
+	assert.Zero(t, box.Uptime)
+}
+
+func TestReclaimWithDeletionDisabled(t *testing.T) {
+	cluster := &config.GetInstance().Cluster
+	orig := *cluster
+	t.Cleanup(func() { *cluster = orig })
+	cluster.ReclaimStopThreshold = time.Hour
+	cluster.ReclaimDeleteThreshold = 24 * time.Hour
+
+	var removed []string
+	daemon := newGroupDaemon([]map[string]interface{}{
+		groupContainer("c1", "box-1", "", "running"),
+		groupContainer("c2", "box-2", "", "exited"),
+	}, &removed)
+	svc := newTestService(t, daemon)
+	// Both boxes have been idle past the delete threshold
+	svc.accessTracker = idleTracker{AccessTracker: tracker.NewInMemoryAccessTracker(), since: time.Now().Add(-48 * time.Hour)}
+
+	cluster.ReclaimDeleteEnabled = false
+	result, err := svc.Reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"box-1"}, result.StoppedIDs, "idle running boxes are still stopped")
+	assert.Zero(t, result.DeletedCount)
+	assert.Empty(t, removed, "stopped boxes must not be deleted")
+
+	cluster.ReclaimDeleteEnabled = true
+	result, err = svc.Reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"box-2"}, result.DeletedIDs)
+	assert.Equal(t, []string{"c2"}, removed)
+}
+
+func TestReclaimWarnsBeforeStopping(t *testing.T) {
+	cluster := &config.GetInstance().Cluster
+	orig := *cluster
+	t.Cleanup(func() { *cluster = orig })
+	cluster.ReclaimWarnThreshold = time.Hour
+	cluster.ReclaimStopThreshold = 2 * time.Hour
+	cluster.ReclaimGracePeriod = 10 * time.Minute
+
+	var warnings []model.BoxReclaimWarning
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var warning model.BoxReclaimWarning
+		json.NewDecoder(r.Body).Decode(&warning)
+		warnings = append(warnings, warning)
+	}))
+	defer webhook.Close()
+	cluster.ReclaimWebhook = webhook.URL
+
+	daemon := newGroupDaemon([]map[string]interface{}{
+		groupContainer("c1", "box-1", "", "running"),
+	}, nil)
+	svc := newTestService(t, daemon)
+	accesses := tracker.NewInMemoryAccessTracker()
+	idleFor := func(d time.Duration) {
+		svc.accessTracker = idleTracker{AccessTracker: accesses, since: time.Now().Add(-d)}
+	}
+
+	// Past the warn threshold only: warned, not stopped
+	idleFor(90 * time.Minute)
+	result, err := svc.Reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"box-1"}, result.WarnedIDs)
+	assert.Empty(t, result.StoppedIDs)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, service.ReclaimWarningEvent, warnings[0].Event)
+	assert.Equal(t, "box-1", warnings[0].BoxID)
+	assert.Equal(t, "stop", warnings[0].Action)
+	assert.Equal(t, "1h30m0s", warnings[0].IdleFor)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), warnings[0].NotBefore, time.Minute)
+
+	// Past the stop threshold, but the grace period since the warning has not elapsed
+	idleFor(3 * time.Hour)
+	result, err = svc.Reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, result.WarnedIDs, "a box is warned only once")
+	assert.Empty(t, result.StoppedIDs)
+	assert.NotContains(t, daemon.Calls(), "POST /containers/c1/stop")
+
+	// Once the grace period has elapsed the box is stopped
+	cluster.ReclaimGracePeriod = 0
+	result, err = svc.Reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"box-1"}, result.StoppedIDs)
+	assert.Contains(t, daemon.Calls(), "POST /containers/c1/stop")
+	assert.Len(t, warnings, 1)
+
+	// An access cancels the warning, so the box is warned again first
+	accesses.Update("box-1")
+	result, err = svc.Reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"box-1"}, result.WarnedIDs)
+	assert.Empty(t, result.StoppedIDs)
+	assert.Len(t, warnings, 2)
+}
+
+// idleTracker reports every box as last accessed at since
+type idleTracker struct {
+	tracker.AccessTracker
+	since time.Time
+}
+
+func (t idleTracker) GetLastAccessed(id string) (time.Time, bool) {
+	return t.since, true
+}
+
+// stubTracker is an access tracker whose times tests can set directly
+type stubTracker struct {
+	mu    sync.Mutex
+	times map[string]time.Time
+}
+
+func (t *stubTracker) Update(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.times[id] = time.Now()
+}
+
+func (t *stubTracker) GetLastAccessed(id string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ts, ok := t.times[id]
+	return ts, ok
+}
+
+func (t *stubTracker) Remove(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.times, id)
+}
+
+func (t *stubTracker) MarkWarned(id string) {}
+
+func (t *stubTracker) GetWarned(id string) (time.Time, bool) { return time.Time{}, false }
+
+func (t *stubTracker) Snapshot() map[string]time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	snapshot := make(map[string]time.Time, len(t.times))
+	for id, ts := range t.times {
+		snapshot[id] = ts
+	}
+	return snapshot
+}
+
+func TestTouchKeepsBoxFromReclaim(t *testing.T) {
+	cluster := &config.GetInstance().Cluster
+	orig := *cluster
+	t.Cleanup(func() { *cluster = orig })
+	cluster.ReclaimStopThreshold = time.Hour
+
+	var removed []string
+	daemon := newGroupDaemon([]map[string]interface{}{
+		groupContainer("c1", "box-1", "", "running"),
+	}, &removed)
+	svc := newTestService(t, daemon)
+	idleSince := time.Now().Add(-2 * time.Hour)
+	svc.accessTracker = &stubTracker{times: map[string]time.Time{"box-1": idleSince}}
+
+	result, err := svc.Touch(context.Background(), "box-1")
+	require.NoError(t, err)
+	assert.Equal(t, "box-1", result.ID)
+	assert.True(t, result.LastAccessedAt.After(idleSince), "touch must advance the access time")
+	lastAccessed, _ := svc.accessTracker.GetLastAccessed("box-1")
+	assert.Equal(t, result.LastAccessedAt, lastAccessed)
+
+	reclaimed, err := svc.Reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, reclaimed.StoppedIDs)
+	assert.NotContains(t, daemon.Calls(), "POST /containers/c1/stop")
+
+	_, err = svc.Touch(context.Background(), "box-missing")
+	assert.ErrorIs(t, err, service.ErrBoxNotFound)
+}
+
+func TestUpdateChangesResourceLimits(t *testing.T) {
+	var updated container.UpdateConfig
+	daemon := newGroupDaemon([]map[string]interface{}{
+		groupContainer("c1", "box-1", "", "running"),
+	}, nil)
+	daemon.handlers["POST /containers/c1/update"] = func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&updated)
+		writeJSON(map[string]interface{}{"Warnings": []string{}})(w, r)
+	}
+	daemon.inspect = map[string]interface{}{
+		"Id":     "c1",
+		"State":  map[string]interface{}{"Status": "running"},
+		"Config": map[string]interface{}{"Labels": map[string]string{labelID: "box-1"}},
+		"HostConfig": map[string]interface{}{
+			"CpuPeriod": 100000,
+			"CpuQuota":  150000,
+			"Memory":    1 << 30,
+		},
+	}
+	svc := newTestService(t, daemon)
+
+	box, err := svc.Update(context.Background(), "box-1", &model.BoxUpdateParams{CPU: 1.5, Memory: "1g"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100000), updated.CPUPeriod)
+	assert.Equal(t, int64(150000), updated.CPUQuota)
+	assert.Equal(t, int64(1<<30), updated.Memory)
+	assert.Equal(t, int64(2<<30), updated.MemorySwap)
+	assert.Equal(t, 1.5, box.Config.CPU)
+	assert.Equal(t, float64(1024), box.Config.Memory)
+
+	_, err = svc.Update(context.Background(), "box-missing", &model.BoxUpdateParams{CPU: 1})
+	assert.ErrorIs(t, err, service.ErrBoxNotFound)
+}
+
+func TestUpdateRejectsInvalidLimits(t *testing.T) {
+	daemon := &fakeDaemon{}
+	svc := newTestService(t, daemon)
+
+	for _, params := range []*model.BoxUpdateParams{
+		{},
+		{CPU: -1},
+		{CPU: 0.001},
+		{Memory: "lots"},
+	} {
+		_, err := svc.Update(context.Background(), "box-1", params)
+		assert.ErrorIs(t, err, service.ErrInvalidParams, "%+v", params)
+	}
+	assert.Empty(t, daemon.Calls())
+}
+
+// unavailableBackend is a tracker backend that is always down
+type unavailableBackend struct{}
+
+var errBackendDown = errors.New("backend down")
+
+func (unavailableBackend) Update(string, time.Time) error { return errBackendDown }
+func (unavailableBackend) GetLastAccessed(string) (time.Time, bool, error) {
+	return time.Time{}, false, errBackendDown
+}
+func (unavailableBackend) Remove(string) error { return errBackendDown }
+
+func TestBoxOperationsSurviveTrackerBackendFailure(t *testing.T) {
+	setupShareDir(t)
+	accessTracker := tracker.NewFallbackAccessTracker(unavailableBackend{})
+
+	var created map[string]interface{}
+	svc := newTestService(t, newCreateDaemon(&created))
+	svc.accessTracker = accessTracker
+	box, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{})
+	require.NoError(t, err)
+	assert.True(t, accessTracker.Degraded())
+
+	var removed []string
+	daemon := newGroupDaemon([]map[string]interface{}{
+		groupContainer("c1", box.ID, "", "running"),
+	}, &removed)
+	svc = newTestService(t, daemon)
+	svc.accessTracker = accessTracker
+
+	result, err := svc.Touch(context.Background(), box.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), result.LastAccessedAt, time.Minute, "in-memory times stand in for the backend")
+
+	reclaimed, err := svc.Reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, reclaimed.StoppedIDs, "recently touched boxes are kept")
+}
+
+func TestCreateLinuxBoxNameSuffix(t *testing.T) {
+	setupShareDir(t)
+
+	var name string
+	var created struct{ Labels map[string]string }
+	daemon := newCreateDaemon(&created)
+	daemon.inspect = nil
+	daemon.handlers["POST /containers/create"] = func(w http.ResponseWriter, r *http.Request) {
+		name = r.URL.Query().Get("name")
+		json.NewDecoder(r.Body).Decode(&created)
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(map[string]string{"Id": "c1"})(w, r)
+	}
+	// Only the ID label and the container ID resolve the box, not gbox-<id>
+	daemon.handlers["GET /containers/json"] = func(w http.ResponseWriter, r *http.Request) {
+		writeJSON([]map[string]interface{}{{"Id": "c1", "Names": []string{"/" + name}, "Labels": created.Labels, "State": "running"}})(w, r)
+	}
+	daemon.handlers["GET /containers/c1/json"] = func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(map[string]interface{}{
+			"Id":     "c1",
+			"Name":   "/" + name,
+			"State":  map[string]interface{}{"Status": "running"},
+			"Config": map[string]interface{}{"Labels": created.Labels},
+		})(w, r)
+	}
+	svc := newTestService(t, daemon)
+
+	box, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{NameSuffix: "my-app.v2"}})
+	require.NoError(t, err)
+	assert.Equal(t, "gbox-"+box.ID+"-my-app.v2", name)
+
+	found, err := svc.Get(context.Background(), box.ID)
+	require.NoError(t, err)
+	assert.Equal(t, box.ID, found.ID)
+
+	for _, suffix := range []string{"my app", "-app", "app/1", strings.Repeat("a", maxNameSuffixLength+1)} {
+		_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{NameSuffix: suffix}})
+		assert.ErrorIs(t, err, service.ErrInvalidParams, suffix)
+	}
+}
+
+func TestCreateLinuxBoxSysctls(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct {
+		HostConfig struct{ Sysctls map[string]string }
+	}
+	svc := newTestService(t, newCreateDaemon(&created))
+
+	sysctls := map[string]string{"net.core.somaxconn": "1024", "kernel.shmmax": "68719476736"}
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{Sysctls: sysctls}})
+	require.NoError(t, err)
+	assert.Equal(t, sysctls, created.HostConfig.Sysctls)
+
+	// vm.swappiness is not namespaced and would change the host
+	unsafe := map[string]string{"vm.swappiness": "10"}
+	for _, invalid := range []map[string]string{unsafe, {"net.core.somaxconn": ""}, {"net core": "1"}} {
+		created.HostConfig.Sysctls = nil
+		_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{Sysctls: invalid}})
+		assert.ErrorIs(t, err, service.ErrInvalidParams, "%v", invalid)
+		assert.Nil(t, created.HostConfig.Sysctls, "no container may be created for %v", invalid)
+	}
+
+	svc.allowUnsafeSysctls = true
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{Sysctls: unsafe}})
+	require.NoError(t, err)
+	assert.Equal(t, unsafe, created.HostConfig.Sysctls)
+}
+
+func TestPlanLinuxBoxCreatesNothing(t *testing.T) {
+	setupShareDir(t)
+
+	daemon := &fakeDaemon{}
+	svc := newTestService(t, daemon)
+
+	plan, err := svc.PlanLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		Envs:       map[string]string{"APP_ENV": "prod"},
+		Memory:     "512m",
+		DNSOptions: []string{"ndots:2"},
+		Sysctls:    map[string]string{"net.core.somaxconn": "1024"},
+		NameSuffix: "web",
+		Cmd:        []string{"python3", "-m", "http.server"},
+	}})
+	require.NoError(t, err)
+	assert.Empty(t, daemon.Calls(), "a dry run must not call the Docker daemon")
+	assert.NoDirExists(t, filepath.Join(config.GetInstance().File.Share, plan.ID))
+
+	assert.Equal(t, "gbox-"+plan.ID+"-web", plan.Name)
+	assert.Equal(t, GetImage(""), plan.Image)
+
+	// The spec serializes like the body of a container create request
+	var spec struct {
+		Config struct {
+			Image  string
+			Cmd    []string
+			Env    []string
+			Labels map[string]string
+		} `json:"config"`
+		HostConfig struct {
+			Memory     int64
+			DNSOptions []string `json:"DnsOptions"`
+			Sysctls    map[string]string
+		} `json:"hostConfig"`
+	}
+	data, err := json.Marshal(plan.Spec)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &spec))
+	assert.Equal(t, plan.Image, spec.Config.Image)
+	assert.Equal(t, []string{"python3", "-m", "http.server"}, spec.Config.Cmd)
+	assert.Contains(t, spec.Config.Env, "APP_ENV=prod")
+	assert.Equal(t, plan.ID, spec.Config.Labels[labelID])
+	assert.Equal(t, int64(512*1024*1024), spec.HostConfig.Memory)
+	assert.Equal(t, []string{"ndots:2"}, spec.HostConfig.DNSOptions)
+	assert.Equal(t, map[string]string{"net.core.somaxconn": "1024"}, spec.HostConfig.Sysctls)
+
+	_, err = svc.PlanLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{OomScoreAdj: 5000}})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
+
+func TestGetRedactsSecretEnv(t *testing.T) {
+	cluster := &config.GetInstance().Cluster
+	orig := *cluster
+	t.Cleanup(func() { *cluster = orig })
+	cluster.RedactEnv = []string{"*_TOKEN", "PASSWORD"}
+
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/gbox-box-1/json": writeJSON(map[string]interface{}{
+			"Id":    "c1",
+			"State": map[string]interface{}{"Status": "running"},
+			"Config": map[string]interface{}{
+				"Labels": map[string]string{labelID: "box-1"},
+				"Env":    []string{"GITHUB_TOKEN=ghp_secret", "password=hunter2", "LANG=C.UTF-8", "TOKEN_URL=https://auth"},
+			},
+		}),
+	}}
+	svc := newTestService(t, daemon)
+
+	box, err := svc.Get(context.Background(), "box-1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"GITHUB_TOKEN": service.RedactedValue,
+		"password":     service.RedactedValue,
+		"LANG":         "C.UTF-8",
+		"TOKEN_URL":    "https://auth",
+	}, box.Config.Envs)
+
+	body, err := json.Marshal(box)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "ghp_secret")
+	assert.NotContains(t, string(body), "hunter2")
+}
+
+func TestCreateLinuxBoxFromImageDigest(t *testing.T) {
+	setupShareDir(t)
+
+	const ref = "python@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	var created struct {
+		Image  string
+		Labels map[string]string
+	}
+	var pulled url.Values
+	daemon := newCreateDaemon(&created)
+	daemon.handlers["POST /images/create"] = func(w http.ResponseWriter, r *http.Request) {
+		pulled = r.URL.Query()
+		writeJSON(map[string]string{"status": "Downloaded newer image"})(w, r)
+	}
+	daemon.handlers["GET /images/"+ref+"/json"] = func(w http.ResponseWriter, r *http.Request) {
+		if pulled == nil {
+			http.Error(w, `{"message":"No such image"}`, http.StatusNotFound)
+			return
+		}
+		writeJSON(map[string]interface{}{"Id": "img"})(w, r)
+	}
+	daemon.inspect = map[string]interface{}{
+		"Id":     "c1",
+		"State":  map[string]interface{}{"Status": "running"},
+		"Config": map[string]interface{}{"Labels": map[string]string{labelID: "box-1", labelImage: ref}},
+	}
+	svc := newTestService(t, daemon)
+
+	box, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{Image: ref}})
+	require.NoError(t, err)
+	assert.Equal(t, ref, created.Image, "no tag is appended to a digest")
+	assert.Equal(t, ref, created.Labels[labelImage])
+	require.NotNil(t, pulled, "a missing digest-pinned image is pulled")
+	assert.Equal(t, "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", pulled.Get("tag"))
+	assert.Equal(t, "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", box.ImageDigest)
+
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{Image: "python@sha256:abc"}})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
+
+func TestImageReferenceDigests(t *testing.T) {
+	const ref = "registry:5000/team/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	assert.Equal(t, ref, EnsureImageTag(ref))
+	assert.Equal(t, ref, GetImage(ref))
+	assert.Equal(t, "python:latest", EnsureImageTag("python"))
+
+	repo, tag, ok := parseImageTag(ref)
+	assert.False(t, ok, "a digest reference has no tag")
+	assert.Equal(t, "registry:5000/team/app", repo)
+	assert.Empty(t, tag)
+}
+
+func TestCreateLinuxBoxImageDefaultCmd(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct{ Cmd []string }
+	daemon := newCreateDaemon(&created)
+	imageLabels := func(labels map[string]string) http.HandlerFunc {
+		return writeJSON(map[string]interface{}{"Id": "img", "Config": map[string]interface{}{"Labels": labels}})
+	}
+	daemon.handlers["GET /images/worker:latest/json"] = imageLabels(map[string]string{labelDefaultCmd: "python3 -m worker"})
+	daemon.handlers["GET /images/server:latest/json"] = imageLabels(map[string]string{labelDefaultCmd: `["node", "server.js"]`})
+	daemon.handlers["GET /images/plain:latest/json"] = imageLabels(nil)
+	daemon.handlers["GET /images/broken:latest/json"] = imageLabels(map[string]string{labelDefaultCmd: `["node",`})
+	svc := newTestService(t, daemon)
+
+	create := func(image string, cmd ...string) error {
+		created.Cmd = nil
+		_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+			Image: image,
+			Cmd:   cmd,
+		}})
+		return err
+	}
+
+	require.NoError(t, create("worker"))
+	assert.Equal(t, []string{"/bin/sh", "-c", "python3 -m worker"}, created.Cmd, "the labeled command is used")
+
+	require.NoError(t, create("worker", "sh", "-c", "env"))
+	assert.Equal(t, []string{"sh", "-c", "env"}, created.Cmd, "an explicit command overrides the label")
+
+	require.NoError(t, create("server"))
+	assert.Equal(t, []string{"node", "server.js"}, created.Cmd, "a JSON array label runs in exec form")
+
+	require.NoError(t, create("plain"))
+	assert.Nil(t, created.Cmd, "images without the label keep their own command")
+
+	assert.ErrorIs(t, create("broken"), service.ErrInvalidParams)
+}
+
+func TestCreateLinuxBoxRunsPostStartHook(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct{ Labels map[string]string }
+	var execCmd []string
+	exitCode := 0
+	daemon := newCreateDaemon(&created)
+	daemon.handlers["POST /containers/c1/exec"] = func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Cmd []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		execCmd = body.Cmd
+		writeJSON(map[string]string{"Id": "exec-1"})(w, r)
+	}
+	daemon.handlers["POST /exec/exec-1/start"] = noContent
+	daemon.handlers["GET /exec/exec-1/json"] = func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(map[string]interface{}{"Running": false, "ExitCode": exitCode})(w, r)
+	}
+	daemon.handlers["DELETE /containers/c1"] = noContent
+	svc := newTestService(t, daemon)
+
+	box, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		PostStart: "./seed.sh",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "box-1", box.ID)
+	assert.Equal(t, []string{"/bin/sh", "-c", "./seed.sh"}, execCmd)
+	assert.Equal(t, "./seed.sh", created.Labels[labelPostStart])
+	calls := daemon.Calls()
+	assert.Less(t, indexOf(calls, "POST /containers/c1/start"), indexOf(calls, "POST /exec/exec-1/start"), "post-start hook must run after start")
+
+	// A failing hook only fails the create when asked to
+	exitCode = 1
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		PostStart: "./seed.sh",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, -1, indexOf(daemon.Calls(), "DELETE /containers/c1"))
+
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		PostStart:            "./seed.sh",
+		PostStartFailOnError: true,
+	}})
+	assert.ErrorContains(t, err, "exited with code 1")
+	assert.NotEqual(t, -1, indexOf(daemon.Calls(), "DELETE /containers/c1"), "the failed box must be removed")
+	assert.NoDirExists(t, filepath.Join(config.GetInstance().File.Share, created.Labels[labelID]))
+
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		PostStartTimeout: "10s",
+	}})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+}
+
+func TestCreateLinuxBoxProvisionsFiles(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct{ Labels map[string]string }
+	daemon := newCreateDaemon(&created)
+	daemon.handlers["POST /containers/c1/exec"] = writeJSON(map[string]string{"Id": "exec-1"})
+	daemon.handlers["POST /exec/exec-1/start"] = noContent
+	daemon.handlers["GET /exec/exec-1/json"] = writeJSON(map[string]interface{}{"Running": false, "ExitCode": 0})
+	svc := newTestService(t, daemon)
+
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		PostStart: "./seed.sh",
+		Files: []model.ProvisionFile{
+			{Path: "seed/data.csv", Content: "id,name\n1,alice\n"},
+			{Path: common.DefaultShareDirPath + "/seed.sh", Content: base64.StdEncoding.EncodeToString([]byte("#!/bin/sh\n")), Encoding: "base64", Mode: "0755"},
+		},
+	}})
+	require.NoError(t, err)
+
+	shareDir := filepath.Join(config.GetInstance().File.Share, created.Labels[labelID])
+	data, err := os.ReadFile(filepath.Join(shareDir, "seed", "data.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "id,name\n1,alice\n", string(data))
+	info, err := os.Stat(filepath.Join(shareDir, "seed.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	calls := daemon.Calls()
+	assert.Less(t, indexOf(calls, "POST /containers/c1/start"), indexOf(calls, "POST /exec/exec-1/start"))
+
+	for _, f := range []model.ProvisionFile{
+		{Path: "../escape"},
+		{Path: "/etc/passwd"},
+		{Path: "a", Encoding: "hex"},
+		{Path: "a", Mode: "999"},
+		{Path: "a", Source: "./a"},
+	} {
+		_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+			Files: []model.ProvisionFile{f},
+		}})
+		assert.ErrorIs(t, err, service.ErrInvalidParams, "%+v", f)
+	}
+}
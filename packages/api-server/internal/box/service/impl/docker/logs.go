@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// maxLogLineSize bounds a single log line when filtering
+const maxLogLineSize = 1024 * 1024
+
+// Logs returns the stdout and stderr of a box's main process as lines of
+// text. With Grep, lines are filtered here so unmatched ones never leave
+// the server.
+func (s *Service) Logs(ctx context.Context, id string, params *model.BoxLogsParams) (io.ReadCloser, error) {
+	var pattern *regexp.Regexp
+	if params.Grep != "" {
+		var err error
+		if pattern, err = regexp.Compile(params.Grep); err != nil {
+			return nil, fmt.Errorf("%w: invalid grep pattern: %v", service.ErrInvalidParams, err)
+		}
+	} else if params.InvertMatch {
+		return nil, fmt.Errorf("%w: invertMatch requires grep", service.ErrInvalidParams)
+	}
+	if params.Tail != "" && params.Tail != "all" {
+		if n, err := strconv.Atoi(params.Tail); err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: tail must be a number of lines or \"all\", got %q", service.ErrInvalidParams, params.Tail)
+		}
+	}
+
+	containerInfo, err := s.getContainerByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := s.client.ContainerLogs(ctx, containerInfo.ID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     params.Follow,
+		Tail:       params.Tail,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs of box %s: %w", id, err)
+	}
+
+	// Boxes run without a TTY, so stdout and stderr arrive multiplexed
+	demuxed, demuxWriter := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(demuxWriter, demuxWriter, logs)
+		demuxWriter.CloseWithError(err)
+	}()
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer logs.Close()
+		defer demuxed.Close()
+		lines := bufio.NewScanner(demuxed)
+		lines.Buffer(make([]byte, 0, 64*1024), maxLogLineSize)
+		var line []byte
+		for lines.Scan() {
+			if pattern != nil && pattern.Match(lines.Bytes()) == params.InvertMatch {
+				continue
+			}
+			line = append(append(line[:0], lines.Bytes()...), '\n')
+			if _, err := writer.Write(line); err != nil {
+				return
+			}
+		}
+		writer.CloseWithError(lines.Err())
+	}()
+	return reader, nil
+}
@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+func newLogsDaemon(lines ...string) *fakeDaemon {
+	return &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{
+			"Id":     "c1",
+			"State":  "running",
+			"Labels": map[string]string{labelID: "box-1"},
+		}}),
+		"GET /containers/c1/logs": logLines(0, false, lines...),
+	}}
+}
+
+func TestLogsGrep(t *testing.T) {
+	svc := newTestService(t, newLogsDaemon("GET /api 200", "error: boom", "GET /health 200", "error: again"))
+	read := func(params *model.BoxLogsParams) string {
+		logs, err := svc.Logs(context.Background(), "box-1", params)
+		require.NoError(t, err)
+		defer logs.Close()
+		out, err := io.ReadAll(logs)
+		require.NoError(t, err)
+		return string(out)
+	}
+
+	assert.Equal(t, "GET /api 200\nerror: boom\nGET /health 200\nerror: again\n", read(&model.BoxLogsParams{}))
+	assert.Equal(t, "error: boom\nerror: again\n", read(&model.BoxLogsParams{Grep: "^error"}))
+	assert.Equal(t, "GET /api 200\nGET /health 200\n", read(&model.BoxLogsParams{Grep: "^error", InvertMatch: true}))
+
+	for _, params := range []*model.BoxLogsParams{{Grep: "("}, {InvertMatch: true}, {Tail: "-1"}} {
+		_, err := svc.Logs(context.Background(), "box-1", params)
+		assert.ErrorIs(t, err, service.ErrInvalidParams)
+	}
+}
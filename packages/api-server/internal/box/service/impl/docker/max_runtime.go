@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// validateMaxRuntime checks the max runtime of a create request
+func validateMaxRuntime(cfg model.CreateBoxConfigParam) error {
+	if cfg.MaxRuntime == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(cfg.MaxRuntime); err != nil || d <= 0 {
+		return fmt.Errorf("%w: invalid max runtime %q", service.ErrInvalidParams, cfg.MaxRuntime)
+	}
+	return nil
+}
+
+// EnforceMaxRuntime implements Service.EnforceMaxRuntime
+func (s *Service) EnforceMaxRuntime(ctx context.Context) (*model.BoxMaxRuntimeResult, error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", fmt.Sprintf("%s=gbox", labelName))
+	filterArgs.Add("label", labelMaxRuntime)
+	filterArgs.Add("status", "running")
+
+	containers, err := s.client.ContainerList(ctx, types.ContainerListOptions{Filters: filterArgs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	result := &model.BoxMaxRuntimeResult{}
+	for _, c := range containers {
+		boxID := c.Labels[labelID]
+		maxRuntime, err := time.ParseDuration(c.Labels[labelMaxRuntime])
+		if err != nil || maxRuntime <= 0 {
+			s.logger.Warn("Box %s has an invalid max runtime %q, skipping", boxID, c.Labels[labelMaxRuntime])
+			continue
+		}
+
+		// The runtime counts from the last start, so a restarted box gets its full runtime again
+		info, err := s.client.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			s.logger.Error("Failed to inspect container %s: %v", c.ID, err)
+			continue
+		}
+		startedAt, err := time.Parse(time.RFC3339Nano, info.State.StartedAt)
+		if err != nil {
+			continue
+		}
+		if runtime := time.Since(startedAt); runtime < maxRuntime {
+			continue
+		}
+
+		s.logger.Warn("Box %s has been running for longer than its max runtime of %v, stopping it", boxID, maxRuntime)
+		if err := s.stopContainer(ctx, c.ID, c.Labels); err != nil {
+			s.logger.Error("Failed to stop container %s: %v", c.ID, err)
+			continue
+		}
+		result.StoppedCount++
+		result.StoppedIDs = append(result.StoppedIDs, boxID)
+	}
+	return result, nil
+}
+
+// maxRuntimeExceeded reports whether a stopped box ran for at least its max
+// runtime, i.e. it was stopped by EnforceMaxRuntime or finished too late.
+// Deriving this from the container state keeps it across server restarts.
+func maxRuntimeExceeded(labels map[string]string, state *types.ContainerState) (string, bool) {
+	if state == nil || state.Status != "exited" || labels[labelMaxRuntime] == "" {
+		return "", false
+	}
+	maxRuntime, err := time.ParseDuration(labels[labelMaxRuntime])
+	if err != nil || maxRuntime <= 0 {
+		return "", false
+	}
+	startedAt, err := time.Parse(time.RFC3339Nano, state.StartedAt)
+	if err != nil {
+		return "", false
+	}
+	finishedAt, err := time.Parse(time.RFC3339Nano, state.FinishedAt)
+	if err != nil || finishedAt.Sub(startedAt) < maxRuntime {
+		return "", false
+	}
+	return fmt.Sprintf("exceeded its max runtime of %v", maxRuntime), true
+}
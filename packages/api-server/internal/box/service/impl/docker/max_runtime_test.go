@@ -0,0 +1,86 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// newMaxRuntimeDaemon fakes a running box with a 1m max runtime that was
+// started at startedAt
+func newMaxRuntimeDaemon(startedAt time.Time) *fakeDaemon {
+	labels := map[string]string{labelID: "box-1", labelMaxRuntime: "1m"}
+	return &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": writeJSON([]map[string]interface{}{{"Id": "c1", "State": "running", "Labels": labels}}),
+		"GET /containers/c1/json": writeJSON(map[string]interface{}{
+			"Id":     "c1",
+			"State":  map[string]interface{}{"Status": "running", "StartedAt": startedAt.Format(time.RFC3339Nano)},
+			"Config": map[string]interface{}{"Labels": labels},
+		}),
+		"POST /containers/c1/stop": noContent,
+	}}
+}
+
+func TestEnforceMaxRuntime(t *testing.T) {
+	daemon := newMaxRuntimeDaemon(time.Now().Add(-2 * time.Hour))
+	svc := newTestService(t, daemon)
+
+	result, err := svc.EnforceMaxRuntime(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.StoppedCount)
+	assert.Equal(t, []string{"box-1"}, result.StoppedIDs)
+	assert.Contains(t, daemon.Calls(), "POST /containers/c1/stop")
+
+	// A box still within its max runtime keeps running
+	daemon = newMaxRuntimeDaemon(time.Now().Add(-30 * time.Second))
+	svc = newTestService(t, daemon)
+
+	result, err = svc.EnforceMaxRuntime(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, result.StoppedCount)
+	assert.NotContains(t, daemon.Calls(), "POST /containers/c1/stop")
+}
+
+func TestGetMarksBoxOverMaxRuntimeFailed(t *testing.T) {
+	startedAt := time.Now().Add(-2 * time.Hour)
+	inspect := func(finishedAt time.Time) *fakeDaemon {
+		return &fakeDaemon{handlers: map[string]http.HandlerFunc{
+			"GET /containers/gbox-box-1/json": writeJSON(map[string]interface{}{
+				"Id": "c1",
+				"State": map[string]interface{}{
+					"Status":     "exited",
+					"StartedAt":  startedAt.Format(time.RFC3339Nano),
+					"FinishedAt": finishedAt.Format(time.RFC3339Nano),
+				},
+				"Config": map[string]interface{}{"Labels": map[string]string{labelID: "box-1", labelMaxRuntime: "1m"}},
+			}),
+		}}
+	}
+
+	box, err := newTestService(t, inspect(startedAt.Add(time.Hour))).Get(context.Background(), "box-1")
+	require.NoError(t, err)
+	assert.Equal(t, model.BoxStatusFailed, box.Status)
+	assert.Equal(t, "exceeded its max runtime of 1m0s", box.StatusReason)
+
+	box, err = newTestService(t, inspect(startedAt.Add(10*time.Second))).Get(context.Background(), "box-1")
+	require.NoError(t, err)
+	assert.Equal(t, "stopped", box.Status, "a box that finished in time is not failed")
+	assert.Empty(t, box.StatusReason)
+}
+
+func TestCreateLinuxBoxInvalidMaxRuntime(t *testing.T) {
+	daemon := &fakeDaemon{}
+	svc := newTestService(t, daemon)
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{
+		Config: model.CreateBoxConfigParam{MaxRuntime: "-5m"},
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidParams)
+	assert.Empty(t, daemon.Calls())
+}
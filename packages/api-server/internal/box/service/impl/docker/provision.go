@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// defaultProvisionFileMode is the permission of provisioned files that do
+// not set one
+const defaultProvisionFileMode os.FileMode = 0644
+
+// provisionFile is a validated file of a create request
+type provisionFile struct {
+	path string // Cleaned path relative to the share directory
+	data []byte
+	mode os.FileMode
+}
+
+// parseProvisionFiles validates the files of a create request and decodes
+// their content
+func parseProvisionFiles(files []model.ProvisionFile) ([]provisionFile, error) {
+	parsed := make([]provisionFile, 0, len(files))
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		rel, err := provisionPath(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		if seen[rel] {
+			return nil, fmt.Errorf("%w: file %q is provisioned more than once", service.ErrInvalidParams, f.Path)
+		}
+		seen[rel] = true
+
+		if f.Source != "" {
+			return nil, fmt.Errorf("%w: file %q has a local source, which the client must send as content", service.ErrInvalidParams, f.Path)
+		}
+
+		var data []byte
+		switch f.Encoding {
+		case "":
+			data = []byte(f.Content)
+		case model.ProvisionFileEncodingBase64:
+			data, err = base64.StdEncoding.DecodeString(f.Content)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid base64 content of file %q: %v", service.ErrInvalidParams, f.Path, err)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported encoding %q of file %q", service.ErrInvalidParams, f.Encoding, f.Path)
+		}
+
+		mode := defaultProvisionFileMode
+		if f.Mode != "" {
+			m, err := strconv.ParseUint(f.Mode, 8, 32)
+			if err != nil || m > 0777 {
+				return nil, fmt.Errorf("%w: invalid mode %q of file %q", service.ErrInvalidParams, f.Mode, f.Path)
+			}
+			mode = os.FileMode(m)
+		}
+
+		parsed = append(parsed, provisionFile{path: rel, data: data, mode: mode})
+	}
+	return parsed, nil
+}
+
+// provisionPath returns a provisioned file's path relative to the share
+// directory, rejecting paths that leave it
+func provisionPath(path string) (string, error) {
+	rel := path
+	if strings.HasPrefix(path, "/") {
+		clean := filepath.Clean(path)
+		if !strings.HasPrefix(clean, common.DefaultShareDirPath+"/") {
+			return "", fmt.Errorf("%w: file %q is outside the share directory %s", service.ErrInvalidParams, path, common.DefaultShareDirPath)
+		}
+		rel = strings.TrimPrefix(clean, common.DefaultShareDirPath+"/")
+	}
+	rel = filepath.Clean(rel)
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%w: invalid file path %q", service.ErrInvalidParams, path)
+	}
+	return rel, nil
+}
+
+// writeProvisionFiles writes files into a box's share directory. The box is
+// already running, so directories it may have replaced with symlinks are
+// not followed out of the share directory.
+func writeProvisionFiles(shareDir string, files []provisionFile) error {
+	root, err := filepath.EvalSymlinks(shareDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve share directory: %w", err)
+	}
+	for _, f := range files {
+		fullPath := filepath.Join(root, f.path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for file %s: %w", f.path, err)
+		}
+		parent, err := filepath.EvalSymlinks(filepath.Dir(fullPath))
+		if err != nil {
+			return fmt.Errorf("failed to resolve directory of file %s: %w", f.path, err)
+		}
+		if parent != root && !strings.HasPrefix(parent, root+string(filepath.Separator)) {
+			return fmt.Errorf("file %s resolves outside the share directory", f.path)
+		}
+
+		file, err := os.OpenFile(filepath.Join(parent, filepath.Base(fullPath)), os.O_WRONLY|os.O_CREATE|os.O_TRUNC|syscall.O_NOFOLLOW, f.mode)
+		if err != nil {
+			return fmt.Errorf("failed to provision file %s: %w", f.path, err)
+		}
+		_, err = file.Write(f.data)
+		if err == nil {
+			// The mode given at creation is subject to the umask
+			err = file.Chmod(f.mode)
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to provision file %s: %w", f.path, err)
+		}
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/go-connections/nat"
 
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
 )
 
@@ -57,11 +58,16 @@ func (s *Service) List(ctx context.Context, params *model.BoxListParams) (*model
 			}
 		case "ancestor":
 			filterArgs.Add("ancestor", filter.Value)
+		case "group":
+			filterArgs.Add("label", fmt.Sprintf("%s=%s", labelGroup, filter.Value))
+		case "owner":
+			filterArgs.Add("label", fmt.Sprintf("%s=%s", labelOwner, filter.Value))
 		}
 	}
 
 	containers, err := s.client.ContainerList(ctx, types.ContainerListOptions{
 		All:     true,
+		Size:    params.Size,
 		Filters: filterArgs,
 	})
 	if err != nil {
@@ -70,7 +76,19 @@ func (s *Service) List(ctx context.Context, params *model.BoxListParams) (*model
 
 	boxes := make([]model.Box, 0, len(containers))
 	for i := range containers {
-		boxes = append(boxes, *containerToBox(&containers[i]))
+		box := containerToBox(&containers[i])
+		if params.Size {
+			sizeRw, sizeRootFs := containers[i].SizeRw, containers[i].SizeRootFs
+			box.SizeRw, box.SizeRootFs = &sizeRw, &sizeRootFs
+		}
+		// The list does not carry start times, so running boxes are inspected
+		// for their uptime; a box that vanished meanwhile just reports none
+		if containers[i].State == "running" {
+			if info, err := s.client.ContainerInspect(ctx, containers[i].ID); err == nil {
+				box.StartedAt, box.Uptime = boxUptime(info.State)
+			}
+		}
+		boxes = append(boxes, *box)
 	}
 
 	return &model.BoxListResult{
@@ -105,9 +123,9 @@ func (s *Service) GetExternalPort(ctx context.Context, id string, internalPort i
 		// Port not found or not published
 		// Check if the port *was* exposed but just not published
 		if _, exposed := containerJSON.Config.ExposedPorts[internalNatPort]; exposed {
-			return 0, fmt.Errorf("internal port %d is exposed but not published for box %s", internalPort, id)
+			return 0, fmt.Errorf("%w: internal port %d is exposed but not published for box %s", service.ErrPortNotPublished, internalPort, id)
 		}
-		return 0, fmt.Errorf("internal port %d not exposed or mapped for box %s", internalPort, id)
+		return 0, fmt.Errorf("%w: internal port %d not exposed or mapped for box %s", service.ErrPortNotPublished, internalPort, id)
 	}
 
 	// Use the first available binding.
@@ -0,0 +1,206 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+)
+
+// Terminal size written to a cast header when the client does not report one
+const (
+	defaultCastWidth  = 80
+	defaultCastHeight = 24
+)
+
+// castHeader is the first line of an asciinema v2 cast file
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Command   string            `json:"command,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// castRecorder writes the input and output of an exec session as an
+// asciinema v2 cast file: a JSON header line followed by one
+// [elapsed seconds, "o" or "i", data] event per line.
+type castRecorder struct {
+	mu    sync.Mutex
+	file  *os.File
+	start time.Time
+	// Trailing bytes of an incomplete UTF-8 sequence, per event type, held
+	// back until the rest of the character arrives
+	partial map[string][]byte
+}
+
+// resolveSharePath maps a path relative to the box's share directory to its
+// host path, such as the file a recording or command output is written to
+func resolveSharePath(boxID, path string) (string, error) {
+	if path == "" || filepath.IsAbs(path) {
+		return "", fmt.Errorf("%w: path %q must be relative to the box share directory", service.ErrInvalidParams, path)
+	}
+	root, _ := config.GetInstance().File.BoxShareDir(boxID)
+	full := filepath.Join(root, path)
+	if !strings.HasPrefix(full, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: path %q escapes the box share directory", service.ErrInvalidParams, path)
+	}
+	return full, nil
+}
+
+// createShareFile creates, or truncates, a file at a path relative to the
+// box's share directory. The box can write to the directory, so symlinks it
+// planted are not followed out of it: the parent directory must resolve
+// inside the share directory and the file itself must not be a symlink.
+func createShareFile(boxID, path string) (*os.File, error) {
+	full, err := resolveSharePath(boxID, path)
+	if err != nil {
+		return nil, err
+	}
+	shareDir, _ := config.GetInstance().File.BoxShareDir(boxID)
+	if err := os.MkdirAll(shareDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create share directory: %w", err)
+	}
+	root, err := filepath.EvalSymlinks(shareDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve share directory: %w", err)
+	}
+	full = filepath.Join(root, strings.TrimPrefix(full, shareDir+string(filepath.Separator)))
+
+	// Check the nearest existing ancestor before creating the missing
+	// directories below it, so none are created through a symlink
+	existing, missing := filepath.Dir(full), ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			existing = resolved
+			break
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to resolve directory of %s: %w", path, err)
+		}
+		missing = filepath.Join(filepath.Base(existing), missing)
+		existing = filepath.Dir(existing)
+	}
+	if !withinDir(root, existing) {
+		return nil, fmt.Errorf("%w: path %q resolves outside the box share directory", service.ErrInvalidParams, path)
+	}
+	if err := os.MkdirAll(filepath.Join(existing, missing), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory of %s: %w", path, err)
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Join(existing, missing))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory of %s: %w", path, err)
+	}
+	if !withinDir(root, parent) {
+		return nil, fmt.Errorf("%w: path %q resolves outside the box share directory", service.ErrInvalidParams, path)
+	}
+	file, err := os.OpenFile(filepath.Join(parent, filepath.Base(full)), os.O_WRONLY|os.O_CREATE|os.O_TRUNC|syscall.O_NOFOLLOW, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// newCastRecorder writes the cast header to file and records to it
+func newCastRecorder(file *os.File, width, height int, command []string) (*castRecorder, error) {
+	if width <= 0 {
+		width = defaultCastWidth
+	}
+	if height <= 0 {
+		height = defaultCastHeight
+	}
+
+	r := &castRecorder{file: file, start: time.Now(), partial: make(map[string][]byte)}
+	header, _ := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Command:   strings.Join(command, " "),
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	})
+	if _, err := file.Write(append(header, '\n')); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write recording header: %w", err)
+	}
+	return r, nil
+}
+
+// output returns a writer recording everything written to it as output events
+func (r *castRecorder) output() writerFunc {
+	return func(p []byte) (int, error) {
+		r.record("o", p)
+		return len(p), nil
+	}
+}
+
+// input records data sent to the command's stdin
+func (r *castRecorder) input(p []byte) {
+	r.record("i", p)
+}
+
+// record writes an event. Failures are not reported so a full disk never
+// interrupts the session being recorded.
+func (r *castRecorder) record(kind string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+
+	data := append(r.partial[kind], p...)
+	// Hold back a trailing incomplete character so it is not replaced by
+	// U+FFFD when the event is encoded
+	complete := completeUTF8Len(data)
+	r.partial[kind] = append([]byte(nil), data[complete:]...)
+	if complete == 0 {
+		return
+	}
+
+	elapsed := time.Since(r.start).Seconds()
+	event, _ := json.Marshal([]interface{}{elapsed, kind, string(data[:complete])})
+	r.file.Write(append(event, '\n'))
+}
+
+// completeUTF8Len returns the length of data without a trailing incomplete
+// UTF-8 character, whose remaining bytes are still to be written
+func completeUTF8Len(data []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if b := data[len(data)-i]; utf8.RuneStart(b) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return len(data) - i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// Close flushes held back bytes and closes the cast file
+func (r *castRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	elapsed := time.Since(r.start).Seconds()
+	for _, kind := range []string{"o", "i"} {
+		if len(r.partial[kind]) > 0 {
+			event, _ := json.Marshal([]interface{}{elapsed, kind, string(r.partial[kind])})
+			r.file.Write(append(event, '\n'))
+		}
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,163 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// readCast parses a cast file into its header and events
+func readCast(t *testing.T, path string) (castHeader, [][]interface{}) {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	lines := bufio.NewScanner(file)
+	require.True(t, lines.Scan(), "cast file has no header")
+	var header castHeader
+	require.NoError(t, json.Unmarshal(lines.Bytes(), &header))
+
+	var events [][]interface{}
+	for lines.Scan() {
+		var event []interface{}
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &event), "invalid event line %q", lines.Text())
+		require.Len(t, event, 3)
+		events = append(events, event)
+	}
+	return header, events
+}
+
+func TestExecWSRecordsCastFile(t *testing.T) {
+	setupShareDir(t)
+	svc := newTestService(t, newInteractiveExecDaemon())
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		svc.ExecWS(r.Context(), "box-1", &model.BoxExecWSParams{
+			Cmd:    []string{"sh"},
+			TTY:    true,
+			Record: "casts/session.cast",
+			Cols:   120,
+			Rows:   40,
+		}, conn)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, []byte("hello\n")))
+	readUntil(t, conn, "hello\n")
+	require.NoError(t, conn.WriteJSON(map[string]string{"type": "stdin_eof"}))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "unexpected error: %v", err)
+
+	header, events := readCast(t, filepath.Join(config.GetInstance().File.Share, "box-1", "casts", "session.cast"))
+	assert.Equal(t, 2, header.Version)
+	assert.Equal(t, 120, header.Width)
+	assert.Equal(t, 40, header.Height)
+	assert.Equal(t, "sh", header.Command)
+
+	var output, input strings.Builder
+	for _, event := range events {
+		assert.GreaterOrEqual(t, event[0].(float64), 0.0)
+		switch event[1] {
+		case "o":
+			output.WriteString(event[2].(string))
+		case "i":
+			input.WriteString(event[2].(string))
+		}
+	}
+	assert.Equal(t, "hello\n", output.String())
+	assert.Equal(t, "hello\n", input.String())
+}
+
+func TestExecWSRecordingValidation(t *testing.T) {
+	setupShareDir(t)
+	daemon := newInteractiveExecDaemon()
+	svc := newTestService(t, daemon)
+
+	for _, params := range []*model.BoxExecWSParams{
+		{Cmd: []string{"sh"}, Record: "../other-box/session.cast"},
+		{Cmd: []string{"sh"}, Record: "/tmp/session.cast"},
+		{Cmd: []string{"sh"}, Record: "session.cast", Detach: true},
+	} {
+		_, err := svc.ExecWS(context.Background(), "box-1", params, nil)
+		assert.ErrorIs(t, err, service.ErrInvalidParams, "record %q", params.Record)
+	}
+	assert.NotContains(t, daemon.Calls(), "POST /containers/c1/exec")
+}
+
+func TestExecWSRecordingDoesNotFollowBoxSymlinks(t *testing.T) {
+	setupShareDir(t)
+	daemon := newInteractiveExecDaemon()
+	svc := newTestService(t, daemon)
+
+	// The box plants links in its share directory to files and directories of the host
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "shadow")
+	require.NoError(t, os.WriteFile(secret, []byte("root:x:0:0"), 0600))
+	shareDir := filepath.Join(config.GetInstance().File.Share, "box-1")
+	require.NoError(t, os.MkdirAll(shareDir, 0755))
+	require.NoError(t, os.Symlink(secret, filepath.Join(shareDir, "planted.cast")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(shareDir, "planted")))
+	t.Cleanup(func() {
+		os.Remove(filepath.Join(shareDir, "planted.cast"))
+		os.Remove(filepath.Join(shareDir, "planted"))
+	})
+
+	for _, record := range []string{"planted.cast", "planted/session.cast", "planted/newdir/deeper/session.cast"} {
+		_, err := svc.ExecWS(context.Background(), "box-1", &model.BoxExecWSParams{Cmd: []string{"sh"}, TTY: true, Record: record}, nil)
+		assert.Error(t, err, "record %q", record)
+	}
+	data, err := os.ReadFile(secret)
+	require.NoError(t, err)
+	assert.Equal(t, "root:x:0:0", string(data), "the host file must not be truncated")
+	assert.NoFileExists(t, filepath.Join(outside, "session.cast"))
+	assert.NoDirExists(t, filepath.Join(outside, "newdir"), "no directory may be created outside the share directory")
+	assert.NotContains(t, daemon.Calls(), "POST /containers/c1/exec")
+}
+
+func TestCastRecorderKeepsSplitCharacters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "split.cast")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	recorder, err := newCastRecorder(file, 0, 0, []string{"echo"})
+	require.NoError(t, err)
+
+	// "é" split across two writes must be recorded as one character
+	out := recorder.output()
+	out.Write([]byte("caf\xc3"))
+	out.Write([]byte("\xa9\n"))
+	require.NoError(t, recorder.Close())
+
+	header, events := readCast(t, path)
+	assert.Equal(t, defaultCastWidth, header.Width)
+	assert.Equal(t, defaultCastHeight, header.Height)
+	var output strings.Builder
+	for _, event := range events {
+		output.WriteString(event[2].(string))
+	}
+	assert.Equal(t, "café\n", output.String())
+}
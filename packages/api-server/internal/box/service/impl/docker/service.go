@@ -8,6 +8,7 @@ import (
 	"github.com/babelcloud/gbox/packages/api-server/config"
 	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
 	"github.com/babelcloud/gbox/packages/api-server/internal/tracker"
+	"github.com/babelcloud/gbox/packages/api-server/pkg/id"
 	"github.com/babelcloud/gbox/packages/api-server/pkg/logger"
 )
 
@@ -17,6 +18,14 @@ type Service struct {
 	logger        *logger.Logger
 	accessTracker tracker.AccessTracker
 	imageService  *ImageService
+	execSessions  *execSessionStore
+	imageCache    *imagePresenceCache
+
+	allowRawDockerOpts     bool
+	allowDockerSocketMount bool
+	defaultEnv             map[string]string // Environment injected into every box
+	boxIDs                 id.Generator      // Generates the IDs of new boxes
+	allowUnsafeSysctls     bool
 }
 
 // NewService creates a new Docker service instance.
@@ -24,6 +33,11 @@ func NewService(tracker tracker.AccessTracker) (*Service, error) {
 	cfg := config.GetInstance()
 	dockerHost := cfg.Cluster.Docker.Host
 
+	boxIDs, err := id.NewGenerator(id.Format(cfg.Cluster.BoxIDFormat), cfg.Cluster.BoxIDPrefix)
+	if err != nil {
+		return nil, err
+	}
+
 	cli, err := client.NewClientWithOpts(client.WithHost(dockerHost))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Docker client: %w", err)
@@ -31,8 +45,10 @@ func NewService(tracker tracker.AccessTracker) (*Service, error) {
 
 	log := logger.New()
 
+	imageCache := newImagePresenceCache(defaultImageCacheTTL)
+
 	// Create and start ImageService.
-	imageService := NewImageService(cli, log)
+	imageService := NewImageService(cli, log, imageCache)
 	imageService.Start()
 
 	return &Service{
@@ -40,6 +56,14 @@ func NewService(tracker tracker.AccessTracker) (*Service, error) {
 		logger:        log,
 		accessTracker: tracker,
 		imageService:  imageService,
+		execSessions:  newExecSessionStore(),
+		imageCache:    imageCache,
+
+		allowRawDockerOpts:     cfg.Cluster.Docker.AllowRawDockerOpts,
+		allowDockerSocketMount: cfg.Cluster.Docker.AllowDockerSocketMount,
+		defaultEnv:             cfg.Cluster.DefaultEnv,
+		boxIDs:                 *boxIDs,
+		allowUnsafeSysctls:     cfg.Cluster.AllowUnsafeSysctls,
 	}, nil
 }
 
@@ -58,4 +82,4 @@ func init() {
 	service.Register("docker", func(tracker tracker.AccessTracker) (service.BoxService, error) {
 		return NewService(tracker)
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// snapshotRepository is the image repository reclaim commits boxes to before
+// deleting them, tagged with the box ID
+const snapshotRepository = "gbox-snapshot"
+
+// boxSnapshot records a box reclaim committed to an image and deleted, with
+// the container spec it is resurrected from
+type boxSnapshot struct {
+	BoxID      string                `json:"boxId"`
+	Image      string                `json:"image"`
+	Name       string                `json:"name"`
+	CreatedAt  time.Time             `json:"createdAt"`
+	Config     *container.Config     `json:"config"`
+	HostConfig *container.HostConfig `json:"hostConfig"`
+}
+
+// snapshotPath returns the file of the snapshot record of a box. Records are
+// kept under the server's home directory, next to the share directory.
+func snapshotPath(boxID string) string {
+	return filepath.Join(config.GetInstance().File.Home, "snapshots", boxID+".json")
+}
+
+// snapshotBox commits a stopped box to an image and records how to create
+// it again. The box itself is left for the caller to delete.
+func (s *Service) snapshotBox(ctx context.Context, containerID, boxID string) error {
+	info, err := s.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	image := snapshotRepository + ":" + boxID
+	if _, err := s.client.ContainerCommit(ctx, containerID, container.CommitOptions{
+		Reference: image,
+		Comment:   "snapshot of reclaimed box " + boxID,
+	}); err != nil {
+		return fmt.Errorf("failed to commit container: %w", err)
+	}
+
+	record, err := json.MarshalIndent(boxSnapshot{
+		BoxID:      boxID,
+		Image:      image,
+		Name:       strings.TrimPrefix(info.Name, "/"),
+		CreatedAt:  time.Now(),
+		Config:     info.Config,
+		HostConfig: info.HostConfig,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot record: %w", err)
+	}
+	path := snapshotPath(boxID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	// Write then rename, so a crash never leaves a truncated record behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, record, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot record: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write snapshot record: %w", err)
+	}
+	s.logger.Info("Committed box %s to %s", boxID, image)
+	return nil
+}
+
+// loadSnapshot reads the snapshot record of a box
+func loadSnapshot(boxID string) (*boxSnapshot, error) {
+	if boxID == "" || filepath.Base(boxID) != boxID || strings.HasPrefix(boxID, ".") {
+		return nil, fmt.Errorf("%w: invalid box ID %q", service.ErrInvalidParams, boxID)
+	}
+	data, err := os.ReadFile(snapshotPath(boxID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no snapshot of box %s: %w", boxID, service.ErrBoxNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot record: %w", err)
+	}
+	var record boxSnapshot
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("invalid snapshot record of box %s: %w", boxID, err)
+	}
+	if record.Config == nil || record.HostConfig == nil {
+		return nil, fmt.Errorf("invalid snapshot record of box %s: no container spec", boxID)
+	}
+	return &record, nil
+}
+
+// Resurrect implements Service.Resurrect
+func (s *Service) Resurrect(ctx context.Context, id string) (*model.Box, error) {
+	if err := s.boxIDs.Validate(id); err != nil {
+		return nil, fmt.Errorf("%w: %v", service.ErrInvalidParams, err)
+	}
+	record, err := loadSnapshot(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.inspectContainerByID(ctx, id); err == nil {
+		return nil, fmt.Errorf("%w: box %s already exists", service.ErrInvalidParams, id)
+	} else if !errors.Is(err, service.ErrBoxNotFound) {
+		return nil, err
+	}
+
+	// The box keeps its ID, labels and mounts, so it finds its share
+	// directory again; only the image is swapped for the snapshot
+	containerConfig := *record.Config
+	containerConfig.Image = record.Image
+	resp, err := s.client.ContainerCreate(ctx, &containerConfig, record.HostConfig, nil, nil, record.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+	if err := s.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+	s.accessTracker.Update(id)
+
+	// The image stays, the resurrected box runs from it
+	if err := os.Remove(snapshotPath(id)); err != nil {
+		s.logger.Warn("Failed to remove snapshot record of box %s: %v", id, err)
+	}
+
+	containerInfo, err := s.inspectContainerByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container details after start: %w", err)
+	}
+	box := containerToBox(containerInfo)
+	box.Connection = boxConnection(id, containerInfo)
+	return box, nil
+}
@@ -0,0 +1,156 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	"github.com/babelcloud/gbox/packages/api-server/internal/tracker"
+)
+
+func TestReclaimSnapshotsBoxAndResurrectsIt(t *testing.T) {
+	cfg := config.GetInstance()
+	origCluster, origHome := cfg.Cluster, cfg.File.Home
+	t.Cleanup(func() { cfg.Cluster, cfg.File.Home = origCluster, origHome })
+	cfg.File.Home = t.TempDir()
+	cfg.Cluster.ReclaimDeleteThreshold = 24 * time.Hour
+	cfg.Cluster.ReclaimDeleteEnabled = true
+	cfg.Cluster.ReclaimSnapshotEnabled = true
+
+	labels := map[string]string{labelID: "550e8400-e29b-41d4-a716-446655440000", labelName: "gbox"}
+	spec := map[string]interface{}{
+		"Image":  "babelcloud/gbox-playwright",
+		"Env":    []string{"APP_ENV=staging"},
+		"Cmd":    []string{"sleep", "infinity"},
+		"Labels": labels,
+	}
+	mounts := []map[string]string{{"Type": "bind", "Source": "/host/share/550e8400-e29b-41d4-a716-446655440000", "Target": "/var/gbox/share"}}
+
+	var removed, resurrected bool
+	var commit string
+	var created struct {
+		Image      string
+		Env        []string
+		Cmd        []string
+		Labels     map[string]string
+		HostConfig struct {
+			Mounts []struct{ Source, Target string }
+		}
+	}
+	daemon := &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json": func(w http.ResponseWriter, r *http.Request) {
+			list := []map[string]interface{}{}
+			if !removed {
+				list = append(list, map[string]interface{}{"Id": "c2", "State": "exited", "Labels": labels})
+			}
+			writeJSON(list)(w, r)
+		},
+		"GET /containers/c2/json": writeJSON(map[string]interface{}{
+			"Id":         "c2",
+			"Name":       "/gbox-550e8400-e29b-41d4-a716-446655440000",
+			"State":      map[string]interface{}{"Status": "exited"},
+			"Config":     spec,
+			"HostConfig": map[string]interface{}{"Mounts": mounts},
+		}),
+		"POST /commit": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			commit = q.Get("container") + " -> " + q.Get("repo") + ":" + q.Get("tag")
+			w.WriteHeader(http.StatusCreated)
+			writeJSON(map[string]string{"Id": "sha256:snap"})(w, r)
+		},
+		"DELETE /containers/c2": func(w http.ResponseWriter, r *http.Request) {
+			removed = true
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"POST /containers/create": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "gbox-550e8400-e29b-41d4-a716-446655440000", r.URL.Query().Get("name"))
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			writeJSON(map[string]string{"Id": "c3"})(w, r)
+		},
+		"POST /containers/c3/start": func(w http.ResponseWriter, r *http.Request) {
+			resurrected = true
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"GET /containers/gbox-550e8400-e29b-41d4-a716-446655440000/json": func(w http.ResponseWriter, r *http.Request) {
+			if !resurrected {
+				http.Error(w, `{"message":"No such container"}`, http.StatusNotFound)
+				return
+			}
+			writeJSON(map[string]interface{}{
+				"Id":     "c3",
+				"State":  map[string]interface{}{"Status": "running", "Running": true},
+				"Config": map[string]interface{}{"Image": "gbox-snapshot:550e8400-e29b-41d4-a716-446655440000", "Labels": labels},
+			})(w, r)
+		},
+	}}
+	svc := newTestService(t, daemon)
+	svc.accessTracker = idleTracker{AccessTracker: tracker.NewInMemoryAccessTracker(), since: time.Now().Add(-48 * time.Hour)}
+
+	result, err := svc.Reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"550e8400-e29b-41d4-a716-446655440000"}, result.DeletedIDs)
+	assert.Equal(t, []string{"550e8400-e29b-41d4-a716-446655440000"}, result.SnapshotIDs)
+	assert.Equal(t, "c2 -> gbox-snapshot:550e8400-e29b-41d4-a716-446655440000", commit)
+	calls := daemon.Calls()
+	assert.Less(t, indexOf(calls, "POST /commit"), indexOf(calls, "DELETE /containers/c2"), "the box must be committed before it is deleted")
+	assert.FileExists(t, snapshotPath("550e8400-e29b-41d4-a716-446655440000"))
+
+	box, err := svc.Resurrect(context.Background(), "550e8400-e29b-41d4-a716-446655440000")
+	require.NoError(t, err)
+	assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", box.ID)
+	assert.Equal(t, "running", box.Status)
+	// The box runs from its committed filesystem, with its own spec and share directory
+	assert.Equal(t, "gbox-snapshot:550e8400-e29b-41d4-a716-446655440000", created.Image)
+	assert.Equal(t, []string{"APP_ENV=staging"}, created.Env)
+	assert.Equal(t, []string{"sleep", "infinity"}, created.Cmd)
+	assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", created.Labels[labelID])
+	require.Len(t, created.HostConfig.Mounts, 1)
+	assert.Equal(t, "/host/share/550e8400-e29b-41d4-a716-446655440000", created.HostConfig.Mounts[0].Source)
+
+	_, err = os.Stat(snapshotPath("550e8400-e29b-41d4-a716-446655440000"))
+	assert.True(t, os.IsNotExist(err), "the record is used up once the box is back")
+	_, err = svc.Resurrect(context.Background(), "550e8400-e29b-41d4-a716-446655440000")
+	assert.ErrorIs(t, err, service.ErrBoxNotFound)
+
+	// IDs not in the configured format never reach the snapshot records
+	for _, boxID := range []string{"box-2", "../550e8400-e29b-41d4-a716-446655440000"} {
+		_, err = svc.Resurrect(context.Background(), boxID)
+		assert.ErrorIs(t, err, service.ErrInvalidParams, boxID)
+	}
+}
+
+func TestReclaimKeepsBoxWhenSnapshotFails(t *testing.T) {
+	cfg := config.GetInstance()
+	origCluster, origHome := cfg.Cluster, cfg.File.Home
+	t.Cleanup(func() { cfg.Cluster, cfg.File.Home = origCluster, origHome })
+	cfg.File.Home = t.TempDir()
+	cfg.Cluster.ReclaimDeleteThreshold = 24 * time.Hour
+	cfg.Cluster.ReclaimDeleteEnabled = true
+	cfg.Cluster.ReclaimSnapshotEnabled = true
+
+	var removed []string
+	daemon := newGroupDaemon([]map[string]interface{}{
+		groupContainer("c2", "box-2", "", "exited"),
+	}, &removed)
+	daemon.handlers["GET /containers/c2/json"] = writeJSON(map[string]interface{}{"Id": "c2", "Name": "/gbox-box-2", "Config": map[string]interface{}{}})
+	daemon.handlers["POST /commit"] = func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"no space left on device"}`, http.StatusInternalServerError)
+	}
+	svc := newTestService(t, daemon)
+	svc.accessTracker = idleTracker{AccessTracker: tracker.NewInMemoryAccessTracker(), since: time.Now().Add(-48 * time.Hour)}
+
+	result, err := svc.Reclaim(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, result.DeletedCount)
+	assert.Empty(t, removed, "a box whose snapshot failed must not be deleted")
+	assert.NoFileExists(t, snapshotPath("box-2"))
+}
@@ -0,0 +1,111 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// signalPattern matches signal names with or without the SIG prefix, and
+// signal numbers
+var signalPattern = regexp.MustCompile(`^(SIG)?[A-Z][A-Z0-9+-]*$|^[1-9][0-9]?$`)
+
+// stopPolicy is how a box is stopped: the signal it is sent, how long it
+// has to exit and whether it is killed when it does not
+type stopPolicy struct {
+	signal string // Empty for the container's stop signal
+	grace  time.Duration
+	noKill bool
+}
+
+// validateStopPolicy validates the stop options of a create request
+func validateStopPolicy(cfg model.CreateBoxConfigParam) error {
+	if cfg.StopSignal != "" && !signalPattern.MatchString(strings.ToUpper(cfg.StopSignal)) {
+		return fmt.Errorf("%w: invalid stop signal %q", service.ErrInvalidParams, cfg.StopSignal)
+	}
+	if cfg.StopGracePeriod != "" {
+		if grace, err := time.ParseDuration(cfg.StopGracePeriod); err != nil || grace < 0 {
+			return fmt.Errorf("%w: invalid stop grace period %q", service.ErrInvalidParams, cfg.StopGracePeriod)
+		}
+	}
+	return nil
+}
+
+// normalizeSignal returns the SIG-prefixed upper case name of a signal
+// name, or the signal number unchanged
+func normalizeSignal(signal string) string {
+	signal = strings.ToUpper(signal)
+	if signal[0] >= '0' && signal[0] <= '9' || strings.HasPrefix(signal, "SIG") {
+		return signal
+	}
+	return "SIG" + signal
+}
+
+// stopTimeoutSeconds rounds a grace period up to the whole seconds Docker takes
+func stopTimeoutSeconds(grace time.Duration) int {
+	return int(math.Ceil(grace.Seconds()))
+}
+
+// stopPolicyFromLabels returns the stop policy a box was created with, or
+// the default one for boxes created without
+func (s *Service) stopPolicyFromLabels(labels map[string]string) stopPolicy {
+	policy := stopPolicy{
+		signal: labels[labelStopSignal],
+		grace:  defaultStopTimeout,
+		noKill: labels[labelStopNoKill] == "true",
+	}
+	if v := labels[labelStopGrace]; v != "" {
+		if grace, err := time.ParseDuration(v); err == nil {
+			policy.grace = grace
+		} else {
+			s.logger.Warn("Invalid stop grace period %q, using %v", v, policy.grace)
+		}
+	}
+	return policy
+}
+
+// stopContainer runs the box's pre-stop hook and then stops it according to
+// its stop policy
+func (s *Service) stopContainer(ctx context.Context, containerID string, labels map[string]string) error {
+	s.runPreStopHook(ctx, containerID, labels)
+
+	policy := s.stopPolicyFromLabels(labels)
+	if !policy.noKill {
+		timeout := stopTimeoutSeconds(policy.grace)
+		return s.client.ContainerStop(ctx, containerID, container.StopOptions{
+			Signal:  policy.signal,
+			Timeout: &timeout,
+		})
+	}
+
+	// Docker kills a container that outlives the stop timeout, so the signal
+	// is sent directly and the box is only waited for
+	signal := policy.signal
+	if signal == "" {
+		signal = "SIGTERM"
+	}
+	if err := s.client.ContainerKill(ctx, containerID, signal); err != nil {
+		return err
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, policy.grace)
+	defer cancel()
+	statusCh, errCh := s.client.ContainerWait(waitCtx, containerID, container.WaitConditionNotRunning)
+	select {
+	case <-statusCh:
+		return nil
+	case err := <-errCh:
+		if errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("box did not exit within %v of %s and is left running, as its stop policy does not kill it", policy.grace, signal)
+		}
+		return err
+	}
+}
@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// newStopDaemon fakes a running box with the given labels, recording the
+// query of stop and kill requests. Waits block until the request ends.
+func newStopDaemon(labels map[string]string, queries map[string]url.Values) *fakeDaemon {
+	labels[labelID] = "box-1"
+	record := func(call string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			queries[call] = r.URL.Query()
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+	return &fakeDaemon{handlers: map[string]http.HandlerFunc{
+		"GET /containers/json":     writeJSON([]map[string]interface{}{{"Id": "c1", "State": "running", "Labels": labels}}),
+		"POST /containers/c1/stop": record("stop"),
+		"POST /containers/c1/kill": record("kill"),
+		"POST /containers/c1/wait": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		},
+		"GET /containers/gbox-box-1/json": writeJSON(map[string]interface{}{
+			"Id":     "c1",
+			"State":  map[string]interface{}{"Status": "exited"},
+			"Config": map[string]interface{}{"Labels": labels},
+		}),
+	}}
+}
+
+func TestStopHonorsStopPolicy(t *testing.T) {
+	queries := map[string]url.Values{}
+	svc := newTestService(t, newStopDaemon(map[string]string{
+		labelStopSignal: "SIGINT",
+		labelStopGrace:  "2500ms",
+	}, queries))
+
+	_, err := svc.Stop(context.Background(), "box-1")
+	require.NoError(t, err)
+	// Docker sends the signal and kills the box once the timeout elapses
+	assert.Equal(t, "SIGINT", queries["stop"].Get("signal"))
+	assert.Equal(t, "3", queries["stop"].Get("t"), "the grace period is rounded up to whole seconds")
+
+	// Boxes without a policy keep the default
+	queries = map[string]url.Values{}
+	svc = newTestService(t, newStopDaemon(map[string]string{}, queries))
+	_, err = svc.Stop(context.Background(), "box-1")
+	require.NoError(t, err)
+	assert.Empty(t, queries["stop"].Get("signal"))
+	assert.Equal(t, "10", queries["stop"].Get("t"))
+}
+
+func TestStopWithoutKillLeavesBoxRunning(t *testing.T) {
+	queries := map[string]url.Values{}
+	daemon := newStopDaemon(map[string]string{
+		labelStopSignal: "SIGINT",
+		labelStopGrace:  "200ms",
+		labelStopNoKill: "true",
+	}, queries)
+	svc := newTestService(t, daemon)
+
+	start := time.Now()
+	_, err := svc.Stop(context.Background(), "box-1")
+	assert.ErrorContains(t, err, "left running")
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond, "the box must be given its grace period")
+	assert.Equal(t, "SIGINT", queries["kill"].Get("signal"))
+	assert.NotContains(t, daemon.Calls(), "POST /containers/c1/stop", "Docker's stop would kill the box")
+}
+
+func TestCreateLinuxBoxStopPolicy(t *testing.T) {
+	setupShareDir(t)
+	svc := newTestService(t, &fakeDaemon{})
+
+	plan, err := svc.PlanLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		StopSignal:      "int",
+		StopGracePeriod: "30s",
+		StopNoKill:      true,
+	}})
+	require.NoError(t, err)
+	cfg := plan.Spec.(map[string]interface{})["config"].(*container.Config)
+	assert.Equal(t, "SIGINT", cfg.StopSignal)
+	require.NotNil(t, cfg.StopTimeout)
+	assert.Equal(t, 30, *cfg.StopTimeout)
+	assert.Equal(t, "SIGINT", cfg.Labels[labelStopSignal])
+	assert.Equal(t, "30s", cfg.Labels[labelStopGrace])
+	assert.Equal(t, "true", cfg.Labels[labelStopNoKill])
+
+	for _, c := range []model.CreateBoxConfigParam{{StopSignal: "not a signal"}, {StopGracePeriod: "-1s"}, {StopGracePeriod: "soon"}} {
+		_, err := svc.PlanLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: c})
+		assert.ErrorIs(t, err, service.ErrInvalidParams)
+	}
+}
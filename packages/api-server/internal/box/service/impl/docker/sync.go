@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// manifestScript prints "<sha256>  ./<path>" for every regular file under $1,
+// and nothing when $1 does not exist yet
+const manifestScript = `cd -- "$1" 2>/dev/null || exit 0; find . -type f -exec sha256sum {} +`
+
+// deleteScript removes the paths following $1, relative to $1
+const deleteScript = `cd -- "$1" && shift && rm -rf -- "$@"`
+
+// sha256sum escapes backslashes and newlines in names, marking such lines
+// with a leading backslash
+var sha256sumUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+
+// SyncManifest returns the content hash of every regular file under a directory in a box
+func (s *Service) SyncManifest(ctx context.Context, id string, params *model.BoxSyncManifestParams) (*model.BoxSyncManifest, error) {
+	if !strings.HasPrefix(params.Path, "/") {
+		return nil, fmt.Errorf("%w: path %q must be absolute", service.ErrInvalidParams, params.Path)
+	}
+
+	result, err := s.Exec(ctx, id, &model.BoxExecParams{
+		Commands: []string{"sh", "-c", manifestScript, "sh", params.Path},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to hash files in %s: %s", params.Path, strings.TrimSpace(result.Stderr))
+	}
+
+	manifest := &model.BoxSyncManifest{Path: params.Path, Files: []model.BoxSyncFile{}}
+	scanner := bufio.NewScanner(strings.NewReader(result.Stdout))
+	for scanner.Scan() {
+		line := scanner.Text()
+		escaped := strings.HasPrefix(line, `\`)
+		if escaped {
+			line = line[1:]
+		}
+		// "<64 hex digits><space><space or *><name>"
+		if len(line) < 67 {
+			continue
+		}
+		name := line[66:]
+		if escaped {
+			name = sha256sumUnescaper.Replace(name)
+		}
+		manifest.Files = append(manifest.Files, model.BoxSyncFile{
+			Path:   strings.TrimPrefix(name, "./"),
+			SHA256: line[:64],
+		})
+	}
+	return manifest, nil
+}
+
+// ApplySync creates a directory in a box when it is missing and removes the
+// listed paths from it, so that changed files can then be extracted into it
+func (s *Service) ApplySync(ctx context.Context, id string, params *model.BoxSyncApplyParams) (*model.BoxSyncResult, error) {
+	if !strings.HasPrefix(params.Path, "/") {
+		return nil, fmt.Errorf("%w: path %q must be absolute", service.ErrInvalidParams, params.Path)
+	}
+	for _, p := range params.Delete {
+		if !isRelativeSubpath(p) {
+			return nil, fmt.Errorf("%w: delete path %q must be relative to %s", service.ErrInvalidParams, p, params.Path)
+		}
+	}
+
+	mkdir, err := s.Exec(ctx, id, &model.BoxExecParams{Commands: []string{"mkdir", "-p", "--", params.Path}})
+	if err != nil {
+		return nil, err
+	}
+	if mkdir.ExitCode != 0 {
+		return nil, fmt.Errorf("failed to create %s: %s", params.Path, strings.TrimSpace(mkdir.Stderr))
+	}
+
+	result := &model.BoxSyncResult{}
+	if len(params.Delete) > 0 {
+		rm, err := s.Exec(ctx, id, &model.BoxExecParams{
+			Commands: append([]string{"sh", "-c", deleteScript, "sh", params.Path}, params.Delete...),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if rm.ExitCode != 0 {
+			return nil, fmt.Errorf("failed to delete files in %s: %s", params.Path, strings.TrimSpace(rm.Stderr))
+		}
+		result.Deleted = len(params.Delete)
+	}
+	return result, nil
+}
+
+// isRelativeSubpath reports whether p names a path strictly below the
+// directory it is relative to
+func isRelativeSubpath(p string) bool {
+	cleaned := path.Clean(p)
+	return p != "" && !path.IsAbs(cleaned) && cleaned != "." && cleaned != ".." && !strings.HasPrefix(cleaned, "../")
+}
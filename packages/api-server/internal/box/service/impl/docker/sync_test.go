@@ -0,0 +1,41 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+func TestSyncManifest(t *testing.T) {
+	hashA := "ca978112ca1bbdcafac231b39a23dc4da786eff8146d7a8b8e7a3c5e1f1b6e6b"
+	hashB := "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"
+	svc := newTestService(t, newPathExecDaemon(map[string]string{
+		"/app":   hashA + "  ./a.txt\n\\" + hashB + "  ./sub/new\\nline\n",
+		"/empty": "",
+	}))
+	ctx := context.Background()
+
+	manifest, err := svc.SyncManifest(ctx, "box-1", &model.BoxSyncManifestParams{Path: "/app"})
+	require.NoError(t, err)
+	assert.Equal(t, []model.BoxSyncFile{
+		{Path: "a.txt", SHA256: hashA},
+		{Path: "sub/new\nline", SHA256: hashB},
+	}, manifest.Files)
+
+	manifest, err = svc.SyncManifest(ctx, "box-1", &model.BoxSyncManifestParams{Path: "/empty"})
+	require.NoError(t, err)
+	assert.Empty(t, manifest.Files)
+}
+
+func TestApplySyncRejectsEscapingDeletes(t *testing.T) {
+	svc := newTestService(t, newPathExecDaemon(nil))
+	for _, p := range []string{"../etc/passwd", "/etc/passwd", ".", "a/../.."} {
+		_, err := svc.ApplySync(context.Background(), "box-1", &model.BoxSyncApplyParams{Path: "/app", Delete: []string{p}})
+		assert.ErrorIs(t, err, service.ErrInvalidParams, p)
+	}
+}
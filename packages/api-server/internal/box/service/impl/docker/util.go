@@ -5,16 +5,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/babelcloud/gbox/packages/api-server/config"
 	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-units"
 )
 
 const (
@@ -28,6 +37,23 @@ const (
 	labelComponent = labelPrefix + ".component"
 	labelManagedBy = labelPrefix + ".managed-by"
 
+	labelAutoRemove     = labelPrefix + ".auto_remove"
+	labelPostStart      = labelPrefix + ".post_start"
+	labelPreStop        = labelPrefix + ".pre_stop"
+	labelPreStopTimeout = labelPrefix + ".pre_stop_timeout"
+	labelStopSignal     = labelPrefix + ".stop_signal"
+	labelStopGrace      = labelPrefix + ".stop_grace_period"
+	labelStopNoKill     = labelPrefix + ".stop_no_kill"
+	labelMaxRuntime     = labelPrefix + ".max_runtime"
+	labelShell          = labelPrefix + ".shell"
+	labelGroup          = labelPrefix + ".group"
+	labelGroupService   = labelPrefix + ".group.service"
+	labelOwner          = labelPrefix + ".owner"
+	labelImage          = labelPrefix + ".image"
+	// labelDefaultCmd is set on images, not boxes, to the command boxes
+	// created from the image run when the request has none
+	labelDefaultCmd = labelPrefix + ".default-cmd"
+
 	DefaultImage = "ubuntu:latest"
 )
 
@@ -37,6 +63,33 @@ func containerName(id string) string {
 	return fmt.Sprintf("gbox-%s", id)
 }
 
+// maxNameSuffixLength bounds the name suffix so container names stay readable
+const maxNameSuffixLength = 64
+
+var nameSuffixPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// validateNameSuffix checks a create request's container name suffix
+func validateNameSuffix(suffix string) error {
+	if suffix == "" {
+		return nil
+	}
+	if len(suffix) > maxNameSuffixLength || !nameSuffixPattern.MatchString(suffix) {
+		return fmt.Errorf("%w: invalid name suffix %q, must start with a letter or digit, contain only letters, digits, '_', '.' or '-' and be at most %d characters",
+			service.ErrInvalidParams, suffix, maxNameSuffixLength)
+	}
+	return nil
+}
+
+// suffixedContainerName returns the container name of a new box, with the
+// requested human-readable suffix appended. Boxes are still looked up by
+// their ID label, so the suffix does not affect resolving them.
+func suffixedContainerName(id, suffix string) string {
+	if suffix == "" {
+		return containerName(id)
+	}
+	return containerName(id) + "-" + suffix
+}
+
 // getContainerByID gets a container by box ID
 func (s *Service) getContainerByID(ctx context.Context, id string) (*types.Container, error) {
 	if id == "" {
@@ -68,6 +121,12 @@ func (s *Service) inspectContainerByID(ctx context.Context, id string) (types.Co
 		return types.ContainerJSON{}, fmt.Errorf("box ID is required")
 	}
 	containerJSON, err := s.client.ContainerInspect(ctx, containerName(id))
+	if errdefs.IsNotFound(err) {
+		// Boxes created with a name suffix are found by their ID label
+		if containerInfo, lookupErr := s.getContainerByID(ctx, id); lookupErr == nil {
+			containerJSON, err = s.client.ContainerInspect(ctx, containerInfo.ID)
+		}
+	}
 	if err != nil {
 		// Reuse the same error handling logic
 		return types.ContainerJSON{}, handleContainerError(err, id)
@@ -91,10 +150,12 @@ func handleContainerError(err error, id string) error {
 
 // containerToBox converts a Docker container to a Box
 func containerToBox(c interface{}) *model.Box {
-	var id, status string
+	var id, status, statusReason string
 	var labels map[string]string
 	var env []string
 	var createdAt time.Time
+	var startedAt *time.Time
+	var uptime int64
 	var cpu, memory, storage float64
 
 	switch c := c.(type) {
@@ -103,9 +164,13 @@ func containerToBox(c interface{}) *model.Box {
 		status = mapContainerState(c.State.Status)
 		labels = c.Config.Labels
 		env = c.Config.Env
+		if reason, failed := maxRuntimeExceeded(labels, c.State); failed {
+			status, statusReason = model.BoxStatusFailed, reason
+		}
 		if t, err := time.Parse(time.RFC3339, c.Created); err == nil {
 			createdAt = t
 		}
+		startedAt, uptime = boxUptime(c.State)
 		// Extract resource limits from HostConfig
 		if c.HostConfig != nil && c.HostConfig.Resources.Memory > 0 {
 			memory = float64(c.HostConfig.Resources.Memory) / (1024 * 1024) // Convert bytes to MB
@@ -160,13 +225,14 @@ func containerToBox(c interface{}) *model.Box {
 	}
 	// --- End Restored Original logic ---
 
-	// Parse environment variables to map
+	// Parse environment variables to map, hiding the values of secrets
 	envMap := make(map[string]string)
 	for _, envVar := range env {
 		if parts := strings.SplitN(envVar, "=", 2); len(parts) == 2 {
 			envMap[parts[0]] = parts[1]
 		}
 	}
+	service.RedactEnv(envMap, config.GetInstance().Cluster.RedactEnv)
 
 	// Extract working directory from labels if available
 	workingDir := ""
@@ -192,6 +258,15 @@ func containerToBox(c interface{}) *model.Box {
 		CreatedAt: createdAt,
 		ExpiresAt: expiresAt,
 		UpdatedAt: updatedAt,
+		GroupID:   labels[labelGroup],
+		Owner:     labels[labelOwner],
+
+		StatusReason: statusReason,
+		ImageDigest:  imageDigest(labels[labelImage]),
+
+		StartedAt: startedAt,
+		Uptime:    uptime,
+
 		Config: model.LinuxAndroidBoxConfig{
 			Envs:       envMap,
 			Labels:     extraLabels, // Use the cleaned extra labels
@@ -215,6 +290,78 @@ func containerToBox(c interface{}) *model.Box {
 	}
 }
 
+// boxUptime returns when a container's current or last run started and, if
+// it is still running, for how many seconds. Docker reports a zero start time
+// for containers that never started.
+func boxUptime(state *types.ContainerState) (*time.Time, int64) {
+	if state == nil {
+		return nil, 0
+	}
+	startedAt, err := time.Parse(time.RFC3339Nano, state.StartedAt)
+	if err != nil || startedAt.IsZero() {
+		return nil, 0
+	}
+	if !state.Running {
+		return &startedAt, 0
+	}
+	uptime := int64(time.Since(startedAt) / time.Second)
+	if uptime < 0 {
+		uptime = 0
+	}
+	return &startedAt, uptime
+}
+
+// boxConnection summarizes how to reach a box: its exec WebSocket URL on this
+// server, the ports Docker published and the host path of its share directory.
+func boxConnection(boxID string, info types.ContainerJSON) *model.BoxConnection {
+	cfg := config.GetInstance()
+
+	host := "localhost"
+	if bind := cfg.Server.BindAddress; bind != "" {
+		if ip := net.ParseIP(bind); ip == nil || !ip.IsUnspecified() {
+			host = bind
+		}
+	}
+	execURL := url.URL{
+		Scheme: "ws",
+		Host:   common.ListenAddress(host, cfg.Server.Port),
+		Path:   "/api/v1/boxes/" + boxID + "/exec",
+	}
+
+	_, hostShareDir := cfg.File.BoxShareDir(boxID)
+	conn := &model.BoxConnection{
+		ExecURL:  execURL.String(),
+		ShareDir: hostShareDir,
+	}
+	if info.NetworkSettings != nil {
+		for port, bindings := range info.NetworkSettings.Ports {
+			for _, binding := range bindings {
+				hostPort, err := strconv.Atoi(binding.HostPort)
+				if err != nil {
+					continue
+				}
+				conn.Ports = append(conn.Ports, model.BoxPort{
+					ContainerPort: port.Int(),
+					Protocol:      port.Proto(),
+					HostIP:        binding.HostIP,
+					HostPort:      hostPort,
+				})
+			}
+		}
+	}
+	sort.Slice(conn.Ports, func(i, j int) bool {
+		a, b := conn.Ports[i], conn.Ports[j]
+		if a.ContainerPort != b.ContainerPort {
+			return a.ContainerPort < b.ContainerPort
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.HostIP < b.HostIP
+	})
+	return conn
+}
+
 // mapContainerState maps Docker container states to Box states
 func mapContainerState(state string) string {
 	switch state {
@@ -261,6 +408,48 @@ func PrepareLabels(boxID string, p *model.LinuxAndroidBoxCreateParam) map[string
 		labels[labelPrefix+".expires_in"] = p.Config.ExpiresIn
 	}
 
+	if p.Config.AutoRemove {
+		labels[labelAutoRemove] = "true"
+	}
+
+	if p.Config.Group != "" {
+		labels[labelGroup] = p.Config.Group
+	}
+
+	if p.Owner != "" {
+		labels[labelOwner] = p.Owner
+	}
+
+	// Post-start hook, recorded for auditing; it runs only at creation
+	if p.Config.PostStart != "" {
+		labels[labelPostStart] = p.Config.PostStart
+	}
+
+	// Pre-stop hook
+	if p.Config.PreStop != "" {
+		labels[labelPreStop] = p.Config.PreStop
+		if p.Config.PreStopTimeout != "" {
+			labels[labelPreStopTimeout] = p.Config.PreStopTimeout
+		}
+	}
+
+	// Stop policy
+	if p.Config.StopSignal != "" {
+		labels[labelStopSignal] = normalizeSignal(p.Config.StopSignal)
+	}
+	if p.Config.StopGracePeriod != "" {
+		labels[labelStopGrace] = p.Config.StopGracePeriod
+	}
+	if p.Config.StopNoKill {
+		labels[labelStopNoKill] = "true"
+	}
+	if p.Config.MaxRuntime != "" {
+		labels[labelMaxRuntime] = p.Config.MaxRuntime
+	}
+	if p.Config.Shell != "" {
+		labels[labelShell] = p.Config.Shell
+	}
+
 	// Environment variables
 	if p.Config.Envs != nil {
 		for k, v := range p.Config.Envs {
@@ -278,6 +467,69 @@ func PrepareLabels(boxID string, p *model.LinuxAndroidBoxCreateParam) map[string
 	return labels
 }
 
+// dnsLabelPattern matches a single DNS label
+var dnsLabelPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// validateDNSSearch checks that every search domain is a valid DNS name
+func validateDNSSearch(domains []string) error {
+	for _, domain := range domains {
+		name := strings.TrimSuffix(domain, ".")
+		if name == "" || len(name) > 253 {
+			return fmt.Errorf("%w: invalid DNS search domain %q", service.ErrInvalidParams, domain)
+		}
+		for _, label := range strings.Split(name, ".") {
+			if !dnsLabelPattern.MatchString(label) {
+				return fmt.Errorf("%w: invalid DNS search domain %q", service.ErrInvalidParams, domain)
+			}
+		}
+	}
+	return nil
+}
+
+// buildResources converts the memory and OOM settings of a create request
+// into Docker resources, rejecting combinations Docker would accept but that
+// leave the host unprotected
+func buildResources(cfg model.CreateBoxConfigParam) (container.Resources, error) {
+	var resources container.Resources
+
+	if cfg.Memory != "" {
+		memory, err := units.RAMInBytes(cfg.Memory)
+		if err != nil || memory <= 0 {
+			return resources, fmt.Errorf("%w: invalid memory limit %q", service.ErrInvalidParams, cfg.Memory)
+		}
+		resources.Memory = memory
+	}
+
+	if cfg.MemoryReservation != "" {
+		reservation, err := units.RAMInBytes(cfg.MemoryReservation)
+		if err != nil || reservation <= 0 {
+			return resources, fmt.Errorf("%w: invalid memory reservation %q", service.ErrInvalidParams, cfg.MemoryReservation)
+		}
+		if resources.Memory > 0 && reservation > resources.Memory {
+			return resources, fmt.Errorf("%w: memory reservation %q exceeds the memory limit %q", service.ErrInvalidParams, cfg.MemoryReservation, cfg.Memory)
+		}
+		resources.MemoryReservation = reservation
+	}
+
+	if cfg.OomKillDisable {
+		if resources.Memory == 0 {
+			return resources, fmt.Errorf("%w: oomKillDisable requires a memory limit", service.ErrInvalidParams)
+		}
+		disable := true
+		resources.OomKillDisable = &disable
+	}
+
+	return resources, nil
+}
+
+// validateOomScoreAdj checks the OOM score adjustment is within the kernel's range
+func validateOomScoreAdj(score int) error {
+	if score < -1000 || score > 1000 {
+		return fmt.Errorf("%w: oomScoreAdj %d is outside the range -1000 to 1000", service.ErrInvalidParams, score)
+	}
+	return nil
+}
+
 // JoinArgs converts a string array to a JSON string
 func JoinArgs(args []string) string {
 	if len(args) == 0 {
@@ -291,19 +543,90 @@ func JoinArgs(args []string) string {
 	return string(argsJSON)
 }
 
-// GetCommand returns the command to run, falling back to default if none specified
-func GetCommand(cmd string, args []string) []string {
+// defaultShell runs the shell-form commands and login sessions of boxes
+// created without a shell
+const defaultShell = "/bin/sh"
+
+// boxShell returns the shell a box was created with, from its labels
+func boxShell(labels map[string]string) string {
+	if shell := labels[labelShell]; shell != "" {
+		return shell
+	}
+	return defaultShell
+}
+
+// parseShell validates the shell of a create request
+func parseShell(shell string) (string, error) {
+	if shell == "" {
+		return defaultShell, nil
+	}
+	if !path.IsAbs(shell) || strings.ContainsAny(shell, " \t\n") {
+		return "", fmt.Errorf("%w: shell %q must be an absolute path such as /bin/bash", service.ErrInvalidParams, shell)
+	}
+	return shell, nil
+}
+
+// GetCommand returns the command to run, falling back to default if none
+// specified. A lone command is run by shell, /bin/sh when empty.
+func GetCommand(shell, cmd string, args []string) []string {
 	if cmd == "" {
 		return []string{"sleep", "infinity"}
 	}
 	if len(args) == 0 {
 		// If no args provided, use shell to parse the command string
-		return []string{"/bin/sh", "-c", cmd}
+		if shell == "" {
+			shell = defaultShell
+		}
+		return []string{shell, "-c", cmd}
 	}
 	// If args are provided, use direct command array
 	return append([]string{cmd}, args...)
 }
 
+// execArgv joins a command and its arguments in exec form: each element is
+// one argv entry of the process, with no shell splitting or quoting, so an
+// argument containing spaces arrives intact. Unlike GetCommand a lone command
+// is not run through a shell. The result never shares cmd's backing array.
+func execArgv(cmd []string, args []string) []string {
+	argv := make([]string, 0, len(cmd)+len(args))
+	argv = append(argv, cmd...)
+	return append(argv, args...)
+}
+
+// defaultExecPath is the PATH of a clean environment when the container sets none
+const defaultExecPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// cleanEnvArgv wraps argv in `env -i` so it runs with only PATH and env,
+// which are sorted for a reproducible command line. A PATH in env wins.
+func cleanEnvArgv(path string, env []string, argv []string) []string {
+	env = append([]string(nil), env...)
+	sort.Strings(env)
+	wrapped := make([]string, 0, 3+len(env)+len(argv))
+	wrapped = append(wrapped, "env", "-i", "PATH="+path)
+	wrapped = append(wrapped, env...)
+	return append(wrapped, argv...)
+}
+
+// loginShells are the shells started as login shells themselves by a login exec
+var loginShells = map[string]bool{"sh": true, "bash": true, "zsh": true, "ash": true, "dash": true, "ksh": true}
+
+// loginShellArgv makes argv run with the box's profile loaded. A bare shell
+// becomes a login shell itself; any other command is run from a login
+// session of the box's shell, which changes back to workingDir in case a
+// profile script changed it.
+func loginShellArgv(argv []string, workingDir, shell string) []string {
+	if len(argv) == 1 && loginShells[path.Base(argv[0])] {
+		return []string{argv[0], "-l"}
+	}
+	wrapped := make([]string, 0, 4+len(argv))
+	if workingDir == "" {
+		wrapped = append(wrapped, shell, "-lc", `exec "$@"`, "sh")
+	} else {
+		wrapped = append(wrapped, shell, "-lc", `cd -- "$0" && exec "$@"`, workingDir)
+	}
+	return append(wrapped, argv...)
+}
+
 // GetEnvVars converts environment variables map to string slice
 func GetEnvVars(env map[string]string) []string {
 	if env == nil {
@@ -324,6 +647,11 @@ func EnsureImageTag(image string) string {
 		return ""
 	}
 
+	// A digest pins the image exactly, so no tag is added
+	if isDigestReference(image) {
+		return image
+	}
+
 	// If already has tag, return as-is
 	if strings.Contains(image, ":") {
 		return image
@@ -373,6 +701,22 @@ func MapToEnv(env map[string]string) []string {
 	return result
 }
 
+// mergeEnv returns defaults overlaid with env, so variables set on the box
+// take precedence over operator defaults
+func mergeEnv(defaults, env map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return env
+	}
+	merged := make(map[string]string, len(defaults)+len(env))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range env {
+		merged[k] = v
+	}
+	return merged
+}
+
 // WaitForResponse reads from a reader until EOF and returns any error encountered
 func WaitForResponse(reader io.Reader) ([]byte, error) {
 	var buf []byte
@@ -437,12 +781,38 @@ func ProcessPullProgress(reader io.Reader, writer io.Writer) error {
 	return nil
 }
 
+// digestPattern matches the digest of a digest reference (e.g., "ubuntu@sha256:...")
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// isDigestReference reports whether imageRef pins an image by digest
+func isDigestReference(imageRef string) bool {
+	return strings.Contains(imageRef, "@")
+}
+
+// imageDigest returns the digest of a digest reference, or "" for a tag reference
+func imageDigest(imageRef string) string {
+	if i := strings.LastIndex(imageRef, "@"); i >= 0 {
+		return imageRef[i+1:]
+	}
+	return ""
+}
+
+// validateImageDigest checks the digest of a digest reference is well formed
+func validateImageDigest(imageRef string) error {
+	if isDigestReference(imageRef) && !digestPattern.MatchString(imageDigest(imageRef)) {
+		return fmt.Errorf("%w: invalid image digest in %q, expected sha256:<64 hex digits>", service.ErrInvalidParams, imageRef)
+	}
+	return nil
+}
+
 // parseImageTag parses a full image reference (e.g., "ubuntu:latest", "ubuntu", "library/ubuntu")
-// into a repository and a tag.
+// into a repository and a tag. A digest reference has no tag, so it is not ok.
 func parseImageTag(imageRef string) (string, string, bool) {
 	// The canonical implementation is docker/distribution's reference.ParseNamed
 	// but using a simpler string split for now to avoid extra dependencies.
-	// Note: This doesn't handle digests, only tags.
+	if isDigestReference(imageRef) {
+		return strings.SplitN(imageRef, "@", 2)[0], "", false
+	}
 	if !strings.Contains(imageRef, "/") {
 		imageRef = "docker.io/library/" + imageRef
 	}
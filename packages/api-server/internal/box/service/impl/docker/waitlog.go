@@ -0,0 +1,120 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// defaultWaitForLogTimeout bounds the wait for a readiness log line when the
+// create request does not set one
+const defaultWaitForLogTimeout = time.Minute
+
+// Output a box logged before failing its readiness wait is quoted in the
+// error, up to this many of the last lines and bytes
+const (
+	startupLogTailLines = 20
+	startupLogTailBytes = 2048
+)
+
+// logWait is a validated wait-for-log request
+type logWait struct {
+	pattern *regexp.Regexp
+	timeout time.Duration
+}
+
+// parseLogWait validates the wait-for-log options of a create request. It
+// returns nil when no wait was requested.
+func parseLogWait(cfg model.CreateBoxConfigParam) (*logWait, error) {
+	if cfg.WaitForLog == "" {
+		if cfg.WaitForLogTimeout != "" {
+			return nil, fmt.Errorf("%w: waitForLogTimeout requires waitForLog", service.ErrInvalidParams)
+		}
+		return nil, nil
+	}
+
+	pattern, err := regexp.Compile(cfg.WaitForLog)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid waitForLog pattern: %v", service.ErrInvalidParams, err)
+	}
+	timeout := defaultWaitForLogTimeout
+	if cfg.WaitForLogTimeout != "" {
+		timeout, err = time.ParseDuration(cfg.WaitForLogTimeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("%w: invalid waitForLogTimeout %q", service.ErrInvalidParams, cfg.WaitForLogTimeout)
+		}
+	}
+	return &logWait{pattern: pattern, timeout: timeout}, nil
+}
+
+// waitForLogLine follows the output of a started box until a line matches
+// the pattern, the box exits or the timeout elapses
+func (s *Service) waitForLogLine(ctx context.Context, boxID, containerID string, wait *logWait) error {
+	ctx, cancel := context.WithTimeout(ctx, wait.timeout)
+	defer cancel()
+
+	logs, err := s.client.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to follow logs of box %s: %w", boxID, err)
+	}
+	defer logs.Close()
+
+	// Boxes run without a TTY, so stdout and stderr arrive multiplexed
+	reader, writer := io.Pipe()
+	defer reader.Close()
+	go func() {
+		_, err := stdcopy.StdCopy(writer, writer, logs)
+		writer.CloseWithError(err)
+	}()
+
+	var tail []string
+	lines := bufio.NewScanner(reader)
+	for lines.Scan() {
+		if wait.pattern.Match(lines.Bytes()) {
+			s.logger.Debug("Box %s logged ready line %q", boxID, lines.Text())
+			return nil
+		}
+		tail = append(tail, lines.Text())
+		if len(tail) > startupLogTailLines {
+			tail = tail[1:]
+		}
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("box %s did not log a line matching %q within %s%s", boxID, wait.pattern, wait.timeout, formatStartupLogs(tail))
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case lines.Err() != nil:
+		return fmt.Errorf("failed to read logs of box %s: %w", boxID, lines.Err())
+	}
+	return fmt.Errorf("box %s exited before logging a line matching %q%s", boxID, wait.pattern, formatStartupLogs(tail))
+}
+
+// formatStartupLogs quotes the last lines a box logged for a readiness
+// error, keeping the end of the output when it is too long
+func formatStartupLogs(tail []string) string {
+	if len(tail) == 0 {
+		return ", the box logged nothing"
+	}
+	output := strings.Join(tail, "\n")
+	if len(output) > startupLogTailBytes {
+		output = "..." + strings.ToValidUTF8(output[len(output)-startupLogTailBytes:], "")
+	}
+	return ", last output:\n" + output
+}
@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// logLines returns a logs handler writing each line in turn as multiplexed
+// stdout, pausing for delay before each one, and holding the stream open
+// afterwards when follow is set
+func logLines(delay time.Duration, follow bool, lines ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+		w.WriteHeader(http.StatusOK)
+		out := stdcopy.NewStdWriter(w, stdcopy.Stdout)
+		for _, line := range lines {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+			out.Write([]byte(line + "\n"))
+			w.(http.Flusher).Flush()
+		}
+		if follow {
+			<-r.Context().Done()
+		}
+	}
+}
+
+func TestCreateLinuxBoxWaitsForLogLine(t *testing.T) {
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	daemon.handlers["GET /containers/c1/logs"] = logLines(150*time.Millisecond, true, "booting", "Server started on :8080")
+	svc := newTestService(t, daemon)
+
+	start := time.Now()
+	box, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		WaitForLog: `Server started`,
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "box-1", box.ID)
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond, "create must return only after the ready line")
+
+	calls := daemon.Calls()
+	assert.Less(t, indexOf(calls, "POST /containers/c1/start"), indexOf(calls, "GET /containers/c1/logs"))
+}
+
+func TestCreateLinuxBoxWaitForLogFailures(t *testing.T) {
+	setupShareDir(t)
+
+	var created struct{ Labels map[string]string }
+	daemon := newCreateDaemon(&created)
+	daemon.handlers["GET /containers/c1/logs"] = logLines(0, true, "booting")
+	daemon.handlers["DELETE /containers/c1"] = noContent
+	svc := newTestService(t, daemon)
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		WaitForLog:        `ready`,
+		WaitForLogTimeout: "200ms",
+	}})
+	assert.ErrorContains(t, err, "did not log a line matching")
+	// The client never learns the box's ID, so the box must not be left behind
+	assert.NotEqual(t, -1, indexOf(daemon.Calls(), "DELETE /containers/c1"), "the box must be removed after the timeout")
+	assert.NoDirExists(t, filepath.Join(config.GetInstance().File.Share, created.Labels[labelID]))
+
+	daemon = newCreateDaemon(&created)
+	daemon.handlers["GET /containers/c1/logs"] = logLines(0, false, "fatal: no config")
+	daemon.handlers["DELETE /containers/c1"] = noContent
+	svc = newTestService(t, daemon)
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		WaitForLog: `ready`,
+	}})
+	assert.ErrorContains(t, err, "exited before logging")
+	assert.NotEqual(t, -1, indexOf(daemon.Calls(), "DELETE /containers/c1"), "the exited box must be removed")
+}
+
+func TestCreateLinuxBoxWaitForLogReportsStartupLogs(t *testing.T) {
+	setupShareDir(t)
+
+	// The box crashes right away, its output explains why
+	daemon := newCreateDaemon(&struct{}{})
+	daemon.handlers["GET /containers/c1/logs"] = logLines(0, false, "loading /etc/app.yaml", "fatal: missing DATABASE_URL")
+	svc := newTestService(t, daemon)
+	_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		WaitForLog: `ready`,
+	}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exited before logging")
+	assert.Contains(t, err.Error(), "last output:\nloading /etc/app.yaml\nfatal: missing DATABASE_URL")
+
+	// Only the end of long output is kept
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %03d %s", i, strings.Repeat("x", 100))
+	}
+	daemon = newCreateDaemon(&struct{}{})
+	daemon.handlers["GET /containers/c1/logs"] = logLines(0, false, lines...)
+	svc = newTestService(t, daemon)
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		WaitForLog: `ready`,
+	}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 099")
+	assert.NotContains(t, err.Error(), "line 079")
+	assert.LessOrEqual(t, len(err.Error()), startupLogTailBytes+200)
+
+	daemon = newCreateDaemon(&struct{}{})
+	daemon.handlers["GET /containers/c1/logs"] = logLines(0, false)
+	svc = newTestService(t, daemon)
+	_, err = svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: model.CreateBoxConfigParam{
+		WaitForLog: `ready`,
+	}})
+	assert.ErrorContains(t, err, "the box logged nothing")
+}
+
+func TestCreateLinuxBoxWaitForLogValidation(t *testing.T) {
+	setupShareDir(t)
+	daemon := newCreateDaemon(&struct{}{})
+	svc := newTestService(t, daemon)
+
+	for _, cfg := range []model.CreateBoxConfigParam{
+		{WaitForLog: `(unclosed`},
+		{WaitForLog: `ready`, WaitForLogTimeout: "soon"},
+		{WaitForLogTimeout: "1m"},
+	} {
+		_, err := svc.CreateLinuxBox(context.Background(), &model.LinuxAndroidBoxCreateParam{Config: cfg})
+		assert.ErrorIs(t, err, service.ErrInvalidParams, "config %+v", cfg)
+	}
+	assert.Empty(t, daemon.Calls())
+}
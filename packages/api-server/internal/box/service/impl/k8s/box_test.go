@@ -0,0 +1,33 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func TestDeploymentStatus(t *testing.T) {
+	replicas := func(n int32) *int32 { return &n }
+	tests := []struct {
+		name       string
+		replicas   *int32
+		available  int32
+		wantStatus string
+	}{
+		{"available", replicas(1), 1, "running"},
+		{"scaled to zero", replicas(0), 0, "stopped"},
+		{"scaling down", replicas(0), 1, "running"},
+		{"starting", replicas(1), 0, "pending"},
+		{"default replicas", nil, 0, "pending"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: tt.replicas},
+				Status: appsv1.DeploymentStatus{AvailableReplicas: tt.available},
+			}
+			assert.Equal(t, tt.wantStatus, deploymentStatus(deployment))
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package k8s
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// fakeExecutor writes canned output and ends the stream with err
+type fakeExecutor struct {
+	stdout, stderr string
+	err            error
+}
+
+func (e fakeExecutor) Stream(options remotecommand.StreamOptions) error {
+	options.Stdout.Write([]byte(e.stdout))
+	options.Stderr.Write([]byte(e.stderr))
+	return e.err
+}
+
+func TestStreamExecExitCode(t *testing.T) {
+	result, err := streamExec(fakeExecutor{
+		stderr: "no such file\n",
+		err:    utilexec.CodeExitError{Err: errors.New("command terminated with exit code 42"), Code: 42},
+	})
+	require.NoError(t, err, "a command exiting non-zero is a result, not an error")
+	assert.Equal(t, 42, result.ExitCode)
+	assert.Equal(t, "no such file\n", result.Stderr)
+
+	result, err = streamExec(fakeExecutor{stdout: "ok\n"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "ok\n", result.Stdout)
+
+	_, err = streamExec(fakeExecutor{err: errors.New("connection reset by peer")})
+	assert.ErrorContains(t, err, "connection reset by peer")
+}
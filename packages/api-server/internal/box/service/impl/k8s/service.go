@@ -6,19 +6,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
 
 	"github.com/babelcloud/gbox/packages/api-server/config"
 	"github.com/babelcloud/gbox/packages/api-server/internal/box/service"
@@ -46,6 +51,12 @@ const (
 	annotationCmd     = annotationPrefix + "/cmd"
 	annotationArgs    = annotationPrefix + "/args"
 	annotationWorkDir = annotationPrefix + "/working-dir"
+
+	// Start and stop scale a box's deployment and poll its pods this often
+	// until they are ready or gone, for at most the timeouts
+	scalePollInterval = time.Second
+	startTimeout      = 2 * time.Minute
+	stopTimeout       = time.Minute
 )
 
 // Service implements the box service interface using Kubernetes
@@ -101,8 +112,9 @@ func (s *Service) List(ctx context.Context, params *model.BoxListParams) (*model
 	boxes := make([]model.Box, 0)
 	for _, deployment := range deployments.Items {
 		boxes = append(boxes, model.Box{
-			ID:     deployment.Labels[labelInstance],
-			Status: string(deployment.Status.AvailableReplicas),
+			ID:        deployment.Labels[labelInstance],
+			Status:    deploymentStatus(&deployment),
+			CreatedAt: deployment.CreationTimestamp.Time,
 		})
 	}
 
@@ -118,11 +130,21 @@ func (s *Service) CreateLinuxBox(ctx context.Context, req *model.LinuxAndroidBox
 	return nil, fmt.Errorf("CreateLinuxBox not implemented")
 }
 
+// PlanLinuxBox returns the spec a new linux box would be created with
+func (s *Service) PlanLinuxBox(ctx context.Context, req *model.LinuxAndroidBoxCreateParam) (*model.BoxCreatePlan, error) {
+	return nil, fmt.Errorf("PlanLinuxBox not implemented")
+}
+
 // CreateAndroidBox creates a new android box
 func (s *Service) CreateAndroidBox(ctx context.Context, req *model.AndroidBoxCreateParam) (*model.Box, error) {
 	return nil, fmt.Errorf("CreateAndroidBox not implemented")
 }
 
+// Compose creates a group of boxes (Not Implemented for K8s)
+func (s *Service) Compose(ctx context.Context, req *model.BoxComposeParams) (*model.BoxComposeResult, error) {
+	return nil, fmt.Errorf("compose not implemented for K8s")
+}
+
 // Delete deletes a box by ID
 func (s *Service) Delete(ctx context.Context, id string, req *model.BoxDeleteParams) (*model.BoxDeleteResult, error) {
 	if id == "" {
@@ -168,6 +190,16 @@ func (s *Service) DeleteAll(ctx context.Context, req *model.BoxesDeleteParams) (
 	}, nil
 }
 
+// DeleteGroup deletes the boxes of a group (Not Implemented for K8s)
+func (s *Service) DeleteGroup(ctx context.Context, group string, req *model.BoxesDeleteParams) (*model.BoxesDeleteResult, error) {
+	return nil, fmt.Errorf("group delete not implemented for K8s")
+}
+
+// StopGroup stops the boxes of a group (Not Implemented for K8s)
+func (s *Service) StopGroup(ctx context.Context, group string) (*model.BoxesStopResult, error) {
+	return nil, fmt.Errorf("group stop not implemented for K8s")
+}
+
 // Get returns a box by ID
 func (s *Service) Get(ctx context.Context, id string) (*model.Box, error) {
 	if id == "" {
@@ -184,7 +216,25 @@ func (s *Service) Get(ctx context.Context, id string) (*model.Box, error) {
 		return nil, fmt.Errorf("failed to get box: %v", err)
 	}
 
-	// Map pod status to box status
+	return podToBox(id, pod), nil
+}
+
+// deploymentStatus describes a box from its deployment: running once a pod
+// is available, stopped when scaled to zero, and pending while a pod is
+// wanted but not yet available
+func deploymentStatus(deployment *appsv1.Deployment) string {
+	switch {
+	case deployment.Status.AvailableReplicas >= 1:
+		return "running"
+	case deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == 0:
+		return "stopped"
+	default:
+		return "pending"
+	}
+}
+
+// podToBox maps the status of a box's pod to the box
+func podToBox(id string, pod *corev1.Pod) *model.Box {
 	var status string
 	switch pod.Status.Phase {
 	case corev1.PodRunning:
@@ -200,10 +250,18 @@ func (s *Service) Get(ctx context.Context, id string) (*model.Box, error) {
 	}
 
 	// Create box model
-	return &model.Box{
+	box := &model.Box{
 		ID:     id,
 		Status: status,
-	}, nil
+	}
+	if pod.Status.StartTime != nil {
+		startedAt := pod.Status.StartTime.Time
+		box.StartedAt = &startedAt
+		if pod.Status.Phase == corev1.PodRunning {
+			box.Uptime = int64(time.Since(startedAt) / time.Second)
+		}
+	}
+	return box
 }
 
 // Exec executes a command in a box
@@ -230,35 +288,48 @@ func (s *Service) Exec(ctx context.Context, id string, req *model.BoxExecParams)
 		return nil, fmt.Errorf("box is not running: %s", id)
 	}
 
-	// Create remote command executor
-	execURL, err := url.Parse(fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/exec", tenantNamespace, pod.Name))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse URL: %v", err)
-	}
-
-	// Get the REST config from the client
-	exec, err := remotecommand.NewSPDYExecutor(s.config, "POST", execURL)
+	// Create remote command executor for the command, capturing its output
+	exec, err := remotecommand.NewSPDYExecutor(s.config, "POST", s.client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(tenantNamespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Command: req.Commands,
+			Stdin:   false,
+			Stdout:  true,
+			Stderr:  true,
+			TTY:     false,
+		}, scheme.ParameterCodec).
+		URL())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create executor: %v", err)
 	}
 
-	// Create stream options
-	streamOptions := remotecommand.StreamOptions{
-		Stdin:             nil,
-		Stdout:            nil,
-		Stderr:            nil,
-		TerminalSizeQueue: nil, // We don't need terminal size queue for now
-		Tty:               false,
-	}
+	return streamExec(exec)
+}
 
-	// Start streaming with context
-	err = exec.Stream(streamOptions)
-	if err != nil {
+// streamExec runs a command through exec and collects its result. A command
+// exiting non-zero is reported by the result's exit code; only failing to
+// run it, e.g. a lost connection, is an error.
+func streamExec(exec remotecommand.Executor) (*model.BoxExecResult, error) {
+	var stdout, stderr bytes.Buffer
+	err := exec.Stream(remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Tty:    false,
+	})
+	exitCode := 0
+	if exitErr, ok := err.(utilexec.CodeExitError); ok {
+		exitCode = exitErr.Code
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to stream: %v", err)
 	}
 
 	return &model.BoxExecResult{
-		ExitCode: 0, // TODO: Get actual exit code from pod
+		ExitCode: exitCode,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
 	}, nil
 }
 
@@ -267,6 +338,36 @@ func (s *Service) RunCode(ctx context.Context, id string, req *model.BoxRunCodeP
 	return nil, fmt.Errorf("run-code operation not implemented for K8s")
 }
 
+// ExecStream runs a command streaming its stdout (Not Implemented for K8s)
+func (s *Service) ExecStream(ctx context.Context, id string, req *model.BoxExecParams, stdin io.Reader, stdout io.Writer) (*model.BoxExecResult, error) {
+	return nil, fmt.Errorf("stream exec not implemented for K8s")
+}
+
+// ExecDetached runs a command detached from the request (Not Implemented for K8s)
+func (s *Service) ExecDetached(ctx context.Context, id string, req *model.BoxExecParams) (*model.BoxExecSession, error) {
+	return nil, fmt.Errorf("detached exec not implemented for K8s")
+}
+
+// GetExecSession returns a detached exec session (Not Implemented for K8s)
+func (s *Service) GetExecSession(ctx context.Context, id string, sessionID string, offset int64) (*model.BoxExecSession, error) {
+	return nil, fmt.Errorf("detached exec not implemented for K8s")
+}
+
+// AttachExecSession re-attaches to an interactive exec session (Not Implemented for K8s)
+func (s *Service) AttachExecSession(ctx context.Context, id string, sessionID string, wsConn *websocket.Conn) (*model.BoxExecResult, error) {
+	return nil, fmt.Errorf("detached exec not implemented for K8s")
+}
+
+// ListExecSessions lists the exec sessions of a box (Not Implemented for K8s)
+func (s *Service) ListExecSessions(ctx context.Context, id string) (*model.BoxExecSessionListResult, error) {
+	return nil, fmt.Errorf("detached exec not implemented for K8s")
+}
+
+// KillExecSession kills an exec session (Not Implemented for K8s)
+func (s *Service) KillExecSession(ctx context.Context, id string, sessionID string) (*model.BoxExecSession, error) {
+	return nil, fmt.Errorf("detached exec not implemented for K8s")
+}
+
 // ExecWS executes a command in a box via WebSocket (Not Implemented for K8s)
 func (s *Service) ExecWS(ctx context.Context, id string, params *model.BoxExecWSParams, wsConn *websocket.Conn) (*model.BoxExecResult, error) {
 	// Close the WebSocket immediately as K8s implementation doesn't support it
@@ -275,22 +376,309 @@ func (s *Service) ExecWS(ctx context.Context, id string, params *model.BoxExecWS
 	return nil, fmt.Errorf("ExecWS is not implemented for the Kubernetes service")
 }
 
-// Start starts a stopped box
+// Start starts a stopped box by scaling its deployment back to one replica,
+// returning once a pod of the box is ready
 func (s *Service) Start(ctx context.Context, id string) (*model.BoxStartResult, error) {
-	// TODO: Implement Kubernetes pod start
-	return nil, fmt.Errorf("Kubernetes start not implemented")
+	if id == "" {
+		return nil, fmt.Errorf("box ID is required")
+	}
+	if err := s.scaleBox(ctx, id, 1); err != nil {
+		return nil, err
+	}
+	s.accessTracker.Update(id)
+
+	var ready *corev1.Pod
+	err := wait.PollImmediateWithContext(ctx, scalePollInterval, startTimeout, func(ctx context.Context) (bool, error) {
+		pods, err := s.client.CoreV1().Pods(tenantNamespace).List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=gbox,%s=%s", labelName, labelInstance, id),
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to list pods: %v", err)
+		}
+		for i := range pods.Items {
+			if podReady(&pods.Items[i]) {
+				ready = &pods.Items[i]
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("box %s had no ready pod within %s of starting", id, startTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return podToBox(id, ready), nil
 }
 
-// Stop stops a running box
+// Stop stops a running box by scaling its deployment to zero replicas,
+// returning once all pods of the box are gone
 func (s *Service) Stop(ctx context.Context, id string) (*model.BoxStopResult, error) {
-	// TODO: Implement Kubernetes pod stop
-	return nil, fmt.Errorf("Kubernetes stop not implemented")
+	if id == "" {
+		return nil, fmt.Errorf("box ID is required")
+	}
+	if err := s.scaleBox(ctx, id, 0); err != nil {
+		return nil, err
+	}
+
+	err := wait.PollImmediateWithContext(ctx, scalePollInterval, stopTimeout, func(ctx context.Context) (bool, error) {
+		// Terminating pods are still listed, so none left means all exited
+		pods, err := s.client.CoreV1().Pods(tenantNamespace).List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=gbox,%s=%s", labelName, labelInstance, id),
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to list pods: %v", err)
+		}
+		return len(pods.Items) == 0, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("box %s still had pods %s after stopping", id, stopTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &model.Box{ID: id, Status: "stopped"}, nil
+}
+
+// scaleBox sets the replicas of the deployment backing a box
+func (s *Service) scaleBox(ctx context.Context, id string, replicas int32) error {
+	deployments, err := s.client.AppsV1().Deployments(tenantNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=gbox,%s=%s", labelName, labelInstance, id),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list deployments: %v", err)
+	}
+	if len(deployments.Items) == 0 {
+		return fmt.Errorf("box %s not found: %w", id, service.ErrBoxNotFound)
+	}
+
+	err = s.patchReplicas(ctx, deployments.Items[0].Name, replicas)
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("box %s not found: %w", id, service.ErrBoxNotFound)
+	}
+	return err
+}
+
+// patchReplicas sets the replicas of a deployment. Not found errors are
+// returned as is.
+func (s *Service) patchReplicas(ctx context.Context, name string, replicas int32) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	_, err := s.client.AppsV1().Deployments(tenantNamespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to scale deployment: %w", err)
+	}
+	return err
+}
+
+// podReady reports whether a pod is running, passes its readiness checks and
+// is not being deleted
+func podReady(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Touch refreshes the last access time of a box (Not Implemented for K8s)
+func (s *Service) Touch(ctx context.Context, id string) (*model.BoxTouchResult, error) {
+	return nil, fmt.Errorf("Kubernetes touch not implemented")
+}
+
+// Update changes the resource limits of a box's deployment, which rolls out
+// new pods with the limits
+func (s *Service) Update(ctx context.Context, id string, params *model.BoxUpdateParams) (*model.BoxUpdateResult, error) {
+	cpu, memory, err := service.ParseResourceLimits(params)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := s.client.AppsV1().Deployments(tenantNamespace).Get(ctx, id, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("box %s not found: %w", id, service.ErrBoxNotFound)
+		}
+		return nil, fmt.Errorf("failed to get deployment: %v", err)
+	}
+
+	containers := deployment.Spec.Template.Spec.Containers
+	for i := range containers {
+		if containers[i].Resources.Limits == nil {
+			containers[i].Resources.Limits = corev1.ResourceList{}
+		}
+		if cpu > 0 {
+			containers[i].Resources.Limits[corev1.ResourceCPU] = *resource.NewMilliQuantity(int64(cpu*1000), resource.DecimalSI)
+		}
+		if memory > 0 {
+			containers[i].Resources.Limits[corev1.ResourceMemory] = *resource.NewQuantity(memory, resource.BinarySI)
+		}
+	}
+
+	updated, err := s.client.AppsV1().Deployments(tenantNamespace).Update(ctx, deployment, metav1.UpdateOptions{})
+	if err != nil {
+		if errors.IsInvalid(err) {
+			return nil, fmt.Errorf("%w: %v", service.ErrInvalidParams, err)
+		}
+		return nil, fmt.Errorf("failed to update deployment: %v", err)
+	}
+
+	box := &model.Box{ID: id}
+	box.Config.Labels = updated.Labels
+	if len(containers) > 0 {
+		limits := updated.Spec.Template.Spec.Containers[0].Resources.Limits
+		box.Config.CPU = float64(limits.Cpu().MilliValue()) / 1000
+		box.Config.Memory = float64(limits.Memory().Value()) / (1024 * 1024)
+	}
+	return box, nil
 }
 
-// Reclaim reclaims inactive boxes
+// Reclaim reclaims inactive boxes following the rules of the Docker
+// service: running boxes idle past the stop threshold are scaled to zero,
+// after a warning when warnings are enabled, and stopped boxes idle past the
+// delete threshold are deleted with their services
 func (s *Service) Reclaim(ctx context.Context) (*model.BoxReclaimResult, error) {
-	// TODO: Implement Kubernetes box reclamation
-	return nil, fmt.Errorf("Kubernetes box reclamation not implemented")
+	cluster := config.GetInstance().Cluster
+
+	deployments, err := s.client.AppsV1().Deployments(tenantNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelName + "=gbox",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %v", err)
+	}
+
+	result := &model.BoxReclaimResult{}
+	skippedCount := 0
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		boxID := deployment.Labels[labelInstance]
+		if boxID == "" {
+			s.logger.Warn("Deployment %s missing %s label, skipping reclaim check", deployment.Name, labelInstance)
+			continue
+		}
+
+		lastAccessed, found := s.accessTracker.GetLastAccessed(boxID)
+		if !found {
+			// The tracker starts counting now, treat the box as recently accessed
+			s.logger.Debug("Box %s first seen by tracker, skipping reclaim this cycle", boxID)
+			skippedCount++
+			continue
+		}
+		idle := time.Since(lastAccessed)
+
+		if deploymentStatus(deployment) == "stopped" {
+			if !cluster.ReclaimDeleteEnabled || idle < cluster.ReclaimDeleteThreshold {
+				skippedCount++
+				continue
+			}
+			s.logger.Info("Deleting inactive stopped box %s (idle for %v)", boxID, idle)
+			if err := s.deleteBoxDeployment(ctx, boxID, deployment.Name); err != nil {
+				s.logger.Error("Failed to delete box %s: %v", boxID, err)
+				continue
+			}
+			s.accessTracker.Remove(boxID)
+			result.DeletedCount++
+			result.DeletedIDs = append(result.DeletedIDs, boxID)
+			continue
+		}
+
+		// With warnings enabled, a box is only stopped a grace period after its warning
+		if cluster.ReclaimWarnThreshold > 0 && idle >= cluster.ReclaimWarnThreshold {
+			warnedAt, warned := s.accessTracker.GetWarned(boxID)
+			if !warned {
+				notBefore := lastAccessed.Add(cluster.ReclaimStopThreshold)
+				if graceEnd := time.Now().Add(cluster.ReclaimGracePeriod); graceEnd.After(notBefore) {
+					notBefore = graceEnd
+				}
+				s.warnReclaim(ctx, boxID, idle, notBefore)
+				result.WarnedCount++
+				result.WarnedIDs = append(result.WarnedIDs, boxID)
+				continue
+			}
+			if time.Since(warnedAt) < cluster.ReclaimGracePeriod {
+				skippedCount++
+				continue
+			}
+		}
+		if idle < cluster.ReclaimStopThreshold {
+			skippedCount++
+			continue
+		}
+		s.logger.Info("Stopping inactive running box %s (idle for %v)", boxID, idle)
+		if err := s.patchReplicas(ctx, deployment.Name, 0); err != nil {
+			s.logger.Error("Failed to stop box %s: %v", boxID, err)
+			continue
+		}
+		result.StoppedCount++
+		result.StoppedIDs = append(result.StoppedIDs, boxID)
+	}
+
+	s.logger.Info("Box reclaim finished. Skipped: %d, Warned: %d, Stopped: %d, Deleted: %d",
+		skippedCount, result.WarnedCount, result.StoppedCount, result.DeletedCount)
+	return result, nil
+}
+
+// warnReclaim records and announces that an idle box will be stopped no
+// sooner than notBefore
+func (s *Service) warnReclaim(ctx context.Context, boxID string, idle time.Duration, notBefore time.Time) {
+	s.logger.Warn("Box %s has been idle for %v and will be stopped after %s unless it is accessed",
+		boxID, idle.Round(time.Second), notBefore.Format(time.RFC3339))
+	s.accessTracker.MarkWarned(boxID)
+
+	webhook := config.GetInstance().Cluster.ReclaimWebhook
+	if webhook == "" {
+		return
+	}
+	warning := &model.BoxReclaimWarning{
+		Event:     service.ReclaimWarningEvent,
+		BoxID:     boxID,
+		Action:    "stop",
+		IdleFor:   idle.Round(time.Second).String(),
+		NotBefore: notBefore,
+	}
+	if err := service.NotifyReclaimWarning(ctx, webhook, warning); err != nil {
+		s.logger.Error("Failed to send reclaim warning for box %s: %v", boxID, err)
+	}
+}
+
+// deleteBoxDeployment deletes the services exposing a box and the
+// deployment backing it
+func (s *Service) deleteBoxDeployment(ctx context.Context, boxID, name string) error {
+	services, err := s.client.CoreV1().Services(tenantNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=gbox,%s=%s", labelName, labelInstance, boxID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list services: %v", err)
+	}
+	for _, svc := range services.Items {
+		err := s.client.CoreV1().Services(tenantNamespace).Delete(ctx, svc.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete service %s: %v", svc.Name, err)
+		}
+	}
+
+	err = s.client.AppsV1().Deployments(tenantNamespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete deployment: %v", err)
+	}
+	return nil
+}
+
+// Resurrect recreates a reclaimed box from its snapshot (Not Implemented for K8s)
+func (s *Service) Resurrect(ctx context.Context, id string) (*model.Box, error) {
+	return nil, fmt.Errorf("resurrect not implemented for K8s")
+}
+
+// EnforceMaxRuntime implements Service.EnforceMaxRuntime
+func (s *Service) EnforceMaxRuntime(ctx context.Context) (*model.BoxMaxRuntimeResult, error) {
+	// TODO: Implement Kubernetes max runtime enforcement. Kubernetes boxes
+	// never get a max runtime yet, and this runs every minute, so report
+	// nothing to do rather than an error.
+	return &model.BoxMaxRuntimeResult{}, nil
 }
 
 // GetArchive gets files from box as tar archive
@@ -610,6 +998,42 @@ func (s *Service) GetExternalPort(ctx context.Context, id string, internalPort i
 
 // Image management methods removed - handled by background ImageManager (Docker only)
 
+// ListImages lists the images referenced by box deployments. Images on the
+// cluster nodes are not visible to the server, so params.All makes no
+// difference and sizes and build times are unknown.
+func (s *Service) ListImages(ctx context.Context, params *model.BoxImageListParams) (*model.BoxImageListResult, error) {
+	deployments, err := s.client.AppsV1().Deployments(tenantNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelName + "=gbox",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %v", err)
+	}
+
+	var refs []string
+	seen := make(map[string]bool)
+	for _, deployment := range deployments.Items {
+		for _, c := range deployment.Spec.Template.Spec.Containers {
+			if !seen[c.Image] {
+				seen[c.Image] = true
+				refs = append(refs, c.Image)
+			}
+		}
+	}
+	sort.Strings(refs)
+
+	result := &model.BoxImageListResult{Images: []model.BoxImage{}}
+	for _, ref := range refs {
+		result.Images = append(result.Images, model.BoxImage{RepoTags: []string{ref}, InUse: true})
+	}
+	return result, nil
+}
+
+// PruneImages prunes dangling images
+func (s *Service) PruneImages(ctx context.Context) (*model.BoxImagePruneResult, error) {
+	// Images live on the cluster nodes and are garbage collected by the kubelet
+	return nil, fmt.Errorf("Kubernetes image pruning not implemented")
+}
+
 // Helper functions
 func getImage(image string) string {
 	if image == "" {
@@ -718,6 +1142,22 @@ func (s *Service) WriteFile(ctx context.Context, id string, params *model.BoxFil
 	return nil, nil
 }
 
+func (s *Service) Logs(ctx context.Context, id string, params *model.BoxLogsParams) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("Logs not implemented")
+}
+
+func (s *Service) StatFile(ctx context.Context, id string, params *model.BoxFileStatParams) (*model.BoxFileStat, error) {
+	return nil, fmt.Errorf("StatFile not implemented")
+}
+
+func (s *Service) SyncManifest(ctx context.Context, id string, params *model.BoxSyncManifestParams) (*model.BoxSyncManifest, error) {
+	return nil, fmt.Errorf("SyncManifest not implemented")
+}
+
+func (s *Service) ApplySync(ctx context.Context, id string, params *model.BoxSyncApplyParams) (*model.BoxSyncResult, error) {
+	return nil, fmt.Errorf("ApplySync not implemented")
+}
+
 func init() {
 	service.Register("k8s", func(tracker tracker.AccessTracker) (service.BoxService, error) {
 		return NewService(tracker)
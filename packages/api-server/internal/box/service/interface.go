@@ -16,17 +16,34 @@ type BoxService interface {
 	List(ctx context.Context, params *model.BoxListParams) (*model.BoxListResult, error)
 	Get(ctx context.Context, id string) (*model.Box, error)
 	CreateLinuxBox(ctx context.Context, params *model.LinuxAndroidBoxCreateParam) (*model.Box, error)
+	PlanLinuxBox(ctx context.Context, params *model.LinuxAndroidBoxCreateParam) (*model.BoxCreatePlan, error)
 	CreateAndroidBox(ctx context.Context, params *model.AndroidBoxCreateParam) (*model.Box, error)
+	Compose(ctx context.Context, params *model.BoxComposeParams) (*model.BoxComposeResult, error)
 	Delete(ctx context.Context, id string, params *model.BoxDeleteParams) (*model.BoxDeleteResult, error)
 	DeleteAll(ctx context.Context, params *model.BoxesDeleteParams) (*model.BoxesDeleteResult, error)
+	DeleteGroup(ctx context.Context, group string, params *model.BoxesDeleteParams) (*model.BoxesDeleteResult, error)
 	Reclaim(ctx context.Context) (*model.BoxReclaimResult, error)
+	Resurrect(ctx context.Context, id string) (*model.Box, error)
+	EnforceMaxRuntime(ctx context.Context) (*model.BoxMaxRuntimeResult, error)
 
 	// Box runtime operations
 	Start(ctx context.Context, id string) (*model.BoxStartResult, error)
 	Stop(ctx context.Context, id string) (*model.BoxStopResult, error)
+	StopGroup(ctx context.Context, group string) (*model.BoxesStopResult, error)
+	Touch(ctx context.Context, id string) (*model.BoxTouchResult, error)
+	Update(ctx context.Context, id string, params *model.BoxUpdateParams) (*model.BoxUpdateResult, error)
 	Exec(ctx context.Context, id string, params *model.BoxExecParams) (*model.BoxExecResult, error)
 	ExecWS(ctx context.Context, id string, params *model.BoxExecWSParams, wsConn *websocket.Conn) (*model.BoxExecResult, error)
+	ExecStream(ctx context.Context, id string, params *model.BoxExecParams, stdin io.Reader, stdout io.Writer) (*model.BoxExecResult, error)
 	RunCode(ctx context.Context, id string, params *model.BoxRunCodeParams) (*model.BoxRunCodeResult, error)
+	ExecDetached(ctx context.Context, id string, params *model.BoxExecParams) (*model.BoxExecSession, error)
+	GetExecSession(ctx context.Context, id string, sessionID string, offset int64) (*model.BoxExecSession, error)
+	AttachExecSession(ctx context.Context, id string, sessionID string, wsConn *websocket.Conn) (*model.BoxExecResult, error)
+	ListExecSessions(ctx context.Context, id string) (*model.BoxExecSessionListResult, error)
+	KillExecSession(ctx context.Context, id string, sessionID string) (*model.BoxExecSession, error)
+
+	// Box log operations
+	Logs(ctx context.Context, id string, params *model.BoxLogsParams) (io.ReadCloser, error)
 
 	// Box file operations
 	GetArchive(ctx context.Context, id string, params *model.BoxArchiveGetParams) (*model.BoxArchiveResult, io.ReadCloser, error)
@@ -37,8 +54,13 @@ type BoxService interface {
 	ListFiles(ctx context.Context, id string, params *model.BoxFileListParams) (*model.BoxFileListResult, error)
 	ReadFile(ctx context.Context, id string, params *model.BoxFileReadParams) (*model.BoxFileReadResult, error)
 	WriteFile(ctx context.Context, id string, params *model.BoxFileWriteParams) (*model.BoxFileWriteResult, error)
+	StatFile(ctx context.Context, id string, params *model.BoxFileStatParams) (*model.BoxFileStat, error)
+	SyncManifest(ctx context.Context, id string, params *model.BoxSyncManifestParams) (*model.BoxSyncManifest, error)
+	ApplySync(ctx context.Context, id string, params *model.BoxSyncApplyParams) (*model.BoxSyncResult, error)
 
 	// Box image operations - removed UpdateBoxImage methods as they are now handled by background ImageManager
+	ListImages(ctx context.Context, params *model.BoxImageListParams) (*model.BoxImageListResult, error)
+	PruneImages(ctx context.Context) (*model.BoxImagePruneResult, error)
 
 	// GetExternalPort retrieves the host port mapping for a specific internal port of a box.
 	GetExternalPort(ctx context.Context, id string, internalPort int) (int, error)
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/docker/go-units"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// MinCPULimit is the smallest CPU limit a box can be given, in cores
+const MinCPULimit = 0.01
+
+// ParseResourceLimits validates the limits of an update request, returning
+// the CPU limit in cores and the memory limit in bytes, 0 when unchanged
+func ParseResourceLimits(params *model.BoxUpdateParams) (cpu float64, memory int64, err error) {
+	if params == nil || (params.CPU == 0 && params.Memory == "") {
+		return 0, 0, fmt.Errorf("%w: at least one of cpu or memory is required", ErrInvalidParams)
+	}
+	if params.CPU != 0 && params.CPU < MinCPULimit {
+		return 0, 0, fmt.Errorf("%w: cpu limit %g must be at least %g", ErrInvalidParams, params.CPU, MinCPULimit)
+	}
+	if params.Memory != "" {
+		memory, err = units.RAMInBytes(params.Memory)
+		if err != nil || memory <= 0 {
+			return 0, 0, fmt.Errorf("%w: invalid memory limit %q", ErrInvalidParams, params.Memory)
+		}
+	}
+	return params.CPU, memory, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// ReclaimWarningEvent is the event of every reclaim warning
+const ReclaimWarningEvent = "box.reclaim.warning"
+
+// reclaimWebhookTimeout bounds a webhook call so a slow receiver cannot hold
+// up the reclaim of other boxes
+const reclaimWebhookTimeout = 10 * time.Second
+
+// NotifyReclaimWarning posts warning as JSON to the webhook at url. Any
+// response other than 2xx is an error.
+func NotifyReclaimWarning(ctx context.Context, url string, warning *model.BoxReclaimWarning) error {
+	body, err := json.Marshal(warning)
+	if err != nil {
+		return fmt.Errorf("failed to encode reclaim warning: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reclaimWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid reclaim webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call reclaim webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("reclaim webhook returned %s", resp.Status)
+	}
+	return nil
+}
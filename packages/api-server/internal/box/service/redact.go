@@ -0,0 +1,24 @@
+package service
+
+import (
+	"path"
+	"strings"
+)
+
+// RedactedValue replaces the values of sensitive environment variables
+const RedactedValue = "***"
+
+// RedactEnv replaces the values in envs of the variables whose name matches
+// one of patterns with RedactedValue. Patterns are globs matched against
+// the whole name, ignoring case, so *_TOKEN matches GITHUB_TOKEN.
+func RedactEnv(envs map[string]string, patterns []string) {
+	for name := range envs {
+		upper := strings.ToUpper(name)
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.ToUpper(pattern), upper); matched {
+				envs[name] = RedactedValue
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// namespacedSysctls are the IPC sysctls isolated per box by the kernel's IPC
+// namespace. Sysctls under namespacedSysctlPrefixes are isolated as well.
+var namespacedSysctls = map[string]bool{
+	"kernel.msgmax":          true,
+	"kernel.msgmnb":          true,
+	"kernel.msgmni":          true,
+	"kernel.sem":             true,
+	"kernel.shmall":          true,
+	"kernel.shmmax":          true,
+	"kernel.shmmni":          true,
+	"kernel.shm_rmid_forced": true,
+}
+
+// namespacedSysctlPrefixes cover the message queue and network namespaces.
+// Boxes never share the host network, so net.* only affects the box.
+var namespacedSysctlPrefixes = []string{"fs.mqueue.", "net."}
+
+var sysctlNamePattern = regexp.MustCompile(`^[a-z0-9_]+(\.[a-zA-Z0-9_*/-]+)+$`)
+
+// IsNamespacedSysctl reports whether setting the sysctl only affects the box
+func IsNamespacedSysctl(name string) bool {
+	if namespacedSysctls[name] {
+		return true
+	}
+	for _, prefix := range namespacedSysctlPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSysctls checks the sysctls of a create request. Sysctls that are not
+// namespaced change the host kernel for every box and are rejected unless
+// allowUnsafe is set.
+func ValidateSysctls(sysctls map[string]string, allowUnsafe bool) error {
+	names := make([]string, 0, len(sysctls))
+	for name := range sysctls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !sysctlNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: invalid sysctl name %q", ErrInvalidParams, name)
+		}
+		if sysctls[name] == "" {
+			return fmt.Errorf("%w: sysctl %s requires a value", ErrInvalidParams, name)
+		}
+		if !allowUnsafe && !IsNamespacedSysctl(name) {
+			return fmt.Errorf("%w: sysctl %s is not namespaced and would affect the host; unsafe sysctls are disabled on this server", ErrInvalidParams, name)
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/emicklei/go-restful/v3"
 
 	browserSvc "github.com/babelcloud/gbox/packages/api-server/internal/browser/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
 )
 
 // Handler wraps the browser service to expose it via API endpoints.
@@ -57,3 +58,376 @@ func (h *Handler) GetCdpURL(req *restful.Request, resp *restful.Response) {
 	resp.Header().Set("Content-Type", "text/plain")
 	_, _ = resp.Write([]byte(cdpURL))
 }
+
+// --- Context Handlers ---
+
+// CreateContext handles POST /boxes/{id}/browser/contexts
+func (h *Handler) CreateContext(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	var params model.CreateContextParams
+	if req.Request.ContentLength != 0 {
+		if err := req.ReadEntity(&params); err != nil {
+			writeError(resp, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+
+	result, err := h.service.CreateContext(req.Request.Context(), boxID, params)
+	if err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	_ = resp.WriteHeaderAndEntity(http.StatusCreated, result)
+}
+
+// DeleteContext handles DELETE /boxes/{id}/browser/contexts/{contextId}
+func (h *Handler) DeleteContext(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	contextID := req.PathParameter("contextId")
+	if boxID == "" || contextID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID and context ID are required"))
+		return
+	}
+
+	if err := h.service.DeleteContext(req.Request.Context(), boxID, contextID); err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// CreatePage handles POST /boxes/{id}/browser/contexts/{contextId}/pages
+func (h *Handler) CreatePage(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	contextID := req.PathParameter("contextId")
+	if boxID == "" || contextID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID and context ID are required"))
+		return
+	}
+
+	var params model.CreatePageParams
+	if req.Request.ContentLength != 0 {
+		if err := req.ReadEntity(&params); err != nil {
+			writeError(resp, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+
+	result, err := h.service.CreatePage(req.Request.Context(), boxID, contextID, params)
+	if err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	_ = resp.WriteHeaderAndEntity(http.StatusCreated, result)
+}
+
+// ListPages handles GET /boxes/{id}/browser/contexts/{contextId}/pages
+func (h *Handler) ListPages(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	contextID := req.PathParameter("contextId")
+	if boxID == "" || contextID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID and context ID are required"))
+		return
+	}
+
+	result, err := h.service.ListPages(req.Request.Context(), boxID, contextID)
+	if err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// FocusPage handles POST /boxes/{id}/browser/contexts/{contextId}/pages/{pageId}/focus
+func (h *Handler) FocusPage(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	contextID := req.PathParameter("contextId")
+	pageID := req.PathParameter("pageId")
+	if boxID == "" || contextID == "" || pageID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID, context ID and page ID are required"))
+		return
+	}
+
+	if err := h.service.FocusPage(req.Request.Context(), boxID, contextID, pageID); err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// --- Page Element Handlers ---
+
+// FindElement handles POST /boxes/{id}/browser/find-element
+func (h *Handler) FindElement(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	var params model.FindElementParams
+	if err := req.ReadEntity(&params); err != nil {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	result, err := h.service.FindElement(req.Request.Context(), boxID, params)
+	if err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// Evaluate handles POST /boxes/{id}/browser/evaluate
+func (h *Handler) Evaluate(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	var params model.EvaluateParams
+	if err := req.ReadEntity(&params); err != nil {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	result, err := h.service.Evaluate(req.Request.Context(), boxID, params)
+	if err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// WaitForNavigation handles POST /boxes/{id}/browser/wait-for-navigation
+func (h *Handler) WaitForNavigation(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	var params model.WaitForNavigationParams
+	if req.Request.ContentLength != 0 {
+		if err := req.ReadEntity(&params); err != nil {
+			writeError(resp, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+
+	result, err := h.service.WaitForNavigation(req.Request.Context(), boxID, params)
+	if err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// WaitForLoadState handles POST /boxes/{id}/browser/wait-for-load-state
+func (h *Handler) WaitForLoadState(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	var params model.WaitForLoadStateParams
+	if req.Request.ContentLength != 0 {
+		if err := req.ReadEntity(&params); err != nil {
+			writeError(resp, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+
+	result, err := h.service.WaitForLoadState(req.Request.Context(), boxID, params)
+	if err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// SetInputFiles handles POST /boxes/{id}/browser/set-input-files
+func (h *Handler) SetInputFiles(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	var params model.SetInputFilesParams
+	if err := req.ReadEntity(&params); err != nil {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	result, err := h.service.SetInputFiles(req.Request.Context(), boxID, params)
+	if err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// --- Network Log Handlers ---
+
+// EnableNetworkLog handles POST /boxes/{id}/browser/network-log
+func (h *Handler) EnableNetworkLog(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	var params model.NetworkLogParams
+	if req.Request.ContentLength != 0 {
+		if err := req.ReadEntity(&params); err != nil {
+			writeError(resp, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+
+	if err := h.service.EnableNetworkLog(req.Request.Context(), boxID, params); err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// GetNetworkLog handles GET /boxes/{id}/browser/network-log
+func (h *Handler) GetNetworkLog(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	result, err := h.service.GetNetworkLog(boxID, req.QueryParameter("urlPattern"))
+	if err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// DisableNetworkLog handles DELETE /boxes/{id}/browser/network-log
+func (h *Handler) DisableNetworkLog(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	if err := h.service.DisableNetworkLog(boxID); err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// --- Console Log Handlers ---
+
+// EnableConsoleLog handles POST /boxes/{id}/browser/console-log
+func (h *Handler) EnableConsoleLog(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	var params model.ConsoleLogParams
+	if req.Request.ContentLength != 0 {
+		if err := req.ReadEntity(&params); err != nil {
+			writeError(resp, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+
+	if err := h.service.EnableConsoleLog(req.Request.Context(), boxID, params); err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// GetConsoleLog handles GET /boxes/{id}/browser/console-log
+func (h *Handler) GetConsoleLog(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	result, err := h.service.GetConsoleLog(boxID, req.QueryParameter("level"))
+	if err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
+// DisableConsoleLog handles DELETE /boxes/{id}/browser/console-log
+func (h *Handler) DisableConsoleLog(req *restful.Request, resp *restful.Response) {
+	boxID := req.PathParameter("id")
+	if boxID == "" {
+		writeError(resp, http.StatusBadRequest, fmt.Errorf("box ID is required"))
+		return
+	}
+
+	if err := h.service.DisableConsoleLog(boxID); err != nil {
+		writeServiceError(resp, err)
+		return
+	}
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// writeServiceError maps browser service errors to HTTP status codes.
+func writeServiceError(resp *restful.Response, err error) {
+	switch {
+	case errors.Is(err, browserSvc.ErrInvalidParams):
+		writeError(resp, http.StatusBadRequest, err)
+	case errors.Is(err, browserSvc.ErrBoxNotFound),
+		errors.Is(err, browserSvc.ErrNoPage),
+		errors.Is(err, browserSvc.ErrElementNotFound),
+		errors.Is(err, browserSvc.ErrNetworkLogDisabled),
+		errors.Is(err, browserSvc.ErrConsoleLogDisabled),
+		errors.Is(err, browserSvc.ErrContextNotFound),
+		errors.Is(err, browserSvc.ErrPageNotFound):
+		writeError(resp, http.StatusNotFound, err)
+	case errors.Is(err, browserSvc.ErrMultipleElements):
+		writeError(resp, http.StatusConflict, err)
+	case errors.Is(err, browserSvc.ErrEvaluationFailed):
+		writeError(resp, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, browserSvc.ErrResultTooLarge):
+		writeError(resp, http.StatusRequestEntityTooLarge, err)
+	case errors.Is(err, browserSvc.ErrTimeout):
+		writeError(resp, http.StatusGatewayTimeout, err)
+	case errors.Is(err, browserSvc.ErrBrowserUnavailable):
+		writeError(resp, http.StatusNotImplemented, err)
+	default:
+		writeError(resp, http.StatusInternalServerError, err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/emicklei/go-restful/v3"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
 )
 
 // RegisterBrowserRoutes adds the browser API routes to the web service.
@@ -18,4 +20,157 @@ func RegisterBrowserRoutes(ws *restful.WebService, handler *Handler) {
 		Returns(http.StatusBadRequest, "Bad Request", nil).
 		Returns(http.StatusNotFound, "Not Found", nil).
 		Returns(http.StatusInternalServerError, "Internal Server Error", nil))
+
+	// --- Context Routes ---
+
+	ws.Route(ws.POST("/boxes/{id}/browser/contexts").To(handler.CreateContext).
+		Doc("Create an isolated browser context with the requested browser engine, optionally emulating a device").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Reads(model.CreateContextParams{}).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(http.StatusCreated, "Created context", model.CreateContextResult{}).
+		Returns(http.StatusBadRequest, "Bad Request", nil).
+		Returns(http.StatusNotFound, "Not Found", nil).
+		Returns(http.StatusNotImplemented, "Browser type not available in the box", nil).
+		Returns(http.StatusInternalServerError, "Internal Server Error", nil))
+
+	ws.Route(ws.DELETE("/boxes/{id}/browser/contexts/{contextId}").To(handler.DeleteContext).
+		Doc("Close a browser context and its pages").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.PathParameter("contextId", "identifier of the browser context").DataType("string")).
+		Returns(http.StatusNoContent, "Context closed", nil).
+		Returns(http.StatusNotFound, "Context not found", nil).
+		Returns(http.StatusInternalServerError, "Internal Server Error", nil))
+
+	ws.Route(ws.POST("/boxes/{id}/browser/contexts/{contextId}/pages").To(handler.CreatePage).
+		Doc("Open a new page in a browser context, in the background").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.PathParameter("contextId", "identifier of the browser context").DataType("string")).
+		Reads(model.CreatePageParams{}).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(http.StatusCreated, "Created page", model.PageInfo{}).
+		Returns(http.StatusNotFound, "Context not found", nil).
+		Returns(http.StatusInternalServerError, "Internal Server Error", nil))
+
+	ws.Route(ws.GET("/boxes/{id}/browser/contexts/{contextId}/pages").To(handler.ListPages).
+		Doc("List the pages of a browser context and which one is focused").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.PathParameter("contextId", "identifier of the browser context").DataType("string")).
+		Returns(http.StatusOK, "Pages of the context", model.ListPagesResult{}).
+		Returns(http.StatusNotFound, "Context not found", nil).
+		Returns(http.StatusInternalServerError, "Internal Server Error", nil))
+
+	ws.Route(ws.POST("/boxes/{id}/browser/contexts/{contextId}/pages/{pageId}/focus").To(handler.FocusPage).
+		Doc("Bring a page to the front and make it the target of page actions without a pageId").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.PathParameter("contextId", "identifier of the browser context").DataType("string")).
+		Param(ws.PathParameter("pageId", "identifier of the page").DataType("string")).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(http.StatusNoContent, "Page focused", nil).
+		Returns(http.StatusNotFound, "Context or page not found", nil).
+		Returns(http.StatusInternalServerError, "Internal Server Error", nil))
+
+	// --- Page Element Routes ---
+
+	ws.Route(ws.POST("/boxes/{id}/browser/find-element").To(handler.FindElement).
+		Doc("Find an element by selector or visible text and return its center coordinates").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Reads(model.FindElementParams{}).
+		Returns(http.StatusOK, "Element location", model.FindElementResult{}).
+		Returns(http.StatusBadRequest, "Bad Request", nil).
+		Returns(http.StatusNotFound, "Element or page not found", nil).
+		Returns(http.StatusConflict, "Multiple elements matched in strict mode", nil).
+		Returns(http.StatusInternalServerError, "Internal Server Error", nil))
+
+	ws.Route(ws.POST("/boxes/{id}/browser/evaluate").To(handler.Evaluate).
+		Doc("Evaluate a JavaScript expression in the box's current page, optionally waiting for the navigation it triggers").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Reads(model.EvaluateParams{}).
+		Returns(http.StatusOK, "JSON serialized result", model.EvaluateResult{}).
+		Returns(http.StatusBadRequest, "Bad Request", nil).
+		Returns(http.StatusNotFound, "Box or page not found", nil).
+		Returns(http.StatusRequestEntityTooLarge, "Result exceeds the size limit", nil).
+		Returns(http.StatusUnprocessableEntity, "Expression threw or returned a non-serializable value", nil).
+		Returns(http.StatusGatewayTimeout, "Evaluation timed out", nil))
+
+	ws.Route(ws.POST("/boxes/{id}/browser/wait-for-navigation").To(handler.WaitForNavigation).
+		Doc("Wait for the next navigation of the box's current page to reach a load state").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Reads(model.WaitForNavigationParams{}).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(http.StatusOK, "URL navigated to", model.NavigationResult{}).
+		Returns(http.StatusBadRequest, "Bad Request", nil).
+		Returns(http.StatusNotFound, "Box or page not found", nil).
+		Returns(http.StatusGatewayTimeout, "No navigation within the timeout", nil))
+
+	ws.Route(ws.POST("/boxes/{id}/browser/wait-for-load-state").To(handler.WaitForLoadState).
+		Doc("Wait until the box's current page has reached a load state").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Reads(model.WaitForLoadStateParams{}).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(http.StatusOK, "Current page URL", model.NavigationResult{}).
+		Returns(http.StatusBadRequest, "Bad Request", nil).
+		Returns(http.StatusNotFound, "Box or page not found", nil).
+		Returns(http.StatusGatewayTimeout, "Load state not reached within the timeout", nil))
+
+	ws.Route(ws.POST("/boxes/{id}/browser/set-input-files").To(handler.SetInputFiles).
+		Doc("Set files from the box's share directory on a file input").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Reads(model.SetInputFilesParams{}).
+		Returns(http.StatusOK, "File names reported by the input", model.SetInputFilesResult{}).
+		Returns(http.StatusBadRequest, "Bad Request", nil).
+		Returns(http.StatusNotFound, "Box, page or element not found", nil).
+		Returns(http.StatusInternalServerError, "Internal Server Error", nil))
+
+	// --- Network Log Routes ---
+
+	ws.Route(ws.POST("/boxes/{id}/browser/network-log").To(handler.EnableNetworkLog).
+		Doc("Start recording network requests made by the box's current page").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Reads(model.NetworkLogParams{}).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(http.StatusNoContent, "Network logging enabled", nil).
+		Returns(http.StatusBadRequest, "Bad Request", nil).
+		Returns(http.StatusNotFound, "Box or page not found", nil).
+		Returns(http.StatusInternalServerError, "Internal Server Error", nil))
+
+	ws.Route(ws.GET("/boxes/{id}/browser/network-log").To(handler.GetNetworkLog).
+		Doc("List recorded network requests, oldest first").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.QueryParameter("urlPattern", "regular expression the request URL must match").DataType("string")).
+		Returns(http.StatusOK, "Recorded requests", model.NetworkLogResult{}).
+		Returns(http.StatusBadRequest, "Bad Request", nil).
+		Returns(http.StatusNotFound, "Network logging is not enabled", nil))
+
+	ws.Route(ws.DELETE("/boxes/{id}/browser/network-log").To(handler.DisableNetworkLog).
+		Doc("Stop recording network requests and discard the log").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Returns(http.StatusNoContent, "Network logging disabled", nil).
+		Returns(http.StatusNotFound, "Network logging is not enabled", nil))
+
+	// --- Console Log Routes ---
+
+	ws.Route(ws.POST("/boxes/{id}/browser/console-log").To(handler.EnableConsoleLog).
+		Doc("Start recording console messages of the box's current page").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Reads(model.ConsoleLogParams{}).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(http.StatusNoContent, "Console logging enabled", nil).
+		Returns(http.StatusBadRequest, "Bad Request", nil).
+		Returns(http.StatusNotFound, "Box or page not found", nil).
+		Returns(http.StatusInternalServerError, "Internal Server Error", nil))
+
+	ws.Route(ws.GET("/boxes/{id}/browser/console-log").To(handler.GetConsoleLog).
+		Doc("List recorded console messages, oldest first").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Param(ws.QueryParameter("level", "comma separated levels to include: debug, info, log, warning, error").DataType("string")).
+		Returns(http.StatusOK, "Recorded messages", model.ConsoleLogResult{}).
+		Returns(http.StatusBadRequest, "Bad Request", nil).
+		Returns(http.StatusNotFound, "Console logging is not enabled", nil))
+
+	ws.Route(ws.DELETE("/boxes/{id}/browser/console-log").To(handler.DisableConsoleLog).
+		Doc("Stop recording console messages and discard the log").
+		Param(ws.PathParameter("id", "identifier of the box").DataType("string")).
+		Returns(http.StatusNoContent, "Console logging disabled", nil).
+		Returns(http.StatusNotFound, "Console logging is not enabled", nil))
 }
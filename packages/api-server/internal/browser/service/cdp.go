@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+)
+
+// cdpTarget is an entry returned by the DevTools /json/list endpoint.
+type cdpTarget struct {
+	ID                   string `json:"id"`
+	Type                 string `json:"type"`
+	URL                  string `json:"url"`
+	Title                string `json:"title"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// cdpMessage is a DevTools protocol command response or event.
+type cdpMessage struct {
+	ID     int64           `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// cdpSession is a DevTools protocol connection to a single page target.
+// A background reader routes command responses to their callers and events
+// to subscribers.
+type cdpSession struct {
+	conn    *websocket.Conn
+	nextID  int64
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	pending     map[int64]chan cdpMessage
+	subscribers []func(method string, params json.RawMessage)
+	done        chan struct{}
+	err         error
+}
+
+func newCdpSession(conn *websocket.Conn) *cdpSession {
+	c := &cdpSession{
+		conn:    conn,
+		pending: make(map[int64]chan cdpMessage),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// listTargets returns the DevTools targets exposed at cdpURL.
+func listTargets(ctx context.Context, cdpURL string) ([]cdpTarget, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cdpURL+"/json/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list browser targets: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list browser targets: status %d", resp.StatusCode)
+	}
+
+	var targets []cdpTarget
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("failed to decode browser targets: %w", err)
+	}
+	return targets, nil
+}
+
+// dialPage connects to the first page target exposed at cdpURL.
+func dialPage(ctx context.Context, cdpURL string) (*cdpSession, error) {
+	targets, err := listTargets(ctx, cdpURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range targets {
+		if target.Type != "page" || target.WebSocketDebuggerURL == "" {
+			continue
+		}
+		return dialDebugger(ctx, cdpURL, target.WebSocketDebuggerURL)
+	}
+	return nil, ErrNoPage
+}
+
+// dialPageTarget connects to the page target with the given ID.
+func dialPageTarget(ctx context.Context, cdpURL string, targetID string) (*cdpSession, error) {
+	return dialDebugger(ctx, cdpURL, "ws://localhost/devtools/page/"+url.PathEscape(targetID))
+}
+
+// dialBrowser connects to the browser target, which manages contexts and
+// targets rather than page content, and returns the browser's product name
+// (e.g. "Chrome/120.0.6099.71").
+func dialBrowser(ctx context.Context, cdpURL string) (*cdpSession, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cdpURL+"/json/version", nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get browser version: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to get browser version: status %d", resp.StatusCode)
+	}
+
+	var version struct {
+		Browser              string `json:"Browser"`
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return nil, "", fmt.Errorf("failed to decode browser version: %w", err)
+	}
+	session, err := dialDebugger(ctx, cdpURL, version.WebSocketDebuggerURL)
+	if err != nil {
+		return nil, "", err
+	}
+	return session, version.Browser, nil
+}
+
+// dialDebugger connects to a debugger WebSocket URL. The URLs reported by
+// the browser use its in-box address, so the host is replaced with the
+// externally reachable one from cdpURL.
+func dialDebugger(ctx context.Context, cdpURL string, debuggerURL string) (*cdpSession, error) {
+	external, err := url.Parse(cdpURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CDP URL %q: %w", cdpURL, err)
+	}
+	wsURL, err := url.Parse(debuggerURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid debugger URL %q: %w", debuggerURL, err)
+	}
+	wsURL.Host = external.Host
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to browser: %w", err)
+	}
+	return newCdpSession(conn), nil
+}
+
+// Close closes the underlying connection.
+func (c *cdpSession) Close() error {
+	return c.conn.Close()
+}
+
+// Done is closed once the connection has been lost or closed.
+func (c *cdpSession) Done() <-chan struct{} {
+	return c.done
+}
+
+// subscribe registers fn to receive every event sent by the page. fn runs on
+// the reader goroutine and must not block or issue commands.
+func (c *cdpSession) subscribe(fn func(method string, params json.RawMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribers = append(c.subscribers, fn)
+}
+
+func (c *cdpSession) readLoop() {
+	for {
+		var msg cdpMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			close(c.done)
+			return
+		}
+
+		c.mu.Lock()
+		if msg.ID != 0 {
+			if ch, ok := c.pending[msg.ID]; ok {
+				delete(c.pending, msg.ID)
+				ch <- msg
+			}
+			c.mu.Unlock()
+			continue
+		}
+		subscribers := c.subscribers
+		c.mu.Unlock()
+
+		for _, fn := range subscribers {
+			fn(msg.Method, msg.Params)
+		}
+	}
+}
+
+// call sends a command and waits for its response, decoding the result into
+// result when it is non-nil.
+func (c *cdpSession) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	id := atomic.AddInt64(&c.nextID, 1)
+	ch := make(chan cdpMessage, 1)
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	request := map[string]interface{}{"id": id, "method": method}
+	if params != nil {
+		request["params"] = params
+	}
+	c.writeMu.Lock()
+	err := c.conn.WriteJSON(request)
+	c.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to send %s: %w", method, err)
+	}
+
+	var msg cdpMessage
+	select {
+	case msg = <-ch:
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	case <-c.done:
+		c.mu.Lock()
+		err := c.err
+		c.mu.Unlock()
+		return fmt.Errorf("failed to read %s response: %w", method, err)
+	}
+
+	if msg.Error != nil {
+		return fmt.Errorf("%s failed: %s", method, msg.Error.Message)
+	}
+	if result != nil && len(msg.Result) > 0 {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// evaluate runs a JavaScript expression in the page and decodes its value
+// into result.
+func (c *cdpSession) evaluate(ctx context.Context, expression string, result interface{}) error {
+	var resp struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+	params := map[string]interface{}{
+		"expression":    expression,
+		"returnByValue": true,
+		"awaitPromise":  true,
+	}
+	if err := c.call(ctx, "Runtime.evaluate", params, &resp); err != nil {
+		return err
+	}
+	if resp.ExceptionDetails != nil {
+		msg := resp.ExceptionDetails.Exception.Description
+		if msg == "" {
+			msg = resp.ExceptionDetails.Text
+		}
+		return fmt.Errorf("script evaluation failed: %s", msg)
+	}
+	if result != nil && len(resp.Result.Value) > 0 {
+		if err := json.Unmarshal(resp.Result.Value, result); err != nil {
+			return fmt.Errorf("failed to decode script result: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// cdpHandler answers a DevTools command with a result.
+type cdpHandler func(params json.RawMessage) interface{}
+
+// fakeBrowser serves the DevTools HTTP and WebSocket endpoints of a browser
+// with a single page until more are added with AddPage. Its debugger URLs point at the in-box address, like a
+// real browser behind a port mapping. Browser and page commands share one
+// handler map, and events are emitted on every open connection, the way a
+// page reports them to each attached session.
+type fakeBrowser struct {
+	server   *httptest.Server
+	handlers map[string]cdpHandler
+	product  string // Browser name reported by /json/version
+
+	mu      sync.Mutex
+	pages   []string
+	calls   []cdpCall
+	conns   map[*websocket.Conn]bool
+	writeMu sync.Mutex
+}
+
+type cdpCall struct {
+	Target string // ID of the page, or "browser"
+	Method string
+	Params json.RawMessage
+}
+
+func newFakeBrowser(t *testing.T, handlers map[string]cdpHandler) *fakeBrowser {
+	t.Helper()
+	b := &fakeBrowser{handlers: handlers, pages: []string{"page-1"}, conns: make(map[*websocket.Conn]bool)}
+	upgrader := websocket.Upgrader{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json/list", func(w http.ResponseWriter, r *http.Request) {
+		targets := []cdpTarget{
+			{ID: "worker", Type: "service_worker", WebSocketDebuggerURL: "ws://localhost:9222/devtools/page/worker"},
+		}
+		for _, id := range b.Pages() {
+			targets = append(targets, cdpTarget{ID: id, Type: "page", WebSocketDebuggerURL: "ws://localhost:9222/devtools/page/" + id})
+		}
+		json.NewEncoder(w).Encode(targets)
+	})
+	mux.HandleFunc("/json/version", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"Browser": b.product, "webSocketDebuggerUrl": "ws://localhost:9222/devtools/browser/b-1"})
+	})
+	mux.HandleFunc("/devtools/", func(w http.ResponseWriter, r *http.Request) {
+		target := "browser"
+		if r.URL.Path != "/devtools/browser/b-1" {
+			target = strings.TrimPrefix(r.URL.Path, "/devtools/page/")
+			known := false
+			for _, id := range b.Pages() {
+				known = known || id == target
+			}
+			if !known {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.conns[conn] = true
+		b.mu.Unlock()
+		defer func() {
+			b.mu.Lock()
+			delete(b.conns, conn)
+			b.mu.Unlock()
+			conn.Close()
+		}()
+		for {
+			var msg struct {
+				ID     int64           `json:"id"`
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params"`
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.calls = append(b.calls, cdpCall{Target: target, Method: msg.Method, Params: msg.Params})
+			b.mu.Unlock()
+
+			// Unrelated events may arrive before a command's response
+			b.write(conn, map[string]interface{}{"method": "Page.frameNavigated", "params": map[string]interface{}{}})
+
+			handler, ok := b.handlers[msg.Method]
+			if !ok {
+				b.write(conn, map[string]interface{}{"id": msg.ID, "error": map[string]interface{}{"code": -32601, "message": "method not found"}})
+				continue
+			}
+			b.write(conn, map[string]interface{}{"id": msg.ID, "result": handler(msg.Params)})
+		}
+	})
+	b.server = httptest.NewServer(mux)
+	t.Cleanup(b.server.Close)
+	return b
+}
+
+func (b *fakeBrowser) write(conn *websocket.Conn, v interface{}) {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	conn.WriteJSON(v)
+}
+
+// Emit sends an event to every connected session.
+func (b *fakeBrowser) Emit(method string, params interface{}) {
+	b.mu.Lock()
+	conns := make([]*websocket.Conn, 0, len(b.conns))
+	for conn := range b.conns {
+		conns = append(conns, conn)
+	}
+	b.mu.Unlock()
+	for _, conn := range conns {
+		b.write(conn, map[string]interface{}{"method": method, "params": params})
+	}
+}
+
+// AddPage adds a page target to the browser.
+func (b *fakeBrowser) AddPage(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pages = append(b.pages, id)
+}
+
+// Pages returns the IDs of the page targets.
+func (b *fakeBrowser) Pages() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.pages...)
+}
+
+// Calls returns the DevTools commands received so far.
+func (b *fakeBrowser) Calls() []cdpCall {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]cdpCall(nil), b.calls...)
+}
+
+// newTestBrowserService returns a service whose boxes all resolve to browser.
+func newTestBrowserService(browser *fakeBrowser) *BrowserService {
+	return &BrowserService{
+		resolveCdpURL: func(boxID string) (string, error) {
+			if boxID != "box-1" {
+				return "", ErrBoxNotFound
+			}
+			return browser.server.URL, nil
+		},
+	}
+}
+
+// evaluateReturning answers Runtime.evaluate with value.
+func evaluateReturning(value interface{}) cdpHandler {
+	return func(json.RawMessage) interface{} {
+		return map[string]interface{}{"result": map[string]interface{}{"type": "object", "value": value}}
+	}
+}
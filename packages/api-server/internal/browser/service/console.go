@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+// defaultConsoleLogSize is the number of messages kept when no buffer size
+// is requested.
+const defaultConsoleLogSize = 500
+
+// consoleLevels maps DevTools console call types to message levels. Types
+// not listed are recorded at the log level.
+var consoleLevels = map[string]string{
+	"debug":   model.ConsoleLevelDebug,
+	"info":    model.ConsoleLevelInfo,
+	"log":     model.ConsoleLevelLog,
+	"warning": model.ConsoleLevelWarning,
+	"error":   model.ConsoleLevelError,
+	"assert":  model.ConsoleLevelError,
+}
+
+// consoleRecorder keeps the most recent console messages of a page in a ring
+// buffer.
+type consoleRecorder struct {
+	session *cdpSession
+
+	mu       sync.Mutex
+	size     int
+	messages []model.ConsoleMessage
+}
+
+func newConsoleRecorder(session *cdpSession, size int) *consoleRecorder {
+	r := &consoleRecorder{session: session, size: size}
+	session.subscribe(r.handleEvent)
+	return r
+}
+
+// consoleArg is a DevTools remote object passed to a console call.
+type consoleArg struct {
+	Type        string          `json:"type"`
+	Value       json.RawMessage `json:"value"`
+	Description string          `json:"description"`
+}
+
+type consoleStackTrace struct {
+	CallFrames []struct {
+		URL          string `json:"url"`
+		LineNumber   int    `json:"lineNumber"`
+		ColumnNumber int    `json:"columnNumber"`
+	} `json:"callFrames"`
+}
+
+func (r *consoleRecorder) handleEvent(method string, params json.RawMessage) {
+	var message model.ConsoleMessage
+	switch method {
+	case "Runtime.consoleAPICalled":
+		var event struct {
+			Type       string             `json:"type"`
+			Args       []consoleArg       `json:"args"`
+			Timestamp  float64            `json:"timestamp"`
+			StackTrace *consoleStackTrace `json:"stackTrace"`
+		}
+		if err := json.Unmarshal(params, &event); err != nil {
+			return
+		}
+		level, ok := consoleLevels[event.Type]
+		if !ok {
+			level = model.ConsoleLevelLog
+		}
+		texts := make([]string, 0, len(event.Args))
+		for _, arg := range event.Args {
+			texts = append(texts, arg.text())
+		}
+		message = model.ConsoleMessage{
+			Level:     level,
+			Text:      strings.Join(texts, " "),
+			Location:  event.StackTrace.location(),
+			Timestamp: epochMillis(event.Timestamp),
+		}
+	case "Runtime.exceptionThrown":
+		var event struct {
+			Timestamp        float64 `json:"timestamp"`
+			ExceptionDetails struct {
+				Text         string             `json:"text"`
+				URL          string             `json:"url"`
+				LineNumber   int                `json:"lineNumber"`
+				ColumnNumber int                `json:"columnNumber"`
+				Exception    *consoleArg        `json:"exception"`
+				StackTrace   *consoleStackTrace `json:"stackTrace"`
+			} `json:"exceptionDetails"`
+		}
+		if err := json.Unmarshal(params, &event); err != nil {
+			return
+		}
+		details := event.ExceptionDetails
+		message = model.ConsoleMessage{
+			Level:     model.ConsoleLevelError,
+			Text:      details.Text,
+			Location:  details.StackTrace.location(),
+			Timestamp: epochMillis(event.Timestamp),
+		}
+		if details.Exception != nil && details.Exception.Description != "" {
+			message.Text = details.Exception.Description
+		}
+		if message.Location == nil && details.URL != "" {
+			message.Location = &model.ConsoleLocation{URL: details.URL, LineNumber: details.LineNumber, ColumnNumber: details.ColumnNumber}
+		}
+	default:
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.messages) >= r.size {
+		r.messages = append(r.messages[:0], r.messages[1:]...)
+	}
+	r.messages = append(r.messages, message)
+}
+
+// text renders a console argument the way the browser console prints it.
+func (a consoleArg) text() string {
+	if len(a.Value) > 0 {
+		var s string
+		if json.Unmarshal(a.Value, &s) == nil {
+			return s
+		}
+		return string(a.Value)
+	}
+	if a.Description != "" {
+		return a.Description
+	}
+	return a.Type
+}
+
+// location returns the top frame of a stack trace, if any.
+func (t *consoleStackTrace) location() *model.ConsoleLocation {
+	if t == nil || len(t.CallFrames) == 0 {
+		return nil
+	}
+	frame := t.CallFrames[0]
+	return &model.ConsoleLocation{URL: frame.URL, LineNumber: frame.LineNumber, ColumnNumber: frame.ColumnNumber}
+}
+
+// epochMillis converts a DevTools timestamp in milliseconds since the epoch.
+func epochMillis(ms float64) time.Time {
+	if ms == 0 {
+		return time.Now().UTC()
+	}
+	sec, frac := math.Modf(ms / 1e3)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+// messagesAt returns the recorded messages whose level is in levels, oldest
+// first. An empty levels matches every message.
+func (r *consoleRecorder) messagesAt(levels map[string]bool) []model.ConsoleMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := make([]model.ConsoleMessage, 0, len(r.messages))
+	for _, message := range r.messages {
+		if len(levels) > 0 && !levels[message.Level] {
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// EnableConsoleLog starts recording the console messages of the box's
+// current page. Enabling an already enabled log keeps the messages recorded
+// so far.
+func (s *BrowserService) EnableConsoleLog(ctx context.Context, boxID string, params model.ConsoleLogParams) error {
+	if params.BufferSize < 0 {
+		return fmt.Errorf("%w: bufferSize must not be negative", ErrInvalidParams)
+	}
+	size := params.BufferSize
+	if size == 0 {
+		size = defaultConsoleLogSize
+	}
+
+	s.consoleMu.Lock()
+	defer s.consoleMu.Unlock()
+
+	if existing, ok := s.consoleLogs[boxID]; ok {
+		select {
+		case <-existing.session.Done():
+			// The page went away, reconnect below
+		default:
+			return nil
+		}
+	}
+
+	page, err := s.openPage(ctx, boxID, "")
+	if err != nil {
+		return err
+	}
+	recorder := newConsoleRecorder(page, size)
+	if err := page.call(ctx, "Runtime.enable", nil, nil); err != nil {
+		page.Close()
+		return err
+	}
+	if s.consoleLogs == nil {
+		s.consoleLogs = make(map[string]*consoleRecorder)
+	}
+	s.consoleLogs[boxID] = recorder
+	return nil
+}
+
+// GetConsoleLog returns the console messages recorded for a box, optionally
+// limited to a comma separated list of levels.
+func (s *BrowserService) GetConsoleLog(boxID string, level string) (*model.ConsoleLogResult, error) {
+	levels := make(map[string]bool)
+	if level != "" {
+		for _, l := range strings.Split(level, ",") {
+			l = strings.TrimSpace(l)
+			switch l {
+			case model.ConsoleLevelDebug, model.ConsoleLevelInfo, model.ConsoleLevelLog, model.ConsoleLevelWarning, model.ConsoleLevelError:
+				levels[l] = true
+			default:
+				return nil, fmt.Errorf("%w: invalid level %q, must be one of debug, info, log, warning, error", ErrInvalidParams, l)
+			}
+		}
+	}
+
+	s.consoleMu.Lock()
+	recorder, ok := s.consoleLogs[boxID]
+	s.consoleMu.Unlock()
+	if !ok {
+		return nil, ErrConsoleLogDisabled
+	}
+
+	return &model.ConsoleLogResult{Messages: recorder.messagesAt(levels)}, nil
+}
+
+// DisableConsoleLog stops recording and discards the console messages
+// recorded for a box.
+func (s *BrowserService) DisableConsoleLog(boxID string) error {
+	s.consoleMu.Lock()
+	recorder, ok := s.consoleLogs[boxID]
+	delete(s.consoleLogs, boxID)
+	s.consoleMu.Unlock()
+	if !ok {
+		return ErrConsoleLogDisabled
+	}
+	return recorder.session.Close()
+}
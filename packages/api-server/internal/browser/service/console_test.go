@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+// consolePage answers evaluations as a page would, reporting the console
+// calls and exceptions the evaluated expressions make.
+func consolePage(browser **fakeBrowser) cdpHandler {
+	return func(params json.RawMessage) interface{} {
+		var p struct{ Expression string }
+		json.Unmarshal(params, &p)
+		b := *browser
+		stack := map[string]interface{}{"callFrames": []interface{}{
+			map[string]interface{}{"url": "http://test.local/app.js", "lineNumber": 12, "columnNumber": 4},
+		}}
+		if strings.Contains(p.Expression, `console.log("hello", 42)`) {
+			b.Emit("Runtime.consoleAPICalled", map[string]interface{}{
+				"type":       "log",
+				"timestamp":  1700000000500.0,
+				"args":       []interface{}{map[string]interface{}{"type": "string", "value": "hello"}, map[string]interface{}{"type": "number", "value": 42}},
+				"stackTrace": stack,
+			})
+		}
+		if strings.Contains(p.Expression, `console.warn("careful")`) {
+			b.Emit("Runtime.consoleAPICalled", map[string]interface{}{
+				"type": "warning",
+				"args": []interface{}{map[string]interface{}{"type": "string", "value": "careful"}},
+			})
+		}
+		if strings.Contains(p.Expression, "undefinedFn()") {
+			b.Emit("Runtime.exceptionThrown", map[string]interface{}{
+				"timestamp": 1700000001000.0,
+				"exceptionDetails": map[string]interface{}{
+					"text":       "Uncaught",
+					"exception":  map[string]interface{}{"type": "object", "description": "ReferenceError: undefinedFn is not defined"},
+					"stackTrace": stack,
+				},
+			})
+		}
+		return map[string]interface{}{"result": map[string]interface{}{"type": "string", "value": "null"}}
+	}
+}
+
+func waitForMessages(t *testing.T, svc *BrowserService, count int) []model.ConsoleMessage {
+	t.Helper()
+	var messages []model.ConsoleMessage
+	require.Eventually(t, func() bool {
+		result, err := svc.GetConsoleLog("box-1", "")
+		if err != nil {
+			return false
+		}
+		messages = result.Messages
+		return len(messages) == count
+	}, 2*time.Second, 10*time.Millisecond)
+	return messages
+}
+
+func TestConsoleLogCapturesEvaluatedLogs(t *testing.T) {
+	var browser *fakeBrowser
+	browser = newFakeBrowser(t, map[string]cdpHandler{
+		"Runtime.enable":   func(json.RawMessage) interface{} { return map[string]interface{}{} },
+		"Runtime.evaluate": consolePage(&browser),
+	})
+	svc := newTestBrowserService(browser)
+	ctx := context.Background()
+
+	_, err := svc.GetConsoleLog("box-1", "")
+	assert.ErrorIs(t, err, ErrConsoleLogDisabled)
+
+	require.NoError(t, svc.EnableConsoleLog(ctx, "box-1", model.ConsoleLogParams{}))
+	for _, expression := range []string{`console.log("hello", 42)`, `console.warn("careful")`, "undefinedFn()"} {
+		_, err := svc.Evaluate(ctx, "box-1", model.EvaluateParams{Expression: expression})
+		require.NoError(t, err)
+	}
+
+	messages := waitForMessages(t, svc, 3)
+	assert.Equal(t, model.ConsoleMessage{
+		Level:     model.ConsoleLevelLog,
+		Text:      "hello 42",
+		Location:  &model.ConsoleLocation{URL: "http://test.local/app.js", LineNumber: 12, ColumnNumber: 4},
+		Timestamp: time.Unix(1700000000, 5e8).UTC(),
+	}, messages[0])
+	assert.Equal(t, model.ConsoleLevelWarning, messages[1].Level)
+	assert.Nil(t, messages[1].Location)
+	assert.Equal(t, model.ConsoleLevelError, messages[2].Level)
+	assert.Equal(t, "ReferenceError: undefinedFn is not defined", messages[2].Text)
+
+	// Filtering by level
+	result, err := svc.GetConsoleLog("box-1", "warning, error")
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 2)
+	assert.Equal(t, "careful", result.Messages[0].Text)
+
+	_, err = svc.GetConsoleLog("box-1", "verbose")
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	require.NoError(t, svc.DisableConsoleLog("box-1"))
+	_, err = svc.GetConsoleLog("box-1", "")
+	assert.ErrorIs(t, err, ErrConsoleLogDisabled)
+}
+
+func TestConsoleLogKeepsMostRecentMessages(t *testing.T) {
+	var browser *fakeBrowser
+	browser = newFakeBrowser(t, map[string]cdpHandler{
+		"Runtime.enable":   func(json.RawMessage) interface{} { return map[string]interface{}{} },
+		"Runtime.evaluate": consolePage(&browser),
+	})
+	svc := newTestBrowserService(browser)
+	ctx := context.Background()
+
+	require.NoError(t, svc.EnableConsoleLog(ctx, "box-1", model.ConsoleLogParams{BufferSize: 1}))
+	_, err := svc.Evaluate(ctx, "box-1", model.EvaluateParams{Expression: `console.log("hello", 42)`})
+	require.NoError(t, err)
+	_, err = svc.Evaluate(ctx, "box-1", model.EvaluateParams{Expression: `console.warn("careful")`})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		result, err := svc.GetConsoleLog("box-1", "")
+		return err == nil && len(result.Messages) == 1 && result.Messages[0].Text == "careful"
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+// browserContext is an isolated browser context. Its DevTools sessions stay
+// open for the life of the context because emulation overrides only last as
+// long as the session that set them. Pages and the focused page are guarded
+// by the service's contextsMu.
+type browserContext struct {
+	id        string
+	boxID     string
+	browser   *cdpSession
+	emulation *model.CreateContextResult
+	// headers and credentials are applied to every page of the context
+	headers     map[string]string
+	credentials *model.HTTPCredentials
+	// pages holds a session per page created through the service
+	pages map[string]*cdpSession
+	// focusedPageID is the page actions target when no page is given
+	focusedPageID string
+}
+
+func (c *browserContext) close() {
+	for _, page := range c.pages {
+		page.Close()
+	}
+	c.browser.Close()
+}
+
+// newPage opens a page in the context, applies the context's emulation and
+// network options to it and then navigates it to url, so the options hold
+// from the first request.
+func (c *browserContext) newPage(ctx context.Context, cdpURL string, url string, background bool) (string, *cdpSession, error) {
+	params := map[string]interface{}{
+		"url":              "about:blank",
+		"browserContextId": c.id,
+	}
+	if background {
+		params["background"] = true
+	}
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := c.browser.call(ctx, "Target.createTarget", params, &target); err != nil {
+		return "", nil, err
+	}
+
+	page, err := dialPageTarget(ctx, cdpURL, target.TargetID)
+	if err == nil {
+		err = applyEmulation(ctx, page, c.emulation)
+		if err == nil {
+			err = c.applyNetworkOptions(ctx, page)
+		}
+		if err == nil && url != "about:blank" {
+			err = page.call(ctx, "Page.navigate", map[string]interface{}{"url": url}, nil)
+		}
+		if err != nil {
+			page.Close()
+		}
+	}
+	if err != nil {
+		c.browser.call(ctx, "Target.closeTarget", map[string]interface{}{"targetId": target.TargetID}, nil)
+		return "", nil, err
+	}
+	return target.TargetID, page, nil
+}
+
+// applyNetworkOptions sends the context's extra headers to a page session
+// and answers the page's authentication challenges with the context's
+// credentials for as long as the session is open. A challenge repeated for
+// the same request means the credentials were rejected and is cancelled.
+func (c *browserContext) applyNetworkOptions(ctx context.Context, page *cdpSession) error {
+	if len(c.headers) > 0 {
+		if err := page.call(ctx, "Network.enable", nil, nil); err != nil {
+			return err
+		}
+		if err := page.call(ctx, "Network.setExtraHTTPHeaders", map[string]interface{}{"headers": c.headers}, nil); err != nil {
+			return err
+		}
+	}
+	if c.credentials == nil {
+		return nil
+	}
+
+	credentials := *c.credentials
+	attempted := make(map[string]bool) // Only used on the reader goroutine
+	page.subscribe(func(method string, params json.RawMessage) {
+		var event struct {
+			RequestID string `json:"requestId"`
+		}
+		switch method {
+		case "Fetch.requestPaused":
+			if json.Unmarshal(params, &event) == nil {
+				go page.call(context.Background(), "Fetch.continueRequest", map[string]interface{}{"requestId": event.RequestID}, nil)
+			}
+		case "Fetch.authRequired":
+			if json.Unmarshal(params, &event) != nil {
+				return
+			}
+			response := map[string]interface{}{"response": "CancelAuth"}
+			if !attempted[event.RequestID] {
+				attempted[event.RequestID] = true
+				response = map[string]interface{}{
+					"response": "ProvideCredentials",
+					"username": credentials.Username,
+					"password": credentials.Password,
+				}
+			}
+			go page.call(context.Background(), "Fetch.continueWithAuth", map[string]interface{}{
+				"requestId":             event.RequestID,
+				"authChallengeResponse": response,
+			}, nil)
+		}
+	})
+	return page.call(ctx, "Fetch.enable", map[string]interface{}{"handleAuthRequests": true}, nil)
+}
+
+// targets returns the pages of the context, including the ones the pages
+// opened themselves.
+func (c *browserContext) targets(ctx context.Context) ([]model.PageInfo, error) {
+	var resp struct {
+		TargetInfos []struct {
+			TargetID         string `json:"targetId"`
+			Type             string `json:"type"`
+			Title            string `json:"title"`
+			URL              string `json:"url"`
+			BrowserContextID string `json:"browserContextId"`
+		} `json:"targetInfos"`
+	}
+	if err := c.browser.call(ctx, "Target.getTargets", map[string]interface{}{}, &resp); err != nil {
+		return nil, err
+	}
+	pages := []model.PageInfo{}
+	for _, info := range resp.TargetInfos {
+		if info.Type != "page" || info.BrowserContextID != c.id {
+			continue
+		}
+		pages = append(pages, model.PageInfo{PageID: info.TargetID, URL: info.URL, Title: info.Title})
+	}
+	return pages, nil
+}
+
+// CreateContext creates an isolated browser context in the box with one
+// page, applying the requested device emulation to it.
+func (s *BrowserService) CreateContext(ctx context.Context, boxID string, params model.CreateContextParams) (*model.CreateContextResult, error) {
+	browserType, err := resolveBrowserType(params.BrowserType, s.browserType)
+	if err != nil {
+		return nil, err
+	}
+	result, err := resolveEmulation(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNetworkOptions(params); err != nil {
+		return nil, err
+	}
+	result.BrowserType = browserType
+
+	cdpURL, err := s.resolveCdpURL(boxID)
+	if err != nil {
+		return nil, err
+	}
+	browser, product, err := dialBrowser(ctx, cdpURL)
+	if err != nil {
+		return nil, err
+	}
+	if engine := productEngine(product); engine != browserType {
+		browser.Close()
+		return nil, fmt.Errorf("%w: box %s runs %s (%s), not %s", ErrBrowserUnavailable, boxID, engine, product, browserType)
+	}
+
+	var created struct {
+		BrowserContextID string `json:"browserContextId"`
+	}
+	if err := browser.call(ctx, "Target.createBrowserContext", map[string]interface{}{}, &created); err != nil {
+		browser.Close()
+		return nil, err
+	}
+	bc := &browserContext{
+		id:          created.BrowserContextID,
+		boxID:       boxID,
+		browser:     browser,
+		emulation:   result,
+		headers:     params.ExtraHTTPHeaders,
+		credentials: params.HTTPCredentials,
+		pages:       make(map[string]*cdpSession),
+	}
+
+	pageID, page, err := bc.newPage(ctx, cdpURL, "about:blank", false)
+	if err != nil {
+		browser.call(ctx, "Target.disposeBrowserContext", map[string]interface{}{"browserContextId": bc.id}, nil)
+		browser.Close()
+		return nil, err
+	}
+	bc.pages[pageID] = page
+	bc.focusedPageID = pageID
+
+	s.contextsMu.Lock()
+	if s.contexts == nil {
+		s.contexts = make(map[string]*browserContext)
+	}
+	s.contexts[bc.id] = bc
+	s.contextsMu.Unlock()
+
+	result.ContextID = bc.id
+	result.PageID = pageID
+	return result, nil
+}
+
+// DeleteContext closes a browser context created with CreateContext along
+// with its pages.
+func (s *BrowserService) DeleteContext(ctx context.Context, boxID string, contextID string) error {
+	s.contextsMu.Lock()
+	bc, ok := s.contexts[contextID]
+	if ok && bc.boxID == boxID {
+		delete(s.contexts, contextID)
+		if s.focusedContexts[boxID] == contextID {
+			delete(s.focusedContexts, boxID)
+		}
+	}
+	s.contextsMu.Unlock()
+	if !ok || bc.boxID != boxID {
+		return ErrContextNotFound
+	}
+
+	defer bc.close()
+	return bc.browser.call(ctx, "Target.disposeBrowserContext", map[string]interface{}{"browserContextId": bc.id}, nil)
+}
+
+// CreatePage opens a new page in a browser context with the context's
+// emulation applied. The page opens in the background and does not take the
+// focus.
+func (s *BrowserService) CreatePage(ctx context.Context, boxID string, contextID string, params model.CreatePageParams) (*model.PageInfo, error) {
+	bc, err := s.lookupContext(boxID, contextID)
+	if err != nil {
+		return nil, err
+	}
+	cdpURL, err := s.resolveCdpURL(boxID)
+	if err != nil {
+		return nil, err
+	}
+	url := params.URL
+	if url == "" {
+		url = "about:blank"
+	}
+
+	pageID, page, err := bc.newPage(ctx, cdpURL, url, true)
+	if err != nil {
+		return nil, err
+	}
+	s.contextsMu.Lock()
+	bc.pages[pageID] = page
+	s.contextsMu.Unlock()
+	return &model.PageInfo{PageID: pageID, URL: url}, nil
+}
+
+// ListPages returns the pages of a browser context, marking the focused one.
+func (s *BrowserService) ListPages(ctx context.Context, boxID string, contextID string) (*model.ListPagesResult, error) {
+	bc, err := s.lookupContext(boxID, contextID)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := bc.targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.contextsMu.Lock()
+	for i := range pages {
+		pages[i].Focused = pages[i].PageID == bc.focusedPageID
+	}
+	s.contextsMu.Unlock()
+	return &model.ListPagesResult{Pages: pages}, nil
+}
+
+// FocusPage brings a page of a browser context to the front and makes it
+// the page that actions without a page ID target in the box.
+func (s *BrowserService) FocusPage(ctx context.Context, boxID string, contextID string, pageID string) error {
+	bc, err := s.lookupContext(boxID, contextID)
+	if err != nil {
+		return err
+	}
+	pages, err := bc.targets(ctx)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, page := range pages {
+		found = found || page.PageID == pageID
+	}
+	if !found {
+		return fmt.Errorf("%w: %s in context %s", ErrPageNotFound, pageID, contextID)
+	}
+	if err := bc.browser.call(ctx, "Target.activateTarget", map[string]interface{}{"targetId": pageID}, nil); err != nil {
+		return err
+	}
+
+	s.contextsMu.Lock()
+	defer s.contextsMu.Unlock()
+	bc.focusedPageID = pageID
+	if s.focusedContexts == nil {
+		s.focusedContexts = make(map[string]string)
+	}
+	s.focusedContexts[boxID] = bc.id
+	return nil
+}
+
+// lookupContext returns a context of the box created with CreateContext.
+func (s *BrowserService) lookupContext(boxID string, contextID string) (*browserContext, error) {
+	s.contextsMu.Lock()
+	defer s.contextsMu.Unlock()
+	bc, ok := s.contexts[contextID]
+	if !ok || bc.boxID != boxID {
+		return nil, ErrContextNotFound
+	}
+	return bc, nil
+}
+
+// focusedPage returns the page focused last in any context of the box, or
+// an empty string when no page was focused.
+func (s *BrowserService) focusedPage(boxID string) string {
+	s.contextsMu.Lock()
+	defer s.contextsMu.Unlock()
+	if bc, ok := s.contexts[s.focusedContexts[boxID]]; ok {
+		return bc.focusedPageID
+	}
+	return ""
+}
+
+// resolveBrowserType validates the requested browser engine, falling back to
+// the default one.
+func resolveBrowserType(requested, fallback string) (string, error) {
+	browserType := requested
+	if browserType == "" {
+		browserType = fallback
+	}
+	if browserType == "" {
+		browserType = model.BrowserTypeChromium
+	}
+	switch browserType {
+	case model.BrowserTypeChromium, model.BrowserTypeFirefox, model.BrowserTypeWebKit:
+		return browserType, nil
+	}
+	return "", fmt.Errorf("%w: unknown browser type %q, must be %s, %s or %s", ErrInvalidParams, browserType,
+		model.BrowserTypeChromium, model.BrowserTypeFirefox, model.BrowserTypeWebKit)
+}
+
+// productEngine returns the engine of a browser from the product name its
+// DevTools endpoint reports. Browsers that do not report one are assumed to
+// be the Chromium the box images ship.
+func productEngine(product string) string {
+	switch {
+	case strings.Contains(product, "Firefox"):
+		return model.BrowserTypeFirefox
+	case strings.Contains(product, "WebKit"), strings.Contains(product, "Safari"):
+		return model.BrowserTypeWebKit
+	}
+	return model.BrowserTypeChromium
+}
+
+// resolveEmulation merges the named device descriptor with the explicit
+// emulation options.
+func resolveEmulation(params model.CreateContextParams) (*model.CreateContextResult, error) {
+	result := &model.CreateContextResult{}
+	if params.Device != "" {
+		device, ok := deviceDescriptors[params.Device]
+		if !ok {
+			names := make([]string, 0, len(deviceDescriptors))
+			for name := range deviceDescriptors {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("%w: unknown device %q, supported devices: %s", ErrInvalidParams, params.Device, strings.Join(names, ", "))
+		}
+		viewport := device.viewport
+		result.Viewport = &viewport
+		result.DeviceScaleFactor = device.deviceScaleFactor
+		result.IsMobile = device.isMobile
+		result.HasTouch = device.hasTouch
+		result.UserAgent = device.userAgent
+	}
+
+	if params.Viewport != nil {
+		if params.Viewport.Width <= 0 || params.Viewport.Height <= 0 {
+			return nil, fmt.Errorf("%w: viewport width and height must be positive", ErrInvalidParams)
+		}
+		viewport := *params.Viewport
+		result.Viewport = &viewport
+	}
+	if params.DeviceScaleFactor < 0 {
+		return nil, fmt.Errorf("%w: deviceScaleFactor must not be negative", ErrInvalidParams)
+	}
+	if params.DeviceScaleFactor > 0 {
+		result.DeviceScaleFactor = params.DeviceScaleFactor
+	}
+	if params.IsMobile != nil {
+		result.IsMobile = *params.IsMobile
+	}
+	if params.HasTouch != nil {
+		result.HasTouch = *params.HasTouch
+	}
+	if params.UserAgent != "" {
+		result.UserAgent = params.UserAgent
+	}
+	return result, nil
+}
+
+// validateNetworkOptions checks the extra headers and credentials of a new
+// context.
+func validateNetworkOptions(params model.CreateContextParams) error {
+	for name := range params.ExtraHTTPHeaders {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			return fmt.Errorf("%w: invalid header name %q", ErrInvalidParams, name)
+		}
+	}
+	if params.HTTPCredentials != nil && params.HTTPCredentials.Username == "" {
+		return fmt.Errorf("%w: httpCredentials require a username", ErrInvalidParams)
+	}
+	return nil
+}
+
+// applyEmulation sends the DevTools emulation overrides for the resolved
+// settings to a page session.
+func applyEmulation(ctx context.Context, page *cdpSession, emulation *model.CreateContextResult) error {
+	if emulation.Viewport != nil || emulation.DeviceScaleFactor > 0 || emulation.IsMobile {
+		metrics := map[string]interface{}{
+			"width":             0,
+			"height":            0,
+			"deviceScaleFactor": emulation.DeviceScaleFactor,
+			"mobile":            emulation.IsMobile,
+		}
+		if emulation.Viewport != nil {
+			metrics["width"] = emulation.Viewport.Width
+			metrics["height"] = emulation.Viewport.Height
+		}
+		if err := page.call(ctx, "Emulation.setDeviceMetricsOverride", metrics, nil); err != nil {
+			return err
+		}
+	}
+	if emulation.UserAgent != "" {
+		if err := page.call(ctx, "Emulation.setUserAgentOverride", map[string]interface{}{"userAgent": emulation.UserAgent}, nil); err != nil {
+			return err
+		}
+	}
+	if emulation.HasTouch {
+		if err := page.call(ctx, "Emulation.setTouchEmulationEnabled", map[string]interface{}{"enabled": true, "maxTouchPoints": 5}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
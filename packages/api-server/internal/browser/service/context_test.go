@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+func newContextBrowser(t *testing.T) *fakeBrowser {
+	empty := func(json.RawMessage) interface{} { return map[string]interface{}{} }
+	return newFakeBrowser(t, map[string]cdpHandler{
+		"Target.createBrowserContext": func(json.RawMessage) interface{} {
+			return map[string]interface{}{"browserContextId": "ctx-1"}
+		},
+		"Target.createTarget": func(json.RawMessage) interface{} {
+			return map[string]interface{}{"targetId": "page-1"}
+		},
+		"Target.disposeBrowserContext":       empty,
+		"Emulation.setDeviceMetricsOverride": empty,
+		"Emulation.setUserAgentOverride":     empty,
+		"Emulation.setTouchEmulationEnabled": empty,
+		"Network.enable":                     empty,
+		"Network.setExtraHTTPHeaders":        empty,
+		"Fetch.enable":                       empty,
+		"Fetch.continueRequest":              empty,
+		"Fetch.continueWithAuth":             empty,
+	})
+}
+
+// callParams returns the parameters of the first call to method.
+func callParams(t *testing.T, browser *fakeBrowser, method string) map[string]interface{} {
+	t.Helper()
+	for _, call := range browser.Calls() {
+		if call.Method == method {
+			var params map[string]interface{}
+			require.NoError(t, json.Unmarshal(call.Params, &params))
+			return params
+		}
+	}
+	t.Fatalf("%s was not called", method)
+	return nil
+}
+
+func TestCreateContextEmulatesMobileDevice(t *testing.T) {
+	browser := newContextBrowser(t)
+	svc := newTestBrowserService(browser)
+
+	result, err := svc.CreateContext(context.Background(), "box-1", model.CreateContextParams{
+		Device:   "Pixel 5",
+		Viewport: &model.Viewport{Width: 400, Height: 800},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "ctx-1", result.ContextID)
+	assert.Equal(t, "page-1", result.PageID)
+	assert.Equal(t, &model.Viewport{Width: 400, Height: 800}, result.Viewport)
+	assert.Equal(t, 2.75, result.DeviceScaleFactor)
+	assert.True(t, result.IsMobile)
+	assert.True(t, result.HasTouch)
+
+	assert.Equal(t, map[string]interface{}{"url": "about:blank", "browserContextId": "ctx-1"},
+		callParams(t, browser, "Target.createTarget"))
+	assert.Equal(t, map[string]interface{}{"width": 400.0, "height": 800.0, "deviceScaleFactor": 2.75, "mobile": true},
+		callParams(t, browser, "Emulation.setDeviceMetricsOverride"))
+	assert.Equal(t, deviceDescriptors["Pixel 5"].userAgent,
+		callParams(t, browser, "Emulation.setUserAgentOverride")["userAgent"])
+	assert.Equal(t, true, callParams(t, browser, "Emulation.setTouchEmulationEnabled")["enabled"])
+
+	require.NoError(t, svc.DeleteContext(context.Background(), "box-1", "ctx-1"))
+	assert.Equal(t, "ctx-1", callParams(t, browser, "Target.disposeBrowserContext")["browserContextId"])
+	assert.ErrorIs(t, svc.DeleteContext(context.Background(), "box-1", "ctx-1"), ErrContextNotFound)
+}
+
+func TestCreateContextWithoutEmulation(t *testing.T) {
+	browser := newContextBrowser(t)
+	svc := newTestBrowserService(browser)
+
+	result, err := svc.CreateContext(context.Background(), "box-1", model.CreateContextParams{UserAgent: "gbox-test"})
+	require.NoError(t, err)
+	assert.Nil(t, result.Viewport)
+	assert.False(t, result.IsMobile)
+
+	for _, call := range browser.Calls() {
+		assert.NotEqual(t, "Emulation.setDeviceMetricsOverride", call.Method)
+		assert.NotEqual(t, "Emulation.setTouchEmulationEnabled", call.Method)
+	}
+	assert.Equal(t, "gbox-test", callParams(t, browser, "Emulation.setUserAgentOverride")["userAgent"])
+}
+
+func TestCreateContextRejectsInvalidEmulation(t *testing.T) {
+	svc := newTestBrowserService(newContextBrowser(t))
+
+	for _, params := range []model.CreateContextParams{
+		{Device: "Nokia 3310"},
+		{Viewport: &model.Viewport{Width: 0, Height: 600}},
+		{DeviceScaleFactor: -1},
+	} {
+		_, err := svc.CreateContext(context.Background(), "box-1", params)
+		assert.ErrorIs(t, err, ErrInvalidParams)
+	}
+}
+
+func TestCreateContextBrowserType(t *testing.T) {
+	browser := newContextBrowser(t)
+	browser.product = "HeadlessChrome/120.0.6099.71"
+	svc := newTestBrowserService(browser)
+	ctx := context.Background()
+
+	result, err := svc.CreateContext(ctx, "box-1", model.CreateContextParams{})
+	require.NoError(t, err)
+	assert.Equal(t, model.BrowserTypeChromium, result.BrowserType)
+
+	_, err = svc.CreateContext(ctx, "box-1", model.CreateContextParams{BrowserType: "opera"})
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	// The engine is not installed in the box
+	before := len(browser.Calls())
+	_, err = svc.CreateContext(ctx, "box-1", model.CreateContextParams{BrowserType: model.BrowserTypeFirefox})
+	assert.ErrorIs(t, err, ErrBrowserUnavailable)
+	assert.Len(t, browser.Calls(), before, "no context may be created for an unavailable engine")
+}
+
+func TestCreateContextNonDefaultBrowserType(t *testing.T) {
+	browser := newContextBrowser(t)
+	browser.product = "Firefox/128.0"
+	svc := newTestBrowserService(browser)
+	svc.browserType = model.BrowserTypeFirefox
+
+	result, err := svc.CreateContext(context.Background(), "box-1", model.CreateContextParams{})
+	require.NoError(t, err)
+	assert.Equal(t, model.BrowserTypeFirefox, result.BrowserType)
+	callParams(t, browser, "Target.createBrowserContext")
+
+	_, err = svc.CreateContext(context.Background(), "box-1", model.CreateContextParams{BrowserType: model.BrowserTypeChromium})
+	assert.ErrorIs(t, err, ErrBrowserUnavailable)
+}
+
+func TestFocusPageTargetsDefaultActions(t *testing.T) {
+	var browser *fakeBrowser
+	empty := func(json.RawMessage) interface{} { return map[string]interface{}{} }
+	browser = newFakeBrowser(t, map[string]cdpHandler{
+		"Target.createBrowserContext": func(json.RawMessage) interface{} {
+			return map[string]interface{}{"browserContextId": "ctx-1"}
+		},
+		"Target.createTarget": func(json.RawMessage) interface{} {
+			id := fmt.Sprintf("tab-%d", len(browser.Pages()))
+			browser.AddPage(id)
+			return map[string]interface{}{"targetId": id}
+		},
+		"Target.getTargets": func(json.RawMessage) interface{} {
+			infos := []map[string]interface{}{}
+			for _, id := range browser.Pages() {
+				contextID := ""
+				if strings.HasPrefix(id, "tab-") {
+					contextID = "ctx-1"
+				}
+				infos = append(infos, map[string]interface{}{
+					"targetId": id, "type": "page", "url": "about:blank", "browserContextId": contextID,
+				})
+			}
+			return map[string]interface{}{"targetInfos": infos}
+		},
+		"Target.activateTarget": empty,
+		"Page.navigate":         empty,
+		"Runtime.evaluate": evaluateReturning(map[string]interface{}{
+			"boxes": []model.BoundingBox{{X: 0, Y: 0, Width: 10, Height: 10}},
+		}),
+	})
+	svc := newTestBrowserService(browser)
+	ctx := context.Background()
+
+	created, err := svc.CreateContext(ctx, "box-1", model.CreateContextParams{})
+	require.NoError(t, err)
+	assert.Equal(t, "tab-1", created.PageID)
+	page, err := svc.CreatePage(ctx, "box-1", "ctx-1", model.CreatePageParams{URL: "https://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "tab-2", page.PageID)
+	assert.Equal(t, "https://example.com", callParams(t, browser, "Page.navigate")["url"], "the page navigates once its options are applied")
+
+	pages, err := svc.ListPages(ctx, "box-1", "ctx-1")
+	require.NoError(t, err)
+	assert.Equal(t, []model.PageInfo{
+		{PageID: "tab-1", URL: "about:blank", Focused: true},
+		{PageID: "tab-2", URL: "about:blank"},
+	}, pages.Pages, "a new page must not take the focus")
+
+	require.NoError(t, svc.FocusPage(ctx, "box-1", "ctx-1", "tab-2"))
+	assert.Equal(t, "tab-2", callParams(t, browser, "Target.activateTarget")["targetId"])
+	pages, err = svc.ListPages(ctx, "box-1", "ctx-1")
+	require.NoError(t, err)
+	assert.False(t, pages.Pages[0].Focused)
+	assert.True(t, pages.Pages[1].Focused)
+
+	// Actions without a page ID target the focused page, others the given one
+	evaluatedOn := func() string {
+		calls := browser.Calls()
+		for i := len(calls) - 1; i >= 0; i-- {
+			if calls[i].Method == "Runtime.evaluate" {
+				return calls[i].Target
+			}
+		}
+		return ""
+	}
+	_, err = svc.FindElement(ctx, "box-1", model.FindElementParams{Selector: "button"})
+	require.NoError(t, err)
+	assert.Equal(t, "tab-2", evaluatedOn())
+	_, err = svc.FindElement(ctx, "box-1", model.FindElementParams{Selector: "button", PageID: "tab-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "tab-1", evaluatedOn())
+
+	_, err = svc.FindElement(ctx, "box-1", model.FindElementParams{Selector: "button", PageID: "tab-9"})
+	assert.ErrorIs(t, err, ErrPageNotFound)
+	assert.ErrorIs(t, svc.FocusPage(ctx, "box-1", "ctx-1", "page-1"), ErrPageNotFound, "pages of other contexts cannot be focused")
+	assert.ErrorIs(t, svc.FocusPage(ctx, "box-1", "ctx-2", "tab-1"), ErrContextNotFound)
+}
+
+func TestCreateContextHeadersAndCredentials(t *testing.T) {
+	browser := newContextBrowser(t)
+	svc := newTestBrowserService(browser)
+
+	_, err := svc.CreateContext(context.Background(), "box-1", model.CreateContextParams{
+		ExtraHTTPHeaders: map[string]string{"X-Gbox-Test": "1"},
+		HTTPCredentials:  &model.HTTPCredentials{Username: "admin", Password: "secret"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{"X-Gbox-Test": "1"}, callParams(t, browser, "Network.setExtraHTTPHeaders")["headers"])
+	assert.Equal(t, true, callParams(t, browser, "Fetch.enable")["handleAuthRequests"])
+
+	// The first challenge of a request is answered, a repeated one cancelled
+	authResponses := func() []interface{} {
+		var responses []interface{}
+		for _, call := range browser.Calls() {
+			if call.Method == "Fetch.continueWithAuth" {
+				var params map[string]interface{}
+				require.NoError(t, json.Unmarshal(call.Params, &params))
+				responses = append(responses, params["authChallengeResponse"])
+			}
+		}
+		return responses
+	}
+	browser.Emit("Fetch.authRequired", map[string]interface{}{"requestId": "interception-1"})
+	require.Eventually(t, func() bool { return len(authResponses()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]interface{}{"response": "ProvideCredentials", "username": "admin", "password": "secret"}, authResponses()[0])
+
+	browser.Emit("Fetch.authRequired", map[string]interface{}{"requestId": "interception-1"})
+	require.Eventually(t, func() bool { return len(authResponses()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]interface{}{"response": "CancelAuth"}, authResponses()[1])
+
+	browser.Emit("Fetch.requestPaused", map[string]interface{}{"requestId": "interception-2"})
+	require.Eventually(t, func() bool {
+		for _, call := range browser.Calls() {
+			if call.Method == "Fetch.continueRequest" {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+
+	for _, params := range []model.CreateContextParams{
+		{ExtraHTTPHeaders: map[string]string{"Bad Header": "1"}},
+		{HTTPCredentials: &model.HTTPCredentials{Password: "secret"}},
+	} {
+		_, err := svc.CreateContext(context.Background(), "box-1", params)
+		assert.ErrorIs(t, err, ErrInvalidParams)
+	}
+}
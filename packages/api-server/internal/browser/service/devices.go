@@ -0,0 +1,48 @@
+package service
+
+import model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+
+// deviceDescriptor holds the emulation settings of a named device, matching
+// Playwright's device registry.
+type deviceDescriptor struct {
+	viewport          model.Viewport
+	deviceScaleFactor float64
+	isMobile          bool
+	hasTouch          bool
+	userAgent         string
+}
+
+var deviceDescriptors = map[string]deviceDescriptor{
+	"iPhone 13": {
+		viewport:          model.Viewport{Width: 390, Height: 664},
+		deviceScaleFactor: 3,
+		isMobile:          true,
+		hasTouch:          true,
+		userAgent:         "Mozilla/5.0 (iPhone; CPU iPhone OS 15_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.0 Mobile/15E148 Safari/604.1",
+	},
+	"iPad Mini": {
+		viewport:          model.Viewport{Width: 768, Height: 1024},
+		deviceScaleFactor: 2,
+		isMobile:          true,
+		hasTouch:          true,
+		userAgent:         "Mozilla/5.0 (iPad; CPU OS 12_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1 Mobile/15E148 Safari/604.1",
+	},
+	"Pixel 5": {
+		viewport:          model.Viewport{Width: 393, Height: 727},
+		deviceScaleFactor: 2.75,
+		isMobile:          true,
+		hasTouch:          true,
+		userAgent:         "Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.0 Mobile Safari/537.36",
+	},
+	"Galaxy S9+": {
+		viewport:          model.Viewport{Width: 320, Height: 658},
+		deviceScaleFactor: 4.5,
+		isMobile:          true,
+		hasTouch:          true,
+		userAgent:         "Mozilla/5.0 (Linux; Android 8.0.0; SM-G965U Build/R16NW) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.0 Mobile Safari/537.36",
+	},
+	"Desktop Chrome": {
+		viewport:          model.Viewport{Width: 1280, Height: 720},
+		deviceScaleFactor: 1,
+	},
+}
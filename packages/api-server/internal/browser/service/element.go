@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+// findElementScript returns the bounding boxes of the visible elements
+// matching a selector, or the innermost visible elements containing a text.
+const findElementScript = `((selector, text) => {
+	const visible = (el) => {
+		const r = el.getBoundingClientRect();
+		return r.width > 0 && r.height > 0;
+	};
+	let elements;
+	if (selector) {
+		try {
+			elements = Array.from(document.querySelectorAll(selector));
+		} catch (e) {
+			return { error: e.message };
+		}
+	} else {
+		const contains = (el) => (el.innerText || "").includes(text);
+		elements = Array.from(document.body.querySelectorAll("*"))
+			.filter((el) => contains(el) && !Array.from(el.children).some(contains));
+	}
+	return {
+		boxes: elements.filter(visible).map((el) => {
+			const r = el.getBoundingClientRect();
+			return { x: r.x, y: r.y, width: r.width, height: r.height };
+		}),
+	};
+})(%s, %s)`
+
+// FindElement locates an element by selector or visible text on the box's
+// current page and returns its center and bounding box, suitable for a
+// subsequent coordinate based click.
+func (s *BrowserService) FindElement(ctx context.Context, boxID string, params model.FindElementParams) (*model.FindElementResult, error) {
+	if (params.Selector == "") == (params.Text == "") {
+		return nil, fmt.Errorf("%w: exactly one of selector or text is required", ErrInvalidParams)
+	}
+
+	page, err := s.openPage(ctx, boxID, params.PageID)
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+
+	selector, _ := json.Marshal(params.Selector)
+	text, _ := json.Marshal(params.Text)
+	var found struct {
+		Boxes []model.BoundingBox `json:"boxes"`
+		Error string              `json:"error"`
+	}
+	if err := page.evaluate(ctx, fmt.Sprintf(findElementScript, selector, text), &found); err != nil {
+		return nil, err
+	}
+	if found.Error != "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidParams, found.Error)
+	}
+
+	switch {
+	case len(found.Boxes) == 0:
+		return nil, ErrElementNotFound
+	case len(found.Boxes) > 1 && params.Strict:
+		return nil, fmt.Errorf("%w: %d elements", ErrMultipleElements, len(found.Boxes))
+	}
+
+	box := found.Boxes[0]
+	return &model.FindElementResult{
+		Center: model.Point{
+			X: box.X + box.Width/2,
+			Y: box.Y + box.Height/2,
+		},
+		BoundingBox: box,
+		Count:       len(found.Boxes),
+	}, nil
+}
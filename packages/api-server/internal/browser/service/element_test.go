@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+func TestFindElementBySelector(t *testing.T) {
+	browser := newFakeBrowser(t, map[string]cdpHandler{
+		"Runtime.evaluate": evaluateReturning(map[string]interface{}{
+			"boxes": []model.BoundingBox{{X: 100, Y: 40, Width: 80, Height: 30}},
+		}),
+	})
+	svc := newTestBrowserService(browser)
+
+	result, err := svc.FindElement(context.Background(), "box-1", model.FindElementParams{Selector: "button#submit", Strict: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, model.BoundingBox{X: 100, Y: 40, Width: 80, Height: 30}, result.BoundingBox)
+	assert.Equal(t, model.Point{X: 140, Y: 55}, result.Center)
+	assert.Equal(t, 1, result.Count)
+
+	calls := browser.Calls()
+	require.Len(t, calls, 1)
+	var params struct{ Expression string }
+	require.NoError(t, json.Unmarshal(calls[0].Params, &params))
+	assert.Contains(t, params.Expression, `("button#submit", "")`)
+}
+
+func TestFindElementMatches(t *testing.T) {
+	twoButtons := map[string]interface{}{"boxes": []model.BoundingBox{
+		{X: 0, Y: 0, Width: 10, Height: 10},
+		{X: 20, Y: 0, Width: 10, Height: 10},
+	}}
+
+	tests := []struct {
+		name    string
+		value   interface{}
+		params  model.FindElementParams
+		wantErr error
+		center  model.Point
+	}{
+		{name: "no match", value: map[string]interface{}{"boxes": []model.BoundingBox{}}, params: model.FindElementParams{Text: "Sign in"}, wantErr: ErrElementNotFound},
+		{name: "strict with several matches", value: twoButtons, params: model.FindElementParams{Selector: "button", Strict: true}, wantErr: ErrMultipleElements},
+		{name: "first of several matches", value: twoButtons, params: model.FindElementParams{Selector: "button"}, center: model.Point{X: 5, Y: 5}},
+		{name: "invalid selector", value: map[string]interface{}{"error": "'##' is not a valid selector"}, params: model.FindElementParams{Selector: "##"}, wantErr: ErrInvalidParams},
+		{name: "selector and text", params: model.FindElementParams{Selector: "button", Text: "OK"}, wantErr: ErrInvalidParams},
+		{name: "neither selector nor text", wantErr: ErrInvalidParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			browser := newFakeBrowser(t, map[string]cdpHandler{"Runtime.evaluate": evaluateReturning(tt.value)})
+			svc := newTestBrowserService(browser)
+
+			result, err := svc.FindElement(context.Background(), "box-1", tt.params)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.center, result.Center)
+		})
+	}
+}
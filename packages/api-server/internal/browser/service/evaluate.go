@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+const (
+	defaultEvaluateTimeout = 30 * time.Second
+	// maxEvaluateResultBytes caps the serialized size of an evaluation result
+	maxEvaluateResultBytes = 1 << 20
+)
+
+// evaluateScript serializes the awaited value of an expression in the page
+// so values without a JSON form are reported instead of silently dropped.
+const evaluateScript = `(async () => {
+	const value = await (%s);
+	if (value === undefined) {
+		return "null";
+	}
+	const json = JSON.stringify(value);
+	if (json === undefined) {
+		throw new Error("result of type " + typeof value + " is not JSON-serializable");
+	}
+	return json;
+})()`
+
+// Evaluate runs a JavaScript expression in the box's current page and
+// returns its JSON serialized result.
+func (s *BrowserService) Evaluate(ctx context.Context, boxID string, params model.EvaluateParams) (*model.EvaluateResult, error) {
+	if params.Expression == "" {
+		return nil, fmt.Errorf("%w: expression is required", ErrInvalidParams)
+	}
+	timeout := defaultEvaluateTimeout
+	if params.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(params.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("%w: invalid timeout %q", ErrInvalidParams, params.Timeout)
+		}
+	}
+
+	var waitState string
+	var waitTimeout time.Duration
+	if params.WaitUntil != "" {
+		var err error
+		waitState, waitTimeout, err = parseNavigationWait(params.WaitUntil, params.WaitTimeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	evalCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	page, err := s.openPage(evalCtx, boxID, params.PageID)
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+
+	// Watch before evaluating, the navigation may start right away
+	var watcher *navigationWatcher
+	if waitState != "" {
+		if watcher, err = watchNavigation(evalCtx, page); err != nil {
+			return nil, err
+		}
+	}
+
+	var serialized string
+	if err := page.evaluate(evalCtx, fmt.Sprintf(evaluateScript, params.Expression), &serialized); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: evaluation exceeded %s", ErrTimeout, timeout)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrEvaluationFailed, err)
+	}
+	if len(serialized) > maxEvaluateResultBytes {
+		return nil, fmt.Errorf("%w: result is %d bytes, limit is %d", ErrResultTooLarge, len(serialized), maxEvaluateResultBytes)
+	}
+	if !json.Valid([]byte(serialized)) {
+		return nil, fmt.Errorf("%w: result is not valid JSON", ErrEvaluationFailed)
+	}
+
+	result := &model.EvaluateResult{Result: json.RawMessage(serialized)}
+	if watcher != nil {
+		navCtx, cancel := context.WithTimeout(ctx, waitTimeout)
+		defer cancel()
+		result.URL, err = watcher.wait(navCtx, waitState, true)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: no navigation reached %q within %s", ErrTimeout, waitState, waitTimeout)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
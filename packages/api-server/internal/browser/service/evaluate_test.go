@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+// fakePage answers evaluations of the wrapped expressions used by the tests
+// as a page containing <h1>Hello</h1> would.
+func fakePage(params json.RawMessage) interface{} {
+	var p struct{ Expression string }
+	json.Unmarshal(params, &p)
+
+	value := func(v string) interface{} {
+		return map[string]interface{}{"result": map[string]interface{}{"type": "string", "value": v}}
+	}
+	switch {
+	case strings.Contains(p.Expression, "(1+1)"):
+		return value("2")
+	case strings.Contains(p.Expression, `(document.querySelector("h1").textContent)`):
+		return value(`"Hello"`)
+	case strings.Contains(p.Expression, "(window)"):
+		return map[string]interface{}{
+			"result":           map[string]interface{}{"type": "object"},
+			"exceptionDetails": map[string]interface{}{"text": "Uncaught", "exception": map[string]interface{}{"description": "TypeError: Converting circular structure to JSON"}},
+		}
+	case strings.Contains(p.Expression, "(bigString)"):
+		return value(`"` + strings.Repeat("a", maxEvaluateResultBytes) + `"`)
+	case strings.Contains(p.Expression, "(new Promise(() => {}))"):
+		time.Sleep(200 * time.Millisecond)
+		return value("null")
+	}
+	return value("null")
+}
+
+func TestEvaluate(t *testing.T) {
+	browser := newFakeBrowser(t, map[string]cdpHandler{"Runtime.evaluate": fakePage})
+	svc := newTestBrowserService(browser)
+	ctx := context.Background()
+
+	result, err := svc.Evaluate(ctx, "box-1", model.EvaluateParams{Expression: "1+1"})
+	require.NoError(t, err)
+	assert.JSONEq(t, "2", string(result.Result))
+
+	result, err = svc.Evaluate(ctx, "box-1", model.EvaluateParams{Expression: `document.querySelector("h1").textContent`})
+	require.NoError(t, err)
+	assert.JSONEq(t, `"Hello"`, string(result.Result))
+
+	calls := browser.Calls()
+	require.Len(t, calls, 2)
+	var params struct {
+		ReturnByValue bool
+		AwaitPromise  bool
+	}
+	require.NoError(t, json.Unmarshal(calls[0].Params, &params))
+	assert.True(t, params.ReturnByValue)
+	assert.True(t, params.AwaitPromise)
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	browser := newFakeBrowser(t, map[string]cdpHandler{"Runtime.evaluate": fakePage})
+	svc := newTestBrowserService(browser)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		params  model.EvaluateParams
+		wantErr error
+	}{
+		{name: "empty expression", params: model.EvaluateParams{}, wantErr: ErrInvalidParams},
+		{name: "invalid timeout", params: model.EvaluateParams{Expression: "1+1", Timeout: "soon"}, wantErr: ErrInvalidParams},
+		{name: "non-serializable result", params: model.EvaluateParams{Expression: "window"}, wantErr: ErrEvaluationFailed},
+		{name: "result over the size cap", params: model.EvaluateParams{Expression: "bigString"}, wantErr: ErrResultTooLarge},
+		{name: "timeout", params: model.EvaluateParams{Expression: "new Promise(() => {})", Timeout: "50ms"}, wantErr: ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Evaluate(ctx, "box-1", tt.params)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
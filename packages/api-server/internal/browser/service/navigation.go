@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+const defaultNavigationTimeout = 30 * time.Second
+
+// loadStateEvents maps the load states to the page event that signals them.
+var loadStateEvents = map[string]string{
+	model.LoadStateDOMContentLoaded: "Page.domContentEventFired",
+	model.LoadStateLoad:             "Page.loadEventFired",
+}
+
+// navigationWatcher follows the main frame navigations and load events of a
+// page session.
+type navigationWatcher struct {
+	mu        sync.Mutex
+	navigated bool
+	url       string
+	reached   map[string]bool // Load states reached since the last navigation
+	changed   chan struct{}
+}
+
+// watchNavigation enables page events on session and starts following them.
+func watchNavigation(ctx context.Context, session *cdpSession) (*navigationWatcher, error) {
+	w := &navigationWatcher{
+		reached: make(map[string]bool),
+		changed: make(chan struct{}, 1),
+	}
+	session.subscribe(w.handleEvent)
+	if err := session.call(ctx, "Page.enable", nil, nil); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *navigationWatcher) handleEvent(method string, params json.RawMessage) {
+	w.mu.Lock()
+	switch method {
+	case "Page.frameNavigated":
+		var event struct {
+			Frame *struct {
+				ParentID string `json:"parentId"`
+				URL      string `json:"url"`
+			} `json:"frame"`
+		}
+		if json.Unmarshal(params, &event) != nil || event.Frame == nil || event.Frame.ParentID != "" {
+			w.mu.Unlock()
+			return
+		}
+		w.navigated = true
+		w.url = event.Frame.URL
+		w.reached = make(map[string]bool)
+	case "Page.navigatedWithinDocument":
+		// Same document navigations (e.g. to an anchor) load nothing
+		var event struct {
+			URL string `json:"url"`
+		}
+		json.Unmarshal(params, &event)
+		w.navigated = true
+		w.url = event.URL
+		for state := range loadStateEvents {
+			w.reached[state] = true
+		}
+	default:
+		matched := false
+		for state, eventMethod := range loadStateEvents {
+			if method == eventMethod {
+				w.reached[state] = true
+				matched = true
+			}
+		}
+		if !matched {
+			w.mu.Unlock()
+			return
+		}
+	}
+	w.mu.Unlock()
+
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until the page reached state, after a navigation when
+// navigation is set, and returns the URL it navigated to.
+func (w *navigationWatcher) wait(ctx context.Context, state string, navigation bool) (string, error) {
+	for {
+		w.mu.Lock()
+		done := (w.navigated || !navigation) && w.reached[state]
+		url := w.url
+		w.mu.Unlock()
+		if done {
+			return url, nil
+		}
+
+		select {
+		case <-w.changed:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// parseNavigationWait validates a load state and timeout, applying defaults.
+func parseNavigationWait(state, timeout string) (string, time.Duration, error) {
+	if state == "" {
+		state = model.LoadStateLoad
+	}
+	if _, ok := loadStateEvents[state]; !ok {
+		return "", 0, fmt.Errorf("%w: invalid load state %q, must be %q or %q", ErrInvalidParams, state, model.LoadStateLoad, model.LoadStateDOMContentLoaded)
+	}
+	d := defaultNavigationTimeout
+	if timeout != "" {
+		var err error
+		d, err = time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return "", 0, fmt.Errorf("%w: invalid timeout %q", ErrInvalidParams, timeout)
+		}
+	}
+	return state, d, nil
+}
+
+// WaitForNavigation waits for the next navigation of the box's current page
+// to reach a load state and returns the URL navigated to.
+func (s *BrowserService) WaitForNavigation(ctx context.Context, boxID string, params model.WaitForNavigationParams) (*model.NavigationResult, error) {
+	state, timeout, err := parseNavigationWait(params.WaitUntil, params.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	page, err := s.openPage(ctx, boxID, params.PageID)
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+
+	watcher, err := watchNavigation(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	url, err := watcher.wait(ctx, state, true)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w: no navigation reached %q within %s", ErrTimeout, state, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &model.NavigationResult{URL: url}, nil
+}
+
+// WaitForLoadState waits until the box's current page has reached a load
+// state, returning at once when it already has.
+func (s *BrowserService) WaitForLoadState(ctx context.Context, boxID string, params model.WaitForLoadStateParams) (*model.NavigationResult, error) {
+	state, timeout, err := parseNavigationWait(params.State, params.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	page, err := s.openPage(ctx, boxID, params.PageID)
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+
+	// Follow events before reading the current state so none is missed
+	watcher, err := watchNavigation(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	var current struct {
+		ReadyState string `json:"readyState"`
+		URL        string `json:"url"`
+	}
+	if err := page.evaluate(ctx, "({readyState: document.readyState, url: location.href})", &current); err != nil {
+		return nil, err
+	}
+	switch {
+	case current.ReadyState == "complete",
+		current.ReadyState == "interactive" && state == model.LoadStateDOMContentLoaded:
+		return &model.NavigationResult{URL: current.URL}, nil
+	}
+
+	url, err := watcher.wait(ctx, state, false)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w: page did not reach %q within %s", ErrTimeout, state, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if url == "" {
+		url = current.URL
+	}
+	return &model.NavigationResult{URL: url}, nil
+}
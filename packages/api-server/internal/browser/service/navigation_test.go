@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+// linkPage answers evaluations as a page whose link leads to next.html,
+// emitting the navigation shortly after the link is clicked.
+func linkPage(browser **fakeBrowser) cdpHandler {
+	return func(params json.RawMessage) interface{} {
+		var p struct{ Expression string }
+		json.Unmarshal(params, &p)
+		value := func(v interface{}) interface{} {
+			return map[string]interface{}{"result": map[string]interface{}{"type": "object", "value": v}}
+		}
+		switch {
+		case strings.Contains(p.Expression, ".click()"):
+			b := *browser
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				b.Emit("Page.frameNavigated", map[string]interface{}{"frame": map[string]interface{}{"id": "child", "parentId": "main", "url": "http://test.local/ad.html"}})
+				b.Emit("Page.frameNavigated", map[string]interface{}{"frame": map[string]interface{}{"id": "main", "url": "http://test.local/next.html"}})
+				b.Emit("Page.domContentEventFired", map[string]interface{}{"timestamp": 1.0})
+				time.Sleep(20 * time.Millisecond)
+				b.Emit("Page.loadEventFired", map[string]interface{}{"timestamp": 1.1})
+			}()
+			return value("null")
+		case strings.Contains(p.Expression, "document.readyState"):
+			return value(map[string]string{"readyState": "complete", "url": "http://test.local/index.html"})
+		}
+		return value("null")
+	}
+}
+
+func TestEvaluateWaitsForNavigation(t *testing.T) {
+	var browser *fakeBrowser
+	browser = newFakeBrowser(t, map[string]cdpHandler{
+		"Page.enable":      func(json.RawMessage) interface{} { return map[string]interface{}{} },
+		"Runtime.evaluate": linkPage(&browser),
+	})
+	svc := newTestBrowserService(browser)
+
+	result, err := svc.Evaluate(context.Background(), "box-1", model.EvaluateParams{
+		Expression: `document.querySelector("a").click()`,
+		WaitUntil:  model.LoadStateLoad,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http://test.local/next.html", result.URL)
+
+	calls := browser.Calls()
+	require.GreaterOrEqual(t, len(calls), 2)
+	assert.Equal(t, "Page.enable", calls[0].Method, "page events must be enabled before the action")
+}
+
+func TestEvaluateWaitForNavigationTimesOut(t *testing.T) {
+	var browser *fakeBrowser
+	browser = newFakeBrowser(t, map[string]cdpHandler{
+		"Page.enable":      func(json.RawMessage) interface{} { return map[string]interface{}{} },
+		"Runtime.evaluate": linkPage(&browser),
+	})
+	svc := newTestBrowserService(browser)
+
+	_, err := svc.Evaluate(context.Background(), "box-1", model.EvaluateParams{
+		Expression:  "1+1",
+		WaitUntil:   model.LoadStateLoad,
+		WaitTimeout: "50ms",
+	})
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	_, err = svc.Evaluate(context.Background(), "box-1", model.EvaluateParams{Expression: "1+1", WaitUntil: "networkidle"})
+	assert.ErrorIs(t, err, ErrInvalidParams)
+}
+
+func TestWaitForLoadState(t *testing.T) {
+	var browser *fakeBrowser
+	browser = newFakeBrowser(t, map[string]cdpHandler{
+		"Page.enable":      func(json.RawMessage) interface{} { return map[string]interface{}{} },
+		"Runtime.evaluate": linkPage(&browser),
+	})
+	svc := newTestBrowserService(browser)
+
+	// The page has already loaded, so there is nothing to wait for
+	result, err := svc.WaitForLoadState(context.Background(), "box-1", model.WaitForLoadStateParams{Timeout: "1s"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://test.local/index.html", result.URL)
+
+	_, err = svc.WaitForNavigation(context.Background(), "box-1", model.WaitForNavigationParams{Timeout: "50ms"})
+	assert.ErrorIs(t, err, ErrTimeout)
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sync"
+	"time"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+// defaultNetworkLogSize is the number of requests kept when no buffer size
+// is requested.
+const defaultNetworkLogSize = 500
+
+// networkEntry is a recorded request along with the monotonic timestamp of
+// its start, used to compute durations.
+type networkEntry struct {
+	request model.NetworkRequest
+	started float64
+}
+
+// networkRecorder keeps the most recent requests of a page in a ring buffer.
+type networkRecorder struct {
+	session *cdpSession
+
+	mu      sync.Mutex
+	size    int
+	entries []*networkEntry
+	byID    map[string]*networkEntry
+}
+
+func newNetworkRecorder(session *cdpSession, size int) *networkRecorder {
+	r := &networkRecorder{
+		session: session,
+		size:    size,
+		byID:    make(map[string]*networkEntry),
+	}
+	session.subscribe(r.handleEvent)
+	return r
+}
+
+type networkEvent struct {
+	RequestID string  `json:"requestId"`
+	Timestamp float64 `json:"timestamp"`
+	WallTime  float64 `json:"wallTime"`
+	Type      string  `json:"type"`
+	ErrorText string  `json:"errorText"`
+	Request   struct {
+		URL    string `json:"url"`
+		Method string `json:"method"`
+	} `json:"request"`
+	Response *struct {
+		Status int `json:"status"`
+	} `json:"response"`
+	RedirectResponse *struct {
+		Status int `json:"status"`
+	} `json:"redirectResponse"`
+}
+
+func (r *networkRecorder) handleEvent(method string, params json.RawMessage) {
+	switch method {
+	case "Network.requestWillBeSent", "Network.responseReceived", "Network.loadingFinished", "Network.loadingFailed":
+	default:
+		return
+	}
+	var event networkEvent
+	if err := json.Unmarshal(params, &event); err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := r.byID[event.RequestID]
+	switch method {
+	case "Network.requestWillBeSent":
+		// A redirect reuses the request ID; close out the previous hop
+		if entry != nil && event.RedirectResponse != nil {
+			entry.request.Status = event.RedirectResponse.Status
+			r.finish(entry, event.Timestamp)
+		}
+		r.add(&networkEntry{
+			request: model.NetworkRequest{
+				RequestID:    event.RequestID,
+				URL:          event.Request.URL,
+				Method:       event.Request.Method,
+				ResourceType: event.Type,
+				StartedAt:    wallTime(event.WallTime),
+			},
+			started: event.Timestamp,
+		})
+	case "Network.responseReceived":
+		if entry != nil && event.Response != nil {
+			entry.request.Status = event.Response.Status
+		}
+	case "Network.loadingFinished":
+		if entry != nil {
+			r.finish(entry, event.Timestamp)
+		}
+	case "Network.loadingFailed":
+		if entry != nil {
+			entry.request.Error = event.ErrorText
+			r.finish(entry, event.Timestamp)
+		}
+	}
+}
+
+// add appends an entry, evicting the oldest once the buffer is full. Callers
+// must hold r.mu.
+func (r *networkRecorder) add(entry *networkEntry) {
+	if len(r.entries) >= r.size {
+		evicted := r.entries[0]
+		if r.byID[evicted.request.RequestID] == evicted {
+			delete(r.byID, evicted.request.RequestID)
+		}
+		r.entries = append(r.entries[:0], r.entries[1:]...)
+	}
+	r.entries = append(r.entries, entry)
+	r.byID[entry.request.RequestID] = entry
+}
+
+func (r *networkRecorder) finish(entry *networkEntry, timestamp float64) {
+	entry.request.Finished = true
+	if timestamp > entry.started {
+		entry.request.DurationMs = math.Round((timestamp-entry.started)*1e6) / 1e3
+	}
+}
+
+// requests returns the recorded requests whose URL matches pattern, oldest
+// first. A nil pattern matches every request.
+func (r *networkRecorder) requests(pattern *regexp.Regexp) []model.NetworkRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	requests := make([]model.NetworkRequest, 0, len(r.entries))
+	for _, entry := range r.entries {
+		if pattern != nil && !pattern.MatchString(entry.request.URL) {
+			continue
+		}
+		requests = append(requests, entry.request)
+	}
+	return requests
+}
+
+// wallTime converts a DevTools wall time in seconds since the epoch.
+func wallTime(seconds float64) time.Time {
+	if seconds == 0 {
+		return time.Now().UTC()
+	}
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+// EnableNetworkLog starts recording the requests made by the box's current
+// page. Enabling an already enabled log keeps the requests recorded so far.
+func (s *BrowserService) EnableNetworkLog(ctx context.Context, boxID string, params model.NetworkLogParams) error {
+	if params.BufferSize < 0 {
+		return fmt.Errorf("%w: bufferSize must not be negative", ErrInvalidParams)
+	}
+	size := params.BufferSize
+	if size == 0 {
+		size = defaultNetworkLogSize
+	}
+
+	s.networkMu.Lock()
+	defer s.networkMu.Unlock()
+
+	if existing, ok := s.networkLogs[boxID]; ok {
+		select {
+		case <-existing.session.Done():
+			// The page went away, reconnect below
+		default:
+			return nil
+		}
+	}
+
+	page, err := s.openPage(ctx, boxID, "")
+	if err != nil {
+		return err
+	}
+	recorder := newNetworkRecorder(page, size)
+	if err := page.call(ctx, "Network.enable", nil, nil); err != nil {
+		page.Close()
+		return err
+	}
+	if s.networkLogs == nil {
+		s.networkLogs = make(map[string]*networkRecorder)
+	}
+	s.networkLogs[boxID] = recorder
+	return nil
+}
+
+// GetNetworkLog returns the requests recorded for a box, optionally limited
+// to URLs matching the urlPattern regular expression.
+func (s *BrowserService) GetNetworkLog(boxID string, urlPattern string) (*model.NetworkLogResult, error) {
+	var pattern *regexp.Regexp
+	if urlPattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(urlPattern); err != nil {
+			return nil, fmt.Errorf("%w: invalid URL pattern: %v", ErrInvalidParams, err)
+		}
+	}
+
+	s.networkMu.Lock()
+	recorder, ok := s.networkLogs[boxID]
+	s.networkMu.Unlock()
+	if !ok {
+		return nil, ErrNetworkLogDisabled
+	}
+
+	return &model.NetworkLogResult{Requests: recorder.requests(pattern)}, nil
+}
+
+// DisableNetworkLog stops recording and discards the requests recorded for a
+// box.
+func (s *BrowserService) DisableNetworkLog(boxID string) error {
+	s.networkMu.Lock()
+	recorder, ok := s.networkLogs[boxID]
+	delete(s.networkLogs, boxID)
+	s.networkMu.Unlock()
+	if !ok {
+		return ErrNetworkLogDisabled
+	}
+	return recorder.session.Close()
+}
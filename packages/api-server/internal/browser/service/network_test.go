@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+// emitPageLoad simulates the page navigating to a test HTML document that
+// loads a stylesheet which fails.
+func emitPageLoad(browser *fakeBrowser) {
+	browser.Emit("Network.requestWillBeSent", map[string]interface{}{
+		"requestId": "1", "timestamp": 10.0, "wallTime": 1700000000.5, "type": "Document",
+		"request": map[string]interface{}{"url": "http://test.local/index.html", "method": "GET"},
+	})
+	browser.Emit("Network.responseReceived", map[string]interface{}{
+		"requestId": "1", "timestamp": 10.1, "response": map[string]interface{}{"status": 200},
+	})
+	browser.Emit("Network.loadingFinished", map[string]interface{}{"requestId": "1", "timestamp": 10.25})
+	browser.Emit("Network.requestWillBeSent", map[string]interface{}{
+		"requestId": "2", "timestamp": 10.3, "wallTime": 1700000000.8, "type": "Stylesheet",
+		"request": map[string]interface{}{"url": "http://test.local/style.css", "method": "GET"},
+	})
+	browser.Emit("Network.loadingFailed", map[string]interface{}{
+		"requestId": "2", "timestamp": 10.4, "errorText": "net::ERR_CONNECTION_REFUSED",
+	})
+}
+
+func waitForRequests(t *testing.T, svc *BrowserService, count int) []model.NetworkRequest {
+	t.Helper()
+	var requests []model.NetworkRequest
+	require.Eventually(t, func() bool {
+		result, err := svc.GetNetworkLog("box-1", "")
+		if err != nil {
+			return false
+		}
+		requests = result.Requests
+		return len(requests) == count && requests[count-1].Finished
+	}, 2*time.Second, 10*time.Millisecond)
+	return requests
+}
+
+func TestNetworkLogRecordsPageRequests(t *testing.T) {
+	browser := newFakeBrowser(t, map[string]cdpHandler{
+		"Network.enable": func(json.RawMessage) interface{} { return map[string]interface{}{} },
+	})
+	svc := newTestBrowserService(browser)
+
+	_, err := svc.GetNetworkLog("box-1", "")
+	assert.ErrorIs(t, err, ErrNetworkLogDisabled)
+
+	require.NoError(t, svc.EnableNetworkLog(context.Background(), "box-1", model.NetworkLogParams{}))
+	emitPageLoad(browser)
+
+	requests := waitForRequests(t, svc, 2)
+	assert.Equal(t, model.NetworkRequest{
+		RequestID:    "1",
+		URL:          "http://test.local/index.html",
+		Method:       "GET",
+		ResourceType: "Document",
+		Status:       200,
+		StartedAt:    time.Unix(1700000000, 5e8).UTC(),
+		DurationMs:   250,
+		Finished:     true,
+	}, requests[0])
+	assert.Equal(t, "net::ERR_CONNECTION_REFUSED", requests[1].Error)
+	assert.Zero(t, requests[1].Status)
+
+	// Filtering by URL pattern
+	result, err := svc.GetNetworkLog("box-1", `\.html$`)
+	require.NoError(t, err)
+	require.Len(t, result.Requests, 1)
+	assert.Equal(t, "http://test.local/index.html", result.Requests[0].URL)
+
+	_, err = svc.GetNetworkLog("box-1", "(")
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	require.NoError(t, svc.DisableNetworkLog("box-1"))
+	_, err = svc.GetNetworkLog("box-1", "")
+	assert.ErrorIs(t, err, ErrNetworkLogDisabled)
+}
+
+func TestNetworkLogKeepsMostRecentRequests(t *testing.T) {
+	browser := newFakeBrowser(t, map[string]cdpHandler{
+		"Network.enable": func(json.RawMessage) interface{} { return map[string]interface{}{} },
+	})
+	svc := newTestBrowserService(browser)
+
+	require.NoError(t, svc.EnableNetworkLog(context.Background(), "box-1", model.NetworkLogParams{BufferSize: 1}))
+	emitPageLoad(browser)
+
+	requests := waitForRequests(t, svc, 1)
+	assert.Equal(t, "http://test.local/style.css", requests[0].URL)
+}
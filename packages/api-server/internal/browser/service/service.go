@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/babelcloud/gbox/packages/api-server/config"
@@ -10,23 +11,84 @@ import (
 )
 
 var (
-	ErrBoxNotFound = fmt.Errorf("box not found")
+	ErrBoxNotFound        = fmt.Errorf("box not found")
+	ErrInvalidParams      = fmt.Errorf("invalid parameters")
+	ErrNoPage             = fmt.Errorf("no open page in browser")
+	ErrElementNotFound    = fmt.Errorf("element not found")
+	ErrMultipleElements   = fmt.Errorf("multiple elements matched")
+	ErrNetworkLogDisabled = fmt.Errorf("network logging is not enabled")
+	ErrConsoleLogDisabled = fmt.Errorf("console logging is not enabled")
+	ErrEvaluationFailed   = fmt.Errorf("script evaluation failed")
+	ErrResultTooLarge     = fmt.Errorf("result too large")
+	ErrTimeout            = fmt.Errorf("browser operation timed out")
+	ErrContextNotFound    = fmt.Errorf("browser context not found")
+	ErrPageNotFound       = fmt.Errorf("page not found")
+	ErrBrowserUnavailable = fmt.Errorf("browser type not available")
 )
 
 // BrowserService handles the core logic for browser automation.
 type BrowserService struct {
 	boxManager boxSvc.BoxService
+	// resolveCdpURL returns the CDP endpoint of a box's browser
+	resolveCdpURL func(boxID string) (string, error)
+	// shareDir is the server side path of the share directory mounted in boxes
+	shareDir string
+	// browserType is the engine of contexts that do not request one
+	browserType string
+
+	networkMu   sync.Mutex
+	networkLogs map[string]*networkRecorder
+
+	consoleMu   sync.Mutex
+	consoleLogs map[string]*consoleRecorder
+
+	contextsMu sync.Mutex
+	contexts   map[string]*browserContext
+	// focusedContexts maps a box to the context whose page was focused last
+	focusedContexts map[string]string
 }
 
 // NewBrowserService creates a new BrowserService.
 func NewBrowserService(boxMgr boxSvc.BoxService) (*BrowserService, error) {
-	return &BrowserService{
-		boxManager: boxMgr,
-	}, nil
+	s := &BrowserService{
+		boxManager:      boxMgr,
+		networkLogs:     make(map[string]*networkRecorder),
+		consoleLogs:     make(map[string]*consoleRecorder),
+		contexts:        make(map[string]*browserContext),
+		focusedContexts: make(map[string]string),
+		shareDir:        config.GetInstance().File.Share,
+		browserType:     config.GetInstance().Browser.BrowserType,
+	}
+	if _, err := resolveBrowserType(s.browserType, ""); err != nil {
+		return nil, err
+	}
+	s.resolveCdpURL = s.GetCdpURL
+	return s, nil
 }
 
 // Close cleans up the service.
 func (s *BrowserService) Close() error {
+	s.networkMu.Lock()
+	for boxID, recorder := range s.networkLogs {
+		recorder.session.Close()
+		delete(s.networkLogs, boxID)
+	}
+	s.networkMu.Unlock()
+
+	s.consoleMu.Lock()
+	for boxID, recorder := range s.consoleLogs {
+		recorder.session.Close()
+		delete(s.consoleLogs, boxID)
+	}
+	s.consoleMu.Unlock()
+
+	s.contextsMu.Lock()
+	for id, bc := range s.contexts {
+		bc.close()
+		delete(s.contexts, id)
+	}
+	s.focusedContexts = make(map[string]string)
+	s.contextsMu.Unlock()
 	return nil
 }
 
@@ -59,4 +121,30 @@ func (s *BrowserService) GetCdpURL(boxID string) (string, error) {
 	return cdpURL, nil
 }
 
+// openPage connects to a page of the box's browser: the one with pageID
+// when given, else the focused page, else the first page of the browser.
+func (s *BrowserService) openPage(ctx context.Context, boxID string, pageID string) (*cdpSession, error) {
+	cdpURL, err := s.resolveCdpURL(boxID)
+	if err != nil {
+		return nil, err
+	}
+	if pageID == "" {
+		pageID = s.focusedPage(boxID)
+	}
+	if pageID == "" {
+		return dialPage(ctx, cdpURL)
+	}
+
+	targets, err := listTargets(ctx, cdpURL)
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range targets {
+		if target.ID == pageID && target.Type == "page" && target.WebSocketDebuggerURL != "" {
+			return dialDebugger(ctx, cdpURL, target.WebSocketDebuggerURL)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPageNotFound, pageID)
+}
+
 // --- Methods below are now implemented in separate files (context.go, page.go, page_action.go) ---
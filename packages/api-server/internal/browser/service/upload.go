@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+// inputFileNamesFunction reports the names of the files selected in an input.
+const inputFileNamesFunction = `function() { return Array.from(this.files || []).map((f) => f.name); }`
+
+// SetInputFiles sets files from the box's share directory on the file input
+// matching selector, as if the user had picked them.
+func (s *BrowserService) SetInputFiles(ctx context.Context, boxID string, params model.SetInputFilesParams) (*model.SetInputFilesResult, error) {
+	if params.Selector == "" {
+		return nil, fmt.Errorf("%w: selector is required", ErrInvalidParams)
+	}
+	if len(params.Files) == 0 {
+		return nil, fmt.Errorf("%w: at least one file is required", ErrInvalidParams)
+	}
+
+	files := make([]string, 0, len(params.Files))
+	for _, file := range params.Files {
+		boxPath, err := s.shareFileInBox(boxID, file)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, boxPath)
+	}
+
+	page, err := s.openPage(ctx, boxID, params.PageID)
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+
+	var doc struct {
+		Root struct {
+			NodeID int64 `json:"nodeId"`
+		} `json:"root"`
+	}
+	if err := page.call(ctx, "DOM.getDocument", map[string]interface{}{"depth": 0}, &doc); err != nil {
+		return nil, err
+	}
+	var query struct {
+		NodeID int64 `json:"nodeId"`
+	}
+	if err := page.call(ctx, "DOM.querySelector", map[string]interface{}{"nodeId": doc.Root.NodeID, "selector": params.Selector}, &query); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidParams, err)
+	}
+	if query.NodeID == 0 {
+		return nil, ErrElementNotFound
+	}
+
+	var described struct {
+		Node struct {
+			NodeName   string   `json:"nodeName"`
+			Attributes []string `json:"attributes"`
+		} `json:"node"`
+	}
+	if err := page.call(ctx, "DOM.describeNode", map[string]interface{}{"nodeId": query.NodeID}, &described); err != nil {
+		return nil, err
+	}
+	if !isFileInput(described.Node.NodeName, described.Node.Attributes) {
+		return nil, fmt.Errorf("%w: %q is not a file input", ErrInvalidParams, params.Selector)
+	}
+
+	if err := page.call(ctx, "DOM.setFileInputFiles", map[string]interface{}{"nodeId": query.NodeID, "files": files}, nil); err != nil {
+		return nil, err
+	}
+
+	// Read back what the input now holds
+	var resolved struct {
+		Object struct {
+			ObjectID string `json:"objectId"`
+		} `json:"object"`
+	}
+	if err := page.call(ctx, "DOM.resolveNode", map[string]interface{}{"nodeId": query.NodeID}, &resolved); err != nil {
+		return nil, err
+	}
+	var names struct {
+		Result struct {
+			Value []string `json:"value"`
+		} `json:"result"`
+	}
+	if err := page.call(ctx, "Runtime.callFunctionOn", map[string]interface{}{
+		"objectId":            resolved.Object.ObjectID,
+		"functionDeclaration": inputFileNamesFunction,
+		"returnByValue":       true,
+	}, &names); err != nil {
+		return nil, err
+	}
+
+	return &model.SetInputFilesResult{Files: names.Result.Value}, nil
+}
+
+// shareFileInBox checks that file exists in the box's share directory and
+// returns its path as seen by the browser inside the box.
+func (s *BrowserService) shareFileInBox(boxID, file string) (string, error) {
+	rel := strings.TrimPrefix(file, common.DefaultShareDirPath)
+	rel = filepath.Clean("/" + rel)
+
+	hostPath := filepath.Join(s.shareDir, boxID, rel)
+	info, err := os.Stat(hostPath)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: file %s does not exist in the share directory", ErrInvalidParams, file)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", file, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%w: %s is not a regular file", ErrInvalidParams, file)
+	}
+
+	return filepath.Join(common.DefaultShareDirPath, rel), nil
+}
+
+// isFileInput reports whether a described DOM node is an <input type=file>.
+// attributes is the flattened name/value list returned by DOM.describeNode.
+func isFileInput(nodeName string, attributes []string) bool {
+	if !strings.EqualFold(nodeName, "input") {
+		return false
+	}
+	for i := 0; i+1 < len(attributes); i += 2 {
+		if strings.EqualFold(attributes[i], "type") {
+			return strings.EqualFold(attributes[i+1], "file")
+		}
+	}
+	return false
+}
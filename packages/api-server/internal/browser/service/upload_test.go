@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/browser"
+)
+
+// newFormBrowser fakes a page with <input id="upload" type="file"> and
+// <input id="name" type="text">.
+func newFormBrowser(t *testing.T) *fakeBrowser {
+	var mu sync.Mutex
+	var selected []string
+	nodes := map[string]int64{"#upload": 2, "#name": 3}
+	inputTypes := map[int64]string{2: "file", 3: "text"}
+
+	return newFakeBrowser(t, map[string]cdpHandler{
+		"DOM.getDocument": func(json.RawMessage) interface{} {
+			return map[string]interface{}{"root": map[string]interface{}{"nodeId": 1}}
+		},
+		"DOM.querySelector": func(params json.RawMessage) interface{} {
+			var p struct{ Selector string }
+			json.Unmarshal(params, &p)
+			return map[string]interface{}{"nodeId": nodes[p.Selector]}
+		},
+		"DOM.describeNode": func(params json.RawMessage) interface{} {
+			var p struct{ NodeID int64 }
+			json.Unmarshal(params, &p)
+			return map[string]interface{}{"node": map[string]interface{}{
+				"nodeName":   "INPUT",
+				"attributes": []string{"id", "x", "type", inputTypes[p.NodeID]},
+			}}
+		},
+		"DOM.setFileInputFiles": func(params json.RawMessage) interface{} {
+			var p struct{ Files []string }
+			json.Unmarshal(params, &p)
+			mu.Lock()
+			selected = p.Files
+			mu.Unlock()
+			return map[string]interface{}{}
+		},
+		"DOM.resolveNode": func(json.RawMessage) interface{} {
+			return map[string]interface{}{"object": map[string]interface{}{"objectId": "obj-2"}}
+		},
+		"Runtime.callFunctionOn": func(json.RawMessage) interface{} {
+			mu.Lock()
+			defer mu.Unlock()
+			names := make([]string, 0, len(selected))
+			for _, f := range selected {
+				names = append(names, path.Base(f))
+			}
+			return map[string]interface{}{"result": map[string]interface{}{"type": "object", "value": names}}
+		},
+	})
+}
+
+func TestSetInputFiles(t *testing.T) {
+	browser := newFormBrowser(t)
+	svc := newTestBrowserService(browser)
+	svc.shareDir = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(svc.shareDir, "box-1", "docs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(svc.shareDir, "box-1", "docs", "report.pdf"), []byte("%PDF"), 0644))
+
+	result, err := svc.SetInputFiles(context.Background(), "box-1", model.SetInputFilesParams{
+		Selector: "#upload",
+		Files:    []string{"/var/gbox/share/docs/report.pdf"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"report.pdf"}, result.Files)
+
+	// The browser is given the path inside the box
+	var set struct {
+		NodeID int64
+		Files  []string
+	}
+	for _, call := range browser.Calls() {
+		if call.Method == "DOM.setFileInputFiles" {
+			require.NoError(t, json.Unmarshal(call.Params, &set))
+		}
+	}
+	assert.Equal(t, int64(2), set.NodeID)
+	assert.Equal(t, []string{"/var/gbox/share/docs/report.pdf"}, set.Files)
+
+	// Paths relative to the share directory are accepted too
+	result, err = svc.SetInputFiles(context.Background(), "box-1", model.SetInputFilesParams{
+		Selector: "#upload",
+		Files:    []string{"docs/report.pdf"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"report.pdf"}, result.Files)
+}
+
+func TestSetInputFilesValidation(t *testing.T) {
+	browser := newFormBrowser(t)
+	svc := newTestBrowserService(browser)
+	svc.shareDir = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(svc.shareDir, "box-1", "docs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(svc.shareDir, "box-1", "photo.png"), []byte("png"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(svc.shareDir, "secret.txt"), []byte("other box"), 0644))
+
+	tests := []struct {
+		name    string
+		params  model.SetInputFilesParams
+		wantErr error
+	}{
+		{name: "missing file", params: model.SetInputFilesParams{Selector: "#upload", Files: []string{"missing.png"}}, wantErr: ErrInvalidParams},
+		{name: "directory", params: model.SetInputFilesParams{Selector: "#upload", Files: []string{"docs"}}, wantErr: ErrInvalidParams},
+		{name: "outside the box share", params: model.SetInputFilesParams{Selector: "#upload", Files: []string{"../secret.txt"}}, wantErr: ErrInvalidParams},
+		{name: "not a file input", params: model.SetInputFilesParams{Selector: "#name", Files: []string{"photo.png"}}, wantErr: ErrInvalidParams},
+		{name: "no matching element", params: model.SetInputFilesParams{Selector: "#avatar", Files: []string{"photo.png"}}, wantErr: ErrElementNotFound},
+		{name: "no files", params: model.SetInputFilesParams{Selector: "#upload"}, wantErr: ErrInvalidParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.SetInputFiles(context.Background(), "box-1", tt.params)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/common/errors"
+)
+
+// APIKeyHeader carries the API key of a request
+const APIKeyHeader = "X-API-Key"
+
+type apiKeyContextKey struct{}
+
+// RequireAPIKey rejects requests to paths other than publicPaths that carry
+// no API key with 401 and those with an unknown key with 403. The key is read
+// from the X-API-Key header or, as sent by the SDK, a bearer Authorization
+// header. The owner of the key and the box it is bound to are available to
+// handlers through APIKeyOwner and APIKeyBox. Without keys authentication is
+// disabled.
+func RequireAPIKey(next http.Handler, keys []config.APIKey, publicPaths ...string) http.Handler {
+	if len(keys) == 0 {
+		return next
+	}
+	// Keys are looked up by digest so lookups do not compare secrets byte by byte
+	known := make(map[[sha256.Size]byte]config.APIKey, len(keys))
+	for _, key := range keys {
+		known[sha256.Sum256([]byte(key.Key))] = key
+	}
+	public := make(map[string]bool, len(publicPaths))
+	for _, p := range publicPaths {
+		public[p] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// CORS preflight requests never carry credentials
+		if public[r.URL.Path] || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := requestAPIKey(r)
+		if key == "" {
+			writeAuthError(w, http.StatusUnauthorized, "API key required in the "+APIKeyHeader+" header")
+			return
+		}
+		apiKey, ok := known[sha256.Sum256([]byte(key))]
+		if !ok {
+			writeAuthError(w, http.StatusForbidden, "Invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, apiKey)))
+	})
+}
+
+// APIKeyOwner returns the owner of the API key that authenticated the
+// request of ctx, or "" when authentication is disabled
+func APIKeyOwner(ctx context.Context) string {
+	key, _ := ctx.Value(apiKeyContextKey{}).(config.APIKey)
+	return key.Owner
+}
+
+// APIKeyBox returns the box the API key that authenticated the request of
+// ctx is bound to, or "" when the key is not bound to a box or
+// authentication is disabled
+func APIKeyBox(ctx context.Context) string {
+	key, _ := ctx.Value(apiKeyContextKey{}).(config.APIKey)
+	return key.Box
+}
+
+func requestAPIKey(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get(APIKeyHeader)); key != "" {
+		return key
+	}
+	key, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return strings.TrimSpace(key)
+}
+
+func writeAuthError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errors.New(status, message))
+}
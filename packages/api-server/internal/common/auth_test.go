@@ -0,0 +1,48 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	var owner string
+	handler := RequireAPIKey(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owner = APIKeyOwner(r.Context())
+	}), []config.APIKey{{Key: "secret", Owner: "alice"}}, "/api/v1/version")
+
+	serve := func(path string, header http.Header) int {
+		owner = ""
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/api/v1/boxes", http.Header{"X-Api-Key": {"secret"}}))
+	assert.Equal(t, "alice", owner)
+	assert.Equal(t, http.StatusOK, serve("/api/v1/boxes", http.Header{"Authorization": {"Bearer secret"}}))
+	assert.Equal(t, "alice", owner)
+
+	assert.Equal(t, http.StatusUnauthorized, serve("/api/v1/boxes", nil))
+	assert.Equal(t, http.StatusForbidden, serve("/api/v1/boxes", http.Header{"X-Api-Key": {"wrong"}}))
+	assert.Empty(t, owner, "rejected requests must not reach the handler")
+
+	assert.Equal(t, http.StatusOK, serve("/api/v1/version", nil))
+	assert.Empty(t, owner)
+}
+
+func TestRequireAPIKeyDisabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	rec := httptest.NewRecorder()
+	RequireAPIKey(next, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/boxes", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	apierrors "github.com/babelcloud/gbox/packages/api-server/internal/common/errors"
+)
+
+// LimitURLLength rejects requests whose request URI (path and query) is
+// longer than maxLength with 414 before they reach routing. A non-positive
+// maxLength disables the check.
+func LimitURLLength(next http.Handler, maxLength int) http.Handler {
+	if maxLength <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri := r.RequestURI
+		if uri == "" {
+			uri = r.URL.RequestURI()
+		}
+		if len(uri) > maxLength {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestURITooLong)
+			json.NewEncoder(w).Encode(apierrors.Newf(http.StatusRequestURITooLong,
+				"Request URI is %d bytes long, the limit is %d", len(uri), maxLength))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LimitJSONBody rejects JSON requests whose body is larger than maxBytes with
+// 413 before they reach routing. The body is read up front through
+// http.MaxBytesReader, so handlers decoding it never see a truncated entity
+// and bodies sent without a Content-Length are capped too. Other content
+// types, such as archive uploads, are not limited. A non-positive maxBytes
+// disables the check.
+func LimitJSONBody(next http.Handler, maxBytes int64) http.Handler {
+	if maxBytes <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody || !isJSONRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tooLarge := func() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(apierrors.Newf(http.StatusRequestEntityTooLarge,
+				"JSON request body is larger than the limit of %d bytes", maxBytes))
+		}
+		if r.ContentLength > maxBytes {
+			tooLarge()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+		r.Body.Close()
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			tooLarge()
+			return
+		}
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(apierrors.Newf(http.StatusBadRequest, "Failed to read request body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isJSONRequest reports whether the request body is declared as JSON
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/common/errors"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+func TestLimitURLLength(t *testing.T) {
+	var served int
+	handler := LimitURLLength(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		w.WriteHeader(http.StatusOK)
+	}), 64)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/v1/files/"+strings.Repeat("a", 100), "application/json", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusRequestURITooLong, resp.StatusCode)
+
+	var body errors.Error
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, http.StatusRequestURITooLong, body.Code)
+	assert.Equal(t, 0, served, "over-length requests must not reach the router")
+
+	resp, err = http.Post(server.URL+"/api/v1/files/short", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, served)
+}
+
+func TestLimitURLLengthDisabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	rec := httptest.NewRecorder()
+	LimitURLLength(next, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+strings.Repeat("a", 10000), nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestLimitJSONBody(t *testing.T) {
+	var created []model.LinuxAndroidBoxCreateParam
+	ws := new(restful.WebService)
+	ws.Path("/api/v1").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+	ws.Route(ws.POST("/boxes/linux").To(func(req *restful.Request, resp *restful.Response) {
+		var params model.LinuxAndroidBoxCreateParam
+		if err := req.ReadEntity(&params); err != nil {
+			resp.WriteErrorString(http.StatusBadRequest, err.Error())
+			return
+		}
+		created = append(created, params)
+		resp.WriteHeader(http.StatusCreated)
+	}))
+	ws.Route(ws.PUT("/upload").Consumes("application/x-tar").To(func(req *restful.Request, resp *restful.Response) {
+		n, _ := io.Copy(io.Discard, req.Request.Body)
+		resp.WriteHeader(http.StatusOK)
+		fmt.Fprint(resp, n)
+	}))
+	container := restful.NewContainer()
+	container.Add(ws)
+	server := httptest.NewServer(LimitJSONBody(container, 1024))
+	defer server.Close()
+
+	post := func(body io.Reader, contentType string) *http.Response {
+		resp, err := http.Post(server.URL+"/api/v1/boxes/linux", contentType, body)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	oversized := `{"config":{"envs":{"A":"` + strings.Repeat("a", 2048) + `"}}}`
+
+	resp := post(strings.NewReader(oversized), "application/json")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	var body errors.Error
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, body.Code)
+
+	// Without a Content-Length the body is cut off while it is read
+	resp = post(io.MultiReader(strings.NewReader(oversized)), "application/json; charset=utf-8")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	assert.Empty(t, created, "oversized bodies must not reach the handler")
+
+	resp = post(strings.NewReader(`{"config":{"envs":{"A":"b"}}}`), "application/json")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Len(t, created, 1)
+	assert.Equal(t, "b", created[0].Config.Envs["A"])
+
+	// Only JSON bodies are limited
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/api/v1/upload", strings.NewReader(strings.Repeat("x", 4096)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-tar")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	n, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "4096", string(n))
+}
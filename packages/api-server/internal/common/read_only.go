@@ -0,0 +1,91 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "github.com/babelcloud/gbox/packages/api-server/internal/common/errors"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/admin"
+)
+
+// ReadOnlyMode is the server's maintenance switch. While enabled,
+// RejectWritesWhenReadOnly turns mutating requests away.
+type ReadOnlyMode struct {
+	mu    sync.Mutex
+	since *time.Time // Set while read-only mode is enabled
+}
+
+// NewReadOnlyMode creates the switch, enabled when enabled is true
+func NewReadOnlyMode(enabled bool) *ReadOnlyMode {
+	m := &ReadOnlyMode{}
+	if enabled {
+		m.EnableReadOnly()
+	}
+	return m
+}
+
+// EnableReadOnly rejects mutating requests from now on. Enabling it again
+// keeps the original start time.
+func (m *ReadOnlyMode) EnableReadOnly() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.since == nil {
+		now := time.Now()
+		m.since = &now
+	}
+}
+
+// DisableReadOnly accepts mutating requests again
+func (m *ReadOnlyMode) DisableReadOnly() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.since = nil
+}
+
+// ReadOnlyStatus reports whether read-only mode is enabled and since when
+func (m *ReadOnlyMode) ReadOnlyStatus() model.ReadOnlyStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return model.ReadOnlyStatus{ReadOnly: m.since != nil, Since: m.since}
+}
+
+// RejectWritesWhenReadOnly answers mutating requests with 503 while mode is
+// enabled, so reads such as listing boxes, logs and file downloads keep
+// working during maintenance. Requests to paths under exemptPrefixes, such
+// as the admin routes that switch the mode off, are always let through.
+func RejectWritesWhenReadOnly(next http.Handler, mode *ReadOnlyMode, exemptPrefixes ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isMutatingRequest(r) || !mode.ReadOnlyStatus().ReadOnly {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, prefix := range exemptPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(apierrors.New(http.StatusServiceUnavailable,
+			"The server is in read-only maintenance mode, only read requests are accepted"))
+	})
+}
+
+// isMutatingRequest classifies a request by method and path. Everything but
+// GET, HEAD and OPTIONS mutates, as do the GET routes that upgrade to an
+// interactive exec session.
+func isMutatingRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		path := strings.TrimSuffix(r.URL.Path, "/")
+		return strings.HasSuffix(path, "/exec") || strings.HasSuffix(path, "/attach")
+	case http.MethodOptions:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/internal/common/errors"
+)
+
+func TestRejectWritesWhenReadOnly(t *testing.T) {
+	var served []string
+	mode := NewReadOnlyMode(true)
+	handler := RejectWritesWhenReadOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}), mode, "/api/v1/admin/")
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/v1/boxes/linux")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var body errors.Error
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, http.StatusServiceUnavailable, body.Code)
+	assert.Contains(t, body.Message, "read-only")
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/boxes").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/boxes/b1/logs").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/boxes/b1/fs/read").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodDelete, "/api/v1/boxes/b1").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodGet, "/api/v1/boxes/b1/exec").Code, "exec sessions upgrade from GET")
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodPost, "/api/v1/boxes/b1/fs/write").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/api/v1/admin/read-only/disable").Code, "admin routes stay reachable")
+	assert.Equal(t, []string{
+		"GET /api/v1/boxes",
+		"GET /api/v1/boxes/b1/logs",
+		"GET /api/v1/boxes/b1/fs/read",
+		"POST /api/v1/admin/read-only/disable",
+	}, served)
+
+	mode.DisableReadOnly()
+	assert.False(t, mode.ReadOnlyStatus().ReadOnly)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/api/v1/boxes/linux").Code)
+}
+
+func TestReadOnlyModeStatus(t *testing.T) {
+	mode := NewReadOnlyMode(false)
+	assert.Equal(t, false, mode.ReadOnlyStatus().ReadOnly)
+	assert.Nil(t, mode.ReadOnlyStatus().Since)
+
+	mode.EnableReadOnly()
+	since := mode.ReadOnlyStatus().Since
+	require.NotNil(t, since)
+	mode.EnableReadOnly()
+	assert.Equal(t, since, mode.ReadOnlyStatus().Since, "enabling again keeps the start time")
+}
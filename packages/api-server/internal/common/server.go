@@ -0,0 +1,41 @@
+package common
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+)
+
+// NewServer creates the HTTP server for handler with the timeouts and limits
+// of cfg. With HTTP2 enabled, clients may also speak cleartext HTTP/2 (h2c).
+func NewServer(addr string, handler http.Handler, cfg config.ServerConfig) *http.Server {
+	handler = LimitJSONBody(handler, cfg.MaxJSONBodyBytes)
+	handler = LimitURLLength(handler, cfg.MaxURLLength)
+	if cfg.HTTP2 {
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: cfg.IdleTimeout})
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// NoTimeouts is a route filter clearing the server's read and write
+// deadlines for long-lived streaming routes such as exec sessions, which
+// would otherwise be cut off by ReadTimeout and WriteTimeout.
+func NoTimeouts(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	rc := http.NewResponseController(resp.ResponseWriter)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+	chain.ProcessFilter(req, resp)
+}
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+)
+
+// serve starts server on a local listener and returns its address
+func serve(t *testing.T, server *http.Server) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return listener.Addr().String()
+}
+
+func TestNewServerCutsOffSlowHeaders(t *testing.T) {
+	var served bool
+	server := NewServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+	}), config.ServerConfig{ReadHeaderTimeout: 200 * time.Millisecond})
+	addr := serve(t, server)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Send the request line but never finish the headers
+	_, err = conn.Write([]byte("GET /api/v1/boxes HTTP/1.1\r\nHost: localhost\r\n"))
+	require.NoError(t, err)
+
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadAll(conn)
+	require.NoError(t, err, "server should close the connection")
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.False(t, served)
+}
+
+func TestNoTimeoutsExemptsStreamingRoutes(t *testing.T) {
+	stream := func(req *restful.Request, resp *restful.Response) {
+		time.Sleep(300 * time.Millisecond)
+		resp.Write([]byte("done"))
+	}
+	ws := new(restful.WebService)
+	ws.Route(ws.GET("/stream").Filter(NoTimeouts).To(stream))
+	ws.Route(ws.GET("/plain").To(stream))
+	container := restful.NewContainer()
+	container.Add(ws)
+
+	addr := serve(t, NewServer("", container, config.ServerConfig{WriteTimeout: 100 * time.Millisecond}))
+
+	resp, err := http.Get("http://" + addr + "/stream")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "done", string(body))
+
+	_, err = http.Get("http://" + addr + "/plain")
+	assert.Error(t, err, "a response written after the write timeout should be cut off")
+}
+
+func TestNewServerAcceptsH2C(t *testing.T) {
+	server := NewServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}), config.ServerConfig{HTTP2: true})
+	addr := serve(t, server)
+
+	// Speak HTTP/2 with prior knowledge over a cleartext connection
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := client.Get("http://" + addr + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "HTTP/2.0", string(body))
+}
+
+// Test that sync requests are limited like any JSON request, while the tar
+// uploads carrying their changed files are not
+func TestNewServerLimitsSyncButNotArchiveUploads(t *testing.T) {
+	server := NewServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}), config.ServerConfig{MaxJSONBodyBytes: 1024})
+	addr := serve(t, server)
+
+	body := `{"delete":["` + strings.Repeat("a", 4096) + `"]}`
+	resp, err := http.Post("http://"+addr+"/api/v1/boxes/box-1/sync", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodPut, "http://"+addr+"/api/v1/boxes/box-1/archive", strings.NewReader(strings.Repeat("a", 4096)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-tar")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -3,6 +3,7 @@ package common
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"time"
 )
 
@@ -56,6 +57,24 @@ func GetLocalIPs() []string {
 	return ips
 }
 
+// ListenAddress joins the bind address and port into an address suitable for
+// http.Server.Addr. An empty bind address listens on all interfaces.
+func ListenAddress(bindAddress string, port int) string {
+	return net.JoinHostPort(bindAddress, strconv.Itoa(port))
+}
+
+// GetAccessibleHosts returns the hosts a server bound to bindAddress can be
+// reached on. Wildcard binds report every local IP.
+func GetAccessibleHosts(bindAddress string) []string {
+	if bindAddress == "" {
+		return GetLocalIPs()
+	}
+	if ip := net.ParseIP(bindAddress); ip != nil && ip.IsUnspecified() {
+		return GetLocalIPs()
+	}
+	return []string{bindAddress}
+}
+
 // FormatDurationConcise provides a simpler string representation for durations.
 func FormatDurationConcise(d time.Duration) string {
 	if d%(24*time.Hour) == 0 {
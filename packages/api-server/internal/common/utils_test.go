@@ -0,0 +1,56 @@
+package common
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenAddress(t *testing.T) {
+	assert.Equal(t, ":28080", ListenAddress("", 28080))
+	assert.Equal(t, "127.0.0.1:28080", ListenAddress("127.0.0.1", 28080))
+	assert.Equal(t, "[::1]:28080", ListenAddress("::1", 28080))
+}
+
+func TestGetAccessibleHosts(t *testing.T) {
+	assert.Equal(t, GetLocalIPs(), GetAccessibleHosts(""))
+	assert.Equal(t, GetLocalIPs(), GetAccessibleHosts("0.0.0.0"))
+	assert.Equal(t, []string{"127.0.0.1"}, GetAccessibleHosts("127.0.0.1"))
+}
+
+func TestServerBindsOnlyToConfiguredAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", ListenAddress("127.0.0.1", 0))
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	tcpAddr := listener.Addr().(*net.TCPAddr)
+	assert.True(t, tcpAddr.IP.Equal(net.ParseIP("127.0.0.1")))
+	port := strconv.Itoa(tcpAddr.Port)
+
+	resp, err := http.Get("http://" + net.JoinHostPort("127.0.0.1", port))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Other local interfaces must not accept connections on the port
+	for _, host := range GetLocalIPs() {
+		ip := net.ParseIP(host)
+		if ip == nil || ip.IsLoopback() {
+			continue
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), time.Second)
+		if err == nil {
+			conn.Close()
+		}
+		assert.Error(t, err, "server should not be reachable on %s", host)
+	}
+}
@@ -2,12 +2,16 @@ package cron
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/robfig/cron/v3"
 
+	"github.com/babelcloud/gbox/packages/api-server/config"
 	boxservice "github.com/babelcloud/gbox/packages/api-server/internal/box/service"
 	fileservice "github.com/babelcloud/gbox/packages/api-server/internal/file/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/admin"
+	boxmodel "github.com/babelcloud/gbox/packages/api-server/pkg/box"
 	"github.com/babelcloud/gbox/packages/api-server/pkg/logger"
 )
 
@@ -16,30 +20,49 @@ const (
 	boxReclaimTimeout = 5 * time.Minute
 	// Timeout for file reclamation
 	fileReclaimTimeout = 10 * time.Minute
+	// Timeout for screenshot pruning
+	screenshotPruneTimeout = 5 * time.Minute
+	// Timeout for max runtime enforcement
+	maxRuntimeTimeout = time.Minute
 )
 
+// AccessTimes is the part of the access tracker reporting box idle times
+type AccessTimes interface {
+	Snapshot() map[string]time.Time
+	GetWarned(id string) (time.Time, bool)
+}
+
 // Manager manages cron jobs
 type Manager struct {
 	cron        *cron.Cron
 	logger      *logger.Logger
 	boxService  boxservice.BoxService
 	fileService *fileservice.FileService
+	accessTimes AccessTimes
+
+	reclaimEntry cron.EntryID
+
+	mu       sync.Mutex
+	pausedAt *time.Time // Set while box reclamation is paused
 }
 
-// NewManager creates a new cron manager
-func NewManager(logger *logger.Logger, boxService boxservice.BoxService, fileService *fileservice.FileService) *Manager {
+// NewManager creates a new cron manager. accessTimes is the tracker box
+// reclamation works from.
+func NewManager(logger *logger.Logger, boxService boxservice.BoxService, fileService *fileservice.FileService, accessTimes AccessTimes) *Manager {
 	return &Manager{
 		cron:        cron.New(cron.WithLogger(cron.DefaultLogger)),
 		logger:      logger,
 		boxService:  boxService,
 		fileService: fileService,
+		accessTimes: accessTimes,
 	}
 }
 
 // Start starts the cron manager
 func (m *Manager) Start() {
 	// Add reclaim jobs
-	_, err := m.cron.AddFunc("*/10 * * * *", m.reclaimBoxes)
+	var err error
+	m.reclaimEntry, err = m.cron.AddFunc("*/10 * * * *", m.reclaimBoxes)
 	if err != nil {
 		m.logger.Fatal("Failed to add box reclaim job: %v", err)
 	}
@@ -50,6 +73,30 @@ func (m *Manager) Start() {
 		m.logger.Fatal("Failed to add file reclaim job: %v", err)
 	}
 
+	// Reclaim each share tier on its own schedule
+	for _, tier := range config.GetInstance().File.ShareTiers {
+		if tier.ReclaimAfter <= 0 {
+			continue
+		}
+		tier := tier
+		_, err = m.cron.AddFunc(tier.ReclaimSchedule, func() { m.reclaimShareTier(tier) })
+		if err != nil {
+			m.logger.Fatal("Failed to add file reclaim job of share tier %s: %v", tier.Name, err)
+		}
+	}
+
+	// Enforce screenshot retention hourly
+	_, err = m.cron.AddFunc("30 * * * *", m.pruneScreenshots)
+	if err != nil {
+		m.logger.Fatal("Failed to add screenshot prune job: %v", err)
+	}
+
+	// Check box max runtimes every minute; they are not affected by reclaim pauses
+	_, err = m.cron.AddFunc("* * * * *", m.enforceMaxRuntime)
+	if err != nil {
+		m.logger.Fatal("Failed to add max runtime job: %v", err)
+	}
+
 	m.cron.Start()
 	m.logger.Info("Cron manager started")
 }
@@ -60,8 +107,106 @@ func (m *Manager) Stop() {
 	m.logger.Info("Cron manager stopped")
 }
 
+// PauseReclaim stops scheduled box reclamation until ResumeReclaim, e.g.
+// during maintenance. Pausing an already paused manager keeps the original
+// pause time.
+func (m *Manager) PauseReclaim() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pausedAt == nil {
+		now := time.Now()
+		m.pausedAt = &now
+		m.logger.Info("Box reclamation paused")
+	}
+}
+
+// ResumeReclaim re-enables scheduled box reclamation
+func (m *Manager) ResumeReclaim() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pausedAt != nil {
+		m.pausedAt = nil
+		m.logger.Info("Box reclamation resumed")
+	}
+}
+
+// ReclaimStatus reports whether box reclamation is paused and when it next runs
+func (m *Manager) ReclaimStatus() model.ReclaimStatus {
+	m.mu.Lock()
+	status := model.ReclaimStatus{Paused: m.pausedAt != nil, PausedAt: m.pausedAt}
+	m.mu.Unlock()
+
+	if next := m.cron.Entry(m.reclaimEntry).Next; !next.IsZero() {
+		status.NextRun = &next
+	}
+	return status
+}
+
+// ReclaimBoxesStatus reports the reclamation status along with every box's
+// idle time and the action the next run would take on it
+func (m *Manager) ReclaimBoxesStatus(ctx context.Context) (*model.ReclaimBoxesStatus, error) {
+	boxes, err := m.boxService.List(ctx, &boxmodel.BoxListParams{})
+	if err != nil {
+		return nil, err
+	}
+	accessed := m.accessTimes.Snapshot()
+	cluster := config.GetInstance().Cluster
+	now := time.Now()
+
+	status := &model.ReclaimBoxesStatus{
+		ReclaimStatus: m.ReclaimStatus(),
+		Boxes:         make([]model.ReclaimBoxStatus, 0, len(boxes.Data)),
+	}
+	for _, box := range boxes.Data {
+		entry := model.ReclaimBoxStatus{ID: box.ID, Status: box.Status, PendingAction: model.ReclaimActionNone}
+		if lastAccessed, ok := accessed[box.ID]; ok {
+			idle := now.Sub(lastAccessed)
+			entry.LastAccessed = &lastAccessed
+			entry.IdleSeconds = int64(idle / time.Second)
+			warnedAt, warned := m.accessTimes.GetWarned(box.ID)
+			if warned {
+				entry.WarnedAt = &warnedAt
+			}
+			entry.PendingAction = pendingReclaimAction(cluster, box.Status, idle, entry.WarnedAt, now)
+		}
+		status.Boxes = append(status.Boxes, entry)
+	}
+	return status, nil
+}
+
+// pendingReclaimAction returns what box reclamation would do with a box,
+// following the rules of the box services' Reclaim
+func pendingReclaimAction(cluster config.ClusterConfig, status string, idle time.Duration, warnedAt *time.Time, now time.Time) string {
+	switch status {
+	case "running":
+		if cluster.ReclaimWarnThreshold > 0 && idle >= cluster.ReclaimWarnThreshold {
+			if warnedAt == nil {
+				return model.ReclaimActionWarn
+			}
+			if now.Sub(*warnedAt) < cluster.ReclaimGracePeriod {
+				return model.ReclaimActionNone
+			}
+		}
+		if idle >= cluster.ReclaimStopThreshold {
+			return model.ReclaimActionStop
+		}
+	case "stopped", "exited":
+		if cluster.ReclaimDeleteEnabled && idle >= cluster.ReclaimDeleteThreshold {
+			return model.ReclaimActionDelete
+		}
+	}
+	return model.ReclaimActionNone
+}
+
 // reclaimBoxes runs the box reclamation job
 func (m *Manager) reclaimBoxes() {
+	m.mu.Lock()
+	paused := m.pausedAt != nil
+	m.mu.Unlock()
+	if paused {
+		m.logger.Info("Skipping scheduled box reclamation, reclamation is paused")
+		return
+	}
 	m.logger.Info("Running scheduled box reclamation")
 	ctx, cancel := context.WithTimeout(context.Background(), boxReclaimTimeout)
 	defer cancel()
@@ -91,3 +236,56 @@ func (m *Manager) reclaimFiles() {
 		}
 	}
 }
+
+// reclaimShareTier runs the file reclaim job of a share tier
+func (m *Manager) reclaimShareTier(tier config.ShareTier) {
+	m.logger.Info("Running scheduled file reclamation of share tier %s", tier.Name)
+	ctx, cancel := context.WithTimeout(context.Background(), fileReclaimTimeout)
+	defer cancel()
+
+	_, err := m.fileService.ReclaimShareTier(ctx, tier)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			m.logger.Error("File reclamation of share tier %s timed out after %v", tier.Name, fileReclaimTimeout)
+		} else {
+			m.logger.Error("Failed to reclaim files of share tier %s: %v", tier.Name, err)
+		}
+	}
+}
+
+// pruneScreenshots runs the screenshot retention job
+func (m *Manager) pruneScreenshots() {
+	ctx, cancel := context.WithTimeout(context.Background(), screenshotPruneTimeout)
+	defer cancel()
+
+	removed, err := m.fileService.PruneScreenshots(ctx)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			m.logger.Error("Screenshot pruning timed out after %v", screenshotPruneTimeout)
+		} else {
+			m.logger.Error("Failed to prune screenshots: %v", err)
+		}
+	}
+	if len(removed) > 0 {
+		m.logger.Info("Pruned %d screenshots", len(removed))
+	}
+}
+
+// enforceMaxRuntime stops boxes that have run longer than their max runtime
+func (m *Manager) enforceMaxRuntime() {
+	ctx, cancel := context.WithTimeout(context.Background(), maxRuntimeTimeout)
+	defer cancel()
+
+	result, err := m.boxService.EnforceMaxRuntime(ctx)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			m.logger.Error("Max runtime enforcement timed out after %v", maxRuntimeTimeout)
+		} else {
+			m.logger.Error("Failed to enforce box max runtimes: %v", err)
+		}
+		return
+	}
+	if result.StoppedCount > 0 {
+		m.logger.Info("Stopped %d boxes that exceeded their max runtime", result.StoppedCount)
+	}
+}
@@ -0,0 +1,183 @@
+package cron
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	boxservice "github.com/babelcloud/gbox/packages/api-server/internal/box/service"
+	fileservice "github.com/babelcloud/gbox/packages/api-server/internal/file/service"
+	"github.com/babelcloud/gbox/packages/api-server/internal/tracker"
+	adminmodel "github.com/babelcloud/gbox/packages/api-server/pkg/admin"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	"github.com/babelcloud/gbox/packages/api-server/pkg/logger"
+)
+
+// countingBoxService counts reclaim runs
+type countingBoxService struct {
+	boxservice.BoxService
+	reclaims int
+}
+
+func (s *countingBoxService) Reclaim(ctx context.Context) (*model.BoxReclaimResult, error) {
+	s.reclaims++
+	return &model.BoxReclaimResult{}, nil
+}
+
+func TestReclaimPause(t *testing.T) {
+	boxes := &countingBoxService{}
+	m := NewManager(logger.New(), boxes, nil, tracker.NewInMemoryAccessTracker())
+	m.Start()
+	defer m.Stop()
+
+	status := m.ReclaimStatus()
+	assert.False(t, status.Paused)
+	require.NotNil(t, status.NextRun)
+
+	m.reclaimBoxes()
+	assert.Equal(t, 1, boxes.reclaims)
+
+	m.PauseReclaim()
+	status = m.ReclaimStatus()
+	assert.True(t, status.Paused)
+	require.NotNil(t, status.PausedAt)
+	pausedAt := *status.PausedAt
+
+	m.reclaimBoxes()
+	assert.Equal(t, 1, boxes.reclaims, "reclamation must be skipped while paused")
+
+	m.PauseReclaim()
+	assert.Equal(t, pausedAt, *m.ReclaimStatus().PausedAt, "pausing again keeps the original pause time")
+
+	m.ResumeReclaim()
+	status = m.ReclaimStatus()
+	assert.False(t, status.Paused)
+	assert.Nil(t, status.PausedAt)
+
+	m.reclaimBoxes()
+	assert.Equal(t, 2, boxes.reclaims)
+}
+
+func TestShareTierReclaimSchedules(t *testing.T) {
+	scratch, persist := t.TempDir(), t.TempDir()
+	file := &config.GetInstance().File
+	orig := file.ShareTiers
+	t.Cleanup(func() { file.ShareTiers = orig })
+	file.ShareTiers = []config.ShareTier{
+		{Name: "scratch", Path: scratch, HostPath: scratch, ReclaimAfter: time.Hour, ReclaimSchedule: "@every 1s"},
+		{Name: "persist", Path: persist, HostPath: persist},
+	}
+
+	writeFile := func(path string, modTime time.Time) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+		require.NoError(t, os.Chtimes(filepath.Dir(path), modTime, modTime))
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	writeFile(filepath.Join(scratch, "box-1", "out.log"), old)
+	writeFile(filepath.Join(scratch, "box-2", "out.log"), time.Now())
+	writeFile(filepath.Join(persist, "box-3", "out.log"), old)
+
+	files, err := fileservice.New(nil)
+	require.NoError(t, err)
+	m := NewManager(logger.New(), &countingBoxService{}, files, tracker.NewInMemoryAccessTracker())
+	m.Start()
+	defer m.Stop()
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(scratch, "box-1"))
+		return os.IsNotExist(err)
+	}, 5*time.Second, 50*time.Millisecond, "old scratch files should be reclaimed on the tier's schedule")
+	assert.FileExists(t, filepath.Join(scratch, "box-2", "out.log"), "recent scratch files are kept")
+	assert.FileExists(t, filepath.Join(persist, "box-3", "out.log"), "the persist tier is never reclaimed")
+}
+
+// listingBoxService lists a fixed set of boxes
+type listingBoxService struct {
+	boxservice.BoxService
+	boxes []model.Box
+}
+
+func (s *listingBoxService) List(ctx context.Context, params *model.BoxListParams) (*model.BoxListResult, error) {
+	return &model.BoxListResult{Data: s.boxes, Total: len(s.boxes)}, nil
+}
+
+// fixedAccessTimes reports fixed access and warning times
+type fixedAccessTimes struct {
+	accessed map[string]time.Time
+	warned   map[string]time.Time
+}
+
+func (a fixedAccessTimes) Snapshot() map[string]time.Time {
+	return a.accessed
+}
+
+func (a fixedAccessTimes) GetWarned(id string) (time.Time, bool) {
+	ts, ok := a.warned[id]
+	return ts, ok
+}
+
+func TestReclaimBoxesStatus(t *testing.T) {
+	now := time.Now()
+	boxes := &listingBoxService{boxes: []model.Box{
+		{ID: "busy", Status: "running"},
+		{ID: "idle", Status: "running"},
+		{ID: "new", Status: "running"},
+	}}
+	accessTimes := fixedAccessTimes{
+		accessed: map[string]time.Time{
+			"busy":    now.Add(-time.Minute),
+			"idle":    now.Add(-30 * 24 * time.Hour),
+			"deleted": now.Add(-time.Hour),
+		},
+		warned: map[string]time.Time{"idle": now.Add(-48 * time.Hour)},
+	}
+	m := NewManager(logger.New(), boxes, nil, accessTimes)
+
+	status, err := m.ReclaimBoxesStatus(context.Background())
+	require.NoError(t, err)
+	assert.False(t, status.Paused)
+	require.Len(t, status.Boxes, 3, "only existing boxes are listed")
+
+	busy, idle, fresh := status.Boxes[0], status.Boxes[1], status.Boxes[2]
+	assert.Equal(t, "busy", busy.ID)
+	assert.InDelta(t, 60, busy.IdleSeconds, 2)
+	assert.Equal(t, adminmodel.ReclaimActionNone, busy.PendingAction)
+
+	assert.Equal(t, adminmodel.ReclaimActionStop, idle.PendingAction)
+	require.NotNil(t, idle.WarnedAt)
+
+	assert.Nil(t, fresh.LastAccessed, "boxes not tracked yet are skipped by reclamation")
+	assert.Equal(t, adminmodel.ReclaimActionNone, fresh.PendingAction)
+}
+
+func TestPendingReclaimAction(t *testing.T) {
+	now := time.Now()
+	cluster := config.ClusterConfig{
+		ReclaimStopThreshold:   time.Hour,
+		ReclaimDeleteThreshold: 24 * time.Hour,
+		ReclaimDeleteEnabled:   true,
+		ReclaimWarnThreshold:   30 * time.Minute,
+		ReclaimGracePeriod:     10 * time.Minute,
+	}
+	recently := now.Add(-time.Minute)
+	long := now.Add(-time.Hour)
+
+	assert.Equal(t, adminmodel.ReclaimActionNone, pendingReclaimAction(cluster, "running", 10*time.Minute, nil, now))
+	assert.Equal(t, adminmodel.ReclaimActionWarn, pendingReclaimAction(cluster, "running", 2*time.Hour, nil, now))
+	assert.Equal(t, adminmodel.ReclaimActionNone, pendingReclaimAction(cluster, "running", 2*time.Hour, &recently, now), "within the grace period")
+	assert.Equal(t, adminmodel.ReclaimActionStop, pendingReclaimAction(cluster, "running", 2*time.Hour, &long, now))
+	assert.Equal(t, adminmodel.ReclaimActionNone, pendingReclaimAction(cluster, "exited", 2*time.Hour, nil, now))
+	assert.Equal(t, adminmodel.ReclaimActionDelete, pendingReclaimAction(cluster, "exited", 48*time.Hour, nil, now))
+	assert.Equal(t, adminmodel.ReclaimActionDelete, pendingReclaimAction(cluster, "stopped", 48*time.Hour, nil, now), "the status boxes are listed with")
+
+	cluster.ReclaimDeleteEnabled = false
+	assert.Equal(t, adminmodel.ReclaimActionNone, pendingReclaimAction(cluster, "exited", 48*time.Hour, nil, now))
+}
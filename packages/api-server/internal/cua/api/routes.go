@@ -1,6 +1,7 @@
 package api
 
 import (
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
 	"github.com/emicklei/go-restful/v3"
 )
 
@@ -20,6 +21,7 @@ type CuaError struct {
 func RegisterCuaRoutes(ws *restful.WebService, cuaHandler *CuaHandler) {
 	// CUA Execute Operation
 	ws.Route(ws.POST("/cua/execute").To(cuaHandler.ExecuteTask).
+		Filter(common.NoTimeouts).
 		Doc("execute a task using computer use agent").
 		Reads(CuaExecuteParams{}).
 		Produces("text/event-stream", "application/json").
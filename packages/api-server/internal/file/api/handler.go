@@ -2,12 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"path/filepath"
 	"strings"
 
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
 	"github.com/babelcloud/gbox/packages/api-server/internal/file/service"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
 	"github.com/emicklei/go-restful/v3"
@@ -15,16 +18,46 @@ import (
 
 // FileHandler handles file operations for the share directory
 type FileHandler struct {
-	service service.FileService
+	service   service.FileService
+	boxScoped bool
 }
 
 // NewFileHandler creates a new FileHandler
 func NewFileHandler(service service.FileService) *FileHandler {
 	return &FileHandler{
-		service: service,
+		service:   service,
+		boxScoped: config.GetInstance().File.BoxScoped,
 	}
 }
 
+// scopeBox returns the box a request is confined to in box-scoped mode: the
+// box its API key is bound to. Clients cannot name the box themselves, so a
+// request without a key bound to a box is not allowed any file access.
+func (h *FileHandler) scopeBox(req *restful.Request) (string, bool) {
+	boxID := common.APIKeyBox(req.Request.Context())
+	return boxID, boxID != ""
+}
+
+// scopePath confines a cleaned share path to the requesting box's
+// subdirectory when box-scoped mode is enabled
+func (h *FileHandler) scopePath(req *restful.Request, cleanPath string) (string, bool) {
+	if !h.boxScoped {
+		return cleanPath, true
+	}
+	boxID, ok := h.scopeBox(req)
+	if !ok {
+		return "", false
+	}
+	// cleanPath is already rooted and cleaned, so it cannot climb out of the box directory
+	return "/" + boxID + cleanPath, true
+}
+
+// replyBoxScopeForbidden rejects a request reaching outside the box its API
+// key is bound to
+func replyBoxScopeForbidden(resp *restful.Response) {
+	replyFileError(resp, http.StatusForbidden, "FORBIDDEN", "Box-scoped file access requires an API key bound to the box")
+}
+
 // HeadFile handles HEAD requests to get file metadata
 func (h *FileHandler) HeadFile(req *restful.Request, resp *restful.Response) {
 	path := req.PathParameter("path")
@@ -34,9 +67,12 @@ func (h *FileHandler) HeadFile(req *restful.Request, resp *restful.Response) {
 	}
 
 	// Clean and validate the path
-	cleanPath := filepath.Clean(path)
-	if !strings.HasPrefix(cleanPath, "/") {
-		cleanPath = "/" + cleanPath
+	// Root the path before cleaning so ".." segments cannot climb above it
+	cleanPath := filepath.Clean("/" + path)
+	cleanPath, ok := h.scopePath(req, cleanPath)
+	if !ok {
+		replyBoxScopeForbidden(resp)
+		return
 	}
 
 	// Get file metadata
@@ -70,9 +106,12 @@ func (h *FileHandler) GetFile(req *restful.Request, resp *restful.Response) {
 	}
 
 	// Clean and validate the path
-	cleanPath := filepath.Clean(path)
-	if !strings.HasPrefix(cleanPath, "/") {
-		cleanPath = "/" + cleanPath
+	// Root the path before cleaning so ".." segments cannot climb above it
+	cleanPath := filepath.Clean("/" + path)
+	cleanPath, ok := h.scopePath(req, cleanPath)
+	if !ok {
+		replyBoxScopeForbidden(resp)
+		return
 	}
 
 	// Get file content
@@ -94,22 +133,154 @@ func (h *FileHandler) GetFile(req *restful.Request, resp *restful.Response) {
 	}
 }
 
-// HandleFileOperation handles file operations like reclaim and share
+// MakeDirectory handles POST requests to create a directory in the share area
+func (h *FileHandler) MakeDirectory(req *restful.Request, resp *restful.Response) {
+	path := req.QueryParameter("path")
+	if path == "" {
+		replyFileError(resp, http.StatusBadRequest, "INVALID_REQUEST", "Path is required")
+		return
+	}
+
+	// Root the path before cleaning so ".." segments cannot climb above it
+	cleanPath := filepath.Clean("/" + path)
+	cleanPath, ok := h.scopePath(req, cleanPath)
+	if !ok {
+		replyBoxScopeForbidden(resp)
+		return
+	}
+
+	stat, err := h.service.MakeDirectory(req.Request.Context(), cleanPath)
+	if err != nil {
+		replyFileServiceError(resp, "Error creating directory", err)
+		return
+	}
+
+	resp.WriteHeaderAndJson(http.StatusCreated, stat, restful.MIME_JSON)
+}
+
+// GetUsage handles GET requests reporting the disk usage of the share directory
+func (h *FileHandler) GetUsage(req *restful.Request, resp *restful.Response) {
+	boxID := req.QueryParameter("boxId")
+	if h.boxScoped {
+		// Box-scoped callers only see their own usage
+		scoped, ok := h.scopePath(req, "/")
+		if !ok {
+			replyBoxScopeForbidden(resp)
+			return
+		}
+		boxID = strings.Trim(scoped, "/")
+	}
+
+	usage, err := h.service.Usage(req.Request.Context(), boxID, req.QueryParameter("refresh") == "true")
+	if err != nil {
+		replyFileError(resp, http.StatusInternalServerError, "INTERNAL_ERROR", fmt.Sprintf("Error computing share usage: %v", err))
+		return
+	}
+
+	resp.WriteAsJson(usage)
+}
+
+// DeleteFile handles DELETE requests to remove a file or directory
+func (h *FileHandler) DeleteFile(req *restful.Request, resp *restful.Response) {
+	cleanPath, ok := h.modifiablePath(req, resp, req.PathParameter("path"))
+	if !ok {
+		return
+	}
+	recursive := req.QueryParameter("recursive") == "true"
+
+	result, err := h.service.DeleteFile(req.Request.Context(), cleanPath, recursive)
+	if err != nil {
+		replyFileServiceError(resp, "Error deleting file", err)
+		return
+	}
+
+	resp.WriteAsJson(result)
+}
+
+// MoveFile handles POST requests to move a file or directory within the share area
+func (h *FileHandler) MoveFile(req *restful.Request, resp *restful.Response) {
+	var moveReq model.FileMoveParams
+	if err := req.ReadEntity(&moveReq); err != nil {
+		replyFileError(resp, http.StatusBadRequest, "INVALID_REQUEST", fmt.Sprintf("Error reading request body: %v", err))
+		return
+	}
+
+	src, ok := h.modifiablePath(req, resp, moveReq.Src)
+	if !ok {
+		return
+	}
+	dst, ok := h.modifiablePath(req, resp, moveReq.Dst)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.MoveFile(req.Request.Context(), src, dst)
+	if err != nil {
+		replyFileServiceError(resp, "Error moving file", err)
+		return
+	}
+
+	resp.WriteAsJson(result)
+}
+
+// modifiablePath validates a path for operations that change the share area.
+// Unlike the read endpoints, which clamp ".." at the root, paths that try to
+// climb out of the share root are rejected outright. The error response has
+// already been written when ok is false.
+func (h *FileHandler) modifiablePath(req *restful.Request, resp *restful.Response, path string) (string, bool) {
+	if path == "" {
+		replyFileError(resp, http.StatusBadRequest, "INVALID_REQUEST", "Path is required")
+		return "", false
+	}
+	rel := filepath.Clean(strings.TrimLeft(path, "/"))
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		replyFileError(resp, http.StatusBadRequest, "INVALID_REQUEST", fmt.Sprintf("Path %q escapes the share directory", path))
+		return "", false
+	}
+
+	cleanPath, ok := h.scopePath(req, filepath.Clean("/"+rel))
+	if !ok {
+		replyBoxScopeForbidden(resp)
+		return "", false
+	}
+	return cleanPath, true
+}
+
+// HandleFileOperation dispatches the POST /files request to the handler of
+// its operation
 func (h *FileHandler) HandleFileOperation(req *restful.Request, resp *restful.Response) {
 	var operationReq model.FileOperationParams
 	if err := req.ReadEntity(&operationReq); err != nil {
 		replyFileError(resp, http.StatusBadRequest, "INVALID_REQUEST", fmt.Sprintf("Error reading request body: %v", err))
 		return
 	}
+	if h.boxScoped {
+		boxID, ok := h.scopeBox(req)
+		// Reclaiming spans every box, and share and write only reach the caller's own box
+		if !ok || operationReq.Operation == model.FileOperationReclaim ||
+			(operationReq.BoxID != "" && operationReq.BoxID != boxID) {
+			replyBoxScopeForbidden(resp)
+			return
+		}
+		operationReq.BoxID = boxID
+	}
 	switch operationReq.Operation {
-	case "reclaim":
+	case model.FileOperationReclaim:
 		h.ReclaimFiles(req, resp)
-	case "share":
-		h.ShareFile(req, resp, operationReq)
-	case "write":
-		h.WriteFile(req, resp, operationReq)
+	case model.FileOperationShare:
+		h.ShareFile(req, resp, model.FileShareParams{BoxID: operationReq.BoxID, Path: operationReq.Path})
+	case model.FileOperationWrite:
+		h.WriteFile(req, resp, model.FileWriteParams{BoxID: operationReq.BoxID, Path: operationReq.Path, Content: operationReq.Content})
 	default:
-		replyFileError(resp, http.StatusBadRequest, "INVALID_REQUEST", "Invalid operation")
+		valid := make([]string, len(model.FileOperations))
+		for i, op := range model.FileOperations {
+			valid[i] = string(op)
+		}
+		message := fmt.Sprintf("Invalid operation %q, valid operations are: %s", operationReq.Operation, strings.Join(valid, ", "))
+		if operationReq.Operation == "" {
+			message = "Operation is required, valid operations are: " + strings.Join(valid, ", ")
+		}
+		replyFileError(resp, http.StatusBadRequest, "INVALID_OPERATION", message)
 	}
 }
 
@@ -125,8 +296,7 @@ func (h *FileHandler) ReclaimFiles(req *restful.Request, resp *restful.Response)
 }
 
 // ShareFile handles sharing a file from a box to the share directory
-func (h *FileHandler) ShareFile(req *restful.Request, resp *restful.Response, shareReq model.FileOperationParams) {
-	fmt.Println("ShareFile shareReq", shareReq)
+func (h *FileHandler) ShareFile(req *restful.Request, resp *restful.Response, shareReq model.FileShareParams) {
 	if shareReq.BoxID == "" || shareReq.Path == "" {
 		replyFileError(resp, http.StatusBadRequest, "INVALID_REQUEST", "Box ID and path are required")
 		return
@@ -141,8 +311,8 @@ func (h *FileHandler) ShareFile(req *restful.Request, resp *restful.Response, sh
 	resp.WriteAsJson(response)
 }
 
-// WriteFile handles PUT requests to write or overwrite file content
-func (h *FileHandler) WriteFile(req *restful.Request, resp *restful.Response, writeReq model.FileOperationParams) {
+// WriteFile handles writing or overwriting file content
+func (h *FileHandler) WriteFile(req *restful.Request, resp *restful.Response, writeReq model.FileWriteParams) {
 	if writeReq.BoxID == "" || writeReq.Path == "" {
 		replyFileError(resp, http.StatusBadRequest, "INVALID_REQUEST", "Box ID and path are required")
 		return
@@ -158,6 +328,20 @@ func (h *FileHandler) WriteFile(req *restful.Request, resp *restful.Response, wr
 	resp.WriteAsJson(response)
 }
 
+// replyFileServiceError maps file service errors to structured error responses
+func replyFileServiceError(resp *restful.Response, prefix string, err error) {
+	switch {
+	case errors.Is(err, service.ErrPathNotFound):
+		replyFileError(resp, http.StatusNotFound, "NOT_FOUND", err.Error())
+	case errors.Is(err, service.ErrPathExists), errors.Is(err, service.ErrDirectoryNotEmpty):
+		replyFileError(resp, http.StatusConflict, "CONFLICT", err.Error())
+	case errors.Is(err, service.ErrPathOutsideShare):
+		replyFileError(resp, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+	default:
+		replyFileError(resp, http.StatusInternalServerError, "INTERNAL_ERROR", fmt.Sprintf("%s: %v", prefix, err))
+	}
+}
+
 // replyFileError writes a structured error response
 func replyFileError(resp *restful.Response, statusCode int, code, message string) {
 	resp.WriteHeader(statusCode)
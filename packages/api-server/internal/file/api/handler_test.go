@@ -0,0 +1,328 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+	"github.com/babelcloud/gbox/packages/api-server/internal/common"
+	"github.com/babelcloud/gbox/packages/api-server/internal/file/service"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+)
+
+func TestBoxScopedFileAccess(t *testing.T) {
+	home := t.TempDir()
+	share := filepath.Join(home, "share")
+	t.Setenv("HOME", home)
+	t.Setenv("GBOX_HOME", home)
+	t.Setenv("GBOX_SHARE", share)
+	t.Setenv("GBOX_SHARE_BOX_SCOPED", "true")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(share, "box-a"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(share, "box-b"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(share, "box-a", "a.txt"), []byte("from a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(share, "box-b", "secret.txt"), []byte("from b"), 0644))
+
+	fileSvc, err := service.New(nil)
+	require.NoError(t, err)
+
+	ws := new(restful.WebService)
+	ws.Path("/api/v1").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+	fileHandler := NewFileHandler(*fileSvc)
+	ws.Route(ws.GET("/files/{path:*}").To(fileHandler.GetFile))
+	ws.Route(ws.POST("/files").To(fileHandler.HandleFileOperation))
+	container := restful.NewContainer()
+	container.Add(ws)
+	// The box scope comes from the key that authenticated the request
+	handler := common.RequireAPIKey(container, []config.APIKey{
+		{Key: "key-a", Owner: "alice", Box: "box-a"},
+		{Key: "key-admin", Owner: "alice"},
+	})
+
+	serve := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(common.APIKeyHeader, key)
+		if body != "" {
+			req.Header.Set("Content-Type", restful.MIME_JSON)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Box A can read its own files
+	rec := serve(http.MethodGet, "/api/v1/files/a.txt", "key-a", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, _ := io.ReadAll(rec.Body)
+	assert.Equal(t, "from a", string(body))
+
+	// Box A cannot reach box B's files, by name or by traversal
+	for _, path := range []string{
+		"/api/v1/files/box-b/secret.txt",
+		"/api/v1/files/../box-b/secret.txt",
+		"/api/v1/files/%2E%2E/box-b/secret.txt",
+	} {
+		rec = serve(http.MethodGet, path, "key-a", "")
+		assert.NotEqual(t, http.StatusOK, rec.Code, "path %s leaked box B's file", path)
+		assert.NotContains(t, rec.Body.String(), "from b")
+	}
+
+	// Naming another box in the request does not change the scope
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/files/secret.txt?boxId=box-b", nil)
+	req.Header.Set(common.APIKeyHeader, "key-a")
+	req.Header.Set("X-Gbox-Box-Id", "box-b")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.NotEqual(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "from b")
+
+	// Operations on another box, or on every box, are rejected
+	for _, op := range []string{
+		`{"operation":"write","boxId":"box-b","path":"/tmp/x","content":"pwned"}`,
+		`{"operation":"share","boxId":"box-b","path":"/etc/passwd"}`,
+		`{"operation":"reclaim"}`,
+	} {
+		rec = serve(http.MethodPost, "/api/v1/files", "key-a", op)
+		assert.Equal(t, http.StatusForbidden, rec.Code, op)
+	}
+
+	// Keys not bound to a box have no file access
+	rec = serve(http.MethodGet, "/api/v1/files/box-b/secret.txt", "key-admin", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = serve(http.MethodPost, "/api/v1/files", "key-admin", `{"operation":"write","boxId":"box-b","path":"/tmp/x","content":"pwned"}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+// newFileTestContainer serves the registered file routes without box scoping
+// and returns the share root they operate on
+func newFileTestContainer(t *testing.T) (*restful.Container, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("GBOX_HOME", home)
+	t.Setenv("GBOX_SHARE", filepath.Join(home, "share"))
+
+	fileSvc, err := service.New(nil)
+	require.NoError(t, err)
+	// The config is a process-wide singleton, so use whichever share root it settled on
+	share := config.GetInstance().File.Share
+
+	ws := new(restful.WebService)
+	ws.Path("/api/v1").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+	RegisterRoutes(ws, &FileHandler{service: *fileSvc})
+	container := restful.NewContainer()
+	container.Add(ws)
+	return container, share
+}
+
+func serveFileRequest(container *restful.Container, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", restful.MIME_JSON)
+	}
+	rec := httptest.NewRecorder()
+	container.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMakeDirectory(t *testing.T) {
+	container, share := newFileTestContainer(t)
+
+	mkdir := func(path string) *httptest.ResponseRecorder {
+		return serveFileRequest(container, http.MethodPost, "/api/v1/files/mkdir?path="+path, "")
+	}
+
+	rec := mkdir("/mkdir-test/a/b/c")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var stat model.FileStat
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stat))
+	assert.Equal(t, model.FileTypeDirectory, stat.Type)
+	assert.Equal(t, "/mkdir-test/a/b/c", stat.Path)
+
+	info, err := os.Stat(filepath.Join(share, "mkdir-test", "a", "b", "c"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	// Compare against a directory made the same way so the process umask is accounted for
+	ref := filepath.Join(t.TempDir(), "ref")
+	require.NoError(t, os.Mkdir(ref, 0755))
+	refInfo, err := os.Stat(ref)
+	require.NoError(t, err)
+	assert.Equal(t, refInfo.Mode().Perm(), info.Mode().Perm())
+	assert.Equal(t, info.Mode().String(), stat.Mode)
+
+	// Traversal stays inside the share root
+	rec = mkdir("../../mkdir-escape")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.DirExists(t, filepath.Join(share, "mkdir-escape"))
+	assert.NoDirExists(t, filepath.Join(filepath.Dir(share), "mkdir-escape"))
+
+	rec = mkdir("")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestDeleteFile(t *testing.T) {
+	container, share := newFileTestContainer(t)
+	root := filepath.Join(share, "delete-test")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "dir", "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file.txt"), []byte("x"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "nested", "inner.txt"), []byte("y"), 0644))
+
+	rec := serveFileRequest(container, http.MethodDelete, "/api/v1/files/delete-test/file.txt", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NoFileExists(t, filepath.Join(root, "file.txt"))
+
+	// Non-empty directories need the recursive flag
+	rec = serveFileRequest(container, http.MethodDelete, "/api/v1/files/delete-test/dir", "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.DirExists(t, filepath.Join(root, "dir"))
+
+	rec = serveFileRequest(container, http.MethodDelete, "/api/v1/files/delete-test/dir?recursive=true", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NoDirExists(t, filepath.Join(root, "dir"))
+
+	rec = serveFileRequest(container, http.MethodDelete, "/api/v1/files/delete-test/missing", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestMoveFile(t *testing.T) {
+	container, share := newFileTestContainer(t)
+	root := filepath.Join(share, "move-test")
+	require.NoError(t, os.MkdirAll(root, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src.txt"), []byte("payload"), 0644))
+
+	rec := serveFileRequest(container, http.MethodPost, "/api/v1/files/move",
+		`{"src":"/move-test/src.txt","dst":"/move-test/sub/dst.txt"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var result model.FileOperationResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.True(t, result.Success)
+	require.NotNil(t, result.Stat)
+	assert.Equal(t, "/move-test/sub/dst.txt", result.Stat.Path)
+
+	assert.NoFileExists(t, filepath.Join(root, "src.txt"))
+	content, err := os.ReadFile(filepath.Join(root, "sub", "dst.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(content))
+
+	// Existing destinations are not overwritten
+	require.NoError(t, os.WriteFile(filepath.Join(root, "other.txt"), []byte("other"), 0644))
+	rec = serveFileRequest(container, http.MethodPost, "/api/v1/files/move",
+		`{"src":"/move-test/other.txt","dst":"/move-test/sub/dst.txt"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestModifyingFilesRejectsTraversal(t *testing.T) {
+	container, share := newFileTestContainer(t)
+	outside := filepath.Join(filepath.Dir(share), "outside.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("keep"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(share, "traversal-test"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(share, "traversal-test", "a.txt"), []byte("a"), 0644))
+
+	rec := serveFileRequest(container, http.MethodDelete, "/api/v1/files/traversal-test/%2E%2E/%2E%2E/outside.txt", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	rec = serveFileRequest(container, http.MethodPost, "/api/v1/files/move",
+		`{"src":"/traversal-test/a.txt","dst":"../outside-moved.txt"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	// A symlink pointing out of the share root cannot be used as a way out either
+	require.NoError(t, os.Symlink(filepath.Dir(share), filepath.Join(share, "traversal-test", "link")))
+	rec = serveFileRequest(container, http.MethodDelete, "/api/v1/files/traversal-test/link/outside.txt", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	rec = serveFileRequest(container, http.MethodDelete, "/api/v1/files/", "")
+	assert.NotEqual(t, http.StatusOK, rec.Code)
+
+	assert.FileExists(t, outside)
+	assert.FileExists(t, filepath.Join(share, "traversal-test", "a.txt"))
+}
+
+func TestHandleFileOperation(t *testing.T) {
+	_, share := newFileTestContainer(t)
+	fileSvc, err := service.New(nil)
+	require.NoError(t, err)
+
+	ws := new(restful.WebService)
+	ws.Path("/api/v1").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+	ws.Route(ws.POST("/files").To((&FileHandler{service: *fileSvc}).HandleFileOperation))
+	container := restful.NewContainer()
+	container.Add(ws)
+
+	operation := func(body string) *httptest.ResponseRecorder {
+		return serveFileRequest(container, http.MethodPost, "/api/v1/files", body)
+	}
+
+	t.Run("share", func(t *testing.T) {
+		// A file already in the box's share directory is shared without a box round trip
+		require.NoError(t, os.MkdirAll(filepath.Join(share, "box-op"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(share, "box-op", "report.txt"), []byte("done"), 0644))
+
+		rec := operation(`{"operation":"share","boxId":"box-op","path":"/box-op/var/gbox/share/report.txt"}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var result model.FileShareResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.True(t, result.Success)
+		require.Len(t, result.FileList, 1)
+		assert.Equal(t, "report.txt", result.FileList[0].Name)
+
+		rec = operation(`{"operation":"share","boxId":"box-op"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("reclaim", func(t *testing.T) {
+		stale := filepath.Join(share, "reclaim-op", "stale.txt")
+		fresh := filepath.Join(share, "reclaim-op", "fresh.txt")
+		require.NoError(t, os.MkdirAll(filepath.Dir(stale), 0755))
+		require.NoError(t, os.WriteFile(stale, []byte("old"), 0644))
+		require.NoError(t, os.WriteFile(fresh, []byte("new"), 0644))
+		old := time.Now().Add(-30 * 24 * time.Hour)
+		require.NoError(t, os.Chtimes(stale, old, old))
+
+		rec := operation(`{"operation":"reclaim"}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var result model.FileReclaimResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		assert.True(t, result.Success)
+		assert.NoFileExists(t, stale)
+		assert.FileExists(t, fresh)
+	})
+
+	t.Run("invalid operation", func(t *testing.T) {
+		for _, body := range []string{`{"operation":"copy"}`, `{}`} {
+			rec := operation(body)
+			require.Equal(t, http.StatusBadRequest, rec.Code, body)
+			var fileErr model.FileError
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &fileErr))
+			assert.Equal(t, "INVALID_OPERATION", fileErr.Code)
+			assert.Contains(t, fileErr.Message, "reclaim, share, write")
+		}
+	})
+}
+
+func TestGetUsage(t *testing.T) {
+	container, share := newFileTestContainer(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(share, "usage-box"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(share, "usage-box", "a.txt"), []byte("12345"), 0644))
+
+	rec := serveFileRequest(container, http.MethodGet, "/api/v1/files/usage?boxId=usage-box&refresh=true", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var usage model.FileUsage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &usage))
+	assert.Equal(t, int64(5), usage.TotalBytes)
+	assert.Equal(t, 1, usage.FileCount)
+	require.Len(t, usage.Boxes, 1)
+	assert.Equal(t, "usage-box", usage.Boxes[0].BoxID)
+}
@@ -1,7 +1,7 @@
 package api
 
 import (
-	// model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
 	"github.com/emicklei/go-restful/v3"
 )
 
@@ -30,8 +30,48 @@ func RegisterRoutes(ws *restful.WebService, handler *FileHandler) {
 	// ws.Route(ws.POST("/files").To(handler.HandleFileOperation).
 	// 	Doc("handle file operations like reclaim, share and write").
 	// 	Reads(model.FileOperationParams{}).
+	// 	Notes("share reads model.FileShareParams, write reads model.FileWriteParams and "+
+	// 		"reclaim takes no parameters. An unknown operation is rejected with INVALID_OPERATION.").
 	// 	Returns(200, "OK", model.FileShareResult{}).
 	// 	Returns(400, "Bad Request", model.FileError{}).
 	// 	Returns(404, "Not Found", model.FileError{}).
 	// 	Returns(500, "Internal Server Error", model.FileError{}))
+
+	ws.Route(ws.GET("/files/usage").To(handler.GetUsage).
+		Doc("report the disk usage of the share directory, in total and per box").
+		Param(ws.QueryParameter("boxId", "only report the usage of this box").DataType("string").Required(false)).
+		Param(ws.QueryParameter("refresh", "recompute instead of using the cached result").DataType("boolean").DefaultValue("false")).
+		Returns(200, "OK", model.FileUsage{}).
+		Returns(403, "Forbidden", model.FileError{}).
+		Returns(500, "Internal Server Error", model.FileError{}))
+
+	ws.Route(ws.POST("/files/mkdir").To(handler.MakeDirectory).
+		Doc("create a directory in the share area").
+		Param(ws.QueryParameter("path", "path of the directory to create").DataType("string").Required(true)).
+		AllowedMethodsWithoutContentType([]string{"POST"}).
+		Returns(201, "Created", model.FileStat{}).
+		Returns(400, "Bad Request", model.FileError{}).
+		Returns(403, "Forbidden", model.FileError{}).
+		Returns(500, "Internal Server Error", model.FileError{}))
+
+	ws.Route(ws.DELETE("/files/{path:*}").To(handler.DeleteFile).
+		Doc("delete a file or directory in the share area").
+		Param(ws.PathParameter("path", "path to the file or directory").DataType("string")).
+		Param(ws.QueryParameter("recursive", "delete non-empty directories").DataType("boolean").DefaultValue("false")).
+		Returns(200, "OK", model.FileOperationResult{}).
+		Returns(400, "Bad Request", model.FileError{}).
+		Returns(403, "Forbidden", model.FileError{}).
+		Returns(404, "Not Found", model.FileError{}).
+		Returns(409, "Conflict", model.FileError{}).
+		Returns(500, "Internal Server Error", model.FileError{}))
+
+	ws.Route(ws.POST("/files/move").To(handler.MoveFile).
+		Doc("move a file or directory within the share area").
+		Reads(model.FileMoveParams{}).
+		Returns(200, "OK", model.FileOperationResult{}).
+		Returns(400, "Bad Request", model.FileError{}).
+		Returns(403, "Forbidden", model.FileError{}).
+		Returns(404, "Not Found", model.FileError{}).
+		Returns(409, "Conflict", model.FileError{}).
+		Returns(500, "Internal Server Error", model.FileError{}))
 }
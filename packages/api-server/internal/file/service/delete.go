@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+)
+
+// DeleteFile removes a file or directory from the share directory.
+// Non-empty directories are only removed when recursive is set.
+func (s *FileService) DeleteFile(ctx context.Context, path string, recursive bool) (*model.FileOperationResult, error) {
+	cleanPath, err := s.validateAndCleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if cleanPath == "/" {
+		return nil, fmt.Errorf("%w: refusing to delete the share root", ErrPathOutsideShare)
+	}
+
+	fullPath, err := s.resolveWithinShare(cleanPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, cleanPath)
+		}
+		return nil, fmt.Errorf("error getting file info: %v", err)
+	}
+
+	if info.IsDir() && recursive {
+		err = os.RemoveAll(fullPath)
+	} else {
+		err = os.Remove(fullPath)
+	}
+	if err != nil {
+		if info.IsDir() && !recursive {
+			if entries, readErr := os.ReadDir(fullPath); readErr == nil && len(entries) > 0 {
+				return nil, fmt.Errorf("%w: %s", ErrDirectoryNotEmpty, cleanPath)
+			}
+		}
+		return nil, fmt.Errorf("error deleting %s: %v", cleanPath, err)
+	}
+
+	return &model.FileOperationResult{
+		Success: true,
+		Message: fmt.Sprintf("Deleted %s", cleanPath),
+	}, nil
+}
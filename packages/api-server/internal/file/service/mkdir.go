@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+)
+
+// MakeDirectory creates a directory, including any missing parents, in the share directory
+func (s *FileService) MakeDirectory(ctx context.Context, path string) (*model.FileStat, error) {
+	cleanPath, err := s.validateAndCleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fullPath, err := s.resolveWithinShare(cleanPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(fullPath, 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory: %v", err)
+	}
+
+	return s.HeadFile(ctx, cleanPath)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+)
+
+// MoveFile moves a file or directory to a new path within the share directory.
+// The destination must not already exist.
+func (s *FileService) MoveFile(ctx context.Context, src, dst string) (*model.FileOperationResult, error) {
+	cleanSrc, err := s.validateAndCleanPath(src)
+	if err != nil {
+		return nil, err
+	}
+	cleanDst, err := s.validateAndCleanPath(dst)
+	if err != nil {
+		return nil, err
+	}
+	if cleanSrc == "/" || cleanDst == "/" {
+		return nil, fmt.Errorf("%w: the share root cannot be moved or replaced", ErrPathOutsideShare)
+	}
+
+	srcPath, err := s.resolveWithinShare(cleanSrc)
+	if err != nil {
+		return nil, err
+	}
+	dstPath, err := s.resolveWithinShare(cleanDst)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Lstat(srcPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, cleanSrc)
+		}
+		return nil, fmt.Errorf("error getting file info: %v", err)
+	}
+	if _, err := os.Lstat(dstPath); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPathExists, cleanDst)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory: %v", err)
+	}
+	if err := os.Rename(srcPath, dstPath); err != nil {
+		return nil, fmt.Errorf("error moving %s to %s: %v", cleanSrc, cleanDst, err)
+	}
+
+	stat, err := s.HeadFile(ctx, cleanDst)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.FileOperationResult{
+		Success: true,
+		Message: fmt.Sprintf("Moved %s to %s", cleanSrc, cleanDst),
+		Stat:    stat,
+	}, nil
+}
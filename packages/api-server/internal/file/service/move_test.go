@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that a move cannot land outside the share directory through a
+// symlinked ancestor, even one below missing directories
+func TestMoveFileDoesNotFollowSymlinksOutOfShare(t *testing.T) {
+	share, outside := t.TempDir(), t.TempDir()
+	s := &FileService{shareDir: share}
+	writeSized(t, filepath.Join(share, "box-a", "f"), 10)
+	require.NoError(t, os.Symlink(outside, filepath.Join(share, "box-a", "link")))
+
+	for _, dst := range []string{"/box-a/link/f", "/box-a/link/sub/f", "/box-a/link/sub/deeper/f"} {
+		_, err := s.MoveFile(context.Background(), "/box-a/f", dst)
+		assert.ErrorIs(t, err, ErrPathOutsideShare, dst)
+	}
+	entries, err := os.ReadDir(outside)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "nothing may be created outside the share directory")
+	assert.FileExists(t, filepath.Join(share, "box-a", "f"))
+
+	// Missing directories inside the share directory are still created
+	_, err = s.MoveFile(context.Background(), "/box-a/f", "/box-a/new/sub/f")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(share, "box-a", "new", "sub", "f"))
+}
+
+// Test that mkdir cannot create directories outside the share directory
+// through a symlinked ancestor
+func TestMakeDirectoryDoesNotFollowSymlinksOutOfShare(t *testing.T) {
+	share, outside := t.TempDir(), t.TempDir()
+	s := &FileService{shareDir: share}
+	require.NoError(t, os.MkdirAll(filepath.Join(share, "box-a"), 0755))
+	require.NoError(t, os.Symlink(outside, filepath.Join(share, "box-a", "link")))
+
+	for _, dir := range []string{"/box-a/link/x", "/box-a/link/x/y"} {
+		_, err := s.MakeDirectory(context.Background(), dir)
+		assert.ErrorIs(t, err, ErrPathOutsideShare, dir)
+	}
+	entries, err := os.ReadDir(outside)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "nothing may be created outside the share directory")
+
+	_, err = s.MakeDirectory(context.Background(), "/box-a/x/y")
+	require.NoError(t, err)
+	assert.DirExists(t, filepath.Join(share, "box-a", "x", "y"))
+}
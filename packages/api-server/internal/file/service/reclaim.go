@@ -7,26 +7,42 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/babelcloud/gbox/packages/api-server/config"
 	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
 )
 
 // ReclaimFiles removes files that haven't been accessed for more than 14 days
 func (s *FileService) ReclaimFiles(ctx context.Context) (*model.FileShareResult, error) {
-	cutoffTime := time.Now().Add(-defaultFileReclaimInterval)
+	return reclaimDir(s.shareDir, time.Now().Add(-defaultFileReclaimInterval))
+}
+
+// ReclaimShareTier removes the files of a share tier that haven't been
+// modified for longer than the tier's ReclaimAfter. Tiers without one are
+// never reclaimed.
+func (s *FileService) ReclaimShareTier(ctx context.Context, tier config.ShareTier) (*model.FileShareResult, error) {
+	if tier.ReclaimAfter <= 0 {
+		return &model.FileShareResult{Success: true, Message: "Share tier is not reclaimed"}, nil
+	}
+	return reclaimDir(tier.Path, time.Now().Add(-tier.ReclaimAfter))
+}
+
+// reclaimDir removes the files below root modified before cutoffTime, and
+// the directories left empty
+func reclaimDir(root string, cutoffTime time.Time) (*model.FileShareResult, error) {
 	var reclaimedFiles []string
 	var fileStats []model.FileStat
 	var errors []string
 	emptyDirs := make(map[string]bool) // Track empty directories
 
 	// Walk through the share directory
-	err := filepath.Walk(s.shareDir, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("Error accessing path %s: %v", path, err))
 			return nil
 		}
 
 		// Skip the share directory itself
-		if path == s.shareDir {
+		if path == root {
 			return nil
 		}
 
@@ -77,7 +93,7 @@ func (s *FileService) ReclaimFiles(ctx context.Context) (*model.FileShareResult,
 	// Check and remove empty directories
 	for dir := range emptyDirs {
 		// Skip the share directory itself
-		if dir == s.shareDir {
+		if dir == root {
 			continue
 		}
 
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ScreenshotDir returns the directory holding screenshots of a box
+func (s *FileService) ScreenshotDir(boxID string) string {
+	return filepath.Join(s.shareDir, boxID, s.screenshots.Dir)
+}
+
+// PruneScreenshots enforces the screenshot retention policy for every box,
+// removing screenshots beyond the configured count or age. It returns the
+// paths of the removed files.
+func (s *FileService) PruneScreenshots(ctx context.Context) ([]string, error) {
+	if s.screenshots.MaxCount <= 0 && s.screenshots.MaxAge <= 0 {
+		return nil, nil
+	}
+
+	boxDirs, err := os.ReadDir(s.shareDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read share directory: %v", err)
+	}
+
+	var removed []string
+	for _, boxDir := range boxDirs {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+		if !boxDir.IsDir() {
+			continue
+		}
+		pruned, err := s.pruneScreenshotDir(s.ScreenshotDir(boxDir.Name()))
+		removed = append(removed, pruned...)
+		if err != nil {
+			log.Error("Error pruning screenshots of box %s: %v", boxDir.Name(), err)
+		}
+	}
+	return removed, nil
+}
+
+// pruneScreenshotDir removes the screenshots in dir that fall outside the
+// retention policy, keeping the newest ones.
+func (s *FileService) pruneScreenshotDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type screenshot struct {
+		path    string
+		modTime time.Time
+	}
+	screenshots := make([]screenshot, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		screenshots = append(screenshots, screenshot{path: filepath.Join(dir, entry.Name()), modTime: info.ModTime()})
+	}
+	sort.Slice(screenshots, func(i, j int) bool {
+		return screenshots[i].modTime.After(screenshots[j].modTime)
+	})
+
+	cutoff := time.Now().Add(-s.screenshots.MaxAge)
+	var removed []string
+	for i, shot := range screenshots {
+		tooMany := s.screenshots.MaxCount > 0 && i >= s.screenshots.MaxCount
+		tooOld := s.screenshots.MaxAge > 0 && shot.modTime.Before(cutoff)
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(shot.path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, shot.path)
+	}
+	return removed, nil
+}
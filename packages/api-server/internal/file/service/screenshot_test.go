@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babelcloud/gbox/packages/api-server/config"
+)
+
+// writeScreenshots creates count screenshots for a box, the first being the
+// oldest, and returns their paths.
+func writeScreenshots(t *testing.T, s *FileService, boxID string, count int, newest time.Time) []string {
+	t.Helper()
+	dir := s.ScreenshotDir(boxID)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	paths := make([]string, count)
+	for i := 0; i < count; i++ {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("shot-%d.png", i))
+		require.NoError(t, os.WriteFile(paths[i], []byte("png"), 0644))
+		modTime := newest.Add(-time.Duration(count-1-i) * time.Hour)
+		require.NoError(t, os.Chtimes(paths[i], modTime, modTime))
+	}
+	return paths
+}
+
+func TestPruneScreenshotsKeepsNewest(t *testing.T) {
+	s := &FileService{
+		shareDir:    t.TempDir(),
+		screenshots: config.ScreenshotConfig{Dir: "shots", MaxCount: 3},
+	}
+	boxA := writeScreenshots(t, s, "box-a", 5, time.Now())
+	boxB := writeScreenshots(t, s, "box-b", 2, time.Now())
+
+	removed, err := s.PruneScreenshots(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, boxA[:2], removed)
+
+	for _, path := range boxA[:2] {
+		assert.NoFileExists(t, path)
+	}
+	for _, path := range append(boxA[2:], boxB...) {
+		assert.FileExists(t, path)
+	}
+}
+
+func TestPruneScreenshotsRemovesExpired(t *testing.T) {
+	s := &FileService{
+		shareDir:    t.TempDir(),
+		screenshots: config.ScreenshotConfig{Dir: "shots", MaxAge: 90 * time.Minute},
+	}
+	paths := writeScreenshots(t, s, "box-a", 4, time.Now())
+	// Files outside the screenshot directory are left alone
+	other := filepath.Join(s.shareDir, "box-a", "report.txt")
+	require.NoError(t, os.WriteFile(other, []byte("keep"), 0644))
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(other, old, old))
+
+	removed, err := s.PruneScreenshots(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, paths[:2], removed)
+	assert.FileExists(t, paths[2])
+	assert.FileExists(t, paths[3])
+	assert.FileExists(t, other)
+}
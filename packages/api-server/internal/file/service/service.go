@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,10 +17,23 @@ const (
 	defaultFileReclaimInterval = 14 * 24 * time.Hour // 14 days
 )
 
+var (
+	// ErrPathNotFound is returned when the requested path does not exist
+	ErrPathNotFound = errors.New("path not found")
+	// ErrPathExists is returned when a destination path is already taken
+	ErrPathExists = errors.New("path already exists")
+	// ErrPathOutsideShare is returned when a path resolves outside the share directory
+	ErrPathOutsideShare = errors.New("path is outside the share directory")
+	// ErrDirectoryNotEmpty is returned when deleting a non-empty directory without recursion
+	ErrDirectoryNotEmpty = errors.New("directory is not empty")
+)
+
 // FileService handles file operations for the share directory
 type FileService struct {
-	shareDir string
-	boxSvc   boxService.BoxService
+	shareDir    string
+	screenshots config.ScreenshotConfig
+	boxSvc      boxService.BoxService
+	usage       *usageCache
 }
 
 // New creates a new Service
@@ -35,8 +49,10 @@ func New(boxSvc boxService.BoxService) (*FileService, error) {
 	log.Info("File service initialized with share directory: %s", shareDir)
 
 	return &FileService{
-		shareDir: shareDir,
-		boxSvc:   boxSvc,
+		shareDir:    shareDir,
+		screenshots: cfg.File.Screenshot,
+		boxSvc:      boxSvc,
+		usage:       &usageCache{ttl: defaultUsageCacheTTL},
 	}, nil
 }
 
@@ -59,3 +75,40 @@ func (s *FileService) validateAndCleanPath(path string) (string, error) {
 func (s *FileService) getFullPath(cleanPath string) string {
 	return filepath.Join(s.shareDir, cleanPath)
 }
+
+// resolveWithinShare returns the full path for a cleaned share path, making
+// sure neither it nor any symlinked ancestor directory points outside the
+// share directory. Ancestors that do not exist yet are checked through the
+// nearest one that does, so they can then be created safely. It is used by
+// operations that modify the share directory.
+func (s *FileService) resolveWithinShare(cleanPath string) (string, error) {
+	fullPath := s.getFullPath(cleanPath)
+
+	root, err := filepath.EvalSymlinks(s.shareDir)
+	if err != nil {
+		return "", fmt.Errorf("error resolving share directory: %v", err)
+	}
+
+	// Walk up to the nearest existing ancestor and resolve it; the missing
+	// components below it are created as plain directories
+	existing, missing := filepath.Dir(fullPath), filepath.Base(fullPath)
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			existing = resolved
+			break
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("error resolving path: %v", err)
+		}
+		missing = filepath.Join(filepath.Base(existing), missing)
+		existing = filepath.Dir(existing)
+	}
+
+	resolved := filepath.Join(existing, missing)
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrPathOutsideShare, cleanPath)
+	}
+	return resolved, nil
+}
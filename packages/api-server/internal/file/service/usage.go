@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+)
+
+// defaultUsageCacheTTL bounds how long a share directory walk is reused
+const defaultUsageCacheTTL = 30 * time.Second
+
+// usageCache holds the last share directory walk. Walks are serialized so
+// concurrent requests on a cold cache share a single walk.
+type usageCache struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	usage *model.FileUsage
+}
+
+// Usage reports the disk space used by the share directory, broken down by
+// box subdirectory. A recent result is served from the cache unless refresh
+// is set. When boxID is not empty, only that box's usage is reported.
+func (s *FileService) Usage(ctx context.Context, boxID string, refresh bool) (*model.FileUsage, error) {
+	usage, err := s.cachedUsage(ctx, refresh)
+	if err != nil {
+		return nil, err
+	}
+	if boxID == "" {
+		return usage, nil
+	}
+
+	scoped := &model.FileUsage{Boxes: []model.BoxFileUsage{}, ComputedAt: usage.ComputedAt}
+	for _, box := range usage.Boxes {
+		if box.BoxID == boxID {
+			scoped.TotalBytes = box.Bytes
+			scoped.FileCount = box.FileCount
+			scoped.Boxes = append(scoped.Boxes, box)
+		}
+	}
+	return scoped, nil
+}
+
+func (s *FileService) cachedUsage(ctx context.Context, refresh bool) (*model.FileUsage, error) {
+	if s.usage == nil {
+		return s.walkUsage(ctx)
+	}
+
+	s.usage.mu.Lock()
+	defer s.usage.mu.Unlock()
+	if !refresh && s.usage.usage != nil && time.Since(s.usage.usage.ComputedAt) < s.usage.ttl {
+		return s.usage.usage, nil
+	}
+	usage, err := s.walkUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.usage.usage = usage
+	return usage, nil
+}
+
+// walkUsage sums the regular files of the share directory. Top-level
+// directories are box share directories; symlinks are not followed.
+func (s *FileService) walkUsage(ctx context.Context) (*model.FileUsage, error) {
+	usage := &model.FileUsage{Boxes: []model.BoxFileUsage{}, ComputedAt: time.Now()}
+	boxes := make(map[string]*model.BoxFileUsage)
+
+	err := filepath.WalkDir(s.shareDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files removed during the walk are simply not counted
+			log.Debug("Skipping %s in usage walk: %v", path, err)
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		rel, _ := filepath.Rel(s.shareDir, path)
+		top, _, nested := strings.Cut(rel, string(filepath.Separator))
+		if d.IsDir() {
+			if rel != "." && !nested {
+				boxes[top] = &model.BoxFileUsage{BoxID: top}
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		usage.TotalBytes += info.Size()
+		usage.FileCount++
+		if box, ok := boxes[top]; ok && nested {
+			box.Bytes += info.Size()
+			box.FileCount++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking share directory: %w", err)
+	}
+
+	for _, box := range boxes {
+		usage.Boxes = append(usage.Boxes, *box)
+	}
+	sort.Slice(usage.Boxes, func(i, j int) bool {
+		if usage.Boxes[i].Bytes != usage.Boxes[j].Bytes {
+			return usage.Boxes[i].Bytes > usage.Boxes[j].Bytes
+		}
+		return usage.Boxes[i].BoxID < usage.Boxes[j].BoxID
+	})
+	return usage, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+)
+
+func writeSized(t *testing.T, path string, size int) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644))
+}
+
+func TestUsageReportsTotalsAndBoxes(t *testing.T) {
+	share := t.TempDir()
+	s := &FileService{shareDir: share, usage: &usageCache{ttl: defaultUsageCacheTTL}}
+
+	writeSized(t, filepath.Join(share, "box-a", "out.txt"), 100)
+	writeSized(t, filepath.Join(share, "box-a", "logs", "run.log"), 250)
+	writeSized(t, filepath.Join(share, "box-b", "data.bin"), 1000)
+	writeSized(t, filepath.Join(share, "notes.txt"), 7)
+	require.NoError(t, os.MkdirAll(filepath.Join(share, "box-c"), 0755))
+	// Symlinks are not followed, so the linked file is not counted twice
+	require.NoError(t, os.Symlink(filepath.Join(share, "box-b", "data.bin"), filepath.Join(share, "box-a", "link")))
+
+	usage, err := s.Usage(context.Background(), "", false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1357), usage.TotalBytes)
+	assert.Equal(t, 4, usage.FileCount)
+	assert.Equal(t, []model.BoxFileUsage{
+		{BoxID: "box-b", Bytes: 1000, FileCount: 1},
+		{BoxID: "box-a", Bytes: 350, FileCount: 2},
+		{BoxID: "box-c"},
+	}, usage.Boxes)
+
+	scoped, err := s.Usage(context.Background(), "box-a", false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(350), scoped.TotalBytes)
+	assert.Equal(t, 2, scoped.FileCount)
+	assert.Len(t, scoped.Boxes, 1)
+}
+
+func TestUsageIsCached(t *testing.T) {
+	share := t.TempDir()
+	s := &FileService{shareDir: share, usage: &usageCache{ttl: defaultUsageCacheTTL}}
+	writeSized(t, filepath.Join(share, "box-a", "out.txt"), 10)
+
+	first, err := s.Usage(context.Background(), "", false)
+	require.NoError(t, err)
+	writeSized(t, filepath.Join(share, "box-a", "more.txt"), 5)
+
+	cached, err := s.Usage(context.Background(), "", false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), cached.TotalBytes, "a recent walk is reused")
+	assert.Equal(t, first.ComputedAt, cached.ComputedAt)
+
+	refreshed, err := s.Usage(context.Background(), "", true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), refreshed.TotalBytes)
+}
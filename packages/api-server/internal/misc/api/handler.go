@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 
+	"github.com/babelcloud/gbox/packages/api-server/internal/misc/model"
 	"github.com/babelcloud/gbox/packages/api-server/internal/misc/service"
 	"github.com/emicklei/go-restful/v3"
 )
@@ -24,3 +25,8 @@ func (h *MiscHandler) GetVersion(req *restful.Request, resp *restful.Response) {
 	version := h.service.GetVersion()
 	resp.WriteHeaderAndJson(http.StatusOK, version, restful.MIME_JSON)
 }
+
+// GetHealth handles GET /health request
+func (h *MiscHandler) GetHealth(req *restful.Request, resp *restful.Response) {
+	resp.WriteHeaderAndJson(http.StatusOK, model.Health{Status: "ok"}, restful.MIME_JSON)
+}
@@ -12,4 +12,9 @@ func RegisterRoutes(ws *restful.WebService, handler *MiscHandler) {
 		Doc("get server version information").
 		Returns(200, "OK", model.VersionInfo{}).
 		Returns(500, "Internal Server Error", nil))
+
+	// Health route, public even when API keys are required
+	ws.Route(ws.GET("/health").To(handler.GetHealth).
+		Doc("check that the server is up").
+		Returns(200, "OK", model.Health{}))
 }
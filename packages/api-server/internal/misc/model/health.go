@@ -0,0 +1,7 @@
+package model
+
+// Health represents the health of the server
+type Health struct {
+	// Status is "ok" while the server is serving requests
+	Status string `json:"status"`
+}
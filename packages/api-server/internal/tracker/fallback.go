@@ -0,0 +1,103 @@
+package tracker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/babelcloud/gbox/packages/api-server/pkg/logger"
+)
+
+// Backend is a store of access times that can fail, such as a persistent
+// database shared by several servers.
+type Backend interface {
+	Update(id string, at time.Time) error
+	GetLastAccessed(id string) (time.Time, bool, error)
+	Remove(id string) error
+}
+
+// FallbackAccessTracker implements AccessTracker on top of a Backend. Every
+// access is also kept in memory, and while the backend fails the in-memory
+// times are used instead, so box operations never fail because of tracking.
+type FallbackAccessTracker struct {
+	backend Backend
+	memory  *InMemoryAccessTracker
+	logger  *logger.Logger
+
+	mu       sync.Mutex
+	degraded bool
+}
+
+// NewFallbackAccessTracker creates a FallbackAccessTracker using backend.
+func NewFallbackAccessTracker(backend Backend) *FallbackAccessTracker {
+	return &FallbackAccessTracker{
+		backend: backend,
+		memory:  NewInMemoryAccessTracker(),
+		logger:  logger.New(),
+	}
+}
+
+// Update sets the last access time for the given ID to now.
+func (t *FallbackAccessTracker) Update(id string) {
+	t.memory.Update(id)
+	at, _ := t.memory.GetLastAccessed(id)
+	t.report(t.backend.Update(id, at))
+}
+
+// GetLastAccessed retrieves the last access time for the given ID from the
+// backend, or from memory while the backend is unavailable.
+func (t *FallbackAccessTracker) GetLastAccessed(id string) (time.Time, bool) {
+	ts, found, err := t.backend.GetLastAccessed(id)
+	t.report(err)
+	if err != nil {
+		return t.memory.GetLastAccessed(id)
+	}
+	return ts, found
+}
+
+// Remove deletes the tracking information for the given ID.
+func (t *FallbackAccessTracker) Remove(id string) {
+	t.memory.Remove(id)
+	t.report(t.backend.Remove(id))
+}
+
+// Snapshot returns a copy of the access times recorded by this server. The
+// backend cannot be enumerated, so boxes only accessed through other servers
+// are missing.
+func (t *FallbackAccessTracker) Snapshot() map[string]time.Time {
+	return t.memory.Snapshot()
+}
+
+// MarkWarned records that the box was warned about reclaim now. Warnings
+// are only kept in memory; after a restart a box is warned again before it
+// is reclaimed.
+func (t *FallbackAccessTracker) MarkWarned(id string) {
+	t.memory.MarkWarned(id)
+}
+
+// GetWarned returns when the box was warned about reclaim, if it has not
+// been accessed since.
+func (t *FallbackAccessTracker) GetWarned(id string) (time.Time, bool) {
+	return t.memory.GetWarned(id)
+}
+
+// Degraded reports whether the last backend call failed.
+func (t *FallbackAccessTracker) Degraded() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.degraded
+}
+
+// report records the outcome of a backend call, logging only when the
+// backend becomes unavailable or recovers to avoid a warning per access.
+func (t *FallbackAccessTracker) report(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case err != nil && !t.degraded:
+		t.degraded = true
+		t.logger.Warn("Access tracker backend unavailable, falling back to in-memory access times: %v", err)
+	case err == nil && t.degraded:
+		t.degraded = false
+		t.logger.Info("Access tracker backend recovered")
+	}
+}
@@ -9,11 +9,15 @@ import (
 type InMemoryAccessTracker struct {
 	mu          sync.RWMutex
 	accessTimes map[string]time.Time
+	warnedAt    map[string]time.Time
 }
 
 // NewInMemoryAccessTracker creates a new InMemoryAccessTracker.
 func NewInMemoryAccessTracker() *InMemoryAccessTracker {
-	return &InMemoryAccessTracker{accessTimes: make(map[string]time.Time)}
+	return &InMemoryAccessTracker{
+		accessTimes: make(map[string]time.Time),
+		warnedAt:    make(map[string]time.Time),
+	}
 }
 
 // Update sets the last access time for the given ID to now.
@@ -21,6 +25,7 @@ func (t *InMemoryAccessTracker) Update(id string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.accessTimes[id] = time.Now()
+	delete(t.warnedAt, id)
 }
 
 // GetLastAccessed retrieves the last access time for the given ID.
@@ -53,4 +58,32 @@ func (t *InMemoryAccessTracker) Remove(id string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	delete(t.accessTimes, id)
+	delete(t.warnedAt, id)
+}
+
+// Snapshot returns a copy of the last access time of every tracked box.
+func (t *InMemoryAccessTracker) Snapshot() map[string]time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	snapshot := make(map[string]time.Time, len(t.accessTimes))
+	for id, ts := range t.accessTimes {
+		snapshot[id] = ts
+	}
+	return snapshot
+}
+
+// MarkWarned records that the box was warned about reclaim now.
+func (t *InMemoryAccessTracker) MarkWarned(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.warnedAt[id] = time.Now()
+}
+
+// GetWarned returns when the box was warned about reclaim, if it has not
+// been accessed since.
+func (t *InMemoryAccessTracker) GetWarned(id string) (time.Time, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	ts, found := t.warnedAt[id]
+	return ts, found
 }
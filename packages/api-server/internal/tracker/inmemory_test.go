@@ -0,0 +1,60 @@
+package tracker
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotIsConsistentCopy(t *testing.T) {
+	tr := NewInMemoryAccessTracker()
+	for i := 0; i < 10; i++ {
+		tr.Update(fmt.Sprintf("stable-%d", i))
+	}
+
+	// Churn other boxes while snapshots are taken; run with -race
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				id := fmt.Sprintf("churn-%d-%d", w, i%50)
+				tr.Update(id)
+				tr.Remove(id)
+			}
+		}(w)
+	}
+
+	for i := 0; i < 200; i++ {
+		snapshot := tr.Snapshot()
+		for j := 0; j < 10; j++ {
+			_, ok := snapshot[fmt.Sprintf("stable-%d", j)]
+			require.True(t, ok, "boxes not touched by the churn are always present")
+		}
+		// The copy is private: changing it does not affect the tracker
+		snapshot["stable-0"] = time.Time{}
+		delete(snapshot, "stable-1")
+	}
+	close(stop)
+	wg.Wait()
+
+	snapshot := tr.Snapshot()
+	assert.Len(t, snapshot, 10, "removed boxes are gone")
+	assert.False(t, snapshot["stable-0"].IsZero())
+
+	later := time.Now()
+	tr.Update("stable-2")
+	assert.True(t, tr.Snapshot()["stable-2"].After(later) || tr.Snapshot()["stable-2"].Equal(later))
+	assert.True(t, snapshot["stable-2"].Before(later) || snapshot["stable-2"].Equal(later), "an earlier snapshot does not change")
+}
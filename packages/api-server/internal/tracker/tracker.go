@@ -7,4 +7,12 @@ type AccessTracker interface {
 	Update(id string)
 	GetLastAccessed(id string) (time.Time, bool)
 	Remove(id string)
+	// MarkWarned records that the box was just warned it is about to be
+	// reclaimed. The warning is cleared by its next access.
+	MarkWarned(id string)
+	// GetWarned returns when the box was warned, unless it was accessed since.
+	GetWarned(id string) (time.Time, bool)
+	// Snapshot returns a copy of the last access time of every tracked box,
+	// safe to iterate while accesses keep being recorded.
+	Snapshot() map[string]time.Time
 }
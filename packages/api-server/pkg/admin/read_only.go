@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// ReadOnlyStatus reports whether the server is in read-only maintenance
+// mode, in which mutating requests are rejected
+type ReadOnlyStatus struct {
+	ReadOnly bool       `json:"readOnly"`
+	Since    *time.Time `json:"since,omitempty"` // When read-only mode was enabled
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// ReclaimStatus reports whether scheduled box reclamation is paused
+type ReclaimStatus struct {
+	Paused   bool       `json:"paused"`
+	PausedAt *time.Time `json:"pausedAt,omitempty"` // When reclamation was paused
+	// NextRun is the next scheduled reclamation. While paused the run still
+	// happens but is skipped.
+	NextRun *time.Time `json:"nextRun,omitempty"`
+}
+
+// Actions the next reclamation run would take on a box
+const (
+	ReclaimActionNone   = "none"
+	ReclaimActionWarn   = "warn"
+	ReclaimActionStop   = "stop"
+	ReclaimActionDelete = "delete"
+)
+
+// ReclaimBoxStatus reports how long a box has been idle and what the next
+// reclamation run would do with it
+type ReclaimBoxStatus struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // Box status, e.g. "running" or "exited"
+	// LastAccessed is unset for boxes the server has not tracked yet, which
+	// reclamation skips for one run
+	LastAccessed  *time.Time `json:"lastAccessed,omitempty"`
+	IdleSeconds   int64      `json:"idleSeconds"`
+	WarnedAt      *time.Time `json:"warnedAt,omitempty"` // When the box was warned about reclamation
+	PendingAction string     `json:"pendingAction"`      // "none", "warn", "stop" or "delete"
+}
+
+// ReclaimBoxesStatus is the reclamation status with the state of every box
+type ReclaimBoxesStatus struct {
+	ReclaimStatus
+	Boxes []ReclaimBoxStatus `json:"boxes"`
+}
@@ -1,8 +1,17 @@
 package model
 
+import "io"
+
 // BoxArchiveGetParams represents the request for getting an archive from a container
 type BoxArchiveGetParams struct {
 	Path string `json:"path" description:"resource in the container's filesystem to archive"`
+	// Follow keeps the archive open after the directory's current content and
+	// appends an entry each time a file under it is created or changed, until
+	// the client disconnects. The stream is a valid tar archive read entry by
+	// entry, but a file may appear several times (the last entry wins) and the
+	// end-of-archive marker is only written if the server ends the stream.
+	// Only directories in the box's share directory can be followed.
+	Follow bool `json:"follow,omitempty" description:"keep streaming files created or changed under the directory"`
 }
 
 // BoxArchiveHeadParams represents the request for getting metadata about a resource in the container's filesystem
@@ -15,7 +24,26 @@ type BoxArchiveExtractParams struct {
 	Path                 string `json:"path" description:"path to a directory in the container to extract the archive's contents into"`
 	NoOverwriteDirNonDir bool   `json:"noOverwriteDirNonDir,omitempty" description:"if true, it will be an error if unpacking would cause an existing directory to be replaced with a non-directory and vice versa"`
 	CopyUIDGID           bool   `json:"copyUIDGID,omitempty" description:"if true, it will copy UID/GID maps to the dest file or dir"`
+	Transactional        bool   `json:"transactional,omitempty" description:"if true, extract into a staging copy of the directory and move it into place only when the whole archive was extracted, leaving the directory unchanged on failure"`
 	Content              []byte `json:"-" description:"the content of the archive to extract"`
+	// Progress receives one json-stream ArchiveExtractEvent per entry and a final summary when set
+	Progress io.Writer `json:"-"`
+}
+
+// ArchiveExtractEvent reports a single entry written during archive extraction
+type ArchiveExtractEvent struct {
+	Status string `json:"status" description:"extracted or skipped"`
+	Path   string `json:"path" description:"path of the entry inside the archive"`
+	Size   int64  `json:"size" description:"size of the entry in bytes"`
+	Type   string `json:"type" description:"entry type (file, directory, symlink, link or other)"`
+}
+
+// ArchiveExtractSummary is the final event of a streamed archive extraction
+type ArchiveExtractSummary struct {
+	Status       string `json:"status" description:"always complete"`
+	FilesWritten int    `json:"filesWritten" description:"number of entries written to the box"`
+	Bytes        int64  `json:"bytes" description:"total size of the written entries"`
+	Skipped      int    `json:"skipped" description:"number of entries that were not written"`
 }
 
 // BoxArchiveResult represents the response for getting an archive
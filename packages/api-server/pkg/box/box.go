@@ -13,6 +13,49 @@ type Box struct {
 	UpdatedAt time.Time             `json:"updatedAt"`
 	ExpiresAt time.Time             `json:"expiresAt"`
 	Type      BoxType               `json:"type"`
+	GroupID   string                `json:"groupId,omitempty"` // ID of the compose group the box belongs to, if any
+	Owner     string                `json:"owner,omitempty"`   // Identity of the caller that created the box, if known
+
+	// When the box's current or last run started, distinct from CreatedAt since
+	// a box can be stopped and started again; unset for a box that never ran
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	// Seconds the box has been running since StartedAt, 0 when it is not running
+	Uptime int64 `json:"uptime"`
+
+	// Why the box has its status, currently only set for failed boxes
+	StatusReason string `json:"statusReason,omitempty"`
+
+	// Digest the box's image is pinned to, set for boxes created from a digest reference
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// Disk usage, only reported when explicitly requested since computing it is expensive
+	SizeRw     *int64 `json:"sizeRw,omitempty"`     // Size of files written to the box's writable layer, in bytes
+	SizeRootFs *int64 `json:"sizeRootFs,omitempty"` // Total size of the box's root filesystem, in bytes
+
+	// How to reach the box, reported in the create response to save a follow-up inspect
+	Connection *BoxConnection `json:"connection,omitempty"`
+	// Non-fatal issues the container runtime reported creating the box, such
+	// as an option it ignores on this platform; only set in the create response
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// BoxStatusFailed is the status of a box that was stopped because it
+// exceeded its max runtime
+const BoxStatusFailed = "failed"
+
+// BoxConnection summarizes how to reach a box
+type BoxConnection struct {
+	ExecURL  string    `json:"execUrl"`         // WebSocket URL of the box's exec endpoint
+	Ports    []BoxPort `json:"ports,omitempty"` // Container ports published on the host
+	ShareDir string    `json:"shareDir"`        // Host path of the box's share directory
+}
+
+// BoxPort is a container port published on the host
+type BoxPort struct {
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+	HostIP        string `json:"hostIp,omitempty"`
+	HostPort      int    `json:"hostPort"`
 }
 
 type BoxType string
@@ -85,3 +128,38 @@ type BoxFileWriteParams struct {
 type BoxFileWriteResult struct {
 	Message string `json:"message"`
 }
+
+// BoxFileStatParams represents a request for the metadata of a path in a box
+type BoxFileStatParams struct {
+	Path string `json:"-"` // Absolute path; symlinks are not followed
+}
+
+// Types of BoxFileStat.Type
+const (
+	FileStatTypeFile        = "file"
+	FileStatTypeDir         = "dir"
+	FileStatTypeSymlink     = "symlink"
+	FileStatTypeFifo        = "fifo"
+	FileStatTypeSocket      = "socket"
+	FileStatTypeCharDevice  = "char-device"
+	FileStatTypeBlockDevice = "block-device"
+)
+
+// BoxFileStat represents the metadata of a path in a box
+type BoxFileStat struct {
+	Path       string    `json:"path"`
+	Type       string    `json:"type"`                 // file, dir, symlink, fifo, socket, char-device or block-device
+	IsDir      bool      `json:"isDir"`                // Whether the path is a directory
+	Size       int64     `json:"size"`                 // Size in bytes
+	Mode       string    `json:"mode"`                 // Permission bits in octal, e.g. "0755"
+	UID        int       `json:"uid"`                  // Owning user ID
+	GID        int       `json:"gid"`                  // Owning group ID
+	ModTime    time.Time `json:"modTime"`              // Last modification time
+	LinkTarget string    `json:"linkTarget,omitempty"` // Target of a symlink
+}
+
+// BoxPortResult maps a box port to the host port it is published on
+type BoxPortResult struct {
+	InternalPort int `json:"internalPort"`
+	ExternalPort int `json:"externalPort"`
+}
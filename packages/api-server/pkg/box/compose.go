@@ -0,0 +1,22 @@
+package model
+
+// ComposeService describes one box in a compose group
+type ComposeService struct {
+	Name      string            `json:"name"`                // Service name, also its hostname on the group network
+	Image     string            `json:"image,omitempty"`     // Image to run; defaults to the standard box image
+	Cmd       []string          `json:"cmd,omitempty"`       // Command to run instead of the image default
+	Envs      map[string]string `json:"envs,omitempty"`      // Environment variables
+	DependsOn []string          `json:"dependsOn,omitempty"` // Services that must be started before this one
+}
+
+// BoxComposeParams represents a request to create a group of boxes
+type BoxComposeParams struct {
+	Services []ComposeService `json:"services"`
+}
+
+// BoxComposeResult represents the response from creating a group of boxes
+type BoxComposeResult struct {
+	GroupID string `json:"groupId"` // Group ID shared by all boxes in the group
+	Network string `json:"network"` // Name of the network the boxes share
+	Boxes   []Box  `json:"boxes"`   // Created boxes, in start order
+}
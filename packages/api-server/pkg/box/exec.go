@@ -1,5 +1,10 @@
 package model
 
+import (
+	"io"
+	"time"
+)
+
 // BoxExecParams represents a request to execute a command in a box
 type BoxExecParams struct {
 	// The command to run. Can be a single string or an array of strings
@@ -10,10 +15,29 @@ type BoxExecParams struct {
 	WorkingDir string `json:"workingDir,omitempty"`
 	// The environment variables to run the command
 	Envs map[string]string `json:"envs,omitempty"`
+	// Run the command with only Envs and the box's PATH instead of inheriting
+	// the box's environment
+	CleanEnv bool `json:"cleanEnv,omitempty"`
+	// Run Commands joined with spaces as a script of the box's shell, so
+	// pipes, redirects and variables work, instead of as an argv
+	Shell bool `json:"shell,omitempty"`
+	// Run the command detached from the request; the response is a BoxExecSession to poll
+	Detach bool `json:"detach,omitempty"`
+	// Write stdout to this file, relative to the box's share directory, instead
+	// of returning it. Not supported with Detach.
+	StdoutFile string `json:"stdoutFile,omitempty"`
+	// Write stderr to this file, relative to the box's share directory, instead
+	// of returning it. May be the same file as StdoutFile. Not supported with Detach.
+	StderrFile string `json:"stderrFile,omitempty"`
+	// Pass the request's connection to the command as stdin. Only used when the
+	// response is a raw stream.
+	Stdin bool `json:"stdin,omitempty"`
+	// Written to the command's stdin, which is then closed. Not supported with
+	// Detach or a raw stream.
+	Input string `json:"input,omitempty"`
 
 	// --- Stream-related fields (temporarily commented out) ---
 	// Args     []string           `json:"args,omitempty"`
-	// Stdin    bool               `json:"stdin,omitempty"`
 	// Stdout   bool               `json:"stdout,omitempty"`
 	// Stderr   bool               `json:"stderr,omitempty"`
 	// TTY      bool               `json:"tty,omitempty"`
@@ -27,6 +51,32 @@ type BoxExecResult struct {
 	Stderr   string `json:"stderr"`   // Standard error from command execution
 }
 
+// BoxExecSession describes a command that keeps running server-side
+// independently of the client connection that started it
+type BoxExecSession struct {
+	ID         string    `json:"id"`                   // Identifier of the exec session
+	BoxID      string    `json:"boxId"`                // Box the command runs in
+	Commands   []string  `json:"commands"`             // Command being executed
+	Running    bool      `json:"running"`              // Whether the command is still running
+	ExitCode   int       `json:"exitCode"`             // Exit code, valid once Running is false
+	Error      string    `json:"error,omitempty"`      // Error that ended the session, if any
+	Output     string    `json:"output"`               // Combined stdout/stderr from the requested offset
+	Offset     int64     `json:"offset"`               // Offset to request next to continue tailing
+	StartedAt  time.Time `json:"startedAt"`            // Time the command was started
+	FinishedAt time.Time `json:"finishedAt,omitempty"` // Time the command finished
+	// Interactive sessions accept stdin over an attached WebSocket and keep only
+	// recent output, replayed when a client re-attaches, instead of Output
+	Interactive bool `json:"interactive,omitempty"`
+	// Whether the command runs with a terminal allocated
+	TTY bool `json:"tty,omitempty"`
+}
+
+// BoxExecSessionListResult represents the exec sessions running in a box
+type BoxExecSessionListResult struct {
+	Data  []BoxExecSession `json:"data"`  // Running sessions, oldest first, without their output
+	Total int              `json:"total"` // Number of running sessions
+}
+
 // BoxRunParams represents a request to run a command in a box
 type BoxRunCodeParams struct {
 	Code       string            `json:"code,omitempty"`
@@ -35,6 +85,27 @@ type BoxRunCodeParams struct {
 	Timeout    string            `json:"timeout,omitempty"`
 	WorkingDir string            `json:"workingDir,omitempty"`
 	Envs       map[string]string `json:"envs,omitempty"` // Environment variables for the command execution
+	// Merge stderr into stdout through a single pipe so the output keeps the order
+	// it was written in. The combined output is returned in Stdout and Stderr is empty.
+	CombineOutput bool `json:"combineOutput,omitempty"`
+	// Output receives one json-stream BoxRunCodeEvent per chunk of output as
+	// it is produced when set; the result then carries only the exit code
+	Output io.Writer `json:"-"`
+}
+
+// Statuses of the events of a streamed run
+const (
+	RunCodeEventStdout = "stdout"
+	RunCodeEventStderr = "stderr"
+	RunCodeEventExit   = "exit"
+)
+
+// BoxRunCodeEvent is an event of a streamed run: a chunk of output, or the
+// exit code as the last event
+type BoxRunCodeEvent struct {
+	Status   string `json:"status" description:"stdout, stderr or exit"`
+	Data     string `json:"data,omitempty" description:"chunk of output"`
+	ExitCode *int   `json:"exitCode,omitempty" description:"exit code of the code, set on the exit event"`
 }
 
 // BoxRunCodeResult represents the response from a run operation
@@ -50,6 +121,20 @@ type BoxExecWSParams struct {
 	Args       []string `json:"args,omitempty"`       // Arguments for the command
 	TTY        bool     `json:"tty,omitempty"`        // Whether to allocate a TTY
 	WorkingDir string   `json:"workingDir,omitempty"` // Working directory inside the container
+	// Run the command from a login shell so the box's profile scripts are
+	// loaded, e.g. a bare bash runs as bash -l. Requires TTY.
+	Login bool `json:"login,omitempty"`
+	// Keep the command running if the WebSocket drops so a client can re-attach
+	// through its exec session
+	Detach bool `json:"detach,omitempty"`
+	// Record the session as an asciinema v2 cast file at this path, relative
+	// to the box's share directory. Not supported with Detach.
+	Record string `json:"record,omitempty"`
+	Cols   int    `json:"cols,omitempty"` // Terminal width written to the recording header, defaults to 80
+	Rows   int    `json:"rows,omitempty"` // Terminal height written to the recording header, defaults to 24
+	// Send output only once a line is complete instead of as soon as it is
+	// written. Not supported with Detach.
+	LineBuffered bool `json:"lineBuffered,omitempty"`
 }
 
 // StreamType represents the type of stream in multiplexed output
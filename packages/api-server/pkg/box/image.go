@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // ImageUpdateParams represents parameters for updating docker images
 type ImageUpdateParams struct {
 	ImageReference string `json:"imageReference,omitempty"` // Image reference to update (format: repo/image or repo/image:tag)
@@ -33,3 +35,28 @@ type ImageInfo struct {
 	Status     ImageStatus `json:"status"`            // "uptodate", "outdated", or "missing"
 	Action     string      `json:"action,omitempty"`  // What will be done: "keep", "delete", "pull"
 }
+
+// BoxImageListParams represents a request to list the images boxes can be created from
+type BoxImageListParams struct {
+	All bool `json:"all,omitempty"` // List every image, not only gbox images and those used by boxes
+}
+
+// BoxImage represents an image available to create boxes from
+type BoxImage struct {
+	ID        string    `json:"id,omitempty"`        // Image ID, if the image is present locally
+	RepoTags  []string  `json:"repoTags"`            // Repository and tag references (e.g., "babelcloud/gbox-playwright:latest")
+	Size      int64     `json:"size"`                // Size in bytes, 0 if unknown
+	CreatedAt time.Time `json:"createdAt,omitempty"` // When the image was built, zero if unknown
+	InUse     bool      `json:"inUse"`               // Whether an existing box uses the image
+}
+
+// BoxImageListResult represents a response from listing images
+type BoxImageListResult struct {
+	Images []BoxImage `json:"images"`
+}
+
+// BoxImagePruneResult represents a response from pruning dangling images
+type BoxImagePruneResult struct {
+	DeletedIDs     []string `json:"deletedIds"`     // IDs of the removed images
+	SpaceReclaimed uint64   `json:"spaceReclaimed"` // Disk space freed, in bytes
+}
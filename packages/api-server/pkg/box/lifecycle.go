@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // ProgressStatus defines the type for progress statuses.
 // These are used in ProgressUpdate messages.
 type ProgressStatus string
@@ -27,15 +29,87 @@ type LinuxAndroidBoxCreateParam struct {
 	Type    string               `json:"type"`              // Type of box to create (linux, android)
 	Wait    bool                 `json:"wait,omitempty"`    // Wait for the box operation to complete
 	Config  CreateBoxConfigParam `json:"config"`            // Box configuration
+	// Identity of the caller creating the box, set by the server from the
+	// request's credentials rather than from the request body
+	Owner string `json:"-"`
 }
 
 // CreateBoxConfigParam represents the configuration for a box
 type CreateBoxConfigParam struct {
-	ExpiresIn string            `json:"expiresIn"` // Box expiration duration (e.g., "1000s")
-	Envs      map[string]string `json:"envs"`      // Environment variables
-	Labels    map[string]string `json:"labels"`    // Key-value labels
+	ExpiresIn  string            `json:"expiresIn"`            // Box expiration duration (e.g., "1000s")
+	Envs       map[string]string `json:"envs"`                 // Environment variables
+	Labels     map[string]string `json:"labels"`               // Key-value labels
+	Group      string            `json:"group,omitempty"`      // Name of the group the box belongs to, for group operations
+	NameSuffix string            `json:"nameSuffix,omitempty"` // Human-readable suffix of the container name (gbox-<id>-<suffix>)
+
+	Image string `json:"image,omitempty"` // Image to create the box from instead of the default, by tag or pinned by digest (e.g., "python@sha256:...")
+
+	Cmd        []string `json:"cmd,omitempty"`        // Command to run in the box, else a custom image's gbox.default-cmd label, else the default long-running one
+	AutoRemove bool     `json:"autoRemove,omitempty"` // Remove the box automatically when its command exits
+	MaxRuntime string   `json:"maxRuntime,omitempty"` // Stop the box and mark it failed when it runs longer than this (e.g., "1h")
+
+	DNSSearch  []string `json:"dnsSearch,omitempty"`  // DNS search domains
+	DNSOptions []string `json:"dnsOptions,omitempty"` // DNS resolver options (e.g., "ndots:2")
+
+	Memory            string `json:"memory,omitempty"`            // Hard memory limit (e.g., "512m")
+	MemoryReservation string `json:"memoryReservation,omitempty"` // Soft memory limit enforced under contention (e.g., "256m"); must not exceed Memory
+	OomKillDisable    bool   `json:"oomKillDisable,omitempty"`    // Disable the OOM killer; requires a memory limit
+	OomScoreAdj       int    `json:"oomScoreAdj,omitempty"`       // OOM score adjustment (-1000 to 1000)
+
+	Sysctls map[string]string `json:"sysctls,omitempty"` // Kernel parameters (e.g., "net.core.somaxconn": "1024"); only namespaced sysctls unless the server allows unsafe ones
+
+	DockerOpts   map[string]string `json:"dockerOpts,omitempty"`   // Allowlisted raw Docker host options (e.g., "shm-size": "1g")
+	DockerSocket bool              `json:"dockerSocket,omitempty"` // Bind-mount the host Docker socket; requires server support
+
+	PullPolicy  string `json:"pullPolicy,omitempty"`  // When to pull the image: "missing" (default), "always" or "never"
+	PullTimeout string `json:"pullTimeout,omitempty"` // Maximum duration of an image pull during create (e.g., "5m"); unlimited by default
+
+	PostStart            string `json:"postStart,omitempty"`            // Command run inside the box right after it starts, before create returns
+	PostStartTimeout     string `json:"postStartTimeout,omitempty"`     // Maximum duration of the post-start command (e.g., "1m")
+	PostStartFailOnError bool   `json:"postStartFailOnError,omitempty"` // Remove the box and fail the create when the post-start command fails
+
+	PreStop        string `json:"preStop,omitempty"`        // Command run inside the box before it is stopped or deleted
+	PreStopTimeout string `json:"preStopTimeout,omitempty"` // Maximum duration of the pre-stop command (e.g., "30s")
+
+	StopSignal      string `json:"stopSignal,omitempty"`      // Signal sent to stop the box (e.g., "SIGINT"); defaults to the image's, usually SIGTERM
+	StopGracePeriod string `json:"stopGracePeriod,omitempty"` // Time the box has to exit after the stop signal (e.g., "30s"); defaults to 10s
+	StopNoKill      bool   `json:"stopNoKill,omitempty"`      // Leave a box that outlives the grace period running instead of killing it
+
+	WaitForLog        string `json:"waitForLog,omitempty"`        // Regular expression; create returns once a box log line matches it
+	WaitForLogTimeout string `json:"waitForLogTimeout,omitempty"` // Maximum time to wait for the log line (e.g., "2m"); defaults to 1m
+
+	DependsOn        string `json:"dependsOn,omitempty"`        // ID of a box that must be running, and healthy if it has a health check, before this box starts
+	DependsOnTimeout string `json:"dependsOnTimeout,omitempty"` // Maximum time to wait for the dependency box (e.g., "5m"); defaults to 1m
+
+	Files []ProvisionFile `json:"files,omitempty"` // Files written into the share directory after the box starts, before it is considered ready
+
+	ShareTier string `json:"shareTier,omitempty"` // Named share tier the box's share directory is created in; defaults to the server's share directory
+
+	Shell string `json:"shell,omitempty"` // Shell running the box's shell-form commands and login sessions (e.g., "/bin/bash"); defaults to /bin/sh
+}
+
+// ProvisionFile is a file a box is created with, written into its share
+// directory so a box spec can carry its initial files
+type ProvisionFile struct {
+	Path     string `json:"path"`               // Path relative to the share directory, or an absolute path inside it
+	Content  string `json:"content,omitempty"`  // File content, encoded as given by Encoding
+	Encoding string `json:"encoding,omitempty"` // Encoding of Content: "" for plain text or "base64"
+	Mode     string `json:"mode,omitempty"`     // Octal permission bits (e.g., "0755"); defaults to 0644
+	Source   string `json:"source,omitempty"`   // Local file to read the content from; resolved by the client, never sent to the server
 }
 
+// Encodings of ProvisionFile.Content
+const (
+	ProvisionFileEncodingBase64 = "base64"
+)
+
+// Image pull policies of CreateBoxConfigParam.PullPolicy
+const (
+	PullPolicyMissing = "missing" // Use the local image, pulling only when it is absent
+	PullPolicyAlways  = "always"  // Pull the image before every create, even when present
+	PullPolicyNever   = "never"   // Never pull; fail when the image is absent
+)
+
 // Legacy types - kept for backwards compatibility but deprecated
 type AndroidBoxCreateParam struct {
 	CreateAndroidBox LinuxAndroidBoxCreateParam
@@ -59,9 +133,19 @@ type BoxCreateResult struct {
 	Message string `json:"message,omitempty"`
 }
 
+// BoxCreatePlan represents the response from a dry-run create: the spec the
+// box would be created with. Nothing is created and the image is not pulled.
+type BoxCreatePlan struct {
+	ID    string      `json:"id"`    // ID the box would get; a real create generates a new one
+	Name  string      `json:"name"`  // Container or workload name
+	Image string      `json:"image"` // Image the box would run
+	Spec  interface{} `json:"spec"`  // Backend spec, e.g. Docker container, host and networking config
+}
+
 // BoxDeleteParams represents a request to delete a box
 type BoxDeleteParams struct {
-	Force bool `json:"force,omitempty"` // Whether to force delete the box
+	Force          bool `json:"force,omitempty"`          // Whether to force delete the box
+	IgnoreNotFound bool `json:"ignoreNotFound,omitempty"` // Treat a box that is already gone as deleted, for retried deletes
 }
 
 // BoxDeleteResult represents a response from deleting a box
@@ -81,6 +165,13 @@ type BoxesDeleteResult struct {
 	IDs     []string `json:"ids,omitempty"` // IDs of deleted boxes
 }
 
+// BoxesStopResult represents a response from stopping multiple boxes
+type BoxesStopResult struct {
+	Count   int      `json:"count"`         // Number of boxes stopped
+	Message string   `json:"message"`       // Response message
+	IDs     []string `json:"ids,omitempty"` // IDs of stopped boxes
+}
+
 // BoxStartResult represents a response from starting a box.
 // Returns the complete box information after starting.
 type BoxStartResult = Box
@@ -89,10 +180,45 @@ type BoxStartResult = Box
 // Returns the complete box information after stopping.
 type BoxStopResult = Box
 
+// BoxUpdateParams changes the resource limits of an existing box. Limits
+// left empty are unchanged.
+type BoxUpdateParams struct {
+	CPU    float64 `json:"cpu,omitempty"`    // CPU limit in cores (e.g., 1.5)
+	Memory string  `json:"memory,omitempty"` // Hard memory limit (e.g., "1g")
+}
+
+// BoxUpdateResult represents a response from updating a box.
+// Returns the complete box information with the new limits.
+type BoxUpdateResult = Box
+
+// BoxTouchResult represents a response from touching a box
+type BoxTouchResult struct {
+	ID             string    `json:"id"`             // ID of the touched box
+	LastAccessedAt time.Time `json:"lastAccessedAt"` // Refreshed last access time used by reclaim
+}
+
 // BoxReclaimResult represents a response from reclaiming boxes
 type BoxReclaimResult struct {
+	StoppedCount int      `json:"stopped_count"`          // Number of boxes stopped
+	DeletedCount int      `json:"deleted_count"`          // Number of boxes deleted
+	WarnedCount  int      `json:"warned_count"`           // Number of boxes warned they are about to be stopped
+	StoppedIDs   []string `json:"stopped_ids,omitempty"`  // IDs of stopped boxes
+	DeletedIDs   []string `json:"deleted_ids,omitempty"`  // IDs of deleted boxes
+	WarnedIDs    []string `json:"warned_ids,omitempty"`   // IDs of warned boxes
+	SnapshotIDs  []string `json:"snapshot_ids,omitempty"` // IDs of deleted boxes committed to an image first, which can be resurrected
+}
+
+// BoxMaxRuntimeResult represents a response from stopping boxes that exceeded their max runtime
+type BoxMaxRuntimeResult struct {
 	StoppedCount int      `json:"stopped_count"`         // Number of boxes stopped
-	DeletedCount int      `json:"deleted_count"`         // Number of boxes deleted
 	StoppedIDs   []string `json:"stopped_ids,omitempty"` // IDs of stopped boxes
-	DeletedIDs   []string `json:"deleted_ids,omitempty"` // IDs of deleted boxes
+}
+
+// BoxReclaimWarning is the notification sent when an idle box is about to be reclaimed
+type BoxReclaimWarning struct {
+	Event     string    `json:"event"`     // Always "box.reclaim.warning"
+	BoxID     string    `json:"boxId"`     // ID of the idle box
+	Action    string    `json:"action"`    // What reclaim will do to the box, e.g. "stop"
+	IdleFor   string    `json:"idleFor"`   // How long the box has been idle
+	NotBefore time.Time `json:"notBefore"` // The action is not taken before this time, nor at all if the box is accessed meanwhile
 }
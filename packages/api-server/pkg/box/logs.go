@@ -0,0 +1,9 @@
+package model
+
+// BoxLogsParams represents a request for the output of a box's main process
+type BoxLogsParams struct {
+	Follow      bool   `json:"follow,omitempty"`      // Keep streaming lines as they are written
+	Tail        string `json:"tail,omitempty"`        // Number of lines from the end to start with, or "all" (default)
+	Grep        string `json:"grep,omitempty"`        // Regular expression; only matching lines are sent
+	InvertMatch bool   `json:"invertMatch,omitempty"` // Send only the lines not matching Grep
+}
@@ -17,6 +17,7 @@ type Filter struct {
 // BoxListParams represents a request to list boxes
 type BoxListParams struct {
 	Filters []Filter `json:"filters,omitempty"` // List of filter conditions
+	Size    bool     `json:"size,omitempty"`    // Whether to compute disk usage for each box (expensive)
 }
 
 // BoxListResult represents a response from listing boxes
@@ -0,0 +1,32 @@
+package model
+
+// BoxSyncManifestParams represents a request for the content hashes of the
+// files under a directory in a box
+type BoxSyncManifestParams struct {
+	Path string `json:"-"` // Absolute directory to sync into
+}
+
+// BoxSyncFile is a regular file under a synced directory
+type BoxSyncFile struct {
+	Path   string `json:"path"`   // Slash-separated path relative to the synced directory
+	SHA256 string `json:"sha256"` // Hex encoded SHA-256 of the file content
+}
+
+// BoxSyncManifest lists the regular files under a directory in a box. A
+// directory that does not exist yet has no files.
+type BoxSyncManifest struct {
+	Path  string        `json:"path"`
+	Files []BoxSyncFile `json:"files"`
+}
+
+// BoxSyncApplyParams represents the deletions computed by a sync client. New
+// and changed files are uploaded separately through the archive endpoint.
+type BoxSyncApplyParams struct {
+	Path   string   `json:"-"`                // Absolute directory to sync into; created when missing
+	Delete []string `json:"delete,omitempty"` // Paths relative to Path to remove
+}
+
+// BoxSyncResult represents the response from applying a sync
+type BoxSyncResult struct {
+	Deleted int `json:"deleted"` // Number of paths removed
+}
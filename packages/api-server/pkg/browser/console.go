@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Console message levels
+const (
+	ConsoleLevelDebug   = "debug"
+	ConsoleLevelInfo    = "info"
+	ConsoleLevelLog     = "log"
+	ConsoleLevelWarning = "warning"
+	ConsoleLevelError   = "error"
+)
+
+// ConsoleLogParams configures console message logging for a box's page.
+type ConsoleLogParams struct {
+	// BufferSize is the number of most recent messages kept; 0 uses the default
+	BufferSize int `json:"bufferSize,omitempty"`
+}
+
+// ConsoleLocation is the script position a console message came from.
+type ConsoleLocation struct {
+	URL          string `json:"url"`
+	LineNumber   int    `json:"lineNumber"`
+	ColumnNumber int    `json:"columnNumber"`
+}
+
+// ConsoleMessage is a console message recorded while console logging is
+// enabled. Uncaught exceptions are recorded at the error level.
+type ConsoleMessage struct {
+	Level     string           `json:"level"`
+	Text      string           `json:"text"`
+	Location  *ConsoleLocation `json:"location,omitempty"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+// ConsoleLogResult lists recorded console messages, oldest first.
+type ConsoleLogResult struct {
+	Messages []ConsoleMessage `json:"messages"`
+}
@@ -11,3 +11,73 @@ func NewCdpURLResult(url string) CdpURLResult {
 		URL: url,
 	}
 }
+
+// Viewport is a page viewport size in CSS pixels.
+type Viewport struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Browser engines a context can be created with
+const (
+	BrowserTypeChromium = "chromium"
+	BrowserTypeFirefox  = "firefox"
+	BrowserTypeWebKit   = "webkit"
+)
+
+// CreateContextParams configures a new isolated browser context. Device
+// selects a named descriptor (e.g. "iPhone 13"); the emulation fields
+// override the descriptor's values. BrowserType defaults to the server's
+// configured engine.
+type CreateContextParams struct {
+	BrowserType       string    `json:"browserType,omitempty"`
+	Device            string    `json:"device,omitempty"`
+	Viewport          *Viewport `json:"viewport,omitempty"`
+	DeviceScaleFactor float64   `json:"deviceScaleFactor,omitempty"`
+	IsMobile          *bool     `json:"isMobile,omitempty"`
+	HasTouch          *bool     `json:"hasTouch,omitempty"`
+	UserAgent         string    `json:"userAgent,omitempty"`
+	// ExtraHTTPHeaders are sent with every request of the context's pages
+	ExtraHTTPHeaders map[string]string `json:"extraHTTPHeaders,omitempty"`
+	// HTTPCredentials answer HTTP authentication challenges of the pages
+	HTTPCredentials *HTTPCredentials `json:"httpCredentials,omitempty"`
+}
+
+// HTTPCredentials are the user name and password for HTTP authentication.
+type HTTPCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// CreateContextResult describes a created browser context and the emulation
+// applied to its page.
+type CreateContextResult struct {
+	ContextID         string    `json:"contextId"`
+	PageID            string    `json:"pageId"`
+	BrowserType       string    `json:"browserType"`
+	Viewport          *Viewport `json:"viewport,omitempty"`
+	DeviceScaleFactor float64   `json:"deviceScaleFactor,omitempty"`
+	IsMobile          bool      `json:"isMobile"`
+	HasTouch          bool      `json:"hasTouch"`
+	UserAgent         string    `json:"userAgent,omitempty"`
+}
+
+// CreatePageParams configures a new page in a browser context. The page
+// opens in the background; focus it with FocusPage to make it the default
+// target of page actions.
+type CreatePageParams struct {
+	URL string `json:"url,omitempty"` // Defaults to about:blank
+}
+
+// PageInfo describes a page of a browser context.
+type PageInfo struct {
+	PageID  string `json:"pageId"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Focused bool   `json:"focused"` // Page actions without a pageId target this page
+}
+
+// ListPagesResult lists the pages of a browser context.
+type ListPagesResult struct {
+	Pages []PageInfo `json:"pages"`
+}
@@ -0,0 +1,34 @@
+package model
+
+// FindElementParams identifies an element on the current page, either by
+// CSS selector or by its visible text.
+type FindElementParams struct {
+	Selector string `json:"selector,omitempty"`
+	Text     string `json:"text,omitempty"`
+	// Strict fails the lookup when more than one element matches instead of
+	// returning the first match
+	Strict bool `json:"strict,omitempty"`
+	// PageID targets a page by ID instead of the focused page
+	PageID string `json:"pageId,omitempty"`
+}
+
+// BoundingBox is an element's position and size in viewport CSS pixels.
+type BoundingBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Point is a position in viewport CSS pixels.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// FindElementResult locates a matched element for coordinate based actions.
+type FindElementResult struct {
+	Center      Point       `json:"center"`
+	BoundingBox BoundingBox `json:"boundingBox"`
+	Count       int         `json:"count"` // Number of visible elements that matched
+}
@@ -0,0 +1,22 @@
+package model
+
+import "encoding/json"
+
+// EvaluateParams is a JavaScript expression to run in a box's current page.
+type EvaluateParams struct {
+	Expression string `json:"expression"`
+	Timeout    string `json:"timeout,omitempty"` // Maximum evaluation time (e.g., "10s"), defaults to 30s
+	// WaitUntil, when set, waits after the evaluation for the navigation it
+	// triggers to reach this load state ("load" or "domcontentloaded")
+	WaitUntil   string `json:"waitUntil,omitempty"`
+	WaitTimeout string `json:"waitTimeout,omitempty"` // Maximum navigation wait, defaults to 30s
+	// PageID targets a page by ID instead of the focused page
+	PageID string `json:"pageId,omitempty"`
+}
+
+// EvaluateResult holds the JSON serialized value the expression produced.
+// Promises are awaited and undefined is returned as null.
+type EvaluateResult struct {
+	Result json.RawMessage `json:"result"`
+	URL    string          `json:"url,omitempty"` // Page URL after the navigation, with WaitUntil
+}
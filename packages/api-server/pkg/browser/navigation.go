@@ -0,0 +1,28 @@
+package model
+
+// Load states a page can be waited for, in the order a page reaches them.
+const (
+	LoadStateDOMContentLoaded = "domcontentloaded" // The HTML has been parsed
+	LoadStateLoad             = "load"             // The page and its subresources have loaded
+)
+
+// WaitForLoadStateParams selects the load state to wait for on the current
+// page.
+type WaitForLoadStateParams struct {
+	State   string `json:"state,omitempty"`   // "load" (default) or "domcontentloaded"
+	Timeout string `json:"timeout,omitempty"` // Maximum wait (e.g., "10s"), defaults to 30s
+	PageID  string `json:"pageId,omitempty"`  // Targets a page by ID instead of the focused page
+}
+
+// WaitForNavigationParams selects the load state the next navigation of the
+// current page must reach.
+type WaitForNavigationParams struct {
+	WaitUntil string `json:"waitUntil,omitempty"` // "load" (default) or "domcontentloaded"
+	Timeout   string `json:"timeout,omitempty"`   // Maximum wait (e.g., "10s"), defaults to 30s
+	PageID    string `json:"pageId,omitempty"`    // Targets a page by ID instead of the focused page
+}
+
+// NavigationResult reports the URL of the page once the wait completed.
+type NavigationResult struct {
+	URL string `json:"url"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// NetworkLogParams configures network request logging for a box's page.
+type NetworkLogParams struct {
+	// BufferSize is the number of most recent requests kept; 0 uses the default
+	BufferSize int `json:"bufferSize,omitempty"`
+}
+
+// NetworkRequest is a request recorded while network logging is enabled.
+type NetworkRequest struct {
+	RequestID    string    `json:"requestId"`
+	URL          string    `json:"url"`
+	Method       string    `json:"method"`
+	ResourceType string    `json:"resourceType,omitempty"`
+	Status       int       `json:"status,omitempty"`
+	StartedAt    time.Time `json:"startedAt"`
+	DurationMs   float64   `json:"durationMs,omitempty"`
+	Finished     bool      `json:"finished"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// NetworkLogResult lists recorded requests, oldest first.
+type NetworkLogResult struct {
+	Requests []NetworkRequest `json:"requests"`
+}
@@ -0,0 +1,16 @@
+package model
+
+// SetInputFilesParams selects files from the box's share directory for a
+// file input on the current page. Files are relative to the share directory
+// or absolute paths under it as seen inside the box.
+type SetInputFilesParams struct {
+	Selector string   `json:"selector"`
+	Files    []string `json:"files"`
+	PageID   string   `json:"pageId,omitempty"` // Targets a page by ID instead of the focused page
+}
+
+// SetInputFilesResult lists the file names the input reports after the
+// files were set.
+type SetInputFilesResult struct {
+	Files []string `json:"files"`
+}
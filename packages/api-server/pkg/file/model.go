@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // FileType represents the type of a file
 type FileType string
 
@@ -29,12 +31,39 @@ type FileError struct {
 	Message string `json:"message"`
 }
 
-// FileOperationParams represents a request to share a file from a box
+// FileOperation names an operation of the POST /files endpoint
+type FileOperation string
+
+const (
+	FileOperationReclaim FileOperation = "reclaim" // Remove share files not accessed recently
+	FileOperationShare   FileOperation = "share"   // Copy a file from a box into the share directory
+	FileOperationWrite   FileOperation = "write"   // Write content to a file in a box's share directory
+)
+
+// FileOperations lists the valid file operations
+var FileOperations = []FileOperation{FileOperationReclaim, FileOperationShare, FileOperationWrite}
+
+// FileOperationParams is the request body of the POST /files endpoint. The
+// fields used besides Operation depend on the operation, see
+// FileShareParams and FileWriteParams.
 type FileOperationParams struct {
-	BoxID   string `json:"boxId"`   // ID of the box to share from
-	Path    string `json:"path"`    // Path to the file in the box
+	BoxID     string        `json:"boxId"`     // ID of the box to share from
+	Path      string        `json:"path"`      // Path to the file in the box
+	Content   string        `json:"content"`   // Content to write to the file
+	Operation FileOperation `json:"operation"` // Operation to perform (share, write, reclaim)
+}
+
+// FileShareParams represents a request to share a file from a box
+type FileShareParams struct {
+	BoxID string `json:"boxId"` // ID of the box to share from
+	Path  string `json:"path"`  // Path to the file in the box
+}
+
+// FileWriteParams represents a request to write a file in a box's share directory
+type FileWriteParams struct {
+	BoxID   string `json:"boxId"`   // ID of the box owning the file
+	Path    string `json:"path"`    // Path of the file to write
 	Content string `json:"content"` // Content to write to the file
-	Operation string `json:"operation"` // Operation to perform (share, write, reclaim)
 }
 
 // FileShareResult represents the response for file sharing operations
@@ -43,3 +72,34 @@ type FileShareResult struct {
 	Message  string     `json:"message"`
 	FileList []FileStat `json:"fileList"`
 }
+
+// FileReclaimResult represents the response of the reclaim operation
+type FileReclaimResult = FileShareResult
+
+// FileMoveParams represents a request to move a file within the share directory
+type FileMoveParams struct {
+	Src string `json:"src"` // Path of the file or directory to move
+	Dst string `json:"dst"` // Destination path
+}
+
+// FileOperationResult represents the response for delete and move operations
+type FileOperationResult struct {
+	Success bool      `json:"success"`
+	Message string    `json:"message"`
+	Stat    *FileStat `json:"stat,omitempty"` // Metadata of the resulting path, if any
+}
+
+// FileUsage reports the disk space used by the share directory
+type FileUsage struct {
+	TotalBytes int64          `json:"totalBytes"` // Size of all regular files, in bytes
+	FileCount  int            `json:"fileCount"`  // Number of regular files
+	Boxes      []BoxFileUsage `json:"boxes"`      // Usage of each box's share subdirectory, largest first
+	ComputedAt time.Time      `json:"computedAt"` // When the share directory was walked
+}
+
+// BoxFileUsage reports the disk space used by one box's share subdirectory
+type BoxFileUsage struct {
+	BoxID     string `json:"boxId"`
+	Bytes     int64  `json:"bytes"`
+	FileCount int    `json:"fileCount"`
+}
@@ -0,0 +1,101 @@
+package id
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+)
+
+// Format selects how box IDs are generated
+type Format string
+
+const (
+	// FormatUUID generates UUID v4 IDs, e.g. "550e8400-e29b-41d4-a716-446655440000"
+	FormatUUID Format = "uuid"
+	// FormatShort generates a prefix followed by 12 random base36 characters,
+	// e.g. "box-k3x9q2m7w1ab"
+	FormatShort Format = "short"
+)
+
+const (
+	// DefaultPrefix is the prefix of short IDs when none is configured
+	DefaultPrefix = "box"
+	// MaxPrefixLength keeps short IDs well within the 63 characters of a
+	// DNS-1123 label
+	MaxPrefixLength = 32
+
+	shortIDLength = 12
+	base36        = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+var (
+	uuidPattern   = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	prefixPattern = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// Generator creates box IDs in a configured format. The zero value
+// generates UUIDs.
+type Generator struct {
+	format  Format
+	prefix  string
+	pattern *regexp.Regexp // Matches short IDs with prefix
+}
+
+// NewGenerator creates a Generator for format. prefix is only used by
+// FormatShort and defaults to DefaultPrefix; it must start with a letter and
+// contain only lowercase letters, digits and inner hyphens so generated IDs
+// are valid DNS-1123 labels.
+func NewGenerator(format Format, prefix string) (*Generator, error) {
+	switch format {
+	case "", FormatUUID:
+		return &Generator{format: FormatUUID}, nil
+	case FormatShort:
+		if prefix == "" {
+			prefix = DefaultPrefix
+		}
+		if len(prefix) > MaxPrefixLength || !prefixPattern.MatchString(prefix) {
+			return nil, fmt.Errorf("invalid box ID prefix %q: must be at most %d lowercase letters, digits or hyphens, starting with a letter and not ending with a hyphen", prefix, MaxPrefixLength)
+		}
+		return &Generator{
+			format:  FormatShort,
+			prefix:  prefix,
+			pattern: regexp.MustCompile(fmt.Sprintf("^%s-[0-9a-z]{%d}$", prefix, shortIDLength)),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown box ID format %q, must be %q or %q", format, FormatUUID, FormatShort)
+	}
+}
+
+// Generate creates a new box ID
+func (g Generator) Generate() string {
+	if g.format != FormatShort {
+		return GenerateBoxID()
+	}
+
+	// Rejection sampling keeps every base36 character equally likely
+	out := make([]byte, 0, shortIDLength)
+	buf := make([]byte, shortIDLength)
+	for len(out) < shortIDLength {
+		if _, err := rand.Read(buf); err != nil {
+			panic(fmt.Sprintf("failed to generate random box ID: %v", err))
+		}
+		for _, b := range buf {
+			if int(b) < 256-256%len(base36) && len(out) < shortIDLength {
+				out = append(out, base36[int(b)%len(base36)])
+			}
+		}
+	}
+	return g.prefix + "-" + string(out)
+}
+
+// Validate reports whether id has the configured format. UUIDs are always
+// accepted so boxes created before the format changed stay addressable.
+func (g Generator) Validate(id string) error {
+	if uuidPattern.MatchString(id) {
+		return nil
+	}
+	if g.pattern != nil && g.pattern.MatchString(id) {
+		return nil
+	}
+	return fmt.Errorf("invalid box ID %q", id)
+}
@@ -2,6 +2,7 @@ package id_test
 
 import (
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/babelcloud/gbox/packages/api-server/pkg/id"
@@ -46,3 +47,51 @@ func TestGenerateBoxID(t *testing.T) {
 			"Variant bit should be 8, 9, a, or b, got %s", variantChar)
 	}
 }
+
+func TestGeneratorFormats(t *testing.T) {
+	dns1123Label := regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+	cases := []struct {
+		format  id.Format
+		prefix  string
+		pattern string
+	}{
+		{id.FormatUUID, "", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"", "", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{id.FormatShort, "", `^box-[0-9a-z]{12}$`},
+		{id.FormatShort, "dev-team1", `^dev-team1-[0-9a-z]{12}$`},
+	}
+	for _, tc := range cases {
+		g, err := id.NewGenerator(tc.format, tc.prefix)
+		assert.NoError(t, err)
+
+		generated := make(map[string]bool)
+		for i := 0; i < 1000; i++ {
+			boxID := g.Generate()
+			assert.Regexp(t, tc.pattern, boxID)
+			assert.Regexp(t, dns1123Label, boxID, "%s is not a DNS-1123 label", boxID)
+			assert.LessOrEqual(t, len(boxID), 63)
+			assert.NoError(t, g.Validate(boxID))
+			assert.False(t, generated[boxID], "Generated duplicate ID: %s", boxID)
+			generated[boxID] = true
+		}
+	}
+
+	short, err := id.NewGenerator(id.FormatShort, "dev")
+	assert.NoError(t, err)
+	assert.NoError(t, short.Validate(id.GenerateBoxID()), "UUIDs created before the format changed stay valid")
+	assert.Error(t, short.Validate("box-k3x9q2m7w1ab"), "wrong prefix")
+	assert.Error(t, short.Validate("dev-K3X9Q2M7W1AB"))
+	assert.Error(t, short.Validate("dev-k3x9"))
+
+	uuid, err := id.NewGenerator(id.FormatUUID, "")
+	assert.NoError(t, err)
+	assert.Error(t, uuid.Validate("box-k3x9q2m7w1ab"))
+
+	for _, prefix := range []string{"Box", "1box", "box-", "b_x", strings.Repeat("a", id.MaxPrefixLength+1)} {
+		_, err := id.NewGenerator(id.FormatShort, prefix)
+		assert.Error(t, err, "prefix %q", prefix)
+	}
+	_, err = id.NewGenerator("ulid", "")
+	assert.Error(t, err)
+}
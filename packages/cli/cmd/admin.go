@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewAdminCommand creates and returns the admin command
+func NewAdminCommand() *cobra.Command {
+	adminCmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Operate the gbox server",
+		Long:  `The admin command is used by operators to control server-wide behaviour, such as box reclamation and maintenance mode.`,
+		Example: `  gbox admin reclaim pause    # Stop reclaiming idle boxes during maintenance
+  gbox admin reclaim resume   # Reclaim idle boxes again
+  gbox admin reclaim status   # Show whether reclamation is paused
+  gbox admin read-only enable # Reject mutating requests during an upgrade`,
+	}
+
+	adminCmd.AddCommand(
+		NewAdminReclaimCommand(),
+		NewAdminReadOnlyCommand(),
+	)
+
+	return adminCmd
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/admin"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type AdminReadOnlyOptions struct {
+	OutputFormat string
+}
+
+// NewAdminReadOnlyCommand creates the command controlling read-only maintenance mode
+func NewAdminReadOnlyCommand() *cobra.Command {
+	readOnlyCmd := &cobra.Command{
+		Use:   "read-only",
+		Short: "Enable or disable read-only maintenance mode",
+		Long:  "Enable or disable read-only maintenance mode, in which the server rejects requests that create, change or delete boxes and files while reads keep working",
+	}
+
+	readOnlyCmd.AddCommand(
+		newAdminReadOnlyActionCommand("enable", "Reject mutating requests during maintenance", "admin/read-only/enable"),
+		newAdminReadOnlyActionCommand("disable", "Accept mutating requests again", "admin/read-only/disable"),
+		newAdminReadOnlyActionCommand("status", "Show whether the server is in read-only mode", ""),
+	)
+
+	return readOnlyCmd
+}
+
+// newAdminReadOnlyActionCommand creates a read-only subcommand that posts to
+// path, or only reads the status when path is empty
+func newAdminReadOnlyActionCommand(use, short, path string) *cobra.Command {
+	opts := &AdminReadOnlyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Example: fmt.Sprintf(`  gbox admin read-only %s
+  gbox admin read-only %s --output json`, use, use),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAdminReadOnly(opts, path)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runAdminReadOnly(opts *AdminReadOnlyOptions, path string) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var status model.ReadOnlyStatus
+	if path == "" {
+		if err := client.Get(context.Background(), "admin/read-only", nil, &status); err != nil {
+			return fmt.Errorf("failed to get read-only mode: %v", err)
+		}
+	} else if err := client.Post(context.Background(), path, nil, &status); err != nil {
+		return fmt.Errorf("failed to update read-only mode: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		statusJSON, _ := json.MarshalIndent(status, "", "  ")
+		fmt.Println(string(statusJSON))
+		return nil
+	}
+
+	if status.ReadOnly {
+		fmt.Printf("Server is in read-only mode since %s, mutating requests are rejected\n", status.Since.Local().Format(time.RFC3339))
+	} else {
+		fmt.Println("Server accepts all requests")
+	}
+	return nil
+}
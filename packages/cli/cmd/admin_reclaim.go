@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/admin"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type AdminReclaimOptions struct {
+	OutputFormat string
+}
+
+// NewAdminReclaimCommand creates the command controlling scheduled box reclamation
+func NewAdminReclaimCommand() *cobra.Command {
+	reclaimCmd := &cobra.Command{
+		Use:   "reclaim",
+		Short: "Pause or resume the reclamation of idle boxes",
+		Long:  "Pause or resume the scheduled reclamation that stops and deletes idle boxes, without restarting the server",
+	}
+
+	reclaimCmd.AddCommand(
+		newAdminReclaimActionCommand("pause", "Pause scheduled box reclamation", "admin/reclaim/pause"),
+		newAdminReclaimActionCommand("resume", "Resume scheduled box reclamation", "admin/reclaim/resume"),
+		newAdminReclaimActionCommand("status", "Show whether box reclamation is paused", ""),
+	)
+
+	return reclaimCmd
+}
+
+// newAdminReclaimActionCommand creates a reclaim subcommand that posts to
+// path, or only reads the status when path is empty
+func newAdminReclaimActionCommand(use, short, path string) *cobra.Command {
+	opts := &AdminReclaimOptions{}
+
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Example: fmt.Sprintf(`  gbox admin reclaim %s
+  gbox admin reclaim %s --output json`, use, use),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAdminReclaim(opts, path)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runAdminReclaim(opts *AdminReclaimOptions, path string) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var status model.ReclaimStatus
+	if path == "" {
+		if err := client.Get(context.Background(), "admin/reclaim", nil, &status); err != nil {
+			return fmt.Errorf("failed to get box reclamation status: %v", err)
+		}
+	} else if err := client.Post(context.Background(), path, nil, &status); err != nil {
+		return fmt.Errorf("failed to update box reclamation: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		statusJSON, _ := json.MarshalIndent(status, "", "  ")
+		fmt.Println(string(statusJSON))
+		return nil
+	}
+
+	if status.Paused {
+		fmt.Printf("Box reclamation is paused since %s\n", status.PausedAt.Local().Format(time.RFC3339))
+		if status.NextRun != nil {
+			fmt.Printf("Next scheduled run at %s will be skipped\n", status.NextRun.Local().Format(time.RFC3339))
+		}
+	} else {
+		fmt.Println("Box reclamation is active")
+		if status.NextRun != nil {
+			fmt.Printf("Next run at %s\n", status.NextRun.Local().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
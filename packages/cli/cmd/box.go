@@ -44,10 +44,21 @@ func NewBoxCommand() *cobra.Command {
 	boxCmd.AddCommand(
 		NewBoxCreateCommand(),
 		NewBoxTerminateCommand(),
+		NewBoxStartCommand(),
+		NewBoxStopCommand(),
+		NewBoxTouchCommand(),
+		NewBoxResurrectCommand(),
+		NewBoxUpdateCommand(),
 		NewBoxListCommand(),
 		NewBoxExecCommand(),
+		NewBoxRunCommand(),
+		NewBoxLogsCommand(),
 		NewBoxInspectCommand(),
 		NewBoxCpCommand(),
+		NewBoxStatCommand(),
+		NewBoxSyncCommand(),
+		NewBoxForwardCommand(),
+		NewBoxImageCommand(),
 	)
 
 	return boxCmd
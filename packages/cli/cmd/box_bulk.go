@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	sdk "github.com/babelcloud/gbox-sdk-go"
+	"github.com/babelcloud/gbox-sdk-go/option"
+)
+
+// bulkConcurrency bounds how many per-box requests a bulk operation has in flight
+const bulkConcurrency = 4
+
+// bulkBox is the part of a listed box a bulk operation needs
+type bulkBox struct {
+	ID     string
+	Status string
+}
+
+// bulkResult is the outcome of a bulk operation on a single box
+type bulkResult struct {
+	ID     string `json:"id"`
+	Result string `json:"result"` // done, skipped or failed
+	Note   string `json:"note,omitempty"`
+}
+
+// listBulkBoxes lists every box, or only those of group when it is set
+func listBulkBoxes(ctx context.Context, client *sdk.Client, group string) ([]bulkBox, error) {
+	var reqOpts []option.RequestOption
+	if group != "" {
+		reqOpts = append(reqOpts, option.WithQueryAdd("filter", "group="+group))
+	}
+	resp, err := client.V1.Boxes.List(ctx, sdk.V1BoxListParams{}, reqOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get box list: %v", err)
+	}
+	boxes := make([]bulkBox, 0, len(resp.Data))
+	for _, box := range resp.Data {
+		boxes = append(boxes, bulkBox{ID: box.ID, Status: string(box.Status)})
+	}
+	return boxes, nil
+}
+
+// runBulk calls action for every box eligible reports true for, at most
+// bulkConcurrency at a time. Other boxes are skipped with a note. Results keep
+// the order of boxes.
+func runBulk(ctx context.Context, boxes []bulkBox, eligible func(status string) bool, action func(ctx context.Context, id string) error) []bulkResult {
+	results := make([]bulkResult, len(boxes))
+	sem := make(chan struct{}, bulkConcurrency)
+	var wg sync.WaitGroup
+	for i, box := range boxes {
+		if !eligible(box.Status) {
+			results[i] = bulkResult{ID: box.ID, Result: "skipped", Note: "box is " + box.Status}
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := action(ctx, id); err != nil {
+				results[i] = bulkResult{ID: id, Result: "failed", Note: err.Error()}
+				return
+			}
+			results[i] = bulkResult{ID: id, Result: "done"}
+		}(i, box.ID)
+	}
+	wg.Wait()
+	return results
+}
+
+// printBulkResults reports the results of a bulk operation, verb being its
+// past tense (e.g. "started"), and returns an error if any box failed
+func printBulkResults(results []bulkResult, verb, outputFormat string) error {
+	var done, skipped, failed int
+	for _, r := range results {
+		switch r.Result {
+		case "done":
+			done++
+		case "skipped":
+			skipped++
+		default:
+			failed++
+		}
+	}
+
+	if outputFormat == "json" {
+		status := "success"
+		if failed > 0 {
+			status = "error"
+		}
+		out, _ := json.Marshal(map[string]interface{}{"status": status, "results": results})
+		fmt.Println(string(out))
+	} else {
+		for _, r := range results {
+			switch r.Result {
+			case "done":
+				fmt.Printf("Box %s %s\n", r.ID, verb)
+			case "skipped":
+				fmt.Printf("Box %s skipped: %s\n", r.ID, r.Note)
+			default:
+				fmt.Printf("Box %s failed: %s\n", r.ID, r.Note)
+			}
+		}
+		fmt.Printf("%d box(es) %s, %d skipped, %d failed\n", done, verb, skipped, failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d box(es) failed", failed)
+	}
+	return nil
+}
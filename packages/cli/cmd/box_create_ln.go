@@ -1,21 +1,64 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
 
 	// internal SDK client
 	sdk "github.com/babelcloud/gbox-sdk-go"
+	"github.com/babelcloud/gbox-sdk-go/option"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
 	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
 	"github.com/spf13/cobra"
 )
 
 type LinuxBoxCreateOptions struct {
-	OutputFormat string
-	Env          []string
-	Labels       []string
+	OutputFormat         string
+	ConfigFile           string
+	ExpiresIn            string
+	Image                string
+	Env                  []string
+	Labels               []string
+	Group                string
+	NameSuffix           string
+	PostStart            string
+	PostStartTimeout     string
+	PostStartFailOnError bool
+	PreStop              string
+	PreStopTimeout       string
+	StopSignal           string
+	StopGracePeriod      string
+	StopNoKill           bool
+	WaitForLog           string
+	WaitForLogTimeout    string
+	DependsOn            string
+	DependsOnTimeout     string
+	Pull                 string
+	PullTimeout          string
+	ShareTier            string
+	Shell                string
+	AutoRemove           bool
+	MaxRuntime           string
+	DNSSearch            []string
+	DNSOptions           []string
+	Memory               string
+	MemoryReservation    string
+	OomKillDisable       bool
+	OomScoreAdj          int
+	Sysctls              []string
+	DockerOpts           []string
+	DockerSocket         bool
+	Files                []model.ProvisionFile
+	DryRun               bool
+	Command              []string
 }
 
 func NewBoxCreateLinuxCommand() *cobra.Command {
@@ -29,11 +72,55 @@ func NewBoxCreateLinuxCommand() *cobra.Command {
 You can specify box configurations through various flags, including which container image to use,
 setting environment variables, adding labels, and specifying a working directory.
 
-Command arguments can be specified directly in the command line or added after the '--' separator.`,
+Command arguments can be specified directly in the command line or added after the '--' separator.
+
+A complete box spec can be kept in a JSON file in the create request format and passed with
+--config-file. Flags given on the command line take precedence over the file's values; env,
+label, sysctl and docker-opt entries are merged by key. The spec's files are written into the
+box's share directory once it starts, before it is considered ready; a file can give its content
+inline or name a local source file, resolved relative to the config file.
+
+--sysctl sets kernel parameters of the box. Namespaced sysctls (net.*, fs.mqueue.* and the
+kernel IPC parameters) are always allowed; others change the host kernel and are rejected
+unless the server enables allow_unsafe_sysctls.
+
+Docker host options not exposed as flags can be passed with --docker-opt when the server
+enables allow_raw_docker_opts. Supported keys: shm-size, pids-limit, cpu-shares,
+cpuset-cpus, init, read-only.
+
+--docker-socket mounts the host Docker socket at /var/run/docker.sock when the server
+enables allow_docker_socket_mount. Anything running in such a box can control the host's
+Docker daemon, which amounts to root access on the host; only use it with trusted code.`,
 		Example: `  gbox box create linux --env PATH=/usr/local/bin:/usr/bin:/bin -- python3 -c 'print("Hello")'
-  gbox box create linux --label project=myapp --label env=prod`,
+  gbox box create linux --label project=myapp --label env=prod
+  gbox box create linux --rm -- sh -c 'make test'
+  gbox box create linux --max-runtime 30m -- ./train.sh
+  gbox box create linux --sysctl net.core.somaxconn=1024
+  gbox box create linux --memory 1g --dry-run
+  gbox box create linux --post-start './seed.sh' --post-start-fail-on-error
+  gbox box create linux --pre-stop 'supervisorctl stop all' --pre-stop-timeout 30s
+  gbox box create linux --stop-signal SIGINT --stop-grace-period 1m
+  gbox box create linux --memory 512m --oom-kill-disable
+  gbox box create linux --docker-opt shm-size=1g --docker-opt pids-limit=512
+  gbox box create linux --wait-for-log 'Server started' -- ./serve.sh
+  gbox box create linux --depends-on <db-box-id> --depends-on-timeout 5m
+  gbox box create linux --pull always --pull-timeout 5m
+  gbox box create linux --image python@sha256:<digest>
+  gbox box create linux --share-tier scratch
+  gbox box create linux --shell /bin/bash -- 'source venv/bin/activate && ./serve.sh'
+  gbox box create linux --config-file box.json --memory 1g`,
 		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				opts.Command = args[dash:]
+			} else {
+				opts.Command = args
+			}
+			if opts.ConfigFile != "" {
+				if err := applyCreateConfigFile(opts, cmd.Flags().Changed); err != nil {
+					return err
+				}
+			}
 			return runLinuxCreate(opts)
 		},
 		DisableFlagsInUseLine: true,
@@ -41,12 +128,51 @@ Command arguments can be specified directly in the command line or added after t
 
 	flags := cmd.Flags()
 	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+	flags.StringVar(&opts.ConfigFile, "config-file", "", "JSON file with the box spec in the create request format; flags override its values")
 	flags.StringArrayVarP(&opts.Env, "env", "e", []string{}, "Environment variables in KEY=VALUE format")
 	flags.StringArrayVarP(&opts.Labels, "label", "l", []string{}, "Custom labels in KEY=VALUE format")
+	flags.StringVar(&opts.Group, "group", "", "Add the box to a named group for group operations (list, stop, terminate)")
+	flags.StringVar(&opts.NameSuffix, "name-suffix", "", "Human-readable suffix of the container name (gbox-<id>-<suffix>); letters, digits, '_', '.' and '-'")
+	flags.BoolVar(&opts.AutoRemove, "rm", false, "Automatically remove the box when its command exits")
+	flags.StringArrayVar(&opts.DNSSearch, "dns-search", []string{}, "DNS search domains")
+	flags.StringArrayVar(&opts.DNSOptions, "dns-option", []string{}, "DNS resolver options (e.g., ndots:2)")
+	flags.StringVar(&opts.Memory, "memory", "", "Memory limit (e.g., 512m, 2g)")
+	flags.StringVar(&opts.MemoryReservation, "memory-reservation", "", "Soft memory limit applied under memory contention (must not exceed --memory)")
+	flags.BoolVar(&opts.OomKillDisable, "oom-kill-disable", false, "Disable the OOM killer for the box (requires --memory)")
+	flags.IntVar(&opts.OomScoreAdj, "oom-score-adj", 0, "Tune the box's OOM preference (-1000 to 1000)")
+	flags.StringArrayVar(&opts.Sysctls, "sysctl", []string{}, "Kernel parameter of the box in KEY=VALUE format (e.g., net.core.somaxconn=1024)")
+	flags.StringArrayVar(&opts.DockerOpts, "docker-opt", []string{}, "Allowlisted Docker host option in KEY=VALUE format (requires server support)")
+	flags.BoolVar(&opts.DockerSocket, "docker-socket", false, "Mount the host Docker socket into the box (grants control of the host; requires server support)")
+	flags.StringVar(&opts.MaxRuntime, "max-runtime", "", "Stop the box and mark it failed when it runs longer than this (e.g., 30m)")
+	flags.StringVar(&opts.PostStart, "post-start", "", "Command to run inside the box right after it starts, before create returns")
+	flags.StringVar(&opts.PostStartTimeout, "post-start-timeout", "", "Maximum duration of the post-start command (default 1m)")
+	flags.BoolVar(&opts.PostStartFailOnError, "post-start-fail-on-error", false, "Remove the box and fail the create when the post-start command fails")
+	flags.StringVar(&opts.PreStop, "pre-stop", "", "Command to run inside the box before it is stopped or deleted")
+	flags.StringVar(&opts.PreStopTimeout, "pre-stop-timeout", "", "Maximum duration of the pre-stop command (e.g., 30s)")
+	flags.StringVar(&opts.StopSignal, "stop-signal", "", "Signal sent to the box's main process when it is stopped (default SIGTERM)")
+	flags.StringVar(&opts.StopGracePeriod, "stop-grace-period", "", "How long to wait after the stop signal before killing the box (default 10s)")
+	flags.BoolVar(&opts.StopNoKill, "stop-no-kill", false, "Never kill the box after the grace period; stopping fails if it is still running")
+	flags.StringVar(&opts.WaitForLog, "wait-for-log", "", "Return only once a box log line matches this regular expression")
+	flags.StringVar(&opts.WaitForLogTimeout, "wait-for-log-timeout", "", "Maximum time to wait for the --wait-for-log line (default 1m)")
+	flags.StringVar(&opts.DependsOn, "depends-on", "", "Start the box only once this box is running, and healthy if it has a health check")
+	flags.StringVar(&opts.DependsOnTimeout, "depends-on-timeout", "", "Maximum time to wait for the --depends-on box (default 1m)")
+	flags.StringVar(&opts.Image, "image", "", "Image to create the box from instead of the default, by tag or pinned by digest (image@sha256:...)")
+	flags.StringVar(&opts.Pull, "pull", "missing", "Image pull policy: missing, always or never")
+	flags.StringVar(&opts.PullTimeout, "pull-timeout", "", "Abort the create when pulling the image takes longer than this (e.g., 5m)")
+	flags.StringVar(&opts.ShareTier, "share-tier", "", "Named share tier configured on the server to create the box's share directory in")
+	flags.StringVar(&opts.Shell, "shell", "", "Shell running the box's shell-form commands and login sessions (default /bin/sh)")
+	flags.BoolVar(&opts.DryRun, "dry-run", false, "Print the container spec the box would be created with, without creating it")
 
 	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
 	})
+	cmd.RegisterFlagCompletionFunc("config-file", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+	})
+	cmd.RegisterFlagCompletionFunc("pull", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"missing", "always", "never"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.RegisterFlagCompletionFunc("depends-on", completeBoxIDs)
 
 	return cmd
 }
@@ -81,6 +207,163 @@ func runLinuxCreate(opts *LinuxBoxCreateOptions) error {
 		},
 	}
 
+	// fields not yet covered by the SDK are set directly on the request body
+	var reqOpts []option.RequestOption
+	if len(opts.Command) > 0 {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.cmd", opts.Command))
+	}
+	if opts.AutoRemove {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.autoRemove", true))
+	}
+	if opts.ExpiresIn != "" {
+		createParams.CreateLinuxBox.Config.ExpiresIn = sdk.String(opts.ExpiresIn)
+	}
+	if opts.Group != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.group", opts.Group))
+	}
+	if opts.NameSuffix != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.nameSuffix", opts.NameSuffix))
+	}
+	if len(opts.DNSSearch) > 0 {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.dnsSearch", opts.DNSSearch))
+	}
+	if len(opts.DNSOptions) > 0 {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.dnsOptions", opts.DNSOptions))
+	}
+	if opts.OomKillDisable && opts.Memory == "" {
+		return fmt.Errorf("--oom-kill-disable requires --memory")
+	}
+	if opts.OomScoreAdj < -1000 || opts.OomScoreAdj > 1000 {
+		return fmt.Errorf("invalid oom-score-adj %d: must be between -1000 and 1000", opts.OomScoreAdj)
+	}
+	if opts.Memory != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.memory", opts.Memory))
+	}
+	if opts.MemoryReservation != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.memoryReservation", opts.MemoryReservation))
+	}
+	if opts.OomKillDisable {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.oomKillDisable", true))
+	}
+	if opts.OomScoreAdj != 0 {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.oomScoreAdj", opts.OomScoreAdj))
+	}
+	if len(opts.Sysctls) > 0 {
+		sysctls, err := parseKeyValuePairs(opts.Sysctls, "sysctl")
+		if err != nil {
+			return err
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.sysctls", sysctls))
+	}
+	if len(opts.DockerOpts) > 0 {
+		dockerOpts, err := parseKeyValuePairs(opts.DockerOpts, "docker option")
+		if err != nil {
+			return err
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.dockerOpts", dockerOpts))
+	}
+	if opts.DockerSocket {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.dockerSocket", true))
+	}
+	if len(opts.Files) > 0 {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.files", opts.Files))
+	}
+	if opts.PostStart != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.postStart", opts.PostStart))
+	}
+	if opts.PostStartTimeout != "" {
+		if opts.PostStart == "" {
+			return fmt.Errorf("--post-start-timeout requires --post-start")
+		}
+		if _, err := time.ParseDuration(opts.PostStartTimeout); err != nil {
+			return fmt.Errorf("invalid post-start timeout %q: %v", opts.PostStartTimeout, err)
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.postStartTimeout", opts.PostStartTimeout))
+	}
+	if opts.PostStartFailOnError {
+		if opts.PostStart == "" {
+			return fmt.Errorf("--post-start-fail-on-error requires --post-start")
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.postStartFailOnError", true))
+	}
+	if opts.PreStop != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.preStop", opts.PreStop))
+	}
+	if opts.PreStopTimeout != "" {
+		if _, err := time.ParseDuration(opts.PreStopTimeout); err != nil {
+			return fmt.Errorf("invalid pre-stop timeout %q: %v", opts.PreStopTimeout, err)
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.preStopTimeout", opts.PreStopTimeout))
+	}
+	if opts.Image != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.image", opts.Image))
+	}
+	if opts.MaxRuntime != "" {
+		if d, err := time.ParseDuration(opts.MaxRuntime); err != nil || d <= 0 {
+			return fmt.Errorf("invalid max runtime %q: must be a positive duration", opts.MaxRuntime)
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.maxRuntime", opts.MaxRuntime))
+	}
+	if opts.StopSignal != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.stopSignal", opts.StopSignal))
+	}
+	if opts.StopGracePeriod != "" {
+		if _, err := time.ParseDuration(opts.StopGracePeriod); err != nil {
+			return fmt.Errorf("invalid stop grace period %q: %v", opts.StopGracePeriod, err)
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.stopGracePeriod", opts.StopGracePeriod))
+	}
+	if opts.StopNoKill {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.stopNoKill", true))
+	}
+	switch opts.Pull {
+	case "missing":
+		// The server default
+	case "always", "never":
+		reqOpts = append(reqOpts, option.WithJSONSet("config.pullPolicy", opts.Pull))
+	default:
+		return fmt.Errorf("invalid --pull policy %q: must be missing, always or never", opts.Pull)
+	}
+	if opts.PullTimeout != "" {
+		if d, err := time.ParseDuration(opts.PullTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid pull timeout %q: must be a positive duration", opts.PullTimeout)
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.pullTimeout", opts.PullTimeout))
+	}
+	if opts.ShareTier != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.shareTier", opts.ShareTier))
+	}
+	if opts.Shell != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.shell", opts.Shell))
+	}
+	if opts.WaitForLog != "" {
+		if _, err := regexp.Compile(opts.WaitForLog); err != nil {
+			return fmt.Errorf("invalid --wait-for-log pattern %q: %v", opts.WaitForLog, err)
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.waitForLog", opts.WaitForLog))
+	}
+	if opts.WaitForLogTimeout != "" {
+		if opts.WaitForLog == "" {
+			return fmt.Errorf("--wait-for-log-timeout requires --wait-for-log")
+		}
+		if _, err := time.ParseDuration(opts.WaitForLogTimeout); err != nil {
+			return fmt.Errorf("invalid wait-for-log timeout %q: %v", opts.WaitForLogTimeout, err)
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.waitForLogTimeout", opts.WaitForLogTimeout))
+	}
+	if opts.DependsOn != "" {
+		reqOpts = append(reqOpts, option.WithJSONSet("config.dependsOn", opts.DependsOn))
+	}
+	if opts.DependsOnTimeout != "" {
+		if opts.DependsOn == "" {
+			return fmt.Errorf("--depends-on-timeout requires --depends-on")
+		}
+		if _, err := time.ParseDuration(opts.DependsOnTimeout); err != nil {
+			return fmt.Errorf("invalid depends-on timeout %q: %v", opts.DependsOnTimeout, err)
+		}
+		reqOpts = append(reqOpts, option.WithJSONSet("config.dependsOnTimeout", opts.DependsOnTimeout))
+	}
+
 	// debug output
 	if os.Getenv("DEBUG") == "true" {
 		fmt.Fprintf(os.Stderr, "Request params:\n")
@@ -90,7 +373,17 @@ func runLinuxCreate(opts *LinuxBoxCreateOptions) error {
 
 	// call SDK
 	ctx := context.Background()
-	box, err := client.V1.Boxes.NewLinux(ctx, createParams)
+	if opts.DryRun {
+		var plan model.BoxCreatePlan
+		reqOpts = append(reqOpts, option.WithQuery("dryRun", "true"), option.WithResponseBodyInto(&plan))
+		if _, err := client.V1.Boxes.NewLinux(ctx, createParams, reqOpts...); err != nil {
+			return fmt.Errorf("failed to plan box: %v", err)
+		}
+		planJSON, _ := json.MarshalIndent(plan, "", "  ")
+		fmt.Println(string(planJSON))
+		return nil
+	}
+	box, err := client.V1.Boxes.NewLinux(ctx, createParams, reqOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to create box: %v", err)
 	}
@@ -101,7 +394,146 @@ func runLinuxCreate(opts *LinuxBoxCreateOptions) error {
 		fmt.Println(string(boxJSON))
 	} else {
 		fmt.Printf("Box created with ID \"%s\"\n", box.ID)
+		// warnings are not part of the SDK model, so read them from the raw response
+		var raw struct {
+			Warnings []string `json:"warnings"`
+		}
+		if err := json.Unmarshal([]byte(box.RawJSON()), &raw); err == nil {
+			for _, warning := range raw.Warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+			}
+		}
 	}
 
 	return nil
 }
+
+// applyCreateConfigFile loads the box spec in opts.ConfigFile into the options
+// whose flags were not set on the command line. The file must match the create
+// request schema; unknown fields are rejected.
+func applyCreateConfigFile(opts *LinuxBoxCreateOptions, changed func(flag string) bool) error {
+	data, err := os.ReadFile(opts.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var spec model.LinuxAndroidBoxCreateParam
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return fmt.Errorf("invalid config file %s: %v", opts.ConfigFile, err)
+	}
+	if dec.More() {
+		return fmt.Errorf("invalid config file %s: unexpected data after the box spec", opts.ConfigFile)
+	}
+	if spec.Type != "" && spec.Type != "linux" {
+		return fmt.Errorf("invalid config file %s: type %q is not a linux box", opts.ConfigFile, spec.Type)
+	}
+
+	cfg := spec.Config
+	// Maps are merged, with the command line's entries applied last so they win
+	opts.Env = append(keyValuePairs(cfg.Envs), opts.Env...)
+	opts.Labels = append(keyValuePairs(cfg.Labels), opts.Labels...)
+	opts.Sysctls = append(keyValuePairs(cfg.Sysctls), opts.Sysctls...)
+	opts.DockerOpts = append(keyValuePairs(cfg.DockerOpts), opts.DockerOpts...)
+
+	opts.ExpiresIn = cfg.ExpiresIn
+	files, err := resolveProvisionFiles(cfg.Files, filepath.Dir(opts.ConfigFile))
+	if err != nil {
+		return fmt.Errorf("invalid config file %s: %v", opts.ConfigFile, err)
+	}
+	opts.Files = files
+	if len(opts.Command) == 0 {
+		opts.Command = cfg.Cmd
+	}
+	setString := func(flag string, dst *string, v string) {
+		if !changed(flag) && v != "" {
+			*dst = v
+		}
+	}
+	setString("group", &opts.Group, cfg.Group)
+	setString("name-suffix", &opts.NameSuffix, cfg.NameSuffix)
+	setString("memory", &opts.Memory, cfg.Memory)
+	setString("memory-reservation", &opts.MemoryReservation, cfg.MemoryReservation)
+	setString("pull", &opts.Pull, cfg.PullPolicy)
+	setString("pull-timeout", &opts.PullTimeout, cfg.PullTimeout)
+	setString("share-tier", &opts.ShareTier, cfg.ShareTier)
+	setString("shell", &opts.Shell, cfg.Shell)
+	setString("image", &opts.Image, cfg.Image)
+	setString("max-runtime", &opts.MaxRuntime, cfg.MaxRuntime)
+	setString("post-start", &opts.PostStart, cfg.PostStart)
+	setString("post-start-timeout", &opts.PostStartTimeout, cfg.PostStartTimeout)
+	setString("pre-stop", &opts.PreStop, cfg.PreStop)
+	setString("pre-stop-timeout", &opts.PreStopTimeout, cfg.PreStopTimeout)
+	setString("stop-signal", &opts.StopSignal, cfg.StopSignal)
+	setString("stop-grace-period", &opts.StopGracePeriod, cfg.StopGracePeriod)
+	setString("wait-for-log", &opts.WaitForLog, cfg.WaitForLog)
+	setString("wait-for-log-timeout", &opts.WaitForLogTimeout, cfg.WaitForLogTimeout)
+	setString("depends-on", &opts.DependsOn, cfg.DependsOn)
+	setString("depends-on-timeout", &opts.DependsOnTimeout, cfg.DependsOnTimeout)
+	setStrings := func(flag string, dst *[]string, v []string) {
+		if !changed(flag) && len(v) > 0 {
+			*dst = v
+		}
+	}
+	setStrings("dns-search", &opts.DNSSearch, cfg.DNSSearch)
+	setStrings("dns-option", &opts.DNSOptions, cfg.DNSOptions)
+	setBool := func(flag string, dst *bool, v bool) {
+		if !changed(flag) && v {
+			*dst = v
+		}
+	}
+	setBool("rm", &opts.AutoRemove, cfg.AutoRemove)
+	setBool("oom-kill-disable", &opts.OomKillDisable, cfg.OomKillDisable)
+	setBool("docker-socket", &opts.DockerSocket, cfg.DockerSocket)
+	setBool("stop-no-kill", &opts.StopNoKill, cfg.StopNoKill)
+	setBool("post-start-fail-on-error", &opts.PostStartFailOnError, cfg.PostStartFailOnError)
+	if !changed("oom-score-adj") && cfg.OomScoreAdj != 0 {
+		opts.OomScoreAdj = cfg.OomScoreAdj
+	}
+	return nil
+}
+
+// keyValuePairs turns a map back into sorted KEY=VALUE pairs
+func keyValuePairs(m map[string]string) []string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// resolveProvisionFiles reads the local source of each file into its
+// content, base64 encoded, keeping the source file's permissions unless the
+// spec sets a mode. Relative sources are resolved against dir.
+func resolveProvisionFiles(files []model.ProvisionFile, dir string) ([]model.ProvisionFile, error) {
+	resolved := make([]model.ProvisionFile, 0, len(files))
+	for _, f := range files {
+		if f.Source != "" {
+			if f.Content != "" {
+				return nil, fmt.Errorf("file %s sets both content and source", f.Path)
+			}
+			source := f.Source
+			if !filepath.IsAbs(source) {
+				source = filepath.Join(dir, source)
+			}
+			info, err := os.Stat(source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read source of file %s: %v", f.Path, err)
+			}
+			data, err := os.ReadFile(source)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read source of file %s: %v", f.Path, err)
+			}
+			f.Content = base64.StdEncoding.EncodeToString(data)
+			f.Encoding = model.ProvisionFileEncodingBase64
+			if f.Mode == "" {
+				f.Mode = fmt.Sprintf("%04o", info.Mode().Perm())
+			}
+			f.Source = ""
+		}
+		resolved = append(resolved, f)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test creating a box from a JSON spec, with a flag overriding a file value
+func TestCreateLinuxFromConfigFile(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "box.json")
+	require.NoError(t, os.WriteFile(spec, []byte(`{
+  "type": "linux",
+  "config": {
+    "expiresIn": "30m",
+    "envs": {"APP_ENV": "staging", "DEBUG": "1"},
+    "labels": {"project": "myapp"},
+    "memory": "512m",
+    "sysctls": {"net.core.somaxconn": "512", "net.ipv4.tcp_fin_timeout": "30"},
+    "pullPolicy": "always",
+    "cmd": ["sleep", "infinity"]
+  }
+}`), 0644))
+
+	var received model.LinuxAndroidBoxCreateParam
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/boxes/linux", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "box-1", "type": "linux", "status": "running"})
+	}))
+	defer server.Close()
+
+	origAPIURL := os.Getenv("API_ENDPOINT")
+	defer os.Setenv("API_ENDPOINT", origAPIURL)
+	os.Setenv("API_ENDPOINT", server.URL)
+
+	cmd := NewBoxCreateLinuxCommand()
+	cmd.SetArgs([]string{"--config-file", spec, "--memory", "1g", "--env", "APP_ENV=prod", "--sysctl", "net.core.somaxconn=1024", "-o", "json"})
+	require.NoError(t, cmd.Execute())
+
+	cfg := received.Config
+	assert.Equal(t, "1g", cfg.Memory, "flag must override the file")
+	assert.Equal(t, map[string]string{"APP_ENV": "prod", "DEBUG": "1"}, cfg.Envs)
+	assert.Equal(t, map[string]string{"project": "myapp"}, cfg.Labels)
+	assert.Equal(t, map[string]string{"net.core.somaxconn": "1024", "net.ipv4.tcp_fin_timeout": "30"}, cfg.Sysctls)
+	assert.Equal(t, "30m", cfg.ExpiresIn)
+	assert.Equal(t, "always", cfg.PullPolicy)
+	assert.Equal(t, []string{"sleep", "infinity"}, cfg.Cmd)
+}
+
+// Test that a spec's local file sources are sent as content
+func TestCreateLinuxConfigFileResolvesFileSources(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "seed.sh"), []byte("#!/bin/sh\n"), 0755))
+	spec := filepath.Join(dir, "box.json")
+	require.NoError(t, os.WriteFile(spec, []byte(`{
+  "config": {
+    "files": [
+      {"path": "seed.sh", "source": "./seed.sh"},
+      {"path": "app.conf", "content": "debug = true\n"}
+    ]
+  }
+}`), 0644))
+
+	opts := &LinuxBoxCreateOptions{ConfigFile: spec}
+	require.NoError(t, applyCreateConfigFile(opts, func(string) bool { return false }))
+	assert.Equal(t, []model.ProvisionFile{
+		{Path: "seed.sh", Content: "IyEvYmluL3NoCg==", Encoding: "base64", Mode: "0755"},
+		{Path: "app.conf", Content: "debug = true\n"},
+	}, opts.Files)
+
+	require.NoError(t, os.WriteFile(spec, []byte(`{"config": {"files": [{"path": "a", "source": "./missing"}]}}`), 0644))
+	assert.Error(t, applyCreateConfigFile(&LinuxBoxCreateOptions{ConfigFile: spec}, func(string) bool { return false }))
+}
+
+// Test that config files not matching the create schema are rejected
+func TestCreateLinuxConfigFileValidation(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"unknown-field": `{"config": {"memroy": "1g"}}`,
+		"wrong-type":    `{"config": {"envs": ["A=1"]}}`,
+		"android":       `{"type": "android", "config": {}}`,
+		"trailing":      `{"config": {}} {}`,
+	} {
+		path := filepath.Join(dir, name+".json")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		err := applyCreateConfigFile(&LinuxBoxCreateOptions{ConfigFile: path}, func(string) bool { return false })
+		assert.Error(t, err, name)
+	}
+}
+
+// Test that --dry-run asks the server for the spec instead of creating the box
+func TestCreateLinuxDryRun(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(model.BoxCreatePlan{ID: "box-1", Name: "gbox-box-1", Image: "alpine", Spec: map[string]interface{}{}})
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	cmd := NewBoxCreateLinuxCommand()
+	cmd.SetArgs([]string{"--dry-run", "--memory", "1g"})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, "dryRun=true", query)
+}
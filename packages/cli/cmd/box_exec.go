@@ -2,7 +2,7 @@ package cmd
 
 import (
 	"bytes"
-	"encoding/binary"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,11 +12,16 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/babelcloud/gbox-sdk-go/option"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
 	"github.com/babelcloud/gbox/packages/cli/config"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
 	"github.com/gorilla/websocket"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
@@ -29,19 +34,33 @@ type BoxExecOptions struct {
 	BoxID       string
 	Command     []string
 	WorkingDir  string
-}
-
-// BoxExecRequest represents the request to execute a command in a box
-type BoxExecRequest struct {
-	Cmd      []string          `json:"cmd"`
-	Args     []string          `json:"args,omitempty"`
-	Stdin    bool              `json:"stdin"`
-	Stdout   bool              `json:"stdout"`
-	Stderr   bool              `json:"stderr"`
-	Tty      bool              `json:"tty"`
-	TermSize map[string]int    `json:"term_size,omitempty"`
-	Env      map[string]string `json:"env,omitempty"`
-	WorkDir  string            `json:"workdir,omitempty"`
+	Raw         bool
+	// DetachOnClose runs the command detached server-side and only tails its output
+	DetachOnClose bool
+	// Reconnect re-attaches to a still-running interactive exec session
+	Reconnect string
+	// Record is the path, relative to the box share directory, of an
+	// asciinema cast file the server records the interactive session to
+	Record string
+	// LineBuffered makes the server send output only once a line is complete
+	LineBuffered bool
+	// StdoutFile and StderrFile are paths, relative to the box share
+	// directory, the server writes the command's output to instead of
+	// streaming it
+	StdoutFile string
+	StderrFile string
+	// Env holds KEY=VALUE variables set for the command
+	Env []string
+	// CleanEnv runs the command with only Env and PATH instead of the box's environment
+	CleanEnv bool
+	// Shell runs the command as a script of the box's shell instead of as an argv
+	Shell bool
+	// Login runs the command from a login shell so the box's profile scripts load
+	Login bool
+	// List lists the box's running exec sessions instead of running a command
+	List bool
+	// Kill kills the command of the exec session with this ID
+	Kill string
 }
 
 // TerminalSize represents terminal dimensions
@@ -84,11 +103,68 @@ positional arguments:
 options:
   -h, --help         show this help message and exit
   -i, --interactive  Enable interactive mode (with stdin)
-  -t, --tty          Force TTY allocation`,
+  -t, --tty          Force TTY allocation
+  --raw              Use an unmultiplexed raw stream in non-TTY mode so binary
+                     data passes through unchanged (stdout only, stderr is dropped)
+  --detach-on-close  Run the command detached on the server and tail its output;
+                     the command keeps running if the CLI exits (stdout and stderr are merged).
+                     With -i or -t the session can be re-attached with --reconnect
+  --reconnect ID     Re-attach to a running interactive session, replaying its recent output
+  --record PATH      Record the interactive session as an asciinema cast file at PATH,
+                     relative to the box share directory
+  --line-buffered    Send output only once a line is complete instead of as soon as it is
+                     written; requires -i or -t
+  --stdout-file PATH Write stdout to PATH, relative to the box share directory, on the
+                     server instead of streaming it; only the exit code is reported
+  --stderr-file PATH Same as --stdout-file for stderr; may name the same file
+  -e, --env KEY=VALUE
+                     Set an environment variable for the command; may be repeated
+  --clean-env        Run the command with only the --env variables and PATH instead
+                     of inheriting the box's environment, for reproducible runs
+  --shell            Run the command as a script of the box's shell (set with
+                     box create --shell, default /bin/sh), so pipes and redirects work
+  -l, --login        Run the command from a login shell so the box's profile scripts
+                     are loaded (a bare shell runs as e.g. bash -l); requires -i or -t
+  --list             List the exec sessions still running in the box
+  --kill ID          Kill the command of an exec session, e.g. a stuck one`,
 		Example: `    gbox box exec 550e8400-e29b-41d4-a716-446655440000 -- ls -l     # List files in box
     gbox box exec 550e8400-e29b-41d4-a716-446655440000 -t -- bash     # Run interactive bash
-    gbox box exec 550e8400-e29b-41d4-a716-446655440000 -i -- cat       # Run cat with stdin`,
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 -t --login -w /app -- bash  # Login shell in /app
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 -i -- cat       # Run cat with stdin
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 --raw -- tar -cf - /var/gbox > out.tar  # Stream binary output
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 -t --detach-on-close -- bash  # Shell that survives a dropped connection
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 --reconnect 3f2a...           # Re-attach to that shell
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 -t --record demo.cast -- bash # Record a shell session
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 --stdout-file job.log --stderr-file job.log -- make  # Keep output server-side
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 --clean-env -e LANG=C -- make  # Ignore the box's environment
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 --shell -- 'ls | wc -l'      # Run a shell pipeline
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 --list                        # List running sessions
+    gbox box exec 550e8400-e29b-41d4-a716-446655440000 --kill 3f2a...                # Kill a stuck session`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.List || opts.Kill != "" {
+				if opts.List && opts.Kill != "" {
+					return fmt.Errorf("--list cannot be combined with --kill")
+				}
+				if len(args) != 1 || cmd.ArgsLenAtDash() != -1 {
+					return fmt.Errorf("--list and --kill take only a box ID")
+				}
+				resolvedBoxID, _, err := ResolveBoxIDPrefix(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to resolve box ID: %w", err)
+				}
+				if opts.List {
+					return runExecListSessions(resolvedBoxID)
+				}
+				return runExecKillSession(resolvedBoxID, opts.Kill)
+			}
+			if opts.Reconnect != "" {
+				if len(args) != 1 || cmd.ArgsLenAtDash() != -1 {
+					return fmt.Errorf("--reconnect takes only a box ID, the command is already running")
+				}
+				opts.BoxID = args[0]
+				return runExec(opts)
+			}
+
 			argsLenAtDash := cmd.ArgsLenAtDash()
 			if argsLenAtDash == -1 {
 				return fmt.Errorf("command must be specified after '--'")
@@ -116,7 +192,11 @@ options:
 			if len(args) == 0 {
 				return completeBoxIDs(cmd, args, toComplete)
 			}
-			// No completion for subsequent arguments before -- or anything after --
+			// The first word after -- is the command, run inside the box
+			if dash := cmd.ArgsLenAtDash(); dash >= 1 && len(args) == dash {
+				return completeBoxExecutables(args[0], toComplete)
+			}
+			// No completion for other arguments, they refer to paths inside the box
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		},
 	}
@@ -125,6 +205,19 @@ options:
 	cmd.Flags().BoolVarP(&opts.Interactive, "interactive", "i", false, "Enable interactive mode (with stdin)")
 	cmd.Flags().BoolVarP(&opts.Tty, "tty", "t", false, "Force TTY allocation")
 	cmd.Flags().StringVarP(&opts.WorkingDir, "workdir", "w", "", "Working directory inside the container")
+	cmd.Flags().BoolVar(&opts.DetachOnClose, "detach-on-close", false, "Keep the command running on the server if the CLI exits, tailing its output")
+	cmd.Flags().BoolVar(&opts.Raw, "raw", false, "Use a raw binary-safe stream in non-TTY mode (stdout only, stderr is dropped)")
+	cmd.Flags().StringVar(&opts.Reconnect, "reconnect", "", "Re-attach to a running interactive exec session by ID")
+	cmd.Flags().StringVar(&opts.Record, "record", "", "Record the interactive session as an asciinema cast file, relative to the box share directory")
+	cmd.Flags().BoolVar(&opts.LineBuffered, "line-buffered", false, "Send output only once a line is complete instead of as soon as it is written (requires -i or -t)")
+	cmd.Flags().StringVar(&opts.StdoutFile, "stdout-file", "", "Write stdout to a file, relative to the box share directory, instead of streaming it")
+	cmd.Flags().StringVar(&opts.StderrFile, "stderr-file", "", "Write stderr to a file, relative to the box share directory, instead of streaming it")
+	cmd.Flags().StringArrayVarP(&opts.Env, "env", "e", nil, "Set an environment variable for the command (KEY=VALUE, may be repeated)")
+	cmd.Flags().BoolVar(&opts.CleanEnv, "clean-env", false, "Run the command with only the --env variables and PATH instead of the box's environment")
+	cmd.Flags().BoolVar(&opts.Shell, "shell", false, "Run the command as a script of the box's shell so pipes and redirects work")
+	cmd.Flags().BoolVarP(&opts.Login, "login", "l", false, "Run the command from a login shell so the box's profile scripts are loaded (requires -i or -t)")
+	cmd.Flags().BoolVar(&opts.List, "list", false, "List the exec sessions still running in the box")
+	cmd.Flags().StringVar(&opts.Kill, "kill", "", "Kill the command of an exec session by ID")
 
 	return cmd
 }
@@ -140,11 +233,93 @@ func runExec(opts *BoxExecOptions) error {
 	// though for this function, we will primarily use resolvedBoxID directly.
 	// opts.BoxID = resolvedBoxID // Optional: update opts if it's used elsewhere by reference
 
+	if opts.Raw && opts.Tty {
+		return fmt.Errorf("--raw cannot be combined with --tty")
+	}
+
+	if opts.Record != "" {
+		if !opts.Interactive && !opts.Tty {
+			return fmt.Errorf("--record requires -i or -t")
+		}
+		if opts.DetachOnClose || opts.Reconnect != "" {
+			return fmt.Errorf("--record cannot be combined with --detach-on-close or --reconnect")
+		}
+	}
+
+	if opts.LineBuffered {
+		if !opts.Interactive && !opts.Tty {
+			return fmt.Errorf("--line-buffered requires -i or -t")
+		}
+		if opts.Raw || opts.DetachOnClose || opts.Reconnect != "" {
+			return fmt.Errorf("--line-buffered cannot be combined with --raw, --detach-on-close or --reconnect")
+		}
+	}
+
+	if opts.Login {
+		if !opts.Interactive && !opts.Tty {
+			return fmt.Errorf("--login requires -i or -t")
+		}
+		if opts.Raw || opts.Reconnect != "" {
+			return fmt.Errorf("--login cannot be combined with --raw or --reconnect")
+		}
+	}
+
+	envs, err := parseExecEnv(opts.Env)
+	if err != nil {
+		return err
+	}
+	if len(envs) > 0 || opts.CleanEnv || opts.Shell {
+		if opts.Interactive || opts.Tty || opts.Raw || opts.Reconnect != "" {
+			return fmt.Errorf("--env, --clean-env and --shell cannot be combined with -i, -t, --raw or --reconnect")
+		}
+		if opts.DetachOnClose {
+			return runExecDetached(opts, resolvedBoxID)
+		}
+		return runExecBuffered(opts, resolvedBoxID)
+	}
+
+	if opts.StdoutFile != "" || opts.StderrFile != "" {
+		if opts.Interactive || opts.Tty || opts.Raw || opts.DetachOnClose || opts.Reconnect != "" {
+			return fmt.Errorf("--stdout-file and --stderr-file cannot be combined with -i, -t, --raw, --detach-on-close or --reconnect")
+		}
+		return runExecBuffered(opts, resolvedBoxID)
+	}
+
+	if opts.Reconnect != "" {
+		if opts.Raw || opts.DetachOnClose {
+			return fmt.Errorf("--reconnect cannot be combined with --raw or --detach-on-close")
+		}
+		// A re-attached session always takes input, and is a shell when run from a terminal
+		opts.Interactive = true
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			opts.Tty = true
+		}
+		return runExecWebSocket(opts, resolvedBoxID)
+	}
+
+	if opts.DetachOnClose {
+		if opts.Raw {
+			return fmt.Errorf("--detach-on-close cannot be combined with --raw")
+		}
+		if opts.Interactive || opts.Tty {
+			return runExecWebSocket(opts, resolvedBoxID)
+		}
+		return runExecDetached(opts, resolvedBoxID)
+	}
+
 	// 如果需要交互式/TTY，则直接走 WebSocket 分支
-	if opts.Interactive || opts.Tty {
+	// --raw 需要字节级透传，因此始终走原始流分支
+	if (opts.Interactive || opts.Tty) && !opts.Raw {
 		return runExecWebSocket(opts, resolvedBoxID)
 	}
 
+	// Non-interactive commands run through the commands API, which passes
+	// every argument as one argv element, e.g. `-- echo "a b"`. Piped stdin
+	// is sent along as the command's input.
+	if !opts.Raw {
+		return runExecBuffered(opts, resolvedBoxID)
+	}
+
 	debug := os.Getenv("DEBUG") == "true"
 	apiBase := config.GetLocalAPIURL()
 	apiURL := fmt.Sprintf("%s/api/v1", strings.TrimSuffix(apiBase, "/"))
@@ -160,40 +335,14 @@ func runExec(opts *BoxExecOptions) error {
 		stdinAvailable = true
 	}
 
-	var termSize *TerminalSize
-	// var err error // err is already declared by ResolveBoxIDPrefix, reuse or shadow
-	if opts.Tty {
-		termSize, err = GetTerminalSize() // This might shadow the err from ResolveBoxIDPrefix if not careful
-		if err != nil {
-			debugLog(fmt.Sprintf("Failed to get terminal size: %v", err))
-			// Decide if this is a fatal error. Original code just logs it.
-		} else if termSize != nil { // Added nil check for termSize
-			debugLog(fmt.Sprintf("Terminal size: height=%d, width=%d", termSize.Height, termSize.Width))
-		}
+	// The command runs without a terminal, so its stdout arrives unmodified
+	params := model.BoxExecParams{
+		Commands:   opts.Command,
+		WorkingDir: opts.WorkingDir,
+		Stdin:      stdinAvailable,
 	}
 
-	request := BoxExecRequest{
-		Cmd:     []string{opts.Command[0]},
-		Args:    opts.Command[1:],
-		Stdin:   stdinAvailable,
-		Stdout:  true,
-		Stderr:  true,
-		Tty:     opts.Tty,
-		WorkDir: opts.WorkingDir,
-	}
-
-	if opts.Tty {
-		request.Stdin = true
-	}
-
-	if termSize != nil {
-		request.TermSize = map[string]int{
-			"height": termSize.Height,
-			"width":  termSize.Width,
-		}
-	}
-
-	requestBody, err := json.Marshal(request)
+	requestBody, err := json.Marshal(params)
 	if err != nil {
 		return fmt.Errorf("failed to encode request: %v", err)
 	}
@@ -201,7 +350,7 @@ func runExec(opts *BoxExecOptions) error {
 	debugLog(fmt.Sprintf("Request body: %s", string(requestBody)))
 
 	// Use resolvedBoxID for the API call
-	requestURL := fmt.Sprintf("%s/boxes/%s/exec", apiURL, resolvedBoxID)
+	requestURL := fmt.Sprintf("%s/boxes/%s/commands", apiURL, resolvedBoxID)
 	req, err := http.NewRequest("POST", requestURL, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %v", err)
@@ -210,19 +359,25 @@ func runExec(opts *BoxExecOptions) error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Upgrade", "tcp")
 	req.Header.Set("Connection", "Upgrade")
-
-	if opts.Tty {
-		req.Header.Set("Accept", "application/vnd.gbox.raw-stream")
-	} else {
-		req.Header.Set("Accept", "application/vnd.gbox.multiplexed-stream")
-	}
+	req.Header.Set("Accept", "application/vnd.gbox.raw-stream")
 
 	debugLog(fmt.Sprintf("Sending request to: POST %s", requestURL))
 	for k, v := range req.Header {
 		debugLog(fmt.Sprintf("Header %s: %s", k, v))
 	}
 
-	client := &http.Client{}
+	// Keep the connection, whose write side is closed at the end of stdin
+	var conn net.Conn
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			c, err := dialer.DialContext(ctx, network, addr)
+			conn = c
+			return c, err
+		},
+	}
+	client := &http.Client{Transport: transport}
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %v", err)
@@ -263,11 +418,187 @@ func runExec(opts *BoxExecOptions) error {
 		return fmt.Errorf("response does not support hijacking")
 	}
 
-	if opts.Tty {
-		return handleRawStream(hijacker)
-	} else {
-		return handleMultiplexedStream(hijacker, stdinAvailable)
+	return pipeRawStream(rawStreamConn{hijacker, conn}, os.Stdin, os.Stdout, stdinAvailable)
+}
+
+// rawStreamConn is the body of an upgraded response that can close the
+// write side of its connection, which the body itself does not expose
+type rawStreamConn struct {
+	io.ReadWriteCloser
+	conn net.Conn
+}
+
+func (c rawStreamConn) CloseWrite() error {
+	if closer, ok := c.conn.(interface{ CloseWrite() error }); ok {
+		return closer.CloseWrite()
 	}
+	return c.Close()
+}
+
+// runExecDetached starts the command as a detached server-side session and
+// tails its output until it finishes. Interrupting the CLI only stops tailing.
+func runExecDetached(opts *BoxExecOptions, resolvedBoxID string) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	envs, err := parseExecEnv(opts.Env)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	body := model.BoxExecParams{
+		Commands:   opts.Command,
+		WorkingDir: opts.WorkingDir,
+		Envs:       envs,
+		CleanEnv:   opts.CleanEnv,
+		Shell:      opts.Shell,
+		Detach:     true,
+	}
+	var session model.BoxExecSession
+	if err := client.Post(ctx, fmt.Sprintf("boxes/%s/commands", resolvedBoxID), body, &session); err != nil {
+		return fmt.Errorf("failed to start detached command: %v", err)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	var offset int64
+	for {
+		path := fmt.Sprintf("boxes/%s/exec-sessions/%s", resolvedBoxID, session.ID)
+		var update model.BoxExecSession
+		if err := client.Get(ctx, path, nil, &update, option.WithQuery("offset", strconv.FormatInt(offset, 10))); err != nil {
+			return fmt.Errorf("failed to get command output: %v", err)
+		}
+		os.Stdout.WriteString(update.Output)
+		offset = update.Offset
+
+		if !update.Running {
+			if update.Error != "" {
+				return fmt.Errorf("command session ended with error: %s", update.Error)
+			}
+			if update.ExitCode != 0 {
+				return fmt.Errorf("command exited with code %d", update.ExitCode)
+			}
+			return nil
+		}
+
+		select {
+		case <-sigChan:
+			fmt.Fprintf(os.Stderr, "\nStopped tailing; command %s keeps running in box %s\n", session.ID, resolvedBoxID)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// runExecListSessions prints the exec sessions still running in a box
+func runExecListSessions(resolvedBoxID string) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var result model.BoxExecSessionListResult
+	if err := client.Get(context.Background(), fmt.Sprintf("boxes/%s/exec-sessions", resolvedBoxID), nil, &result); err != nil {
+		return fmt.Errorf("failed to list exec sessions: %v", err)
+	}
+	if len(result.Data) == 0 {
+		fmt.Println("No running exec sessions")
+		return nil
+	}
+
+	fmt.Printf("%-64s %-25s %-5s %s\n", "ID", "STARTED", "TTY", "COMMAND")
+	for _, session := range result.Data {
+		tty := "no"
+		if session.TTY {
+			tty = "yes"
+		}
+		fmt.Printf("%-64s %-25s %-5s %s\n", session.ID, session.StartedAt.Local().Format(time.RFC3339), tty, strings.Join(session.Commands, " "))
+	}
+	return nil
+}
+
+// runExecKillSession kills the command of an exec session and reports how
+// it ended
+func runExecKillSession(resolvedBoxID, sessionID string) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var session model.BoxExecSession
+	path := fmt.Sprintf("boxes/%s/exec-sessions/%s", resolvedBoxID, url.PathEscape(sessionID))
+	if err := client.Delete(context.Background(), path, nil, &session); err != nil {
+		return fmt.Errorf("failed to kill exec session %s: %v", sessionID, err)
+	}
+	fmt.Printf("Exec session %s ended with exit code %d\n", session.ID, session.ExitCode)
+	return nil
+}
+
+// runExecBuffered runs the command to completion through the commands API and
+// prints its output. Output redirected to files in the box share directory
+// is not printed.
+func runExecBuffered(opts *BoxExecOptions, resolvedBoxID string) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	envs, err := parseExecEnv(opts.Env)
+	if err != nil {
+		return err
+	}
+
+	params := model.BoxExecParams{
+		Commands:   opts.Command,
+		WorkingDir: opts.WorkingDir,
+		Envs:       envs,
+		CleanEnv:   opts.CleanEnv,
+		Shell:      opts.Shell,
+		StdoutFile: opts.StdoutFile,
+		StderrFile: opts.StderrFile,
+	}
+	// Input piped or redirected into the CLI is passed on to the command
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %v", err)
+		}
+		params.Input = string(input)
+	}
+	var result model.BoxExecResult
+	if err := client.Post(context.Background(), fmt.Sprintf("boxes/%s/commands", resolvedBoxID), params, &result); err != nil {
+		return fmt.Errorf("failed to execute command: %v", err)
+	}
+	os.Stdout.WriteString(result.Stdout)
+	os.Stderr.WriteString(result.Stderr)
+	if result.ExitCode != 0 {
+		return fmt.Errorf("command exited with code %d", result.ExitCode)
+	}
+	return nil
+}
+
+// parseExecEnv converts KEY=VALUE entries of --env into a map
+func parseExecEnv(entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	env := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --env '%s': expected KEY=VALUE", entry)
+		}
+		env[key] = value
+	}
+	return env, nil
 }
 
 // runExecWebSocket 通过新的 WebSocket API 执行交互式命令
@@ -280,7 +611,9 @@ func runExecWebSocket(opts *BoxExecOptions, resolvedBoxID string) error {
 	isLocal := currentProfile != nil && (currentProfile.Name == "local" || currentProfile.OrganizationName == "local")
 
 	var apiBase string
-	if isLocal {
+	if endpoint := config.GetAPIEndpoint(); endpoint != "" {
+		apiBase = strings.TrimSuffix(endpoint, "/")
+	} else if isLocal {
 		apiBase = strings.TrimSuffix(config.GetLocalAPIURL(), "/")
 	} else {
 		apiBase = strings.TrimSuffix(config.GetCloudAPIURL(), "/")
@@ -295,6 +628,9 @@ func runExecWebSocket(opts *BoxExecOptions, resolvedBoxID string) error {
 	}
 
 	wsURL := fmt.Sprintf("%s/api/v1/boxes/%s/exec", wsBase, resolvedBoxID)
+	if opts.Reconnect != "" {
+		wsURL = fmt.Sprintf("%s/api/v1/boxes/%s/exec-sessions/%s/attach", wsBase, resolvedBoxID, url.PathEscape(opts.Reconnect))
+	}
 
 	// 解析 URL 以确保合法
 	parsedURL, err := url.Parse(wsURL)
@@ -315,25 +651,56 @@ func runExecWebSocket(opts *BoxExecOptions, resolvedBoxID string) error {
 		headers.Set("X-API-Key", apiKey)
 	}
 
-	conn, _, err := websocket.DefaultDialer.Dial(parsedURL.String(), headers)
+	conn, resp, err := websocket.DefaultDialer.Dial(parsedURL.String(), headers)
 	if err != nil {
+		if opts.Reconnect != "" && resp != nil {
+			switch resp.StatusCode {
+			case http.StatusNotFound:
+				return fmt.Errorf("exec session %s not found in box %s", opts.Reconnect, resolvedBoxID)
+			case http.StatusConflict:
+				return fmt.Errorf("exec session %s is not interactive; use the exec-sessions API to read its output", opts.Reconnect)
+			}
+		}
 		return fmt.Errorf("failed to connect websocket: %v", err)
 	}
 	defer conn.Close()
 
 	// 发送初始化指令
-	initPayload := map[string]interface{}{
-		"command": map[string]interface{}{
-			"commands":    opts.Command,
-			"interactive": true,
-			"workingDir":  opts.WorkingDir,
-		},
-	}
-	// TODO If workingDir is not exists, it should be created by the server.
-	if err := conn.WriteJSON(initPayload); err != nil {
-		return fmt.Errorf("failed to send init payload: %v", err)
+	if opts.Reconnect == "" {
+		initPayload := map[string]interface{}{
+			"command": map[string]interface{}{
+				"commands":    opts.Command,
+				"interactive": true,
+				"workingDir":  opts.WorkingDir,
+				"detach":      opts.DetachOnClose,
+			},
+		}
+		command := initPayload["command"].(map[string]interface{})
+		if opts.Login {
+			command["login"] = true
+		}
+		if opts.LineBuffered {
+			command["lineBuffered"] = true
+		}
+		if opts.Record != "" {
+			command["record"] = opts.Record
+			if size, err := GetTerminalSize(); err == nil {
+				command["cols"] = size.Width
+				command["rows"] = size.Height
+			}
+			fmt.Fprintf(os.Stderr, "Recording session to %s in the box share directory\n", opts.Record)
+		}
+		// TODO If workingDir is not exists, it should be created by the server.
+		if err := conn.WriteJSON(initPayload); err != nil {
+			return fmt.Errorf("failed to send init payload: %v", err)
+		}
 	}
 
+	// A detached session outlives a dropped connection and can be re-attached
+	var sessionMu sync.Mutex
+	sessionID := opts.Reconnect
+	var dropped bool
+
 	// 若开启 TTY，切换终端到 raw
 	var oldState *term.State
 	if opts.Tty {
@@ -351,6 +718,10 @@ func runExecWebSocket(opts *BoxExecOptions, resolvedBoxID string) error {
 		for {
 			msgType, data, err := conn.ReadMessage()
 			if err != nil {
+				// Anything but a normal close means the connection was lost
+				sessionMu.Lock()
+				dropped = !websocket.IsCloseError(err, websocket.CloseNormalClosure)
+				sessionMu.Unlock()
 				// Treat normal close codes as EOF to avoid noisy error message
 				if websocket.IsCloseError(err,
 					websocket.CloseNormalClosure,    // 1000
@@ -370,11 +741,16 @@ func runExecWebSocket(opts *BoxExecOptions, resolvedBoxID string) error {
 				// 尝试解析为 JSON 事件
 				var evt struct {
 					Event   string `json:"event"`
+					ID      string `json:"id"`
 					Data    string `json:"data"`
 					Message string `json:"message"`
 				}
 				if jsonErr := json.Unmarshal(data, &evt); jsonErr == nil && evt.Event != "" {
 					switch evt.Event {
+					case "session":
+						sessionMu.Lock()
+						sessionID = evt.ID
+						sessionMu.Unlock()
 					case "stdout":
 						os.Stdout.Write([]byte(evt.Data))
 					case "stderr":
@@ -434,178 +810,37 @@ func runExecWebSocket(opts *BoxExecOptions, resolvedBoxID string) error {
 
 	// 等待任意 goroutine 结束
 	err = <-errChan
+	if oldState != nil {
+		term.Restore(int(os.Stdin.Fd()), oldState)
+	}
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	if dropped && sessionID != "" {
+		fmt.Fprintf(os.Stderr, "\r\nConnection lost; the session keeps running. Re-attach with:\r\n  gbox box exec %s --reconnect %s\r\n", resolvedBoxID, sessionID)
+	}
 	if err == io.EOF {
 		return nil
 	}
 	return err
 }
 
-// handleRawStream handles raw stream in TTY mode
-func handleRawStream(conn io.ReadWriteCloser) error {
-	// Save terminal state
-	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
-	if err != nil {
-		return fmt.Errorf("failed to set terminal to raw mode: %v", err)
-	}
-	defer term.Restore(int(os.Stdin.Fd()), oldState)
-
-	// Handle terminal resize
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGWINCH)
-	defer signal.Stop(sigChan)
-
-	// Start goroutine for reading from connection
-	errChan := make(chan error, 2)
-	go func() {
-		_, err := io.Copy(os.Stdout, conn)
-		errChan <- err
-	}()
-
-	// Start goroutine for writing to connection
-	go func() {
-		_, err := io.Copy(conn, os.Stdin)
-		errChan <- err
-	}()
-
-	// Wait for either an error or for both goroutines to finish
-	err = <-errChan
-	if err != nil && err != io.EOF {
-		return fmt.Errorf("stream error: %v", err)
-	}
-
-	return nil
-}
-
-// handleMultiplexedStream handles multiplexed stream in non-TTY mode
-// Accepts io.ReadWriteCloser as that's what resp.Body gives us
-func handleMultiplexedStream(conn io.ReadWriteCloser, stdinAvailable bool) error {
-	var wg sync.WaitGroup
-	doneChan := make(chan struct{}) // Channel to signal stdin goroutine to exit
-
-	// Start goroutine for handling stdin if available
+// pipeRawStream copies an unmultiplexed stream byte-for-byte in non-TTY mode.
+// It leaves the local terminal untouched, so it is safe to use in shell
+// pipelines carrying binary data.
+func pipeRawStream(conn io.ReadWriteCloser, stdin io.Reader, stdout io.Writer, stdinAvailable bool) error {
 	if stdinAvailable {
-		wg.Add(1)
 		go func() {
-			defer wg.Done()
-			defer func() {
-				// Try to close the write side... (signal EOF)
-				if tcpConn, ok := conn.(*net.TCPConn); ok {
-					tcpConn.CloseWrite()
-				} else {
-					conn.Close() // Fallback
-				}
-			}()
-
-			buffer := make([]byte, 1024)
-			for {
-				// Use select to read from stdin or wait for done signal
-				stdinReadChan := make(chan struct {
-					n   int
-					err error
-				}, 1)
-
-				// Goroutine to perform the potentially blocking read
-				go func() {
-					n, err := os.Stdin.Read(buffer)
-					stdinReadChan <- struct {
-						n   int
-						err error
-					}{n, err}
-				}()
-
-				select {
-				case <-doneChan: // If main loop signals done
-					// fmt.Fprintln(os.Stderr, "DEBUG: stdin goroutine received done signal")
-					return // Exit goroutine
-				case readResult := <-stdinReadChan: // If stdin read completes
-					n := readResult.n
-					err := readResult.err
-					if n > 0 {
-						if _, writeErr := conn.Write(buffer[:n]); writeErr != nil {
-							fmt.Fprintf(os.Stderr, "Error writing to connection: %v\n", writeErr)
-							return // Exit goroutine on write error
-						}
-					}
-					if err != nil {
-						if err != io.EOF {
-							fmt.Fprintf(os.Stderr, "Error reading from stdin: %v\n", err)
-						}
-						// fmt.Fprintf(os.Stderr, "DEBUG: Exiting stdin goroutine due to err: %v\n", err)
-						return // Exit goroutine on error or EOF
-					}
-					// Optional: Add a timeout to prevent deadlock if stdin somehow hangs unexpectedly
-					// case <-time.After(10 * time.Second):
-					//     fmt.Fprintln(os.Stderr, "DEBUG: stdin read timed out")
-					//     return
-				}
+			io.Copy(conn, stdin)
+			// Signal EOF to the remote side without tearing down the read side
+			if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+				closer.CloseWrite()
 			}
 		}()
 	}
 
-	// Read multiplexed stream from connection
-	var readErr error
-	buf := make([]byte, 8)
-	for {
-		n, err := io.ReadFull(conn, buf)
-		if err != nil {
-			isClosedConnErr := errors.Is(err, net.ErrClosed) || strings.Contains(err.Error(), "use of closed network connection")
-			if err == io.EOF || isClosedConnErr {
-				readErr = nil
-			} else {
-				readErr = fmt.Errorf("failed to read header: %v", err)
-			}
-			break // Exit loop
-		}
-		if n != 8 {
-			readErr = fmt.Errorf("short read on header: got %d bytes, expected 8", n)
-			break
-		}
-
-		// Parse header
-		streamType := buf[0]
-		size := binary.BigEndian.Uint32(buf[4:])
-
-		// Read payload
-		if size > 1*1024*1024 {
-			readErr = fmt.Errorf("unreasonable payload size received: %d", size)
-			break
-		}
-		if size == 0 {
-			continue
-		}
-		payload := make([]byte, size)
-		payloadN, payloadErr := io.ReadFull(conn, payload)
-		if payloadErr != nil {
-			isClosedConnErr := errors.Is(payloadErr, net.ErrClosed) || strings.Contains(payloadErr.Error(), "use of closed network connection")
-			if payloadErr == io.EOF || isClosedConnErr {
-				readErr = nil
-			} else {
-				readErr = fmt.Errorf("failed to read payload: %v", payloadErr)
-			}
-			break
-		}
-		if uint32(payloadN) != size {
-			readErr = fmt.Errorf("short read on payload: got %d bytes, expected %d", payloadN, size)
-			break
-		}
-		switch streamType {
-		case 1:
-			os.Stdout.Write(payload[:payloadN])
-		case 2:
-			os.Stderr.Write(payload[:payloadN])
-		default:
-			fmt.Fprintf(os.Stderr, "Unknown stream type: %d\n", streamType)
-		}
+	_, err := io.Copy(stdout, conn)
+	if err != nil && err != io.EOF && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("stream error: %v", err)
 	}
-
-	// Signal stdin goroutine to exit *before* waiting
-	// fmt.Fprintln(os.Stderr, "DEBUG: Closing doneChan")
-	close(doneChan)
-
-	// Wait for the stdin goroutine to finish
-	// fmt.Fprintln(os.Stderr, "DEBUG: Waiting for stdin goroutine (wg.Wait())")
-	wg.Wait()
-	// fmt.Fprintln(os.Stderr, "DEBUG: Stdin goroutine finished")
-
-	return readErr
+	return nil
 }
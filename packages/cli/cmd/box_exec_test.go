@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// withStdin replaces os.Stdin for the test with a pipe carrying input
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	stdin, stdinWriter, err := os.Pipe()
+	require.NoError(t, err)
+	go func() {
+		stdinWriter.WriteString(input)
+		stdinWriter.Close()
+	}()
+	origStdin := os.Stdin
+	os.Stdin = stdin
+	t.Cleanup(func() {
+		os.Stdin = origStdin
+		stdin.Close()
+	})
+}
+
+// Test that binary data piped through a --raw exec round-trips byte-for-byte
+func TestPipeRawStreamBinaryRoundTrip(t *testing.T) {
+	payload := make([]byte, 256*1024)
+	for i := range payload {
+		payload[i] = byte(i % 256)
+	}
+	// Start with bytes that look like a multiplexed stream header
+	copy(payload, []byte{2, 0, 0, 0, 0xff, 0xff, 0xff, 0xff})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// Fake remote side: echo stdin back until EOF, like `cat` running in the box
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var stdout bytes.Buffer
+	err = pipeRawStream(conn.(*net.TCPConn), bytes.NewReader(payload), &stdout, true)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(payload, stdout.Bytes()), "raw stream output differs from input")
+}
+
+// Test that --reconnect refuses a new command, since it attaches to one already running
+func TestBoxExecReconnectRejectsCommand(t *testing.T) {
+	cmd := NewBoxExecCommand()
+	cmd.SetArgs([]string{"box-1", "--reconnect", "exec-1", "--", "bash"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--reconnect takes only a box ID")
+}
+
+// Test that --login is refused outside interactive sessions, which run no shell
+func TestBoxExecLoginRequiresInteractive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/boxes" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[{"id":"box-1","type":"linux","status":"running"}]}`))
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	for _, opts := range []*BoxExecOptions{
+		{BoxID: "box-1", Command: []string{"bash"}, Login: true},
+		{BoxID: "box-1", Command: []string{"bash"}, Login: true, Interactive: true, Raw: true},
+	} {
+		err := runExec(opts)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--login")
+	}
+}
+
+// Test that an argument containing spaces reaches the server as one argv element
+func TestBoxExecPreservesArgumentQuoting(t *testing.T) {
+	var commands []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/boxes/box-1/commands" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		var body struct {
+			Commands []string `json:"commands"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		commands = body.Commands
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"exitCode":0,"stdout":"a b\n"}`))
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	withStdin(t, "")
+	opts := &BoxExecOptions{Command: []string{"echo", "a b", `$HOME "quoted" 'x'`}}
+	require.NoError(t, runExecBuffered(opts, "box-1"))
+	assert.Equal(t, []string{"echo", "a b", `$HOME "quoted" 'x'`}, commands)
+}
+
+// Test that --clean-env and --env reach the commands API
+func TestBoxExecCleanEnv(t *testing.T) {
+	var params model.BoxExecParams
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/boxes/box-1/commands" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"exitCode":0}`))
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	withStdin(t, "")
+	opts := &BoxExecOptions{Command: []string{"printenv"}, Env: []string{"LANG=C", "OPTS=a=b"}, CleanEnv: true}
+	require.NoError(t, runExecBuffered(opts, "box-1"))
+	assert.True(t, params.CleanEnv)
+	assert.Equal(t, map[string]string{"LANG": "C", "OPTS": "a=b"}, params.Envs)
+
+	_, err := parseExecEnv([]string{"NOVALUE"})
+	assert.Error(t, err)
+}
+
+// Test that --kill deletes the session through the exec-sessions API
+func TestBoxExecKillSession(t *testing.T) {
+	var method, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"exec-1","boxId":"box-1","running":false,"exitCode":137}`))
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	require.NoError(t, runExecKillSession("box-1", "exec-1"))
+	assert.Equal(t, http.MethodDelete, method)
+	assert.Equal(t, "/api/v1/boxes/box-1/exec-sessions/exec-1", path)
+
+	cmd := NewBoxExecCommand()
+	cmd.SetArgs([]string{"box-1", "--list", "--", "bash"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--list and --kill take only a box ID")
+}
+
+// Test that with piped stdin the command is still sent as one argv through
+// the commands API, along with the piped input
+func TestBoxExecPreservesArgumentQuotingWithoutTerminal(t *testing.T) {
+	var params model.BoxExecParams
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/boxes":
+			w.Write([]byte(`{"data":[{"id":"box-1","type":"linux","status":"running"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/boxes/box-1/commands":
+			json.NewDecoder(r.Body).Decode(&params)
+			w.Write([]byte(`{"exitCode":0,"stdout":"a b\n"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	withStdin(t, "")
+	require.NoError(t, runExec(&BoxExecOptions{BoxID: "box-1", Command: []string{"echo", "a b"}}))
+	assert.Equal(t, []string{"echo", "a b"}, params.Commands)
+	assert.Empty(t, params.Input)
+
+	// Piped input reaches the command
+	withStdin(t, "piped\ndata")
+	require.NoError(t, runExec(&BoxExecOptions{BoxID: "box-1", Command: []string{"cat"}}))
+	assert.Equal(t, []string{"cat"}, params.Commands)
+	assert.Equal(t, "piped\ndata", params.Input)
+}
+
+// Test that --raw sends the command to the commands API as a raw stream and
+// round-trips piped binary stdin byte-for-byte, ending it with a half-close
+func TestBoxExecRawRoundTrip(t *testing.T) {
+	payload := make([]byte, 256*1024)
+	for i := range payload {
+		payload[i] = byte(i % 256)
+	}
+	copy(payload, []byte{2, 0, 0, 0, 0xff, 0xff, 0xff, 0xff})
+
+	var params model.BoxExecParams
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/boxes":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"data":[{"id":"box-1","type":"linux","status":"running"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/boxes/box-1/commands":
+			assert.Equal(t, "application/vnd.gbox.raw-stream", r.Header.Get("Accept"))
+			json.NewDecoder(r.Body).Decode(&params)
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.gbox.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			buf.Flush()
+			// Run `cat` until the client ends stdin
+			io.Copy(conn, buf.Reader)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	stdin, stdinWriter, err := os.Pipe()
+	require.NoError(t, err)
+	defer stdin.Close()
+	go func() {
+		stdinWriter.Write(payload)
+		stdinWriter.Close()
+	}()
+	stdoutReader, stdout, err := os.Pipe()
+	require.NoError(t, err)
+	defer stdoutReader.Close()
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(stdoutReader)
+		output <- data
+	}()
+
+	origStdin, origStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdin, stdout
+	err = runExec(&BoxExecOptions{BoxID: "box-1", Command: []string{"cat", "-"}, Raw: true})
+	os.Stdin, os.Stdout = origStdin, origStdout
+	stdout.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"cat", "-"}, params.Commands)
+	assert.True(t, params.Stdin)
+	assert.True(t, bytes.Equal(payload, <-output), "raw stream output differs from input")
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/babelcloud/gbox/packages/cli/config"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxForwardOptions struct {
+	Address string
+}
+
+func NewBoxForwardCommand() *cobra.Command {
+	opts := &BoxForwardOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "forward <box-id> [LOCAL:]REMOTE",
+		Short: "Forward a local port to a port of a box",
+		Long: `Forward connections to a local port to a port inside a box until interrupted.
+
+The box port must be published on the host running the gbox server; connections are
+proxied to the published host port. LOCAL defaults to REMOTE.`,
+		Example: `  gbox box forward 550e8400-e29b-41d4-a716-446655440000 8080
+  gbox box forward 550e8400-e29b-41d4-a716-446655440000 9000:8080
+  gbox box forward 550e8400-e29b-41d4-a716-446655440000 9000:8080 --address 0.0.0.0`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return runForward(ctx, opts, args, nil)
+		},
+		ValidArgsFunction: completeBoxIDs,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.Address, "address", "127.0.0.1", "Local address to listen on")
+
+	return cmd
+}
+
+// parsePortMapping parses "[LOCAL:]REMOTE"
+func parsePortMapping(mapping string) (local, remote int, err error) {
+	localStr, remoteStr, found := strings.Cut(mapping, ":")
+	if !found {
+		remoteStr = localStr
+	}
+	parse := func(s string) (int, error) {
+		port, err := strconv.Atoi(s)
+		if err != nil || port < 0 || port > 65535 {
+			return 0, fmt.Errorf("invalid port %q in %q (must be [LOCAL:]REMOTE)", s, mapping)
+		}
+		return port, nil
+	}
+	if local, err = parse(localStr); err != nil {
+		return 0, 0, err
+	}
+	if remote, err = parse(remoteStr); err != nil {
+		return 0, 0, err
+	}
+	if remote == 0 {
+		return 0, 0, fmt.Errorf("invalid remote port 0 in %q", mapping)
+	}
+	return local, remote, nil
+}
+
+// runForward forwards connections until ctx is done. ready, when set, is
+// called with the listening address once connections are accepted.
+func runForward(ctx context.Context, opts *BoxForwardOptions, args []string, ready func(net.Addr)) error {
+	localPort, remotePort, err := parsePortMapping(args[1])
+	if err != nil {
+		return err
+	}
+
+	boxID, _, err := ResolveBoxIDPrefix(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve box ID: %w", err)
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+	var port struct {
+		ExternalPort int `json:"externalPort"`
+	}
+	if err := client.Get(ctx, fmt.Sprintf("boxes/%s/ports/%d", url.PathEscape(boxID), remotePort), nil, &port); err != nil {
+		return fmt.Errorf("failed to look up port %d of box %s: %v", remotePort, boxID, err)
+	}
+
+	// Published ports live on the host running the server
+	apiURL, err := url.Parse(config.GetLocalAPIURL())
+	if err != nil {
+		return fmt.Errorf("invalid API endpoint: %v", err)
+	}
+	target := net.JoinHostPort(apiURL.Hostname(), strconv.Itoa(port.ExternalPort))
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(opts.Address, strconv.Itoa(localPort)))
+	if err != nil {
+		return fmt.Errorf("failed to listen on local port %d: %v", localPort, err)
+	}
+	fmt.Fprintf(os.Stderr, "Forwarding %s -> box %s port %d\n", listener.Addr(), boxID, remotePort)
+	if ready != nil {
+		ready(listener.Addr())
+	}
+	return forwardConnections(ctx, listener, target)
+}
+
+// forwardConnections proxies every connection accepted on listener to target
+// until ctx is done, then closes the listener and waits for open connections.
+func forwardConnections(ctx context.Context, listener net.Listener, target string) error {
+	var wg sync.WaitGroup
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			wg.Wait()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			proxyConnection(ctx, conn, target)
+		}()
+	}
+}
+
+// proxyConnection copies bytes both ways between conn and a new connection
+// to target, passing on half-closes.
+func proxyConnection(ctx context.Context, conn net.Conn, target string) {
+	defer conn.Close()
+	var dialer net.Dialer
+	upstream, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to connect to %s: %v\n", target, err)
+		return
+	}
+	defer upstream.Close()
+
+	// Tear down both sides when forwarding stops
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+		upstream.Close()
+	})
+	defer stop()
+
+	done := make(chan struct{}, 2)
+	pipe := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		if tcp, ok := dst.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		} else {
+			dst.Close()
+		}
+		done <- struct{}{}
+	}
+	go pipe(upstream, conn)
+	go pipe(conn, upstream)
+	<-done
+	<-done
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startBoxPort serves a line based echo on a random port, standing in for
+// the published port of a service in a box.
+func startBoxPort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					fmt.Fprintf(conn, "box got %s\n", scanner.Text())
+				}
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestForwardProxiesBothWays(t *testing.T) {
+	boxPort := startBoxPort(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/boxes":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{{"id": "box-1", "type": "linux", "status": "running"}},
+			})
+		case "/api/v1/boxes/box-1/ports/8080":
+			json.NewEncoder(w).Encode(map[string]int{"internalPort": 8080, "externalPort": boxPort})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	origAPIURL := os.Getenv("API_ENDPOINT")
+	defer os.Setenv("API_ENDPOINT", origAPIURL)
+	os.Setenv("API_ENDPOINT", server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan net.Addr, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- runForward(ctx, &BoxForwardOptions{Address: "127.0.0.1"}, []string{"box-1", "0:8080"}, func(addr net.Addr) { ready <- addr })
+	}()
+	var addr net.Addr
+	select {
+	case addr = <-ready:
+	case err := <-done:
+		t.Fatalf("forward failed: %v", err)
+	}
+
+	// Concurrent connections are proxied independently
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", addr.String())
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for j := 0; j < 2; j++ {
+				fmt.Fprintf(conn, "hello %d.%d\n", i, j)
+				line, err := reader.ReadString('\n')
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf("box got hello %d.%d", i, j), strings.TrimSpace(line))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestParsePortMapping(t *testing.T) {
+	local, remote, err := parsePortMapping("9000:8080")
+	require.NoError(t, err)
+	assert.Equal(t, 9000, local)
+	assert.Equal(t, 8080, remote)
+
+	local, remote, err = parsePortMapping("8080")
+	require.NoError(t, err)
+	assert.Equal(t, 8080, local)
+	assert.Equal(t, 8080, remote)
+
+	for _, mapping := range []string{"", "http", "9000:", "9000:0", "70000:80", "1:2:3"} {
+		_, _, err := parsePortMapping(mapping)
+		assert.Error(t, err, mapping)
+	}
+}
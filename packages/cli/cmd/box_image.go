@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/babelcloud/gbox-sdk-go/option"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxImageListOptions struct {
+	OutputFormat string
+	All          bool
+}
+
+type BoxImagePruneOptions struct {
+	OutputFormat string
+	Force        bool
+}
+
+// NewBoxImageCommand creates the command grouping box image operations
+func NewBoxImageCommand() *cobra.Command {
+	imageCmd := &cobra.Command{
+		Use:   "image",
+		Short: "Manage the images boxes are created from",
+	}
+
+	imageCmd.AddCommand(
+		NewBoxImageListCommand(),
+		NewBoxImagePruneCommand(),
+	)
+
+	return imageCmd
+}
+
+func NewBoxImageListCommand() *cobra.Command {
+	opts := &BoxImageListOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the images available to create boxes from",
+		Long: `List the images available on the server to create boxes from. By default only
+gbox images and images used by existing boxes are listed.`,
+		Example: `  gbox box image list
+  gbox box image list --all
+  gbox box image list --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImageList(opts, os.Stdout)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+	flags.BoolVarP(&opts.All, "all", "a", false, "List every image on the server")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runImageList(opts *BoxImageListOptions, out io.Writer) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var reqOpts []option.RequestOption
+	if opts.All {
+		reqOpts = append(reqOpts, option.WithQuery("all", "true"))
+	}
+	var result model.BoxImageListResult
+	if err := client.Get(context.Background(), "boxes/images", nil, &result, reqOpts...); err != nil {
+		return fmt.Errorf("failed to list images: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		resultJSON, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintln(out, string(resultJSON))
+		return nil
+	}
+
+	if len(result.Images) == 0 {
+		fmt.Fprintln(out, "No images found")
+		return nil
+	}
+
+	fmt.Fprintln(out, "IMAGE                                              SIZE       CREATED")
+	fmt.Fprintln(out, "-------------------------------------------------- ---------- ---------------")
+	for _, img := range result.Images {
+		size, created := "-", "-"
+		if img.Size > 0 {
+			size = humanSize(float64(img.Size))
+		}
+		if !img.CreatedAt.IsZero() {
+			created = formatAge(time.Since(img.CreatedAt)) + " ago"
+		}
+		refs := img.RepoTags
+		if len(refs) == 0 {
+			refs = []string{"<none>:<none>"}
+		}
+		// An image with several tags is listed once per tag, like docker images
+		for _, ref := range refs {
+			fmt.Fprintf(out, "%-50s %-10s %s\n", ref, size, created)
+		}
+	}
+	return nil
+}
+
+func NewBoxImagePruneCommand() *cobra.Command {
+	opts := &BoxImagePruneOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove dangling images",
+		Long: `Remove dangling images, untagged images left behind when a newer version is
+pulled, to reclaim disk space on the server.`,
+		Example: `  gbox box image prune
+  gbox box image prune --force --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImagePrune(opts, os.Stdin, os.Stdout)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+	flags.BoolVarP(&opts.Force, "force", "f", false, "Prune without confirmation")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runImagePrune(opts *BoxImagePruneOptions, in io.Reader, out io.Writer) error {
+	if !opts.Force {
+		fmt.Fprint(out, "This will remove all dangling images. Are you sure you want to continue? [y/N] ")
+		reply, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read input: %v", err)
+		}
+		reply = strings.TrimSpace(strings.ToLower(reply))
+		if reply != "y" && reply != "yes" {
+			if opts.OutputFormat == "json" {
+				fmt.Fprintln(out, `{"status":"cancelled","message":"Operation cancelled by user"}`)
+			} else {
+				fmt.Fprintln(out, "Operation cancelled")
+			}
+			return nil
+		}
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var result model.BoxImagePruneResult
+	if err := client.Post(context.Background(), "boxes/images/prune", nil, &result); err != nil {
+		return fmt.Errorf("failed to prune images: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		resultJSON, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintln(out, string(resultJSON))
+		return nil
+	}
+
+	for _, id := range result.DeletedIDs {
+		fmt.Fprintf(out, "Deleted: %s\n", id)
+	}
+	fmt.Fprintf(out, "Total reclaimed space: %s\n", humanSize(float64(result.SpaceReclaimed)))
+	return nil
+}
+
+// formatAge renders a duration in the largest whole unit, e.g. "3 days"
+func formatAge(d time.Duration) string {
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"week", 7 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	for _, u := range units {
+		if n := int(d / u.size); n > 0 {
+			if n == 1 {
+				return "1 " + u.name
+			}
+			return fmt.Sprintf("%d %ss", n, u.name)
+		}
+	}
+	return "less than a minute"
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageListRendersRepoTagSizeAndAge(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/boxes/images", r.URL.Path)
+		query = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"images": []map[string]interface{}{
+				{"id": "sha256:pw", "repoTags": []string{"babelcloud/gbox-playwright:latest"}, "size": 2500000000, "createdAt": time.Now().Add(-72 * time.Hour)},
+				{"id": "sha256:none", "repoTags": []string{}, "size": 0},
+			},
+		})
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	var out bytes.Buffer
+	require.NoError(t, runImageList(&BoxImageListOptions{OutputFormat: "text", All: true}, &out))
+	assert.Equal(t, "all=true", query)
+	assert.Regexp(t, `babelcloud/gbox-playwright:latest +2\.5GB +3 days ago`, out.String())
+	assert.Regexp(t, `<none>:<none> +- +-`, out.String())
+}
+
+func TestImagePruneReportsFreedSpace(t *testing.T) {
+	pruned := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST /api/v1/boxes/images/prune", r.Method+" "+r.URL.Path)
+		pruned++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"deletedIds":     []string{"sha256:a", "sha256:b"},
+			"spaceReclaimed": 1500000,
+		})
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	// Declining the confirmation prunes nothing
+	var out bytes.Buffer
+	require.NoError(t, runImagePrune(&BoxImagePruneOptions{}, strings.NewReader("n\n"), &out))
+	assert.Zero(t, pruned)
+	assert.Contains(t, out.String(), "Operation cancelled")
+
+	out.Reset()
+	require.NoError(t, runImagePrune(&BoxImagePruneOptions{}, strings.NewReader("y\n"), &out))
+	assert.Equal(t, 1, pruned)
+	assert.Contains(t, out.String(), "Deleted: sha256:b")
+	assert.Contains(t, out.String(), "Total reclaimed space: 1.5MB")
+
+	out.Reset()
+	require.NoError(t, runImagePrune(&BoxImagePruneOptions{Force: true}, strings.NewReader(""), &out))
+	assert.Equal(t, 2, pruned, "--force skips the confirmation")
+}
@@ -81,9 +81,17 @@ func runInspect(boxIDPrefix string, opts *BoxInspectOptions) error {
 		if err := json.Unmarshal(boxBytes, &data); err != nil {
 			return fmt.Errorf("failed to parse box data: %v", err)
 		}
+		// uptime is not part of the SDK model, so read it from the raw response
+		var raw map[string]interface{}
+		if err := json.Unmarshal([]byte(box.RawJSON()), &raw); err == nil {
+			if startedAt, ok := raw["startedAt"]; ok {
+				data["startedAt"] = startedAt
+			}
+			data["uptime"] = formatUptime(raw)
+		}
 
 		// 定义期望的键顺序
-		orderedKeys := []string{"id", "image", "status", "createdAt", "extra_labels"}
+		orderedKeys := []string{"id", "image", "status", "createdAt", "startedAt", "uptime", "extra_labels"}
 		printedKeys := make(map[string]bool)
 
 		// 按期望顺序打印键
@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
+	"time"
 
 	// 内部 SDK 客户端
 	sdk "github.com/babelcloud/gbox-sdk-go"
+	"github.com/babelcloud/gbox-sdk-go/option"
+	"github.com/babelcloud/gbox/packages/cli/config"
 	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
 	"github.com/spf13/cobra"
 )
@@ -18,6 +20,9 @@ import (
 type BoxListOptions struct {
 	OutputFormat string
 	Filters      []string
+	Group        string
+	Size         bool
+	Mine         bool
 }
 
 type BoxResponse struct {
@@ -38,7 +43,10 @@ func NewBoxListCommand() *cobra.Command {
 		Example: `  gbox box list
   gbox box list --output json
   gbox box list --filter 'label=project=myapp'
-  gbox box list --filter 'ancestor=ubuntu:latest'`,
+  gbox box list --filter 'ancestor=ubuntu:latest'
+  gbox box list --group web
+  gbox box list --mine
+  gbox box list --size`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runList(opts)
 		},
@@ -47,6 +55,9 @@ func NewBoxListCommand() *cobra.Command {
 	flags := cmd.Flags()
 	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
 	flags.StringArrayVarP(&opts.Filters, "filter", "f", []string{}, "Filter boxes (format: field=value)")
+	flags.StringVar(&opts.Group, "group", "", "Only list boxes in the named group")
+	flags.BoolVar(&opts.Mine, "mine", false, "Only list boxes created by you")
+	flags.BoolVarP(&opts.Size, "size", "s", false, "Display disk usage of each box (slower, computed by the server on request)")
 
 	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
@@ -57,12 +68,17 @@ func NewBoxListCommand() *cobra.Command {
 
 func runList(opts *BoxListOptions) error {
 	// 如果显式指定了 API_ENDPOINT，则直接通过 HTTP 调用以保持原始字段（如 image）
-	if base := os.Getenv("API_ENDPOINT"); base != "" {
-		boxes, err := fetchBoxesDirect(base, opts.Filters)
+	filters := opts.Filters
+	if opts.Group != "" {
+		filters = append(filters, "group="+opts.Group)
+	}
+
+	if base := config.GetAPIEndpoint(); base != "" {
+		boxes, err := fetchBoxesDirect(base, filters, opts.Size, opts.Mine)
 		if err != nil {
 			return fmt.Errorf("API call failed: %v", err)
 		}
-		return outputBoxes(boxes, opts.OutputFormat)
+		return outputBoxes(boxes, opts.OutputFormat, opts.Size)
 	}
 
 	// 创建 SDK 客户端
@@ -72,21 +88,35 @@ func runList(opts *BoxListOptions) error {
 	}
 
 	// 解析过滤参数
-	params := buildListParams(opts.Filters)
+	params := buildListParams(filters)
+
+	// size and group are not part of the SDK params yet
+	var reqOpts []option.RequestOption
+	for _, f := range filters {
+		if strings.HasPrefix(f, "group=") {
+			reqOpts = append(reqOpts, option.WithQueryAdd("filter", f))
+		}
+	}
+	if opts.Size {
+		reqOpts = append(reqOpts, option.WithQuery("size", "true"))
+	}
+	if opts.Mine {
+		reqOpts = append(reqOpts, option.WithQuery("mine", "true"))
+	}
 
 	// 调用 API
 	ctx := context.Background()
-	resp, err := client.V1.Boxes.List(ctx, params)
+	resp, err := client.V1.Boxes.List(ctx, params, reqOpts...)
 	if err != nil {
 		return fmt.Errorf("API call failed: %v", err)
 	}
 
 	// 输出结果
-	return printResponse(resp, opts.OutputFormat)
+	return printResponse(resp, opts.OutputFormat, opts.Size)
 }
 
 // fetchBoxesDirect calls the boxes API directly and returns the raw data slice
-func fetchBoxesDirect(base string, filters []string) ([]map[string]interface{}, error) {
+func fetchBoxesDirect(base string, filters []string, size, mine bool) ([]map[string]interface{}, error) {
 	u, err := url.Parse(strings.TrimSuffix(base, "/"))
 	if err != nil {
 		return nil, err
@@ -99,11 +129,25 @@ func fetchBoxesDirect(base string, filters []string) ([]map[string]interface{},
 		if strings.HasPrefix(f, "label=") || strings.HasPrefix(f, "labels=") {
 			q.Add("labels", strings.TrimPrefix(strings.TrimPrefix(f, "label="), "labels="))
 		}
+		if strings.HasPrefix(f, "group=") {
+			q.Add("filter", f)
+		}
 		// other filters can be added similarly when needed
 	}
+	if size {
+		q.Set("size", "true")
+	}
+	if mine {
+		q.Set("mine", "true")
+	}
 	u.RawQuery = q.Encode()
 
-	resp, err := http.Get(u.String())
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(gboxclient.UserHeader, gboxclient.LocalUser())
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -123,7 +167,7 @@ func fetchBoxesDirect(base string, filters []string) ([]map[string]interface{},
 }
 
 // outputBoxes prints boxes according to output format using raw maps
-func outputBoxes(data []map[string]interface{}, format string) error {
+func outputBoxes(data []map[string]interface{}, format string, showSize bool) error {
 	if format == "json" {
 		out := map[string]interface{}{"data": data}
 		bytes, _ := json.MarshalIndent(out, "", "  ")
@@ -136,17 +180,76 @@ func outputBoxes(data []map[string]interface{}, format string) error {
 		return nil
 	}
 
-	fmt.Println("ID                                      TYPE       STATUS")
-	fmt.Println("---------------------------------------- ---------- ---------------")
+	printBoxTableHeader(showSize)
 	for _, m := range data {
 		id, _ := m["id"].(string)
 		typ, _ := m["type"].(string)
 		status, _ := m["status"].(string)
-		fmt.Printf("%-40s %-10s %s\n", id, typ, status)
+		printBoxTableRow(id, typ, status, showSize, m)
 	}
 	return nil
 }
 
+// printBoxTableHeader prints the text table header, with a SIZE column when requested
+func printBoxTableHeader(showSize bool) {
+	if showSize {
+		fmt.Println("ID                                      TYPE       STATUS          UPTIME       OWNER                SIZE")
+		fmt.Println("---------------------------------------- ---------- --------------- ------------ -------------------- ------------------------------")
+		return
+	}
+	fmt.Println("ID                                      TYPE       STATUS          UPTIME       OWNER")
+	fmt.Println("---------------------------------------- ---------- --------------- ------------ --------------------")
+}
+
+// printBoxTableRow prints a single box row; raw holds the box's JSON fields
+// for the uptime, owner and size lookup
+func printBoxTableRow(id, typ, status string, showSize bool, raw map[string]interface{}) {
+	owner, _ := raw["owner"].(string)
+	if owner == "" {
+		owner = "-"
+	}
+	if !showSize {
+		fmt.Printf("%-40s %-10s %-15s %-12s %s\n", id, typ, status, formatUptime(raw), owner)
+		return
+	}
+	fmt.Printf("%-40s %-10s %-15s %-12s %-20s %s\n", id, typ, status, formatUptime(raw), owner, formatBoxSize(raw))
+}
+
+// formatUptime renders how long a running box has been up, or "-" for a box
+// that is not running
+func formatUptime(raw map[string]interface{}) string {
+	seconds, _ := raw["uptime"].(float64)
+	if seconds <= 0 {
+		return "-"
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+// formatBoxSize renders disk usage the way docker ps -s does: the writable
+// layer size followed by the total root filesystem size
+func formatBoxSize(raw map[string]interface{}) string {
+	sizeRw, okRw := raw["sizeRw"].(float64)
+	sizeRootFs, okRoot := raw["sizeRootFs"].(float64)
+	if !okRw && !okRoot {
+		return "-"
+	}
+	return fmt.Sprintf("%s (virtual %s)", humanSize(sizeRw), humanSize(sizeRootFs))
+}
+
+// humanSize formats a byte count using decimal units
+func humanSize(bytes float64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+	i := 0
+	for bytes >= 1000 && i < len(units)-1 {
+		bytes /= 1000
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f%s", bytes, units[i])
+	}
+	return fmt.Sprintf("%.3g%s", bytes, units[i])
+}
+
 // buildListParams parses CLI --filter flags into SDK query parameters
 func buildListParams(filters []string) sdk.V1BoxListParams {
 	var params sdk.V1BoxListParams
@@ -169,7 +272,7 @@ func buildListParams(filters []string) sdk.V1BoxListParams {
 }
 
 // printResponse handles output based on the selected format
-func printResponse(resp *sdk.V1BoxListResponse, outputFormat string) error {
+func printResponse(resp *sdk.V1BoxListResponse, outputFormat string, showSize bool) error {
 	if resp == nil {
 		return fmt.Errorf("empty response")
 	}
@@ -181,6 +284,10 @@ func printResponse(resp *sdk.V1BoxListResponse, outputFormat string) error {
 			Image  string `json:"image"`
 			Status string `json:"status"`
 			Type   string `json:"type"`
+			Owner  string `json:"owner,omitempty"`
+
+			SizeRw     interface{} `json:"sizeRw,omitempty"`
+			SizeRootFs interface{} `json:"sizeRootFs,omitempty"`
 		}
 		var out struct {
 			Data []simpleBox `json:"data"`
@@ -206,6 +313,12 @@ func printResponse(resp *sdk.V1BoxListResponse, outputFormat string) error {
 			if v, ok := m["type"].(string); ok {
 				sb.Type = v
 			}
+			if v, ok := m["owner"].(string); ok {
+				sb.Owner = v
+			}
+			if showSize {
+				sb.SizeRw, sb.SizeRootFs = m["sizeRw"], m["sizeRootFs"]
+			}
 			out.Data = append(out.Data, sb)
 		}
 
@@ -222,11 +335,21 @@ func printResponse(resp *sdk.V1BoxListResponse, outputFormat string) error {
 		return nil
 	}
 
-	fmt.Println("ID                                      TYPE       STATUS")
-	fmt.Println("---------------------------------------- ---------- ---------------")
+	// uptimes, owners and sizes are not part of the SDK model, so read them from the raw response
+	rawBoxes := map[string]map[string]interface{}{}
+	var raw struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	_ = json.Unmarshal([]byte(resp.RawJSON()), &raw)
+	for _, m := range raw.Data {
+		if id, ok := m["id"].(string); ok {
+			rawBoxes[id] = m
+		}
+	}
 
+	printBoxTableHeader(showSize)
 	for _, box := range resp.Data {
-		fmt.Printf("%-40s %-10s %s\n", box.ID, box.Type, box.Status)
+		printBoxTableRow(box.ID, string(box.Type), box.Status, showSize, rawBoxes[box.ID])
 	}
 
 	return nil
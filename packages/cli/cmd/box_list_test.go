@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/babelcloud/gbox/packages/cli/config"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListMineIdentifiesLocalUser(t *testing.T) {
+	var query, user string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, user = r.URL.RawQuery, r.Header.Get(gboxclient.UserHeader)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{{"id": "box-1", "type": "linux", "status": "running", "owner": user}},
+		})
+	}))
+	defer server.Close()
+
+	origAPIURL := os.Getenv("API_ENDPOINT")
+	defer os.Setenv("API_ENDPOINT", origAPIURL)
+	os.Setenv("API_ENDPOINT", server.URL)
+
+	require.NoError(t, runList(&BoxListOptions{OutputFormat: "json", Mine: true}))
+	assert.Contains(t, query, "mine=true")
+	assert.Equal(t, gboxclient.LocalUser(), user)
+	assert.NotEmpty(t, user)
+}
+
+// Test that --api-endpoint overrides API_ENDPOINT for a single invocation
+func TestAPIEndpointFlagOverridesEnv(t *testing.T) {
+	listed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		listed = r.URL.Path == "/api/v1/boxes"
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": []map[string]interface{}{}})
+	}))
+	defer server.Close()
+	envServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to the API_ENDPOINT server: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer envServer.Close()
+
+	t.Setenv("API_ENDPOINT", envServer.URL)
+	t.Cleanup(func() {
+		apiEndpoint = ""
+		config.SetAPIEndpoint("")
+		rootCmd.SetArgs(nil)
+	})
+
+	rootCmd.SetArgs([]string{"box", "list", "--api-endpoint", server.URL, "-o", "json"})
+	require.NoError(t, rootCmd.Execute())
+	assert.True(t, listed)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/babelcloud/gbox-sdk-go/option"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxLogsOptions struct {
+	Follow      bool
+	Tail        int
+	Grep        string
+	InvertMatch bool
+}
+
+func NewBoxLogsCommand() *cobra.Command {
+	opts := &BoxLogsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "logs <box-id>",
+		Short: "Show the output of a box",
+		Long:  "Show the stdout and stderr of a box's main process. --grep filters lines on the server, so lines that do not match are never transferred.",
+		Example: `  gbox box logs 550e8400-e29b-41d4-a716-446655440000
+  gbox box logs 550e8400 -f --grep 'ERROR|WARN'
+  gbox box logs 550e8400 --tail 100 --grep healthcheck --invert-match`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLogs(opts, args[0], os.Stdout)
+		},
+		ValidArgsFunction: completeBoxIDs,
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVarP(&opts.Follow, "follow", "f", false, "Keep streaming new lines")
+	flags.IntVar(&opts.Tail, "tail", -1, "Number of lines to show from the end of the logs (default all)")
+	flags.StringVar(&opts.Grep, "grep", "", "Only show lines matching this regular expression")
+	flags.BoolVarP(&opts.InvertMatch, "invert-match", "v", false, "Only show lines not matching --grep")
+
+	return cmd
+}
+
+func runLogs(opts *BoxLogsOptions, boxIDPrefix string, out io.Writer) error {
+	if opts.InvertMatch && opts.Grep == "" {
+		return fmt.Errorf("--invert-match requires --grep")
+	}
+
+	resolvedBoxID, _, err := ResolveBoxIDPrefix(boxIDPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to resolve box ID: %w", err)
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	reqOpts := []option.RequestOption{option.WithHeader("Accept", "text/plain")}
+	if opts.Follow {
+		reqOpts = append(reqOpts, option.WithQuery("follow", "true"))
+	}
+	if opts.Tail >= 0 {
+		reqOpts = append(reqOpts, option.WithQuery("tail", strconv.Itoa(opts.Tail)))
+	}
+	if opts.Grep != "" {
+		reqOpts = append(reqOpts, option.WithQuery("grep", opts.Grep))
+	}
+	if opts.InvertMatch {
+		reqOpts = append(reqOpts, option.WithQuery("invertMatch", "true"))
+	}
+
+	var resp *http.Response
+	if err := client.Get(context.Background(), "boxes/"+resolvedBoxID+"/logs", nil, &resp, reqOpts...); err != nil {
+		return fmt.Errorf("failed to get logs: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("failed to read logs: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoxLogsGrep(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/boxes":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{{"id": "box-1", "type": "linux", "status": "running"}},
+			})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/boxes/box-1/logs":
+			query = r.URL.Query()
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("GET /api 200\n"))
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	var out bytes.Buffer
+	require.NoError(t, runLogs(&BoxLogsOptions{Tail: -1, Grep: "^error", InvertMatch: true}, "box-1", &out))
+	assert.Equal(t, "GET /api 200\n", out.String())
+	assert.Equal(t, "^error", query.Get("grep"))
+	assert.Equal(t, "true", query.Get("invertMatch"))
+	assert.False(t, query.Has("tail"))
+
+	assert.Error(t, runLogs(&BoxLogsOptions{Tail: -1, InvertMatch: true}, "box-1", &out))
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxResurrectOptions struct {
+	OutputFormat string
+}
+
+func NewBoxResurrectCommand() *cobra.Command {
+	opts := &BoxResurrectOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "resurrect <box-id>",
+		Short: "Recreate a reclaimed box from its snapshot",
+		Long: `Recreate a box that idle reclaim deleted, from the image it was committed to
+before deletion. The box comes back with its ID, configuration and filesystem.
+Requires reclaimSnapshotEnabled on a Docker server. The full box ID is needed,
+since the deleted box is not listed.`,
+		Example: `  gbox box resurrect 550e8400-e29b-41d4-a716-446655440000
+  gbox box resurrect 550e8400-e29b-41d4-a716-446655440000 --output json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runResurrect(opts, args[0])
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runResurrect(opts *BoxResurrectOptions, boxID string) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var box model.Box
+	if err := client.Post(context.Background(), "boxes/"+boxID+"/resurrect", nil, &box); err != nil {
+		return fmt.Errorf("failed to resurrect box: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		out, _ := json.MarshalIndent(box, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Printf("Box %s resurrected, status: %s\n", box.ID, box.Status)
+	}
+	return nil
+}
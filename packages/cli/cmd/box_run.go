@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/babelcloud/gbox-sdk-go/option"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxRunOptions struct {
+	Language      string
+	File          string
+	Timeout       string
+	WorkingDir    string
+	Env           []string
+	CombineOutput bool
+	Stream        bool
+}
+
+func NewBoxRunCommand() *cobra.Command {
+	opts := &BoxRunOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "run <box-id> [code] [-- args...]",
+		Short: "Run code in a box",
+		Long: `Run a snippet of code in a box and print its output. The code is given as an
+argument or read from --file ("-" reads stdin). With --stream, output is printed
+as it is produced instead of once the code has finished.`,
+		Example: `  gbox box run 550e8400 'echo hello'
+  gbox box run 550e8400 --language python3 --file train.py --stream
+  gbox box run 550e8400 -l python3 'import sys; print(sys.argv)' -- --epochs 3`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var code string
+			var argv []string
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				argv = args[dash:]
+				args = args[:dash]
+			}
+			switch {
+			case len(args) > 2:
+				return fmt.Errorf("expected the box ID and at most one code argument, pass code arguments after --")
+			case len(args) == 2 && opts.File != "":
+				return fmt.Errorf("code cannot be given both as an argument and with --file")
+			case len(args) == 2:
+				code = args[1]
+			case opts.File != "":
+				data, err := readCodeFile(opts.File)
+				if err != nil {
+					return err
+				}
+				code = string(data)
+			default:
+				return fmt.Errorf("no code to run, give it as an argument or with --file")
+			}
+			return runCode(opts, args[0], code, argv, os.Stdout, os.Stderr)
+		},
+		ValidArgsFunction: completeBoxIDs,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.Language, "language", "l", "bash", "Language of the code: bash, python3 or typescript")
+	flags.StringVarP(&opts.File, "file", "f", "", "Read the code from a file, or from stdin with -")
+	flags.StringVar(&opts.Timeout, "timeout", "", "Maximum run time of the code (e.g., 30s)")
+	flags.StringVarP(&opts.WorkingDir, "workdir", "w", "", "Working directory of the code in the box")
+	flags.StringArrayVarP(&opts.Env, "env", "e", nil, "Set an environment variable for the code (KEY=VALUE, repeatable)")
+	flags.BoolVar(&opts.CombineOutput, "combine-output", false, "Merge stderr into stdout, keeping the order it was written in")
+	flags.BoolVar(&opts.Stream, "stream", false, "Print output as it is produced")
+
+	return cmd
+}
+
+// readCodeFile reads the code to run from path, or from stdin for "-"
+func readCodeFile(path string) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read code from stdin: %v", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read code file: %v", err)
+	}
+	return data, nil
+}
+
+func runCode(opts *BoxRunOptions, boxIDPrefix, code string, argv []string, stdout, stderr io.Writer) error {
+	envs, err := parseExecEnv(opts.Env)
+	if err != nil {
+		return err
+	}
+
+	resolvedBoxID, _, err := ResolveBoxIDPrefix(boxIDPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to resolve box ID: %w", err)
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	params := model.BoxRunCodeParams{
+		Code:          code,
+		Language:      opts.Language,
+		Argv:          argv,
+		Timeout:       opts.Timeout,
+		WorkingDir:    opts.WorkingDir,
+		Envs:          envs,
+		CombineOutput: opts.CombineOutput,
+	}
+	path := fmt.Sprintf("boxes/%s/run-code", resolvedBoxID)
+
+	if !opts.Stream {
+		var result model.BoxRunCodeResult
+		if err := client.Post(context.Background(), path, params, &result); err != nil {
+			return fmt.Errorf("failed to run code: %v", err)
+		}
+		io.WriteString(stdout, result.Stdout)
+		io.WriteString(stderr, result.Stderr)
+		if result.ExitCode != 0 {
+			return fmt.Errorf("code exited with code %d", result.ExitCode)
+		}
+		return nil
+	}
+
+	var resp *http.Response
+	err = client.Post(context.Background(), path, params, &resp,
+		option.WithQuery("stream", "true"),
+		option.WithHeader("Accept", "application/json-stream"))
+	if err != nil {
+		return fmt.Errorf("failed to run code: %v", err)
+	}
+	defer resp.Body.Close()
+	return printRunCodeEvents(resp.Body, stdout, stderr)
+}
+
+// printRunCodeEvents writes the output events of a streamed run as they
+// arrive and returns an error for a failed run or a non-zero exit code
+func printRunCodeEvents(r io.Reader, stdout, stderr io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event struct {
+			model.BoxRunCodeEvent
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("invalid run event: %v", err)
+		}
+		switch event.Status {
+		case model.RunCodeEventStdout:
+			io.WriteString(stdout, event.Data)
+		case model.RunCodeEventStderr:
+			io.WriteString(stderr, event.Data)
+		case model.RunCodeEventExit:
+			if event.ExitCode != nil && *event.ExitCode != 0 {
+				return fmt.Errorf("code exited with code %d", *event.ExitCode)
+			}
+			return nil
+		case "error":
+			return fmt.Errorf("failed to run code: %s", event.Error)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read run output: %v", err)
+	}
+	return fmt.Errorf("run output ended before the code exited")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+func TestBoxRunStream(t *testing.T) {
+	var params model.BoxRunCodeParams
+	var stream string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/boxes":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{{"id": "box-1", "type": "linux", "status": "running"}},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/boxes/box-1/run-code":
+			json.NewDecoder(r.Body).Decode(&params)
+			stream = r.URL.Query().Get("stream")
+			w.Header().Set("Content-Type", "application/json-stream")
+			w.Write([]byte(`{"status":"stdout","data":"epoch 1\n"}` + "\n"))
+			w.Write([]byte(`{"status":"stderr","data":"warning\n"}` + "\n"))
+			w.Write([]byte(`{"status":"stdout","data":"epoch 2\n"}` + "\n"))
+			w.Write([]byte(`{"status":"exit","exitCode":2}` + "\n"))
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	var stdout, stderr bytes.Buffer
+	opts := &BoxRunOptions{Language: "python3", Stream: true, Env: []string{"SEED=1"}}
+	err := runCode(opts, "box-1", "print(1)", []string{"--epochs", "2"}, &stdout, &stderr)
+	assert.EqualError(t, err, "code exited with code 2")
+	assert.Equal(t, "epoch 1\nepoch 2\n", stdout.String())
+	assert.Equal(t, "warning\n", stderr.String())
+
+	assert.Equal(t, "true", stream)
+	assert.Equal(t, "print(1)", params.Code)
+	assert.Equal(t, "python3", params.Language)
+	assert.Equal(t, []string{"--epochs", "2"}, params.Argv)
+	assert.Equal(t, map[string]string{"SEED": "1"}, params.Envs)
+
+	// A stream cut off before the exit event is an error
+	require.Error(t, printRunCodeEvents(bytes.NewBufferString(`{"status":"stdout","data":"x"}`+"\n"), &stdout, &stderr))
+	assert.EqualError(t, printRunCodeEvents(bytes.NewBufferString(`{"status":"error","error":"box is not running"}`+"\n"), &stdout, &stderr),
+		"failed to run code: box is not running")
+}
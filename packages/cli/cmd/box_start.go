@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	sdk "github.com/babelcloud/gbox-sdk-go"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxStartOptions struct {
+	OutputFormat string
+	StartAll     bool
+	Group        string
+}
+
+func NewBoxStartCommand() *cobra.Command {
+	opts := &BoxStartOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "start [box-id]",
+		Short: "Start a box by its ID",
+		Long:  "Start a stopped box by its ID, every stopped box of a group, or all stopped boxes",
+		Example: `  gbox box start 550e8400-e29b-41d4-a716-446655440000
+  gbox box start --all
+  gbox box start --group web`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStart(opts, args)
+		},
+		ValidArgsFunction: completeBoxIDs,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+	flags.BoolVarP(&opts.StartAll, "all", "a", false, "Start every stopped box")
+	flags.StringVar(&opts.Group, "group", "", "Start every stopped box of the named group")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+// startable reports whether a box in status can be started
+func startable(status string) bool {
+	return status == "stopped" || status == "created"
+}
+
+func runStart(opts *BoxStartOptions, args []string) error {
+	selectors := 0
+	for _, set := range []bool{opts.StartAll, opts.Group != "", len(args) > 0} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors == 0 {
+		return fmt.Errorf("must specify either --all, --group or a box ID")
+	}
+	if selectors > 1 {
+		return fmt.Errorf("--all, --group and a box ID are mutually exclusive")
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+	ctx := context.Background()
+
+	if opts.StartAll || opts.Group != "" {
+		boxes, err := listBulkBoxes(ctx, client, opts.Group)
+		if err != nil {
+			return err
+		}
+		results := runBulk(ctx, boxes, startable, func(ctx context.Context, id string) error {
+			_, err := client.V1.Boxes.Start(ctx, id, sdk.V1BoxStartParams{})
+			return err
+		})
+		return printBulkResults(results, "started", opts.OutputFormat)
+	}
+
+	resolvedBoxID, _, err := ResolveBoxIDPrefix(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve box ID: %w", err)
+	}
+	if _, err := client.V1.Boxes.Start(ctx, resolvedBoxID, sdk.V1BoxStartParams{}); err != nil {
+		return fmt.Errorf("failed to start box: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		fmt.Println(`{"status":"success","message":"Box started successfully"}`)
+	} else {
+		fmt.Printf("Box %s started successfully\n", resolvedBoxID)
+	}
+	return nil
+}
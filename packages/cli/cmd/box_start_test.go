@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that start --all only starts the boxes that are stopped
+func TestStartAllStartsOnlyStoppedBoxes(t *testing.T) {
+	boxes := []map[string]interface{}{
+		{"id": "box-running", "type": "linux", "status": "running"},
+		{"id": "box-stopped-1", "type": "linux", "status": "stopped"},
+		{"id": "box-stopped-2", "type": "linux", "status": "stopped"},
+		{"id": "box-removing", "type": "linux", "status": "removing"},
+	}
+
+	var mu sync.Mutex
+	var started []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/boxes":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": boxes, "page": 1, "pageSize": 10, "total": len(boxes)})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/start"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/boxes/"), "/start")
+			mu.Lock()
+			started = append(started, id)
+			mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "type": "linux", "status": "running"})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	origAPIURL := os.Getenv("API_ENDPOINT")
+	defer os.Setenv("API_ENDPOINT", origAPIURL)
+	os.Setenv("API_ENDPOINT", server.URL)
+
+	err := runStart(&BoxStartOptions{OutputFormat: "json", StartAll: true}, nil)
+	require.NoError(t, err)
+
+	sort.Strings(started)
+	assert.Equal(t, []string{"box-stopped-1", "box-stopped-2"}, started)
+}
+
+// Test that a failed box is reported without stopping the others
+func TestRunBulkReportsFailures(t *testing.T) {
+	boxes := []bulkBox{{ID: "a", Status: "running"}, {ID: "b", Status: "running"}, {ID: "c", Status: "stopped"}}
+	results := runBulk(context.Background(), boxes, func(status string) bool { return status == "running" }, func(_ context.Context, id string) error {
+		if id == "a" {
+			return assert.AnError
+		}
+		return nil
+	})
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "failed", results[0].Result)
+	assert.Equal(t, "done", results[1].Result)
+	assert.Equal(t, bulkResult{ID: "c", Result: "skipped", Note: "box is stopped"}, results[2])
+	assert.Error(t, printBulkResults(results, "stopped", "json"))
+}
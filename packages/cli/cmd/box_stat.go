@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/babelcloud/gbox-sdk-go/option"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxStatOptions struct {
+	OutputFormat string
+}
+
+func NewBoxStatCommand() *cobra.Command {
+	opts := &BoxStatOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "stat <box-id> <path>",
+		Short: "Show metadata of a file in a box",
+		Long:  "Show the type, size, mode, owner and modification time of a path in a box without downloading it. Symlinks are not followed; their target is shown instead.",
+		Example: `  gbox box stat 550e8400-e29b-41d4-a716-446655440000 /etc/hosts
+  gbox box stat 550e8400 /usr/bin/python3 --output json`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStat(opts, args[0], args[1])
+		},
+		ValidArgsFunction: completeBoxIDs,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runStat(opts *BoxStatOptions, boxIDPrefix, path string) error {
+	resolvedBoxID, _, err := ResolveBoxIDPrefix(boxIDPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to resolve box ID: %w", err)
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var stat model.BoxFileStat
+	if err := client.Get(context.Background(), "boxes/"+resolvedBoxID+"/stat", nil, &stat, option.WithQuery("path", path)); err != nil {
+		return fmt.Errorf("failed to stat %s: %v", path, err)
+	}
+
+	if opts.OutputFormat == "json" {
+		out, _ := json.MarshalIndent(stat, "", "  ")
+		fmt.Println(string(out))
+		return nil
+	}
+
+	name := stat.Path
+	if stat.LinkTarget != "" {
+		name += " -> " + stat.LinkTarget
+	}
+	fmt.Printf("  Path: %s\n", name)
+	fmt.Printf("  Type: %s\n", stat.Type)
+	fmt.Printf("  Size: %d\n", stat.Size)
+	fmt.Printf("  Mode: %s\n", stat.Mode)
+	fmt.Printf(" Owner: %d:%d\n", stat.UID, stat.GID)
+	fmt.Printf("Modify: %s\n", stat.ModTime.Local().Format(time.RFC3339))
+	return nil
+}
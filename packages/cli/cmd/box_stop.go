@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	sdk "github.com/babelcloud/gbox-sdk-go"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxStopOptions struct {
+	OutputFormat string
+	StopAll      bool
+	Group        string
+}
+
+func NewBoxStopCommand() *cobra.Command {
+	opts := &BoxStopOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "stop [box-id]",
+		Short: "Stop a box by its ID",
+		Long:  "Stop a running box by its ID, every running box of a group, or all running boxes",
+		Example: `  gbox box stop 550e8400-e29b-41d4-a716-446655440000
+  gbox box stop --all
+  gbox box stop --group web`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStop(opts, args)
+		},
+		ValidArgsFunction: completeBoxIDs,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+	flags.BoolVarP(&opts.StopAll, "all", "a", false, "Stop every running box")
+	flags.StringVar(&opts.Group, "group", "", "Stop every running box of the named group")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runStop(opts *BoxStopOptions, args []string) error {
+	selectors := 0
+	for _, set := range []bool{opts.StopAll, opts.Group != "", len(args) > 0} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors == 0 {
+		return fmt.Errorf("must specify either --all, --group or a box ID")
+	}
+	if selectors > 1 {
+		return fmt.Errorf("--all, --group and a box ID are mutually exclusive")
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+	ctx := context.Background()
+
+	if opts.StopAll {
+		boxes, err := listBulkBoxes(ctx, client, "")
+		if err != nil {
+			return err
+		}
+		results := runBulk(ctx, boxes, func(status string) bool { return status == "running" }, func(ctx context.Context, id string) error {
+			_, err := client.V1.Boxes.Stop(ctx, id, sdk.V1BoxStopParams{})
+			return err
+		})
+		return printBulkResults(results, "stopped", opts.OutputFormat)
+	}
+
+	if opts.Group != "" {
+		var result struct {
+			Count int      `json:"count"`
+			IDs   []string `json:"ids"`
+		}
+		if err := client.Post(ctx, "groups/"+url.PathEscape(opts.Group)+"/stop", nil, &result); err != nil {
+			return fmt.Errorf("failed to stop group %s: %v", opts.Group, err)
+		}
+		if opts.OutputFormat == "json" {
+			out, _ := json.Marshal(map[string]interface{}{"status": "success", "count": result.Count, "ids": result.IDs})
+			fmt.Println(string(out))
+		} else {
+			for _, id := range result.IDs {
+				fmt.Printf("Box %s stopped\n", id)
+			}
+			fmt.Printf("%d box(es) of group %s stopped\n", result.Count, opts.Group)
+		}
+		return nil
+	}
+
+	resolvedBoxID, _, err := ResolveBoxIDPrefix(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve box ID: %w", err)
+	}
+	if _, err := client.V1.Boxes.Stop(ctx, resolvedBoxID, sdk.V1BoxStopParams{}); err != nil {
+		return fmt.Errorf("failed to stop box: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		fmt.Println(`{"status":"success","message":"Box stopped successfully"}`)
+	} else {
+		fmt.Printf("Box %s stopped successfully\n", resolvedBoxID)
+	}
+	return nil
+}
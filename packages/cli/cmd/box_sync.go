@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/babelcloud/gbox-sdk-go/option"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxSyncOptions struct {
+	Delete       bool
+	OutputFormat string
+}
+
+// boxSyncSummary reports the outcome of a sync
+type boxSyncSummary struct {
+	Uploaded  []string `json:"uploaded"`
+	Deleted   []string `json:"deleted"`
+	Unchanged int      `json:"unchanged"`
+}
+
+func NewBoxSyncCommand() *cobra.Command {
+	opts := &BoxSyncOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "sync <local-dir> <box-id>:<path>",
+		Short: "Sync a local directory into a box, uploading only changed files",
+		Long: `Sync a local directory into a directory of a box. The content hashes of the
+remote files are compared with the local ones and only new or changed files are
+uploaded. With --delete, remote files that no longer exist locally are removed.`,
+		Example: `  gbox box sync ./src 550e8400:/app
+  gbox box sync ./src 550e8400:/app --delete`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSync(opts, args[0], args[1])
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVar(&opts.Delete, "delete", false, "Remove files from the box that do not exist in the local directory")
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runSync(opts *BoxSyncOptions, src, dst string) error {
+	boxPath, err := parseBoxPath(dst)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(src); err != nil {
+		return fmt.Errorf("failed to read local directory: %v", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", src)
+	}
+
+	resolvedBoxID, _, err := ResolveBoxIDPrefix(boxPath.BoxID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve box ID: %w", err)
+	}
+
+	local, err := hashLocalFiles(src)
+	if err != nil {
+		return fmt.Errorf("failed to hash local files: %v", err)
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+	ctx := context.Background()
+	path := "boxes/" + resolvedBoxID + "/sync"
+
+	var manifest model.BoxSyncManifest
+	if err := client.Get(ctx, path, nil, &manifest, option.WithQuery("path", boxPath.Path)); err != nil {
+		return fmt.Errorf("failed to list files in box: %v", err)
+	}
+
+	summary, params := diffSync(local, manifest.Files, opts.Delete)
+	if len(summary.Uploaded) > 0 || len(summary.Deleted) > 0 {
+		// Creates the directory the changed files are extracted into
+		var result model.BoxSyncResult
+		if err := client.Post(ctx, path, params, &result, option.WithQuery("path", boxPath.Path)); err != nil {
+			return fmt.Errorf("failed to sync files to box: %v", err)
+		}
+	}
+	if len(summary.Uploaded) > 0 {
+		archive := archiveFiles(src, summary.Uploaded)
+		defer archive.Close()
+		err := client.Put(ctx, "boxes/"+resolvedBoxID+"/archive", nil, nil,
+			option.WithQuery("path", boxPath.Path),
+			option.WithRequestBody("application/x-tar", archive))
+		if err != nil {
+			return fmt.Errorf("failed to upload changed files to box: %v", err)
+		}
+	}
+
+	if opts.OutputFormat == "json" {
+		out, _ := json.MarshalIndent(summary, "", "  ")
+		fmt.Println(string(out))
+		return nil
+	}
+	for _, p := range summary.Uploaded {
+		fmt.Printf("uploaded %s\n", p)
+	}
+	for _, p := range summary.Deleted {
+		fmt.Printf("deleted  %s\n", p)
+	}
+	fmt.Printf("Synced %s to %s:%s: %d uploaded, %d deleted, %d unchanged\n",
+		src, resolvedBoxID, boxPath.Path, len(summary.Uploaded), len(summary.Deleted), summary.Unchanged)
+	return nil
+}
+
+// hashLocalFiles returns the SHA-256 of every regular file under dir, keyed
+// by its slash-separated path relative to dir
+func hashLocalFiles(dir string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	return hashes, err
+}
+
+// diffSync compares the local hashes with the remote files, returning the
+// files to upload and, when deleteExtra is set, the remote files to remove
+func diffSync(local map[string]string, remote []model.BoxSyncFile, deleteExtra bool) (boxSyncSummary, model.BoxSyncApplyParams) {
+	summary := boxSyncSummary{Uploaded: []string{}, Deleted: []string{}}
+	remoteHashes := make(map[string]string, len(remote))
+	for _, f := range remote {
+		remoteHashes[f.Path] = f.SHA256
+	}
+
+	for p, hash := range local {
+		if remoteHashes[p] == hash {
+			summary.Unchanged++
+		} else {
+			summary.Uploaded = append(summary.Uploaded, p)
+		}
+	}
+	if deleteExtra {
+		for p := range remoteHashes {
+			if _, ok := local[p]; !ok {
+				summary.Deleted = append(summary.Deleted, p)
+			}
+		}
+	}
+	sort.Strings(summary.Uploaded)
+	sort.Strings(summary.Deleted)
+	return summary, model.BoxSyncApplyParams{Delete: summary.Deleted}
+}
+
+// archiveFiles streams a tar archive of the given files of dir, named by
+// their relative paths
+func archiveFiles(dir string, files []string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		for _, rel := range files {
+			if err := addFileToArchive(tw, filepath.Join(dir, filepath.FromSlash(rel)), rel); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(tw.Close())
+	}()
+	return pr
+}
+
+func addFileToArchive(tw *tar.Writer, p, name string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+)
+
+// fakeSyncServer keeps the files of a box directory in memory and records
+// the names uploaded by each sync
+type fakeSyncServer struct {
+	files   map[string][]byte
+	uploads [][]string
+	deletes [][]string
+}
+
+func (s *fakeSyncServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/boxes":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{{"id": "box-1", "type": "linux", "status": "running"}},
+		})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/boxes/box-1/sync":
+		manifest := model.BoxSyncManifest{Path: r.URL.Query().Get("path"), Files: []model.BoxSyncFile{}}
+		for name, content := range s.files {
+			sum := sha256.Sum256(content)
+			manifest.Files = append(manifest.Files, model.BoxSyncFile{Path: name, SHA256: hex.EncodeToString(sum[:])})
+		}
+		json.NewEncoder(w).Encode(manifest)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/boxes/box-1/sync":
+		var params model.BoxSyncApplyParams
+		json.NewDecoder(r.Body).Decode(&params)
+		for _, name := range params.Delete {
+			delete(s.files, name)
+		}
+		s.deletes = append(s.deletes, params.Delete)
+		json.NewEncoder(w).Encode(model.BoxSyncResult{Deleted: len(params.Delete)})
+	case r.Method == http.MethodPut && r.URL.Path == "/api/v1/boxes/box-1/archive":
+		if r.Header.Get("Content-Type") != "application/x-tar" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		var uploaded []string
+		tr := tar.NewReader(r.Body)
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				break
+			}
+			content, _ := io.ReadAll(tr)
+			s.files[hdr.Name] = content
+			uploaded = append(uploaded, hdr.Name)
+		}
+		sort.Strings(uploaded)
+		s.uploads = append(s.uploads, uploaded)
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestBoxSyncUploadsOnlyChangedFiles(t *testing.T) {
+	fake := &fakeSyncServer{files: map[string][]byte{"stale.txt": []byte("old")}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b"), 0644))
+
+	opts := &BoxSyncOptions{OutputFormat: "json"}
+	require.NoError(t, runSync(opts, dir, "box-1:/app"))
+	require.Len(t, fake.uploads, 1)
+	assert.Equal(t, []string{"a.txt", "sub/b.txt"}, fake.uploads[0])
+	assert.Empty(t, fake.deletes[0], "remote files are kept without --delete")
+
+	// Only the modified file is uploaded again
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b2"), 0644))
+	require.NoError(t, runSync(opts, dir, "box-1:/app"))
+	require.Len(t, fake.uploads, 2)
+	assert.Equal(t, []string{"sub/b.txt"}, fake.uploads[1])
+	assert.Equal(t, []byte("b2"), fake.files["sub/b.txt"])
+
+	// An unchanged tree sends nothing; --delete removes files absent locally
+	require.NoError(t, runSync(opts, dir, "box-1:/app"))
+	assert.Len(t, fake.uploads, 2)
+	assert.Len(t, fake.deletes, 2)
+	require.NoError(t, runSync(&BoxSyncOptions{Delete: true, OutputFormat: "json"}, dir, "box-1:/app"))
+	require.Len(t, fake.deletes, 3)
+	assert.Equal(t, []string{"stale.txt"}, fake.deletes[2])
+	assert.Len(t, fake.uploads, 2, "nothing is uploaded when only deletions remain")
+	assert.NotContains(t, fake.files, "stale.txt")
+}
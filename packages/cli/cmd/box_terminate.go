@@ -3,20 +3,27 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 
 	// 内部 SDK 客户端
 	sdk "github.com/babelcloud/gbox-sdk-go"
+	"github.com/babelcloud/gbox-sdk-go/option"
 	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
 	"github.com/spf13/cobra"
 )
 
 type BoxTerminateOptions struct {
-	OutputFormat string
-	TerminateAll bool
-	Force        bool
+	OutputFormat   string
+	TerminateAll   bool
+	Group          string
+	Force          bool
+	IgnoreNotFound bool
 }
 
 func NewBoxTerminateCommand() *cobra.Command {
@@ -25,10 +32,12 @@ func NewBoxTerminateCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "terminate [box-id]",
 		Short: "Terminate a box by its ID",
-		Long:  "Terminate a box by its ID or terminate all boxes",
+		Long:  "Terminate a box by its ID, every box of a group, or all boxes",
 		Example: `  gbox box terminate 550e8400-e29b-41d4-a716-446655440000
   gbox box terminate --all --force
   gbox box terminate --all
+  gbox box terminate --group web --force
+  gbox box terminate 550e8400-e29b-41d4-a716-446655440000 --ignore-not-found
   gbox box terminate 550e8400-e29b-41d4-a716-446655440000 --output json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runTerminate(opts, args)
@@ -39,7 +48,9 @@ func NewBoxTerminateCommand() *cobra.Command {
 	flags := cmd.Flags()
 	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
 	flags.BoolVarP(&opts.TerminateAll, "all", "a", false, "Terminate all boxes")
+	flags.StringVar(&opts.Group, "group", "", "Terminate every box of the named group")
 	flags.BoolVarP(&opts.Force, "force", "f", false, "Force termination without confirmation")
+	flags.BoolVar(&opts.IgnoreNotFound, "ignore-not-found", false, "Succeed when the box does not exist or is already terminated")
 
 	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
@@ -49,17 +60,25 @@ func NewBoxTerminateCommand() *cobra.Command {
 }
 
 func runTerminate(opts *BoxTerminateOptions, args []string) error {
-	if !opts.TerminateAll && len(args) == 0 {
-		return fmt.Errorf("must specify either --all or a box ID")
+	selectors := 0
+	for _, set := range []bool{opts.TerminateAll, opts.Group != "", len(args) > 0} {
+		if set {
+			selectors++
+		}
 	}
-
-	if opts.TerminateAll && len(args) > 0 {
-		return fmt.Errorf("cannot specify both --all and a box ID")
+	if selectors == 0 {
+		return fmt.Errorf("must specify either --all, --group or a box ID")
+	}
+	if selectors > 1 {
+		return fmt.Errorf("--all, --group and a box ID are mutually exclusive")
 	}
 
 	if opts.TerminateAll {
 		return terminateAllBoxes(opts)
 	}
+	if opts.Group != "" {
+		return terminateGroup(opts)
+	}
 
 	return terminateBox(args[0], opts)
 }
@@ -115,7 +134,7 @@ func terminateAllBoxes(opts *BoxTerminateOptions) error {
 
 	success := true
 	for _, box := range resp.Data {
-		if err := performBoxTermination(client, box.ID); err != nil {
+		if err := performBoxTermination(client, box.ID, opts.IgnoreNotFound); err != nil {
 			fmt.Printf("Error: Failed to terminate box %s: %v\n", box.ID, err)
 			success = false
 		}
@@ -138,9 +157,59 @@ func terminateAllBoxes(opts *BoxTerminateOptions) error {
 	return nil
 }
 
+// terminateGroup deletes every box of a group in a single server-side call
+func terminateGroup(opts *BoxTerminateOptions) error {
+	if !opts.Force {
+		fmt.Printf("Are you sure you want to terminate every box of group %q? [y/N] ", opts.Group)
+		reader := bufio.NewReader(os.Stdin)
+		reply, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read input: %v", err)
+		}
+
+		reply = strings.TrimSpace(strings.ToLower(reply))
+		if reply != "y" && reply != "yes" {
+			if opts.OutputFormat == "json" {
+				fmt.Println(`{"status":"cancelled","message":"Operation cancelled by user"}`)
+			} else {
+				fmt.Println("Operation cancelled")
+			}
+			return nil
+		}
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var result struct {
+		Count int      `json:"count"`
+		IDs   []string `json:"ids"`
+	}
+	if err := client.Delete(context.Background(), "groups/"+url.PathEscape(opts.Group), nil, &result); err != nil {
+		return fmt.Errorf("failed to terminate group %s: %v", opts.Group, err)
+	}
+
+	if opts.OutputFormat == "json" {
+		out, _ := json.Marshal(map[string]interface{}{"status": "success", "count": result.Count, "ids": result.IDs})
+		fmt.Println(string(out))
+	} else {
+		for _, id := range result.IDs {
+			fmt.Printf("Box %s terminated\n", id)
+		}
+		fmt.Printf("%d box(es) of group %s terminated\n", result.Count, opts.Group)
+	}
+	return nil
+}
+
 func terminateBox(boxIDPrefix string, opts *BoxTerminateOptions) error {
 	resolvedBoxID, _, err := ResolveBoxIDPrefix(boxIDPrefix)
 	if err != nil {
+		if opts.IgnoreNotFound && errors.Is(err, errNoBoxFound) {
+			printBoxNotFound(boxIDPrefix, opts)
+			return nil
+		}
 		return fmt.Errorf("failed to resolve box ID: %w", err)
 	}
 
@@ -150,7 +219,7 @@ func terminateBox(boxIDPrefix string, opts *BoxTerminateOptions) error {
 		return fmt.Errorf("failed to initialize gbox client: %v", err)
 	}
 
-	if err := performBoxTermination(client, resolvedBoxID); err != nil {
+	if err := performBoxTermination(client, resolvedBoxID, opts.IgnoreNotFound); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		return nil
 	}
@@ -163,9 +232,24 @@ func terminateBox(boxIDPrefix string, opts *BoxTerminateOptions) error {
 	return nil
 }
 
-func performBoxTermination(client *sdk.Client, boxID string) error {
+// printBoxNotFound reports a box that was already gone as a successful termination
+func printBoxNotFound(boxID string, opts *BoxTerminateOptions) {
+	if opts.OutputFormat == "json" {
+		fmt.Println(`{"status":"success","message":"Box not found, nothing to terminate"}`)
+	} else {
+		fmt.Printf("Box %s not found, nothing to terminate\n", boxID)
+	}
+}
+
+// performBoxTermination terminates a box. With ignoreNotFound a box that is
+// already gone counts as terminated, so a retried termination succeeds.
+func performBoxTermination(client *sdk.Client, boxID string, ignoreNotFound bool) error {
 	// 构建 SDK 参数
 	terminateParams := sdk.V1BoxTerminateParams{}
+	var reqOpts []option.RequestOption
+	if ignoreNotFound {
+		reqOpts = append(reqOpts, option.WithQuery("ignoreNotFound", "true"))
+	}
 
 	// 调试输出
 	if os.Getenv("DEBUG") == "true" {
@@ -174,7 +258,12 @@ func performBoxTermination(client *sdk.Client, boxID string) error {
 
 	// 调用 SDK
 	ctx := context.Background()
-	err := client.V1.Boxes.Terminate(ctx, boxID, terminateParams)
+	err := client.V1.Boxes.Terminate(ctx, boxID, terminateParams, reqOpts...)
+	var apiErr *sdk.Error
+	if ignoreNotFound && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		// Servers without ignoreNotFound support still answer 404
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to terminate box: %v", err)
 	}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerminateIgnoreNotFound(t *testing.T) {
+	var terminated []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/boxes":
+			// box-1 is listed but removed before the terminate request arrives
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{{"id": "box-1", "type": "linux", "status": "running"}},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/boxes/box-1/terminate":
+			terminated = append(terminated, r.URL.RawQuery)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"BoxNotFound","message":"box not found"}`))
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+
+	origAPIURL := os.Getenv("API_ENDPOINT")
+	defer os.Setenv("API_ENDPOINT", origAPIURL)
+	os.Setenv("API_ENDPOINT", server.URL)
+
+	opts := &BoxTerminateOptions{OutputFormat: "json", IgnoreNotFound: true}
+	require.NoError(t, runTerminate(opts, []string{"missing"}))
+	assert.Empty(t, terminated, "an unknown box must not be terminated")
+
+	require.NoError(t, runTerminate(opts, []string{"box-1"}))
+	require.Len(t, terminated, 1)
+	assert.Equal(t, "ignoreNotFound=true", terminated[0])
+
+	err := runTerminate(&BoxTerminateOptions{OutputFormat: "json"}, []string{"missing"})
+	assert.ErrorIs(t, err, errNoBoxFound)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxTouchOptions struct {
+	OutputFormat string
+}
+
+func NewBoxTouchCommand() *cobra.Command {
+	opts := &BoxTouchOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "touch <box-id>",
+		Short: "Mark a box as recently used",
+		Long:  "Refresh the last access time of a box so idle reclaim does not stop it, without running a command in it",
+		Example: `  gbox box touch 550e8400-e29b-41d4-a716-446655440000
+  gbox box touch 550e8400 --output json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTouch(opts, args[0])
+		},
+		ValidArgsFunction: completeBoxIDs,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runTouch(opts *BoxTouchOptions, boxIDPrefix string) error {
+	resolvedBoxID, _, err := ResolveBoxIDPrefix(boxIDPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to resolve box ID: %w", err)
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var result model.BoxTouchResult
+	if err := client.Post(context.Background(), "boxes/"+resolvedBoxID+"/touch", nil, &result); err != nil {
+		return fmt.Errorf("failed to touch box: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		out, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Printf("Box %s last accessed at %s\n", result.ID, result.LastAccessedAt.Local().Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/box"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type BoxUpdateOptions struct {
+	CPU          float64
+	Memory       string
+	OutputFormat string
+}
+
+func NewBoxUpdateCommand() *cobra.Command {
+	opts := &BoxUpdateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "update <box-id>",
+		Short: "Change the resource limits of a box",
+		Long:  "Change the CPU and memory limits of a running box without recreating it",
+		Example: `  gbox box update 550e8400-e29b-41d4-a716-446655440000 --cpu 2
+  gbox box update 550e8400 --memory 4g
+  gbox box update 550e8400 --cpu 1.5 --memory 2g --output json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("cpu") && !cmd.Flags().Changed("memory") {
+				return fmt.Errorf("at least one of --cpu or --memory is required")
+			}
+			return runUpdate(opts, args[0])
+		},
+		ValidArgsFunction: completeBoxIDs,
+	}
+
+	flags := cmd.Flags()
+	flags.Float64Var(&opts.CPU, "cpu", 0, "CPU limit in cores, e.g. 1.5")
+	flags.StringVar(&opts.Memory, "memory", "", "Memory limit, e.g. 512m or 2g")
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runUpdate(opts *BoxUpdateOptions, boxIDPrefix string) error {
+	resolvedBoxID, _, err := ResolveBoxIDPrefix(boxIDPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to resolve box ID: %w", err)
+	}
+
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	params := model.BoxUpdateParams{CPU: opts.CPU, Memory: opts.Memory}
+	var result model.BoxUpdateResult
+	if err := client.Post(context.Background(), "boxes/"+resolvedBoxID+"/update", params, &result); err != nil {
+		return fmt.Errorf("failed to update box: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		out, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Printf("Box %s updated: cpu %g, memory %g MB\n", result.ID, result.Config.CPU, result.Config.Memory)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCompletionCommand creates the command generating shell completion scripts
+func NewCompletionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate the shell completion script",
+		Long: `Generate the completion script of gbox for the given shell. Besides commands
+and flags, the script completes box IDs and the executables inside a box by
+asking the API server.`,
+		Example: `  # Bash, for the current session
+  source <(gbox completion bash)
+
+  # Bash, for every session (Linux)
+  gbox completion bash > /etc/bash_completion.d/gbox
+
+  # Zsh, with compinit enabled
+  gbox completion zsh > "${fpath[1]}/_gbox"
+
+  # Fish
+  gbox completion fish > ~/.config/fish/completions/gbox.fish
+
+  # PowerShell
+  gbox completion powershell | Out-String | Invoke-Expression`,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCompletion(cmd, args[0])
+		},
+	}
+
+	return cmd
+}
+
+// runCompletion writes the completion script of the root command for shell
+func runCompletion(cmd *cobra.Command, shell string) error {
+	root, out := cmd.Root(), cmd.OutOrStdout()
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(out, true)
+	case "zsh":
+		return root.GenZshCompletion(out)
+	case "fish":
+		return root.GenFishCompletion(out, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(out)
+	}
+	return fmt.Errorf("unsupported shell %q: must be bash, zsh, fish or powershell", shell)
+}
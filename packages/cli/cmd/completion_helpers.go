@@ -2,16 +2,34 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/adrg/xdg"
 	// 内部 SDK 客户端
 	sdk "github.com/babelcloud/gbox-sdk-go"
 	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
 	"github.com/spf13/cobra"
 )
 
+// execCompletionCacheTTL is how long the executables found in a box are
+// reused for completion before the box is probed again
+const execCompletionCacheTTL = 5 * time.Minute
+
+// completionCacheDir holds the per-box executable lists. Every completion
+// runs in a new process, so the cache lives on disk.
+var completionCacheDir = filepath.Join(xdg.CacheHome, "gbox", "completion")
+
+// execProbeCommand lists the executables in the usual PATH directories of a box
+var execProbeCommand = []string{"sh", "-c", "ls -1 /usr/local/sbin /usr/local/bin /usr/sbin /usr/bin /sbin /bin 2>/dev/null"}
+
 // completeBoxIDs provides completion for box IDs by fetching them from the API.
 func completeBoxIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	debug := os.Getenv("DEBUG") == "true"
@@ -51,8 +69,101 @@ func completeBoxIDs(cmd *cobra.Command, args []string, toComplete string) ([]str
 	return ids, cobra.ShellCompDirectiveNoFileComp
 }
 
-// ResolveBoxIDPrefix takes a prefix string and returns the unique full Box ID if found,
-// or an error if not found or if multiple matches exist.
+// completeBoxExecutables suggests executables found in a box for the first
+// word of the command passed to exec
+func completeBoxExecutables(boxIDPrefix string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	debug := os.Getenv("DEBUG") == "true"
+
+	boxID, _, err := ResolveBoxIDPrefix(boxIDPrefix)
+	if err != nil {
+		if debug {
+			fmt.Fprintf(os.Stderr, "DEBUG: [completion] Failed to resolve box ID: %v\n", err)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	executables, err := loadBoxExecutables(boxID)
+	if err != nil {
+		if debug {
+			fmt.Fprintf(os.Stderr, "DEBUG: [completion] Failed to list executables of box %s: %v\n", boxID, err)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var matches []string
+	for _, name := range executables {
+		if strings.HasPrefix(name, toComplete) {
+			matches = append(matches, name)
+		}
+	}
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
+
+// loadBoxExecutables returns the executables of a box, from the cache when
+// it is recent enough
+func loadBoxExecutables(boxID string) ([]string, error) {
+	cachePath := filepath.Join(completionCacheDir, boxID+".json")
+	if info, err := os.Stat(cachePath); err == nil && time.Since(info.ModTime()) < execCompletionCacheTTL {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			var cached []string
+			if json.Unmarshal(data, &cached) == nil {
+				return cached, nil
+			}
+		}
+	}
+
+	executables, err := probeBoxExecutables(boxID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A cache that cannot be written only costs a probe next time
+	if err := os.MkdirAll(completionCacheDir, 0755); err == nil {
+		if data, err := json.Marshal(executables); err == nil {
+			os.WriteFile(cachePath, data, 0644)
+		}
+	}
+	return executables, nil
+}
+
+// probeBoxExecutables runs a quick listing of the PATH directories in a box
+func probeBoxExecutables(boxID string) ([]string, error) {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize gbox client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := client.V1.Boxes.ExecuteCommands(ctx, boxID, sdk.V1BoxExecuteCommandsParams{
+		Commands: sdk.V1BoxExecuteCommandsParamsCommandsUnion{OfStringArray: execProbeCommand},
+		Timeout:  sdk.String("5s"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var executables []string
+	for _, line := range strings.Split(resp.Stdout, "\n") {
+		name := strings.TrimSpace(line)
+		// ls prints a "dir:" heading before each directory's entries
+		if name == "" || strings.HasSuffix(name, ":") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		executables = append(executables, name)
+	}
+	sort.Strings(executables)
+	return executables, nil
+}
+
+// boxAliasLabel is the box label whose value can be used in place of the box ID
+const boxAliasLabel = "name"
+
+// ResolveBoxIDPrefix takes a box ID, a unique ID prefix or a box alias (the
+// value of its "name" label) and returns the full Box ID, or an error if
+// nothing matches or the reference is ambiguous.
 // It also returns the list of matched IDs in case of multiple matches.
 func ResolveBoxIDPrefix(prefix string) (fullID string, matchedIDs []string, err error) {
 	debug := os.Getenv("DEBUG") == "true"
@@ -84,31 +195,77 @@ func ResolveBoxIDPrefix(prefix string) (fullID string, matchedIDs []string, err
 		return "", nil, fmt.Errorf("failed to get box list: %w", err)
 	}
 
-	if debug {
-		var allIDs []string
-		for _, box := range resp.Data {
-			allIDs = append(allIDs, box.ID)
-		}
-		fmt.Fprintf(os.Stderr, "DEBUG: [ResolveBoxIDPrefix] All fetched IDs: %v\n", allIDs)
-	}
-	// 执行前缀匹配
+	aliases := make(map[string]string, len(resp.Data))
+	var allIDs []string
 	for _, box := range resp.Data {
-		if strings.HasPrefix(box.ID, prefix) {
-			matchedIDs = append(matchedIDs, box.ID)
+		allIDs = append(allIDs, box.ID)
+		if labels, ok := box.Config.Labels.(map[string]interface{}); ok {
+			if alias, ok := labels[boxAliasLabel].(string); ok {
+				aliases[box.ID] = alias
+			}
 		}
 	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "DEBUG: [ResolveBoxIDPrefix] All fetched IDs: %v, aliases: %v\n", allIDs, aliases)
+	}
 
+	fullID, matchedIDs, err = resolveBoxRef(prefix, allIDs, aliases)
 	if debug {
-		fmt.Fprintf(os.Stderr, "DEBUG: [ResolveBoxIDPrefix] Matched IDs for prefix '%s': %v\n", prefix, matchedIDs)
+		fmt.Fprintf(os.Stderr, "DEBUG: [ResolveBoxIDPrefix] Matched IDs for '%s': %v\n", prefix, matchedIDs)
 	}
+	return fullID, matchedIDs, err
+}
+
+// errNoBoxFound is returned when a box reference matches no box
+var errNoBoxFound = errors.New("no box found")
+
+var (
+	uuidBoxIDPattern  = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	shortBoxIDPattern = regexp.MustCompile(`^[a-z][-a-z0-9]*-([0-9a-z]{12})$`)
+)
+
+// shortIDBody returns the random part of a box ID in the server's short
+// format, "<prefix>-<12 random characters>", or "" for other IDs. Matching it
+// lets a box be referenced without typing the prefix shared by all boxes.
+func shortIDBody(id string) string {
+	if uuidBoxIDPattern.MatchString(id) {
+		return ""
+	}
+	if m := shortBoxIDPattern.FindStringSubmatch(id); m != nil {
+		return m[1]
+	}
+	return ""
+}
 
-	// 处理匹配结果
-	if len(matchedIDs) == 0 {
-		return "", nil, fmt.Errorf("no box found with ID prefix: %s", prefix)
+// resolveBoxRef matches ref against the given box IDs and aliases, trying an
+// exact ID, then a unique ID prefix, then an alias. For short IDs a prefix
+// of the random part also counts as an ID prefix. A reference matching
+// several ID prefixes still resolves when exactly one box has it as alias.
+func resolveBoxRef(ref string, ids []string, aliases map[string]string) (string, []string, error) {
+	var prefixMatches, aliasMatches []string
+	for _, id := range ids {
+		if id == ref {
+			return id, []string{id}, nil
+		}
+		if strings.HasPrefix(id, ref) || (ref != "" && strings.HasPrefix(shortIDBody(id), ref)) {
+			prefixMatches = append(prefixMatches, id)
+		}
+		if aliases[id] == ref {
+			aliasMatches = append(aliasMatches, id)
+		}
+	}
+
+	if len(prefixMatches) == 1 {
+		return prefixMatches[0], prefixMatches, nil
+	}
+	if len(aliasMatches) == 1 {
+		return aliasMatches[0], aliasMatches, nil
+	}
+	if len(aliasMatches) > 1 {
+		return "", aliasMatches, fmt.Errorf("multiple boxes are named '%s'. Please use a box ID. Matches:\n  %s", ref, strings.Join(aliasMatches, "\n  "))
 	}
-	if len(matchedIDs) == 1 {
-		return matchedIDs[0], matchedIDs, nil // 唯一匹配
+	if len(prefixMatches) > 1 {
+		return "", prefixMatches, fmt.Errorf("multiple boxes found with ID prefix '%s'. Please be more specific. Matches:\n  %s", ref, strings.Join(prefixMatches, "\n  "))
 	}
-	// 多个匹配
-	return "", matchedIDs, fmt.Errorf("multiple boxes found with ID prefix '%s'. Please be more specific. Matches:\n  %s", prefix, strings.Join(matchedIDs, "\n  "))
+	return "", nil, fmt.Errorf("%w with ID prefix or name: %s", errNoBoxFound, ref)
 }
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that exec completion suggests the executables found in the box and
+// reuses the cached probe result
+func TestCompleteBoxExecutables(t *testing.T) {
+	var probes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/boxes":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{{"id": "box-1234", "type": "linux", "status": "running"}},
+			})
+		case "/api/v1/boxes/box-1234/commands":
+			atomic.AddInt32(&probes, 1)
+			var body struct {
+				Commands []string `json:"commands"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, execProbeCommand, body.Commands)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"exitCode": 0,
+				"stdout":   "/usr/bin:\npython3\nbash\n\n/bin:\nbash\nls\n",
+				"stderr":   "",
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("API_ENDPOINT", server.URL)
+	origCacheDir := completionCacheDir
+	completionCacheDir = t.TempDir()
+	defer func() { completionCacheDir = origCacheDir }()
+
+	names, directive := completeBoxExecutables("box-12", "")
+	assert.Equal(t, []string{"bash", "ls", "python3"}, names)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+
+	names, _ = completeBoxExecutables("box-12", "py")
+	assert.Equal(t, []string{"python3"}, names)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&probes), "second completion should use the cache")
+}
+
+func TestResolveBoxRef(t *testing.T) {
+	ids := []string{"abc123", "abd456", "web789", "f00"}
+	aliases := map[string]string{"abd456": "web", "f00": "abc123x"}
+
+	for _, tt := range []struct {
+		ref     string
+		want    string
+		matches []string
+		wantErr string
+	}{
+		{ref: "abc123", want: "abc123", matches: []string{"abc123"}},
+		{ref: "abc", want: "abc123", matches: []string{"abc123"}},
+		{ref: "abc123x", want: "f00", matches: []string{"f00"}},
+		// "web" is both a unique ID prefix and another box's alias; the prefix wins
+		{ref: "web", want: "web789", matches: []string{"web789"}},
+		{ref: "ab", matches: []string{"abc123", "abd456"}, wantErr: "multiple boxes found with ID prefix"},
+		{ref: "nope", wantErr: "no box found"},
+	} {
+		t.Run(tt.ref, func(t *testing.T) {
+			id, matches, err := resolveBoxRef(tt.ref, ids, aliases)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, id)
+			assert.Equal(t, tt.matches, matches)
+		})
+	}
+}
+
+func TestResolveBoxRefIDFormats(t *testing.T) {
+	ids := []string{
+		"550e8400-e29b-41d4-a716-446655440000",
+		"ab0e8400-e29b-41d4-a716-446655440000",
+		"box-k3x9q2m7w1ab",
+		"box-k3a7c0d2e4f6",
+		"dev-team1-z8y7x6w5v4u3",
+	}
+
+	for _, tt := range []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{ref: "550e", want: ids[0]},
+		{ref: "ab0e8400-e29b-41d4-a716-446655440000", want: ids[1]},
+		// The last UUID group is not matched on its own
+		{ref: "4466", wantErr: "no box found"},
+		{ref: "box-k3x", want: ids[2]},
+		{ref: "k3x", want: ids[2]},
+		{ref: "k3a7c0d2e4f6", want: ids[3]},
+		{ref: "k3", wantErr: "multiple boxes found"},
+		{ref: "z8y", want: ids[4]},
+		{ref: "dev-team1-", want: ids[4]},
+		{ref: "team1", wantErr: "no box found"},
+	} {
+		t.Run(tt.ref, func(t *testing.T) {
+			id, _, err := resolveBoxRef(tt.ref, ids, nil)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, id)
+		})
+	}
+}
+
+func TestResolveBoxRefAliasAmbiguity(t *testing.T) {
+	ids := []string{"abc123", "abd456", "e01"}
+
+	// An ambiguous prefix is settled by a unique alias
+	id, _, err := resolveBoxRef("ab", ids, map[string]string{"e01": "ab"})
+	require.NoError(t, err)
+	assert.Equal(t, "e01", id)
+
+	_, matches, err := resolveBoxRef("db", ids, map[string]string{"abc123": "db", "e01": "db"})
+	assert.ErrorContains(t, err, "multiple boxes are named 'db'")
+	assert.Equal(t, []string{"abc123", "e01"}, matches)
+}
+
+func TestResolveBoxIDPrefixByName(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]interface{}{
+				{"id": "box-1234", "type": "linux", "status": "running", "config": map[string]interface{}{"labels": map[string]string{"name": "web"}}},
+				{"id": "box-5678", "type": "linux", "status": "running", "config": map[string]interface{}{"labels": map[string]string{}}},
+			},
+		})
+	}))
+	defer server.Close()
+	t.Setenv("API_ENDPOINT", server.URL)
+
+	id, _, err := ResolveBoxIDPrefix("web")
+	require.NoError(t, err)
+	assert.Equal(t, "box-1234", id)
+
+	id, _, err = ResolveBoxIDPrefix("box-5")
+	require.NoError(t, err)
+	assert.Equal(t, "box-5678", id)
+}
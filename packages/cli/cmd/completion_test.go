@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that every supported shell gets its completion script
+func TestCompletionScripts(t *testing.T) {
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+	})
+
+	for shell, marker := range map[string]string{
+		"bash":       "__start_gbox",
+		"zsh":        "#compdef gbox",
+		"fish":       "complete -c gbox",
+		"powershell": "Register-ArgumentCompleter",
+	} {
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetArgs([]string{"completion", shell})
+		require.NoError(t, rootCmd.Execute(), shell)
+		assert.Contains(t, out.String(), marker, shell)
+	}
+
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{"completion", "tcsh"})
+	assert.Error(t, rootCmd.Execute())
+}
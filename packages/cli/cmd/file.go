@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewFileCommand creates and returns the file command
+func NewFileCommand() *cobra.Command {
+	fileCmd := &cobra.Command{
+		Use:   "file",
+		Short: "Manage files in the share directory",
+		Long:  `The file command is used to manage files and directories in the gbox share directory.`,
+		Example: `  gbox file mkdir /550e8400-e29b-41d4-a716-446655440000/work/output          # Create a directory
+  gbox file rm -r /550e8400-e29b-41d4-a716-446655440000/work/output          # Delete a directory
+  gbox file mv /550e8400-e29b-41d4-a716-446655440000/a.txt /550e8400-e29b-41d4-a716-446655440000/b.txt  # Move a file
+  gbox file usage                                                            # Show share directory disk usage`,
+	}
+
+	fileCmd.AddCommand(
+		NewFileMkdirCommand(),
+		NewFileRmCommand(),
+		NewFileMvCommand(),
+		NewFileUsageCommand(),
+	)
+
+	return fileCmd
+}
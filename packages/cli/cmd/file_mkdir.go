@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/babelcloud/gbox-sdk-go/option"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type FileMkdirOptions struct {
+	OutputFormat string
+}
+
+func NewFileMkdirCommand() *cobra.Command {
+	opts := &FileMkdirOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "mkdir <path>",
+		Short: "Create a directory in the share directory",
+		Long:  "Create a directory, including any missing parents, in the share directory",
+		Example: `  gbox file mkdir /550e8400-e29b-41d4-a716-446655440000/work/output
+  gbox file mkdir /550e8400-e29b-41d4-a716-446655440000/work/output --output json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFileMkdir(args[0], opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	return cmd
+}
+
+func runFileMkdir(path string, opts *FileMkdirOptions) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var stat model.FileStat
+	if err := client.Post(context.Background(), "files/mkdir", nil, &stat, option.WithQuery("path", path)); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		statJSON, _ := json.MarshalIndent(stat, "", "  ")
+		fmt.Println(string(statJSON))
+	} else {
+		fmt.Printf("Directory created: %s (%s)\n", stat.Path, stat.Mode)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+func NewFileMvCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "mv <src> <dst>",
+		Short:   "Move a file or directory within the share directory",
+		Long:    "Move a file or directory within the share directory. The destination must not already exist.",
+		Example: `  gbox file mv /550e8400-e29b-41d4-a716-446655440000/out.txt /550e8400-e29b-41d4-a716-446655440000/archive/out.txt`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFileMv(args[0], args[1])
+		},
+	}
+
+	return cmd
+}
+
+func runFileMv(src, dst string) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	body := model.FileMoveParams{Src: src, Dst: dst}
+	var result model.FileOperationResult
+	if err := client.Post(context.Background(), "files/move", body, &result); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %v", src, dst, err)
+	}
+
+	fmt.Println(result.Message)
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/babelcloud/gbox-sdk-go/option"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type FileRmOptions struct {
+	Recursive bool
+}
+
+func NewFileRmCommand() *cobra.Command {
+	opts := &FileRmOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "rm <path>",
+		Short: "Delete a file or directory in the share directory",
+		Long:  "Delete a file or directory in the share directory. Non-empty directories require --recursive.",
+		Example: `  gbox file rm /550e8400-e29b-41d4-a716-446655440000/work/output.txt
+  gbox file rm -r /550e8400-e29b-41d4-a716-446655440000/work/tmp`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFileRm(args[0], opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVarP(&opts.Recursive, "recursive", "r", false, "Delete directories and their contents")
+
+	return cmd
+}
+
+func runFileRm(path string, opts *FileRmOptions) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var reqOpts []option.RequestOption
+	if opts.Recursive {
+		reqOpts = append(reqOpts, option.WithQuery("recursive", "true"))
+	}
+
+	var result model.FileOperationResult
+	if err := client.Delete(context.Background(), "files/"+escapeSharePath(path), nil, &result, reqOpts...); err != nil {
+		return fmt.Errorf("failed to delete %s: %v", path, err)
+	}
+
+	fmt.Println(result.Message)
+	return nil
+}
+
+// escapeSharePath escapes each segment of a share path for use in a URL path
+func escapeSharePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
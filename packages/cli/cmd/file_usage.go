@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/babelcloud/gbox-sdk-go/option"
+	model "github.com/babelcloud/gbox/packages/api-server/pkg/file"
+	gboxclient "github.com/babelcloud/gbox/packages/cli/internal/gboxsdk"
+	"github.com/spf13/cobra"
+)
+
+type FileUsageOptions struct {
+	OutputFormat string
+	BoxID        string
+	Refresh      bool
+}
+
+func NewFileUsageCommand() *cobra.Command {
+	opts := &FileUsageOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show disk usage of the share directory",
+		Long:  "Show how much disk space the share directory uses, in total and for each box",
+		Example: `  gbox file usage
+  gbox file usage --box 550e8400-e29b-41d4-a716-446655440000
+  gbox file usage --refresh --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFileUsage(opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.OutputFormat, "output", "o", "text", "Output format (json or text)")
+	flags.StringVar(&opts.BoxID, "box", "", "Only show the usage of this box")
+	flags.BoolVar(&opts.Refresh, "refresh", false, "Recompute the usage instead of using the server's cached result")
+
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "text"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.RegisterFlagCompletionFunc("box", completeBoxIDs)
+
+	return cmd
+}
+
+func runFileUsage(opts *FileUsageOptions) error {
+	client, err := gboxclient.NewClientFromProfile()
+	if err != nil {
+		return fmt.Errorf("failed to initialize gbox client: %v", err)
+	}
+
+	var reqOpts []option.RequestOption
+	if opts.BoxID != "" {
+		boxID, _, err := ResolveBoxIDPrefix(opts.BoxID)
+		if err != nil {
+			return fmt.Errorf("failed to resolve box ID: %w", err)
+		}
+		reqOpts = append(reqOpts, option.WithQuery("boxId", boxID))
+	}
+	if opts.Refresh {
+		reqOpts = append(reqOpts, option.WithQuery("refresh", "true"))
+	}
+
+	var usage model.FileUsage
+	if err := client.Get(context.Background(), "files/usage", nil, &usage, reqOpts...); err != nil {
+		return fmt.Errorf("failed to get share usage: %v", err)
+	}
+
+	if opts.OutputFormat == "json" {
+		usageJSON, _ := json.MarshalIndent(usage, "", "  ")
+		fmt.Println(string(usageJSON))
+		return nil
+	}
+
+	if len(usage.Boxes) > 0 {
+		fmt.Println("BOX ID                                   SIZE         FILES")
+		fmt.Println("---------------------------------------- ------------ ----------")
+		for _, box := range usage.Boxes {
+			fmt.Printf("%-40s %-12s %d\n", box.BoxID, humanSize(float64(box.Bytes)), box.FileCount)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Total: %s in %d files\n", humanSize(float64(usage.TotalBytes)), usage.FileCount)
+	return nil
+}
@@ -21,6 +21,9 @@ var (
 
 	scriptDir string
 
+	// apiEndpoint is the value of the global --api-endpoint flag
+	apiEndpoint string
+
 	rootCmd = &cobra.Command{
 		Use:   "gbox",
 		Short: "Gru CLI Tool",
@@ -64,16 +67,25 @@ func init() {
 	}
 
 	rootCmd.Flags().BoolP("version", "v", false, "Print version information and exit")
+	rootCmd.PersistentFlags().StringVar(&apiEndpoint, "api-endpoint", "", "API server to use for this invocation, overriding API_ENDPOINT and the profile")
+
+	// Runs after flag parsing, before any command or its pre-run hooks
+	cobra.OnInitialize(func() {
+		config.SetAPIEndpoint(apiEndpoint)
+	})
 
 	for alias, cmd := range aliasMap {
 		createAliasCommand(alias, cmd)
 	}
 
 	rootCmd.AddCommand(NewBoxCommand())
+	rootCmd.AddCommand(NewFileCommand())
 	rootCmd.AddCommand(NewClusterCommand())
+	rootCmd.AddCommand(NewAdminCommand())
 	rootCmd.AddCommand(NewMcpCommand())
 	rootCmd.AddCommand(NewCuaCommand())
 	rootCmd.AddCommand(NewVersionCommand())
+	rootCmd.AddCommand(NewCompletionCommand())
 }
 
 func createAliasCommand(alias, targetCmd string) {
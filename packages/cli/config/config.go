@@ -59,8 +59,29 @@ func init() {
 	}
 }
 
+// apiEndpoint is the endpoint given with --api-endpoint, if any
+var apiEndpoint string
+
+// SetAPIEndpoint overrides the API endpoint for the rest of the invocation,
+// taking precedence over API_ENDPOINT, the config file and the profile
+func SetAPIEndpoint(endpoint string) {
+	apiEndpoint = endpoint
+}
+
+// GetAPIEndpoint returns the API endpoint explicitly chosen with
+// --api-endpoint or API_ENDPOINT, or "" when the current profile decides
+func GetAPIEndpoint() string {
+	if apiEndpoint != "" {
+		return apiEndpoint
+	}
+	return os.Getenv("API_ENDPOINT")
+}
+
 // GetLocalAPIURL returns the local API server URL
 func GetLocalAPIURL() string {
+	if apiEndpoint != "" {
+		return apiEndpoint
+	}
 	return v.GetString("api.endpoint.local")
 }
 
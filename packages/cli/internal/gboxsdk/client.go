@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/user"
 	"strings"
 
 	sdk "github.com/babelcloud/gbox-sdk-go"
@@ -27,10 +28,10 @@ type profile struct {
 // If the active profile's organization is "local" then the client will be
 // created without an API key.
 func NewClientFromProfile() (*sdk.Client, error) {
-	// Environment variable takes precedence: if API_ENDPOINT is set, use it directly
-	if endpoint := os.Getenv("API_ENDPOINT"); endpoint != "" {
+	// An explicit endpoint takes precedence: if --api-endpoint or API_ENDPOINT is set, use it directly
+	if endpoint := config.GetAPIEndpoint(); endpoint != "" {
 		base := strings.TrimSuffix(endpoint, "/") + "/api/v1"
-		client := sdk.NewClient(option.WithBaseURL(base))
+		client := sdk.NewClient(option.WithBaseURL(base), withLocalUser())
 		return &client, nil
 	}
 
@@ -64,6 +65,7 @@ func NewClientFromProfile() (*sdk.Client, error) {
 		base := strings.TrimSuffix(config.GetLocalAPIURL(), "/") + "/api/v1"
 		client := sdk.NewClient(
 			option.WithBaseURL(base),
+			withLocalUser(),
 		)
 		return &client, nil
 	}
@@ -79,3 +81,22 @@ func NewClientFromProfile() (*sdk.Client, error) {
 	)
 	return &client, nil
 }
+
+// UserHeader identifies the local user to a server that is used without an
+// API key, so boxes can be attributed to whoever created them.
+const UserHeader = "X-Gbox-User"
+
+// LocalUser returns the name of the OS user running the CLI.
+func LocalUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+func withLocalUser() option.RequestOption {
+	return option.WithHeader(UserHeader, LocalUser())
+}